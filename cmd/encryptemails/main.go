@@ -0,0 +1,85 @@
+// Command encryptemails encrypts the subscriber addresses stored in plaintext, for
+// deployments that enable SUBSCRIBER_EMAIL_KEY on existing data:
+//
+//	go run ./cmd/encryptemails -batch 500
+//
+// It encrypts subscribers and their address changes, the recipients of queued and failed emails
+// and of post delivery reports, and the subscriber addresses in webhook payloads, and replaces
+// suppressed addresses by their blind index. Already encrypted rows are skipped, so it can be run again safely, e.g.
+// after a rollout during which older instances still stored plaintext.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	batch := flag.Int("batch", 500, "rows read per query")
+	flag.Parse()
+
+	if err := run(*batch); err != nil {
+		fmt.Fprintf(os.Stderr, "encryptemails: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(batch int) error {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error loading .env file: %w", err)
+	}
+	cfg, err := config.LoadWithFile()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	application, err := app.New(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer application.Stop(ctx)
+
+	encrypted, duplicates, err := application.Repositories.Subscriber.EncryptStoredEmails(ctx, batch)
+	if err != nil {
+		return err
+	}
+	suppressed, err := application.Repositories.Suppression.EncryptStoredEmails(ctx)
+	if err != nil {
+		return err
+	}
+
+	queued, err := application.Repositories.Outbox.EncryptStoredEmails(ctx, batch)
+	if err != nil {
+		return err
+	}
+	failed, err := application.Repositories.EmailJob.EncryptStoredEmails(ctx, batch)
+	if err != nil {
+		return err
+	}
+	reports, err := application.Repositories.SendAttempt.EncryptStoredEmails(ctx, batch)
+	if err != nil {
+		return err
+	}
+	deliveries, err := application.Repositories.Webhook.EncryptStoredEmails(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("encrypted %d subscriber addresses, %d suppressed addresses\n", encrypted, suppressed)
+	fmt.Printf("encrypted %d queued and %d failed email recipients, %d delivery reports, %d webhook deliveries\n", queued, failed, reports, deliveries)
+	if duplicates > 0 {
+		fmt.Printf("%d addresses left in plaintext: the newsletter has the same address encrypted in another case\n", duplicates)
+	}
+	return nil
+}
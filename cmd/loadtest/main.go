@@ -0,0 +1,47 @@
+// Command loadtest generates load-test scenarios for the subscribe and publish paths and
+// benchmarks the publish pipeline end to end.
+//
+//	go run ./cmd/loadtest targets -base-url http://localhost:8080/api/v1 -newsletter <id> -token <jwt> -n 1000
+//	go run ./cmd/loadtest publish -editor <profile id> -subscribers 5000 -provider-latency 80ms
+//	go run ./cmd/loadtest subscribe-race -newsletter <id> -concurrency 20
+//
+// "targets" writes a vegeta JSON targets file and a k6 script; "publish" seeds a throwaway
+// newsletter with N subscribers, publishes a post against a stubbed email provider and
+// reports the publish-to-last-email latency, so pool sizes and worker counts can be tuned;
+// BenchmarkPublishDispatch in internal/services measures the render and dispatch part
+// without a database, under go test -bench.
+// "subscribe-race" subscribes the same address concurrently and fails unless exactly one
+// call wins and the others report an existing subscription.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "targets":
+		err = runTargets(os.Args[2:])
+	case "publish":
+		err = runPublish(os.Args[2:])
+	case "subscribe-race":
+		err = runSubscribeRace(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadtest: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: loadtest <targets|publish|subscribe-race> [flags]")
+	os.Exit(2)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// stubProvider emulates the Resend emails endpoint with a fixed latency and records deliveries
+type stubProvider struct {
+	latency  time.Duration
+	failRate float64
+
+	received atomic.Int64
+	mu       sync.Mutex
+	first    time.Time
+	last     time.Time
+}
+
+func (p *stubProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	time.Sleep(p.latency)
+
+	n := p.received.Add(1)
+	now := time.Now()
+	p.mu.Lock()
+	if p.first.IsZero() {
+		p.first = now
+	}
+	p.last = now
+	p.mu.Unlock()
+
+	// Fail deterministically every 1/failRate requests
+	if p.failRate > 0 && n%int64(1/p.failRate) == 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]any{"statusCode": 500, "message": "stubbed failure"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": uuid.NewString()})
+}
+
+func runPublish(args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	editor := fs.String("editor", "", "profile ID owning the throwaway newsletter (required)")
+	subscribers := fs.Int("subscribers", 1000, "number of subscribers to seed")
+	workers := fs.Int("workers", 0, "email dispatch workers (default: MAIL_DISPATCH_WORKERS)")
+	rate := fs.Float64("rate", -1, "email provider requests per second, 0 for no cap (default: MAIL_RATE_PER_SECOND)")
+	latency := fs.Duration("provider-latency", 50*time.Millisecond, "simulated email provider latency per request")
+	failRate := fs.Float64("provider-fail-rate", 0, "fraction of provider requests that fail, e.g. 0.01")
+	keep := fs.Bool("keep", false, "keep the seeded newsletter instead of deleting it")
+	verbose := fs.Bool("v", false, "log application output")
+	fs.Parse(args)
+
+	if *failRate < 0 || *failRate > 1 {
+		return fmt.Errorf("-provider-fail-rate must be between 0 and 1")
+	}
+
+	editorID, err := uuid.Parse(*editor)
+	if err != nil {
+		return fmt.Errorf("-editor must be a profile UUID: %w", err)
+	}
+
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("loading .env: %w", err)
+	}
+
+	logOutput := io.Discard
+	if *verbose {
+		logOutput = os.Stderr
+	}
+	logger := slog.New(slog.NewJSONHandler(logOutput, nil))
+
+	provider := &stubProvider{latency: *latency, failRate: *failRate}
+	stub := httptest.NewServer(provider)
+	defer stub.Close()
+
+	cfg, err := config.LoadWithFile()
+	if err != nil {
+		return err
+	}
+	cfg.Resend.BaseURL = stub.URL + "/"
+	if cfg.Resend.Sender == "" {
+		cfg.Resend.Sender = "loadtest@loadtest.invalid"
+	}
+	if *workers > 0 {
+		cfg.Mailing.DispatchWorkers = *workers
+	}
+	if *rate >= 0 {
+		cfg.Mailing.RatePerSecond = *rate
+	}
+
+	ctx := context.Background()
+	application, err := app.New(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer application.Stop(ctx)
+	dbpool := application.DB
+	newsletterService := application.Services.Newsletter
+	postService := application.Services.Post
+
+	description := "Throwaway newsletter created by cmd/loadtest"
+	newsletter, err := newsletterService.CreateNewsletter(ctx, editorID.String(), generated.NewsletterCreate{
+		Name:        "Load test " + time.Now().UTC().Format(time.RFC3339),
+		Description: &description,
+	})
+	if err != nil {
+		return fmt.Errorf("creating newsletter: %w", err)
+	}
+	newsletterID := uuid.UUID(*newsletter.Id)
+	if !*keep {
+		defer cleanup(dbpool, newsletterID)
+	}
+
+	seedStart := time.Now()
+	if err := seedSubscribers(ctx, dbpool, newsletterID, *subscribers); err != nil {
+		return fmt.Errorf("seeding subscribers: %w", err)
+	}
+	fmt.Printf("seeded %d subscribers in %s\n", *subscribers, time.Since(seedStart).Round(time.Millisecond))
+
+	now := time.Now().UTC()
+	contentHTML := "<h1>Load test</h1><p>Generated by cmd/loadtest.</p>"
+	start := time.Now()
+	_, err = postService.CreatePost(ctx, editorID, generated.PublishPostRequest{
+		Title:       "Load test post",
+		ContentHtml: &contentHTML,
+		ScheduledAt: &now,
+	}, newsletterID)
+	returned := time.Since(start)
+	if err != nil {
+		return fmt.Errorf("publishing post: %w", err)
+	}
+
+	// The post's emails are queued; send them like the outbox dispatcher does
+	if _, _, err := postService.DeliverQueuedEmails(ctx); err != nil {
+		return fmt.Errorf("sending queued emails: %w", err)
+	}
+
+	provider.mu.Lock()
+	first, last := provider.first, provider.last
+	provider.mu.Unlock()
+	received := provider.received.Load()
+
+	fmt.Printf("dispatch workers:          %d\n", cfg.Mailing.DispatchWorkers)
+	fmt.Printf("send rate cap:             %g/s\n", cfg.Mailing.RatePerSecond)
+	fmt.Printf("provider latency:          %s\n", *latency)
+	fmt.Printf("emails received:           %d / %d\n", received, *subscribers)
+	fmt.Printf("publish call returned in:  %s\n", returned.Round(time.Millisecond))
+	if received > 0 {
+		fmt.Printf("publish to first email:    %s\n", first.Sub(start).Round(time.Millisecond))
+		fmt.Printf("publish to last email:     %s\n", last.Sub(start).Round(time.Millisecond))
+		fmt.Printf("throughput:                %.1f emails/s\n", float64(received)/last.Sub(start).Seconds())
+	}
+	return nil
+}
+
+// seedSubscribers inserts n confirmed subscribers in a single statement
+func seedSubscribers(ctx context.Context, db *pgxpool.Pool, newsletterID uuid.UUID, n int) error {
+	query := `
+		INSERT INTO subscribers (newsletter_id, email, unsubscribe_token, is_confirmed)
+		SELECT $1, 'lt-' || $2 || '-' || i || '@loadtest.invalid', gen_random_uuid()::text, TRUE
+		FROM generate_series(1, $3::int) AS i
+	`
+	_, err := db.Exec(ctx, query, newsletterID, newsletterID.String()[:8], n)
+	return err
+}
+
+// cleanup removes the throwaway newsletter together with its subscribers and posts
+func cleanup(db *pgxpool.Pool, newsletterID uuid.UUID) {
+	ctx := context.Background()
+	for _, query := range []string{
+		`DELETE FROM subscribers WHERE newsletter_id = $1`,
+		`DELETE FROM published_posts WHERE newsletter_id = $1`,
+		`DELETE FROM newsletters WHERE id = $1`,
+	} {
+		if _, err := db.Exec(ctx, query, newsletterID); err != nil {
+			fmt.Fprintf(os.Stderr, "loadtest: cleanup of newsletter %s failed: %v\n", newsletterID, err)
+			return
+		}
+	}
+}
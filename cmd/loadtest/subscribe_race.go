@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// runSubscribeRace fires concurrent subscribe calls for the same address at a real database
+// and checks that exactly one succeeds and every other call reports ErrAlreadySubscribed,
+// i.e. the unique constraint (not ExistsByEmail) is what settles the race.
+func runSubscribeRace(args []string) error {
+	fs := flag.NewFlagSet("subscribe-race", flag.ExitOnError)
+	newsletter := fs.String("newsletter", "", "newsletter to subscribe to (required); the test subscriber is removed afterwards")
+	concurrency := fs.Int("concurrency", 20, "number of simultaneous subscribe calls")
+	rounds := fs.Int("rounds", 5, "number of races, each with a fresh address")
+	verbose := fs.Bool("v", false, "log application output")
+	fs.Parse(args)
+
+	newsletterID, err := uuid.Parse(*newsletter)
+	if err != nil {
+		return fmt.Errorf("-newsletter must be a newsletter UUID: %w", err)
+	}
+	if *concurrency < 2 {
+		return fmt.Errorf("-concurrency must be at least 2")
+	}
+
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("loading .env: %w", err)
+	}
+
+	logOutput := io.Discard
+	if *verbose {
+		logOutput = os.Stderr
+	}
+	logger := slog.New(slog.NewJSONHandler(logOutput, nil))
+
+	// Confirmation emails go to a stub so the race is not slowed down or billed by the provider
+	stub := httptest.NewServer(&stubProvider{})
+	defer stub.Close()
+
+	cfg, err := config.LoadWithFile()
+	if err != nil {
+		return err
+	}
+	cfg.Resend.BaseURL = stub.URL + "/"
+	if cfg.Resend.Sender == "" {
+		cfg.Resend.Sender = "loadtest@loadtest.invalid"
+	}
+
+	ctx := context.Background()
+	application, err := app.New(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer application.Stop(ctx)
+	subscriberService := application.Services.Subscriber
+
+	failed := false
+	for round := 1; round <= *rounds; round++ {
+		email := fmt.Sprintf("race-%s@loadtest.invalid", uuid.NewString()[:8])
+
+		var (
+			wg      sync.WaitGroup
+			start   = make(chan struct{})
+			mu      sync.Mutex
+			created int
+			ids     []uuid.UUID
+			dupes   int
+			others  []error
+		)
+		for i := 0; i < *concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				subscriber, err := subscriberService.Subscribe(ctx, newsletterID, openapi_types.Email(email))
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					created++
+					ids = append(ids, *subscriber.Id)
+				case errors.Is(err, services.ErrAlreadySubscribed):
+					dupes++
+				default:
+					others = append(others, err)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		// Deleted by ID, as the stored address may be encrypted
+		if _, err := application.DB.Exec(ctx, `DELETE FROM subscribers WHERE id = ANY($1)`, ids); err != nil {
+			fmt.Fprintf(os.Stderr, "loadtest: cleanup of %s failed: %v\n", email, err)
+		}
+
+		status := "ok"
+		if created != 1 || len(others) > 0 {
+			status = "FAIL"
+			failed = true
+		}
+		fmt.Printf("round %d: %s created=%d already_subscribed=%d other_errors=%d\n", round, status, created, dupes, len(others))
+		for _, err := range others {
+			fmt.Printf("  error: %v\n", err)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("concurrent subscribes were not deduplicated")
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// vegetaTarget is one line of a vegeta targets file in -format=json
+type vegetaTarget struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Body   []byte              `json:"body,omitempty"` // base64 encoded by encoding/json, as vegeta expects
+	Header map[string][]string `json:"header,omitempty"`
+}
+
+func runTargets(args []string) error {
+	fs := flag.NewFlagSet("targets", flag.ExitOnError)
+	baseURL := fs.String("base-url", "http://localhost:8080/api/v1", "API base URL")
+	newsletterID := fs.String("newsletter", "", "newsletter to subscribe to and publish into (required)")
+	token := fs.String("token", "", "editor JWT for the publish scenario; the publish targets are skipped without it")
+	n := fs.Int("n", 1000, "number of subscribe targets (unique emails)")
+	posts := fs.Int("posts", 10, "number of publish targets")
+	emailDomain := fs.String("email-domain", "loadtest.invalid", "domain of generated subscriber emails")
+	outDir := fs.String("out", "loadtest", "output directory")
+	fs.Parse(args)
+
+	if *newsletterID == "" {
+		return fmt.Errorf("-newsletter is required")
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+	base := strings.TrimRight(*baseURL, "/")
+	runID := time.Now().UTC().Format("20060102150405")
+
+	subscribeURL := fmt.Sprintf("%s/newsletters/%s/subscribe", base, *newsletterID)
+	var subscribe []vegetaTarget
+	for i := 0; i < *n; i++ {
+		body, _ := json.Marshal(generated.SubscriptionRequest{
+			Email: openapi_types.Email(subscriberEmail(runID, i, *emailDomain)),
+		})
+		subscribe = append(subscribe, vegetaTarget{
+			Method: http.MethodPost,
+			URL:    subscribeURL,
+			Body:   body,
+			Header: map[string][]string{"Content-Type": {"application/json"}},
+		})
+	}
+	if err := writeTargets(filepath.Join(*outDir, "subscribe.vegeta.jsonl"), subscribe); err != nil {
+		return err
+	}
+
+	if *token != "" {
+		publishURL := fmt.Sprintf("%s/newsletters/%s/posts", base, *newsletterID)
+		var publish []vegetaTarget
+		for i := 0; i < *posts; i++ {
+			body, _ := json.Marshal(publishRequest(runID, i))
+			publish = append(publish, vegetaTarget{
+				Method: http.MethodPost,
+				URL:    publishURL,
+				Body:   body,
+				Header: map[string][]string{
+					"Content-Type":  {"application/json"},
+					"Authorization": {"Bearer " + *token},
+				},
+			})
+		}
+		if err := writeTargets(filepath.Join(*outDir, "publish.vegeta.jsonl"), publish); err != nil {
+			return err
+		}
+	}
+
+	if err := writeK6Script(filepath.Join(*outDir, "scenarios.k6.js"), k6Params{
+		BaseURL:      base,
+		NewsletterID: *newsletterID,
+		EmailDomain:  *emailDomain,
+		RunID:        runID,
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote scenarios to %s\n", *outDir)
+	fmt.Printf("  vegeta: vegeta attack -format=json -rate=50/s -duration=30s < %s | vegeta report\n", filepath.Join(*outDir, "subscribe.vegeta.jsonl"))
+	fmt.Printf("  k6:     k6 run -e TOKEN=<jwt> %s\n", filepath.Join(*outDir, "scenarios.k6.js"))
+	return nil
+}
+
+func subscriberEmail(runID string, i int, domain string) string {
+	return fmt.Sprintf("lt-%s-%06d@%s", runID, i, domain)
+}
+
+func publishRequest(runID string, i int) generated.PublishPostRequest {
+	now := time.Now().UTC()
+	contentHTML := fmt.Sprintf("<h1>Load test %s #%d</h1><p>Generated by cmd/loadtest.</p>", runID, i)
+	return generated.PublishPostRequest{
+		Title:       fmt.Sprintf("Load test %s #%d", runID, i),
+		ContentHtml: &contentHTML,
+		ScheduledAt: &now,
+	}
+}
+
+func writeTargets(path string, targets []vegetaTarget) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, t := range targets {
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+type k6Params struct {
+	BaseURL      string
+	NewsletterID string
+	EmailDomain  string
+	RunID        string
+}
+
+var k6Script = template.Must(template.New("k6").Parse(`// Generated by go run ./cmd/loadtest targets
+// Run with: k6 run -e TOKEN=<editor jwt> scenarios.k6.js
+import http from 'k6/http';
+import { check } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || '{{.BaseURL}}';
+const NEWSLETTER_ID = __ENV.NEWSLETTER_ID || '{{.NewsletterID}}';
+const TOKEN = __ENV.TOKEN || '';
+
+export const options = {
+  scenarios: {
+    subscribe: {
+      executor: 'constant-arrival-rate',
+      exec: 'subscribe',
+      rate: Number(__ENV.SUBSCRIBE_RATE || 50),
+      timeUnit: '1s',
+      duration: __ENV.DURATION || '1m',
+      preAllocatedVUs: 20,
+      maxVUs: 200,
+    },
+    publish: {
+      executor: 'constant-arrival-rate',
+      exec: 'publish',
+      rate: Number(__ENV.PUBLISH_RATE || 1),
+      timeUnit: '10s',
+      duration: __ENV.DURATION || '1m',
+      preAllocatedVUs: 2,
+      maxVUs: 10,
+    },
+  },
+  thresholds: {
+    'http_req_duration{scenario:subscribe}': ['p(95)<500'],
+    'http_req_failed{scenario:subscribe}': ['rate<0.01'],
+  },
+};
+
+export function subscribe() {
+  const email = ` + "`lt-{{.RunID}}-${__VU}-${__ITER}@{{.EmailDomain}}`" + `;
+  const res = http.post(` + "`${BASE_URL}/newsletters/${NEWSLETTER_ID}/subscribe`" + `, JSON.stringify({ email }), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  check(res, { 'subscribed': (r) => r.status === 201 || r.status === 200 });
+}
+
+export function publish() {
+  if (!TOKEN) {
+    return;
+  }
+  const payload = {
+    title: ` + "`Load test {{.RunID}} ${__VU}-${__ITER}`" + `,
+    content_html: '<p>Generated by cmd/loadtest.</p>',
+    scheduled_at: new Date().toISOString(),
+  };
+  const res = http.post(` + "`${BASE_URL}/newsletters/${NEWSLETTER_ID}/posts`" + `, JSON.stringify(payload), {
+    headers: { 'Content-Type': 'application/json', Authorization: ` + "`Bearer ${TOKEN}`" + ` },
+  });
+  check(res, { 'published': (r) => r.status === 201 });
+}
+`))
+
+func writeK6Script(path string, params k6Params) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := k6Script.Execute(f, params); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+
+	"github.com/joho/godotenv"
+)
+
+// runBackup writes a snapshot to BACKUP_URL now, regardless of the schedule
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	timeout := fs.Duration("timeout", time.Hour, "timeout for the whole backup")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	application, err := openApp(ctx)
+	if err != nil {
+		return err
+	}
+	defer application.Stop(ctx)
+
+	result, err := application.Services.Backup.CreateSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d rows (%d bytes) to %s\n", result.Rows, result.Bytes, result.Location)
+	fmt.Printf("restore with: newsletterctl restore -snapshot %s\n", result.Key)
+	return nil
+}
+
+// runRestore inserts the rows of a snapshot below BACKUP_URL into the configured database
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	snapshot := fs.String("snapshot", "", "key of the snapshot below BACKUP_URL, e.g. newsletter-backup-20260101T000000Z.jsonl.gz (required)")
+	timeout := fs.Duration("timeout", time.Hour, "timeout for the whole restore")
+	fs.Parse(args)
+
+	if *snapshot == "" {
+		return errors.New("-snapshot is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	application, err := openApp(ctx)
+	if err != nil {
+		return err
+	}
+	defer application.Stop(ctx)
+
+	inserted, err := application.Services.Backup.Restore(ctx, *snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %d rows from %s, rows that already existed were kept\n", inserted, *snapshot)
+	return nil
+}
+
+// openApp connects to the database configured like the server, from the environment, .env
+// and CONFIG_FILE
+func openApp(ctx context.Context) (*app.App, error) {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error loading .env file: %w", err)
+	}
+	cfg, err := config.LoadWithFile()
+	if err != nil {
+		return nil, err
+	}
+	return app.New(ctx, cfg, logger)
+}
@@ -0,0 +1,52 @@
+// Command newsletterctl operates on newsletters through the public API and on backups of
+// the database.
+//
+//	go run ./cmd/newsletterctl promote -from-url https://staging.example.com/api/v1 -from-newsletter <id> \
+//	    -to-url https://api.example.com/api/v1 -to-newsletter <id> [-apply]
+//
+// "promote" exports the config bundle of the source newsletter, shows how the target's
+// settings would change and, with -apply, imports the bundle into the target. Without
+// -apply it is a dry run. Bearer tokens are read from NEWSLETTERCTL_FROM_TOKEN and
+// NEWSLETTERCTL_TO_TOKEN unless given as flags, so they stay out of shell history.
+//
+//	go run ./cmd/newsletterctl backup
+//	go run ./cmd/newsletterctl restore -snapshot newsletter-backup-20260101T000000Z.jsonl.gz
+//
+// "backup" writes a snapshot of the critical tables to BACKUP_URL right away. "restore"
+// inserts the rows of a snapshot into the configured database in one transaction, keeping
+// rows that already exist. The database must be migrated to the version the snapshot was
+// taken at, and the Supabase auth users the profiles and newsletters belong to must exist.
+// Both read the database and backup settings like the server does.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "promote":
+		err = runPromote(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "newsletterctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: newsletterctl <promote|backup|restore> [flags]")
+	os.Exit(2)
+}
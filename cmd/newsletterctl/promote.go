@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// environment is one side of a promotion: an API base URL, a token and a newsletter there
+type environment struct {
+	name       string
+	client     *generated.ClientWithResponses
+	newsletter uuid.UUID
+}
+
+// runPromote copies the config bundle of a newsletter in one environment to a newsletter in
+// another, after printing the delta. Branding is left alone unless -include-branding is set,
+// since staging and production newsletters usually carry different names.
+func runPromote(args []string) error {
+	fs := flag.NewFlagSet("promote", flag.ExitOnError)
+	fromURL := fs.String("from-url", "", "API base URL of the source environment, e.g. https://staging.example.com/api/v1 (required)")
+	fromToken := fs.String("from-token", os.Getenv("NEWSLETTERCTL_FROM_TOKEN"), "bearer token for the source (default $NEWSLETTERCTL_FROM_TOKEN)")
+	fromNewsletter := fs.String("from-newsletter", "", "source newsletter ID (required)")
+	toURL := fs.String("to-url", "", "API base URL of the target environment (required)")
+	toToken := fs.String("to-token", os.Getenv("NEWSLETTERCTL_TO_TOKEN"), "bearer token for the target (default $NEWSLETTERCTL_TO_TOKEN)")
+	toNewsletter := fs.String("to-newsletter", "", "target newsletter ID (required)")
+	includeBranding := fs.Bool("include-branding", false, "also promote branding (the newsletter name)")
+	apply := fs.Bool("apply", false, "import the bundle into the target; without it only the delta is shown")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout for the whole promotion")
+	fs.Parse(args)
+
+	source, err := newEnvironment("source", *fromURL, *fromToken, *fromNewsletter)
+	if err != nil {
+		return err
+	}
+	target, err := newEnvironment("target", *toURL, *toToken, *toNewsletter)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	bundle, err := source.exportBundle(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := target.exportBundle(ctx)
+	if err != nil {
+		return err
+	}
+	if !*includeBranding {
+		bundle.Branding = nil
+	}
+
+	changes := bundleDelta(current, bundle)
+	fmt.Printf("promote %s -> %s\n", source.newsletter, target.newsletter)
+	if len(changes) == 0 {
+		fmt.Println("target is up to date")
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Println("  " + change)
+	}
+
+	if !*apply {
+		fmt.Println("dry run, re-run with -apply to import")
+		return nil
+	}
+
+	resp, err := target.client.PutNewslettersNewsletterIdConfigBundleWithResponse(ctx, target.newsletter, *bundle)
+	if err != nil {
+		return fmt.Errorf("import into target: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return fmt.Errorf("import into target: %s: %s", resp.Status(), strings.TrimSpace(string(resp.Body)))
+	}
+	fmt.Printf("applied %d change(s)\n", len(changes))
+	return nil
+}
+
+func newEnvironment(name, baseURL, token, newsletter string) (*environment, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("%s URL is required", name)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%s token is required", name)
+	}
+	id, err := uuid.Parse(newsletter)
+	if err != nil {
+		return nil, fmt.Errorf("%s newsletter must be a UUID: %w", name, err)
+	}
+
+	client, err := generated.NewClientWithResponses(baseURL, generated.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("%s client: %w", name, err)
+	}
+	return &environment{name: name, client: client, newsletter: id}, nil
+}
+
+func (e *environment) exportBundle(ctx context.Context) (*generated.NewsletterConfigBundle, error) {
+	resp, err := e.client.GetNewslettersNewsletterIdConfigBundleWithResponse(ctx, e.newsletter)
+	if err != nil {
+		return nil, fmt.Errorf("export from %s: %w", e.name, err)
+	}
+	if resp.JSON200 == nil {
+		return nil, fmt.Errorf("export from %s: %s: %s", e.name, resp.Status(), strings.TrimSpace(string(resp.Body)))
+	}
+	return resp.JSON200, nil
+}
+
+// bundleDelta lists the settings an import of next would change in current, one line per field
+func bundleDelta(current, next *generated.NewsletterConfigBundle) []string {
+	var changes []string
+	diff := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, before, after))
+		}
+	}
+
+	if next.Branding != nil {
+		before := ""
+		if current.Branding != nil {
+			before = current.Branding.Name
+		}
+		diff("name", quote(&before), quote(&next.Branding.Name))
+	}
+	diff("description", quote(current.Settings.Description), quote(next.Settings.Description))
+	diff("catch_up_policy", string(current.Settings.CatchUpPolicy), string(next.Settings.CatchUpPolicy))
+	diff("catch_up_max_age_minutes", intOrNull(current.Settings.CatchUpMaxAgeMinutes), intOrNull(next.Settings.CatchUpMaxAgeMinutes))
+	diff("unconfirmed_retention_days", intOrNull(current.Settings.UnconfirmedRetentionDays), intOrNull(next.Settings.UnconfirmedRetentionDays))
+	return changes
+}
+
+func quote(s *string) string {
+	if s == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%q", *s)
+}
+
+func intOrNull(i *int) string {
+	if i == nil {
+		return "null"
+	}
+	return fmt.Sprint(*i)
+}
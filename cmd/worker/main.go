@@ -0,0 +1,72 @@
+// Command worker runs the background jobs (scheduled post publisher, outbox dispatcher) without the HTTP API,
+// so background processing can be scaled and deployed independently of the API servers.
+// It is wired through the same app bootstrap as cmd/server. Run the API with
+// SCHEDULER_ENABLED=false when a worker is deployed; the advisory lock keeps concurrent
+// publishers safe either way.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/logging"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Initialize logger
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	})).With("process", "worker")
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Error loading .env file", "error", err)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadWithFile()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Replace the bootstrap logger with the configured one (level, PII redaction)
+	logger = logging.New(os.Stdout, cfg.Logging).With("process", "worker")
+
+	// A termination signal cancels ctx, also while the worker is still connecting to the database;
+	// after the first signal the default handling applies again, so a second one kills
+	ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	application, err := app.New(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("Failed to initialize application", "error", err)
+		os.Exit(1)
+	}
+
+	// The worker always runs the background jobs, SCHEDULER_ENABLED only applies to the API server
+	if err := application.Start(app.StartOptions{RunScheduler: true, MetricsPort: cfg.Scheduler.WorkerMetricsPort}); err != nil {
+		logger.Error("Worker failed to start", "error", err)
+		application.Stop(context.Background())
+		os.Exit(1)
+	}
+	logger.Info("Worker started")
+
+	<-ctx.Done()
+	stopSignals()
+	logger.Info("Shutting down after termination signal")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Scheduler.ShutdownGracePeriod)
+	defer cancel()
+	if err := application.Stop(shutdownCtx); err != nil {
+		logger.Error("Shutdown did not complete cleanly", "error", err)
+	}
+	logger.Info("Shutdown complete")
+}
@@ -0,0 +1,115 @@
+// Package alerting reports operational failures (panics, systemic send failures) to the
+// on-call channel configured with ALERT_WEBHOOK_URL.
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/utils"
+)
+
+// Alert is a single operational failure
+type Alert struct {
+	// Source identifies the component, e.g. "http" or "scheduler"
+	Source    string            `json:"source"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	Instance  string            `json:"instance"`
+	Time      time.Time         `json:"time"`
+}
+
+// Notifier delivers alerts. Notify must not block the caller.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert)
+}
+
+// New returns a webhook notifier when ALERT_WEBHOOK_URL is set and a log-only notifier otherwise
+func New(cfg config.AlertingConfig, httpClient *http.Client, logger *slog.Logger) Notifier {
+	if cfg.WebhookURL == "" {
+		return &logNotifier{logger: logger}
+	}
+	return &webhookNotifier{
+		url:        cfg.WebhookURL,
+		httpClient: httpClient,
+		throttle:   cfg.Throttle,
+		logger:     logger,
+		lastSent:   make(map[string]time.Time),
+	}
+}
+
+type logNotifier struct {
+	logger *slog.Logger
+}
+
+func (n *logNotifier) Notify(ctx context.Context, alert Alert) {
+	n.logger.Error("Alert", "source", alert.Source, "message", alert.Message, "request_id", alert.RequestID)
+}
+
+// webhookNotifier posts alerts as JSON. Identical alerts (same source and message) are sent at
+// most once per throttle window so a crash loop doesn't flood the channel.
+type webhookNotifier struct {
+	url        string
+	httpClient *http.Client
+	throttle   time.Duration
+	logger     *slog.Logger
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, alert Alert) {
+	if alert.Time.IsZero() {
+		alert.Time = time.Now().UTC()
+	}
+	alert.Instance = utils.InstanceID()
+
+	key := alert.Source + "\xff" + alert.Message
+	n.mu.Lock()
+	if last, ok := n.lastSent[key]; ok && alert.Time.Sub(last) < n.throttle {
+		n.mu.Unlock()
+		return
+	}
+	n.lastSent[key] = alert.Time
+	n.mu.Unlock()
+
+	// Deliver in the background, detached from the request that triggered the alert
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := n.send(ctx, alert); err != nil {
+			n.logger.Error("Failed to deliver alert", "source", alert.Source, "error", err)
+		}
+	}()
+}
+
+func (n *webhookNotifier) send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,631 @@
+// Package app builds the application's dependency graph once so every entrypoint
+// (API server, worker, tools) wires repositories, services and background jobs the same way.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go-newsletter/internal/alerting"
+	"go-newsletter/internal/assets"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/database"
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/hostedpage"
+	"go-newsletter/internal/httpclient"
+	"go-newsletter/internal/lint"
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/objectstore"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/scheduler"
+	"go-newsletter/internal/server"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/suggest"
+	"go-newsletter/internal/tracing"
+	"go-newsletter/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repositories groups the data access layer
+type Repositories struct {
+	Profile       *repository.ProfileRepository
+	Newsletter    *repository.NewsletterRepository
+	Subscriber    *repository.SubscriberRepository
+	Post          *repository.PostRepository
+	Scheduler     *repository.SchedulerRepository
+	EmailJob      *repository.EmailJobRepository
+	Outbox        *repository.OutboxRepository
+	Incident      *repository.IncidentRepository
+	Usage         *repository.UsageRepository
+	Plan          *repository.PlanRepository
+	Coupon        *repository.CouponRepository
+	Suppression   *repository.SuppressionRepository
+	RuntimeFlag   *repository.RuntimeFlagRepository
+	Cost          *repository.CostRepository
+	Retention     *repository.RetentionRepository
+	Backup        *repository.BackupRepository
+	Notification  *repository.NotificationRepository
+	Onboarding    *repository.OnboardingRepository
+	SampleContent *repository.SampleContentRepository
+	APIKey        *repository.APIKeyRepository
+	Webhook       *repository.WebhookRepository
+	EmailTemplate *repository.EmailTemplateRepository
+	Inbox         *repository.InboxRepository
+	SendAttempt   *repository.SendAttemptRepository
+	Integration   *repository.IntegrationClientRepository
+	AccountLink   *repository.AccountLinkRepository
+	SecurityEvent *repository.SecurityEventRepository
+	Member        *repository.MemberRepository
+	Session       *repository.SessionRepository
+	Tracking      *repository.TrackingRepository
+	Stats         *repository.StatsRepository
+	GDPR          *repository.GDPRRepository
+	Review        *repository.ReviewRepository
+}
+
+// Services groups the business logic layer
+type Services struct {
+	Auth           *services.AuthService
+	Profile        *services.ProfileService
+	Newsletter     *services.NewsletterService
+	Mailing        *services.MailingService
+	Subscriber     *services.SubscriberService
+	Post           *services.PostService
+	EmailJob       *services.EmailJobService
+	Incident       *services.IncidentService
+	Usage          *services.UsageService
+	Plan           *services.PlanService
+	Coupon         *services.CouponService
+	Suppression    *services.SuppressionService
+	ReadOnly       *services.ReadOnlyService
+	Cost           *services.CostService
+	Retention      *services.RetentionService
+	Backup         *services.BackupService
+	Notification   *services.NotificationService
+	Onboarding     *services.OnboardingService
+	SampleContent  *services.SampleContentService
+	Suggestion     *services.SuggestionService
+	Summary        *services.SummaryService
+	Deliverability *services.DeliverabilityService
+	APIKey         *services.APIKeyService
+	Webhook        *services.WebhookService
+	EmailTemplate  *services.EmailTemplateService
+	Inbox          *services.InboxService
+	Badge          *services.BadgeService
+	ResendWebhook  *services.ResendWebhookService
+	OAuth          *services.OAuthService
+	SocialAuth     *services.SocialAuthService
+	MagicLink      *services.MagicLinkService
+	SecurityEvent  *services.SecurityEventService
+	Authorization  *services.AuthorizationService
+	Member         *services.MemberService
+	Session        *services.SessionService
+	Tracking       *services.TrackingService
+	Stats          *services.StatsService
+	GDPR           *services.GDPRService
+	Review         *services.ReviewService
+}
+
+// App is the fully wired application
+type App struct {
+	Config     *config.Config
+	Logger     *slog.Logger
+	DB         *pgxpool.Pool
+	HTTPClient *http.Client
+	Alerts     alerting.Notifier
+
+	Repositories        Repositories
+	Services            Services
+	PostPublisher       *scheduler.PostPublisher
+	ConfirmationRetrier *scheduler.ConfirmationRetrier
+	OutboxDispatcher    *scheduler.OutboxDispatcher
+	WebhookDispatcher   *scheduler.WebhookDispatcher
+	RetentionJob        *scheduler.RetentionJob
+	BackupJob           *scheduler.BackupJob
+	Server              *server.Server
+	Router              http.Handler
+	// ReadOnly is set when the database schema is incompatible with this build and
+	// DB_SCHEMA_MISMATCH=read_only: writes are rejected and background jobs do not run
+	ReadOnly bool
+
+	ownsDB     bool
+	httpServer *http.Server
+	// metricsServer serves /metrics when the HTTP API does not
+	metricsServer *http.Server
+	// shutdownTracing flushes the spans not exported yet; nil for apps made with Build
+	shutdownTracing func(context.Context) error
+	// cancelRequests cancels the context of requests still in flight when shutdown gives up on them
+	cancelRequests context.CancelFunc
+	serveErr       chan error
+	stopOnce       sync.Once
+	stopErr        error
+}
+
+// StartOptions selects which parts of the application Start runs
+type StartOptions struct {
+	// ServeHTTP listens on the configured port and serves the API. With ServeHTTP or
+	// RunScheduler the outbox dispatcher sends the emails of published posts and the webhook
+	// dispatcher calls the webhooks of newsletter events.
+	ServeHTTP bool
+	// RunScheduler starts the scheduled post publisher, the confirmation email retrier, the
+	// retention job and, when BACKUP_URL is set, the backup job
+	RunScheduler bool
+	// MetricsPort serves /metrics on a port of its own, for processes without ServeHTTP
+	MetricsPort string
+}
+
+// New connects to the database and builds the application
+func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*App, error) {
+	switch cfg.Database.SchemaMismatch {
+	case database.SchemaMismatchRefuse, database.SchemaMismatchReadOnly:
+	default:
+		return nil, fmt.Errorf("invalid DB_SCHEMA_MISMATCH %q, use %s or %s", cfg.Database.SchemaMismatch, database.SchemaMismatchRefuse, database.SchemaMismatchReadOnly)
+	}
+
+	switch cfg.Retention.UnconfirmedAction {
+	case services.UnconfirmedDelete, services.UnconfirmedAnonymize:
+	default:
+		return nil, fmt.Errorf("invalid RETENTION_UNCONFIRMED_ACTION %q, use %s or %s", cfg.Retention.UnconfirmedAction, services.UnconfirmedDelete, services.UnconfirmedAnonymize)
+	}
+
+	dbpool, err := database.Connect(ctx, cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Refuse to run against a schema this build cannot read or write safely
+	readOnly := false
+	if _, err := database.CheckSchema(ctx, dbpool, logger); err != nil {
+		if !errors.Is(err, database.ErrSchemaIncompatible) || cfg.Database.SchemaMismatch != database.SchemaMismatchReadOnly {
+			dbpool.Close()
+			return nil, err
+		}
+		logger.Error("Starting in read-only mode", "error", err)
+		readOnly = true
+	}
+
+	// Warn early when the schema is missing indexes the queries rely on
+	database.CheckIndexes(ctx, dbpool, logger)
+
+	a, err := build(cfg, logger, dbpool, readOnly)
+	if err != nil {
+		dbpool.Close()
+		return nil, err
+	}
+	a.ownsDB = true
+
+	a.shutdownTracing, err = tracing.Setup(ctx, cfg.Tracing, logger)
+	if err != nil {
+		dbpool.Close()
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	return a, nil
+}
+
+// Build wires the application on top of an existing pool. The caller keeps ownership of
+// the pool. A missing dependency is reported as an error rather than a panic.
+func Build(cfg *config.Config, logger *slog.Logger, dbpool *pgxpool.Pool) (*App, error) {
+	return build(cfg, logger, dbpool, false)
+}
+
+func build(cfg *config.Config, logger *slog.Logger, dbpool *pgxpool.Pool, readOnly bool) (a *App, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a, err = nil, fmt.Errorf("invalid dependency graph: %v", r)
+		}
+	}()
+	utils.RequireDependencies("App", utils.Dep("config", cfg), utils.Dep("logger", logger), utils.Dep("dbpool", dbpool))
+
+	// Unsubscribe-all links, tracked links and data request tokens are signed with a secret of
+	// their own, never the JWT secret, which signs nothing but sessions
+	if cfg.Security.UnsubscribeSecret == "" {
+		return nil, errors.New("UNSUBSCRIBE_SECRET is required, it signs unsubscribe-all links, tracked links and data request tokens")
+	}
+
+	// Shared outbound HTTP client (connection pooling for Supabase, Resend, ...)
+	httpClient, err := httpclient.New(cfg.HTTPClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize HTTP client: %w", err)
+	}
+
+	// Subscriber addresses are encrypted at rest when a key is configured
+	emails, err := emailcrypt.New(cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize subscriber email encryption: %w", err)
+	}
+	if emails.Enabled() {
+		logger.Info("Subscriber email encryption enabled")
+	}
+
+	// Snapshots can take longer than HTTP_CLIENT_TIMEOUT to upload; the job's context bounds them
+	backupStore, err := objectstore.New(cfg.Backup, &http.Client{Transport: httpClient.Transport})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize backup storage: %w", err)
+	}
+
+	// Deliverability rules are read once; a broken rules file stops startup rather than disabling the checks
+	linter, err := lint.Load(cfg.Lint.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	var blockAt *lint.Severity
+	if cfg.Lint.BlockSeverity != "" {
+		severity, err := lint.ParseSeverity(cfg.Lint.BlockSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LINT_BLOCK_SEVERITY: %w", err)
+		}
+		blockAt = &severity
+	}
+
+	// The footer of subscriber emails is added by a render hook, like hooks of custom builds
+	if cfg.Mailing.FooterHTMLFile != "" {
+		footer, err := os.ReadFile(cfg.Mailing.FooterHTMLFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MAIL_FOOTER_HTML_FILE: %w", err)
+		}
+		emailrender.RegisterHook(emailrender.AppendHTML(string(footer)))
+	}
+
+	// Branding of the pages browsers get for email links, read once like the lint rules
+	hostedPages, err := hostedpage.Load(cfg.HostedPages.BrandingFile)
+	if err != nil {
+		return nil, err
+	}
+	uploadsStore, err := objectstore.Open(objectstore.Settings{
+		URL:               cfg.Assets.UploadsURL,
+		S3Endpoint:        cfg.Assets.S3Endpoint,
+		S3Region:          cfg.Assets.S3Region,
+		S3AccessKeyID:     cfg.Assets.S3AccessKeyID,
+		S3SecretAccessKey: cfg.Assets.S3SecretAccessKey,
+		EnvPrefix:         "ASSETS_UPLOADS",
+	}, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize asset uploads: %w", err)
+	}
+
+	// The model provider serves suggestions and, with SUMMARY_MODE=llm, post summaries. Model
+	// providers often answer slower than HTTP_CLIENT_TIMEOUT, so they get their own timeout.
+	var suggestionProvider, summaryProvider suggest.Provider
+	switch cfg.Summary.Mode {
+	case "extractive", "llm":
+	default:
+		return nil, fmt.Errorf("unsupported SUMMARY_MODE %q, use extractive or llm", cfg.Summary.Mode)
+	}
+	if cfg.Suggestions.Enabled || cfg.Summary.Mode == "llm" {
+		provider, err := suggest.New(cfg.Suggestions, &http.Client{Transport: httpClient.Transport, Timeout: cfg.Suggestions.Timeout})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize suggestions: %w", err)
+		}
+		if cfg.Suggestions.Enabled {
+			suggestionProvider = provider
+		}
+		if cfg.Summary.Mode == "llm" {
+			summaryProvider = provider
+		}
+	}
+
+	a = &App{
+		Config:     cfg,
+		Logger:     logger,
+		DB:         dbpool,
+		HTTPClient: httpClient,
+		Alerts:     alerting.New(cfg.Alerting, httpClient, logger),
+		ReadOnly:   readOnly,
+		serveErr:   make(chan error, 1),
+	}
+
+	a.Repositories = Repositories{
+		Profile:       repository.NewProfileRepository(dbpool, logger),
+		Newsletter:    repository.NewNewsletterRepository(dbpool, logger),
+		Subscriber:    repository.NewSubscriberRepository(dbpool, emails, logger),
+		Post:          repository.NewPostRepository(dbpool, emails, logger),
+		Scheduler:     repository.NewSchedulerRepository(dbpool, logger),
+		EmailJob:      repository.NewEmailJobRepository(dbpool, emails, logger),
+		Outbox:        repository.NewOutboxRepository(dbpool, emails, logger),
+		Incident:      repository.NewIncidentRepository(dbpool, logger),
+		Usage:         repository.NewUsageRepository(dbpool, logger),
+		Plan:          repository.NewPlanRepository(dbpool, logger),
+		Coupon:        repository.NewCouponRepository(dbpool, logger),
+		Suppression:   repository.NewSuppressionRepository(dbpool, emails, logger),
+		RuntimeFlag:   repository.NewRuntimeFlagRepository(dbpool, logger),
+		Cost:          repository.NewCostRepository(dbpool, logger),
+		Retention:     repository.NewRetentionRepository(dbpool, logger),
+		Backup:        repository.NewBackupRepository(dbpool, logger),
+		Notification:  repository.NewNotificationRepository(dbpool, emails, logger),
+		Onboarding:    repository.NewOnboardingRepository(dbpool, logger),
+		SampleContent: repository.NewSampleContentRepository(dbpool, emails, logger),
+		APIKey:        repository.NewAPIKeyRepository(dbpool, logger),
+		Webhook:       repository.NewWebhookRepository(dbpool, emails, logger),
+		EmailTemplate: repository.NewEmailTemplateRepository(dbpool, logger),
+		Inbox:         repository.NewInboxRepository(dbpool, logger),
+		SendAttempt:   repository.NewSendAttemptRepository(dbpool, emails, logger),
+		Integration:   repository.NewIntegrationClientRepository(dbpool, logger),
+		AccountLink:   repository.NewAccountLinkRepository(dbpool, logger),
+		SecurityEvent: repository.NewSecurityEventRepository(dbpool, logger),
+		Member:        repository.NewMemberRepository(dbpool, logger),
+		Session:       repository.NewSessionRepository(dbpool, logger),
+		Tracking:      repository.NewTrackingRepository(dbpool, logger),
+		Stats:         repository.NewStatsRepository(dbpool, logger),
+		GDPR:          repository.NewGDPRRepository(dbpool, emails, logger),
+		Review:        repository.NewReviewRepository(dbpool, logger),
+	}
+
+	s := &a.Services
+	s.Auth = services.NewAuthService(cfg.Supabase.JWTSecrets(), logger)
+	s.Profile = services.NewProfileService(a.Repositories.Profile, logger)
+	s.Authorization = services.NewAuthorizationService(a.Repositories.Member, logger)
+	s.Newsletter = services.NewNewsletterService(a.Repositories.Newsletter, s.Authorization, cfg, logger)
+	s.Mailing, err = services.NewMailingService(cfg, httpClient, logger)
+	if err != nil {
+		return nil, err
+	}
+	s.Member = services.NewMemberService(a.Repositories.Member, s.Newsletter, s.Mailing, cfg, logger)
+	s.Inbox = services.NewInboxService(a.Repositories.Inbox, cfg, logger)
+	s.Incident = services.NewIncidentService(a.Repositories.Incident, s.Mailing, s.Inbox, a.Alerts, cfg, logger)
+	s.Cost = services.NewCostService(a.Repositories.Cost, s.Newsletter, cfg, logger)
+	s.EmailJob = services.NewEmailJobService(a.Repositories.EmailJob, s.Mailing, s.Incident, s.Cost, logger)
+	s.Plan = services.NewPlanService(a.Repositories.Plan, logger)
+	s.Coupon = services.NewCouponService(a.Repositories.Coupon, s.Plan, logger)
+	s.Webhook = services.NewWebhookService(a.Repositories.Webhook, s.Newsletter, httpclient.NewOutbound(cfg.HTTPClient, cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivateTargets), cfg, logger)
+	s.EmailTemplate = services.NewEmailTemplateService(a.Repositories.EmailTemplate, s.Newsletter, logger)
+	s.Suppression = services.NewSuppressionService(a.Repositories.Suppression, s.Newsletter, s.Webhook, cfg, logger)
+	s.Subscriber = services.NewSubscriberService(services.SubscriberServiceDeps{
+		Subscribers:   a.Repositories.Subscriber,
+		Newsletter:    s.Newsletter,
+		Mailing:       s.Mailing,
+		EmailJob:      s.EmailJob,
+		Plan:          s.Plan,
+		Suppression:   s.Suppression,
+		Cost:          s.Cost,
+		Webhook:       s.Webhook,
+		EmailTemplate: s.EmailTemplate,
+		Config:        cfg,
+		Logger:        logger,
+	})
+	s.Summary = services.NewSummaryService(summaryProvider, cfg, logger)
+	s.Deliverability = services.NewDeliverabilityService(linter, blockAt, logger)
+	s.Tracking = services.NewTrackingService(a.Repositories.Tracking, cfg, logger)
+	s.Stats = services.NewStatsService(a.Repositories.Stats, s.Newsletter, logger)
+	s.GDPR = services.NewGDPRService(a.Repositories.GDPR, s.Mailing, cfg, logger)
+	s.Review = services.NewReviewService(a.Repositories.Review, a.Repositories.Post, s.Newsletter, logger)
+	s.Post = services.NewPostService(services.PostServiceDeps{
+		Posts:          a.Repositories.Post,
+		Outbox:         a.Repositories.Outbox,
+		SendAttempts:   a.Repositories.SendAttempt,
+		Newsletter:     s.Newsletter,
+		Subscriber:     s.Subscriber,
+		Mailing:        s.Mailing,
+		EmailJob:       s.EmailJob,
+		Incident:       s.Incident,
+		Plan:           s.Plan,
+		Suppression:    s.Suppression,
+		Cost:           s.Cost,
+		Summary:        s.Summary,
+		Deliverability: s.Deliverability,
+		Webhook:        s.Webhook,
+		Inbox:          s.Inbox,
+		EmailTemplate:  s.EmailTemplate,
+		Tracking:       s.Tracking,
+		Review:         s.Review,
+		Config:         cfg,
+		Logger:         logger,
+	})
+	s.Usage = services.NewUsageService(a.Repositories.Usage, cfg, logger)
+	s.ReadOnly = services.NewReadOnlyService(a.Repositories.RuntimeFlag, cfg, logger)
+	s.Retention = services.NewRetentionService(a.Repositories.Retention, a.Repositories.Newsletter, a.Repositories.Post, a.Repositories.Subscriber, cfg, logger)
+	s.Backup = services.NewBackupService(a.Repositories.Backup, backupStore, cfg, logger)
+	s.Onboarding = services.NewOnboardingService(a.Repositories.Onboarding, logger)
+	s.SampleContent = services.NewSampleContentService(a.Repositories.SampleContent, s.Newsletter, logger)
+	s.Suggestion = services.NewSuggestionService(suggestionProvider, s.Post, s.Newsletter, cfg, logger)
+	s.APIKey = services.NewAPIKeyService(a.Repositories.APIKey, logger)
+	s.OAuth = services.NewOAuthService(a.Repositories.Integration, cfg, logger)
+	s.SecurityEvent = services.NewSecurityEventService(a.Repositories.SecurityEvent, s.Mailing, logger)
+	s.SocialAuth = services.NewSocialAuthService(s.Auth, s.Profile, s.SecurityEvent, a.Repositories.AccountLink, httpClient, cfg, logger)
+	s.MagicLink = services.NewMagicLinkService(s.Auth, s.Profile, s.SecurityEvent, httpClient, cfg, logger)
+	s.Session = services.NewSessionService(a.Repositories.Session, s.Auth, s.Profile, httpClient, cfg, logger)
+	s.Badge = services.NewBadgeService(s.Newsletter, a.Repositories.Subscriber, logger)
+	s.ResendWebhook = services.NewResendWebhookService(a.Repositories.Subscriber, s.Suppression, cfg, logger)
+	s.Notification = services.NewNotificationService(a.Repositories.Notification, s.Newsletter, s.Post, s.Mailing, s.Inbox, logger)
+
+	// The publisher is always constructed so admins can inspect and trigger it manually
+	a.PostPublisher = scheduler.NewPostPublisher(s.Post, a.Repositories.Scheduler, logger.With("component", "postPublisher"))
+	a.ConfirmationRetrier = scheduler.NewConfirmationRetrier(s.Subscriber, logger.With("component", "confirmationRetrier"))
+	a.OutboxDispatcher = scheduler.NewOutboxDispatcher(s.Post, cfg.Mailing.OutboxPollInterval, logger.With("component", "outboxDispatcher"))
+	a.WebhookDispatcher = scheduler.NewWebhookDispatcher(s.Webhook, cfg.Webhooks.PollInterval, logger.With("component", "webhookDispatcher"))
+	a.RetentionJob = scheduler.NewRetentionJob(s.Retention, s.Inbox, s.OAuth, cfg.Retention.Interval, logger.With("component", "retentionJob"))
+	a.BackupJob = scheduler.NewBackupJob(s.Backup, cfg.Backup.Interval, logger.With("component", "backupJob"))
+
+	if cfg.Security.CSRFSecret == "" {
+		logger.Warn("CSRF_SECRET not set, hosted form tokens are only valid on the instance that issued them")
+	}
+	if cfg.Supabase.ServiceRoleKey == "" {
+		logger.Warn("SUPABASE_SERVICE_ROLE_KEY not set, admins cannot revoke the sessions of users")
+	}
+
+	responder := utils.NewHTTPResponder(logger)
+	a.Server = server.NewServer(server.Deps{
+		Config:        cfg,
+		Logger:        logger,
+		Responder:     responder,
+		HostedPages:   hostedPages,
+		PostPublisher: a.PostPublisher,
+		Profile:       s.Profile,
+		Auth:          s.Auth,
+		Mailing:       s.Mailing,
+		Newsletter:    s.Newsletter,
+		Subscriber:    s.Subscriber,
+		Post:          s.Post,
+		EmailJob:      s.EmailJob,
+		Usage:         s.Usage,
+		Plan:          s.Plan,
+		Coupon:        s.Coupon,
+		Suppression:   s.Suppression,
+		ReadOnly:      s.ReadOnly,
+		Cost:          s.Cost,
+		Retention:     s.Retention,
+		Notification:  s.Notification,
+		Onboarding:    s.Onboarding,
+		SampleContent: s.SampleContent,
+		Suggestion:    s.Suggestion,
+		APIKey:        s.APIKey,
+		Webhook:       s.Webhook,
+		EmailTemplate: s.EmailTemplate,
+		Inbox:         s.Inbox,
+		Badge:         s.Badge,
+		ResendWebhook: s.ResendWebhook,
+		OAuth:         s.OAuth,
+		SocialAuth:    s.SocialAuth,
+		MagicLink:     s.MagicLink,
+		SecurityEvent: s.SecurityEvent,
+		Member:        s.Member,
+		Session:       s.Session,
+		Tracking:      s.Tracking,
+		Stats:         s.Stats,
+		GDPR:          s.GDPR,
+		Review:        s.Review,
+	})
+	a.Router, err = server.NewRouter(logger, a.Server, assets.NewHandler(uploadsStore, cfg.Assets.UploadsMaxAge, logger), cfg, a.Alerts, s.Usage, s.ReadOnly, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Start runs the selected components in the background. It returns once the HTTP
+// listener is bound, so port conflicts are reported synchronously.
+func (a *App) Start(opts StartOptions) error {
+	if opts.ServeHTTP {
+		addr := ":" + a.Config.Server.Port
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		// Requests run in a context of their own so Stop can cancel the ones that outlive the grace period
+		requestCtx, cancelRequests := context.WithCancel(context.Background())
+		a.cancelRequests = cancelRequests
+		a.httpServer = &http.Server{
+			Handler:           a.Router,
+			BaseContext:       func(net.Listener) context.Context { return requestCtx },
+			ReadHeaderTimeout: a.Config.Server.ReadHeaderTimeout,
+			ReadTimeout:       a.Config.Server.ReadTimeout,
+			WriteTimeout:      a.Config.Server.WriteTimeout,
+		}
+		if !a.ReadOnly {
+			a.Services.Usage.Start()
+		}
+		go func() {
+			a.Logger.Info("Starting server", "port", a.Config.Server.Port)
+			if err := a.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.Logger.Error("HTTP server stopped unexpectedly", "error", err)
+				a.serveErr <- err
+			}
+		}()
+	}
+
+	if opts.MetricsPort != "" && !opts.ServeHTTP {
+		addr := ":" + opts.MetricsPort
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		a.metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			a.Logger.Info("Serving metrics", "port", opts.MetricsPort)
+			if err := a.metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.Logger.Error("Metrics server stopped unexpectedly", "error", err)
+			}
+		}()
+	}
+
+	// Posts published through the API queue their emails and events, so the API sends them as well
+	if (opts.ServeHTTP || opts.RunScheduler) && !a.ReadOnly {
+		a.OutboxDispatcher.Start()
+		a.WebhookDispatcher.Start()
+	}
+
+	if opts.RunScheduler {
+		if a.ReadOnly {
+			a.Logger.Warn("Scheduled post publisher not started, the application is read-only")
+		} else {
+			a.PostPublisher.Start()
+			a.ConfirmationRetrier.Start()
+			a.RetentionJob.Start()
+			if a.Services.Backup.Enabled() {
+				a.BackupJob.Start()
+			}
+		}
+	}
+	return nil
+}
+
+// Err returns a channel that receives an error when the HTTP server stops unexpectedly; the
+// application should then be stopped like on a termination signal
+func (a *App) Err() <-chan error {
+	return a.serveErr
+}
+
+// Stop stops accepting requests, drains the background jobs, flushes API usage, closes the database pool
+// (if the App opened it) and flushes traces, in that order so nothing in flight loses the database under it. ctx bounds
+// the whole shutdown; requests still running when it is done are cancelled.
+func (a *App) Stop(ctx context.Context) error {
+	a.stopOnce.Do(func() {
+		var errs []error
+		if a.httpServer != nil {
+			if err := a.httpServer.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("HTTP server shutdown: %w", err))
+			}
+			a.cancelRequests()
+		}
+		if a.metricsServer != nil {
+			if err := a.metricsServer.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("metrics server shutdown: %w", err))
+			}
+		}
+		if err := a.PostPublisher.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("scheduled post publisher did not drain: %w", err))
+		}
+		if err := a.ConfirmationRetrier.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("confirmation email retrier did not drain: %w", err))
+		}
+		if err := a.OutboxDispatcher.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("outbox dispatcher did not drain: %w", err))
+		}
+		if err := a.WebhookDispatcher.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("webhook dispatcher did not drain: %w", err))
+		}
+		if err := a.RetentionJob.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("retention job did not drain: %w", err))
+		}
+		if err := a.BackupJob.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("backup job did not drain: %w", err))
+		}
+		// Write the API usage counted since the last flush before the pool closes
+		if !a.ReadOnly {
+			if err := a.Services.Usage.Stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("API usage flush: %w", err))
+			}
+		}
+		if a.ownsDB {
+			a.DB.Close()
+		}
+		if a.shutdownTracing != nil {
+			if err := a.shutdownTracing(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("trace export flush: %w", err))
+			}
+		}
+		a.stopErr = errors.Join(errs...)
+	})
+	return a.stopErr
+}
@@ -0,0 +1,106 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestStartDropsStalledClients checks that the API server started by Start applies the
+// configured read timeouts, so a client that never finishes its request headers is disconnected
+func TestStartDropsStalledClients(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	cfg.Server.Port = freePort(t)
+	cfg.Server.ReadHeaderTimeout = 100 * time.Millisecond
+	cfg.Security.UnsubscribeSecret = "lifecycle-unsubscribe-secret"
+
+	a, err := app.Build(cfg, slog.New(slog.NewJSONHandler(io.Discard, nil)), unreachablePool(t))
+	if err != nil {
+		t.Fatalf("building app: %v", err)
+	}
+	if err := a.Start(app.StartOptions{ServeHTTP: true}); err != nil {
+		t.Fatalf("starting app: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.Stop(ctx); err != nil {
+			t.Errorf("stopping app: %v", err)
+		}
+	})
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+cfg.Server.Port)
+	if err != nil {
+		t.Fatalf("dialing server: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /api/v1/healthz HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatalf("writing partial request: %v", err)
+	}
+
+	// Without a timeout the server would wait for the rest of the headers until this deadline
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("server kept a client with unfinished request headers connected")
+	}
+}
+
+// TestBuildRequiresUnsubscribeSecret checks that links and data request tokens are never signed
+// with the JWT secret when UNSUBSCRIBE_SECRET is missing
+func TestBuildRequiresUnsubscribeSecret(t *testing.T) {
+	t.Setenv("SUPABASE_JWT_SECRET", "lifecycle-jwt-secret")
+	t.Setenv("UNSUBSCRIBE_SECRET", "")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	a, err := app.Build(cfg, slog.New(slog.NewJSONHandler(io.Discard, nil)), unreachablePool(t))
+	if err == nil {
+		t.Fatalf("Build() without UNSUBSCRIBE_SECRET = %v, want error", a)
+	}
+	if !strings.Contains(err.Error(), "UNSUBSCRIBE_SECRET") {
+		t.Errorf("Build() error = %q, want it to name UNSUBSCRIBE_SECRET", err)
+	}
+}
+
+// unreachablePool is a pool of a database that can never be connected to
+func unreachablePool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	poolConfig, err := pgxpool.ParseConfig("host=127.0.0.1 port=1 user=lifecycle dbname=lifecycle sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("parsing pool config: %v", err)
+	}
+	dbpool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		t.Fatalf("creating pool: %v", err)
+	}
+	t.Cleanup(dbpool.Close)
+	return dbpool
+}
+
+func freePort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("finding a free port: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
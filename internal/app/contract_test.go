@@ -0,0 +1,281 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go-newsletter/internal/app"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/services"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The contract test runs every operation of api/openapi.yaml against the real router behind
+// httptest.NewServer and validates each response (status code, content type and body schema)
+// against the spec, so go test fails when the handlers drift from the contract.
+//
+// No database or external service is needed: the pool points at an unreachable address, so
+// data-dependent paths return the documented 500 error and the test still covers routing,
+// authentication, parameter validation and error bodies.
+
+const (
+	specPath = "../../api/openapi.yaml"
+	// specBaseURL is the server of the spec, which its routes are looked up under
+	specBaseURL = "http://localhost:8080/api/v1"
+	jwtSecret   = "contract-check-secret"
+)
+
+// scenario is one request sent for an operation
+type scenario struct {
+	name          string
+	authenticated bool
+	pathValue     func(param *openapi3.Parameter) string
+	body          string
+}
+
+var scenarios = []scenario{
+	{
+		name:      "unauthenticated",
+		pathValue: validPathValue,
+	},
+	{
+		name:          "authenticated",
+		authenticated: true,
+		pathValue:     validPathValue,
+		body:          "{}",
+	},
+	{
+		name:          "malformed path parameter",
+		authenticated: true,
+		pathValue:     func(*openapi3.Parameter) string { return "not-a-uuid" },
+		body:          "{}",
+	},
+	{
+		name:          "malformed body",
+		authenticated: true,
+		pathValue:     validPathValue,
+		body:          "{",
+	},
+}
+
+func TestContract(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromFile(specPath)
+	if err != nil {
+		t.Fatalf("loading %s: %v", specPath, err)
+	}
+	specRouter, err := legacy.NewRouter(spec)
+	if err != nil {
+		t.Fatalf("building spec router: %v", err)
+	}
+
+	// The open pixel of post emails is binary, like files
+	openapi3filter.RegisterBodyDecoder("image/gif", openapi3filter.FileBodyDecoder)
+
+	t.Setenv("SUPABASE_JWT_SECRET", jwtSecret)
+	t.Setenv("UNSUBSCRIBE_SECRET", "contract-unsubscribe-secret")
+	server := httptest.NewServer(newHandler(t))
+	defer server.Close()
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	token := signToken(t)
+
+	paths := spec.Paths.InMatchingOrder()
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := spec.Paths.Value(path)
+		for method, op := range item.Operations() {
+			for _, sc := range scenarios {
+				if !applies(sc, item, op) {
+					continue
+				}
+				t.Run(fmt.Sprintf("%s %s [%s]", method, path, sc.name), func(t *testing.T) {
+					if err := check(client, server.URL+"/api/v1", specRouter, token, method, path, item, op, sc); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+		}
+	}
+}
+
+// applies skips scenarios that make no sense for an operation
+func applies(sc scenario, item *openapi3.PathItem, op *openapi3.Operation) bool {
+	switch sc.name {
+	case "unauthenticated":
+		return requiresAuth(op)
+	case "malformed path parameter":
+		return hasUUIDPathParam(item, op)
+	case "malformed body":
+		return op.RequestBody != nil
+	}
+	return true
+}
+
+func check(client *http.Client, baseURL string, specRouter routers.Router, token, method, path string, item *openapi3.PathItem, op *openapi3.Operation, sc scenario) error {
+	var body io.Reader
+	if op.RequestBody != nil && sc.body != "" {
+		body = strings.NewReader(sc.body)
+	}
+
+	filled := fillPath(path, item, op, sc.pathValue)
+	req, err := http.NewRequest(method, baseURL+filled, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if sc.authenticated {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	specReq := httptest.NewRequest(method, specBaseURL+filled, nil)
+	route, pathParams, err := specRouter.FindRoute(specReq)
+	if err != nil {
+		return fmt.Errorf("spec route lookup: %w", err)
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    specReq,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   io.NopCloser(bytes.NewReader(respBody)),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+		},
+	}
+	if err := openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+		return fmt.Errorf("status %d, body %q: %s", resp.StatusCode, truncate(string(respBody), 200), summarize(err))
+	}
+	return nil
+}
+
+// newHandler wires the real router on top of a database pool that can never connect
+func newHandler(t *testing.T) http.Handler {
+	t.Helper()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	poolConfig, err := pgxpool.ParseConfig("host=127.0.0.1 port=1 user=contract dbname=contract sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("parsing pool config: %v", err)
+	}
+	dbpool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		t.Fatalf("creating pool: %v", err)
+	}
+	t.Cleanup(dbpool.Close)
+
+	a, err := app.Build(cfg, logger, dbpool)
+	if err != nil {
+		t.Fatalf("building API router: %v", err)
+	}
+	return a.Router
+}
+
+func signToken(t *testing.T) string {
+	t.Helper()
+	claims := services.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		UserID: uuid.NewString(),
+		Email:  "contract@example.com",
+		Role:   "authenticated",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
+
+func pathParams(item *openapi3.PathItem, op *openapi3.Operation) []*openapi3.Parameter {
+	var params []*openapi3.Parameter
+	for _, ref := range append(append(openapi3.Parameters{}, item.Parameters...), op.Parameters...) {
+		if ref.Value != nil && ref.Value.In == openapi3.ParameterInPath {
+			params = append(params, ref.Value)
+		}
+	}
+	return params
+}
+
+func fillPath(path string, item *openapi3.PathItem, op *openapi3.Operation, value func(*openapi3.Parameter) string) string {
+	for _, p := range pathParams(item, op) {
+		path = strings.ReplaceAll(path, "{"+p.Name+"}", value(p))
+	}
+	return path
+}
+
+func validPathValue(p *openapi3.Parameter) string {
+	if p.Schema != nil && p.Schema.Value != nil && p.Schema.Value.Format == "uuid" {
+		return uuid.NewString()
+	}
+	return "contract-check-token"
+}
+
+func hasUUIDPathParam(item *openapi3.PathItem, op *openapi3.Operation) bool {
+	for _, p := range pathParams(item, op) {
+		if p.Schema != nil && p.Schema.Value != nil && p.Schema.Value.Format == "uuid" {
+			return true
+		}
+	}
+	return false
+}
+
+func requiresAuth(op *openapi3.Operation) bool {
+	return op.Security == nil || len(*op.Security) > 0
+}
+
+// summarize keeps the first line of each validation error; kin-openapi appends full schema dumps
+func summarize(err error) string {
+	var lines []string
+	for _, part := range strings.Split(err.Error(), " | ") {
+		lines = append(lines, strings.SplitN(part, "\n", 2)[0])
+	}
+	return strings.Join(lines, "; ")
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "…"
+}
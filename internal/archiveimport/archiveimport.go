@@ -0,0 +1,267 @@
+// Package archiveimport reads the archive of a newsletter exported from another tool, a zip of
+// HTML files with one post each, into posts with their original publication dates. The content
+// goes through the email policy of the sanitize package like any post HTML.
+package archiveimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/sanitize"
+	"go-newsletter/internal/summarize"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// MaxFiles bounds the HTML files read from one archive
+	MaxFiles = 1000
+	// MaxBytes bounds the uncompressed size of all HTML files read from one archive
+	MaxBytes = 64 << 20
+	// maxFileBytes bounds the uncompressed size of one file, so a small archive cannot expand
+	// into gigabytes
+	maxFileBytes = 5 << 20
+)
+
+// ErrInvalidArchive is returned for data that is not a zip archive
+var ErrInvalidArchive = errors.New("not a zip archive")
+
+// Post is a post read from a file of the archive
+type Post struct {
+	File        string
+	Title       string
+	HTML        string
+	Text        string
+	PublishedAt time.Time
+}
+
+// Skipped is a file of the archive that could not be read as a post, and why
+type Skipped struct {
+	File   string
+	Reason string
+}
+
+// dateMeta are the names and properties of <meta> tags exporters put the publication date in
+var dateMeta = []string{"article:published_time", "date", "dc.date", "dcterms.created", "pubdate", "publish_date", "publication_date"}
+
+// dateFormats are the layouts publication dates are read in; dates without zone are UTC
+var dateFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// fileDate matches a date at the start of a file name, as in 2023-04-01-spring-update.html
+var fileDate = regexp.MustCompile(`^(\d{4})[-_](\d{2})[-_](\d{2})`)
+
+// Read returns the posts of the HTML files in the zip archive, oldest first, and the files it
+// skipped. Other files, directories and the metadata macOS adds to archives are ignored. An
+// archive whose HTML files add up to more than MaxBytes uncompressed is rejected.
+func Read(data []byte) ([]Post, []Skipped, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, ErrInvalidArchive
+	}
+
+	var posts []Post
+	var skipped []Skipped
+	files := 0
+	total := 0
+	for _, file := range archive.File {
+		name := file.Name
+		base := path.Base(name)
+		if file.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		if ext := strings.ToLower(path.Ext(base)); ext != ".html" && ext != ".htm" {
+			continue
+		}
+		if files++; files > MaxFiles {
+			return nil, nil, fmt.Errorf("the archive has more than %d HTML files", MaxFiles)
+		}
+
+		data, err := readFile(file)
+		if err != nil {
+			skipped = append(skipped, Skipped{File: name, Reason: err.Error()})
+			continue
+		}
+		if total += len(data); total > MaxBytes {
+			return nil, nil, fmt.Errorf("the HTML files of the archive are larger than %d MB uncompressed", MaxBytes>>20)
+		}
+		post, err := parse(name, data)
+		if err != nil {
+			skipped = append(skipped, Skipped{File: name, Reason: err.Error()})
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	sort.SliceStable(posts, func(a, b int) bool { return posts[a].PublishedAt.Before(posts[b].PublishedAt) })
+	return posts, skipped, nil
+}
+
+// readFile returns the uncompressed content of file
+func readFile(file *zip.File) ([]byte, error) {
+	if file.UncompressedSize64 > maxFileBytes {
+		return nil, fmt.Errorf("file is larger than %d MB", maxFileBytes>>20)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, errors.New("file cannot be read")
+	}
+	defer reader.Close()
+	// The size in the header can lie, so the read is bounded too
+	data, err := io.ReadAll(io.LimitReader(reader, maxFileBytes+1))
+	if err != nil {
+		return nil, errors.New("file cannot be read")
+	}
+	if len(data) > maxFileBytes {
+		return nil, fmt.Errorf("file is larger than %d MB", maxFileBytes>>20)
+	}
+	return data, nil
+}
+
+// parse reads the title from <title> or the first <h1>, the publication date from the <meta>
+// tags of dateMeta, the first <time datetime> or the file name, and the content from <body>.
+// An <h1> repeating the title is left out of the content, as the archive shows the title.
+func parse(name string, data []byte) (Post, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return Post{}, errors.New("file is not HTML")
+	}
+
+	var title, heading, metaDate, timeDate string
+	var body, headingNode *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if title == "" {
+					title = textOf(n)
+				}
+			case atom.H1:
+				if headingNode == nil {
+					heading, headingNode = textOf(n), n
+				}
+			case atom.Meta:
+				if metaDate == "" && isDateMeta(n) {
+					metaDate = attr(n, "content")
+				}
+			case atom.Time:
+				if timeDate == "" {
+					timeDate = attr(n, "datetime")
+				}
+			case atom.Body:
+				body = n
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	if title == "" {
+		title = heading
+	}
+	if title == "" {
+		return Post{}, errors.New("no <title> or <h1> to take the title from")
+	}
+	publishedAt, ok := firstDate(metaDate, timeDate, fileNameDate(path.Base(name)))
+	if !ok {
+		return Post{}, errors.New("no publication date in a <meta> tag, a <time> element or the file name")
+	}
+	if body == nil {
+		return Post{}, errors.New("no <body>")
+	}
+	if headingNode != nil && heading == title {
+		headingNode.Parent.RemoveChild(headingNode)
+	}
+
+	var content strings.Builder
+	for child := body.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&content, child); err != nil {
+			return Post{}, errors.New("file is not HTML")
+		}
+	}
+	sanitized := strings.TrimSpace(sanitize.EmailHTML(content.String()))
+	text := summarize.PlainText(sanitized)
+	if text == "" {
+		return Post{}, errors.New("the post has no content")
+	}
+
+	return Post{
+		File:        name,
+		Title:       title,
+		HTML:        sanitized,
+		Text:        text,
+		PublishedAt: publishedAt,
+	}, nil
+}
+
+func isDateMeta(n *html.Node) bool {
+	for _, key := range []string{"name", "property", "itemprop"} {
+		value := strings.ToLower(attr(n, key))
+		for _, name := range dateMeta {
+			if value == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func firstDate(values ...string) (time.Time, bool) {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		for _, format := range dateFormats {
+			if t, err := time.Parse(format, value); err == nil {
+				return t.UTC(), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func fileNameDate(name string) string {
+	match := fileDate.FindStringSubmatch(name)
+	if match == nil {
+		return ""
+	}
+	return match[1] + "-" + match[2] + "-" + match[3]
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textOf returns the text inside n with its whitespace collapsed
+func textOf(n *html.Node) string {
+	var text strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+			text.WriteByte(' ')
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(text.String()), " ")
+}
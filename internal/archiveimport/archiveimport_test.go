@@ -0,0 +1,62 @@
+package archiveimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// archive zips the files, each named after its index
+func archive(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for i, content := range files {
+		file, err := writer.Create(fmt.Sprintf("2023-04-%02d-post.html", i%28+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRejectsArchiveExpandingPastMaxBytes(t *testing.T) {
+	// Each file is under the limit of a file and compresses to a few KB
+	post := "<html><head><title>Update</title></head><body><p>" + strings.Repeat("a", maxFileBytes-100) + "</p></body></html>"
+	files := make([]string, MaxBytes/len(post)+1)
+	for i := range files {
+		files[i] = post
+	}
+	data := archive(t, files...)
+
+	posts, skipped, err := Read(data)
+	if err == nil {
+		t.Fatalf("Read() of %d KB expanding to %d MB = %d posts, %d skipped, want error", len(data)>>10, len(files)*len(post)>>20, len(posts), len(skipped))
+	}
+	if !strings.Contains(err.Error(), "uncompressed") {
+		t.Errorf("Read() error = %q, want it to mention the uncompressed size", err)
+	}
+}
+
+func TestReadAcceptsArchiveUnderMaxBytes(t *testing.T) {
+	data := archive(t, "<html><head><title>Update</title></head><body><p>Spring is here.</p></body></html>")
+
+	posts, skipped, err := Read(data)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(posts) != 1 || len(skipped) != 0 {
+		t.Fatalf("Read() = %d posts, %d skipped, want 1 post", len(posts), len(skipped))
+	}
+	if posts[0].Title != "Update" {
+		t.Errorf("title = %q, want Update", posts[0].Title)
+	}
+}
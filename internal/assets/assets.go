@@ -0,0 +1,181 @@
+// Package assets serves the static files of hosted pages: the stylesheets and templates built
+// into the binary, and files uploaded to object storage. Built-in files are addressed by a hash
+// of their content, so browsers may keep them for good and a new build changes their URL.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/objectstore"
+)
+
+// PathPrefix is where assets are served
+const PathPrefix = "/assets/"
+
+// uploadsPrefix is where uploads are served, below PathPrefix
+const uploadsPrefix = "uploads/"
+
+// immutableCacheControl is sent for content hashed names, whose content never changes
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// plainCacheControl is sent for built-in assets requested without their hash, e.g. by pages
+// cached before a deploy
+const plainCacheControl = "public, max-age=300"
+
+// hashLength is the number of hex digits of content hashes in names
+const hashLength = 16
+
+// hashedName matches names like logo.0123456789abcdef.png, whose content the hash identifies
+var hashedName = regexp.MustCompile(`\.[0-9a-f]{16,64}\.[A-Za-z0-9]+$`)
+
+//go:embed static templates
+var files embed.FS
+
+// Templates holds the default templates of hosted pages
+var Templates fs.FS = mustSub("templates")
+
+type asset struct {
+	content []byte
+	// hashed is the name with the content hash before the extension
+	hashed string
+	etag   string
+}
+
+// static holds the built-in assets by name and by hashed name
+var static = loadStatic()
+
+func mustSub(dir string) fs.FS {
+	sub, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+func loadStatic() map[string]*asset {
+	entries, err := files.ReadDir("static")
+	if err != nil {
+		panic(err)
+	}
+	assets := make(map[string]*asset, 2*len(entries))
+	for _, entry := range entries {
+		content, err := files.ReadFile("static/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])[:hashLength]
+		ext := path.Ext(entry.Name())
+		a := &asset{
+			content: content,
+			hashed:  strings.TrimSuffix(entry.Name(), ext) + "." + hash + ext,
+			etag:    `"` + hash + `"`,
+		}
+		assets[entry.Name()] = a
+		assets[a.hashed] = a
+	}
+	return assets
+}
+
+// Path returns the URL path of the built-in asset name, with its content hash in it
+func Path(name string) string {
+	if a, ok := static[name]; ok {
+		return PathPrefix + a.hashed
+	}
+	return PathPrefix + name
+}
+
+// Handler serves the built-in assets below PathPrefix, and the objects of an uploads store
+// below PathPrefix/uploads/
+type Handler struct {
+	uploads       objectstore.Store
+	uploadsMaxAge time.Duration
+	logger        *slog.Logger
+}
+
+// NewHandler creates a Handler. Without an uploads store, uploads are not found. Uploads with
+// a content hash in their name are cached for good, others for uploadsMaxAge.
+func NewHandler(uploads objectstore.Store, uploadsMaxAge time.Duration, logger *slog.Logger) *Handler {
+	return &Handler{
+		uploads:       uploads,
+		uploadsMaxAge: uploadsMaxAge,
+		logger:        logger,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if key, ok := strings.CutPrefix(name, uploadsPrefix); ok {
+		h.serveUpload(w, r, key)
+		return
+	}
+
+	a, ok := static[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if name == a.hashed {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", plainCacheControl)
+	}
+	w.Header().Set("ETag", a.etag)
+	w.Header().Set("Content-Type", contentType(name))
+	// ServeContent answers If-None-Match with 304 and handles range requests
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.content))
+}
+
+func (h *Handler) serveUpload(w http.ResponseWriter, r *http.Request, key string) {
+	if h.uploads == nil || !fs.ValidPath(key) || key == "." {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := h.uploads.Get(r.Context(), key)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to read uploaded asset", "key", key, "error", err)
+		http.Error(w, "Asset unavailable", http.StatusBadGateway)
+		return
+	}
+	defer body.Close()
+
+	if hashedName.MatchString(path.Base(key)) {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(h.uploadsMaxAge.Seconds())))
+	}
+	w.Header().Set("Content-Type", contentType(key))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to send uploaded asset", "key", key, "error", err)
+	}
+}
+
+func contentType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
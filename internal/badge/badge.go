@@ -0,0 +1,78 @@
+// Package badge renders small two-part SVG badges in the style of shields.io, like the
+// subscriber count badge editors embed on their sites.
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"unicode/utf8"
+)
+
+// charWidth approximates the width in pixels of a character of 11px Verdana; badges do not
+// need exact text metrics, only enough room for their text
+const (
+	charWidth = 7
+	padding   = 10
+)
+
+var svgTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+<title>{{.Label}}: {{.Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{.Message}}</text><text x="{{.MessageX}}" y="14">{{.Message}}</text>
+</g>
+</svg>
+`))
+
+type svgData struct {
+	Label, Message, Color           string
+	Width, LabelWidth, MessageWidth int
+	LabelX, MessageX                float64
+}
+
+// Render returns an SVG badge with the label on grey and the message on color. Both texts are
+// escaped, so they may come from users.
+func Render(label, message, color string) []byte {
+	labelWidth := utf8.RuneCountInString(label)*charWidth + padding
+	messageWidth := utf8.RuneCountInString(message)*charWidth + padding
+	data := svgData{
+		Label:        label,
+		Message:      message,
+		Color:        color,
+		Width:        labelWidth + messageWidth,
+		LabelWidth:   labelWidth,
+		MessageWidth: messageWidth,
+		LabelX:       float64(labelWidth) / 2,
+		MessageX:     float64(labelWidth) + float64(messageWidth)/2,
+	}
+
+	var svg bytes.Buffer
+	if err := svgTemplate.Execute(&svg, data); err != nil {
+		panic("badge: " + err.Error())
+	}
+	return svg.Bytes()
+}
+
+// Count formats a count the way badges show it, e.g. 950, 1.2k or 3.4M
+func Count(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprintf("%d", n)
+	case n < 999_950: // larger counts would round up to 1000.0k
+		return trimZero(fmt.Sprintf("%.1f", float64(n)/1000)) + "k"
+	default:
+		return trimZero(fmt.Sprintf("%.1f", float64(n)/1_000_000)) + "M"
+	}
+}
+
+// trimZero drops a ".0" decimal, so 2000 shows as 2k rather than 2.0k
+func trimZero(s string) string {
+	if len(s) > 2 && s[len(s)-2:] == ".0" {
+		return s[:len(s)-2]
+	}
+	return s
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,20 +11,57 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Logging  LoggingConfig
-	Supabase SupabaseConfig
-	Resend   ResendConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Logging     LoggingConfig
+	Tracing     TracingConfig
+	Supabase    SupabaseConfig
+	Resend      ResendConfig
+	Mailing     MailingConfig
+	Scheduler   SchedulerConfig
+	HTTPClient  HTTPClientConfig
+	Security    SecurityConfig
+	Alerting    AlertingConfig
+	Usage       UsageConfig
+	Costs       CostsConfig
+	Retention   RetentionConfig
+	Backup      BackupConfig
+	Suggestions SuggestionsConfig
+	Summary     SummaryConfig
+	Lint        LintConfig
+	HostedPages HostedPagesConfig
+	Assets      AssetsConfig
+	Webhooks    WebhooksConfig
+	OAuth       OAuthConfig
+	Invitations InvitationsConfig
+	// File is the config file the settings were layered on, empty when env-only
+	File string
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	ApiBaseURL   string
-	Port         string
-	ApiVersion   string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	ApiBaseURL string
+	Port       string
+	ApiVersion string
+	// ReadHeaderTimeout, ReadTimeout and WriteTimeout bound how long a client may take to send
+	// its request headers, its whole request and to read the response; streaming exports and
+	// archive uploads extend their own deadlines
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	// TrustedProxies are the CIDR ranges (or single IPs) of reverse proxies whose
+	// X-Forwarded-For / X-Real-IP headers are honored; empty means never trust them
+	TrustedProxies []string
+	// ReadOnlyRefreshInterval is how long each replica caches the admin read-only flag
+	ReadOnlyRefreshInterval time.Duration
+	// PublishConcurrency and BulkConcurrency cap the in-flight requests per process of the
+	// publishing routes and of the bulk export/import routes; zero disables the cap
+	PublishConcurrency int
+	BulkConcurrency    int
+	// PublicPostURL is the page of a published post listed in sitemaps, with {newsletterId} and
+	// {postId} placeholders, for archives hosted on their own site; empty lists the post's
+	// public API URL
+	PublicPostURL string
 }
 
 // DatabaseConfig holds database-related configuration
@@ -31,28 +69,340 @@ type DatabaseConfig struct {
 	Host     string
 	Port     string
 	User     string
-	Password string
+	Password string `config:"secret"`
 	Database string
 	SSLMode  string
 	MaxConns int32
 	MinConns int32
+	// AcquireTimeout bounds how long a query waits for a free pool connection before the
+	// request is answered with 503; zero waits as long as the request does
+	AcquireTimeout time.Duration
+	// WarmupConns is the number of connections opened at startup before serving traffic
+	WarmupConns int32
+	// SlowQueryThreshold is the duration above which statements are logged; zero disables it
+	SlowQueryThreshold time.Duration
+	// SchemaMismatch is what startup does when the schema is incompatible with the build:
+	// "refuse" to start or run "read_only"
+	SchemaMismatch string
 }
 
 type ResendConfig struct {
 	Sender string
-	ApiKey string
+	ApiKey string `config:"secret"`
+	// BaseURL overrides the Resend API endpoint, e.g. to point load tests at a stub
+	BaseURL string
+	// WebhookSecret verifies the signature of Resend webhooks (whsec_...); empty disables
+	// POST /webhooks/resend
+	WebhookSecret string `config:"secret"`
+}
+
+// MailingConfig holds settings for bulk email dispatch
+type MailingConfig struct {
+	// DispatchWorkers bounds how many personalised emails are sent concurrently
+	DispatchWorkers int
+	// MaxSendAttempts is how often a bulk dispatch tries each recipient before giving up
+	MaxSendAttempts int
+	// RetryBackoff is the wait before the first retry; it doubles on every further attempt
+	RetryBackoff time.Duration
+	// RatePerSecond caps the requests per second sent to the email provider across all
+	// dispatch workers; zero disables the cap. A 429 from the provider pauses sends regardless.
+	RatePerSecond float64
+	// SystemicFailurePercent is the share of failed recipients at which a post's delivery
+	// failure is treated as systemic and admins are alerted
+	SystemicFailurePercent int
+	// UnsubscribeAllLink adds an "unsubscribe from all newsletters" link to post footers
+	UnsubscribeAllLink bool
+	// Tracking adds an open pixel to post emails and routes their links through click
+	// tracking, for the open and click rates of posts
+	Tracking bool
+	// ConfirmationMaxAttempts is how often a subscription confirmation email is sent before giving up
+	ConfirmationMaxAttempts int
+	// ConfirmationRetryBackoff is the wait before resending a failed confirmation email; it doubles on every further attempt
+	ConfirmationRetryBackoff time.Duration
+	// FooterHTMLFile is an HTML file added to the end of every subscriber email, e.g. a legal
+	// disclaimer; empty adds nothing
+	FooterHTMLFile string
+	// ConfirmationTokenTTL is how long a confirmation link works; zero keeps links working
+	ConfirmationTokenTTL time.Duration
+	// ConfirmationExpiredRetention is how long an unconfirmed subscription is kept after its
+	// confirmation link expired, for the subscriber to request a new one. The retention job then
+	// deletes it, even when the retention of unconfirmed subscribers is longer.
+	ConfirmationExpiredRetention time.Duration
+	// OutboxPollInterval is how often the outbox dispatcher looks for queued post emails
+	OutboxPollInterval time.Duration
+	// OutboxBatchSize is the number of queued emails a dispatcher claims at a time
+	OutboxBatchSize int
+	// OutboxMaxAttempts is how many dispatches a queued email gets before it is marked failed
+	OutboxMaxAttempts int
+	// OutboxRetryBackoff is the wait before a failed queued email is dispatched again; it
+	// doubles on every further attempt
+	OutboxRetryBackoff time.Duration
+	// OutboxLease is how long a claimed email is hidden from other dispatchers; emails of a
+	// dispatcher that died are sent again after it
+	OutboxLease time.Duration
+	// OutboxRetention is how long sent and failed emails stay in the outbox
+	OutboxRetention time.Duration
+}
+
+// SchedulerConfig holds settings for the scheduled post publisher
+type SchedulerConfig struct {
+	// Enabled starts the scheduled post publisher in this process
+	Enabled bool
+	// CatchUpThreshold is how far in the past scheduled_at must be before a post is
+	// considered overdue and the newsletter's catch-up policy applies
+	CatchUpThreshold time.Duration
+	// ShutdownGracePeriod is how long shutdown waits for an in-flight publish before cancelling it
+	ShutdownGracePeriod time.Duration
+	// WorkerMetricsPort is the port cmd/worker serves /metrics on, so its publish counters can
+	// be scraped; empty serves nothing. The API server serves /metrics on its own port.
+	WorkerMetricsPort string
+}
+
+// HTTPClientConfig holds settings for the shared outbound HTTP client
+// used for calls to Supabase, Resend and other third-party APIs
+type HTTPClientConfig struct {
+	Timeout             time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	// ProxyURL may carry credentials
+	ProxyURL string `config:"secret"`
+}
+
+// DefaultAPIContentSecurityPolicy is used for JSON responses, which never load sub-resources
+const DefaultAPIContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// DefaultHTMLContentSecurityPolicy is used for hosted pages (confirmation, archive, subscribe form):
+// same-origin scripts and forms, inline styles for email-like markup and remote images
+const DefaultHTMLContentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' https: data:; form-action 'self'; frame-ancestors 'none'; base-uri 'none'; object-src 'none'"
+
+// SecurityConfig holds settings for the security response headers
+type SecurityConfig struct {
+	// HSTSMaxAge is the Strict-Transport-Security max-age; zero disables HSTS
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// ReferrerPolicy applies to every response
+	ReferrerPolicy string
+	// APIContentSecurityPolicy applies to JSON API responses
+	APIContentSecurityPolicy string
+	// HTMLContentSecurityPolicy applies to hosted pages opened in a browser
+	HTMLContentSecurityPolicy string
+	// CSRFSecret signs the CSRF tokens of hosted forms; it must be shared by all instances
+	CSRFSecret string `config:"secret"`
+	// CSRFSecureCookie marks the CSRF cookie and the sign-in cookie of identity providers Secure;
+	// disable only for local HTTP development
+	CSRFSecureCookie bool
+	// UnsubscribeSecret signs unsubscribe-all links, tracked links and data request tokens, and
+	// is required; links in sent emails stop working when it changes
+	UnsubscribeSecret string `config:"secret"`
+	// SubscriberEmailKey is the base64 encoded 32-byte key subscriber addresses are encrypted
+	// with; encryption is disabled when neither it nor SubscriberEmailKeyFile is set
+	SubscriberEmailKey string `config:"secret"`
+	// SubscriberEmailKeyFile reads the key from a file instead, e.g. a KMS or secret manager mount
+	SubscriberEmailKeyFile string
+	// LocationHeader is the request header a CDN or proxy in front of the API reports the
+	// client's approximate location in (e.g. CF-IPCountry), recorded with sign-ins; empty
+	// leaves the location unknown
+	LocationHeader string
+}
+
+// AlertingConfig holds settings for operational alerts
+type AlertingConfig struct {
+	// WebhookURL receives alerts as JSON (e.g. a Slack or PagerDuty webhook); alerts are only logged when empty
+	WebhookURL string `config:"secret"`
+	// Throttle is the minimum interval between identical alerts
+	Throttle time.Duration
+}
+
+// UsageConfig holds settings for API usage tracking
+type UsageConfig struct {
+	// FlushInterval is how often per-editor call counts aggregated in memory are written to the database
+	FlushInterval time.Duration
+}
+
+// CostsConfig holds the email provider pricing used to estimate sending costs
+type CostsConfig struct {
+	// Provider labels the recorded costs, e.g. "resend"
+	Provider string
+	// PricePerThousand is the provider's price for 1000 emails, in Currency
+	PricePerThousand float64
+	// Currency of PricePerThousand; change it only together with the price
+	Currency string
+}
+
+// RetentionConfig holds the data retention policy for subscribers who never confirmed and for
+// the in-app notifications of editors
+type RetentionConfig struct {
+	// UnconfirmedDays is how long unconfirmed subscribers are kept unless their newsletter overrides
+	// it; zero keeps them unless the newsletter sets a retention
+	UnconfirmedDays int
+	// UnconfirmedAction is what happens to unconfirmed subscribers past their retention or whose
+	// confirmation link expired: delete, or anonymize to keep them in statistics
+	UnconfirmedAction string
+	// NotificationDays is how long inbox notifications are kept, read or not; zero keeps them
+	NotificationDays int
+	// DeletedDays is how long deleted newsletters, posts and subscribers can be restored before
+	// they are deleted for good; zero keeps them
+	DeletedDays int
+	// Interval is the time between retention runs
+	Interval time.Duration
+	// DryRun only reports what a run would delete
+	DryRun bool
+	// BatchSize caps the subscribers deleted per statement
+	BatchSize int
+}
+
+// BackupConfig holds settings for the periodic snapshot of critical tables to object storage
+type BackupConfig struct {
+	// URL is where snapshots are written: s3://bucket/prefix or file:///path; empty disables backups
+	URL string
+	// Interval is the time between snapshots; zero only allows on-demand snapshots
+	Interval time.Duration
+	// S3Endpoint overrides the AWS endpoint for S3 compatible stores, e.g. https://minio.internal:9000
+	S3Endpoint        string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string `config:"secret"`
+}
+
+// SuggestionsConfig holds settings for subject line and preheader suggestions by a language model
+type SuggestionsConfig struct {
+	// Enabled is the feature flag; suggestions answer 404 while it is off
+	Enabled bool
+	// Provider is the model API: "openai" (or any OpenAI compatible server) or "anthropic"
+	Provider string
+	// BaseURL overrides the provider's API endpoint, e.g. for a self-hosted model server
+	BaseURL string
+	APIKey  string `config:"secret"`
+	Model   string
+	// Count is the number of subject lines and of preheaders requested
+	Count int
+	// Timeout bounds a provider call; models often answer slower than HTTP_CLIENT_TIMEOUT
+	Timeout time.Duration
+}
+
+// SummaryConfig holds settings for the summaries stored on posts for previews
+type SummaryConfig struct {
+	// Mode is "extractive" (sentences picked from the post) or "llm" (written by the
+	// SUGGESTIONS_PROVIDER model, extractive when it fails)
+	Mode string
+	// MaxChars is the longest summary stored
+	MaxChars int
+}
+
+// LintConfig holds settings for the deliverability checks of post emails
+type LintConfig struct {
+	// RulesFile is a YAML file of rules; the built-in rules apply when it is empty
+	RulesFile string
+	// BlockSeverity (info, warning or error) refuses to send posts with an issue at or above
+	// it; when empty issues are only reported by the preflight endpoint
+	BlockSeverity string
+}
+
+// HostedPagesConfig holds settings for the pages browsers get when opening links from emails
+type HostedPagesConfig struct {
+	// BrandingFile is a YAML file with the default branding of the pages and the branding of
+	// custom domains; a neutral branding applies when it is empty
+	BrandingFile string
+}
+
+// AssetsConfig holds settings for serving the static files of hosted pages under /assets
+type AssetsConfig struct {
+	// UploadsURL is the object store uploads are served from under /assets/uploads:
+	// s3://bucket/prefix or file:///path; empty serves no uploads
+	UploadsURL string
+	// UploadsMaxAge is how long browsers cache uploads without a content hash in their name
+	UploadsMaxAge     time.Duration
+	S3Endpoint        string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string `config:"secret"`
+}
+
+// WebhooksConfig holds settings for the delivery of newsletter webhooks
+type WebhooksConfig struct {
+	// PollInterval is how often the webhook dispatcher looks for pending deliveries
+	PollInterval time.Duration
+	// BatchSize is the number of deliveries a dispatcher claims at a time
+	BatchSize int
+	// MaxAttempts is how many calls a delivery gets before it is marked failed
+	MaxAttempts int
+	// RetryBackoff is the wait before a failed delivery is retried; it doubles with every
+	// further attempt
+	RetryBackoff time.Duration
+	// Lease is how long a claimed delivery is hidden from other dispatchers
+	Lease time.Duration
+	// Timeout bounds a single call of a webhook URL
+	Timeout time.Duration
+	// Retention is how long delivered and failed deliveries stay in the delivery log
+	Retention time.Duration
+	// AllowPrivateTargets permits webhook URLs on loopback and private networks, for development
+	AllowPrivateTargets bool
+}
+
+// OAuthConfig holds settings for the OAuth2 client credentials flow of integration clients
+type OAuthConfig struct {
+	// TokenTTL is how long an issued access token is valid
+	TokenTTL time.Duration
+}
+
+// InvitationsConfig holds settings for invitations to join a newsletter as a member
+type InvitationsConfig struct {
+	// URL is the page of the editor app that accepts invitations; the token is added as ?token=.
+	// Empty sends only the token, to paste into the app.
+	URL string
+	// TTL is how long an invitation can be accepted
+	TTL time.Duration
 }
 
 // LoggingConfig holds logging-related configuration
 type LoggingConfig struct {
 	Level string
+	// RedactPII masks email addresses and redacts tokens in every log line; keep it on in production
+	RedactPII bool
+}
+
+// TracingConfig holds settings for the OpenTelemetry traces of requests, SQL statements, email
+// sends and scheduler runs
+type TracingConfig struct {
+	// OTLPEndpoint is the URL of the OTLP/HTTP collector, e.g. http://localhost:4318; empty
+	// disables tracing. OTEL_EXPORTER_OTLP_HEADERS adds headers, e.g. for authentication.
+	OTLPEndpoint string
+	ServiceName  string
+	// SampleRatio is the share of traces recorded, from 0 to 1; traces started by a caller that
+	// sampled them are always recorded
+	SampleRatio float64
 }
 
 // SupabaseConfig holds Supabase-related configuration
 type SupabaseConfig struct {
-	URL       string
-	AnonKey   string
-	JWTSecret string
+	URL     string
+	AnonKey string `config:"secret"`
+	// ServiceRoleKey authorizes the admin API of Supabase Auth, which signs users out when an
+	// admin revokes their sessions
+	ServiceRoleKey string `config:"secret"`
+	// JWTSecret is the current secret Supabase signs access tokens with
+	JWTSecret string `config:"secret"`
+	// PreviousJWTSecrets are still accepted after Supabase rotated its secret, so sessions
+	// signed with an old one stay valid until they expire
+	PreviousJWTSecrets []string `config:"secret"`
+	// OAuthProviders are the identity providers editors may sign in with through Supabase,
+	// each enabled in the Supabase project
+	OAuthProviders []string
+	// MagicLinkRedirectURL is where magic links send the browser; empty for the Site URL of
+	// the Supabase project
+	MagicLinkRedirectURL string
+}
+
+// JWTSecrets returns the secrets access tokens are checked with, the current one first
+func (c SupabaseConfig) JWTSecrets() []string {
+	if c.JWTSecret == "" {
+		return c.PreviousJWTSecrets
+	}
+	return append([]string{c.JWTSecret}, c.PreviousJWTSecrets...)
 }
 
 func (c Config) BuildApiBaseUrl() string {
@@ -60,37 +410,187 @@ func (c Config) BuildApiBaseUrl() string {
 }
 
 // Load loads configuration from environment variables
-func Load() *Config {
+func Load() (*Config, error) {
+	// SUPABASE_JWT_SECRET lists the current secret first, then previous ones during a rotation
+	jwtSecrets := utils.GetListWithDefault("SUPABASE_JWT_SECRET", []string{""})
+	if len(jwtSecrets) == 0 {
+		return nil, errors.New("invalid SUPABASE_JWT_SECRET: set but lists no secret")
+	}
 
 	return &Config{
 		Server: ServerConfig{
-			ApiBaseURL:   utils.GetEnvWithDefault("API_BASE_URL", "http://localhost"),
-			Port:         utils.GetEnvWithDefault("PORT", "8080"),
-			ApiVersion:   utils.GetEnvWithDefault("API_VERSION", "1"),
-			ReadTimeout:  utils.GetDurationWithDefault("READ_TIMEOUT", 15*time.Second),
-			WriteTimeout: utils.GetDurationWithDefault("WRITE_TIMEOUT", 15*time.Second),
+			ApiBaseURL:              utils.GetEnvWithDefault("API_BASE_URL", "http://localhost"),
+			Port:                    utils.GetEnvWithDefault("PORT", "8080"),
+			ApiVersion:              utils.GetEnvWithDefault("API_VERSION", "1"),
+			ReadHeaderTimeout:       utils.GetDurationWithDefault("READ_HEADER_TIMEOUT", 5*time.Second),
+			ReadTimeout:             utils.GetDurationWithDefault("READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:            utils.GetDurationWithDefault("WRITE_TIMEOUT", 15*time.Second),
+			TrustedProxies:          utils.GetListWithDefault("TRUSTED_PROXIES", nil),
+			ReadOnlyRefreshInterval: utils.GetDurationWithDefault("READ_ONLY_REFRESH_INTERVAL", 5*time.Second),
+			PublishConcurrency:      utils.GetIntWithDefault("CONCURRENCY_PUBLISH", 4),
+			BulkConcurrency:         utils.GetIntWithDefault("CONCURRENCY_BULK", 4),
+			PublicPostURL:           utils.GetEnvWithDefault("PUBLIC_POST_URL", ""),
 		},
 		Database: DatabaseConfig{
-			Host:     utils.GetEnvWithDefault("PGHOST", "localhost"),
-			Port:     utils.GetEnvWithDefault("PGPORT", "5432"),
-			User:     utils.GetEnvWithDefault("PGUSER", "postgres.iiivolgfmqsxvlrggwsh"),
-			Password: os.Getenv("PGPASSWORD"),
-			Database: utils.GetEnvWithDefault("PGDATABASE", "postgres"),
-			SSLMode:  utils.GetEnvWithDefault("PGSSLMODE", "require"),
-			MaxConns: utils.GetInt32WithDefault("DB_MAX_CONNS", 10),
-			MinConns: utils.GetInt32WithDefault("DB_MIN_CONNS", 2),
+			Host:               utils.GetEnvWithDefault("PGHOST", "localhost"),
+			Port:               utils.GetEnvWithDefault("PGPORT", "5432"),
+			User:               utils.GetEnvWithDefault("PGUSER", "postgres.iiivolgfmqsxvlrggwsh"),
+			Password:           os.Getenv("PGPASSWORD"),
+			Database:           utils.GetEnvWithDefault("PGDATABASE", "postgres"),
+			SSLMode:            utils.GetEnvWithDefault("PGSSLMODE", "require"),
+			MaxConns:           utils.GetInt32WithDefault("DB_MAX_CONNS", 10),
+			MinConns:           utils.GetInt32WithDefault("DB_MIN_CONNS", 2),
+			AcquireTimeout:     utils.GetDurationWithDefault("DB_ACQUIRE_TIMEOUT", 3*time.Second),
+			WarmupConns:        utils.GetInt32WithDefault("DB_WARMUP_CONNS", utils.GetInt32WithDefault("DB_MIN_CONNS", 2)),
+			SlowQueryThreshold: utils.GetDurationWithDefault("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+			SchemaMismatch:     utils.GetEnvWithDefault("DB_SCHEMA_MISMATCH", "refuse"),
 		},
 		Logging: LoggingConfig{
-			Level: utils.GetEnvWithDefault("LOG_LEVEL", "info"),
+			Level:     utils.GetEnvWithDefault("LOG_LEVEL", "info"),
+			RedactPII: utils.GetBoolWithDefault("LOG_REDACT_PII", true),
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+			ServiceName:  utils.GetEnvWithDefault("OTEL_SERVICE_NAME", "go-newsletter"),
+			SampleRatio:  utils.GetFloatWithDefault("OTEL_TRACES_SAMPLE_RATIO", 1),
 		},
 		Supabase: SupabaseConfig{
-			URL:       os.Getenv("SUPABASE_URL"),
-			AnonKey:   os.Getenv("SUPABASE_ANON_KEY"),
-			JWTSecret: os.Getenv("SUPABASE_JWT_SECRET"),
+			URL:                  os.Getenv("SUPABASE_URL"),
+			AnonKey:              os.Getenv("SUPABASE_ANON_KEY"),
+			ServiceRoleKey:       os.Getenv("SUPABASE_SERVICE_ROLE_KEY"),
+			JWTSecret:            jwtSecrets[0],
+			PreviousJWTSecrets:   jwtSecrets[1:],
+			OAuthProviders:       utils.GetListWithDefault("SUPABASE_OAUTH_PROVIDERS", []string{"google", "github"}),
+			MagicLinkRedirectURL: os.Getenv("SUPABASE_MAGIC_LINK_REDIRECT_URL"),
 		},
 		Resend: ResendConfig{
-			Sender: os.Getenv("RESEND_SENDER"),
-			ApiKey: os.Getenv("RESEND_API_KEY"),
+			Sender:        os.Getenv("RESEND_SENDER"),
+			ApiKey:        os.Getenv("RESEND_API_KEY"),
+			BaseURL:       os.Getenv("RESEND_BASE_URL"),
+			WebhookSecret: os.Getenv("RESEND_WEBHOOK_SECRET"),
 		},
-	}
+		Mailing: MailingConfig{
+			DispatchWorkers:              utils.GetIntWithDefault("MAIL_DISPATCH_WORKERS", 8),
+			MaxSendAttempts:              utils.GetIntWithDefault("MAIL_MAX_SEND_ATTEMPTS", 3),
+			RetryBackoff:                 utils.GetDurationWithDefault("MAIL_RETRY_BACKOFF", 2*time.Second),
+			RatePerSecond:                utils.GetFloatWithDefault("MAIL_RATE_PER_SECOND", 10),
+			SystemicFailurePercent:       utils.GetIntWithDefault("MAIL_SYSTEMIC_FAILURE_PERCENT", 50),
+			UnsubscribeAllLink:           utils.GetBoolWithDefault("MAIL_UNSUBSCRIBE_ALL_LINK", false),
+			Tracking:                     utils.GetBoolWithDefault("MAIL_TRACKING", false),
+			ConfirmationMaxAttempts:      utils.GetIntWithDefault("MAIL_CONFIRMATION_MAX_ATTEMPTS", 6),
+			ConfirmationRetryBackoff:     utils.GetDurationWithDefault("MAIL_CONFIRMATION_RETRY_BACKOFF", 5*time.Minute),
+			FooterHTMLFile:               os.Getenv("MAIL_FOOTER_HTML_FILE"),
+			ConfirmationTokenTTL:         utils.GetDurationWithDefault("MAIL_CONFIRMATION_TOKEN_TTL", 48*time.Hour),
+			ConfirmationExpiredRetention: utils.GetDurationWithDefault("MAIL_CONFIRMATION_EXPIRED_RETENTION", 7*24*time.Hour),
+			OutboxPollInterval:           utils.GetDurationWithDefault("MAIL_OUTBOX_POLL_INTERVAL", 2*time.Second),
+			OutboxBatchSize:              utils.GetIntWithDefault("MAIL_OUTBOX_BATCH_SIZE", 500),
+			OutboxMaxAttempts:            utils.GetIntWithDefault("MAIL_OUTBOX_MAX_ATTEMPTS", 5),
+			OutboxRetryBackoff:           utils.GetDurationWithDefault("MAIL_OUTBOX_RETRY_BACKOFF", time.Minute),
+			OutboxLease:                  utils.GetDurationWithDefault("MAIL_OUTBOX_LEASE", 5*time.Minute),
+			OutboxRetention:              utils.GetDurationWithDefault("MAIL_OUTBOX_RETENTION", 72*time.Hour),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:             utils.GetBoolWithDefault("SCHEDULER_ENABLED", false),
+			CatchUpThreshold:    utils.GetDurationWithDefault("SCHEDULER_CATCH_UP_THRESHOLD", time.Hour),
+			ShutdownGracePeriod: utils.GetDurationWithDefault("SCHEDULER_SHUTDOWN_GRACE_PERIOD", 30*time.Second),
+			WorkerMetricsPort:   os.Getenv("WORKER_METRICS_PORT"),
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:                utils.GetDurationWithDefault("SECURITY_HSTS_MAX_AGE", 180*24*time.Hour),
+			HSTSIncludeSubdomains:     utils.GetBoolWithDefault("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false),
+			ReferrerPolicy:            utils.GetEnvWithDefault("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+			APIContentSecurityPolicy:  utils.GetEnvWithDefault("SECURITY_API_CSP", DefaultAPIContentSecurityPolicy),
+			HTMLContentSecurityPolicy: utils.GetEnvWithDefault("SECURITY_HTML_CSP", DefaultHTMLContentSecurityPolicy),
+			CSRFSecret:                os.Getenv("CSRF_SECRET"),
+			CSRFSecureCookie:          utils.GetBoolWithDefault("CSRF_SECURE_COOKIE", true),
+			UnsubscribeSecret:         os.Getenv("UNSUBSCRIBE_SECRET"),
+			SubscriberEmailKey:        os.Getenv("SUBSCRIBER_EMAIL_KEY"),
+			SubscriberEmailKeyFile:    os.Getenv("SUBSCRIBER_EMAIL_KEY_FILE"),
+			LocationHeader:            os.Getenv("SECURITY_LOCATION_HEADER"),
+		},
+		Alerting: AlertingConfig{
+			WebhookURL: os.Getenv("ALERT_WEBHOOK_URL"),
+			Throttle:   utils.GetDurationWithDefault("ALERT_THROTTLE", time.Minute),
+		},
+		Usage: UsageConfig{
+			FlushInterval: utils.GetDurationWithDefault("USAGE_FLUSH_INTERVAL", 30*time.Second),
+		},
+		Costs: CostsConfig{
+			Provider:         utils.GetEnvWithDefault("EMAIL_COST_PROVIDER", "resend"),
+			PricePerThousand: utils.GetFloatWithDefault("EMAIL_COST_PER_THOUSAND", 0.40),
+			Currency:         utils.GetEnvWithDefault("EMAIL_COST_CURRENCY", "USD"),
+		},
+		Retention: RetentionConfig{
+			UnconfirmedDays:   utils.GetIntWithDefault("RETENTION_UNCONFIRMED_DAYS", 30),
+			UnconfirmedAction: utils.GetEnvWithDefault("RETENTION_UNCONFIRMED_ACTION", "delete"),
+			NotificationDays:  utils.GetIntWithDefault("RETENTION_NOTIFICATION_DAYS", 90),
+			DeletedDays:       utils.GetIntWithDefault("RETENTION_DELETED_DAYS", 30),
+			Interval:          utils.GetDurationWithDefault("RETENTION_INTERVAL", time.Hour),
+			DryRun:            utils.GetBoolWithDefault("RETENTION_DRY_RUN", false),
+			BatchSize:         utils.GetIntWithDefault("RETENTION_BATCH_SIZE", 500),
+		},
+		Backup: BackupConfig{
+			URL:               os.Getenv("BACKUP_URL"),
+			Interval:          utils.GetDurationWithDefault("BACKUP_INTERVAL", 24*time.Hour),
+			S3Endpoint:        os.Getenv("BACKUP_S3_ENDPOINT"),
+			S3Region:          utils.GetEnvWithDefault("BACKUP_S3_REGION", "us-east-1"),
+			S3AccessKeyID:     os.Getenv("BACKUP_S3_ACCESS_KEY_ID"),
+			S3SecretAccessKey: os.Getenv("BACKUP_S3_SECRET_ACCESS_KEY"),
+		},
+		Suggestions: SuggestionsConfig{
+			Enabled:  utils.GetBoolWithDefault("SUGGESTIONS_ENABLED", false),
+			Provider: utils.GetEnvWithDefault("SUGGESTIONS_PROVIDER", "openai"),
+			BaseURL:  os.Getenv("SUGGESTIONS_BASE_URL"),
+			APIKey:   os.Getenv("SUGGESTIONS_API_KEY"),
+			Model:    os.Getenv("SUGGESTIONS_MODEL"),
+			Count:    utils.GetIntWithDefault("SUGGESTIONS_COUNT", 3),
+			Timeout:  utils.GetDurationWithDefault("SUGGESTIONS_TIMEOUT", 30*time.Second),
+		},
+		Summary: SummaryConfig{
+			Mode:     utils.GetEnvWithDefault("SUMMARY_MODE", "extractive"),
+			MaxChars: utils.GetIntWithDefault("SUMMARY_MAX_CHARS", 280),
+		},
+		Lint: LintConfig{
+			RulesFile:     os.Getenv("LINT_RULES_FILE"),
+			BlockSeverity: os.Getenv("LINT_BLOCK_SEVERITY"),
+		},
+		HostedPages: HostedPagesConfig{
+			BrandingFile: os.Getenv("HOSTED_PAGES_BRANDING_FILE"),
+		},
+		Assets: AssetsConfig{
+			UploadsURL:        os.Getenv("ASSETS_UPLOADS_URL"),
+			UploadsMaxAge:     utils.GetDurationWithDefault("ASSETS_UPLOADS_MAX_AGE", time.Hour),
+			S3Endpoint:        os.Getenv("ASSETS_UPLOADS_S3_ENDPOINT"),
+			S3Region:          utils.GetEnvWithDefault("ASSETS_UPLOADS_S3_REGION", "us-east-1"),
+			S3AccessKeyID:     os.Getenv("ASSETS_UPLOADS_S3_ACCESS_KEY_ID"),
+			S3SecretAccessKey: os.Getenv("ASSETS_UPLOADS_S3_SECRET_ACCESS_KEY"),
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:        utils.GetDurationWithDefault("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+			BatchSize:           utils.GetIntWithDefault("WEBHOOK_BATCH_SIZE", 100),
+			MaxAttempts:         utils.GetIntWithDefault("WEBHOOK_MAX_ATTEMPTS", 8),
+			RetryBackoff:        utils.GetDurationWithDefault("WEBHOOK_RETRY_BACKOFF", 30*time.Second),
+			Lease:               utils.GetDurationWithDefault("WEBHOOK_LEASE", 2*time.Minute),
+			Timeout:             utils.GetDurationWithDefault("WEBHOOK_TIMEOUT", 10*time.Second),
+			Retention:           utils.GetDurationWithDefault("WEBHOOK_RETENTION", 7*24*time.Hour),
+			AllowPrivateTargets: utils.GetBoolWithDefault("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+		},
+		OAuth: OAuthConfig{
+			TokenTTL: utils.GetDurationWithDefault("OAUTH_TOKEN_TTL", 15*time.Minute),
+		},
+		Invitations: InvitationsConfig{
+			URL: os.Getenv("INVITATION_URL"),
+			TTL: utils.GetDurationWithDefault("INVITATION_TTL", 7*24*time.Hour),
+		},
+		HTTPClient: HTTPClientConfig{
+			Timeout:             utils.GetDurationWithDefault("HTTP_CLIENT_TIMEOUT", 15*time.Second),
+			DialTimeout:         utils.GetDurationWithDefault("HTTP_CLIENT_DIAL_TIMEOUT", 5*time.Second),
+			TLSHandshakeTimeout: utils.GetDurationWithDefault("HTTP_CLIENT_TLS_HANDSHAKE_TIMEOUT", 5*time.Second),
+			IdleConnTimeout:     utils.GetDurationWithDefault("HTTP_CLIENT_IDLE_CONN_TIMEOUT", 90*time.Second),
+			MaxIdleConns:        utils.GetIntWithDefault("HTTP_CLIENT_MAX_IDLE_CONNS", 100),
+			MaxIdleConnsPerHost: utils.GetIntWithDefault("HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST", 20),
+			MaxConnsPerHost:     utils.GetIntWithDefault("HTTP_CLIENT_MAX_CONNS_PER_HOST", 0),
+			ProxyURL:            os.Getenv("HTTP_CLIENT_PROXY_URL"),
+		},
+	}, nil
 }
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadRejectsJWTSecretListWithoutSecret(t *testing.T) {
+	for _, value := range []string{",", " , "} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("SUPABASE_JWT_SECRET", value)
+			cfg, err := Load()
+			if err == nil {
+				t.Fatalf("Load() with SUPABASE_JWT_SECRET=%q = %+v, want error", value, cfg.Supabase)
+			}
+			if !strings.Contains(err.Error(), "SUPABASE_JWT_SECRET") {
+				t.Errorf("Load() error = %q, want it to name SUPABASE_JWT_SECRET", err)
+			}
+		})
+	}
+}
+
+func TestLoadJWTSecretRotation(t *testing.T) {
+	t.Setenv("SUPABASE_JWT_SECRET", " current , previous ,")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Supabase.JWTSecret != "current" {
+		t.Errorf("JWTSecret = %q, want %q", cfg.Supabase.JWTSecret, "current")
+	}
+	if got := cfg.Supabase.PreviousJWTSecrets; len(got) != 1 || got[0] != "previous" {
+		t.Errorf("PreviousJWTSecrets = %q, want [previous]", got)
+	}
+}
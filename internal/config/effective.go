@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// RedactedValue replaces secret settings in the effective configuration
+const RedactedValue = "[REDACTED]"
+
+// Effective returns the configuration as section -> setting -> value for debugging.
+// Fields tagged `config:"secret"` are redacted when set.
+func (c Config) Effective() map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+
+	root := reflect.ValueOf(c)
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+
+		settings := make(map[string]string)
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			value := section.Field(j)
+
+			switch {
+			case field.Tag.Get("config") == "secret":
+				if !value.IsZero() {
+					settings[field.Name] = RedactedValue
+				} else {
+					settings[field.Name] = ""
+				}
+			case value.Type() == reflect.TypeOf(time.Duration(0)):
+				settings[field.Name] = time.Duration(value.Int()).String()
+			default:
+				settings[field.Name] = fmt.Sprint(value.Interface())
+			}
+		}
+		sections[root.Type().Field(i).Name] = settings
+	}
+	return sections
+}
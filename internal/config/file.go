@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnvVar names the environment variable pointing at an optional config file
+const FileEnvVar = "CONFIG_FILE"
+
+// LoadWithFile loads the config file named by CONFIG_FILE (if any) and then the configuration.
+// File values only fill in variables that are not already set, so the environment always
+// overrides the file, the same way godotenv treats .env.
+func LoadWithFile() (*Config, error) {
+	path := os.Getenv(FileEnvVar)
+	if path != "" {
+		if err := applyFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	cfg.File = path
+	return cfg, nil
+}
+
+// applyFile reads a YAML or JSON config file and exports its settings as environment variables.
+// Keys are environment variable names; nested maps are joined with "_", so
+// `scheduler: {enabled: true}` is the same as `SCHEDULER_ENABLED: true`.
+func applyFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("unsupported config file format %q, use .yaml, .yml or .json", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	settings := make(map[string]string)
+	if err := flatten("", raw, settings); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for key, value := range settings {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to apply config setting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func flatten(prefix string, values map[string]any, out map[string]string) error {
+	for key, value := range values {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case map[string]any:
+			if err := flatten(name, v, out); err != nil {
+				return err
+			}
+		case []any:
+			return fmt.Errorf("setting %s: lists are not supported", name)
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
@@ -15,6 +15,14 @@ type NewsletterConfig struct {
 	MaxDescriptionLength int
 	TooLongDescMessage   string
 
+	// Catch-up policy constraints
+	InvalidCatchUpMessage    string
+	MissingCatchUpAgeMessage string
+	InvalidCatchUpAgeMessage string
+
+	// Retention constraints
+	InvalidRetentionMessage string
+
 	// ID constraints
 	InvalidIDMessage string
 }
@@ -35,6 +43,14 @@ func DefaultNewsletterConfig() *NewsletterConfig {
 		MaxDescriptionLength: 500,
 		TooLongDescMessage:   "Description must be less than 500 characters",
 
+		// Catch-up policy constraints
+		InvalidCatchUpMessage:    "Catch-up policy must be one of send_all, latest_only, skip_older_than",
+		MissingCatchUpAgeMessage: "catch_up_max_age_minutes is required with the skip_older_than policy",
+		InvalidCatchUpAgeMessage: "catch_up_max_age_minutes must be at least 1",
+
+		// Retention constraints
+		InvalidRetentionMessage: "unconfirmed_retention_days must be at least 1",
+
 		// ID constraints
 		InvalidIDMessage: "Invalid newsletter ID format",
 	}
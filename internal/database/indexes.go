@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ExpectedIndex describes an index the repositories rely on for acceptable query performance
+type ExpectedIndex struct {
+	Table string
+	Name  string
+}
+
+// ExpectedIndexes lists the indexes created by the migrations in /migrations.
+// Keep this in sync when adding indexes so the startup check can report missing ones.
+var ExpectedIndexes = []ExpectedIndex{
+	{Table: "subscribers", Name: "unique_newsletter_subscriber"},
+	{Table: "subscribers", Name: "idx_subscribers_newsletter_active"},
+	{Table: "subscribers", Name: "idx_subscribers_email"},
+	{Table: "subscribers", Name: "idx_subscribers_email_lower"},
+	{Table: "subscribers", Name: "idx_subscribers_confirmation_retry_at"},
+	{Table: "subscribers", Name: "unique_newsletter_subscriber_email_hash"},
+	{Table: "subscribers", Name: "idx_subscribers_email_hash"},
+	{Table: "subscribers", Name: "idx_subscribers_unconfirmed_subscribed_at"},
+	{Table: "subscribers", Name: "idx_subscribers_newsletter_subscribed_at_id"},
+	{Table: "subscribers", Name: "idx_subscribers_deleted_at"},
+	{Table: "subscribers", Name: "idx_subscribers_confirmation_expires_at"},
+	{Table: "subscriber_email_changes", Name: "idx_subscriber_email_changes_subscriber"},
+	{Table: "published_posts", Name: "idx_published_posts_status_scheduled_at"},
+	{Table: "published_posts", Name: "idx_published_posts_newsletter_published_at"},
+	{Table: "published_posts", Name: "idx_published_posts_newsletter_drafts"},
+	{Table: "newsletters", Name: "idx_newsletters_editor_id_created_at"},
+	{Table: "email_jobs", Name: "idx_email_jobs_status_created_at"},
+	{Table: "email_outbox", Name: "idx_email_outbox_pending_available_at"},
+	{Table: "email_outbox", Name: "idx_email_outbox_post_id"},
+	{Table: "email_outbox", Name: "idx_email_outbox_finished_at"},
+	{Table: "subscriber_notifications", Name: "idx_subscriber_notifications_newsletter_created_at"},
+	{Table: "incidents", Name: "idx_incidents_post_id"},
+	{Table: "plan_grants", Name: "unique_coupon_redemption"},
+	{Table: "plan_grants", Name: "idx_plan_grants_editor_ends_at"},
+	{Table: "email_costs", Name: "idx_email_costs_editor_sent_at"},
+	{Table: "email_costs", Name: "idx_email_costs_newsletter_sent_at"},
+	{Table: "audit_log", Name: "idx_audit_log_newsletter_created_at"},
+	{Table: "backups", Name: "idx_backups_started_at"},
+	{Table: "api_keys", Name: "unique_api_key_hash"},
+	{Table: "api_keys", Name: "idx_api_keys_editor_created_at"},
+	{Table: "webhooks", Name: "idx_webhooks_newsletter_id"},
+	{Table: "webhook_deliveries", Name: "idx_webhook_deliveries_pending_available_at"},
+	{Table: "webhook_deliveries", Name: "idx_webhook_deliveries_webhook_created_at"},
+	{Table: "webhook_deliveries", Name: "idx_webhook_deliveries_finished_at"},
+	{Table: "inbox_notifications", Name: "idx_inbox_notifications_editor_created_at"},
+	{Table: "inbox_notifications", Name: "idx_inbox_notifications_unread"},
+	{Table: "inbox_notifications", Name: "idx_inbox_notifications_created_at"},
+	{Table: "post_send_attempts", Name: "idx_post_send_attempts_post_sent_at"},
+	{Table: "integration_clients", Name: "unique_integration_client_id"},
+	{Table: "integration_clients", Name: "idx_integration_clients_editor_created_at"},
+	{Table: "oauth_access_tokens", Name: "idx_oauth_access_tokens_expires_at"},
+	{Table: "newsletter_suppressions", Name: "unique_newsletter_suppression"},
+	{Table: "newsletter_suppressions", Name: "idx_newsletter_suppressions_newsletter_created_at"},
+	{Table: "newsletter_suppressions", Name: "idx_newsletter_suppressions_email_key"},
+	{Table: "account_link_requests", Name: "idx_account_link_requests_user_id"},
+	{Table: "newsletters", Name: "idx_newsletters_deleted_at"},
+	{Table: "published_posts", Name: "idx_published_posts_deleted_at"},
+	{Table: "audit_log", Name: "idx_audit_log_security_events"},
+	{Table: "newsletter_members", Name: "unique_newsletter_member"},
+	{Table: "newsletter_members", Name: "idx_newsletter_members_editor_id"},
+	{Table: "newsletter_invitations", Name: "unique_pending_newsletter_invitation"},
+	{Table: "email_message_events", Name: "idx_email_message_events_post_kind"},
+	{Table: "post_reviews", Name: "idx_post_reviews_in_review_submitted_at"},
+}
+
+// CheckIndexes warns about expected indexes that are missing from the database.
+// It never fails startup; a missing index degrades performance but not correctness.
+func CheckIndexes(ctx context.Context, db *pgxpool.Pool, logger *slog.Logger) {
+	query := `
+		SELECT indexname
+		FROM pg_indexes
+		WHERE schemaname = 'public'
+	`
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		logger.WarnContext(ctx, "Could not verify database indexes", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			logger.WarnContext(ctx, "Could not verify database indexes", "error", err)
+			return
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		logger.WarnContext(ctx, "Could not verify database indexes", "error", err)
+		return
+	}
+
+	missing := 0
+	for _, idx := range ExpectedIndexes {
+		if !existing[idx.Name] {
+			missing++
+			logger.WarnContext(ctx, "Expected database index is missing, run the migrations", "table", idx.Table, "index", idx.Name)
+		}
+	}
+	if missing == 0 {
+		logger.InfoContext(ctx, "All expected database indexes are present", "count", len(ExpectedIndexes))
+	}
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Connect creates the application connection pool and verifies it with a ping
+func Connect(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*pgxpool.Pool, error) {
+	// Build connection string from individual parameters
+	connConfig := map[string]string{
+		"user":     "postgres.iiivolgfmqsxvlrggwsh",
+		"password": os.Getenv("PGPASSWORD"),
+		"host":     os.Getenv("PGHOST"),
+		"port":     os.Getenv("PGPORT"),
+		"dbname":   "postgres",
+		"sslmode":  "require",
+		"timezone": "Europe/Prague",
+	}
+
+	// Convert map to connection string
+	var connStr []string
+	for k, v := range connConfig {
+		if v != "" {
+			connStr = append(connStr, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	databaseURL := strings.Join(connStr, " ")
+	if databaseURL == "" {
+		return nil, fmt.Errorf("database connection parameters are not set properly")
+	}
+
+	// Parse config and configure connection pool
+	parsedConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+
+	// Configure connection pool settings
+	parsedConfig.MaxConns = cfg.Database.MaxConns
+	parsedConfig.MinConns = min(cfg.Database.MinConns, cfg.Database.MaxConns)
+	parsedConfig.MaxConnLifetime = time.Hour
+	parsedConfig.MaxConnIdleTime = time.Minute * 30
+
+	// Disable automatic prepared statement caching to avoid conflicts
+	parsedConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+
+	// Name sessions after this instance so lock holders are identifiable in pg_stat_activity
+	parsedConfig.ConnConfig.RuntimeParams["application_name"] = utils.InstanceID()
+
+	// Record per-query metrics, log slow statements and bound the wait for a free connection
+	queryTracer := NewQueryTracer(cfg.Database.SlowQueryThreshold, logger.With("component", "db"))
+	parsedConfig.ConnConfig.Tracer = NewPoolTracer(queryTracer, cfg.Database.AcquireTimeout)
+
+	// Initialize connection pool
+	dbpool, err := pgxpool.NewWithConfig(ctx, parsedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	// Verify connection
+	if err := dbpool.Ping(ctx); err != nil {
+		dbpool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	WarmUp(ctx, dbpool, cfg.Database.WarmupConns, logger)
+	statsPool.Store(dbpool)
+
+	logger.Info("Successfully connected to the database")
+	return dbpool, nil
+}
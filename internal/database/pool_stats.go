@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statsPool is the pool reported by the db_pool_* gauges, set by Connect
+var statsPool atomic.Pointer[pgxpool.Pool]
+
+var (
+	acquireWait = metrics.NewHistogram("db_pool_acquire_wait_seconds", "Time spent waiting for a pool connection.",
+		metrics.DefaultBuckets)
+	acquireTimeoutsTotal = metrics.NewCounter("db_pool_acquire_timeouts_total", "Pool acquires that found no free connection within the acquire timeout.")
+
+	_ = metrics.NewGaugeFunc("db_pool_acquired_conns", "Pool connections currently in use.", func() float64 {
+		return poolStat(func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) })
+	})
+	_ = metrics.NewGaugeFunc("db_pool_idle_conns", "Open pool connections currently idle.", func() float64 {
+		return poolStat(func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) })
+	})
+	_ = metrics.NewGaugeFunc("db_pool_total_conns", "Open pool connections, including ones being established.", func() float64 {
+		return poolStat(func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) })
+	})
+	_ = metrics.NewGaugeFunc("db_pool_max_conns", "Maximum size of the pool.", func() float64 {
+		return poolStat(func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) })
+	})
+	_ = metrics.NewGaugeFunc("db_pool_saturation", "Share of the maximum pool size in use, 1 when every connection is busy.", func() float64 {
+		return poolStat(func(s *pgxpool.Stat) float64 {
+			if s.MaxConns() == 0 {
+				return 0
+			}
+			return float64(s.AcquiredConns()) / float64(s.MaxConns())
+		})
+	})
+	_ = metrics.NewGaugeFunc("db_pool_empty_acquires", "Acquires that had to wait because no connection was idle, since startup.", func() float64 {
+		return poolStat(func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) })
+	})
+)
+
+func poolStat(value func(*pgxpool.Stat) float64) float64 {
+	pool := statsPool.Load()
+	if pool == nil {
+		return 0
+	}
+	return value(pool.Stat())
+}
+
+// PoolTracer is the QueryTracer extended with pgxpool's acquire hooks: it records how long
+// queries wait for a connection and gives up after the acquire timeout with
+// models.ErrDatabaseBusy, instead of letting requests queue until they time out
+type PoolTracer struct {
+	*QueryTracer
+	acquireTimeout time.Duration
+}
+
+// NewPoolTracer creates a tracer; a zero acquireTimeout waits as long as the caller's context
+func NewPoolTracer(queryTracer *QueryTracer, acquireTimeout time.Duration) *PoolTracer {
+	return &PoolTracer{
+		QueryTracer:    queryTracer,
+		acquireTimeout: acquireTimeout,
+	}
+}
+
+// acquireContext bounds an acquire by the acquire timeout. When the timeout rather than the
+// caller ends it, Err reports models.ErrDatabaseBusy, which pgxpool returns as is.
+type acquireContext struct {
+	context.Context
+	start  time.Time
+	cancel context.CancelFunc
+}
+
+func (c *acquireContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == models.ErrDatabaseBusy {
+		return models.ErrDatabaseBusy
+	}
+	return err
+}
+
+// TraceAcquireStart implements pgxpool.AcquireTracer
+func (t *PoolTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	cancel := context.CancelFunc(func() {})
+	if t.acquireTimeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, t.acquireTimeout, models.ErrDatabaseBusy)
+	}
+	return &acquireContext{Context: ctx, start: time.Now(), cancel: cancel}
+}
+
+// TraceAcquireEnd implements pgxpool.AcquireTracer
+func (t *PoolTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	ac, ok := ctx.(*acquireContext)
+	if !ok {
+		return
+	}
+	ac.cancel()
+	acquireWait.ObserveDuration(time.Since(ac.start))
+	if errors.Is(data.Err, models.ErrDatabaseBusy) {
+		acquireTimeoutsTotal.Inc()
+		t.logger.WarnContext(ctx, "No database connection available within the acquire timeout", "timeout", t.acquireTimeout)
+	}
+}
+
+// WarmUp opens up to n pool connections concurrently, so the first requests after startup do
+// not pay for connection setup. Failures are logged; the pool opens connections on demand.
+func WarmUp(ctx context.Context, pool *pgxpool.Pool, n int32, logger *slog.Logger) {
+	n = min(n, pool.Config().MaxConns)
+	if n <= 0 {
+		return
+	}
+
+	start := time.Now()
+	conns := make([]*pgxpool.Conn, n)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := pool.Acquire(ctx)
+			if err != nil {
+				logger.WarnContext(ctx, "Failed to open connection during pool warm-up", "error", err)
+				return
+			}
+			conns[i] = conn
+		}()
+	}
+	wg.Wait()
+
+	opened := 0
+	for _, conn := range conns {
+		if conn != nil {
+			conn.Release()
+			opened++
+		}
+	}
+	logger.InfoContext(ctx, "Warmed up database pool", "connections", opened, "duration_ms", time.Since(start).Milliseconds())
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SchemaVersion is the migration this build was written against. Bump it together with every
+// new migration, which records its number in the schema_version table.
+const SchemaVersion = 49
+
+// What to do when the database schema is incompatible with this build
+const (
+	// SchemaMismatchRefuse stops startup
+	SchemaMismatchRefuse = "refuse"
+	// SchemaMismatchReadOnly starts without writing to the database: reads are served,
+	// writes are answered with 503 and background jobs do not run
+	SchemaMismatchReadOnly = "read_only"
+)
+
+// ErrSchemaIncompatible is returned when the database schema cannot be used by this build
+var ErrSchemaIncompatible = errors.New("database schema is incompatible with this build")
+
+// undefinedTable is the SQLSTATE of a query on a missing table
+const undefinedTable = "42P01"
+
+// SchemaState is the schema version recorded in the database
+type SchemaState struct {
+	Version              int
+	MinCompatibleVersion int
+}
+
+// CheckSchema compares the database schema with SchemaVersion. A database behind this build
+// (migrations not applied yet) is incompatible. A database ahead of it (blue/green rollout,
+// migrations applied while this build still runs) is compatible unless a migration raised
+// min_compatible_version above SchemaVersion. A database without schema_version predates it.
+func CheckSchema(ctx context.Context, db *pgxpool.Pool, logger *slog.Logger) (*SchemaState, error) {
+	state := &SchemaState{}
+	err := db.QueryRow(ctx, `SELECT version, min_compatible_version FROM schema_version`).Scan(&state.Version, &state.MinCompatibleVersion)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if !errors.Is(err, pgx.ErrNoRows) && !(errors.As(err, &pgErr) && pgErr.Code == undefinedTable) {
+			return nil, fmt.Errorf("failed to read schema version: %w", err)
+		}
+	}
+
+	switch {
+	case state.Version < SchemaVersion:
+		return state, fmt.Errorf("%w: database is at migration %d, this build needs %d; run the migrations", ErrSchemaIncompatible, state.Version, SchemaVersion)
+	case state.MinCompatibleVersion > SchemaVersion:
+		return state, fmt.Errorf("%w: database is at migration %d, which needs a build for at least %d; this build is for %d", ErrSchemaIncompatible, state.Version, state.MinCompatibleVersion, SchemaVersion)
+	case state.Version > SchemaVersion:
+		logger.WarnContext(ctx, "Database schema is ahead of this build, deploy the new version", "schemaVersion", state.Version, "buildSchemaVersion", SchemaVersion)
+	}
+	return state, nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	queriesTotal  = metrics.NewCounterVec("db_queries_total", "Executed SQL statements by query name and outcome.", "query", "status")
+	queryDuration = metrics.NewHistogramVec("db_query_duration_seconds", "SQL statement latency by query name.",
+		metrics.DefaultBuckets, "query")
+	slowQueriesTotal = metrics.NewCounterVec("db_slow_queries_total", "SQL statements exceeding the slow query threshold.", "query")
+)
+
+const (
+	maxLoggedArgLength = 64
+	maxLoggedSQLLength = 500
+)
+
+var (
+	nameCommentPattern = regexp.MustCompile(`(?m)^\s*--\s*name:\s*(\S+)`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+	tablePattern       = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|JOIN)\s+(?:public\.)?([a-z_][a-z0-9_]*)`)
+	emailPattern       = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+)
+
+// QueryTracer is a pgx.QueryTracer that records per-query metrics, logs statements slower
+// than the configured threshold together with their (sanitized) arguments, and traces each
+// statement as a span named after the query. Spans carry the statement, never its arguments.
+type QueryTracer struct {
+	slowThreshold time.Duration
+	logger        *slog.Logger
+}
+
+type traceKey struct{}
+
+type traceData struct {
+	start time.Time
+	name  string
+	sql   string
+	args  []any
+	span  trace.Span
+}
+
+// NewQueryTracer creates a tracer; a zero threshold disables slow query logging
+func NewQueryTracer(slowThreshold time.Duration, logger *slog.Logger) *QueryTracer {
+	return &QueryTracer{
+		slowThreshold: slowThreshold,
+		logger:        logger,
+	}
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := QueryName(data.SQL)
+	ctx, span := tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.query.text", truncate(whitespacePattern.ReplaceAllString(strings.TrimSpace(data.SQL), " "), maxLoggedSQLLength)),
+		),
+	)
+	return context.WithValue(ctx, traceKey{}, &traceData{
+		start: time.Now(),
+		name:  name,
+		sql:   data.SQL,
+		args:  data.Args,
+		span:  span,
+	})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	td, ok := ctx.Value(traceKey{}).(*traceData)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(td.start)
+
+	status := "ok"
+	var spanErr error
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		status = "error"
+		spanErr = data.Err
+	}
+	td.span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	tracing.End(td.span, spanErr)
+	queriesTotal.Inc(td.name, status)
+	queryDuration.ObserveDuration(elapsed, td.name)
+
+	if t.slowThreshold > 0 && elapsed >= t.slowThreshold {
+		slowQueriesTotal.Inc(td.name)
+		t.logger.WarnContext(ctx, "Slow query",
+			"query", td.name,
+			"duration_ms", elapsed.Milliseconds(),
+			"threshold_ms", t.slowThreshold.Milliseconds(),
+			"sql", truncate(whitespacePattern.ReplaceAllString(strings.TrimSpace(td.sql), " "), maxLoggedSQLLength),
+			"args", SanitizeArgs(td.args),
+			"rows", data.CommandTag.RowsAffected(),
+		)
+	}
+}
+
+// QueryName derives a stable, low-cardinality name for a statement. A leading
+// "-- name: Foo" comment wins; otherwise the verb and primary table are used (e.g. "select subscribers").
+func QueryName(sql string) string {
+	if m := nameCommentPattern.FindStringSubmatch(sql); m != nil {
+		return m[1]
+	}
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	verb := strings.ToLower(fields[0])
+	if m := tablePattern.FindStringSubmatch(sql); m != nil {
+		return verb + " " + strings.ToLower(m[1])
+	}
+	return verb
+}
+
+// SanitizeArgs renders bound arguments for logging without leaking personal data:
+// email addresses are masked and long strings (HTML bodies, tokens) are truncated.
+func SanitizeArgs(args []any) []string {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		out = append(out, sanitizeArg(arg))
+	}
+	return out
+}
+
+func sanitizeArg(arg any) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return sanitizeString(v)
+	case *string:
+		if v == nil {
+			return "NULL"
+		}
+		return sanitizeString(*v)
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	default:
+		return truncate(fmt.Sprintf("%v", v), maxLoggedArgLength)
+	}
+}
+
+func sanitizeString(v string) string {
+	if emailPattern.MatchString(v) {
+		at := strings.LastIndex(v, "@")
+		return v[:1] + "***" + v[at:]
+	}
+	return truncate(v, maxLoggedArgLength)
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "…"
+}
@@ -0,0 +1,149 @@
+// Package emailcrypt encrypts subscriber email addresses at rest. Addresses are sealed with
+// AES-256-GCM and looked up through a blind index, an HMAC of the normalized address, so
+// equality checks keep working without decrypting. A nil *Cipher means encryption is
+// disabled: addresses are stored as given and no index is computed.
+package emailcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-newsletter/internal/config"
+)
+
+// prefix marks stored values that are encrypted; values without it are legacy plaintext
+const prefix = "enc:v1:"
+
+// indexPrefix marks blind indexes stored in place of an address, e.g. on the suppression list
+const indexPrefix = "idx:"
+
+// additionalData binds ciphertexts to their purpose, so they cannot be replayed elsewhere
+var additionalData = []byte("go-newsletter subscriber email")
+
+// ErrKeyMissing is returned when an encrypted address is read while encryption is disabled
+var ErrKeyMissing = errors.New("email address is encrypted but no subscriber email key is configured")
+
+// Cipher seals, opens and indexes email addresses with keys derived from one master key
+type Cipher struct {
+	aead     cipher.AEAD
+	indexKey []byte
+}
+
+// New creates a cipher from the configured master key. It returns nil, and so disables
+// encryption, when no key is configured.
+func New(cfg config.SecurityConfig) (*Cipher, error) {
+	encoded := cfg.SubscriberEmailKey
+	if cfg.SubscriberEmailKeyFile != "" {
+		content, err := os.ReadFile(cfg.SubscriberEmailKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read subscriber email key file: %w", err)
+		}
+		encoded = string(content)
+	}
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("subscriber email key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("subscriber email key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(derive(key, "encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead, indexKey: derive(key, "blind-index")}, nil
+}
+
+// derive returns a subkey for label, so the encryption and index keys are independent
+func derive(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// Enabled reports whether addresses are encrypted
+func (c *Cipher) Enabled() bool {
+	return c != nil
+}
+
+// Seal returns the value to store for email: the encrypted address, or email itself when
+// encryption is disabled
+func (c *Cipher) Seal(email string) (string, error) {
+	if c == nil {
+		return email, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(email), additionalData)
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open returns the address of a stored value. Plaintext values, stored before encryption was
+// enabled, are returned unchanged.
+func (c *Cipher) Open(stored string) (string, error) {
+	if !IsSealed(stored) {
+		return stored, nil
+	}
+	if c == nil {
+		return "", ErrKeyMissing
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, prefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted email: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("malformed encrypted email: too short")
+	}
+	email, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additionalData)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt email: %w", err)
+	}
+	return string(email), nil
+}
+
+// Index returns the blind index of email, matching regardless of case. It is nil when
+// encryption is disabled.
+func (c *Cipher) Index(email string) []byte {
+	if c == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(strings.ToLower(email)))
+	return mac.Sum(nil)
+}
+
+// SuppressionKey returns the key the address is stored under on the suppression list: the
+// lowercased address, or its blind index when encryption is enabled
+func (c *Cipher) SuppressionKey(email string) string {
+	if c == nil {
+		return strings.ToLower(email)
+	}
+	return indexPrefix + hex.EncodeToString(c.Index(email))
+}
+
+// IsSealed reports whether a stored value is encrypted
+func IsSealed(stored string) bool {
+	return strings.HasPrefix(stored, prefix)
+}
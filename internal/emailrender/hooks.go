@@ -0,0 +1,94 @@
+package emailrender
+
+import (
+	"strings"
+	"sync"
+)
+
+// Hook transforms emails as they are rendered, e.g. to add a company footer or a legal
+// disclaimer to every email without changing templates. Hooks are registered at startup with
+// RegisterHook.
+type Hook interface {
+	// BeforeRender is called before the template of the email is executed and may change it
+	BeforeRender(email *Email)
+	// AfterRender is called with the rendered email and may change its subject and HTML. The
+	// unsubscribe link must stay in the HTML.
+	AfterRender(email Email, rendered *Rendered)
+}
+
+// Email is what hooks see of an email being rendered
+type Email struct {
+	// Kind is KindPost, KindConfirmation or KindEmailChange
+	Kind           Kind
+	NewsletterName string
+	// Title and ContentHTML are set for posts only. ContentHTML is inserted as it is, so hooks
+	// must only add HTML they trust.
+	Title       string
+	ContentHTML string
+}
+
+// HookFuncs adapts functions to a Hook; either may be nil
+type HookFuncs struct {
+	Before func(email *Email)
+	After  func(email Email, rendered *Rendered)
+}
+
+func (h HookFuncs) BeforeRender(email *Email) {
+	if h.Before != nil {
+		h.Before(email)
+	}
+}
+
+func (h HookFuncs) AfterRender(email Email, rendered *Rendered) {
+	if h.After != nil {
+		h.After(email, rendered)
+	}
+}
+
+// AppendHTML returns a hook adding html to the end of every email, before </body> when the
+// template has one
+func AppendHTML(html string) Hook {
+	return HookFuncs{After: func(_ Email, rendered *Rendered) {
+		if i := strings.LastIndex(strings.ToLower(rendered.HTML), "</body>"); i >= 0 {
+			rendered.HTML = rendered.HTML[:i] + html + rendered.HTML[i:]
+			return
+		}
+		rendered.HTML += html
+	}}
+}
+
+var hooks struct {
+	sync.RWMutex
+	registered []Hook
+}
+
+// RegisterHook adds a hook to every email rendered from now on. Hooks run in the order they were
+// registered; register them at startup, before emails are sent.
+func RegisterHook(hook Hook) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.registered = append(hooks.registered, hook)
+}
+
+func registeredHooks() []Hook {
+	hooks.RLock()
+	defer hooks.RUnlock()
+	return hooks.registered
+}
+
+// render runs the hooks around execute, which renders the email as the hooks left it. When
+// execute fails, the after hooks are skipped.
+func render(email Email, execute func(email Email) (Rendered, error)) (Rendered, error) {
+	registered := registeredHooks()
+	for _, hook := range registered {
+		hook.BeforeRender(&email)
+	}
+	rendered, err := execute(email)
+	if err != nil {
+		return Rendered{}, err
+	}
+	for _, hook := range registered {
+		hook.AfterRender(email, &rendered)
+	}
+	return rendered, nil
+}
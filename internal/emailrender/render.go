@@ -0,0 +1,85 @@
+package emailrender
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// PostEmail holds everything needed to render a post for a single recipient
+type PostEmail struct {
+	NewsletterName string `json:"newsletter_name"`
+	Title          string `json:"title"`
+	// ContentHTML is inserted as it is; callers pass post HTML that was already sanitized
+	ContentHTML    string `json:"content_html"`
+	UnsubscribeURL string `json:"unsubscribe_url"`
+	// UnsubscribeAllURL is optional; when set, the footer also links to unsubscribing from all newsletters
+	UnsubscribeAllURL string `json:"unsubscribe_all_url,omitempty"`
+	// Template is the newsletter's post template; nil uses the default layout
+	Template *Template `json:"-"`
+}
+
+// ConfirmationEmail holds everything needed to render a subscription or email change confirmation
+type ConfirmationEmail struct {
+	NewsletterName string
+	ConfirmURL     string
+	// Template is the newsletter's confirmation template; nil uses the default one
+	Template *Template
+}
+
+// Rendered is the final subject and HTML body of an email
+type Rendered struct {
+	Subject string
+	HTML    string
+	// ListUnsubscribeURL is the recipient's unsubscribe link for the List-Unsubscribe header
+	ListUnsubscribeURL string
+}
+
+// RenderPost renders a post email. Without hooks it is deterministic so its output can be
+// compared against golden files (see golden_test.go). A custom template that cannot render it
+// returns ErrTemplateOutput.
+func RenderPost(p PostEmail) (Rendered, error) {
+	email := Email{Kind: KindPost, NewsletterName: p.NewsletterName, Title: p.Title, ContentHTML: p.ContentHTML}
+	return render(email, func(email Email) (Rendered, error) {
+		subject := email.Title
+		if email.NewsletterName != "" {
+			subject = email.NewsletterName + ": " + email.Title
+		}
+
+		html, err := execute(p.Template, KindPost, postData{
+			NewsletterName:    email.NewsletterName,
+			Title:             email.Title,
+			Content:           template.HTML(email.ContentHTML),
+			UnsubscribeURL:    p.UnsubscribeURL,
+			UnsubscribeAllURL: p.UnsubscribeAllURL,
+		}, p.UnsubscribeURL)
+		if err != nil {
+			return Rendered{}, err
+		}
+
+		return Rendered{Subject: subject, HTML: html, ListUnsubscribeURL: p.UnsubscribeURL}, nil
+	})
+}
+
+// RenderConfirmation renders the email asking to confirm a subscription. A custom template that
+// cannot render it returns ErrTemplateOutput.
+func RenderConfirmation(c ConfirmationEmail) (Rendered, error) {
+	return render(Email{Kind: KindConfirmation, NewsletterName: c.NewsletterName}, func(email Email) (Rendered, error) {
+		html, err := execute(c.Template, KindConfirmation, linkData{NewsletterName: email.NewsletterName, ConfirmURL: c.ConfirmURL}, c.ConfirmURL)
+		if err != nil {
+			return Rendered{}, err
+		}
+		return Rendered{Subject: "Confirm Your Newsletter Subscription", HTML: html}, nil
+	})
+}
+
+// RenderEmailChange renders the email asking to confirm moving a subscription to a new address
+func RenderEmailChange(c ConfirmationEmail) Rendered {
+	rendered, _ := render(Email{Kind: KindEmailChange, NewsletterName: c.NewsletterName}, func(email Email) (Rendered, error) {
+		var html bytes.Buffer
+		if err := emailChangeTemplate.tmpl.Execute(&html, linkData{NewsletterName: email.NewsletterName, ConfirmURL: c.ConfirmURL}); err != nil {
+			panic("emailrender: email change template: " + err.Error())
+		}
+		return Rendered{Subject: "Confirm Your New Email Address", HTML: html.String()}, nil
+	})
+	return rendered
+}
@@ -0,0 +1,235 @@
+package emailrender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"reflect"
+	"strings"
+
+	"go-newsletter/internal/sanitize"
+)
+
+// Kind names an email that newsletters can give their own template
+type Kind string
+
+const (
+	// KindPost is the layout of published posts; variables: NewsletterName, Title, Content,
+	// UnsubscribeURL and UnsubscribeAllURL (empty unless the link is enabled)
+	KindPost Kind = "post"
+	// KindConfirmation is the email asking to confirm a subscription; variables:
+	// NewsletterName and ConfirmURL
+	KindConfirmation Kind = "confirmation"
+	// KindEmailChange is the email verifying a new address of a subscription; it has no custom
+	// templates
+	KindEmailChange Kind = "email_change"
+)
+
+// Kinds lists every customizable kind, in the order they are listed in the API
+var Kinds = []Kind{KindPost, KindConfirmation}
+
+// MaxTemplateSize is the largest template source accepted, in bytes
+const MaxTemplateSize = 100 * 1024
+
+const defaultPostSource = `<div style="max-width: 600px; margin: 0 auto; font-family: Arial, sans-serif">
+{{- if .NewsletterName}}
+<p style="color: #666666; font-size: 14px">{{.NewsletterName}}</p>
+{{- end}}
+{{.Content}}
+<br><br>
+<hr>
+<p><small>Pokud už nechcete dostávat tyto zprávy, můžete se <a href="{{.UnsubscribeURL}}">odhlásit zde</a>.
+{{- if .UnsubscribeAllURL}} Odhlásit se můžete také <a href="{{.UnsubscribeAllURL}}">ze všech newsletterů</a>.{{end}}</small></p>
+</div>
+`
+
+const defaultConfirmationSource = `<h1>Confirm Your Subscription to {{.NewsletterName}}</h1>
+<p>Thank you for subscribing to our newsletter! Please click the link below to confirm your subscription:</p>
+<p><a href="{{.ConfirmURL}}">Confirm Subscription</a></p>
+<p>If you did not request this subscription, you can safely ignore this email.</p>
+`
+
+// emailChangeSource is not customizable: the email goes to an address that is not subscribed yet
+const emailChangeSource = `<h1>Confirm Your New Email Address for {{.NewsletterName}}</h1>
+<p>We received a request to move your subscription to this address. Please click the link below within 24 hours to confirm the change:</p>
+<p><a href="{{.ConfirmURL}}">Confirm Email Change</a></p>
+<p>If you did not request this change, you can safely ignore this email.</p>
+`
+
+// postData and linkData are the variables available to the templates. Their doc tags describe
+// them to editors; see Variables.
+type postData struct {
+	NewsletterName    string        `doc:"Name of the newsletter"`
+	Title             string        `doc:"Title of the post"`
+	Content           template.HTML `doc:"Content of the post, as HTML"`
+	UnsubscribeURL    string        `doc:"Link unsubscribing the recipient from the newsletter"`
+	UnsubscribeAllURL string        `doc:"Link unsubscribing the recipient from all newsletters; empty unless enabled"`
+}
+
+type linkData struct {
+	NewsletterName string `doc:"Name of the newsletter"`
+	ConfirmURL     string `doc:"Link confirming the subscription"`
+}
+
+// samples are rendered with each new template to catch unknown variables and missing links
+const (
+	sampleUnsubscribeURL = "https://example.com/unsubscribe/sample-token"
+	sampleConfirmURL     = "https://example.com/subscribe/confirm/sample-token"
+)
+
+var samples = map[Kind]any{
+	KindPost: postData{
+		NewsletterName:    "Sample Newsletter",
+		Title:             "Sample post",
+		Content:           "<p>Sample content</p>",
+		UnsubscribeURL:    sampleUnsubscribeURL,
+		UnsubscribeAllURL: "https://example.com/unsubscribe-all/sample-token",
+	},
+	KindConfirmation: linkData{NewsletterName: "Sample Newsletter", ConfirmURL: sampleConfirmURL},
+}
+
+// requiredLinks must appear in the output of a template, so no template can drop them
+var requiredLinks = map[Kind]struct {
+	url      string
+	variable string
+}{
+	KindPost:         {sampleUnsubscribeURL, "{{.UnsubscribeURL}}"},
+	KindConfirmation: {sampleConfirmURL, "{{.ConfirmURL}}"},
+}
+
+var (
+	defaultTemplates = map[Kind]*Template{
+		KindPost:         mustParse(KindPost, defaultPostSource),
+		KindConfirmation: mustParse(KindConfirmation, defaultConfirmationSource),
+	}
+	emailChangeTemplate = mustParse(KindEmailChange, emailChangeSource)
+)
+
+// Variable is a value templates of a kind can use, like {{.NewsletterName}}
+type Variable struct {
+	Kind        Kind
+	Name        string
+	Description string
+	// Sample is the value new templates are checked with
+	Sample string
+	// Required is set when every template of the kind must use the variable
+	Required bool
+}
+
+// Tag is how a template refers to the variable
+func (v Variable) Tag() string {
+	return "{{." + v.Name + "}}"
+}
+
+// Variables lists the variables of the kind, in the order of their fields. They are read from
+// the data templates are executed with, so the list cannot miss a variable templates can use.
+func Variables(kind Kind) []Variable {
+	sample, ok := samples[kind]
+	if !ok {
+		return nil
+	}
+	value := reflect.ValueOf(sample)
+	variables := make([]Variable, 0, value.NumField())
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		variable := Variable{
+			Kind:        kind,
+			Name:        field.Name,
+			Description: field.Tag.Get("doc"),
+			Sample:      fmt.Sprint(value.Field(i).Interface()),
+		}
+		variable.Required = requiredLinks[kind].variable == variable.Tag()
+		variables = append(variables, variable)
+	}
+	return variables
+}
+
+// Template is a parsed email template of a kind
+type Template struct {
+	kind Kind
+	tmpl *template.Template
+}
+
+// ErrUnknownKind is returned for kinds not in Kinds
+var ErrUnknownKind = errors.New("unknown email template kind")
+
+// ValidKind reports whether kind is a customizable kind
+func ValidKind(kind Kind) bool {
+	_, ok := samples[kind]
+	return ok
+}
+
+// DefaultSource returns the source of the built-in template of the kind
+func DefaultSource(kind Kind) string {
+	switch kind {
+	case KindPost:
+		return defaultPostSource
+	case KindConfirmation:
+		return defaultConfirmationSource
+	}
+	return ""
+}
+
+// ErrTemplateOutput is returned, wrapped, when a custom template fails to render an email or
+// its sanitized output lacks the required link. The email is not sent rather than sent with
+// another layout.
+var ErrTemplateOutput = errors.New("email template cannot render the email")
+
+// ParseTemplate parses the source of a custom template and renders it once with sample data,
+// sanitized as it is when sending. The error describes what is wrong in words fit for the
+// editor who wrote the template.
+func ParseTemplate(kind Kind, source string) (*Template, error) {
+	if !ValidKind(kind) {
+		return nil, ErrUnknownKind
+	}
+	if len(source) > MaxTemplateSize {
+		return nil, fmt.Errorf("template is larger than %d bytes", MaxTemplateSize)
+	}
+
+	tmpl, err := template.New(string(kind)).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("template does not parse: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, samples[kind]); err != nil {
+		return nil, fmt.Errorf("template does not render: %w", err)
+	}
+	if link := requiredLinks[kind]; !containsLink(sanitize.EmailDocument(out.String()), link.url) {
+		return nil, fmt.Errorf("template must contain the link %s", link.variable)
+	}
+	return &Template{kind: kind, tmpl: tmpl}, nil
+}
+
+func mustParse(kind Kind, source string) *Template {
+	return &Template{kind: kind, tmpl: template.Must(template.New(string(kind)).Parse(source))}
+}
+
+// execute renders t, or the default template of its kind when t is nil. Custom templates are
+// written by editors, so their output is sanitized like post content and must still contain
+// link, the recipient's unsubscribe or confirmation URL; ParseTemplate only checked sample data.
+func execute(t *Template, kind Kind, data any, link string) (string, error) {
+	var out bytes.Buffer
+	if t == nil || t.kind != kind {
+		if err := defaultTemplates[kind].tmpl.Execute(&out, data); err != nil {
+			// The built-in templates only use fields of their data
+			panic(fmt.Sprintf("emailrender: default %s template: %v", kind, err))
+		}
+		return out.String(), nil
+	}
+
+	if err := t.tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("%w: %s template does not render: %v", ErrTemplateOutput, kind, err)
+	}
+	sanitized := sanitize.EmailDocument(out.String())
+	if !containsLink(sanitized, link) {
+		return "", fmt.Errorf("%w: %s template output lacks the link %s", ErrTemplateOutput, kind, requiredLinks[kind].variable)
+	}
+	return sanitized, nil
+}
+
+// containsLink reports whether the link appears in the HTML, as it is or escaped in an attribute
+func containsLink(out string, link string) bool {
+	return strings.Contains(out, link) || strings.Contains(out, html.EscapeString(link))
+}
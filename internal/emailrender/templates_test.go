@@ -0,0 +1,65 @@
+package emailrender
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const unsubscribeURL = "https://example.com/api/v1/unsubscribe/token"
+
+func TestRenderPostSanitizesCustomTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(KindPost, `<html><head><title>{{.Title}}</title><script>track()</script></head>`+
+		`<body onload="track()"><a href="javascript:track()">Open</a>{{.Content}}`+
+		`<a href="{{.UnsubscribeURL}}">Unsubscribe</a></body></html>`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	rendered, err := RenderPost(PostEmail{Title: "Hello", ContentHTML: "<p>Hi</p>", UnsubscribeURL: unsubscribeURL, Template: tmpl})
+	if err != nil {
+		t.Fatalf("RenderPost: %v", err)
+	}
+	for _, unsafe := range []string{"<script", "track()", "onload", "javascript:"} {
+		if strings.Contains(rendered.HTML, unsafe) {
+			t.Errorf("rendered HTML contains %q:\n%s", unsafe, rendered.HTML)
+		}
+	}
+	for _, kept := range []string{"<title>Hello</title>", "<p>Hi</p>", `href="` + unsubscribeURL + `"`} {
+		if !strings.Contains(rendered.HTML, kept) {
+			t.Errorf("rendered HTML lacks %q:\n%s", kept, rendered.HTML)
+		}
+	}
+}
+
+func TestRenderPostFailsWithoutUnsubscribeLink(t *testing.T) {
+	// The sample post has another title, so ParseTemplate sees the link
+	tmpl, err := ParseTemplate(KindPost, `{{.Content}}{{if ne .Title "Last issue"}}<a href="{{.UnsubscribeURL}}">Unsubscribe</a>{{end}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	_, err = RenderPost(PostEmail{Title: "Last issue", ContentHTML: "<p>Bye</p>", UnsubscribeURL: unsubscribeURL, Template: tmpl})
+	if !errors.Is(err, ErrTemplateOutput) {
+		t.Fatalf("RenderPost error = %v, want ErrTemplateOutput", err)
+	}
+}
+
+func TestParseTemplateRejectsLinkDroppedBySanitizing(t *testing.T) {
+	_, err := ParseTemplate(KindPost, `{{.Content}}<form action="{{.UnsubscribeURL}}"><button>Unsubscribe</button></form>`)
+	if err == nil {
+		t.Fatal("ParseTemplate accepted a template whose only unsubscribe link is a form")
+	}
+}
+
+func TestRenderConfirmationFailsWhenTemplateErrors(t *testing.T) {
+	tmpl, err := ParseTemplate(KindConfirmation, `{{if eq .NewsletterName "Broken"}}{{index .NewsletterName 99}}{{end}}<a href="{{.ConfirmURL}}">Confirm</a>`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+
+	_, err = RenderConfirmation(ConfirmationEmail{NewsletterName: "Broken", ConfirmURL: "https://example.com/subscribe/confirm/token", Template: tmpl})
+	if !errors.Is(err, ErrTemplateOutput) {
+		t.Fatalf("RenderConfirmation error = %v, want ErrTemplateOutput", err)
+	}
+}
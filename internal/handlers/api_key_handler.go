@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+	responder     *utils.HTTPResponder
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService, responder *utils.HTTPResponder) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		responder:     responder,
+	}
+}
+
+// sessionUser returns the signed-in editor. Keys are managed with a session only, so a leaked
+// key or integration token cannot be used to mint new keys or to revoke the editor's other keys.
+func (h *APIKeyHandler) sessionUser(w http.ResponseWriter, r *http.Request) (*services.UserContext, bool) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return nil, false
+	}
+	if user.Automated() {
+		h.responder.HandleError(w, r, models.NewForbiddenError("API keys and integration tokens cannot manage API keys, sign in instead"))
+		return nil, false
+	}
+	return user, true
+}
+
+// ListKeys handles GET /me/api-keys
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(r.Context(), user.UserID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, keys)
+}
+
+// CreateKey handles POST /me/api-keys
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req generated.ApiKeyCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	key, err := h.apiKeyService.CreateKey(r.Context(), user.UserID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, key)
+}
+
+// RevokeKey handles DELETE /me/api-keys/{apiKeyId}
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := uuid.Parse(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid API key ID"))
+		return
+	}
+
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(r.Context(), user.UserID, keyID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
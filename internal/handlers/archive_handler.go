@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"go-newsletter/internal/feed"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/sitemap"
+	"go-newsletter/internal/utils"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// archiveCacheControl lets browsers and CDNs in front of archive pages reuse responses briefly
+const archiveCacheControl = "public, max-age=60"
+
+// badgeCacheControl caches badges for as long as the server does, and lets CDNs serve a stale
+// badge for a day while they revalidate, since embedding sites can be busy
+const badgeCacheControl = "public, max-age=300, s-maxage=300, stale-while-revalidate=86400"
+
+// feedCacheControl matches how often feed readers poll; Last-Modified lets them revalidate cheaply
+const feedCacheControl = "public, max-age=300"
+
+// sitemapCacheControl lets CDNs absorb crawlers; Last-Modified lets them revalidate cheaply
+const sitemapCacheControl = "public, max-age=3600"
+
+// ArchiveHandler serves the public archive of newsletters, without authentication
+type ArchiveHandler struct {
+	newsletterService *services.NewsletterService
+	postService       *services.PostService
+	badgeService      *services.BadgeService
+	responder         *utils.HTTPResponder
+}
+
+func NewArchiveHandler(newsletterService *services.NewsletterService, postService *services.PostService, badgeService *services.BadgeService, responder *utils.HTTPResponder) *ArchiveHandler {
+	return &ArchiveHandler{
+		newsletterService: newsletterService,
+		postService:       postService,
+		badgeService:      badgeService,
+		responder:         responder,
+	}
+}
+
+// GetNewsletter handles GET /public/newsletters/{newsletterId}
+func (h *ArchiveHandler) GetNewsletter(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	newsletter, err := h.newsletterService.GetPublicNewsletter(r.Context(), newsletterID.String())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", archiveCacheControl)
+	w.Header().Set("X-Robots-Tag", newsletter.Robots)
+	h.responder.RespondJSON(w, http.StatusOK, newsletter)
+}
+
+// ListPosts handles GET /public/newsletters/{newsletterId}/posts
+func (h *ArchiveHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	if !h.setRobots(w, r, newsletterID) {
+		return
+	}
+
+	posts, next, err := h.postService.ListPublicPosts(r.Context(), newsletterID, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	w.Header().Set("Cache-Control", archiveCacheControl)
+	h.responder.RespondJSON(w, http.StatusOK, posts)
+}
+
+// GetPost handles GET /public/newsletters/{newsletterId}/posts/{postId}
+func (h *ArchiveHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+	postID, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
+		return
+	}
+
+	if !h.setRobots(w, r, newsletterID) {
+		return
+	}
+
+	post, err := h.postService.GetPublicPost(r.Context(), newsletterID, postID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", archiveCacheControl)
+	h.responder.RespondJSON(w, http.StatusOK, post)
+}
+
+// GetBadge handles GET /public/newsletters/{newsletterId}/badge.svg
+func (h *ArchiveHandler) GetBadge(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	badge, err := h.badgeService.SubscriberBadge(r.Context(), newsletterID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", badgeCacheControl)
+	w.Header().Set("ETag", badge.ETag)
+	if r.Header.Get("If-None-Match") == badge.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(badge.SVG)
+}
+
+// GetFeed handles GET /public/newsletters/{newsletterId}/feed.xml. It serves RSS 2.0 unless
+// the format query parameter asks for atom.
+func (h *ArchiveHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	render, contentType := feed.RSS, "application/rss+xml; charset=utf-8"
+	switch format {
+	case "", "rss":
+	case "atom":
+		render, contentType = feed.Atom, "application/atom+xml; charset=utf-8"
+	default:
+		h.responder.HandleError(w, r, models.NewBadRequestError("format must be rss or atom"))
+		return
+	}
+
+	if !h.setRobots(w, r, newsletterID) {
+		return
+	}
+
+	f, err := h.postService.PublicFeed(r.Context(), newsletterID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	if format == "atom" {
+		f.SelfURL += "?format=atom"
+	}
+	body, err := render(*f)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", feedCacheControl)
+	w.Header().Set("Content-Type", contentType)
+	// ServeContent answers If-Modified-Since with 304 based on the newest post
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(body))
+}
+
+// GetSitemap handles GET /public/newsletters/{newsletterId}/sitemap.xml
+func (h *ArchiveHandler) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	urls, err := h.postService.PublicSitemap(r.Context(), newsletterID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	body, err := sitemap.Render(urls)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", sitemapCacheControl)
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	// The archive comes first, last modified when the newest post was published
+	http.ServeContent(w, r, "", urls[0].LastMod, bytes.NewReader(body))
+}
+
+// setRobots sends the robots directives the editor chose for the newsletter's archive as
+// X-Robots-Tag. When the newsletter cannot be read it answers with the error and returns false.
+func (h *ArchiveHandler) setRobots(w http.ResponseWriter, r *http.Request, newsletterID uuid.UUID) bool {
+	newsletter, err := h.newsletterService.GetPublicNewsletter(r.Context(), newsletterID.String())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return false
+	}
+	w.Header().Set("X-Robots-Tag", newsletter.Robots)
+	return true
+}
@@ -27,7 +27,7 @@ func (h *AuthHandler) PostAuthPasswordResetRequest(w http.ResponseWriter, r *htt
 	response := map[string]interface{}{
 		"message": "Password reset is handled by Supabase Auth on the frontend",
 		"instructions": map[string]interface{}{
-			"frontend":     "Use supabase.auth.resetPasswordForEmail(email)",
+			"frontend":      "Use supabase.auth.resetPasswordForEmail(email)",
 			"documentation": "https://supabase.com/docs/guides/auth/passwords#reset-a-password",
 		},
 	}
@@ -39,9 +39,9 @@ func (h *AuthHandler) PostAuthSignin(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"message": "Sign-in is handled by Supabase Auth on the frontend",
 		"instructions": map[string]interface{}{
-			"frontend":     "Use supabase.auth.signInWithPassword({ email, password })",
+			"frontend":      "Use supabase.auth.signInWithPassword({ email, password })",
 			"documentation": "https://supabase.com/docs/guides/auth/passwords#sign-in-with-password",
-			"note":         "After successful sign-in, include the JWT token in the Authorization header for API requests",
+			"note":          "After successful sign-in, include the JWT token in the Authorization header for API requests",
 		},
 	}
 	h.responder.RespondJSON(w, http.StatusOK, response)
@@ -52,10 +52,10 @@ func (h *AuthHandler) PostAuthSignup(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"message": "Sign-up is handled by Supabase Auth on the frontend",
 		"instructions": map[string]interface{}{
-			"frontend":     "Use supabase.auth.signUp({ email, password })",
+			"frontend":      "Use supabase.auth.signUp({ email, password })",
 			"documentation": "https://supabase.com/docs/guides/auth/passwords#sign-up-with-password",
-			"note":         "A profile will be automatically created in the profiles table upon successful registration",
+			"note":          "A profile will be automatically created in the profiles table upon successful registration",
 		},
 	}
 	h.responder.RespondJSON(w, http.StatusOK, response)
-} 
\ No newline at end of file
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+)
+
+type ConfigHandler struct {
+	cfg             *config.Config
+	readOnlyService *services.ReadOnlyService
+	responder       *utils.HTTPResponder
+}
+
+func NewConfigHandler(cfg *config.Config, readOnlyService *services.ReadOnlyService, responder *utils.HTTPResponder) *ConfigHandler {
+	return &ConfigHandler{
+		cfg:             cfg,
+		readOnlyService: readOnlyService,
+		responder:       responder,
+	}
+}
+
+// GetEffective handles GET /admin/config
+func (h *ConfigHandler) GetEffective(w http.ResponseWriter, r *http.Request) {
+	instanceID := utils.InstanceID()
+	sections := h.cfg.Effective()
+
+	response := generated.EffectiveConfig{
+		InstanceId: &instanceID,
+		Sections:   &sections,
+	}
+	if h.cfg.File != "" {
+		response.ConfigFile = &h.cfg.File
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, response)
+}
+
+// GetReadOnly handles GET /admin/config/read-only
+func (h *ConfigHandler) GetReadOnly(w http.ResponseWriter, r *http.Request) {
+	mode, err := h.readOnlyService.Get(r.Context())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, mode)
+}
+
+// PutReadOnly handles PUT /admin/config/read-only
+func (h *ConfigHandler) PutReadOnly(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.ReadOnlyModeUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	mode, err := h.readOnlyService.Set(r.Context(), user.UserID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, mode)
+}
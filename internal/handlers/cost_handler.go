@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CostHandler struct {
+	costService    *services.CostService
+	profileService *services.ProfileService
+	responder      *utils.HTTPResponder
+}
+
+func NewCostHandler(costService *services.CostService, profileService *services.ProfileService, responder *utils.HTTPResponder) *CostHandler {
+	return &CostHandler{
+		costService:    costService,
+		profileService: profileService,
+		responder:      responder,
+	}
+}
+
+// GetMyCosts handles GET /me/costs
+func (h *CostHandler) GetMyCosts(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	costs, err := h.costService.GetEditorCosts(r.Context(), user.UserID, r.URL.Query().Get("period"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, costs)
+}
+
+// GetNewsletterCosts handles GET /newsletters/{newsletterId}/costs
+func (h *CostHandler) GetNewsletterCosts(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	costs, err := h.costService.GetNewsletterCosts(r.Context(), newsletterID, user.UserID.String(), r.URL.Query().Get("period"))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			err = models.NewNotFoundError("Newsletter not found")
+		}
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, costs)
+}
+
+// GetUserCosts handles GET /admin/users/{userId}/costs
+func (h *CostHandler) GetUserCosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	if _, err := h.profileService.GetProfileByID(r.Context(), userID.String()); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	costs, err := h.costService.GetEditorCosts(r.Context(), userID, r.URL.Query().Get("period"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, costs)
+}
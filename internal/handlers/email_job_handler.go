@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type EmailJobHandler struct {
+	emailJobService *services.EmailJobService
+	responder       *utils.HTTPResponder
+}
+
+func NewEmailJobHandler(emailJobService *services.EmailJobService, responder *utils.HTTPResponder) *EmailJobHandler {
+	return &EmailJobHandler{
+		emailJobService: emailJobService,
+		responder:       responder,
+	}
+}
+
+// ListJobs handles GET /admin/jobs
+func (h *EmailJobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	var params generated.GetAdminJobsParams
+	query := r.URL.Query()
+	if raw := query.Get("status"); raw != "" {
+		status := generated.EmailJobStatus(raw)
+		params.Status = &status
+	}
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	jobs, next, err := h.emailJobService.ListJobs(r.Context(), params, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+
+	h.responder.RespondJSON(w, http.StatusOK, jobs)
+}
+
+// RetryJob handles POST /admin/jobs/{jobId}/retry
+func (h *EmailJobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid job ID"))
+		return
+	}
+
+	job, err := h.emailJobService.RetryJob(r.Context(), jobID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type EmailTemplateHandler struct {
+	emailTemplateService *services.EmailTemplateService
+	responder            *utils.HTTPResponder
+}
+
+func NewEmailTemplateHandler(emailTemplateService *services.EmailTemplateService, responder *utils.HTTPResponder) *EmailTemplateHandler {
+	return &EmailTemplateHandler{
+		emailTemplateService: emailTemplateService,
+		responder:            responder,
+	}
+}
+
+// ListTemplates handles GET /newsletters/{newsletterId}/email-templates
+func (h *EmailTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	templates, err := h.emailTemplateService.ListTemplates(r.Context(), user.UserID, newsletterID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, templates)
+}
+
+// ListVariables handles GET /newsletters/{newsletterId}/template/variables
+func (h *EmailTemplateHandler) ListVariables(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	variables, err := h.emailTemplateService.ListVariables(r.Context(), user.UserID, newsletterID, emailrender.Kind(r.URL.Query().Get("kind")))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, variables)
+}
+
+// GetTemplate handles GET /newsletters/{newsletterId}/email-templates/{kind}
+func (h *EmailTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	template, err := h.emailTemplateService.GetTemplate(r.Context(), user.UserID, newsletterID, emailrender.Kind(chi.URLParam(r, "kind")))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, template)
+}
+
+// SetTemplate handles PUT /newsletters/{newsletterId}/email-templates/{kind}
+func (h *EmailTemplateHandler) SetTemplate(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.EmailTemplateUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	template, err := h.emailTemplateService.SetTemplate(r.Context(), user.UserID, newsletterID, emailrender.Kind(chi.URLParam(r, "kind")), req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, template)
+}
+
+// ResetTemplate handles DELETE /newsletters/{newsletterId}/email-templates/{kind}
+func (h *EmailTemplateHandler) ResetTemplate(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.emailTemplateService.ResetTemplate(r.Context(), user.UserID, newsletterID, emailrender.Kind(chi.URLParam(r, "kind"))); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
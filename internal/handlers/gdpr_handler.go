@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+)
+
+type GDPRHandler struct {
+	gdprService *services.GDPRService
+	responder   *utils.HTTPResponder
+}
+
+func NewGDPRHandler(gdprService *services.GDPRService, responder *utils.HTTPResponder) *GDPRHandler {
+	return &GDPRHandler{
+		gdprService: gdprService,
+		responder:   responder,
+	}
+}
+
+// RequestVerification handles POST /gdpr/subscribers/verification
+func (h *GDPRHandler) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	var req generated.SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	if err := h.gdprService.RequestVerification(r.Context(), string(req.Email)); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "If we hold data of the address, a verification token is on its way.",
+	}
+	h.responder.RespondJSON(w, http.StatusAccepted, response)
+}
+
+// Export handles POST /gdpr/subscribers/export
+func (h *GDPRHandler) Export(w http.ResponseWriter, r *http.Request) {
+	var req generated.SubscriberDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	export, err := h.gdprService.Export(r.Context(), req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, export)
+}
+
+// Erase handles DELETE /gdpr/subscribers
+func (h *GDPRHandler) Erase(w http.ResponseWriter, r *http.Request) {
+	var req generated.SubscriberDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	erased, err := h.gdprService.Erase(r.Context(), req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, erased)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"go-newsletter/internal/hostedpage"
+	"go-newsletter/internal/utils"
+	"net/http"
+)
+
+// HostedPageHandler answers links opened from emails. Browsers get a branded page, other
+// clients the JSON the API always returned.
+type HostedPageHandler struct {
+	pages     *hostedpage.Pages
+	responder *utils.HTTPResponder
+}
+
+func NewHostedPageHandler(pages *hostedpage.Pages, responder *utils.HTTPResponder) *HostedPageHandler {
+	return &HostedPageHandler{
+		pages:     pages,
+		responder: responder,
+	}
+}
+
+// Respond sends a page with the title and message, or the message as JSON
+func (h *HostedPageHandler) Respond(w http.ResponseWriter, r *http.Request, status int, title string, message string) {
+	if !hostedpage.WantsHTML(r) {
+		response := struct {
+			Message string `json:"message"`
+		}{
+			Message: message,
+		}
+		h.responder.RespondJSON(w, status, response)
+		return
+	}
+	h.write(w, r, hostedpage.Page{Status: status, Title: title, Message: message})
+}
+
+// HandleError sends an error page, or the JSON error
+func (h *HostedPageHandler) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	if !hostedpage.WantsHTML(r) {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+	errorResponse := h.responder.ErrorBody(w, r, err)
+	status := int(errorResponse.Code)
+	title := "Something went wrong"
+	if status >= 400 && status < 500 {
+		title = "This link doesn't work"
+	}
+	h.write(w, r, hostedpage.Page{Status: status, Title: title, Message: errorResponse.Message})
+}
+
+// NotFound answers requests for unknown paths, with a page for browsers
+func (h *HostedPageHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	if !hostedpage.WantsHTML(r) {
+		http.NotFound(w, r)
+		return
+	}
+	h.write(w, r, hostedpage.Page{
+		Status:  http.StatusNotFound,
+		Title:   "Page not found",
+		Message: "The page you are looking for does not exist. If you followed a link from an email, it may have been copied incompletely.",
+	})
+}
+
+func (h *HostedPageHandler) write(w http.ResponseWriter, r *http.Request, page hostedpage.Page) {
+	if err := h.pages.Write(w, r, page); err != nil {
+		h.responder.Logger.ErrorContext(r.Context(), "Failed to render hosted page", "status", page.Status, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type InboxHandler struct {
+	inboxService *services.InboxService
+	responder    *utils.HTTPResponder
+}
+
+func NewInboxHandler(inboxService *services.InboxService, responder *utils.HTTPResponder) *InboxHandler {
+	return &InboxHandler{
+		inboxService: inboxService,
+		responder:    responder,
+	}
+}
+
+// ListNotifications handles GET /me/notifications
+func (h *InboxHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	unreadOnly := false
+	if raw := r.URL.Query().Get("unread"); raw != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(raw)
+		if err != nil {
+			h.responder.HandleError(w, r, models.NewBadRequestError("unread must be a boolean"))
+			return
+		}
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	notifications, next, err := h.inboxService.ListNotifications(r.Context(), user.UserID, unreadOnly, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, notifications)
+}
+
+// UnreadCount handles GET /me/notifications/unread-count
+func (h *InboxHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	count, err := h.inboxService.UnreadCount(r.Context(), user.UserID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, count)
+}
+
+// MarkAllRead handles POST /me/notifications/read-all
+func (h *InboxHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.inboxService.MarkAllRead(r.Context(), user.UserID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkRead handles POST /me/notifications/{notificationId}/read
+func (h *InboxHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := uuid.Parse(chi.URLParam(r, "notificationId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid notification ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.inboxService.MarkRead(r.Context(), user.UserID, notificationID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
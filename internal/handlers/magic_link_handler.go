@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+)
+
+// magicLinkRetryAfter is the Retry-After, in seconds, when Supabase refuses to send another
+// link to the address yet
+const magicLinkRetryAfter = "60"
+
+type MagicLinkHandler struct {
+	magicLinkService *services.MagicLinkService
+	responder        *utils.HTTPResponder
+}
+
+func NewMagicLinkHandler(magicLinkService *services.MagicLinkService, responder *utils.HTTPResponder) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		responder:        responder,
+	}
+}
+
+// Send handles POST /auth/magic-link
+func (h *MagicLinkHandler) Send(w http.ResponseWriter, r *http.Request) {
+	var req generated.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	if err := h.magicLinkService.Send(r.Context(), req); err != nil {
+		var apiErr models.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", magicLinkRetryAfter)
+		}
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: "Check your email for a sign-in link.",
+	}
+	h.responder.RespondJSON(w, http.StatusOK, response)
+}
+
+// Verify handles POST /auth/magic-link/verify
+func (h *MagicLinkHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	// The response carries an access token
+	w.Header().Set("Cache-Control", "no-store")
+
+	var req generated.MagicLinkVerification
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	auth, err := h.magicLinkService.Verify(r.Context(), req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, auth)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type MemberHandler struct {
+	memberService *services.MemberService
+	responder     *utils.HTTPResponder
+}
+
+func NewMemberHandler(memberService *services.MemberService, responder *utils.HTTPResponder) *MemberHandler {
+	return &MemberHandler{
+		memberService: memberService,
+		responder:     responder,
+	}
+}
+
+// ListMembers handles GET /newsletters/{newsletterId}/members
+func (h *MemberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	members, err := h.memberService.ListMembers(r.Context(), user.UserID, newsletterID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, members)
+}
+
+// InviteMember handles POST /newsletters/{newsletterId}/members
+func (h *MemberHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.NewsletterMemberInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	invitation, err := h.memberService.Invite(r.Context(), user.UserID, newsletterID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, invitation)
+}
+
+// RemoveMember handles DELETE /newsletters/{newsletterId}/members/{editorId}
+func (h *MemberHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "editorId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid editor ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.memberService.RemoveMember(r.Context(), user.UserID, newsletterID, memberID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation handles POST /me/invitations/accept
+func (h *MemberHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.InvitationAcceptance
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	member, err := h.memberService.Accept(r.Context(), user, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, member)
+}
@@ -3,25 +3,25 @@ package handlers
 import (
 	"encoding/json"
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/services"
 	"go-newsletter/internal/utils"
 	"go-newsletter/pkg/generated"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type NewsletterHandler struct {
-	service        *services.NewsletterService
-	profileService *services.ProfileService
-	responder      *utils.HTTPResponder
+	service   *services.NewsletterService
+	responder *utils.HTTPResponder
 }
 
-func NewNewsletterHandler(service *services.NewsletterService, profileService *services.ProfileService, responder *utils.HTTPResponder) *NewsletterHandler {
+func NewNewsletterHandler(service *services.NewsletterService, responder *utils.HTTPResponder) *NewsletterHandler {
 	return &NewsletterHandler{
-		service:        service,
-		profileService: profileService,
-		responder:      responder,
+		service:   service,
+		responder: responder,
 	}
 }
 
@@ -32,12 +32,26 @@ func (h *NewsletterHandler) GetNewslettersOwnedByEditor(w http.ResponseWriter, r
 		return
 	}
 
-	newsletters, err := h.service.GetNewslettersOwnedByEditor(r.Context(), user.UserID.String())
+	var includes []generated.GetNewslettersParamsInclude
+	if raw := r.URL.Query().Get("include"); raw != "" {
+		for _, include := range strings.Split(raw, ",") {
+			includes = append(includes, generated.GetNewslettersParamsInclude(strings.TrimSpace(include)))
+		}
+	}
+
+	page, err := pagination.FromRequest(r)
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
 	}
 
+	newsletters, next, err := h.service.GetNewslettersOwnedByEditorWithIncludes(r.Context(), user.UserID.String(), includes, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
 	h.responder.RespondJSON(w, http.StatusOK, newsletters)
 }
 
@@ -54,7 +68,7 @@ func (h *NewsletterHandler) GetNewsletterByID(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	newsletter, err := h.service.GetNewsletterByIDCheckOwnership(r.Context(), newsletterID, user.UserID.String())
+	newsletter, err := h.service.GetNewsletterForMember(r.Context(), newsletterID, user.UserID.String())
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
@@ -117,76 +131,90 @@ func (h *NewsletterHandler) PutNewsletters(w http.ResponseWriter, r *http.Reques
 	h.responder.RespondJSON(w, http.StatusOK, newsletter)
 }
 
-func (h *NewsletterHandler) DeleteNewsletter(w http.ResponseWriter, r *http.Request) {
+// ExportConfigBundle handles GET /newsletters/{newsletterId}/config-bundle
+func (h *NewsletterHandler) ExportConfigBundle(w http.ResponseWriter, r *http.Request) {
 	user, ok := services.GetUserFromContext(r.Context())
 	if !ok {
 		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
 		return
 	}
 
-	newsletterID := chi.URLParam(r, "newsletterId")
-	if newsletterID == "" {
-		h.responder.HandleError(w, r, models.NewBadRequestError("Newsletter ID is required"))
-		return
-	}
-
-	if err := h.service.DeleteNewsletter(r.Context(), user.UserID.String(), newsletterID); err != nil {
-		if models.IsNotFoundError(err) {
-			h.responder.HandleError(w, r, models.NewNotFoundError("Newsletter not found"))
-			return
-		}
+	bundle, err := h.service.ExportConfigBundle(r.Context(), user.UserID.String(), chi.URLParam(r, "newsletterId"))
+	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	h.responder.RespondJSON(w, http.StatusOK, bundle)
 }
 
-func (h *NewsletterHandler) GetAllNewsletters(w http.ResponseWriter, r *http.Request) {
+// ImportConfigBundle handles PUT /newsletters/{newsletterId}/config-bundle
+func (h *NewsletterHandler) ImportConfigBundle(w http.ResponseWriter, r *http.Request) {
 	user, ok := services.GetUserFromContext(r.Context())
 	if !ok {
 		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
 		return
 	}
 
-	profile, err := h.profileService.GetProfileByID(r.Context(), user.UserID.String())
-	if err != nil {
-		h.responder.HandleError(w, r, err)
-		return
-	}
-
-	if profile.IsAdmin == nil || !*profile.IsAdmin {
-		h.responder.HandleError(w, r, models.NewForbiddenError("Admin access required"))
+	var req generated.NewsletterConfigBundle
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
 		return
 	}
 
-	newsletters, err := h.service.AdminGetAllNewsletters(r.Context())
+	newsletter, err := h.service.ImportConfigBundle(r.Context(), user.UserID.String(), chi.URLParam(r, "newsletterId"), req)
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
 	}
 
-	h.responder.RespondJSON(w, http.StatusOK, newsletters)
+	h.responder.RespondJSON(w, http.StatusOK, newsletter)
 }
 
-func (h *NewsletterHandler) DeleteNewsletterByID(w http.ResponseWriter, r *http.Request) {
+func (h *NewsletterHandler) DeleteNewsletter(w http.ResponseWriter, r *http.Request) {
 	user, ok := services.GetUserFromContext(r.Context())
 	if !ok {
 		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
 		return
 	}
 
-	profile, err := h.profileService.GetProfileByID(r.Context(), user.UserID.String())
+	newsletterID := chi.URLParam(r, "newsletterId")
+	if newsletterID == "" {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Newsletter ID is required"))
+		return
+	}
+
+	if err := h.service.DeleteNewsletter(r.Context(), user.UserID.String(), newsletterID); err != nil {
+		if models.IsNotFoundError(err) {
+			h.responder.HandleError(w, r, models.NewNotFoundError("Newsletter not found"))
+			return
+		}
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NewsletterHandler) GetAllNewsletters(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.FromRequest(r)
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
 	}
 
-	if profile.IsAdmin == nil || !*profile.IsAdmin {
-		h.responder.HandleError(w, r, models.NewForbiddenError("Admin access required"))
+	newsletters, next, err := h.service.AdminGetAllNewsletters(r.Context(), page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
 		return
 	}
 
+	pagination.SetNextCursor(w, next)
+
+	h.responder.RespondJSON(w, http.StatusOK, newsletters)
+}
+
+func (h *NewsletterHandler) DeleteNewsletterByID(w http.ResponseWriter, r *http.Request) {
 	newsletterID := chi.URLParam(r, "newsletterId")
 	if newsletterID == "" {
 		h.responder.HandleError(w, r, models.NewBadRequestError("Newsletter ID is required"))
@@ -204,3 +232,32 @@ func (h *NewsletterHandler) DeleteNewsletterByID(w http.ResponseWriter, r *http.
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetDeletedNewsletters handles GET /admin/newsletters/deleted
+func (h *NewsletterHandler) GetDeletedNewsletters(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	newsletters, next, err := h.service.AdminGetDeletedNewsletters(r.Context(), page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, newsletters)
+}
+
+// RestoreNewsletter handles POST /admin/newsletters/{newsletterId}/restore
+func (h *NewsletterHandler) RestoreNewsletter(w http.ResponseWriter, r *http.Request) {
+	newsletter, err := h.service.AdminRestoreNewsletter(r.Context(), chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, newsletter)
+}
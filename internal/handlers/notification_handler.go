@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+	responder           *utils.HTTPResponder
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService, responder *utils.HTTPResponder) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		responder:           responder,
+	}
+}
+
+// NotifySubscribers handles POST /admin/newsletters/{newsletterId}/notify-subscribers
+func (h *NotificationHandler) NotifySubscribers(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.SubscriberNotificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	notification, err := h.notificationService.NotifySubscribers(r.Context(), user.UserID, newsletterID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusAccepted, notification)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxOAuthFormBytes bounds the form bodies of the token and introspection endpoints
+const maxOAuthFormBytes = 64 << 10
+
+type OAuthHandler struct {
+	oauthService *services.OAuthService
+	responder    *utils.HTTPResponder
+}
+
+func NewOAuthHandler(oauthService *services.OAuthService, responder *utils.HTTPResponder) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		responder:    responder,
+	}
+}
+
+// Token handles POST /oauth/token
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	// Tokens must never be cached (RFC 6749, section 5.1), errors included
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	creds, err := h.parseForm(w, r)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	token, err := h.oauthService.IssueToken(r.Context(), creds, r.PostForm.Get("grant_type"), r.PostForm.Get("scope"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, token)
+}
+
+// Introspect handles POST /oauth/introspect
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	creds, err := h.parseForm(w, r)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	introspection, err := h.oauthService.Introspect(r.Context(), creds, r.PostForm.Get("token"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, introspection)
+}
+
+// parseForm reads the form body and the client credentials, from HTTP Basic authentication or
+// from the form; clients must use only one of them (RFC 6749, section 2.3)
+func (h *OAuthHandler) parseForm(w http.ResponseWriter, r *http.Request) (services.ClientCredentials, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxOAuthFormBytes)
+	if err := r.ParseForm(); err != nil {
+		return services.ClientCredentials{}, &services.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request", Description: "Invalid form body"}
+	}
+
+	formCreds := services.ClientCredentials{
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return formCreds, nil
+	}
+	if formCreds.ClientSecret != "" {
+		return services.ClientCredentials{}, &services.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request", Description: "Send the client credentials either with HTTP Basic authentication or in the form, not both"}
+	}
+
+	// Basic credentials are form-encoded before they are joined (RFC 6749, section 2.3.1)
+	clientID, errID := url.QueryUnescape(user)
+	secret, errSecret := url.QueryUnescape(pass)
+	if errID != nil || errSecret != nil {
+		return services.ClientCredentials{}, &services.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request", Description: "Malformed HTTP Basic credentials"}
+	}
+	return services.ClientCredentials{ClientID: clientID, ClientSecret: secret}, nil
+}
+
+// handleError answers OAuth errors in the OAuth2 format and anything else like every other route
+func (h *OAuthHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	if oauthErr.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	body := generated.OAuthError{Error: oauthErr.Code}
+	if oauthErr.Description != "" {
+		body.ErrorDescription = &oauthErr.Description
+	}
+	h.responder.RespondJSON(w, oauthErr.Status, body)
+}
+
+// sessionUser returns the signed-in editor. Clients are managed with a session only, so a
+// leaked token or key cannot be used to register clients or revoke the editor's others.
+func (h *OAuthHandler) sessionUser(w http.ResponseWriter, r *http.Request) (*services.UserContext, bool) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return nil, false
+	}
+	if user.Automated() {
+		h.responder.HandleError(w, r, models.NewForbiddenError("API keys and integration tokens cannot manage integration clients, sign in instead"))
+		return nil, false
+	}
+	return user, true
+}
+
+// ListClients handles GET /me/integration-clients
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	clients, err := h.oauthService.ListClients(r.Context(), user.UserID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, clients)
+}
+
+// CreateClient handles POST /me/integration-clients
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req generated.IntegrationClientCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	client, err := h.oauthService.CreateClient(r.Context(), user.UserID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, client)
+}
+
+// RevokeClient handles DELETE /me/integration-clients/{integrationClientId}
+func (h *OAuthHandler) RevokeClient(w http.ResponseWriter, r *http.Request) {
+	clientID, err := uuid.Parse(chi.URLParam(r, "integrationClientId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid integration client ID"))
+		return
+	}
+
+	user, ok := h.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.oauthService.RevokeClient(r.Context(), user.UserID, clientID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+)
+
+type OnboardingHandler struct {
+	onboardingService *services.OnboardingService
+	responder         *utils.HTTPResponder
+}
+
+func NewOnboardingHandler(onboardingService *services.OnboardingService, responder *utils.HTTPResponder) *OnboardingHandler {
+	return &OnboardingHandler{
+		onboardingService: onboardingService,
+		responder:         responder,
+	}
+}
+
+// GetChecklist handles GET /me/onboarding
+func (h *OnboardingHandler) GetChecklist(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	checklist, err := h.onboardingService.GetChecklist(r.Context(), user.UserID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, checklist)
+}
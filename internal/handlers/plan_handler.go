@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PlanHandler struct {
+	planService   *services.PlanService
+	couponService *services.CouponService
+	responder     *utils.HTTPResponder
+}
+
+func NewPlanHandler(planService *services.PlanService, couponService *services.CouponService, responder *utils.HTTPResponder) *PlanHandler {
+	return &PlanHandler{
+		planService:   planService,
+		couponService: couponService,
+		responder:     responder,
+	}
+}
+
+// GetMyPlan handles GET /me/plan
+func (h *PlanHandler) GetMyPlan(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	usage, err := h.planService.GetPlanUsage(r.Context(), user.UserID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, usage)
+}
+
+// ListPlans handles GET /admin/plans
+func (h *PlanHandler) ListPlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := h.planService.ListPlans(r.Context())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, plans)
+}
+
+// AssignPlan handles PUT /admin/users/{userId}/plan
+func (h *PlanHandler) AssignPlan(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	var req generated.PlanAssignment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	usage, err := h.planService.AssignPlan(r.Context(), userID, req.PlanId)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, usage)
+}
+
+// ListCoupons handles GET /admin/coupons
+func (h *PlanHandler) ListCoupons(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	coupons, next, err := h.couponService.ListCoupons(r.Context(), page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+
+	h.responder.RespondJSON(w, http.StatusOK, coupons)
+}
+
+// CreateCoupon handles POST /admin/coupons
+func (h *PlanHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.CouponCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	coupon, err := h.couponService.CreateCoupon(r.Context(), user.UserID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, coupon)
+}
+
+// RedeemCoupon handles POST /me/coupons/redeem
+func (h *PlanHandler) RedeemCoupon(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.CouponRedemption
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	usage, err := h.couponService.RedeemCoupon(r.Context(), user.UserID, req.Code)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, usage)
+}
+
+// ExtendTrial handles POST /admin/users/{userId}/trial
+func (h *PlanHandler) ExtendTrial(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	var req generated.TrialExtension
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	usage, err := h.couponService.ExtendTrial(r.Context(), user.UserID, userID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, usage)
+}
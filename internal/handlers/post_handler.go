@@ -2,16 +2,29 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/services"
 	"go-newsletter/internal/utils"
 	"go-newsletter/pkg/generated"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// maxPostImportBytes bounds the zip archives of POST /newsletters/{newsletterId}/posts/import
+const maxPostImportBytes = 32 << 20
+
+// postImportTimeout replaces the server's read and write timeouts for archive imports, which
+// take longer to upload and to store than a regular request
+const postImportTimeout = 5 * time.Minute
+
 type PostHandler struct {
 	postService *services.PostService
 	responder   *utils.HTTPResponder
@@ -40,13 +53,20 @@ func (h *PostHandler) GetPostsByNewsletterId(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
 	// Get posts
-	posts, err := h.postService.GetPostsByNewsletterId(r.Context(), newsletterID, user.UserID.String(), published)
+	posts, next, err := h.postService.GetPostsByNewsletterId(r.Context(), newsletterID, user.UserID.String(), published, page)
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
 	}
 
+	pagination.SetNextCursor(w, next)
 	h.responder.RespondJSON(w, http.StatusOK, posts)
 }
 
@@ -111,7 +131,7 @@ func (h *PostHandler) DeletePostById(w http.ResponseWriter, r *http.Request) {
 func (h *PostHandler) PostPost(w http.ResponseWriter, r *http.Request) {
 	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
 	if err != nil {
-		http.Error(w, "Invalid newsletter ID", http.StatusBadRequest)
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
 		return
 	}
 
@@ -139,13 +159,13 @@ func (h *PostHandler) PostPost(w http.ResponseWriter, r *http.Request) {
 func (h *PostHandler) PutPost(w http.ResponseWriter, r *http.Request) {
 	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
 	if err != nil {
-		http.Error(w, "Invalid newsletter ID", http.StatusBadRequest)
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
 		return
 	}
 
 	postId, err := uuid.Parse(chi.URLParam(r, "postId"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
 		return
 	}
 
@@ -169,3 +189,366 @@ func (h *PostHandler) PutPost(w http.ResponseWriter, r *http.Request) {
 
 	h.responder.RespondJSON(w, http.StatusOK, post)
 }
+
+// GetDeliveryStats handles GET /newsletters/{newsletterId}/posts/{postId}/delivery
+func (h *PostHandler) GetDeliveryStats(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	postId, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	stats, err := h.postService.GetPostDeliveryStats(r.Context(), user.UserID, newsletterID, postId)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, stats)
+}
+
+// GetStats handles GET /newsletters/{newsletterId}/posts/{postId}/stats
+func (h *PostHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	postId, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	stats, err := h.postService.GetPostEngagementStats(r.Context(), user.UserID, newsletterID, postId)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, stats)
+}
+
+// GetDeliveryReport handles GET /newsletters/{newsletterId}/posts/{postId}/delivery-report
+func (h *PostHandler) GetDeliveryReport(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	postId, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	report, next, err := h.postService.GetPostDeliveryReport(r.Context(), user.UserID, newsletterID, postId, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, report)
+}
+
+// GetPreflight handles GET /newsletters/{newsletterId}/posts/{postId}/preflight
+func (h *PostHandler) GetPreflight(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	report, err := h.postService.PreflightPost(r.Context(), user.UserID, newsletterID, postID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, report)
+}
+
+// ImportArchive handles POST /newsletters/{newsletterId}/posts/import
+func (h *PostHandler) ImportArchive(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	// Writers that cannot change the deadlines keep the server's timeouts
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(postImportTimeout))
+	rc.SetWriteDeadline(time.Now().Add(postImportTimeout))
+	archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPostImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.responder.HandleError(w, r, models.NewBadRequestError(fmt.Sprintf("The archive is larger than %d MB", maxPostImportBytes>>20)))
+			return
+		}
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	result, err := h.postService.ImportArchive(r.Context(), user.UserID, newsletterID, archive)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, result)
+}
+
+// GetDrafts handles GET /newsletters/{newsletterId}/drafts
+func (h *PostHandler) GetDrafts(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	drafts, next, err := h.postService.GetDrafts(r.Context(), newsletterID, user.UserID.String(), page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, drafts)
+}
+
+// GetDraft handles GET /newsletters/{newsletterId}/drafts/{postId}
+func (h *PostHandler) GetDraft(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	draft, err := h.postService.GetDraft(r.Context(), newsletterID, postID, user.UserID.String())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, draft)
+}
+
+// PostDraft handles POST /newsletters/{newsletterId}/drafts
+func (h *PostHandler) PostDraft(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.DraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	draft, err := h.postService.CreateDraft(r.Context(), user.UserID, newsletterID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, draft)
+}
+
+// PutDraft handles PUT /newsletters/{newsletterId}/drafts/{postId}
+func (h *PostHandler) PutDraft(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.DraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	draft, err := h.postService.UpdateDraft(r.Context(), user.UserID, newsletterID, postID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, draft)
+}
+
+// DeleteDraft handles DELETE /newsletters/{newsletterId}/drafts/{postId}
+func (h *PostHandler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.postService.DeleteDraft(r.Context(), user.UserID, newsletterID, postID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PublishDraft handles POST /newsletters/{newsletterId}/drafts/{postId}/publish. The body is
+// optional; without one the draft is published immediately.
+func (h *PostHandler) PublishDraft(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.PublishDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	post, err := h.postService.PublishDraft(r.Context(), user.UserID, newsletterID, postID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, post)
+}
+
+// parseNewsletterPostIDs reads the newsletterId and postId path parameters
+func parseNewsletterPostIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, models.NewBadRequestError("Invalid newsletter ID")
+	}
+	postID, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, models.NewBadRequestError("Invalid post ID")
+	}
+	return newsletterID, postID, nil
+}
+
+// RepublishPost handles POST /admin/posts/{postId}/republish
+func (h *PostHandler) RepublishPost(w http.ResponseWriter, r *http.Request) {
+	postId, err := uuid.Parse(chi.URLParam(r, "postId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid post ID"))
+		return
+	}
+
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		dryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			h.responder.HandleError(w, r, models.NewBadRequestError("dry_run must be a boolean"))
+			return
+		}
+	}
+
+	result, err := h.postService.RepublishPost(r.Context(), postId, dryRun)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, result)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/services"
 	"go-newsletter/internal/utils"
 	"go-newsletter/pkg/generated"
@@ -52,7 +53,13 @@ func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 
 // GetAllProfiles handles GET /profiles
 func (h *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request) {
-	profiles, err := h.service.GetAllProfiles(r.Context())
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	profiles, next, err := h.service.GetAllProfiles(r.Context(), page)
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
@@ -64,6 +71,7 @@ func (h *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request)
 		editorProfiles = append(editorProfiles, utils.ProfileToEditorProfile(profileCopy))
 	}
 
+	pagination.SetNextCursor(w, next)
 	h.responder.RespondJSON(w, http.StatusOK, editorProfiles)
 }
 
@@ -130,24 +138,6 @@ func (h *ProfileHandler) PutMe(w http.ResponseWriter, r *http.Request) {
 
 // GrantAdmin handles PUT /admin/users/{userId}/grant-admin endpoint
 func (h *ProfileHandler) GrantAdmin(w http.ResponseWriter, r *http.Request) {
-	// Get authenticated user from context
-	user, ok := services.GetUserFromContext(r.Context())
-	if !ok {
-		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
-		return
-	}
-
-	// Check if user is admin
-	profile, err := h.service.GetProfileByID(r.Context(), user.UserID.String())
-	if err != nil {
-		h.responder.HandleError(w, r, err)
-		return
-	}
-	if profile.IsAdmin == nil || !*profile.IsAdmin {
-		h.responder.HandleError(w, r, models.NewForbiddenError("Admin privileges required"))
-		return
-	}
-
 	// Get target user ID from URL
 	id := chi.URLParam(r, "userId")
 	if id == "" {
@@ -167,24 +157,6 @@ func (h *ProfileHandler) GrantAdmin(w http.ResponseWriter, r *http.Request) {
 
 // RevokeAdmin handles PUT /admin/users/{userId}/revoke-admin endpoint
 func (h *ProfileHandler) RevokeAdmin(w http.ResponseWriter, r *http.Request) {
-	// Get authenticated user from context
-	user, ok := services.GetUserFromContext(r.Context())
-	if !ok {
-		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
-		return
-	}
-
-	// Check if user is admin
-	profile, err := h.service.GetProfileByID(r.Context(), user.UserID.String())
-	if err != nil {
-		h.responder.HandleError(w, r, err)
-		return
-	}
-	if profile.IsAdmin == nil || !*profile.IsAdmin {
-		h.responder.HandleError(w, r, models.NewForbiddenError("Admin privileges required"))
-		return
-	}
-
 	// Get target user ID from URL
 	id := chi.URLParam(r, "userId")
 	if id == "" {
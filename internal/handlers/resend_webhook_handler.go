@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"io"
+	"net/http"
+)
+
+// maxResendEventSize bounds the body of a Resend webhook; events are a few kilobytes
+const maxResendEventSize = 1 << 20
+
+type ResendWebhookHandler struct {
+	resendWebhookService *services.ResendWebhookService
+	responder            *utils.HTTPResponder
+}
+
+func NewResendWebhookHandler(resendWebhookService *services.ResendWebhookService, responder *utils.HTTPResponder) *ResendWebhookHandler {
+	return &ResendWebhookHandler{
+		resendWebhookService: resendWebhookService,
+		responder:            responder,
+	}
+}
+
+// ReceiveEvent handles POST /webhooks/resend
+func (h *ResendWebhookHandler) ReceiveEvent(w http.ResponseWriter, r *http.Request) {
+	// The signature covers the raw body, so it is read before decoding
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxResendEventSize))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	if err := h.resendWebhookService.Verify(r.Header.Get("svix-id"), r.Header.Get("svix-timestamp"), r.Header.Get("svix-signature"), body); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	if err := h.resendWebhookService.HandleEvent(r.Context(), body); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+)
+
+type RetentionHandler struct {
+	retentionService *services.RetentionService
+	responder        *utils.HTTPResponder
+}
+
+func NewRetentionHandler(retentionService *services.RetentionService, responder *utils.HTTPResponder) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+		responder:        responder,
+	}
+}
+
+// GetUnconfirmedReport handles GET /admin/retention/unconfirmed
+func (h *RetentionHandler) GetUnconfirmedReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.retentionService.GetUnconfirmedReport(r.Context())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, report)
+}
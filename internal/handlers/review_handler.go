@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ReviewHandler struct {
+	reviewService *services.ReviewService
+	responder     *utils.HTTPResponder
+}
+
+func NewReviewHandler(reviewService *services.ReviewService, responder *utils.HTTPResponder) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		responder:     responder,
+	}
+}
+
+// GetReview handles GET /newsletters/{newsletterId}/drafts/{postId}/review
+func (h *ReviewHandler) GetReview(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	review, err := h.reviewService.GetReview(r.Context(), user.UserID, newsletterID, postID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, review)
+}
+
+// Submit handles POST /newsletters/{newsletterId}/drafts/{postId}/review/submit
+func (h *ReviewHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	review, err := h.reviewService.Submit(r.Context(), user.UserID, newsletterID, postID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, review)
+}
+
+// Approve handles POST /newsletters/{newsletterId}/drafts/{postId}/review/approve. The body is
+// optional; without one the draft is approved without a comment.
+func (h *ReviewHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.PostReviewDecision
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	review, err := h.reviewService.Approve(r.Context(), user.UserID, newsletterID, postID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, review)
+}
+
+// Reject handles POST /newsletters/{newsletterId}/drafts/{postId}/review/reject
+func (h *ReviewHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.PostReviewDecision
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid JSON payload"))
+		return
+	}
+
+	review, err := h.reviewService.Reject(r.Context(), user.UserID, newsletterID, postID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, review)
+}
+
+// GetQueue handles GET /newsletters/{newsletterId}/review-queue
+func (h *ReviewHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	items, next, err := h.reviewService.Queue(r.Context(), user.UserID, newsletterID, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, items)
+}
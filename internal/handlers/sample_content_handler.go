@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SampleContentHandler struct {
+	sampleContentService *services.SampleContentService
+	responder            *utils.HTTPResponder
+}
+
+func NewSampleContentHandler(sampleContentService *services.SampleContentService, responder *utils.HTTPResponder) *SampleContentHandler {
+	return &SampleContentHandler{
+		sampleContentService: sampleContentService,
+		responder:            responder,
+	}
+}
+
+// CreateSampleContent handles POST /newsletters/{newsletterId}/sample-content
+func (h *SampleContentHandler) CreateSampleContent(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	content, err := h.sampleContentService.CreateSampleContent(r.Context(), user.UserID, newsletterID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, content)
+}
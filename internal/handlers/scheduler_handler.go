@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"go-newsletter/internal/scheduler"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+)
+
+type SchedulerHandler struct {
+	publisher *scheduler.PostPublisher
+	responder *utils.HTTPResponder
+}
+
+func NewSchedulerHandler(publisher *scheduler.PostPublisher, responder *utils.HTTPResponder) *SchedulerHandler {
+	return &SchedulerHandler{
+		publisher: publisher,
+		responder: responder,
+	}
+}
+
+// GetStatus handles GET /admin/scheduler/status
+func (h *SchedulerHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.publisher.Status(r.Context())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, status)
+}
+
+// Run handles POST /admin/scheduler/run
+func (h *SchedulerHandler) Run(w http.ResponseWriter, r *http.Request) {
+	result, err := h.publisher.RunNow(r.Context())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, generated.SchedulerRunResult{
+		StartedAt:    &result.StartedAt,
+		FinishedAt:   &result.FinishedAt,
+		LockAcquired: &result.LockAcquired,
+		Published:    &result.Published,
+		Failed:       &result.Failed,
+		Skipped:      &result.Skipped,
+	})
+}
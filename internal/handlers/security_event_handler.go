@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+)
+
+type SecurityEventHandler struct {
+	securityEventService *services.SecurityEventService
+	responder            *utils.HTTPResponder
+}
+
+func NewSecurityEventHandler(securityEventService *services.SecurityEventService, responder *utils.HTTPResponder) *SecurityEventHandler {
+	return &SecurityEventHandler{
+		securityEventService: securityEventService,
+		responder:            responder,
+	}
+}
+
+// List handles GET /me/security-events
+func (h *SecurityEventHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	events, next, err := h.securityEventService.List(r.Context(), user.UserID, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, events)
+}
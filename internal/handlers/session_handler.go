@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// refreshRetryAfter is the Retry-After, in seconds, when Supabase refuses to refresh the
+// session yet
+const refreshRetryAfter = "30"
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+	responder      *utils.HTTPResponder
+}
+
+func NewSessionHandler(sessionService *services.SessionService, responder *utils.HTTPResponder) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		responder:      responder,
+	}
+}
+
+// Refresh handles POST /auth/refresh
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	// The response carries an access token
+	w.Header().Set("Cache-Control", "no-store")
+
+	var req generated.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	auth, err := h.sessionService.Refresh(r.Context(), req)
+	if err != nil {
+		var apiErr models.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", refreshRetryAfter)
+		}
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, auth)
+}
+
+// RevokeSessions handles POST /admin/users/{userId}/revoke-sessions
+func (h *SessionHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	var req generated.SessionRevocation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	result, err := h.sessionService.RevokeSessions(r.Context(), user.UserID, userID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, result)
+}
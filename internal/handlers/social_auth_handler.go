@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// oauthVerifierCookie keeps the PKCE verifier of a sign-in in the browser that started it
+	oauthVerifierCookie = "oauth_verifier"
+	// oauthVerifierMaxAge is how long the browser has to sign in with the provider, in seconds
+	oauthVerifierMaxAge = 10 * 60
+)
+
+type SocialAuthHandler struct {
+	socialAuthService *services.SocialAuthService
+	secureCookie      bool
+	responder         *utils.HTTPResponder
+}
+
+func NewSocialAuthHandler(socialAuthService *services.SocialAuthService, secureCookie bool, responder *utils.HTTPResponder) *SocialAuthHandler {
+	return &SocialAuthHandler{
+		socialAuthService: socialAuthService,
+		secureCookie:      secureCookie,
+		responder:         responder,
+	}
+}
+
+// Start handles GET /auth/oauth/{provider}/start
+func (h *SocialAuthHandler) Start(w http.ResponseWriter, r *http.Request) {
+	signIn, err := h.socialAuthService.Start(chi.URLParam(r, "provider"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.setVerifierCookie(w, signIn.Verifier, oauthVerifierMaxAge)
+	http.Redirect(w, r, signIn.URL, http.StatusFound)
+}
+
+// Callback handles GET /auth/oauth/{provider}/callback
+func (h *SocialAuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	// The response carries an access token; the verifier is single-use either way
+	w.Header().Set("Cache-Control", "no-store")
+	h.setVerifierCookie(w, "", -1)
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		message := "Sign-in with the identity provider was cancelled or refused"
+		if description := query.Get("error_description"); description != "" {
+			message += ": " + description
+		}
+		h.responder.HandleError(w, r, models.NewUnauthorizedError(message))
+		return
+	}
+
+	cookie, err := r.Cookie(oauthVerifierCookie)
+	if err != nil || cookie.Value == "" {
+		h.responder.HandleError(w, r, models.NewBadRequestError("The sign-in expired or was started in another browser, please start again"))
+		return
+	}
+
+	auth, err := h.socialAuthService.Complete(r.Context(), chi.URLParam(r, "provider"), query.Get("code"), cookie.Value)
+	var linkErr *services.AccountLinkRequiredError
+	if errors.As(err, &linkErr) {
+		h.responder.RespondJSON(w, http.StatusConflict, linkErr.Link)
+		return
+	}
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, auth)
+}
+
+// Link handles POST /auth/oauth/link
+func (h *SocialAuthHandler) Link(w http.ResponseWriter, r *http.Request) {
+	var req generated.AccountLinkConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	signIn, err := h.socialAuthService.LinkAccount(r.Context(), req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.setVerifierCookie(w, signIn.Verifier, oauthVerifierMaxAge)
+	h.responder.RespondJSON(w, http.StatusOK, generated.AccountLinkStart{Url: signIn.URL})
+}
+
+// setVerifierCookie stores the verifier, or deletes it with a negative maxAge. Lax cookies are
+// sent on the top-level redirect back from the provider but not on cross-site requests.
+func (h *SocialAuthHandler) setVerifierCookie(w http.ResponseWriter, verifier string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthVerifierCookie,
+		Value:    verifier,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
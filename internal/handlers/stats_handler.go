@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type StatsHandler struct {
+	statsService *services.StatsService
+	responder    *utils.HTTPResponder
+}
+
+func NewStatsHandler(statsService *services.StatsService, responder *utils.HTTPResponder) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+		responder:    responder,
+	}
+}
+
+// GetSubscriberGrowth handles GET /newsletters/{newsletterId}/stats
+func (h *StatsHandler) GetSubscriberGrowth(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	query := r.URL.Query()
+	growth, err := h.statsService.GetSubscriberGrowth(r.Context(), newsletterID, user.UserID.String(), query.Get("interval"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			err = models.NewNotFoundError("Newsletter not found")
+		}
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, growth)
+}
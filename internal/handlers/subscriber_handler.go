@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/utils"
 	"net/http"
+	"strconv"
+	"time"
 
 	"go-newsletter/internal/services"
 	"go-newsletter/pkg/generated"
@@ -14,14 +19,18 @@ import (
 )
 
 type SubscriberHandler struct {
-	subscriberService *services.SubscriberService
-	responder         *utils.HTTPResponder
+	subscriberService  *services.SubscriberService
+	suppressionService *services.SuppressionService
+	hostedPages        *HostedPageHandler
+	responder          *utils.HTTPResponder
 }
 
-func NewSubscriberHandler(subscriberService *services.SubscriberService, responder *utils.HTTPResponder) *SubscriberHandler {
+func NewSubscriberHandler(subscriberService *services.SubscriberService, suppressionService *services.SuppressionService, hostedPages *HostedPageHandler, responder *utils.HTTPResponder) *SubscriberHandler {
 	return &SubscriberHandler{
-		subscriberService: subscriberService,
-		responder:         responder,
+		subscriberService:  subscriberService,
+		suppressionService: suppressionService,
+		hostedPages:        hostedPages,
+		responder:          responder,
 	}
 }
 
@@ -40,16 +49,176 @@ func (h *SubscriberHandler) ListSubscribers(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Get subscribers
-	subscribers, err := h.subscriberService.ListSubscribers(r.Context(), newsletterID, user.UserID.String())
+	// Without limit or cursor, stream subscribers straight from the database rows
+	if !pagination.Requested(r) {
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(subscriberExportTimeout))
+		h.responder.StreamJSONArray(w, r, http.StatusOK, func(emit func(v interface{}) error) error {
+			return h.subscriberService.StreamSubscribers(r.Context(), newsletterID, user.UserID.String(), func(s *generated.Subscriber) error {
+				return emit(s)
+			})
+		})
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
 	if err != nil {
 		h.responder.HandleError(w, r, err)
 		return
 	}
 
+	subscribers, next, err := h.subscriberService.ListSubscribers(r.Context(), newsletterID, user.UserID.String(), page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
 	h.responder.RespondJSON(w, http.StatusOK, subscribers)
 }
 
+// ListDeletedSubscribers handles GET /newsletters/{newsletterId}/subscribers/deleted
+func (h *SubscriberHandler) ListDeletedSubscribers(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	subscribers, next, err := h.subscriberService.ListDeletedSubscribers(r.Context(), newsletterID, user.UserID.String(), page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, subscribers)
+}
+
+// DeleteSubscriber handles DELETE /newsletters/{newsletterId}/subscribers/{subscriberId}
+func (h *SubscriberHandler) DeleteSubscriber(w http.ResponseWriter, r *http.Request) {
+	newsletterID, subscriberID, err := parseNewsletterSubscriberIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.subscriberService.DeleteSubscriber(r.Context(), newsletterID, subscriberID, user.UserID.String()); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreSubscriber handles POST /newsletters/{newsletterId}/subscribers/{subscriberId}/restore
+func (h *SubscriberHandler) RestoreSubscriber(w http.ResponseWriter, r *http.Request) {
+	newsletterID, subscriberID, err := parseNewsletterSubscriberIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	subscriber, err := h.subscriberService.RestoreSubscriber(r.Context(), newsletterID, subscriberID, user.UserID.String())
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, subscriber)
+}
+
+// MergeSubscribers handles POST /newsletters/{newsletterId}/subscribers/merge
+func (h *SubscriberHandler) MergeSubscribers(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.SubscriberMerge
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	subscriber, err := h.subscriberService.MergeSubscribers(r.Context(), newsletterID, user.UserID.String(), req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, subscriber)
+}
+
+// parseNewsletterSubscriberIDs reads the newsletterId and subscriberId path parameters
+func parseNewsletterSubscriberIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, models.NewBadRequestError("Invalid newsletter ID")
+	}
+	subscriberID, err := uuid.Parse(chi.URLParam(r, "subscriberId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, models.NewBadRequestError("Invalid subscriber ID")
+	}
+	return newsletterID, subscriberID, nil
+}
+
+// ResendConfirmations handles POST /newsletters/{newsletterId}/subscribers/resend-confirmations
+func (h *SubscriberHandler) ResendConfirmations(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	result, err := h.subscriberService.ResendPendingConfirmations(r.Context(), newsletterID, user.UserID.String())
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			err = models.NewNotFoundError("Newsletter not found")
+		}
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, result)
+}
+
 // Subscribe handles POST /newsletters/{newsletterId}/subscribe
 func (h *SubscriberHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
@@ -64,8 +233,14 @@ func (h *SubscriberHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.subscriberService.Subscribe(r.Context(), newsletterID, req.Email)
+	subscriber, err := h.subscriberService.Subscribe(r.Context(), newsletterID, req.Email)
 	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlreadySubscribed):
+			err = models.NewConflictError("Email is already subscribed to this newsletter")
+		case errors.Is(err, services.ErrNotFound):
+			err = models.NewNotFoundError("Newsletter not found")
+		}
 		h.responder.HandleError(w, r, err)
 		return
 	}
@@ -75,14 +250,32 @@ func (h *SubscriberHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 	}{
 		Message: "Subscription successful. Please check your email to confirm your subscription.",
 	}
+	// Newsletters without double opt-in confirm subscriptions at once
+	if subscriber.IsConfirmed != nil && *subscriber.IsConfirmed {
+		response.Message = "Subscription successful."
+	}
 
 	h.responder.RespondJSON(w, http.StatusOK, response)
 }
 
-// ConfirmSubscription handles the confirmation of a subscription using a token
-func (h *SubscriberHandler) ConfirmSubscription(w http.ResponseWriter, r *http.Request, confirmationToken string) {
-	err := h.subscriberService.ConfirmSubscription(r.Context(), confirmationToken)
+// ResendConfirmation handles POST /newsletters/{newsletterId}/subscribe/resend-confirmation
+func (h *SubscriberHandler) ResendConfirmation(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
 	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	var req generated.SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	if err := h.subscriberService.ResendConfirmation(r.Context(), newsletterID, req.Email); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			err = models.NewNotFoundError("Newsletter not found")
+		}
 		h.responder.HandleError(w, r, err)
 		return
 	}
@@ -90,16 +283,60 @@ func (h *SubscriberHandler) ConfirmSubscription(w http.ResponseWriter, r *http.R
 	response := struct {
 		Message string `json:"message"`
 	}{
-		Message: "Subscription confirmed successfully",
+		Message: "If the address has an unconfirmed subscription, a new confirmation email is on its way.",
 	}
+	h.responder.RespondJSON(w, http.StatusAccepted, response)
+}
 
-	h.responder.RespondJSON(w, http.StatusOK, response)
+// ConfirmSubscription handles the confirmation of a subscription using a token
+func (h *SubscriberHandler) ConfirmSubscription(w http.ResponseWriter, r *http.Request, confirmationToken string) {
+	err := h.subscriberService.ConfirmSubscription(r.Context(), confirmationToken)
+	if err != nil {
+		h.hostedPages.HandleError(w, r, err)
+		return
+	}
+
+	h.hostedPages.Respond(w, r, http.StatusOK, "Subscription confirmed", "Subscription confirmed successfully")
 }
 
 // Unsubscribe handles the unsubscription using a token
 func (h *SubscriberHandler) Unsubscribe(w http.ResponseWriter, r *http.Request, unsubscribeToken string) {
 	err := h.subscriberService.Unsubscribe(r.Context(), unsubscribeToken)
 	if err != nil {
+		h.hostedPages.HandleError(w, r, err)
+		return
+	}
+
+	h.hostedPages.Respond(w, r, http.StatusOK, "You are unsubscribed", "Successfully unsubscribed from the newsletter")
+}
+
+// UnsubscribeAll handles GET /unsubscribe-all/{token}
+func (h *SubscriberHandler) UnsubscribeAll(w http.ResponseWriter, r *http.Request, token string) {
+	err := h.suppressionService.UnsubscribeAll(r.Context(), token)
+	if err != nil {
+		h.hostedPages.HandleError(w, r, err)
+		return
+	}
+
+	h.hostedPages.Respond(w, r, http.StatusOK, "You are unsubscribed", "Successfully unsubscribed from all newsletters")
+}
+
+// RequestEmailChange handles POST /subscriptions/{unsubscribeToken}/email-change
+func (h *SubscriberHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request, unsubscribeToken string) {
+	var req generated.EmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	err := h.subscriberService.RequestEmailChange(r.Context(), unsubscribeToken, req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlreadySubscribed):
+			err = models.NewConflictError("The new email address is already subscribed to this newsletter")
+		case errors.Is(err, services.ErrNotFound):
+			err = models.NewNotFoundError("Subscription not found")
+		}
 		h.responder.HandleError(w, r, err)
 		return
 	}
@@ -107,8 +344,106 @@ func (h *SubscriberHandler) Unsubscribe(w http.ResponseWriter, r *http.Request,
 	response := struct {
 		Message string `json:"message"`
 	}{
-		Message: "Successfully unsubscribed from the newsletter",
+		Message: "Please check the new email address to confirm the change.",
 	}
 
-	h.responder.RespondJSON(w, http.StatusOK, response)
+	h.responder.RespondJSON(w, http.StatusAccepted, response)
+}
+
+// ConfirmEmailChange handles GET /subscriptions/email-change/confirm/{changeToken}
+func (h *SubscriberHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request, changeToken string) {
+	err := h.subscriberService.ConfirmEmailChange(r.Context(), changeToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlreadySubscribed):
+			err = models.NewConflictError("The new email address is already subscribed to this newsletter")
+		case errors.Is(err, services.ErrNotFound):
+			err = models.NewNotFoundError("Email change not found or already confirmed")
+		}
+		h.hostedPages.HandleError(w, r, err)
+		return
+	}
+
+	h.hostedPages.Respond(w, r, http.StatusOK, "Email address changed", "Email address changed successfully")
+}
+
+// subscriberExportTimeout replaces the server's write timeout for exports and unpaged listings,
+// which stream for longer than a regular response
+const subscriberExportTimeout = 10 * time.Minute
+
+// subscriberExportColumns are the CSV columns of an export, the properties of SubscriberExportRow
+var subscriberExportColumns = []string{"id", "email", "status", "is_confirmed", "confirmation_email_status", "subscribed_at", "unsubscribed_at", "is_sample"}
+
+// ExportSubscribers handles GET /newsletters/{newsletterId}/subscribers/export
+func (h *SubscriberHandler) ExportSubscribers(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	format := generated.GetNewslettersNewsletterIdSubscribersExportParamsFormat(r.URL.Query().Get("format"))
+	switch format {
+	case "":
+		format = generated.Csv
+	case generated.Csv, generated.Json:
+	default:
+		h.responder.HandleError(w, r, models.NewBadRequestError("format must be csv or json"))
+		return
+	}
+
+	// Writers that cannot change the deadline keep the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(subscriberExportTimeout))
+
+	export := func(fn func(row *generated.SubscriberExportRow) error) error {
+		err := h.subscriberService.ExportSubscribers(r.Context(), newsletterID, user.UserID.String(), fn)
+		if errors.Is(err, services.ErrNotFound) {
+			return models.NewNotFoundError("Newsletter not found")
+		}
+		return err
+	}
+
+	if format == generated.Json {
+		h.responder.StreamJSONArray(w, r, http.StatusOK, func(emit func(v interface{}) error) error {
+			return export(func(row *generated.SubscriberExportRow) error {
+				return emit(row)
+			})
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("subscribers-%s.csv", newsletterID)
+	h.responder.StreamCSV(w, r, http.StatusOK, filename, subscriberExportColumns, func(emit func(record []string) error) error {
+		return export(func(row *generated.SubscriberExportRow) error {
+			return emit(subscriberExportRecord(row))
+		})
+	})
+}
+
+// subscriberExportRecord formats a row in the order of subscriberExportColumns
+func subscriberExportRecord(row *generated.SubscriberExportRow) []string {
+	confirmationStatus := ""
+	if row.ConfirmationEmailStatus != nil {
+		confirmationStatus = *row.ConfirmationEmailStatus
+	}
+	unsubscribedAt := ""
+	if row.UnsubscribedAt != nil {
+		unsubscribedAt = row.UnsubscribedAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		row.Id.String(),
+		string(row.Email),
+		string(row.Status),
+		strconv.FormatBool(row.IsConfirmed),
+		confirmationStatus,
+		row.SubscribedAt.UTC().Format(time.RFC3339),
+		unsubscribedAt,
+		strconv.FormatBool(row.IsSample),
+	}
 }
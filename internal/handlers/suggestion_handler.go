@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+)
+
+type SuggestionHandler struct {
+	suggestionService *services.SuggestionService
+	responder         *utils.HTTPResponder
+}
+
+func NewSuggestionHandler(suggestionService *services.SuggestionService, responder *utils.HTTPResponder) *SuggestionHandler {
+	return &SuggestionHandler{
+		suggestionService: suggestionService,
+		responder:         responder,
+	}
+}
+
+// SuggestForPost handles POST /newsletters/{newsletterId}/posts/{postId}/suggestions
+func (h *SuggestionHandler) SuggestForPost(w http.ResponseWriter, r *http.Request) {
+	newsletterID, postID, err := parseNewsletterPostIDs(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	suggestions, err := h.suggestionService.SuggestForPost(r.Context(), user.UserID, newsletterID, postID)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, suggestions)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SuppressionHandler struct {
+	suppressionService *services.SuppressionService
+	responder          *utils.HTTPResponder
+}
+
+func NewSuppressionHandler(suppressionService *services.SuppressionService, responder *utils.HTTPResponder) *SuppressionHandler {
+	return &SuppressionHandler{
+		suppressionService: suppressionService,
+		responder:          responder,
+	}
+}
+
+// ListSuppressions handles GET /newsletters/{newsletterId}/suppressions
+func (h *SuppressionHandler) ListSuppressions(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	suppressions, next, err := h.suppressionService.ListNewsletterSuppressions(r.Context(), user.UserID, newsletterID, page)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	pagination.SetNextCursor(w, next)
+	h.responder.RespondJSON(w, http.StatusOK, suppressions)
+}
+
+// CreateSuppression handles POST /newsletters/{newsletterId}/suppressions
+func (h *SuppressionHandler) CreateSuppression(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	var req generated.NewsletterSuppressionCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid request body"))
+		return
+	}
+
+	suppression, err := h.suppressionService.BlockForNewsletter(r.Context(), user.UserID, newsletterID, req)
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusCreated, suppression)
+}
+
+// DeleteSuppression handles DELETE /newsletters/{newsletterId}/suppressions/{suppressionId}
+func (h *SuppressionHandler) DeleteSuppression(w http.ResponseWriter, r *http.Request) {
+	newsletterID, err := uuid.Parse(chi.URLParam(r, "newsletterId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid newsletter ID"))
+		return
+	}
+	suppressionID, err := uuid.Parse(chi.URLParam(r, "suppressionId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid suppression ID"))
+		return
+	}
+
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	if err := h.suppressionService.UnblockForNewsletter(r.Context(), user.UserID, newsletterID, suppressionID); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/tracking"
+	"go-newsletter/internal/utils"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// TrackingHandler serves the open pixel and click redirects of post emails, without
+// authentication
+type TrackingHandler struct {
+	trackingService *services.TrackingService
+	responder       *utils.HTTPResponder
+}
+
+func NewTrackingHandler(trackingService *services.TrackingService, responder *utils.HTTPResponder) *TrackingHandler {
+	return &TrackingHandler{
+		trackingService: trackingService,
+		responder:       responder,
+	}
+}
+
+// Open handles GET /t/open/{messageId}
+func (h *TrackingHandler) Open(w http.ResponseWriter, r *http.Request) {
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid message ID"))
+		return
+	}
+
+	h.trackingService.RecordOpen(r.Context(), messageID)
+
+	// Every load must reach the server to be counted
+	w.Header().Set("Cache-Control", "no-store, max-age=0")
+	w.Header().Set("Content-Type", "image/gif")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(tracking.Pixel)
+}
+
+// Click handles GET /t/click/{messageId}
+func (h *TrackingHandler) Click(w http.ResponseWriter, r *http.Request) {
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid message ID"))
+		return
+	}
+
+	query := r.URL.Query()
+	target, err := h.trackingService.RecordClick(r.Context(), messageID, query.Get("url"), query.Get("sig"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store, max-age=0")
+	http.Redirect(w, r, target, http.StatusFound)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsageHandler struct {
+	usageService   *services.UsageService
+	profileService *services.ProfileService
+	responder      *utils.HTTPResponder
+}
+
+func NewUsageHandler(usageService *services.UsageService, profileService *services.ProfileService, responder *utils.HTTPResponder) *UsageHandler {
+	return &UsageHandler{
+		usageService:   usageService,
+		profileService: profileService,
+		responder:      responder,
+	}
+}
+
+// GetMyUsage handles GET /me/usage
+func (h *UsageHandler) GetMyUsage(w http.ResponseWriter, r *http.Request) {
+	user, ok := services.GetUserFromContext(r.Context())
+	if !ok {
+		h.responder.HandleError(w, r, models.NewUnauthorizedError("User not authenticated"))
+		return
+	}
+
+	usage, err := h.usageService.GetUsage(r.Context(), user.UserID, r.URL.Query().Get("period"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, usage)
+}
+
+// GetUserUsage handles GET /admin/users/{userId}/usage
+func (h *UsageHandler) GetUserUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		h.responder.HandleError(w, r, models.NewBadRequestError("Invalid user ID"))
+		return
+	}
+
+	if _, err := h.profileService.GetProfileByID(r.Context(), userID.String()); err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	usage, err := h.usageService.GetUsage(r.Context(), userID, r.URL.Query().Get("period"))
+	if err != nil {
+		h.responder.HandleError(w, r, err)
+		return
+	}
+
+	h.responder.RespondJSON(w, http.StatusOK, usage)
+}
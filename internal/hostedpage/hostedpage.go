@@ -0,0 +1,254 @@
+// Package hostedpage renders the HTML pages subscribers see when they open a link from an
+// email in a browser, like the confirmation and unsubscribe links, and the errors of those
+// links. Pages carry the branding of the domain they are served on, so newsletters on custom
+// domains show their own logo, colors and contact link.
+package hostedpage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-newsletter/internal/assets"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxCachedPages bounds the rendered pages kept in memory. Pages differ only by domain, status
+// and message, all coming from configuration and code, so the bound is rarely reached.
+const maxCachedPages = 512
+
+// Branding is how pages of a domain look
+type Branding struct {
+	// Name is shown as the page header and in the page title
+	Name string `yaml:"name"`
+	// LogoURL is an https image shown above the name
+	LogoURL string `yaml:"logo_url"`
+	// PrimaryColor is a hex color for the header and links
+	PrimaryColor string `yaml:"primary_color"`
+	// BackgroundColor is a hex color for the page background
+	BackgroundColor string `yaml:"background_color"`
+	// ContactURL is an https or mailto: link subscribers can reach the sender at
+	ContactURL string `yaml:"contact_url"`
+}
+
+// DefaultBranding is used for domains without branding when no default is configured
+var DefaultBranding = Branding{
+	Name:            "Newsletter",
+	PrimaryColor:    "#1f2937",
+	BackgroundColor: "#f3f4f6",
+}
+
+// Page is the content of a page
+type Page struct {
+	Status  int
+	Title   string
+	Message string
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// pageTemplate is the default hosted page; its stylesheet is the built-in hosted.css
+var pageTemplate = template.Must(template.ParseFS(assets.Templates, "hosted_page.html"))
+
+// Pages renders hosted pages with the branding of the domain they are requested on. Rendered
+// pages are cached, so serving a page again does not execute the template.
+type Pages struct {
+	fallback Branding
+	domains  map[string]Branding
+
+	mu    sync.RWMutex
+	cache map[cacheKey][]byte
+}
+
+type cacheKey struct {
+	host string
+	page Page
+}
+
+// Load reads the branding file at path. Without a path every page uses DefaultBranding.
+func Load(path string) (*Pages, error) {
+	if path == "" {
+		return New(DefaultBranding, nil)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosted page branding: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads the branding of hosted pages from YAML: the default branding, and the branding
+// of custom domains keyed by host name
+func Parse(data []byte) (*Pages, error) {
+	var file struct {
+		Default *Branding           `yaml:"default"`
+		Domains map[string]Branding `yaml:"domains"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid hosted page branding: %w", err)
+	}
+	fallback := DefaultBranding
+	if file.Default != nil {
+		fallback = *file.Default
+	}
+	return New(fallback, file.Domains)
+}
+
+// New creates Pages with the fallback branding and the branding of custom domains. Missing
+// names and colors of a domain are taken from the fallback.
+func New(fallback Branding, domains map[string]Branding) (*Pages, error) {
+	fallback = fallback.withDefaults(DefaultBranding)
+	if err := fallback.validate(); err != nil {
+		return nil, fmt.Errorf("default hosted page branding: %w", err)
+	}
+
+	pages := &Pages{
+		fallback: fallback,
+		domains:  make(map[string]Branding, len(domains)),
+		cache:    make(map[cacheKey][]byte),
+	}
+	for host, branding := range domains {
+		branding = branding.withDefaults(fallback)
+		if err := branding.validate(); err != nil {
+			return nil, fmt.Errorf("hosted page branding of %q: %w", host, err)
+		}
+		pages.domains[normalizeHost(host)] = branding
+	}
+	return pages, nil
+}
+
+func (b Branding) withDefaults(fallback Branding) Branding {
+	if b.Name == "" {
+		b.Name = fallback.Name
+	}
+	if b.PrimaryColor == "" {
+		b.PrimaryColor = fallback.PrimaryColor
+	}
+	if b.BackgroundColor == "" {
+		b.BackgroundColor = fallback.BackgroundColor
+	}
+	return b
+}
+
+func (b Branding) validate() error {
+	if !hexColor.MatchString(b.PrimaryColor) {
+		return fmt.Errorf("primary_color %q is not a hex color like #1f2937", b.PrimaryColor)
+	}
+	if !hexColor.MatchString(b.BackgroundColor) {
+		return fmt.Errorf("background_color %q is not a hex color like #f3f4f6", b.BackgroundColor)
+	}
+	if b.LogoURL != "" && !hasScheme(b.LogoURL, "https") {
+		return fmt.Errorf("logo_url must be an https URL")
+	}
+	if b.ContactURL != "" && !hasScheme(b.ContactURL, "https", "mailto") {
+		return fmt.Errorf("contact_url must be an https or mailto: URL")
+	}
+	return nil
+}
+
+func hasScheme(raw string, schemes ...string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && (u.Host != "" || u.Opaque != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// Render returns the page with the branding of host
+func (p *Pages) Render(host string, page Page) ([]byte, error) {
+	host = normalizeHost(host)
+	branding, ok := p.domains[host]
+	if !ok {
+		// Unknown hosts share one cache entry, so arbitrary Host headers cannot fill the cache
+		host = ""
+		branding = p.fallback
+	}
+	key := cacheKey{host: host, page: page}
+
+	p.mu.RLock()
+	body, cached := p.cache[key]
+	p.mu.RUnlock()
+	if cached {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Branding   Branding
+		Page       Page
+		Stylesheet string
+	}{branding, page, assets.Path("hosted.css")}
+	if err := pageTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	body = buf.Bytes()
+
+	p.mu.Lock()
+	if len(p.cache) < maxCachedPages {
+		p.cache[key] = body
+	}
+	p.mu.Unlock()
+	return body, nil
+}
+
+// Write renders the page with the branding of the request's host and writes it. Pages answer
+// links carrying tokens, so they are not cached by browsers or proxies. Nothing is written when
+// rendering fails.
+func (p *Pages) Write(w http.ResponseWriter, r *http.Request, page Page) error {
+	body, err := p.Render(r.Host, page)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(page.Status)
+	w.Write(body)
+	return nil
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// WantsHTML reports whether the request prefers an HTML page to JSON, as browsers following a
+// link do. Clients asking for anything, like curl, get JSON.
+func WantsHTML(r *http.Request) bool {
+	htmlQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case "text/html":
+			htmlQ = max(htmlQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return htmlQ > 0 && htmlQ > jsonQ
+}
@@ -0,0 +1,279 @@
+// Package lint checks rendered emails for deliverability problems, e.g. subjects in capitals,
+// spam phrases or shortened links. Rules are read from YAML; default_rules.yaml documents the
+// rule types and is used when no rules file is configured.
+package lint
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-newsletter/internal/summarize"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_rules.yaml
+var defaultRules []byte
+
+// Severity ranks how likely an issue is to hurt delivery
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity reads a severity name
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown severity %q, use info, warning or error", name)
+}
+
+func (s *Severity) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := ParseSeverity(node.Value)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Rule is one check; which settings apply depends on its Type
+type Rule struct {
+	ID          string   `yaml:"id"`
+	Description string   `yaml:"description"`
+	Type        string   `yaml:"type"`
+	Severity    Severity `yaml:"severity"`
+	MaxRatio    float64  `yaml:"max_ratio"`
+	MinLetters  int      `yaml:"min_letters"`
+	MaxLength   int      `yaml:"max_length"`
+	Words       []string `yaml:"words"`
+	Fields      []string `yaml:"fields"`
+	Domains     []string `yaml:"domains"`
+}
+
+// Email is a rendered email as recipients get it
+type Email struct {
+	Subject string
+	HTML    string
+}
+
+// Finding is an issue a rule found in an email
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// Linter checks emails against a set of rules
+type Linter struct {
+	rules []Rule
+}
+
+// Load reads the rules file at path, or the default rules when path is empty
+func Load(path string) (*Linter, error) {
+	if path == "" {
+		return Parse(defaultRules)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lint rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads rules from YAML and validates them
+func Parse(data []byte) (*Linter, error) {
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid lint rules: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range file.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("lint rule of type %q has no id", rule.Type)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("lint rule %q is defined twice", rule.ID)
+		}
+		seen[rule.ID] = true
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("lint rule %q: %w", rule.ID, err)
+		}
+	}
+	return &Linter{rules: file.Rules}, nil
+}
+
+func (r Rule) validate() error {
+	switch r.Type {
+	case "caps_subject", "link_text_ratio":
+		if r.MaxRatio <= 0 || r.MaxRatio > 1 {
+			return fmt.Errorf("max_ratio must be above 0 and at most 1")
+		}
+	case "subject_length":
+		if r.MaxLength <= 0 {
+			return fmt.Errorf("max_length must be positive")
+		}
+	case "spam_words":
+		if len(r.Words) == 0 {
+			return fmt.Errorf("words must not be empty")
+		}
+		for _, field := range r.Fields {
+			if field != "subject" && field != "body" {
+				return fmt.Errorf("unknown field %q, use subject or body", field)
+			}
+		}
+	case "url_shorteners":
+		if len(r.Domains) == 0 {
+			return fmt.Errorf("domains must not be empty")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	return nil
+}
+
+// Rules returns the number of rules loaded
+func (l *Linter) Rules() int {
+	return len(l.rules)
+}
+
+// Check runs every rule on the email and returns the findings, most severe first
+func (l *Linter) Check(email Email) []Finding {
+	text := summarize.PlainText(email.HTML)
+	findings := []Finding{}
+	for _, rule := range l.rules {
+		if message := rule.check(email, text); message != "" {
+			findings = append(findings, Finding{Rule: rule.ID, Severity: rule.Severity, Message: message})
+		}
+	}
+	sort.SliceStable(findings, func(a, b int) bool { return findings[a].Severity > findings[b].Severity })
+	return findings
+}
+
+// Blocks reports whether any finding is at or above threshold
+func Blocks(findings []Finding, threshold Severity) bool {
+	for _, finding := range findings {
+		if finding.Severity >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	linkPattern = regexp.MustCompile(`(?is)<a\b[^>]*>(.*?)</a>`)
+	hrefPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*["']([^"']+)["']`)
+)
+
+// check returns a message describing the issue, or "" when the email passes
+func (r Rule) check(email Email, text string) string {
+	switch r.Type {
+	case "caps_subject":
+		letters, upper := 0, 0
+		for _, c := range email.Subject {
+			if unicode.IsLetter(c) {
+				letters++
+				if unicode.IsUpper(c) {
+					upper++
+				}
+			}
+		}
+		if letters >= r.MinLetters && letters > 0 && float64(upper)/float64(letters) > r.MaxRatio {
+			return fmt.Sprintf("%d%% of the subject's letters are capitals", upper*100/letters)
+		}
+	case "subject_length":
+		if length := utf8.RuneCountInString(email.Subject); length > r.MaxLength {
+			return fmt.Sprintf("The subject is %d characters long, more than %d", length, r.MaxLength)
+		}
+	case "spam_words":
+		for _, word := range r.Words {
+			if r.appliesTo("subject") && containsPhrase(email.Subject, word) {
+				return fmt.Sprintf("The subject contains %q", word)
+			}
+			if r.appliesTo("body") && containsPhrase(text, word) {
+				return fmt.Sprintf("The content contains %q", word)
+			}
+		}
+	case "link_text_ratio":
+		total := len(strings.Join(strings.Fields(text), ""))
+		linked := 0
+		for _, match := range linkPattern.FindAllStringSubmatch(email.HTML, -1) {
+			linked += len(strings.Join(strings.Fields(summarize.PlainText(match[1])), ""))
+		}
+		if total > 0 && float64(linked)/float64(total) > r.MaxRatio {
+			return fmt.Sprintf("%d%% of the text is link text", linked*100/total)
+		}
+	case "url_shorteners":
+		for _, match := range hrefPattern.FindAllStringSubmatch(email.HTML, -1) {
+			link, err := url.Parse(strings.TrimSpace(html.UnescapeString(match[1])))
+			if err != nil {
+				continue
+			}
+			host := strings.ToLower(link.Hostname())
+			for _, domain := range r.Domains {
+				domain = strings.ToLower(domain)
+				if host == domain || strings.HasSuffix(host, "."+domain) {
+					return fmt.Sprintf("The link to %s uses the URL shortener %s", link.String(), domain)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (r Rule) appliesTo(field string) bool {
+	if len(r.Fields) == 0 {
+		return true
+	}
+	for _, f := range r.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPhrase reports whether text contains phrase as whole words, ignoring case
+func containsPhrase(text string, phrase string) bool {
+	text, phrase = strings.ToLower(text), strings.ToLower(phrase)
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], phrase)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(phrase)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
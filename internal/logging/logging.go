@@ -0,0 +1,130 @@
+// Package logging builds the application logger and keeps personal data out of the logs.
+// Email addresses and tokens passed to log calls should be wrapped with Email and Token, which
+// always mask them. With redaction enabled (LOG_REDACT_PII) the logger also scrubs addresses,
+// link tokens, JWTs, API keys and other issued secrets from every logged string and error,
+// catching values that reach the logs unwrapped, e.g. inside provider or database errors.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go-newsletter/internal/config"
+)
+
+// Redacted replaces secrets in logs
+const Redacted = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// tokenURLPattern matches the per-recipient secrets in subscription links. Longer prefixes come
+	// first, so the email change confirmation is not mistaken for a subscription token.
+	tokenURLPattern = regexp.MustCompile(`(/subscriptions/email-change/confirm/|/email-change/confirm/|/subscribe/confirm/|/unsubscribe-all/|/unsubscribe/|/subscriptions/)[^"'\s<>?#/]+`)
+	jwtPattern      = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+	// secretPattern matches the secrets the service issues: a prefix and 32 random bytes in
+	// unpadded base64url. The prefixes are editor API keys (nl_), integration access tokens
+	// (nlat_) and client secrets (nls_), account link tokens (nllt_), member invitation tokens
+	// (nlinv_) and webhook signing secrets (whsec_).
+	secretPattern = regexp.MustCompile(`\b(?:nl|nlat|nls|nllt|nlinv|whsec)_[A-Za-z0-9_\-]{43}`)
+)
+
+// sensitiveKeys are attribute keys whose string values are always redacted
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"authorization": true,
+	"api_key":       true,
+}
+
+// emailKeys are attribute keys whose string values are email addresses
+var emailKeys = map[string]bool{
+	"email":     true,
+	"recipient": true,
+	"oldemail":  true,
+	"newemail":  true,
+}
+
+// New creates the JSON logger writing to w at the configured level, scrubbing personal data
+// when redaction is enabled
+func New(w io.Writer, cfg config.LoggingConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(cfg.Level)}
+	if cfg.RedactPII {
+		opts.ReplaceAttr = Sanitize
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+func parseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// Sanitize is a slog ReplaceAttr function that masks addresses and redacts tokens in strings and
+// errors, and redacts values logged under sensitive keys
+func Sanitize(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch a.Value.Kind() {
+	case slog.KindString:
+		value := a.Value.String()
+		switch {
+		case sensitiveKeys[key]:
+			return slog.String(a.Key, Redacted)
+		case emailKeys[key]:
+			return slog.String(a.Key, maskEmail(value))
+		}
+		return slog.String(a.Key, RedactString(value))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// RedactString masks the email addresses and redacts the link tokens, JWTs, API keys and other
+// issued secrets in s
+func RedactString(s string) string {
+	s = RedactTokenURLs(s)
+	s = jwtPattern.ReplaceAllString(s, Redacted)
+	s = secretPattern.ReplaceAllString(s, Redacted)
+	return emailPattern.ReplaceAllStringFunc(s, maskEmail)
+}
+
+// RedactTokenURLs redacts the tokens of subscription links in s, keeping the link paths
+func RedactTokenURLs(s string) string {
+	return tokenURLPattern.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// maskEmail keeps the first character and the domain of an address, e.g. j***@example.com
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return Redacted
+	}
+	if local == "" {
+		return "***@" + domain
+	}
+	return string([]rune(local)[:1]) + "***@" + domain
+}
+
+// Email wraps an address so logs show it masked
+type Email string
+
+// LogValue implements slog.LogValuer
+func (e Email) LogValue() slog.Value {
+	return slog.StringValue(maskEmail(string(e)))
+}
+
+// Token wraps a secret token so logs never show it
+type Token string
+
+// LogValue implements slog.LogValuer
+func (Token) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+// secret is the 43-character base64url body of an issued secret, 32 random bytes unpadded
+var secret = strings.Repeat("aB3-_", 8) + "xYz"
+
+func TestRedactStringIssuedSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{name: "API key", prefix: "nl_"},
+		{name: "integration access token", prefix: "nlat_"},
+		{name: "integration client secret", prefix: "nls_"},
+		{name: "account link token", prefix: "nllt_"},
+		{name: "member invitation token", prefix: "nlinv_"},
+		{name: "webhook signing secret", prefix: "whsec_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := tt.prefix + secret
+			got := RedactString("request failed for " + value + ": unauthorized")
+			if strings.Contains(got, secret) {
+				t.Fatalf("RedactString(%q) = %q, secret not redacted", value, got)
+			}
+			if want := "request failed for " + Redacted + ": unauthorized"; got != want {
+				t.Errorf("RedactString(%q) = %q, want %q", value, got, want)
+			}
+		})
+	}
+}
+
+func TestRedactStringKeepsOtherWords(t *testing.T) {
+	for _, s := range []string{
+		"nl_short",
+		"nlc_" + secret[:16],
+		"key_nl_" + secret[:8],
+		"tonl_" + secret,
+	} {
+		if got := RedactString(s); got != s {
+			t.Errorf("RedactString(%q) = %q, want it unchanged", s, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package markdown renders the Markdown of posts to the HTML sent in emails and the plain text
+// stored next to it. Raw HTML in the Markdown is dropped and the rendered HTML goes through the
+// email policy of the sanitize package, like post HTML, so Markdown cannot carry scripts or event
+// handlers into a post.
+package markdown
+
+import (
+	"bytes"
+
+	"go-newsletter/internal/sanitize"
+	"go-newsletter/internal/summarize"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// converter follows CommonMark with the GitHub extensions (tables, strikethrough, autolinks and
+// task lists); without the unsafe option it leaves raw HTML out
+var converter = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Content is a post body rendered from Markdown
+type Content struct {
+	HTML string
+	// Text is the plain text of HTML, one line per block
+	Text string
+}
+
+// Render converts Markdown to sanitized HTML and plain text
+func Render(source string) (Content, error) {
+	var rendered bytes.Buffer
+	if err := converter.Convert([]byte(source), &rendered); err != nil {
+		return Content{}, err
+	}
+	html := sanitize.EmailHTML(rendered.String())
+	return Content{
+		HTML: html,
+		Text: summarize.PlainText(html),
+	}, nil
+}
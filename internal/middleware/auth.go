@@ -2,33 +2,57 @@ package middleware
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
 
 	"go-newsletter/internal/models"
 	"go-newsletter/internal/services"
+	"go-newsletter/pkg/generated"
 )
 
-// AuthMiddleware wraps handlers to require JWT authentication
+// AuthMiddleware wraps handlers to require JWT, integration token or API key authentication
 type AuthMiddleware struct {
-	authService *services.AuthService
-	logger      *slog.Logger
+	authService    *services.AuthService
+	apiKeyService  *services.APIKeyService
+	oauthService   *services.OAuthService
+	profileService *services.ProfileService
+	logger         *slog.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authService *services.AuthService, logger *slog.Logger) *AuthMiddleware {
+func NewAuthMiddleware(authService *services.AuthService, apiKeyService *services.APIKeyService, oauthService *services.OAuthService, profileService *services.ProfileService, logger *slog.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
-		logger:      logger,
+		authService:    authService,
+		apiKeyService:  apiKeyService,
+		oauthService:   oauthService,
+		profileService: profileService,
+		logger:         logger,
 	}
 }
 
-// RequireAuth middleware that validates JWT and adds user context
+// RequireAuth middleware that validates JWT and adds user context. Requests without an
+// Authorization header may authenticate with an editor API key in X-API-Key instead.
 func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
+		if apiKey := r.Header.Get("X-API-Key"); authHeader == "" && apiKey != "" {
+			user, err := m.apiKeyService.Authenticate(r.Context(), apiKey)
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				m.logger.Warn("API key validation failed")
+				m.handleUnauthorized(w, "Invalid or revoked API key")
+				return
+			}
+			if err != nil {
+				m.logger.Error("API key lookup failed", "error", err.Error())
+				m.writeError(w, models.NewInternalServerError("Could not verify the API key"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(services.AddUserToContext(r.Context(), user)))
+			return
+		}
 		if authHeader == "" {
 			m.handleUnauthorized(w, "Missing authorization header")
 			return
@@ -40,6 +64,23 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// Access tokens of integration clients are opaque and introspected in the database
+		if token := strings.TrimPrefix(authHeader, "Bearer "); strings.HasPrefix(token, services.AccessTokenPrefix) {
+			user, err := m.oauthService.Authenticate(r.Context(), token)
+			if errors.Is(err, services.ErrInvalidAccessToken) {
+				m.logger.Warn("Integration token validation failed")
+				m.handleUnauthorized(w, "Invalid or expired token")
+				return
+			}
+			if err != nil {
+				m.logger.Error("Integration token lookup failed", "error", err.Error())
+				m.writeError(w, models.NewInternalServerError("Could not verify the token"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(services.AddUserToContext(r.Context(), user)))
+			return
+		}
+
 		// Get user from token
 		user, err := m.authService.GetUserFromToken(authHeader)
 		if err != nil {
@@ -57,19 +98,33 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
-// RequireAdmin middleware that requires admin privileges
+// RequireAdmin middleware that requires a signed-in editor whose profile has admin privileges
 func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 	return m.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get user from context
-		_, ok := services.GetUserFromContext(r.Context())
+		user, ok := services.GetUserFromContext(r.Context())
 		if !ok {
 			m.handleUnauthorized(w, "User context not found")
 			return
 		}
 
-		// Check admin status - we'll need to check the profiles table
-		// For now, we'll implement this check in the handler level
-		// since admin status is stored in the database, not in JWT
+		// API keys and integrations automate an editor's own work; administration needs a signed-in admin
+		if user.Automated() {
+			m.writeError(w, models.NewForbiddenError("API keys and integration tokens cannot be used for admin routes"))
+			return
+		}
+
+		// Admin status is stored in the profiles table, not in the JWT
+		isAdmin, err := m.profileService.IsAdmin(r.Context(), user.UserID.String())
+		if err != nil {
+			m.logger.Error("Admin status lookup failed", "error", err.Error())
+			m.writeError(w, models.NewInternalServerError("Could not verify admin privileges"))
+			return
+		}
+		if !isAdmin {
+			m.writeError(w, models.NewForbiddenError("Admin privileges required"))
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	}))
@@ -98,18 +153,19 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 }
 
 func (m *AuthMiddleware) handleUnauthorized(w http.ResponseWriter, message string) {
+	m.writeError(w, models.NewUnauthorizedError(message))
+}
+
+// writeError answers with the documented Error schema, like HTTPResponder.HandleError
+func (m *AuthMiddleware) writeError(w http.ResponseWriter, apiErr models.APIError) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	
-	// Use existing error patterns
-	apiErr := models.NewUnauthorizedError(message)
-	response := map[string]interface{}{
-		"error": map[string]interface{}{
-			"code":    apiErr.Code,
-			"message": apiErr.Message,
-		},
+	w.WriteHeader(apiErr.Code)
+
+	response := generated.Error{
+		Code:    int32(apiErr.Code),
+		Message: apiErr.Message,
 	}
-	
+
 	// Write JSON response
 	json.NewEncoder(w).Encode(response)
-} 
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-newsletter/internal/services"
+)
+
+// maxUserAgentLength caps the user agents recorded in security events
+const maxUserAgentLength = 512
+
+// ClientInfo adds the client's IP address, user agent and approximate location to the request
+// context, for the security events of sign-ins. The location is read from locationHeader, which
+// a CDN or proxy in front of the API sets (e.g. CF-IPCountry); empty leaves it unknown. Run it
+// after TrustedRealIP so the address is the client's, not the proxy's.
+func ClientInfo(locationHeader string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := services.ClientInfo{
+				UserAgent: r.UserAgent(),
+			}
+			if len(info.UserAgent) > maxUserAgentLength {
+				info.UserAgent = info.UserAgent[:maxUserAgentLength]
+			}
+			if addr, ok := remoteAddr(r.RemoteAddr); ok {
+				info.IP = addr.String()
+			}
+			if locationHeader != "" {
+				info.Location = r.Header.Get(locationHeader)
+			}
+			next.ServeHTTP(w, r.WithContext(services.WithClientInfo(r.Context(), info)))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-newsletter/internal/metrics"
+	"go-newsletter/pkg/generated"
+)
+
+var concurrencyRejectedTotal = metrics.NewCounterVec("http_concurrency_rejected_total", "Requests rejected because their route group was at its concurrency limit.", "group")
+
+// ConcurrencyLimit allows at most limit requests of a route group to run at once in this
+// process; further requests are answered with 429 instead of queueing, so heavy routes
+// (publishing, bulk exports and imports) cannot take every database connection. Use one
+// middleware value for all routes of a group so they share the limit. A limit of zero or
+// less disables it.
+func ConcurrencyLimit(group string, limit int) func(http.Handler) http.Handler {
+	slots := make(chan struct{}, max(limit, 0))
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				next.ServeHTTP(w, r)
+			default:
+				concurrencyRejectedTotal.Inc(group)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(generated.Error{
+					Code:    http.StatusTooManyRequests,
+					Message: "Too many concurrent requests of this kind, please try again shortly",
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec("http_requests_total", "Served HTTP requests by method, route pattern and status code.",
+		"method", "route", "status")
+	httpRequestDuration = metrics.NewHistogramVec("http_request_duration_seconds", "HTTP request latency by method and route pattern.",
+		metrics.DefaultBuckets, "method", "route")
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing random paths cannot
+// create a series per path. Unmatched paths below a mounted router end in its /* pattern.
+const unmatchedRoute = "unmatched"
+
+// RequestMetrics counts requests and records their latency per route pattern, like
+// /api/v1/newsletters/{newsletterId}/posts, rather than per path. Use it outside Recoverer so
+// panics are counted as the 500 they are answered with.
+func RequestMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			route := routeLabel(r)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			httpRequestsTotal.Inc(r.Method, route, strconv.Itoa(status))
+			httpRequestDuration.ObserveDuration(time.Since(start), r.Method, route)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// routeLabel is the pattern of the route that served r, or unmatchedRoute; it is only known
+// once the router has run
+func routeLabel(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" && !strings.HasSuffix(pattern, "/*") {
+			return pattern
+		}
+	}
+	return unmatchedRoute
+}
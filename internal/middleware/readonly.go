@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go-newsletter/pkg/generated"
+)
+
+// ReadOnly answers 503 to requests that would write to the database while isReadOnly reports
+// read-only mode: the degraded mode of an incompatible schema (see database.SchemaMismatchReadOnly)
+// or the flag admins switch on during incidents. Safe methods pass unless blockSafe is set, for
+// routes that write on GET such as links opened from emails.
+// When isReadOnly is nil the middleware does nothing.
+func ReadOnly(isReadOnly func(ctx context.Context) bool, blockSafe bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if isReadOnly == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				if !blockSafe {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			if !isReadOnly(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(generated.Error{
+				Code:    http.StatusServiceUnavailable,
+				Message: "The service is temporarily read-only, please try again later",
+			})
+		})
+	}
+}
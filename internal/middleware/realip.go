@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedRealIP replaces chi's RealIP: X-Forwarded-For and X-Real-IP are only honored when the
+// direct peer is inside one of the trusted proxy ranges, so clients can't spoof their address.
+// With no trusted proxies the headers are ignored and RemoteAddr is left untouched.
+func TrustedRealIP(trustedProxies []netip.Prefix) func(next http.Handler) http.Handler {
+	trusted := func(addr netip.Addr) bool {
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, ok := remoteAddr(r.RemoteAddr)
+			if !ok || !trusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if clientIP, ok := forwardedClientIP(r.Header, trusted); ok {
+				r.RemoteAddr = clientIP.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP walks X-Forwarded-For from the right, skipping our own proxies, and returns the
+// first untrusted hop. Entries further left were supplied by the client and can't be trusted.
+func forwardedClientIP(header http.Header, trusted func(netip.Addr) bool) (netip.Addr, bool) {
+	var hops []string
+	for _, value := range header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	var last netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		last = addr.Unmap()
+		if !trusted(last) {
+			return last, true
+		}
+	}
+	// Every hop was one of our proxies; the leftmost valid one is the best we have
+	if last.IsValid() {
+		return last, true
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+func remoteAddr(remote string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		host = remote
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"go-newsletter/internal/alerting"
+	"go-newsletter/internal/metrics"
+	"go-newsletter/pkg/generated"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+var panicsTotal = metrics.NewCounter("http_panics_total", "Handler panics recovered by the HTTP server.")
+
+// Recoverer replaces chi's Recoverer: it logs the panic with its stack trace and request ID,
+// raises an alert and answers with the standard Error payload instead of a bare 500.
+func Recoverer(logger *slog.Logger, notifier alerting.Notifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				// http.ErrAbortHandler is the documented way to abort a response, not a bug
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				requestID := chimiddleware.GetReqID(r.Context())
+				stack := string(debug.Stack())
+				panicsTotal.Inc()
+				logger.Error("Recovered from panic",
+					"panic", fmt.Sprint(rvr),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request_id", requestID,
+					"stack", stack,
+				)
+				notifier.Notify(r.Context(), alerting.Alert{
+					Source:    "http",
+					Message:   fmt.Sprintf("panic: %v", rvr),
+					RequestID: requestID,
+					Details: map[string]string{
+						"method": r.Method,
+						"path":   r.URL.Path,
+						"stack":  stack,
+					},
+				})
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(generated.Error{
+					Code:    http.StatusInternalServerError,
+					Message: "Internal server error",
+				})
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
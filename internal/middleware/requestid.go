@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-newsletter/internal/utils"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader is returned on every response so clients can quote it in bug reports
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDPropagation must run after chi's RequestID. It echoes the request ID in the response
+// and makes it the correlation ID for outbound calls and emails triggered by the request.
+func RequestIDPropagation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := chimiddleware.GetReqID(r.Context())
+		if requestID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		ctx := utils.WithCorrelationID(r.Context(), requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-newsletter/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UsageTracking counts the call against the authenticated editor once the handler has run,
+// keyed by method and route pattern so path parameters don't fragment the counts.
+// It must be installed after RequireAuth or RequireAdmin; unauthenticated requests are not counted.
+func UsageTracking(usage *services.UsageService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			user, ok := services.GetUserFromContext(r.Context())
+			if !ok {
+				return
+			}
+			pattern := chi.RouteContext(r.Context()).RoutePattern()
+			if pattern == "" {
+				pattern = r.URL.Path
+			}
+			usage.Record(user.UserID, r.Method+" "+pattern)
+		})
+	}
+}
@@ -30,13 +30,7 @@ func UUIDParamValidationMiddleware(paramName string) func(next http.Handler) htt
 					Code:    http.StatusBadRequest,
 					Message: fmt.Sprintf("Invalid %s: %s", paramName, err.Error()),
 				}
-				response := map[string]interface{}{
-					"error": map[string]interface{}{
-						"code":    apiErr.Code,
-						"message": apiErr.Message,
-					},
-				}
-				json.NewEncoder(w).Encode(response)
+				json.NewEncoder(w).Encode(apiErr)
 				return
 			}
 
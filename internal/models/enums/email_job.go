@@ -0,0 +1,13 @@
+package enums
+
+// EmailJobKind is what a failed email was
+type EmailJobKind string
+
+const (
+	PostEmail         EmailJobKind = "post"
+	ConfirmationEmail EmailJobKind = "confirmation"
+)
+
+func (k EmailJobKind) String() string {
+	return string(k)
+}
@@ -0,0 +1,12 @@
+package enums
+
+// PlanFeature is a capability that is only available on some plans
+type PlanFeature string
+
+const (
+	CustomDomainFeature PlanFeature = "custom_domain"
+)
+
+func (f PlanFeature) String() string {
+	return string(f)
+}
@@ -3,8 +3,12 @@ package enums
 type PostStatus string
 
 const (
+	// Draft is a work-in-progress post without a schedule; it is never sent until promoted
+	Draft     PostStatus = "DRAFT"
 	Posted    PostStatus = "POSTED"
 	Scheduled PostStatus = "SCHEDULED"
+	// Skipped marks an overdue post the scheduler did not send because of the newsletter's catch-up policy
+	Skipped PostStatus = "SKIPPED"
 )
 
 func (s PostStatus) String() string {
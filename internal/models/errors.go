@@ -1,8 +1,16 @@
 package models
 
+import "errors"
+
+// ErrDatabaseBusy is returned by database calls that found no free pool connection within
+// the acquire timeout. Handlers answer it with 503 and Retry-After.
+var ErrDatabaseBusy = errors.New("no database connection available")
+
 type APIError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// Reason is a machine-readable cause, set for errors clients are expected to branch on
+	Reason string `json:"reason,omitempty"`
 }
 
 func (e APIError) Error() string {
@@ -22,6 +30,11 @@ func NewForbiddenError(message string) APIError {
 	return APIError{Code: 403, Message: message}
 }
 
+// NewUpgradeRequiredError reports that the editor's plan does not allow the action
+func NewUpgradeRequiredError(reason, message string) APIError {
+	return APIError{Code: 402, Message: message, Reason: reason}
+}
+
 func NewNotFoundError(message string) APIError {
 	return APIError{Code: 404, Message: message}
 }
@@ -30,10 +43,26 @@ func NewConflictError(message string) APIError {
 	return APIError{Code: 409, Message: message}
 }
 
+// NewUnprocessableError reports a well-formed request whose content cannot be accepted;
+// reason says which check failed
+func NewUnprocessableError(reason, message string) APIError {
+	return APIError{Code: 422, Message: message, Reason: reason}
+}
+
+// NewTooManyRequestsError reports that the caller has to wait before trying again
+func NewTooManyRequestsError(message string) APIError {
+	return APIError{Code: 429, Message: message}
+}
+
 func NewInternalServerError(message string) APIError {
 	return APIError{Code: 500, Message: message}
 }
 
+// NewBadGatewayError reports that a third-party service the request depends on failed
+func NewBadGatewayError(message string) APIError {
+	return APIError{Code: 502, Message: message}
+}
+
 func IsNotFoundError(err error) bool {
 	if err == nil {
 		return false
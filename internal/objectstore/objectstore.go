@@ -0,0 +1,146 @@
+// Package objectstore writes and reads objects in S3 compatible storage or a local directory,
+// without an SDK. It is used for backups and uploaded assets, so it only supports whole-object
+// puts and gets.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-newsletter/internal/config"
+)
+
+// ErrNotFound is returned by Get for a key that does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Store puts and gets whole objects by key
+type Store interface {
+	// Put stores body under key, replacing an existing object
+	Put(ctx context.Context, key string, body io.ReadSeeker) error
+	// Get opens the object stored under key; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Location describes where key is stored, for logs and operators
+	Location(key string) string
+}
+
+// Settings locate a store and authenticate to it
+type Settings struct {
+	// URL is s3://bucket/prefix or file:///path
+	URL string
+	// S3Endpoint overrides the AWS endpoint for S3 compatible stores
+	S3Endpoint        string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	// EnvPrefix names the environment variables of the settings in errors, e.g. BACKUP for
+	// BACKUP_URL and BACKUP_S3_ENDPOINT
+	EnvPrefix string
+}
+
+// New opens the backup store configured by cfg.URL, s3://bucket/prefix or file:///path. It
+// returns nil when no URL is configured.
+func New(cfg config.BackupConfig, client *http.Client) (Store, error) {
+	return Open(Settings{
+		URL:               cfg.URL,
+		S3Endpoint:        cfg.S3Endpoint,
+		S3Region:          cfg.S3Region,
+		S3AccessKeyID:     cfg.S3AccessKeyID,
+		S3SecretAccessKey: cfg.S3SecretAccessKey,
+		EnvPrefix:         "BACKUP",
+	}, client)
+}
+
+// Open opens the store located by settings. It returns nil when no URL is configured.
+func Open(settings Settings, client *http.Client) (Store, error) {
+	if settings.URL == "" {
+		return nil, nil
+	}
+	prefix := settings.EnvPrefix
+	u, err := url.Parse(settings.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s_URL: %w", prefix, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("%s_URL file:// needs a path, e.g. file:///var/lib/newsletter", prefix)
+		}
+		return &fileStore{dir: filepath.FromSlash(u.Path)}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s_URL s3:// needs a bucket, e.g. s3://my-bucket/newsletter", prefix)
+		}
+		if settings.S3AccessKeyID == "" || settings.S3SecretAccessKey == "" {
+			return nil, fmt.Errorf("%[1]s_S3_ACCESS_KEY_ID and %[1]s_S3_SECRET_ACCESS_KEY are required for s3:// stores", prefix)
+		}
+		endpoint := settings.S3Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", settings.S3Region)
+		}
+		base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_S3_ENDPOINT: %w", prefix, err)
+		}
+		return &s3Store{
+			client:    client,
+			endpoint:  base,
+			bucket:    u.Host,
+			prefix:    strings.Trim(u.Path, "/"),
+			region:    settings.S3Region,
+			accessKey: settings.S3AccessKeyID,
+			secretKey: settings.S3SecretAccessKey,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported %s_URL scheme %q, use s3:// or file://", prefix, u.Scheme)
+	}
+}
+
+// fileStore keeps objects as files below dir, e.g. on a mounted volume
+type fileStore struct {
+	dir string
+}
+
+func (s *fileStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *fileStore) Put(_ context.Context, key string, body io.ReadSeeker) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so a failed put never leaves a truncated object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fileStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *fileStore) Location(key string) string {
+	return "file://" + filepath.ToSlash(s.path(key))
+}
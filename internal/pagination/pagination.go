@@ -0,0 +1,124 @@
+// Package pagination implements the keyset pagination shared by the list endpoints: a page
+// is read with the limit and cursor query parameters, and the cursor of the next page is
+// returned in the X-Next-Cursor header. Cursors are opaque to clients; they hold the sort
+// key of the last item returned, so the repositories continue with a keyset query instead
+// of an OFFSET that rescans the skipped rows.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-newsletter/internal/models"
+)
+
+const (
+	// DefaultLimit is the page size when the request does not set limit
+	DefaultLimit = 100
+	// MaxLimit is the largest page size a request may ask for
+	MaxLimit = 500
+	// NextCursorHeader carries the cursor of the next page; it is absent on the last page
+	NextCursorHeader = "X-Next-Cursor"
+)
+
+// Cursor is the sort key of the last item of a page. Listings are sorted by a timestamp,
+// newest first, with ID as the tie-breaker.
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+// Encode returns the cursor in the form clients pass back as the cursor query parameter
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor produced by Encode
+func Decode(raw string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, models.NewBadRequestError("Invalid cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.Time.IsZero() {
+		return nil, models.NewBadRequestError("Invalid cursor")
+	}
+	return &c, nil
+}
+
+// Page selects up to Limit items following After, or the first items when After is nil
+type Page struct {
+	Limit int
+	After *Cursor
+}
+
+// FirstPage is the first page of DefaultLimit items
+func FirstPage() Page {
+	return Page{Limit: DefaultLimit}
+}
+
+// FromRequest reads the limit and cursor query parameters. Invalid values are reported as
+// bad request errors.
+func FromRequest(r *http.Request) (Page, error) {
+	page := FirstPage()
+	query := r.URL.Query()
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return page, models.NewBadRequestError("limit must be an integer between 1 and 500")
+		}
+		page.Limit = limit
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		after, err := Decode(raw)
+		if err != nil {
+			return page, err
+		}
+		page.After = after
+	}
+	return page, nil
+}
+
+// Requested reports whether the request sets limit or cursor. Listings that stream their
+// rows return everything to requests that set neither.
+func Requested(r *http.Request) bool {
+	query := r.URL.Query()
+	return query.Has("limit") || query.Has("cursor")
+}
+
+// KeysetArgs returns the sort key of After as query arguments, both nil on the first page.
+// Queries compare them as ($n::timestamptz IS NULL OR (sort_column, id) < ($n, $n+1)).
+func (p Page) KeysetArgs() (any, any) {
+	if p.After == nil {
+		return nil, nil
+	}
+	return p.After.Time, p.After.ID
+}
+
+// FetchLimit is the number of rows to query: one more than the page, to tell whether a next
+// page exists
+func (p Page) FetchLimit() int {
+	return p.Limit + 1
+}
+
+// Trim cuts items fetched with FetchLimit to the page and returns the cursor of the next
+// page, nil when items was the last page. key returns the sort key of an item.
+func Trim[T any](items []T, page Page, key func(T) Cursor) ([]T, *Cursor) {
+	if len(items) <= page.Limit {
+		return items, nil
+	}
+	items = items[:page.Limit]
+	next := key(items[len(items)-1])
+	return items, &next
+}
+
+// SetNextCursor sets the X-Next-Cursor header when there is a next page
+func SetNextCursor(w http.ResponseWriter, next *Cursor) {
+	if next != nil {
+		w.Header().Set(NextCursorHeader, next.Encode())
+	}
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrAccountInUse is returned when the user of a link request started editing newsletters
+// before the link completed, so deleting it would lose their work
+var ErrAccountInUse = errors.New("account is in use")
+
+// AccountLinkRequest is a sign-in with an identity provider waiting to be linked to the
+// password account of the same email
+type AccountLinkRequest struct {
+	UserID    uuid.UUID
+	AccountID uuid.UUID
+	Email     string
+	Provider  string
+	FullName  string
+	AvatarURL string
+	ExpiresAt time.Time
+}
+
+// userOwnsNothing is true for Supabase users that neither own nor edit a newsletter
+const userOwnsNothing = `
+	NOT EXISTS (SELECT 1 FROM newsletters n WHERE n.editor_id = u.id)
+	AND NOT EXISTS (SELECT 1 FROM newsletter_members m WHERE m.editor_id = u.id)
+`
+
+type AccountLinkRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewAccountLinkRepository(db *pgxpool.Pool, logger *slog.Logger) *AccountLinkRepository {
+	return &AccountLinkRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// FindPasswordAccount returns the oldest other Supabase user with the email that signs in
+// with a password, as long as the user is new and owns nothing; ErrNotFound otherwise
+func (r *AccountLinkRepository) FindPasswordAccount(ctx context.Context, userID uuid.UUID, email string) (uuid.UUID, error) {
+	query := `
+		SELECT a.id
+		FROM auth.users a
+		JOIN public.profiles p ON p.id = a.id
+		WHERE lower(a.email) = lower($2)
+		  AND a.id <> $1
+		  AND COALESCE(a.encrypted_password, '') <> ''
+		  AND EXISTS (SELECT 1 FROM auth.users u WHERE u.id = $1 AND ` + userOwnsNothing + `)
+		ORDER BY a.created_at
+		LIMIT 1
+	`
+	var accountID uuid.UUID
+	err := r.db.QueryRow(ctx, query, userID, email).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up password account", "userId", userID, "error", err)
+		return uuid.Nil, err
+	}
+	return accountID, nil
+}
+
+// Create stores a link request by the hash of its token, replacing earlier requests of the
+// user, and deletes expired requests
+func (r *AccountLinkRepository) Create(ctx context.Context, tokenHash string, req AccountLinkRequest) error {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM account_link_requests WHERE user_id = $1 OR expires_at <= now()`, req.UserID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO account_link_requests (token_hash, user_id, account_id, email, provider, full_name, avatar_url, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, tokenHash, req.UserID, req.AccountID, req.Email, req.Provider, req.FullName, req.AvatarURL, req.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to store account link request", "userId", req.UserID, "error", err)
+	}
+	return err
+}
+
+// GetActive returns the unexpired link request with the given token hash; ErrNotFound when
+// there is none
+func (r *AccountLinkRepository) GetActive(ctx context.Context, tokenHash string) (*AccountLinkRequest, error) {
+	query := `
+		SELECT user_id, account_id, email, provider, full_name, avatar_url, expires_at
+		FROM account_link_requests
+		WHERE token_hash = $1 AND expires_at > now()
+	`
+	var req AccountLinkRequest
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&req.UserID, &req.AccountID, &req.Email, &req.Provider, &req.FullName, &req.AvatarURL, &req.ExpiresAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up account link request", "error", err)
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Merge completes a link request: the name and avatar from the provider fill in those the
+// account's profile lacks, and the user the sign-in created is deleted with its profile and
+// identity, which frees the identity to be linked to the account. ErrNotFound when the request
+// expired or completed meanwhile, ErrAccountInUse when the user started editing newsletters.
+func (r *AccountLinkRepository) Merge(ctx context.Context, tokenHash string) error {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var req AccountLinkRequest
+		err := tx.QueryRow(ctx, `
+			SELECT user_id, account_id, full_name, avatar_url
+			FROM account_link_requests
+			WHERE token_hash = $1 AND expires_at > now()
+			FOR UPDATE
+		`, tokenHash).Scan(&req.UserID, &req.AccountID, &req.FullName, &req.AvatarURL)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE public.profiles
+			SET full_name = CASE WHEN COALESCE(full_name, '') = '' THEN $2 ELSE full_name END,
+			    avatar_url = CASE WHEN COALESCE(avatar_url, '') = '' THEN $3 ELSE avatar_url END,
+			    updated_at = NOW()
+			WHERE id = $1
+		`, req.AccountID, req.FullName, req.AvatarURL)
+		if err != nil {
+			return err
+		}
+
+		// Cascades to the user's identities, profile and link requests
+		tag, err := tx.Exec(ctx, `DELETE FROM auth.users u WHERE u.id = $1 AND `+userOwnsNothing, req.UserID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAccountInUse
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrAccountInUse) {
+		r.logger.ErrorContext(ctx, "Failed to merge linked account", "error", err)
+	}
+	return err
+}
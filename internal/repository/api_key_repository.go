@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyColumns = `id, name, prefix, created_at, last_used_at, revoked_at, scopes`
+
+// APIKeyOwner is the editor an active API key belongs to
+type APIKeyOwner struct {
+	KeyID      uuid.UUID
+	EditorID   uuid.UUID
+	LastUsedAt *time.Time
+	// Scopes limit what the key can do; nil when it is unrestricted
+	Scopes []string
+}
+
+type APIKeyRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool, logger *slog.Logger) *APIKeyRepository {
+	return &APIKeyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create stores a new key of the editor by its hash; nil scopes leave the key unrestricted
+func (r *APIKeyRepository) Create(ctx context.Context, editorID uuid.UUID, name string, prefix string, keyHash string, scopes []string) (*generated.ApiKey, error) {
+	query := `
+		INSERT INTO api_keys (editor_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + apiKeyColumns
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, editorID, name, prefix, keyHash, scopes))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create API key", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListByEditor returns the editor's keys, newest first
+func (r *APIKeyRepository) ListByEditor(ctx context.Context, editorID uuid.UUID) ([]generated.ApiKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE editor_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(ctx, query, editorID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query API keys", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []generated.ApiKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan API key row", "error", err)
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating API key rows", "error", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CountActiveByEditor returns the number of the editor's keys that are not revoked
+func (r *APIKeyRepository) CountActiveByEditor(ctx context.Context, editorID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM api_keys WHERE editor_id = $1 AND revoked_at IS NULL`, editorID).Scan(&count)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count API keys", "editorId", editorID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Revoke revokes one of the editor's keys; ErrNotFound when the editor has no such key.
+// Revoking a revoked key keeps its original revocation time.
+func (r *APIKeyRepository) Revoke(ctx context.Context, editorID uuid.UUID, keyID uuid.UUID) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1 AND editor_id = $2
+	`
+	tag, err := r.db.Exec(ctx, query, keyID, editorID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to revoke API key", "id", keyID, "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetActiveOwner returns the owner of the active key with the given hash; ErrNotFound when
+// there is none or it was revoked
+func (r *APIKeyRepository) GetActiveOwner(ctx context.Context, keyHash string) (*APIKeyOwner, error) {
+	query := `
+		SELECT id, editor_id, last_used_at, scopes
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`
+	var owner APIKeyOwner
+	err := r.db.QueryRow(ctx, query, keyHash).Scan(&owner.KeyID, &owner.EditorID, &owner.LastUsedAt, &owner.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up API key", "error", err)
+		return nil, err
+	}
+	return &owner, nil
+}
+
+// TouchLastUsed records a request made with the key
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, keyID uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = now() WHERE id = $1`, keyID); err != nil {
+		r.logger.WarnContext(ctx, "Failed to record API key use", "id", keyID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*generated.ApiKey, error) {
+	var key generated.ApiKey
+	err := row.Scan(
+		&key.Id,
+		&key.Name,
+		&key.Prefix,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.Scopes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
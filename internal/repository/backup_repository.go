@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BackupTables are the tables written to snapshots, parents before children so a restore
+// can insert them in this order without violating foreign keys
+var BackupTables = []string{
+	"plans",
+	"profiles",
+	"newsletters",
+	"newsletter_members",
+	"subscribers",
+	"subscriber_email_changes",
+	"email_suppressions",
+	"newsletter_suppressions",
+	"published_posts",
+	"post_reviews",
+	"subscriber_notifications",
+	"email_jobs",
+	"email_outbox",
+	"email_messages",
+	"email_message_events",
+	"incidents",
+	"audit_log",
+}
+
+// BackupInsertFunc inserts rows of a backed up table, skipping rows that already exist, and
+// returns how many were inserted
+type BackupInsertFunc func(ctx context.Context, table string, rows []json.RawMessage) (int64, error)
+
+type BackupRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewBackupRepository(db *pgxpool.Pool, logger *slog.Logger) *BackupRepository {
+	return &BackupRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// StartBackup records a running backup under objectKey unless a backup that did not fail
+// started less than minAge ago, in which case it returns false. Concurrent callers are
+// serialized, so of several instances only one takes a scheduled snapshot.
+func (r *BackupRepository) StartBackup(ctx context.Context, objectKey string, schemaVersion int, minAge time.Duration) (uuid.UUID, bool, error) {
+	var id uuid.UUID
+	started := false
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('backups'))`); err != nil {
+			return err
+		}
+
+		var recent bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM backups
+				WHERE status <> 'failed' AND started_at > now() - $1::interval
+			)
+		`, minAge).Scan(&recent)
+		if err != nil || recent {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO backups (object_key, schema_version)
+			VALUES ($1, $2)
+			RETURNING id
+		`, objectKey, schemaVersion).Scan(&id)
+		started = err == nil
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to start backup", "error", err)
+		return uuid.Nil, false, err
+	}
+	return id, started, nil
+}
+
+// FinishBackup marks a backup as succeeded
+func (r *BackupRepository) FinishBackup(ctx context.Context, id uuid.UUID, rows, size int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE backups
+		SET status = 'succeeded', row_count = $2, size_bytes = $3, finished_at = now()
+		WHERE id = $1
+	`, id, rows, size)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to finish backup", "backupId", id, "error", err)
+	}
+	return err
+}
+
+// FailBackup marks a backup as failed, so the next scheduled run retries it
+func (r *BackupRepository) FailBackup(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE backups
+		SET status = 'failed', error = $2, finished_at = now()
+		WHERE id = $1
+	`, id, reason)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record failed backup", "backupId", id, "error", err)
+	}
+	return err
+}
+
+// ExportTables reads BackupTables from one consistent snapshot of the database. begin receives
+// the schema version of the snapshot before the first row; row receives each row as JSON.
+func (r *BackupRepository) ExportTables(ctx context.Context, begin func(schemaVersion int) error, row func(table string, data []byte) error) error {
+	txOptions := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err := pgx.BeginTxFunc(ctx, r.db, txOptions, func(tx pgx.Tx) error {
+		var schemaVersion int
+		if err := tx.QueryRow(ctx, `SELECT version FROM schema_version`).Scan(&schemaVersion); err != nil {
+			return err
+		}
+		if err := begin(schemaVersion); err != nil {
+			return err
+		}
+
+		for _, table := range BackupTables {
+			name := pgx.Identifier{table}.Sanitize()
+			rows, err := tx.Query(ctx, `SELECT to_jsonb(t)::text FROM `+name+` t`)
+			if err != nil {
+				return fmt.Errorf("export %s: %w", table, err)
+			}
+			for rows.Next() {
+				var data []byte
+				if err := rows.Scan(&data); err != nil {
+					rows.Close()
+					return fmt.Errorf("export %s: %w", table, err)
+				}
+				if err := row(table, data); err != nil {
+					rows.Close()
+					return err
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("export %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to export tables", "error", err)
+	}
+	return err
+}
+
+// RestoreTables runs fn in one transaction, so a restore that fails part way leaves the
+// database unchanged. Rows whose key already exists are kept as they are.
+func (r *BackupRepository) RestoreTables(ctx context.Context, fn func(insert BackupInsertFunc) error) error {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		return fn(func(ctx context.Context, table string, rows []json.RawMessage) (int64, error) {
+			if !slices.Contains(BackupTables, table) {
+				return 0, fmt.Errorf("snapshot contains unknown table %q", table)
+			}
+			name := pgx.Identifier{table}.Sanitize()
+			query := `
+				INSERT INTO ` + name + `
+				SELECT * FROM jsonb_populate_recordset(NULL::` + name + `, $1::jsonb)
+				ON CONFLICT DO NOTHING
+			`
+			var batch bytes.Buffer
+			batch.WriteByte('[')
+			for i, row := range rows {
+				if i > 0 {
+					batch.WriteByte(',')
+				}
+				batch.Write(row)
+			}
+			batch.WriteByte(']')
+			result, err := tx.Exec(ctx, query, batch.String())
+			if err != nil {
+				return 0, fmt.Errorf("restore %s: %w", table, err)
+			}
+			return result.RowsAffected(), nil
+		})
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to restore tables", "error", err)
+	}
+	return err
+}
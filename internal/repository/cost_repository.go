@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmailCost is the estimated cost of one send operation
+type EmailCost struct {
+	NewsletterID   uuid.UUID
+	PostID         *uuid.UUID
+	Kind           string
+	Provider       string
+	Emails         int
+	UnitCostMicros int64
+}
+
+// CostLine is the cost of one kind of email sent for a newsletter during a period. The
+// newsletter is nil once it has been deleted.
+type CostLine struct {
+	NewsletterID   *uuid.UUID
+	NewsletterName *string
+	Kind           string
+	Emails         int64
+	CostMicros     int64
+}
+
+type CostRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewCostRepository(db *pgxpool.Pool, logger *slog.Logger) *CostRepository {
+	return &CostRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Record stores the cost of a send operation, attributed to the newsletter's editor
+func (r *CostRepository) Record(ctx context.Context, cost EmailCost) error {
+	query := `
+		INSERT INTO email_costs (editor_id, newsletter_id, post_id, kind, provider, emails, unit_cost_micros, cost_micros)
+		SELECT n.editor_id, n.id, $2, $3, $4, $5, $6, $5 * $6
+		FROM newsletters n
+		WHERE n.id = $1
+	`
+	_, err := r.db.Exec(ctx, query, cost.NewsletterID, cost.PostID, cost.Kind, cost.Provider, cost.Emails, cost.UnitCostMicros)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record email cost", "newsletterId", cost.NewsletterID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// SummarizeByEditor returns the editor's costs in [from, to) per newsletter and kind
+func (r *CostRepository) SummarizeByEditor(ctx context.Context, editorID uuid.UUID, from, to time.Time) ([]CostLine, error) {
+	return r.summarize(ctx, `c.editor_id = $1`, editorID, from, to)
+}
+
+// SummarizeByNewsletter returns the newsletter's costs in [from, to) per kind
+func (r *CostRepository) SummarizeByNewsletter(ctx context.Context, newsletterID uuid.UUID, from, to time.Time) ([]CostLine, error) {
+	return r.summarize(ctx, `c.newsletter_id = $1`, newsletterID, from, to)
+}
+
+func (r *CostRepository) summarize(ctx context.Context, filter string, id uuid.UUID, from, to time.Time) ([]CostLine, error) {
+	query := `
+		SELECT c.newsletter_id, n.name, c.kind, SUM(c.emails), SUM(c.cost_micros)
+		FROM email_costs c
+		LEFT JOIN newsletters n ON n.id = c.newsletter_id
+		WHERE ` + filter + ` AND c.sent_at >= $2 AND c.sent_at < $3
+		GROUP BY c.newsletter_id, n.name, c.kind
+		ORDER BY SUM(c.cost_micros) DESC, n.name, c.kind
+	`
+	rows, err := r.db.Query(ctx, query, id, from, to)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query email costs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []CostLine
+	for rows.Next() {
+		var line CostLine
+		if err := rows.Scan(&line.NewsletterID, &line.NewsletterName, &line.Kind, &line.Emails, &line.CostMicros); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan email cost row", "error", err)
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating email cost rows", "error", err)
+		return nil, err
+	}
+	return lines, nil
+}
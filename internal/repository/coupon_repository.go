@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCouponExpired   = errors.New("coupon expired")
+	ErrCouponExhausted = errors.New("coupon fully redeemed")
+)
+
+const couponColumns = `code, plan_id, duration_days, max_redemptions, redemptions, expires_at, created_at`
+
+// NewCoupon is a coupon to issue
+type NewCoupon struct {
+	Code           string
+	PlanID         string
+	DurationDays   int
+	MaxRedemptions *int
+	ExpiresAt      *time.Time
+	CreatedBy      uuid.UUID
+}
+
+type CouponRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewCouponRepository(db *pgxpool.Pool, logger *slog.Logger) *CouponRepository {
+	return &CouponRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create issues a coupon; ErrAlreadyExists when the code is taken
+func (r *CouponRepository) Create(ctx context.Context, coupon NewCoupon) (*generated.Coupon, error) {
+	query := `
+		INSERT INTO coupons (code, plan_id, duration_days, max_redemptions, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + couponColumns
+	created, err := scanCoupon(r.db.QueryRow(ctx, query,
+		coupon.Code,
+		coupon.PlanID,
+		coupon.DurationDays,
+		coupon.MaxRedemptions,
+		coupon.ExpiresAt,
+		coupon.CreatedBy,
+	))
+	if err != nil {
+		if hasPgCode(err, uniqueViolation) {
+			return nil, ErrAlreadyExists
+		}
+		r.logger.ErrorContext(ctx, "Failed to create coupon", "code", coupon.Code, "error", err)
+		return nil, err
+	}
+	return created, nil
+}
+
+// List returns a page of coupons, newest first
+func (r *CouponRepository) List(ctx context.Context, page pagination.Page) ([]generated.Coupon, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + couponColumns + `
+		FROM coupons
+		WHERE ($1::timestamptz IS NULL OR (created_at, code) < ($1, $2::text))
+		ORDER BY created_at DESC, code DESC
+		LIMIT $3
+	`
+	after, afterCode := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, after, afterCode, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query coupons", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	coupons := []generated.Coupon{}
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan coupon row", "error", err)
+			return nil, nil, err
+		}
+		coupons = append(coupons, *coupon)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating coupon rows", "error", err)
+		return nil, nil, err
+	}
+
+	coupons, next := pagination.Trim(coupons, page, func(c generated.Coupon) pagination.Cursor {
+		return pagination.Cursor{Time: *c.CreatedAt, ID: *c.Code}
+	})
+	return coupons, next, nil
+}
+
+// Redeem grants the coupon's plan to the editor. The coupon row is locked so concurrent
+// redemptions cannot exceed max_redemptions. The grant starts when the editor's current grant
+// of the same plan ends, or now. Returns ErrNotFound for an unknown code or editor,
+// ErrCouponExpired, ErrCouponExhausted, or ErrAlreadyExists when the editor redeemed it before.
+func (r *CouponRepository) Redeem(ctx context.Context, editorID uuid.UUID, code string) (*generated.PlanGrant, error) {
+	var grant *generated.PlanGrant
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		coupon, err := scanCoupon(tx.QueryRow(ctx, `SELECT `+couponColumns+` FROM coupons WHERE code = $1 FOR UPDATE`, code))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(time.Now()) {
+			return ErrCouponExpired
+		}
+		if coupon.MaxRedemptions != nil && *coupon.Redemptions >= *coupon.MaxRedemptions {
+			return ErrCouponExhausted
+		}
+
+		startsAt, err := grantStart(ctx, tx, editorID, *coupon.PlanId)
+		if err != nil {
+			return err
+		}
+		query := `
+			INSERT INTO plan_grants (editor_id, plan_id, source, coupon_code, starts_at, ends_at)
+			VALUES ($1, $2, $3, $4, $5, $5::timestamptz + make_interval(days => $6::int))
+			RETURNING ` + planGrantColumns
+		grant, err = scanPlanGrant(tx.QueryRow(ctx, query,
+			editorID, *coupon.PlanId, generated.PlanSourceCoupon, code, startsAt, *coupon.DurationDays))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE coupons SET redemptions = redemptions + 1 WHERE code = $1`, code)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrCouponExpired), errors.Is(err, ErrCouponExhausted):
+			return nil, err
+		case hasPgCode(err, uniqueViolation):
+			return nil, ErrAlreadyExists
+		case hasPgCode(err, foreignKeyViolation):
+			return nil, ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to redeem coupon", "editorId", editorID, "code", code, "error", err)
+		return nil, err
+	}
+	return grant, nil
+}
+
+// ExtendTrial adds days to the editor's latest trial of the plan that has not ended, or starts
+// a new trial. Returns ErrNotFound when the editor has no profile.
+func (r *CouponRepository) ExtendTrial(ctx context.Context, editorID uuid.UUID, planID string, days int, adminID uuid.UUID) (*generated.PlanGrant, error) {
+	var grant *generated.PlanGrant
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		extend := `
+			UPDATE plan_grants
+			SET ends_at = ends_at + make_interval(days => $4::int), created_by = $5, updated_at = now()
+			WHERE id = (
+				SELECT id FROM plan_grants
+				WHERE editor_id = $1 AND plan_id = $2 AND source = $3 AND ends_at > now()
+				ORDER BY ends_at DESC
+				LIMIT 1
+				FOR UPDATE
+			)
+			RETURNING ` + planGrantColumns
+		var err error
+		grant, err = scanPlanGrant(tx.QueryRow(ctx, extend, editorID, planID, generated.PlanSourceTrial, days, adminID))
+		if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		startsAt, err := grantStart(ctx, tx, editorID, planID)
+		if err != nil {
+			return err
+		}
+		create := `
+			INSERT INTO plan_grants (editor_id, plan_id, source, starts_at, ends_at, created_by)
+			VALUES ($1, $2, $3, $4, $4::timestamptz + make_interval(days => $5::int), $6)
+			RETURNING ` + planGrantColumns
+		grant, err = scanPlanGrant(tx.QueryRow(ctx, create, editorID, planID, generated.PlanSourceTrial, startsAt, days, adminID))
+		return err
+	})
+	if err != nil {
+		if hasPgCode(err, foreignKeyViolation) {
+			return nil, ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to extend trial", "editorId", editorID, "planId", planID, "error", err)
+		return nil, err
+	}
+	return grant, nil
+}
+
+// grantStart is when a new grant of the plan starts: after the editor's last grant of the same
+// plan that has not ended, so consecutive grants add up instead of overlapping
+func grantStart(ctx context.Context, tx pgx.Tx, editorID uuid.UUID, planID string) (time.Time, error) {
+	query := `
+		SELECT GREATEST(now(), COALESCE(MAX(ends_at), now()))
+		FROM plan_grants
+		WHERE editor_id = $1 AND plan_id = $2 AND ends_at > now()
+	`
+	var startsAt time.Time
+	err := tx.QueryRow(ctx, query, editorID, planID).Scan(&startsAt)
+	return startsAt, err
+}
+
+func scanCoupon(row pgx.Row) (*generated.Coupon, error) {
+	coupon := &generated.Coupon{}
+	err := row.Scan(
+		&coupon.Code,
+		&coupon.PlanId,
+		&coupon.DurationDays,
+		&coupon.MaxRedemptions,
+		&coupon.Redemptions,
+		&coupon.ExpiresAt,
+		&coupon.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const emailJobColumns = `id, kind, status, newsletter_id, post_id, recipient, subject, html, correlation_id, attempts, last_error, created_at, updated_at`
+
+// NewEmailJob is a failed delivery to record
+type NewEmailJob struct {
+	Kind          string
+	NewsletterID  uuid.UUID
+	PostID        *uuid.UUID
+	Recipient     string
+	Subject       string
+	HTML          string
+	CorrelationID string
+	// ListUnsubscribe is the recipient's one-click unsubscribe URL, empty for none
+	ListUnsubscribe string
+	Error           string
+	// Attempts already made before the job was recorded
+	Attempts int
+}
+
+type EmailJobRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewEmailJobRepository creates the repository; with emails set, recipients are stored encrypted
+func NewEmailJobRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *EmailJobRepository {
+	return &EmailJobRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// RecordFailures stores failed deliveries in a single batch
+func (r *EmailJobRepository) RecordFailures(ctx context.Context, jobs []NewEmailJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO email_jobs (kind, newsletter_id, post_id, recipient, recipient_hash, subject, html, correlation_id, list_unsubscribe, last_error, attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11)
+	`
+	batch := &pgx.Batch{}
+	for _, job := range jobs {
+		recipient, err := r.emails.Seal(job.Recipient)
+		if err != nil {
+			return err
+		}
+		batch.Queue(query, job.Kind, job.NewsletterID, job.PostID, recipient, r.emails.Index(job.Recipient), job.Subject, job.HTML, job.CorrelationID, job.ListUnsubscribe, job.Error, job.Attempts)
+	}
+
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record failed email jobs", "count", len(jobs), "error", err)
+		return err
+	}
+	return nil
+}
+
+// List returns a page of jobs in the given status, newest first
+func (r *EmailJobRepository) List(ctx context.Context, status generated.EmailJobStatus, page pagination.Page) ([]generated.EmailJob, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + emailJobColumns + `
+		FROM email_jobs
+		WHERE status = $1
+		  AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, status, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query email jobs", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	jobs := []generated.EmailJob{}
+	for rows.Next() {
+		job, err := r.scanEmailJob(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan email job row", "error", err)
+			return nil, nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating email job rows", "error", err)
+		return nil, nil, err
+	}
+
+	jobs, next := pagination.Trim(jobs, page, func(j generated.EmailJob) pagination.Cursor {
+		return pagination.Cursor{Time: *j.CreatedAt, ID: j.Id.String()}
+	})
+	return jobs, next, nil
+}
+
+// GetByID returns pgx.ErrNoRows when the job does not exist
+func (r *EmailJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*generated.EmailJob, error) {
+	query := `
+		SELECT ` + emailJobColumns + `
+		FROM email_jobs
+		WHERE id = $1
+	`
+	return r.scanEmailJob(r.db.QueryRow(ctx, query, id))
+}
+
+// GetListUnsubscribe returns the List-Unsubscribe URL stored with a job, empty when it has none.
+// It is kept apart from the API model since the URL contains the recipient's unsubscribe token.
+func (r *EmailJobRepository) GetListUnsubscribe(ctx context.Context, id uuid.UUID) (string, error) {
+	query := `
+		SELECT COALESCE(list_unsubscribe, '')
+		FROM email_jobs
+		WHERE id = $1
+	`
+	var url string
+	if err := r.db.QueryRow(ctx, query, id).Scan(&url); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get List-Unsubscribe of email job", "jobId", id, "error", err)
+		return "", err
+	}
+	return url, nil
+}
+
+// RecordAttempt counts a retry and stores its outcome; an empty sendError marks the job succeeded
+func (r *EmailJobRepository) RecordAttempt(ctx context.Context, id uuid.UUID, sendError string) (*generated.EmailJob, error) {
+	query := `
+		UPDATE email_jobs
+		SET attempts = attempts + 1,
+			status = CASE WHEN $2 = '' THEN 'succeeded' ELSE 'failed' END,
+			last_error = NULLIF($2, ''),
+			updated_at = now()
+		WHERE id = $1
+		RETURNING ` + emailJobColumns
+	job, err := r.scanEmailJob(r.db.QueryRow(ctx, query, id, sendError))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record email job attempt", "id", id, "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// scanEmailJob scans a job and opens its recipient
+func (r *EmailJobRepository) scanEmailJob(row pgx.Row) (*generated.EmailJob, error) {
+	job := &generated.EmailJob{}
+	err := row.Scan(
+		&job.Id,
+		&job.Kind,
+		&job.Status,
+		&job.NewsletterId,
+		&job.PostId,
+		&job.Recipient,
+		&job.Subject,
+		&job.Html,
+		&job.CorrelationId,
+		&job.Attempts,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if job.Recipient != nil {
+		recipient, err := r.emails.Open(string(*job.Recipient))
+		if err != nil {
+			return nil, err
+		}
+		*job.Recipient = openapi_types.Email(recipient)
+	}
+	return job, nil
+}
+
+// CountFailedByPost counts the post's failed emails not yet delivered by a retry
+func (r *EmailJobRepository) CountFailedByPost(ctx context.Context, postID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM email_jobs
+		WHERE post_id = $1 AND status = 'failed'
+	`
+	var count int
+	if err := r.db.QueryRow(ctx, query, postID).Scan(&count); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count failed email jobs", "postId", postID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// EncryptStoredEmails encrypts the recipients of failed deliveries stored in plaintext. Returns
+// how many were encrypted.
+func (r *EmailJobRepository) EncryptStoredEmails(ctx context.Context, batchSize int) (int, error) {
+	if !r.emails.Enabled() {
+		return 0, errors.New("subscriber email encryption is not enabled")
+	}
+
+	var encrypted int
+	err := forEachPlaintext(ctx, r.db, `
+		SELECT id, recipient FROM email_jobs
+		WHERE id > $1 AND recipient_hash IS NULL
+		ORDER BY id
+		LIMIT $2
+	`, batchSize, func(id uuid.UUID, values []string) error {
+		recipient, err := r.emails.Seal(values[0])
+		if err != nil {
+			return err
+		}
+		_, err = r.db.Exec(ctx, `
+			UPDATE email_jobs SET recipient = $2, recipient_hash = $3
+			WHERE id = $1 AND recipient_hash IS NULL
+		`, id, recipient, r.emails.Index(values[0]))
+		if err != nil {
+			return err
+		}
+		encrypted++
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt email job recipients", "error", err)
+		return encrypted, err
+	}
+	return encrypted, nil
+}
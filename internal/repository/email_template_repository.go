@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const emailTemplateColumns = `kind, html, updated_at`
+
+type EmailTemplateRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewEmailTemplateRepository(db *pgxpool.Pool, logger *slog.Logger) *EmailTemplateRepository {
+	return &EmailTemplateRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListByNewsletter returns the newsletter's own templates
+func (r *EmailTemplateRepository) ListByNewsletter(ctx context.Context, newsletterID uuid.UUID) ([]generated.EmailTemplate, error) {
+	query := `
+		SELECT ` + emailTemplateColumns + `
+		FROM email_templates
+		WHERE newsletter_id = $1
+		ORDER BY kind
+	`
+	rows, err := r.db.Query(ctx, query, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query email templates", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []generated.EmailTemplate{}
+	for rows.Next() {
+		template, err := scanEmailTemplate(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan email template row", "error", err)
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating email template rows", "error", err)
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Get returns the newsletter's template of the kind; ErrNotFound when it has none
+func (r *EmailTemplateRepository) Get(ctx context.Context, newsletterID uuid.UUID, kind string) (*generated.EmailTemplate, error) {
+	query := `
+		SELECT ` + emailTemplateColumns + `
+		FROM email_templates
+		WHERE newsletter_id = $1 AND kind = $2
+	`
+	template, err := scanEmailTemplate(r.db.QueryRow(ctx, query, newsletterID, kind))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get email template", "newsletterId", newsletterID, "kind", kind, "error", err)
+		return nil, err
+	}
+	return template, nil
+}
+
+// Upsert sets the newsletter's template of the kind
+func (r *EmailTemplateRepository) Upsert(ctx context.Context, newsletterID uuid.UUID, kind string, html string) (*generated.EmailTemplate, error) {
+	query := `
+		INSERT INTO email_templates (newsletter_id, kind, html)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (newsletter_id, kind) DO UPDATE
+		SET html = EXCLUDED.html, updated_at = now()
+		RETURNING ` + emailTemplateColumns
+	template, err := scanEmailTemplate(r.db.QueryRow(ctx, query, newsletterID, kind, html))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to save email template", "newsletterId", newsletterID, "kind", kind, "error", err)
+		return nil, err
+	}
+	return template, nil
+}
+
+// Delete deletes the newsletter's template of the kind; ErrNotFound when it has none
+func (r *EmailTemplateRepository) Delete(ctx context.Context, newsletterID uuid.UUID, kind string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM email_templates WHERE newsletter_id = $1 AND kind = $2`, newsletterID, kind)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete email template", "newsletterId", newsletterID, "kind", kind, "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanEmailTemplate(row pgx.Row) (*generated.EmailTemplate, error) {
+	var template generated.EmailTemplate
+	err := row.Scan(
+		&template.Kind,
+		&template.Html,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	custom := true
+	template.Custom = &custom
+	return &template, nil
+}
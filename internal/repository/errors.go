@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrAlreadyExists is returned when an insert hits a unique constraint
+var ErrAlreadyExists = errors.New("already exists")
+
+// PostgreSQL error codes the repositories translate
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// hasPgCode reports whether err is a PostgreSQL error with the given SQLSTATE
+func hasPgCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// isUniqueViolation reports whether err violates the named unique constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == constraint
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/pkg/generated"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// AuditSubscriberErased is recorded for each subscription erased on request of its address
+const AuditSubscriberErased = "subscriber.erased"
+
+// matchingSubscribers selects the real subscriptions of the address ($1 lowercased, $2 its blind
+// index), deleted and anonymized ones included
+const matchingSubscribers = `
+	SELECT s.id FROM subscribers s
+	WHERE (lower(s.email) = $1 OR s.email_hash = $2) AND NOT s.is_sample
+`
+
+// matchingChanges selects the address changes to or from the address, on its own subscriptions
+// or on others
+const matchingChanges = `
+	SELECT c.id FROM subscriber_email_changes c
+	WHERE c.subscriber_id IN (` + matchingSubscribers + `)
+		OR lower(c.old_email) = $1 OR c.old_email_hash = $2
+		OR lower(c.new_email) = $1 OR c.new_email_hash = $2
+`
+
+// GDPRRepository finds and erases everything stored about a subscriber's address, across all
+// newsletters, for data subject requests
+type GDPRRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewGDPRRepository creates the repository; with emails set, encrypted addresses are matched by
+// their blind index
+func NewGDPRRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *GDPRRepository {
+	return &GDPRRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// HasData reports whether any subscription or suppression of the address is stored
+func (r *GDPRRepository) HasData(ctx context.Context, email string) (bool, error) {
+	email = strings.ToLower(email)
+	query := `
+		SELECT EXISTS (` + matchingSubscribers + `)
+			OR EXISTS (SELECT 1 FROM email_suppressions WHERE email IN ($1, $3))
+			OR EXISTS (SELECT 1 FROM newsletter_suppressions WHERE email_key IN ($1, $3))
+	`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, email, r.emails.Index(email), r.emails.SuppressionKey(email)).Scan(&exists)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check for subscriber data", "error", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// Export collects the subscriptions, address changes, suppressions and emails of the address.
+// Emails come from the outbox and the kept failed deliveries, without their content.
+func (r *GDPRRepository) Export(ctx context.Context, email string) (*generated.SubscriberDataExport, error) {
+	email = strings.ToLower(email)
+	index := r.emails.Index(email)
+	export := &generated.SubscriberDataExport{
+		Email:         openapi_types.Email(email),
+		Subscriptions: []generated.SubscriberDataSubscription{},
+		EmailChanges:  []generated.SubscriberDataEmailChange{},
+		Suppressions:  []generated.SubscriberDataSuppression{},
+		Emails:        []generated.SubscriberDataEmail{},
+	}
+
+	// One snapshot, so a change in between cannot show up in one list but not in another
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT s.id, s.newsletter_id, n.name, s.subscribed_at, s.is_confirmed, s.confirmed_at,
+				s.confirmation_status, s.unsubscribed_at, s.deleted_at
+			FROM subscribers s
+			JOIN newsletters n ON n.id = s.newsletter_id
+			WHERE s.id IN (`+matchingSubscribers+`)
+			ORDER BY s.subscribed_at
+		`, email, index)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var sub generated.SubscriberDataSubscription
+			if err := rows.Scan(&sub.Id, &sub.NewsletterId, &sub.NewsletterName, &sub.SubscribedAt, &sub.IsConfirmed, &sub.ConfirmedAt,
+				&sub.ConfirmationEmailStatus, &sub.UnsubscribedAt, &sub.DeletedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			export.Subscriptions = append(export.Subscriptions, sub)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, `
+			SELECT c.subscriber_id, c.old_email, c.new_email, c.requested_at, c.confirmed_at
+			FROM subscriber_email_changes c
+			WHERE c.id IN (`+matchingChanges+`)
+			ORDER BY c.requested_at
+		`, email, index)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var change generated.SubscriberDataEmailChange
+			var oldEmail, newEmail string
+			if err := rows.Scan(&change.SubscriberId, &oldEmail, &newEmail, &change.RequestedAt, &change.ConfirmedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			if oldEmail, err = r.emails.Open(oldEmail); err != nil {
+				rows.Close()
+				return err
+			}
+			if newEmail, err = r.emails.Open(newEmail); err != nil {
+				rows.Close()
+				return err
+			}
+			change.OldEmail = openapi_types.Email(oldEmail)
+			change.NewEmail = openapi_types.Email(newEmail)
+			export.EmailChanges = append(export.EmailChanges, change)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, `
+			SELECT NULL::uuid, reason, created_at FROM email_suppressions WHERE email IN ($1, $2)
+			UNION ALL
+			SELECT newsletter_id, reason, created_at FROM newsletter_suppressions WHERE email_key IN ($1, $2)
+			ORDER BY created_at
+		`, email, r.emails.SuppressionKey(email))
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var suppression generated.SubscriberDataSuppression
+			if err := rows.Scan(&suppression.NewsletterId, &suppression.Reason, &suppression.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			export.Suppressions = append(export.Suppressions, suppression)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, `
+			SELECT newsletter_id, subject, status, created_at FROM email_outbox WHERE lower(recipient) = $1 OR recipient_hash = $2
+			UNION ALL
+			SELECT newsletter_id, subject, status, created_at FROM email_jobs WHERE lower(recipient) = $1 OR recipient_hash = $2
+			ORDER BY created_at
+		`, email, index)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var sent generated.SubscriberDataEmail
+			if err := rows.Scan(&sent.NewsletterId, &sent.Subject, &sent.Status, &sent.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			export.Emails = append(export.Emails, sent)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to export subscriber data", "error", err)
+		return nil, err
+	}
+	return export, nil
+}
+
+// Erase deletes everything stored about the address in one transaction and records an audit
+// entry, without the address, for each erased subscription. Post delivery reports keep their
+// counts; only the failures naming the address are removed.
+func (r *GDPRRepository) Erase(ctx context.Context, email string) (*generated.SubscriberErasure, error) {
+	email = strings.ToLower(email)
+	index := r.emails.Index(email)
+	keys := []string{email, r.emails.SuppressionKey(email)}
+	erased := &generated.SubscriberErasure{}
+
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Deleted before the subscriptions, whose changes would cascade uncounted; changes to or
+		// from the address recorded on other subscriptions go too
+		result, err := tx.Exec(ctx, `
+			DELETE FROM subscriber_email_changes
+			WHERE id IN (`+matchingChanges+`)
+		`, email, index)
+		if err != nil {
+			return err
+		}
+		erased.EmailChanges = result.RowsAffected()
+
+		err = tx.QueryRow(ctx, `
+			WITH erased AS (
+				DELETE FROM subscribers s
+				WHERE s.id IN (`+matchingSubscribers+`)
+				RETURNING s.id, s.newsletter_id, s.subscribed_at
+			), audited AS (
+				INSERT INTO audit_log (action, newsletter_id, target_id, details)
+				SELECT $3, e.newsletter_id, e.id, jsonb_build_object('subscribed_at', e.subscribed_at)
+				FROM erased e
+			)
+			SELECT count(*) FROM erased
+		`, email, index, AuditSubscriberErased).Scan(&erased.Subscriptions)
+		if err != nil {
+			return err
+		}
+
+		result, err = tx.Exec(ctx, `DELETE FROM email_suppressions WHERE email = ANY($1)`, keys)
+		if err != nil {
+			return err
+		}
+		erased.Suppressions = result.RowsAffected()
+		result, err = tx.Exec(ctx, `DELETE FROM newsletter_suppressions WHERE email_key = ANY($1)`, keys)
+		if err != nil {
+			return err
+		}
+		erased.Suppressions += result.RowsAffected()
+
+		// Incidents of erased failed deliveries cascade
+		result, err = tx.Exec(ctx, `DELETE FROM email_outbox WHERE lower(recipient) = $1 OR recipient_hash = $2`, email, index)
+		if err != nil {
+			return err
+		}
+		erased.Emails = result.RowsAffected()
+		result, err = tx.Exec(ctx, `DELETE FROM email_jobs WHERE lower(recipient) = $1 OR recipient_hash = $2`, email, index)
+		if err != nil {
+			return err
+		}
+		erased.Emails += result.RowsAffected()
+
+		// Subscriber events carry the address in their payload
+		result, err = tx.Exec(ctx, `
+			DELETE FROM webhook_deliveries
+			WHERE lower(payload->'data'->>'email') = $1 OR email_hash = $2
+		`, email, index)
+		if err != nil {
+			return err
+		}
+		erased.WebhookDeliveries = result.RowsAffected()
+
+		// Encrypted failures carry the hex blind index of their recipient
+		var recipientHash *string
+		if index != nil {
+			encoded := hex.EncodeToString(index)
+			recipientHash = &encoded
+		}
+		result, err = tx.Exec(ctx, `
+			UPDATE post_send_attempts
+			SET failures = COALESCE((
+				SELECT jsonb_agg(f ORDER BY ordinality)
+				FROM jsonb_array_elements(failures) WITH ORDINALITY AS e(f, ordinality)
+				WHERE NOT COALESCE(lower(f->>'recipient') = $1 OR f->>'recipient_hash' = $2, false)
+			), '[]'::jsonb)
+			WHERE EXISTS (
+				SELECT 1 FROM jsonb_array_elements(failures) f
+				WHERE lower(f->>'recipient') = $1 OR f->>'recipient_hash' = $2
+			)
+		`, email, recipientHash)
+		if err != nil {
+			return err
+		}
+		erased.DeliveryReports = result.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to erase subscriber data", "error", err)
+		return nil, err
+	}
+	return erased, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const inboxNotificationColumns = `id, kind, title, body, newsletter_id, post_id, read_at, created_at`
+
+// NewInboxNotification is a notification for the editor of a newsletter
+type NewInboxNotification struct {
+	NewsletterID uuid.UUID
+	PostID       *uuid.UUID
+	Kind         string
+	Title        string
+	Body         string
+}
+
+type InboxRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewInboxRepository(db *pgxpool.Pool, logger *slog.Logger) *InboxRepository {
+	return &InboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateForNewsletter adds a notification to the inbox of the newsletter's editor
+func (r *InboxRepository) CreateForNewsletter(ctx context.Context, n NewInboxNotification) error {
+	query := `
+		INSERT INTO inbox_notifications (editor_id, kind, title, body, newsletter_id, post_id)
+		SELECT editor_id, $2, $3, $4, id, $5
+		FROM newsletters
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(ctx, query, n.NewsletterID, n.Kind, n.Title, n.Body, n.PostID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create inbox notification", "newsletterId", n.NewsletterID, "kind", n.Kind, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListByEditor retrieves a page of the editor's notifications, newest first, optionally only
+// the unread ones
+func (r *InboxRepository) ListByEditor(ctx context.Context, editorID uuid.UUID, unreadOnly bool, page pagination.Page) ([]generated.InboxNotification, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + inboxNotificationColumns + `
+		FROM inbox_notifications
+		WHERE editor_id = $1
+		  AND (NOT $2 OR read_at IS NULL)
+		  AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, editorID, unreadOnly, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query inbox notifications", "editorId", editorID, "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	notifications := []generated.InboxNotification{}
+	for rows.Next() {
+		notification, err := scanInboxNotification(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan inbox notification row", "error", err)
+			return nil, nil, err
+		}
+		notifications = append(notifications, *notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating inbox notification rows", "error", err)
+		return nil, nil, err
+	}
+
+	notifications, next := pagination.Trim(notifications, page, func(n generated.InboxNotification) pagination.Cursor {
+		return pagination.Cursor{Time: *n.CreatedAt, ID: n.Id.String()}
+	})
+	return notifications, next, nil
+}
+
+// CountUnread returns how many of the editor's notifications are unread
+func (r *InboxRepository) CountUnread(ctx context.Context, editorID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM inbox_notifications WHERE editor_id = $1 AND read_at IS NULL`, editorID).Scan(&count)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count unread inbox notifications", "editorId", editorID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead marks a notification of the editor as read, keeping the time of an earlier read;
+// ErrNotFound when the editor has no such notification
+func (r *InboxRepository) MarkRead(ctx context.Context, editorID uuid.UUID, id uuid.UUID) error {
+	query := `
+		UPDATE inbox_notifications
+		SET read_at = COALESCE(read_at, now())
+		WHERE id = $1 AND editor_id = $2
+	`
+	tag, err := r.db.Exec(ctx, query, id, editorID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark inbox notification as read", "id", id, "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the editor as read and returns how many there were
+func (r *InboxRepository) MarkAllRead(ctx context.Context, editorID uuid.UUID) (int64, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE inbox_notifications SET read_at = now() WHERE editor_id = $1 AND read_at IS NULL`, editorID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark inbox notifications as read", "editorId", editorID, "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteOlderThan deletes up to limit notifications created more than days ago
+func (r *InboxRepository) DeleteOlderThan(ctx context.Context, days int, limit int) (int64, error) {
+	query := `
+		DELETE FROM inbox_notifications
+		WHERE id IN (
+			SELECT id FROM inbox_notifications
+			WHERE created_at < now() - make_interval(days => $1)
+			LIMIT $2
+		)
+	`
+	tag, err := r.db.Exec(ctx, query, days, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete old inbox notifications", "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanInboxNotification(row pgx.Row) (*generated.InboxNotification, error) {
+	var notification generated.InboxNotification
+	err := row.Scan(
+		&notification.Id,
+		&notification.Kind,
+		&notification.Title,
+		&notification.Body,
+		&notification.NewsletterId,
+		&notification.PostId,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
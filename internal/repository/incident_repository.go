@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const incidentColumns = `id, newsletter_id, post_id, email_job_id, scope, systemic, failed_count, message, created_at, resolved_at`
+
+// NewIncident is a permanently failed delivery to record
+type NewIncident struct {
+	NewsletterID uuid.UUID
+	PostID       *uuid.UUID
+	EmailJobID   *uuid.UUID
+	Scope        generated.IncidentScope
+	Systemic     bool
+	FailedCount  int
+	Message      string
+}
+
+type IncidentRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewIncidentRepository(db *pgxpool.Pool, logger *slog.Logger) *IncidentRepository {
+	return &IncidentRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *IncidentRepository) Create(ctx context.Context, incident NewIncident) (*generated.Incident, error) {
+	query := `
+		INSERT INTO incidents (newsletter_id, post_id, email_job_id, scope, systemic, failed_count, message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + incidentColumns
+	row := r.db.QueryRow(ctx, query,
+		incident.NewsletterID,
+		incident.PostID,
+		incident.EmailJobID,
+		incident.Scope,
+		incident.Systemic,
+		incident.FailedCount,
+		incident.Message,
+	)
+	created, err := scanIncident(row)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create incident", "newsletterId", incident.NewsletterID, "error", err)
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListByPost returns the incidents of a post, newest first
+func (r *IncidentRepository) ListByPost(ctx context.Context, postID uuid.UUID) ([]generated.Incident, error) {
+	query := `
+		SELECT ` + incidentColumns + `
+		FROM incidents
+		WHERE post_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, postID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query incidents", "postId", postID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	incidents := []generated.Incident{}
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan incident row", "error", err)
+			return nil, err
+		}
+		incidents = append(incidents, *incident)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating incident rows", "error", err)
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// NewsletterEditorEmail returns the auth email of the newsletter's editor
+func (r *IncidentRepository) NewsletterEditorEmail(ctx context.Context, newsletterID uuid.UUID) (string, error) {
+	query := `
+		SELECT u.email
+		FROM public.newsletters n
+		JOIN auth.users u ON u.id = n.editor_id
+		WHERE n.id = $1
+	`
+	var email string
+	if err := r.db.QueryRow(ctx, query, newsletterID).Scan(&email); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get newsletter editor email", "newsletterId", newsletterID, "error", err)
+		return "", err
+	}
+	return email, nil
+}
+
+func scanIncident(row pgx.Row) (*generated.Incident, error) {
+	incident := &generated.Incident{}
+	err := row.Scan(
+		&incident.Id,
+		&incident.NewsletterId,
+		&incident.PostId,
+		&incident.EmailJobId,
+		&incident.Scope,
+		&incident.Systemic,
+		&incident.FailedCount,
+		&incident.Message,
+		&incident.CreatedAt,
+		&incident.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
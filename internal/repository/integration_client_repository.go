@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const integrationClientColumns = `id, name, client_id, scopes, created_at, revoked_at`
+
+// IntegrationClientCredentials is what an active integration client authenticates with
+type IntegrationClientCredentials struct {
+	ID         uuid.UUID
+	EditorID   uuid.UUID
+	ClientID   string
+	SecretHash string
+	Scopes     []string
+}
+
+// AccessTokenOwner is the client and editor an active access token was issued for
+type AccessTokenOwner struct {
+	ClientID  uuid.UUID
+	EditorID  uuid.UUID
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+type IntegrationClientRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewIntegrationClientRepository(db *pgxpool.Pool, logger *slog.Logger) *IntegrationClientRepository {
+	return &IntegrationClientRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create registers a client of the editor by the hash of its secret
+func (r *IntegrationClientRepository) Create(ctx context.Context, editorID uuid.UUID, name string, clientID string, secretHash string, scopes []string) (*generated.IntegrationClient, error) {
+	query := `
+		INSERT INTO integration_clients (editor_id, name, client_id, secret_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + integrationClientColumns
+	client, err := scanIntegrationClient(r.db.QueryRow(ctx, query, editorID, name, clientID, secretHash, scopes))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create integration client", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListByEditor returns the editor's clients, newest first
+func (r *IntegrationClientRepository) ListByEditor(ctx context.Context, editorID uuid.UUID) ([]generated.IntegrationClient, error) {
+	query := `
+		SELECT ` + integrationClientColumns + `
+		FROM integration_clients
+		WHERE editor_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(ctx, query, editorID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query integration clients", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []generated.IntegrationClient{}
+	for rows.Next() {
+		client, err := scanIntegrationClient(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan integration client row", "error", err)
+			return nil, err
+		}
+		clients = append(clients, *client)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating integration client rows", "error", err)
+		return nil, err
+	}
+	return clients, nil
+}
+
+// CountActiveByEditor returns the number of the editor's clients that are not revoked
+func (r *IntegrationClientRepository) CountActiveByEditor(ctx context.Context, editorID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM integration_clients WHERE editor_id = $1 AND revoked_at IS NULL`, editorID).Scan(&count)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count integration clients", "editorId", editorID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Revoke revokes one of the editor's clients and deletes the tokens issued to it; ErrNotFound
+// when the editor has no such client. Revoking a revoked client keeps its revocation time.
+func (r *IntegrationClientRepository) Revoke(ctx context.Context, editorID uuid.UUID, id uuid.UUID) error {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE integration_clients
+			SET revoked_at = COALESCE(revoked_at, now())
+			WHERE id = $1 AND editor_id = $2
+		`, id, editorID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM oauth_access_tokens WHERE client_id = $1`, id)
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.logger.ErrorContext(ctx, "Failed to revoke integration client", "id", id, "error", err)
+	}
+	return err
+}
+
+// GetActiveCredentials returns the active client with the given client ID; ErrNotFound when
+// there is none or it was revoked
+func (r *IntegrationClientRepository) GetActiveCredentials(ctx context.Context, clientID string) (*IntegrationClientCredentials, error) {
+	query := `
+		SELECT id, editor_id, client_id, secret_hash, scopes
+		FROM integration_clients
+		WHERE client_id = $1 AND revoked_at IS NULL
+	`
+	var c IntegrationClientCredentials
+	err := r.db.QueryRow(ctx, query, clientID).Scan(&c.ID, &c.EditorID, &c.ClientID, &c.SecretHash, &c.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up integration client", "error", err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateToken stores an access token of a client by its hash
+func (r *IntegrationClientRepository) CreateToken(ctx context.Context, clientID uuid.UUID, tokenHash string, scopes []string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO oauth_access_tokens (token_hash, client_id, scopes, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := r.db.Exec(ctx, query, tokenHash, clientID, scopes, expiresAt); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to store access token", "clientId", clientID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetActiveToken returns the owner of the unexpired token with the given hash; ErrNotFound when
+// there is none or its client was revoked
+func (r *IntegrationClientRepository) GetActiveToken(ctx context.Context, tokenHash string) (*AccessTokenOwner, error) {
+	query := `
+		SELECT t.client_id, c.editor_id, t.scopes, t.expires_at
+		FROM oauth_access_tokens t
+		JOIN integration_clients c ON c.id = t.client_id
+		WHERE t.token_hash = $1
+		  AND t.expires_at > now()
+		  AND c.revoked_at IS NULL
+	`
+	var owner AccessTokenOwner
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&owner.ClientID, &owner.EditorID, &owner.Scopes, &owner.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up access token", "error", err)
+		return nil, err
+	}
+	return &owner, nil
+}
+
+// DeleteExpiredTokens deletes up to limit access tokens that have expired
+func (r *IntegrationClientRepository) DeleteExpiredTokens(ctx context.Context, limit int) (int64, error) {
+	query := `
+		DELETE FROM oauth_access_tokens
+		WHERE token_hash IN (
+			SELECT token_hash FROM oauth_access_tokens
+			WHERE expires_at <= now()
+			LIMIT $1
+		)
+	`
+	tag, err := r.db.Exec(ctx, query, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete expired access tokens", "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanIntegrationClient(row pgx.Row) (*generated.IntegrationClient, error) {
+	var client generated.IntegrationClient
+	err := row.Scan(
+		&client.Id,
+		&client.Name,
+		&client.ClientId,
+		&client.Scopes,
+		&client.CreatedAt,
+		&client.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Roles of newsletter members, from most to least access
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// NewInvitation is an invitation to join a newsletter about to be stored
+type NewInvitation struct {
+	NewsletterID uuid.UUID
+	Email        string
+	Role         string
+	InvitedBy    uuid.UUID
+	ExpiresAt    time.Time
+}
+
+const memberColumns = `m.newsletter_id, m.editor_id, u.email, p.full_name, m.role, m.created_at`
+
+const invitationColumns = `id, newsletter_id, email, role, expires_at, created_at`
+
+type MemberRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewMemberRepository(db *pgxpool.Pool, logger *slog.Logger) *MemberRepository {
+	return &MemberRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetRole returns the role of the editor in the newsletter; ErrNotFound when they are not a member
+func (r *MemberRepository) GetRole(ctx context.Context, newsletterID uuid.UUID, editorID uuid.UUID) (string, error) {
+	var role string
+	err := r.db.QueryRow(ctx, `SELECT role FROM newsletter_members WHERE newsletter_id = $1 AND editor_id = $2`, newsletterID, editorID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up newsletter member role", "newsletterId", newsletterID, "editorId", editorID, "error", err)
+		return "", err
+	}
+	return role, nil
+}
+
+// List returns the members of the newsletter, the owner first and the others in the order they joined
+func (r *MemberRepository) List(ctx context.Context, newsletterID uuid.UUID) ([]generated.NewsletterMember, error) {
+	query := `
+		SELECT ` + memberColumns + `
+		FROM newsletter_members m
+		JOIN auth.users u ON u.id = m.editor_id
+		LEFT JOIN public.profiles p ON p.id = m.editor_id
+		WHERE m.newsletter_id = $1
+		ORDER BY m.role = 'owner' DESC, m.created_at, m.editor_id
+	`
+	rows, err := r.db.Query(ctx, query, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query newsletter members", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []generated.NewsletterMember{}
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan newsletter member row", "error", err)
+			return nil, err
+		}
+		members = append(members, *member)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating newsletter member rows", "error", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// IsMemberEmail reports whether an editor signed up with the email is a member of the newsletter
+func (r *MemberRepository) IsMemberEmail(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM newsletter_members m
+			JOIN auth.users u ON u.id = m.editor_id
+			WHERE m.newsletter_id = $1 AND lower(u.email) = lower($2)
+		)
+	`
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, newsletterID, email).Scan(&exists); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check newsletter member email", "newsletterId", newsletterID, "error", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// Remove removes a member who is not the owner; ErrNotFound when there is no such member
+func (r *MemberRepository) Remove(ctx context.Context, newsletterID uuid.UUID, editorID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM newsletter_members
+		WHERE newsletter_id = $1 AND editor_id = $2 AND role <> $3
+	`, newsletterID, editorID, RoleOwner)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to remove newsletter member", "newsletterId", newsletterID, "editorId", editorID, "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateInvitation stores an invitation by the hash of its token, replacing the pending
+// invitation of the address, and deletes the newsletter's expired invitations
+func (r *MemberRepository) CreateInvitation(ctx context.Context, tokenHash string, inv NewInvitation) (*generated.NewsletterInvitation, error) {
+	var invitation *generated.NewsletterInvitation
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM newsletter_invitations
+			WHERE newsletter_id = $1 AND accepted_at IS NULL AND (lower(email) = lower($2) OR expires_at <= now())
+		`, inv.NewsletterID, inv.Email)
+		if err != nil {
+			return err
+		}
+
+		invitation, err = scanInvitation(tx.QueryRow(ctx, `
+			INSERT INTO newsletter_invitations (newsletter_id, email, role, token_hash, invited_by, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+invitationColumns,
+			inv.NewsletterID, inv.Email, inv.Role, tokenHash, inv.InvitedBy, inv.ExpiresAt,
+		))
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to store newsletter invitation", "newsletterId", inv.NewsletterID, "error", err)
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetPendingInvitation returns the unexpired, unaccepted invitation with the token hash to a
+// newsletter that was not deleted; ErrNotFound when there is none
+func (r *MemberRepository) GetPendingInvitation(ctx context.Context, tokenHash string) (*generated.NewsletterInvitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM newsletter_invitations i
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > now()
+		  AND EXISTS (SELECT 1 FROM newsletters n WHERE n.id = i.newsletter_id AND n.deleted_at IS NULL)
+	`
+	invitation, err := scanInvitation(r.db.QueryRow(ctx, query, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up newsletter invitation", "error", err)
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// AcceptInvitation makes the editor a member with the role of the pending invitation and marks
+// it accepted. An editor who is a member already keeps their role. ErrNotFound when the
+// invitation expired or was accepted meanwhile.
+func (r *MemberRepository) AcceptInvitation(ctx context.Context, tokenHash string, editorID uuid.UUID) (*generated.NewsletterMember, error) {
+	var member *generated.NewsletterMember
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var newsletterID uuid.UUID
+		var role string
+		err := tx.QueryRow(ctx, `
+			UPDATE newsletter_invitations
+			SET accepted_at = now()
+			WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > now()
+			  AND EXISTS (SELECT 1 FROM newsletters n WHERE n.id = newsletter_id AND n.deleted_at IS NULL)
+			RETURNING newsletter_id, role
+		`, tokenHash).Scan(&newsletterID, &role)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO newsletter_members (newsletter_id, editor_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (newsletter_id, editor_id) DO NOTHING
+		`, newsletterID, editorID, role)
+		if err != nil {
+			return err
+		}
+
+		member, err = scanMember(tx.QueryRow(ctx, `
+			SELECT `+memberColumns+`
+			FROM newsletter_members m
+			JOIN auth.users u ON u.id = m.editor_id
+			LEFT JOIN public.profiles p ON p.id = m.editor_id
+			WHERE m.newsletter_id = $1 AND m.editor_id = $2
+		`, newsletterID, editorID))
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.logger.ErrorContext(ctx, "Failed to accept newsletter invitation", "editorId", editorID, "error", err)
+	}
+	return member, err
+}
+
+func scanMember(row pgx.Row) (*generated.NewsletterMember, error) {
+	var m generated.NewsletterMember
+	err := row.Scan(&m.NewsletterId, &m.EditorId, &m.Email, &m.FullName, &m.Role, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func scanInvitation(row pgx.Row) (*generated.NewsletterInvitation, error) {
+	var i generated.NewsletterInvitation
+	err := row.Scan(&i.Id, &i.NewsletterId, &i.Email, &i.Role, &i.ExpiresAt, &i.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
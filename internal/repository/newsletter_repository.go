@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/pkg/generated"
 	"log/slog"
 	"time"
@@ -17,6 +18,35 @@ type NewsletterRepository struct {
 	logger *slog.Logger
 }
 
+// newsletterColumns is the column list scanned by scanNewsletter
+const newsletterColumns = `id, name, description, editor_id, created_at, updated_at, catch_up_policy, catch_up_max_age_minutes, unconfirmed_retention_days, public_badge, search_indexing, double_opt_in, require_post_approval, deleted_at`
+
+// scanNewsletter scans a row selected with newsletterColumns, followed by any extra columns
+func scanNewsletter(row pgx.Row, n *generated.Newsletter, extra ...any) error {
+	var catchUpPolicy generated.CatchUpPolicy
+	dest := []any{
+		&n.Id,
+		&n.Name,
+		&n.Description,
+		&n.EditorId,
+		&n.CreatedAt,
+		&n.UpdatedAt,
+		&catchUpPolicy,
+		&n.CatchUpMaxAgeMinutes,
+		&n.UnconfirmedRetentionDays,
+		&n.PublicBadge,
+		&n.SearchIndexing,
+		&n.DoubleOptIn,
+		&n.RequirePostApproval,
+		&n.DeletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	n.CatchUpPolicy = &catchUpPolicy
+	return nil
+}
+
 func NewNewsletterRepository(db *pgxpool.Pool, logger *slog.Logger) *NewsletterRepository {
 	return &NewsletterRepository{
 		db:     db,
@@ -24,58 +54,121 @@ func NewNewsletterRepository(db *pgxpool.Pool, logger *slog.Logger) *NewsletterR
 	}
 }
 
-// Retrieves a list of newsletters owned by the authenticated editor.
-func (r *NewsletterRepository) GetNewslettersOwnedByEditor(ctx context.Context, editorID string) ([]generated.Newsletter, error) {
+// memberOfNewsletter is true for newsletters the editor in $1 is a member of, owners included
+const memberOfNewsletter = `id IN (SELECT newsletter_id FROM newsletter_members WHERE editor_id = $1)`
+
+// newsletterCursor is the keyset position of a newsletter in listings sorted by created_at
+func newsletterCursor(n generated.Newsletter) pagination.Cursor {
+	return pagination.Cursor{Time: *n.CreatedAt, ID: n.Id.String()}
+}
+
+// Retrieves a page of the newsletters the authenticated editor owns or is a member of, newest first.
+func (r *NewsletterRepository) GetNewslettersOwnedByEditor(ctx context.Context, editorID string, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
 	query := `
-		SELECT id, name, description, editor_id, created_at, updated_at
+		SELECT ` + newsletterColumns + `
 		FROM public.newsletters
-		WHERE editor_id = $1
-		ORDER BY created_at DESC
+		WHERE ` + memberOfNewsletter + ` AND deleted_at IS NULL
+		  AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
 	`
-	rows, err := r.db.Query(ctx, query, editorID)
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, editorID, after, afterID, page.FetchLimit())
 	if err != nil {
 		r.logger.ErrorContext(ctx, "REPO: Failed to get all newsletters", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 	var newsletters []generated.Newsletter
 	for rows.Next() {
 		var n generated.Newsletter
-		if err := rows.Scan(&n.Id,
-			&n.Name,
-			&n.Description,
-			&n.EditorId,
-			&n.CreatedAt,
-			&n.UpdatedAt); err != nil {
+		if err := scanNewsletter(rows, &n); err != nil {
 			r.logger.ErrorContext(ctx, "Failed to scan newsletter row", "error", err)
-			return nil, err
+			return nil, nil, err
 		}
 		newsletters = append(newsletters, n)
 	}
 
 	if err := rows.Err(); err != nil {
 		r.logger.ErrorContext(ctx, "Error iterating newsletter rows", "error", err)
-		return nil, err
+		return nil, nil, err
+	}
+
+	newsletters, next := pagination.Trim(newsletters, page, newsletterCursor)
+	return newsletters, next, nil
+
+}
+
+// NewsletterListOptions selects the optional aggregates embedded in newsletter listings
+type NewsletterListOptions struct {
+	IncludeSubscriberCount bool
+	IncludeLastPublishedAt bool
+}
+
+// GetNewslettersOwnedByEditorEnriched lists a page of the newsletters the editor owns or is a member of together with the requested aggregates.
+// Aggregates are computed with lateral joins in a single query instead of one follow-up query per newsletter.
+func (r *NewsletterRepository) GetNewslettersOwnedByEditorEnriched(ctx context.Context, editorID string, opts NewsletterListOptions, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
+	query := `
+		SELECT n.*, sc.subscriber_count, lp.last_published_at
+		FROM (
+			SELECT ` + newsletterColumns + `
+			FROM public.newsletters
+			WHERE ` + memberOfNewsletter + ` AND deleted_at IS NULL
+			  AND ($4::timestamptz IS NULL OR (created_at, id) < ($4, $5::uuid))
+			ORDER BY created_at DESC, id DESC
+			LIMIT $6
+		) n
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS subscriber_count
+			FROM public.subscribers s
+			WHERE $2 AND s.newsletter_id = n.id AND s.unsubscribed_at IS NULL AND s.deleted_at IS NULL AND NOT s.is_sample
+		) sc ON true
+		LEFT JOIN LATERAL (
+			SELECT MAX(p.published_at) AS last_published_at
+			FROM public.published_posts p
+			WHERE $3 AND p.newsletter_id = n.id AND p.published_at IS NOT NULL AND p.deleted_at IS NULL
+		) lp ON true
+		ORDER BY n.created_at DESC, n.id DESC
+	`
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, editorID, opts.IncludeSubscriberCount, opts.IncludeLastPublishedAt, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: Failed to get enriched newsletters", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var newsletters []generated.Newsletter
+	for rows.Next() {
+		var n generated.Newsletter
+		var subscriberCount int64
+		if err := scanNewsletter(rows, &n, &subscriberCount, &n.LastPublishedAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan newsletter row", "error", err)
+			return nil, nil, err
+		}
+		if opts.IncludeSubscriberCount {
+			n.SubscriberCount = &subscriberCount
+		}
+		newsletters = append(newsletters, n)
 	}
 
-	return newsletters, nil
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating newsletter rows", "error", err)
+		return nil, nil, err
+	}
 
+	newsletters, next := pagination.Trim(newsletters, page, newsletterCursor)
+	return newsletters, next, nil
 }
 
 func (r *NewsletterRepository) GetByID(ctx context.Context, newsletterID string) (*generated.Newsletter, error) {
 	query := `
-		SELECT id, name, description, editor_id, created_at, updated_at
+		SELECT ` + newsletterColumns + `
 		FROM public.newsletters
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	var n generated.Newsletter
-	err := r.db.QueryRow(ctx, query, newsletterID).Scan(
-		&n.Id,
-		&n.Name,
-		&n.Description,
-		&n.EditorId,
-		&n.CreatedAt,
-		&n.UpdatedAt)
+	err := scanNewsletter(r.db.QueryRow(ctx, query, newsletterID), &n)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			r.logger.ErrorContext(ctx, "REPO: Newsletter not found", "id", newsletterID)
@@ -90,9 +183,15 @@ func (r *NewsletterRepository) GetByID(ctx context.Context, newsletterID string)
 
 func (r *NewsletterRepository) Create(ctx context.Context, editorID string, newsletterCreate *generated.NewsletterCreate) (*generated.Newsletter, error) {
 	query := `
-	INSERT INTO public.newsletters (id, name, description, editor_id, created_at, updated_at)
+	WITH created AS (
+		INSERT INTO public.newsletters (id, name, description, editor_id, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, name, description, editor_id, created_at, updated_at
+		RETURNING ` + newsletterColumns + `
+	), owner AS (
+		INSERT INTO newsletter_members (newsletter_id, editor_id, role)
+		SELECT id, editor_id, 'owner' FROM created
+	)
+	SELECT ` + newsletterColumns + ` FROM created
 	`
 
 	// ProfileRepo uses SQL NOW() func for this part.
@@ -100,21 +199,14 @@ func (r *NewsletterRepository) Create(ctx context.Context, editorID string, news
 	now := time.Now()
 
 	var n generated.Newsletter
-	err := r.db.QueryRow(ctx, query,
+	err := scanNewsletter(r.db.QueryRow(ctx, query,
 		id,
 		newsletterCreate.Name,
 		newsletterCreate.Description,
 		editorID,
 		now,
 		now,
-	).Scan(
-		&n.Id,
-		&n.Name,
-		&n.Description,
-		&n.EditorId,
-		&n.CreatedAt,
-		&n.UpdatedAt,
-	)
+	), &n)
 
 	if err != nil {
 		r.logger.Error("REPO: failed to create newsletter", "error", err)
@@ -142,22 +234,49 @@ func (r *NewsletterRepository) Update(ctx context.Context, newsletterID string,
 		description = newsletterUpdate.Description
 	}
 
+	catchUpPolicy := current.CatchUpPolicy
+	catchUpMaxAge := current.CatchUpMaxAgeMinutes
+	if newsletterUpdate.CatchUpPolicy != nil {
+		catchUpPolicy = newsletterUpdate.CatchUpPolicy
+	}
+	if newsletterUpdate.CatchUpMaxAgeMinutes != nil {
+		catchUpMaxAge = newsletterUpdate.CatchUpMaxAgeMinutes
+	}
+
+	retentionDays := current.UnconfirmedRetentionDays
+	if newsletterUpdate.UnconfirmedRetentionDays != nil {
+		retentionDays = newsletterUpdate.UnconfirmedRetentionDays
+	}
+
+	publicBadge := current.PublicBadge
+	if newsletterUpdate.PublicBadge != nil {
+		publicBadge = newsletterUpdate.PublicBadge
+	}
+
+	searchIndexing := current.SearchIndexing
+	if newsletterUpdate.SearchIndexing != nil {
+		searchIndexing = newsletterUpdate.SearchIndexing
+	}
+
+	doubleOptIn := current.DoubleOptIn
+	if newsletterUpdate.DoubleOptIn != nil {
+		doubleOptIn = newsletterUpdate.DoubleOptIn
+	}
+
+	requirePostApproval := current.RequirePostApproval
+	if newsletterUpdate.RequirePostApproval != nil {
+		requirePostApproval = newsletterUpdate.RequirePostApproval
+	}
+
 	query := `
 		UPDATE public.newsletters
-		SET name = $2, description = $3, updated_at = $4
-		WHERE id = $1
-		RETURNING id, name, description, editor_id, created_at, updated_at
+		SET name = $2, description = $3, updated_at = $4, catch_up_policy = $5, catch_up_max_age_minutes = $6, unconfirmed_retention_days = $7, public_badge = $8, search_indexing = $9, double_opt_in = $10, require_post_approval = $11
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + newsletterColumns + `
 	`
 	now := time.Now()
 	var n generated.Newsletter
-	err = r.db.QueryRow(ctx, query, newsletterID, name, description, now).Scan(
-		&n.Id,
-		&n.Name,
-		&n.Description,
-		&n.EditorId,
-		&n.CreatedAt,
-		&n.UpdatedAt,
-	)
+	err = scanNewsletter(r.db.QueryRow(ctx, query, newsletterID, name, description, now, catchUpPolicy, catchUpMaxAge, retentionDays, publicBadge, searchIndexing, doubleOptIn, requirePostApproval), &n)
 	if err != nil {
 		r.logger.Error("REPO: failed to update newsletter", "error", err)
 		return nil, err
@@ -166,10 +285,34 @@ func (r *NewsletterRepository) Update(ctx context.Context, newsletterID string,
 	return &n, nil
 }
 
+// ApplyConfig replaces the newsletter's name and settings as a whole, e.g. from an imported
+// config bundle. Unlike Update, nil settings clear the column instead of keeping it.
+func (r *NewsletterRepository) ApplyConfig(ctx context.Context, newsletterID string, name string, settings generated.NewsletterSettings) (*generated.Newsletter, error) {
+	query := `
+		UPDATE public.newsletters
+		SET name = $2, description = $3, updated_at = now(), catch_up_policy = $4, catch_up_max_age_minutes = $5, unconfirmed_retention_days = $6, public_badge = COALESCE($7, false), search_indexing = COALESCE($8, true), double_opt_in = COALESCE($9, true), require_post_approval = COALESCE($10, false)
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + newsletterColumns + `
+	`
+	var n generated.Newsletter
+	err := scanNewsletter(r.db.QueryRow(ctx, query, newsletterID, name, settings.Description, settings.CatchUpPolicy, settings.CatchUpMaxAgeMinutes, settings.UnconfirmedRetentionDays, settings.PublicBadge, settings.SearchIndexing, settings.DoubleOptIn, settings.RequirePostApproval), &n)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.NewNotFoundError("Newsletter not found")
+		}
+		r.logger.ErrorContext(ctx, "REPO: failed to apply newsletter config", "error", err)
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// Delete marks the newsletter deleted; it is hidden from then on until it is restored
 func (r *NewsletterRepository) Delete(ctx context.Context, newsletterID string) error {
 	query := `
-		DELETE FROM public.newsletters
-		WHERE id = $1
+		UPDATE public.newsletters
+		SET deleted_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	result, err := r.db.Exec(ctx, query, newsletterID)
 	if err != nil {
@@ -186,46 +329,48 @@ func (r *NewsletterRepository) Delete(ctx context.Context, newsletterID string)
 	return nil
 }
 
-func (r *NewsletterRepository) AdminGetAll(ctx context.Context) ([]generated.Newsletter, error) {
+func (r *NewsletterRepository) AdminGetAll(ctx context.Context, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
 	query := `
-		SELECT id, name, description, editor_id, created_at, updated_at
+		SELECT ` + newsletterColumns + `
 		FROM public.newsletters
-		ORDER BY created_at DESC
+		WHERE deleted_at IS NULL
+		  AND ($1::timestamptz IS NULL OR (created_at, id) < ($1, $2::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
 	`
-	rows, err := r.db.Query(ctx, query)
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, after, afterID, page.FetchLimit())
 	if err != nil {
 		r.logger.ErrorContext(ctx, "REPO: Failed to get all newsletters", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	var newsletters []generated.Newsletter
 	for rows.Next() {
 		var n generated.Newsletter
-		if err := rows.Scan(&n.Id,
-			&n.Name,
-			&n.Description,
-			&n.EditorId,
-			&n.CreatedAt,
-			&n.UpdatedAt); err != nil {
+		if err := scanNewsletter(rows, &n); err != nil {
 			r.logger.ErrorContext(ctx, "Failed to scan newsletter row", "error", err)
-			return nil, err
+			return nil, nil, err
 		}
 		newsletters = append(newsletters, n)
 	}
 
 	if err := rows.Err(); err != nil {
 		r.logger.ErrorContext(ctx, "Error iterating newsletter rows", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	return newsletters, nil
+	newsletters, next := pagination.Trim(newsletters, page, newsletterCursor)
+	return newsletters, next, nil
 }
 
+// AdminDeleteByID marks any newsletter deleted, like Delete
 func (r *NewsletterRepository) AdminDeleteByID(ctx context.Context, newsletterID string) error {
 	query := `
-		DELETE FROM public.newsletters
-		WHERE id = $1
+		UPDATE public.newsletters
+		SET deleted_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	result, err := r.db.Exec(ctx, query, newsletterID)
 	if err != nil {
@@ -242,6 +387,114 @@ func (r *NewsletterRepository) AdminDeleteByID(ctx context.Context, newsletterID
 	return nil
 }
 
+// GetDeleted retrieves a page of the newsletters deleted after since, most recently deleted
+// first
+func (r *NewsletterRepository) GetDeleted(ctx context.Context, since time.Time, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + newsletterColumns + `
+		FROM public.newsletters
+		WHERE deleted_at > $1
+		  AND ($2::timestamptz IS NULL OR (deleted_at, id) < ($2, $3::uuid))
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $4
+	`
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, since, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: Failed to get deleted newsletters", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var newsletters []generated.Newsletter
+	for rows.Next() {
+		var n generated.Newsletter
+		if err := scanNewsletter(rows, &n); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan newsletter row", "error", err)
+			return nil, nil, err
+		}
+		newsletters = append(newsletters, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating newsletter rows", "error", err)
+		return nil, nil, err
+	}
+
+	newsletters, next := pagination.Trim(newsletters, page, func(n generated.Newsletter) pagination.Cursor {
+		return pagination.Cursor{Time: *n.DeletedAt, ID: n.Id.String()}
+	})
+	return newsletters, next, nil
+}
+
+// GetDeletedByID retrieves a newsletter deleted after since
+func (r *NewsletterRepository) GetDeletedByID(ctx context.Context, newsletterID string, since time.Time) (*generated.Newsletter, error) {
+	query := `
+		SELECT ` + newsletterColumns + `
+		FROM public.newsletters
+		WHERE id = $1 AND deleted_at > $2
+	`
+	var n generated.Newsletter
+	err := scanNewsletter(r.db.QueryRow(ctx, query, newsletterID, since), &n)
+	if err == pgx.ErrNoRows {
+		return nil, models.NewNotFoundError("Deleted newsletter not found")
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: Failed to get deleted newsletter", "id", newsletterID, "error", err)
+		return nil, err
+	}
+	return &n, nil
+}
+
+// Restore undeletes a newsletter deleted after since, with its subscribers and the posts that
+// were not deleted on their own
+func (r *NewsletterRepository) Restore(ctx context.Context, newsletterID string, since time.Time) (*generated.Newsletter, error) {
+	query := `
+		UPDATE public.newsletters
+		SET deleted_at = NULL, updated_at = now()
+		WHERE id = $1 AND deleted_at > $2
+		RETURNING ` + newsletterColumns + `
+	`
+	var n generated.Newsletter
+	err := scanNewsletter(r.db.QueryRow(ctx, query, newsletterID, since), &n)
+	if err == pgx.ErrNoRows {
+		return nil, models.NewNotFoundError("Deleted newsletter not found")
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to restore newsletter", "id", newsletterID, "error", err)
+		return nil, err
+	}
+	return &n, nil
+}
+
+// PurgeDeleted deletes up to limit newsletters deleted before the given time for good, with
+// their posts, subscribers and members, in one statement; rows that reference those cascade.
+// Rows locked by another run are skipped. Returns how many newsletters were deleted.
+func (r *NewsletterRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		WITH purged AS (
+			SELECT id FROM newsletters
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), posts AS (
+			DELETE FROM published_posts WHERE newsletter_id IN (SELECT id FROM purged)
+		), subscribers AS (
+			DELETE FROM subscribers WHERE newsletter_id IN (SELECT id FROM purged)
+		), members AS (
+			DELETE FROM newsletter_members WHERE newsletter_id IN (SELECT id FROM purged)
+		)
+		DELETE FROM newsletters WHERE id IN (SELECT id FROM purged)
+	`
+	result, err := r.db.Exec(ctx, query, before, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to purge deleted newsletters", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 // CheckDuplicateName checks if an editor already has a newsletter with the given name
 func (r *NewsletterRepository) CheckDuplicateName(ctx context.Context, editorID string, name string, excludeID string) (bool, error) {
 	query := `
@@ -249,6 +502,7 @@ func (r *NewsletterRepository) CheckDuplicateName(ctx context.Context, editorID
 			SELECT 1
 			FROM public.newsletters
 			WHERE editor_id = $1::uuid
+			AND deleted_at IS NULL
 			AND LOWER(name) = LOWER($2)
 			AND (CASE 
 				WHEN $3 = '' THEN true
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditSubscribersNotified is the audit action recorded when an admin emails all subscribers of a newsletter
+const AuditSubscribersNotified = "newsletter.subscribers_notified"
+
+// NewNotification is an admin-authored message to the subscribers of a newsletter
+type NewNotification struct {
+	NewsletterID uuid.UUID
+	AdminID      uuid.UUID
+	Subject      string
+	ContentHTML  string
+	Reason       string
+	// ScheduledAt is when the emails are sent; nil sends them right away
+	ScheduledAt *time.Time
+}
+
+type NotificationRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewNotificationRepository creates the repository; with emails set, recipients of queued
+// notification emails are stored encrypted
+func NewNotificationRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *NotificationRepository {
+	return &NotificationRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// Create records a notification, queues its emails in the outbox for its scheduled time and
+// writes the audit entry, in one transaction. It also returns the auth email of the
+// newsletter's editor, empty when the editor has none.
+func (r *NotificationRepository) Create(ctx context.Context, notification NewNotification, emails []NewOutboxEmail) (*generated.SubscriberNotification, string, error) {
+	created := &generated.SubscriberNotification{}
+	var editorEmail string
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO subscriber_notifications (newsletter_id, admin_id, subject, content_html, reason, recipient_count, scheduled_at)
+			VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, now()))
+			RETURNING id, newsletter_id, admin_id, subject, reason, recipient_count, scheduled_at, created_at
+		`, notification.NewsletterID, notification.AdminID, notification.Subject, notification.ContentHTML, notification.Reason, len(emails), notification.ScheduledAt).Scan(
+			&created.Id,
+			&created.NewsletterId,
+			&created.AdminId,
+			&created.Subject,
+			&created.Reason,
+			&created.RecipientCount,
+			&created.ScheduledAt,
+			&created.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		id := uuid.UUID(*created.Id)
+		for i := range emails {
+			emails[i].NotificationID = &id
+			emails[i].AvailableAt = created.ScheduledAt
+		}
+		if len(emails) > 0 {
+			batch, err := outboxBatch(r.emails, emails)
+			if err != nil {
+				return err
+			}
+			if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO audit_log (action, actor_id, newsletter_id, target_id, details)
+			VALUES ($1, $2, $3, $4, jsonb_build_object('subject', $5::text, 'reason', $6::text, 'recipient_count', $7::int, 'scheduled_at', $8::timestamptz))
+		`, AuditSubscribersNotified, notification.AdminID, notification.NewsletterID, id, notification.Subject, notification.Reason, len(emails), created.ScheduledAt)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `
+			SELECT u.email
+			FROM public.newsletters n
+			JOIN auth.users u ON u.id = n.editor_id
+			WHERE n.id = $1
+		`, notification.NewsletterID).Scan(&editorEmail)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create subscriber notification", "newsletterId", notification.NewsletterID, "error", err)
+		return nil, "", err
+	}
+	return created, editorEmail, nil
+}
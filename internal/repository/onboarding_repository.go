@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/models/enums"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OnboardingState tells which setup steps an editor has done
+type OnboardingState struct {
+	ProfileFilled     bool
+	NewsletterCreated bool
+	SenderVerified    bool
+	FirstSubscriber   bool
+	FirstPost         bool
+}
+
+type OnboardingRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewOnboardingRepository(db *pgxpool.Pool, logger *slog.Logger) *OnboardingRepository {
+	return &OnboardingRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetState computes the editor's setup steps from their profile, auth account and newsletters
+func (r *OnboardingRepository) GetState(ctx context.Context, editorID uuid.UUID) (*OnboardingState, error) {
+	query := `
+		SELECT
+			COALESCE(btrim(p.full_name) <> '', false),
+			EXISTS (SELECT 1 FROM newsletters n WHERE n.editor_id = me.id AND n.deleted_at IS NULL),
+			EXISTS (SELECT 1 FROM auth.users u WHERE u.id = me.id AND u.email_confirmed_at IS NOT NULL),
+			EXISTS (
+				SELECT 1 FROM subscribers s
+				JOIN newsletters n ON n.id = s.newsletter_id
+				WHERE n.editor_id = me.id AND n.deleted_at IS NULL AND s.is_confirmed AND NOT s.is_sample AND s.deleted_at IS NULL
+			),
+			EXISTS (
+				SELECT 1 FROM published_posts pp
+				JOIN newsletters n ON n.id = pp.newsletter_id
+				WHERE n.editor_id = me.id AND n.deleted_at IS NULL AND pp.status = $2 AND pp.deleted_at IS NULL
+			)
+		FROM (SELECT $1::uuid AS id) me
+		LEFT JOIN profiles p ON p.id = me.id
+	`
+
+	var state OnboardingState
+	err := r.db.QueryRow(ctx, query, editorID, enums.Posted.String()).Scan(
+		&state.ProfileFilled,
+		&state.NewsletterCreated,
+		&state.SenderVerified,
+		&state.FirstSubscriber,
+		&state.FirstPost,
+	)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to compute onboarding state", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	return &state, nil
+}
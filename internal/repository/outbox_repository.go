@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/emailcrypt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// NewOutboxEmail is an email to queue for sending; it belongs to either a post or a
+// subscriber notification
+type NewOutboxEmail struct {
+	NewsletterID   uuid.UUID
+	PostID         *uuid.UUID
+	NotificationID *uuid.UUID
+	Recipient      string
+	Subject        string
+	HTML           string
+	CorrelationID  string
+	// ListUnsubscribe is the recipient's one-click unsubscribe URL, empty for none
+	ListUnsubscribe string
+	// AvailableAt delays sending until then; nil sends as soon as possible
+	AvailableAt *time.Time
+	// MessageID identifies a post email in its open and click tracking; uuid.Nil when untracked
+	MessageID uuid.UUID
+}
+
+// OutboxEmail is a queued email claimed for sending
+type OutboxEmail struct {
+	ID              uuid.UUID
+	NewsletterID    uuid.UUID
+	PostID          *uuid.UUID
+	NotificationID  *uuid.UUID
+	Recipient       string
+	Subject         string
+	HTML            string
+	CorrelationID   string
+	ListUnsubscribe string
+	// Attempts counts the dispatches of the email, including the current one
+	Attempts int
+}
+
+type OutboxRepository struct {
+	db     BatchDB
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewOutboxRepository creates the repository; with emails set, recipients are stored encrypted
+func NewOutboxRepository(db BatchDB, emails *emailcrypt.Cipher, logger *slog.Logger) *OutboxRepository {
+	return &OutboxRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// Enqueue queues emails for the outbox dispatcher in a single batch, which runs as one
+// transaction
+func (r *OutboxRepository) Enqueue(ctx context.Context, emails []NewOutboxEmail) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	batch, err := outboxBatch(r.emails, emails)
+	if err != nil {
+		return err
+	}
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to queue emails", "count", len(emails), "error", err)
+		return err
+	}
+	return nil
+}
+
+// outboxBatch inserts emails into the outbox, with recipients sealed by cipher, and registers
+// the message IDs of tracked post emails, so opens and clicks are only counted for emails that
+// were queued. PostRepository.PublishPost and NotificationRepository.Create send it in the
+// transaction that creates what they belong to.
+func outboxBatch(cipher *emailcrypt.Cipher, emails []NewOutboxEmail) (*pgx.Batch, error) {
+	query := `
+		INSERT INTO email_outbox (newsletter_id, post_id, notification_id, recipient, recipient_hash, subject, html, correlation_id, list_unsubscribe, available_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), COALESCE($10, now()))
+	`
+	type trackedPost struct {
+		postID       uuid.UUID
+		newsletterID uuid.UUID
+	}
+	messages := make(map[trackedPost][]uuid.UUID)
+
+	batch := &pgx.Batch{}
+	for _, email := range emails {
+		recipient, err := cipher.Seal(email.Recipient)
+		if err != nil {
+			return nil, err
+		}
+		batch.Queue(query, email.NewsletterID, email.PostID, email.NotificationID, recipient, cipher.Index(email.Recipient), email.Subject, email.HTML, email.CorrelationID, email.ListUnsubscribe, email.AvailableAt)
+		if email.MessageID != uuid.Nil && email.PostID != nil {
+			post := trackedPost{postID: *email.PostID, newsletterID: email.NewsletterID}
+			messages[post] = append(messages[post], email.MessageID)
+		}
+	}
+	for post, ids := range messages {
+		batch.Queue(`
+			INSERT INTO email_messages (id, post_id, newsletter_id)
+			SELECT unnest($1::uuid[]), $2, $3
+		`, ids, post.postID, post.newsletterID)
+	}
+	return batch, nil
+}
+
+// Claim leases up to limit due pending emails, oldest first, and counts an attempt on each.
+// Claimed emails are hidden from other dispatchers until the lease expires, so emails of a
+// dispatcher that died before marking them are claimed again. Emails whose recipient cannot be
+// decrypted are marked failed and left out.
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxEmail, error) {
+	query := `
+		UPDATE email_outbox
+		SET available_at = now() + $2::interval, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND available_at <= now()
+			ORDER BY available_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, newsletter_id, post_id, notification_id, recipient, subject, html, COALESCE(correlation_id, ''), COALESCE(list_unsubscribe, ''), attempts
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, lease)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to claim queued emails", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		emails []OutboxEmail
+		sealed = make(map[uuid.UUID]error)
+	)
+	for rows.Next() {
+		var email OutboxEmail
+		err := rows.Scan(
+			&email.ID,
+			&email.NewsletterID,
+			&email.PostID,
+			&email.NotificationID,
+			&email.Recipient,
+			&email.Subject,
+			&email.HTML,
+			&email.CorrelationID,
+			&email.ListUnsubscribe,
+			&email.Attempts,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan queued email row", "error", err)
+			return nil, err
+		}
+		if email.Recipient, err = r.emails.Open(email.Recipient); err != nil {
+			sealed[email.ID] = err
+			continue
+		}
+		emails = append(emails, email)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating queued email rows", "error", err)
+		return nil, err
+	}
+	rows.Close()
+
+	// A recipient that cannot be decrypted never will be, e.g. after a key change, so the
+	// email fails for good instead of coming back on every drain. The claim has to finish
+	// first, as its row locks would block the update.
+	for id, openErr := range sealed {
+		r.logger.ErrorContext(ctx, "Failed to decrypt queued email recipient, marking it failed", "id", id, "error", openErr)
+		r.MarkFailed(ctx, id, openErr.Error())
+	}
+	return emails, nil
+}
+
+// MarkSent marks emails as sent
+func (r *OutboxRepository) MarkSent(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := `
+		UPDATE email_outbox
+		SET status = 'sent', last_error = NULL, finished_at = now()
+		WHERE id = ANY($1)
+	`
+	if _, err := r.db.Exec(ctx, query, ids); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark queued emails as sent", "count", len(ids), "error", err)
+		return err
+	}
+	return nil
+}
+
+// Reschedule returns a claimed email to the queue, due again after delay
+func (r *OutboxRepository) Reschedule(ctx context.Context, id uuid.UUID, delay time.Duration, sendError string) error {
+	query := `
+		UPDATE email_outbox
+		SET available_at = now() + $2::interval, last_error = $3
+		WHERE id = $1 AND status = 'pending'
+	`
+	if _, err := r.db.Exec(ctx, query, id, delay, sendError); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to reschedule queued email", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// MarkFailed marks an email as failed for good
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, sendError string) error {
+	query := `
+		UPDATE email_outbox
+		SET status = 'failed', last_error = $2, finished_at = now()
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(ctx, query, id, sendError); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark queued email as failed", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// CountPending returns the number of emails of a post still waiting to be sent
+func (r *OutboxRepository) CountPending(ctx context.Context, postID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM email_outbox
+		WHERE post_id = $1 AND status = 'pending'
+	`
+	var count int
+	if err := r.db.QueryRow(ctx, query, postID).Scan(&count); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count queued emails", "postId", postID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Purge deletes sent and failed emails that finished more than retention ago
+func (r *OutboxRepository) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+		DELETE FROM email_outbox
+		WHERE status <> 'pending' AND finished_at < now() - $1::interval
+	`
+	result, err := r.db.Exec(ctx, query, retention)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to purge finished queued emails", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// EncryptStoredEmails encrypts the recipients of queued emails stored in plaintext. Returns how
+// many were encrypted.
+func (r *OutboxRepository) EncryptStoredEmails(ctx context.Context, batchSize int) (int, error) {
+	if !r.emails.Enabled() {
+		return 0, errors.New("subscriber email encryption is not enabled")
+	}
+
+	var encrypted int
+	err := forEachPlaintext(ctx, r.db, `
+		SELECT id, recipient FROM email_outbox
+		WHERE id > $1 AND recipient_hash IS NULL
+		ORDER BY id
+		LIMIT $2
+	`, batchSize, func(id uuid.UUID, values []string) error {
+		recipient, err := r.emails.Seal(values[0])
+		if err != nil {
+			return err
+		}
+		_, err = r.db.Exec(ctx, `
+			UPDATE email_outbox SET recipient = $2, recipient_hash = $3
+			WHERE id = $1 AND recipient_hash IS NULL
+		`, id, recipient, r.emails.Index(values[0]))
+		if err != nil {
+			return err
+		}
+		encrypted++
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt queued email recipients", "error", err)
+		return encrypted, err
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/emailcrypt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// claimDB answers the claim query with its rows and records the emails marked failed
+type claimDB struct {
+	rows   [][]any
+	failed map[uuid.UUID]string
+}
+
+func (db *claimDB) Begin(context.Context) (pgx.Tx, error) {
+	return nil, errors.New("unexpected Begin")
+}
+
+func (db *claimDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if !strings.Contains(sql, "status = 'failed'") {
+		return pgconn.CommandTag{}, errors.New("unexpected Exec")
+	}
+	db.failed[args[0].(uuid.UUID)] = args[1].(string)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (db *claimDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return &claimRows{rows: db.rows, next: -1}, nil
+}
+
+func (db *claimDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return nil
+}
+
+func (db *claimDB) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return nil
+}
+
+// claimRows scans the claimed rows in the column order of the claim query; methods Claim
+// does not call are left to the nil pgx.Rows
+type claimRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+}
+
+func (r *claimRows) Next() bool {
+	r.next++
+	return r.next < len(r.rows)
+}
+
+func (r *claimRows) Scan(dest ...any) error {
+	row := r.rows[r.next]
+	*dest[0].(*uuid.UUID) = row[0].(uuid.UUID)
+	*dest[4].(*string) = row[1].(string)
+	*dest[5].(*string) = "Spring issue"
+	*dest[9].(*int) = 1
+	return nil
+}
+
+func (r *claimRows) Err() error { return nil }
+func (r *claimRows) Close()     {}
+
+func newTestCipher(t *testing.T, seed byte) *emailcrypt.Cipher {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune('a'+seed)), 32)))
+	c, err := emailcrypt.New(config.SecurityConfig{SubscriberEmailKey: key})
+	if err != nil {
+		t.Fatalf("creating cipher: %v", err)
+	}
+	return c
+}
+
+func TestClaimFailsUndecryptableEmails(t *testing.T) {
+	current, rotated := newTestCipher(t, 0), newTestCipher(t, 1)
+	readable, err := current.Seal("reader@example.com")
+	if err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+	unreadable, err := rotated.Seal("lost@example.com")
+	if err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+
+	readableID, unreadableID, plainID := uuid.New(), uuid.New(), uuid.New()
+	db := &claimDB{
+		rows: [][]any{
+			{readableID, readable},
+			{unreadableID, unreadable},
+			{plainID, "plain@example.com"},
+		},
+		failed: map[uuid.UUID]string{},
+	}
+	repo := NewOutboxRepository(db, current, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	emails, err := repo.Claim(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(emails) != 2 || emails[0].ID != readableID || emails[0].Recipient != "reader@example.com" || emails[1].ID != plainID {
+		t.Fatalf("Claim = %+v, want the readable and the plaintext email", emails)
+	}
+	if len(db.failed) != 1 || !strings.Contains(db.failed[unreadableID], "decrypt") {
+		t.Fatalf("marked failed = %v, want only the undecryptable email with the decryption error", db.failed)
+	}
+}
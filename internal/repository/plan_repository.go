@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultPlanID is the plan of editors that were never assigned one
+const DefaultPlanID = "free"
+
+const planColumns = `id, name, max_subscribers, monthly_emails, custom_domain`
+
+const planGrantColumns = `id, plan_id, source, coupon_code, starts_at, ends_at`
+
+// EditorPlan is the plan in effect for an editor and why it applies
+type EditorPlan struct {
+	Plan   generated.Plan
+	Source generated.PlanSource
+	// ExpiresAt is when a trial or coupon plan ends; nil for the assigned plan
+	ExpiresAt *time.Time
+}
+
+type PlanRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewPlanRepository(db *pgxpool.Pool, logger *slog.Logger) *PlanRepository {
+	return &PlanRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List returns all plans, smallest first
+func (r *PlanRepository) List(ctx context.Context) ([]generated.Plan, error) {
+	query := `
+		SELECT ` + planColumns + `
+		FROM plans
+		ORDER BY max_subscribers NULLS LAST, id
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query plans", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []generated.Plan{}
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan plan row", "error", err)
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating plan rows", "error", err)
+		return nil, err
+	}
+	return plans, nil
+}
+
+func (r *PlanRepository) GetByID(ctx context.Context, id string) (*generated.Plan, error) {
+	query := `
+		SELECT ` + planColumns + `
+		FROM plans
+		WHERE id = $1
+	`
+	plan, err := scanPlan(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get plan", "planId", id, "error", err)
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetByEditor returns the plan in effect for the editor: the largest of the assigned plan and
+// any active trial or coupon grant. Editors without a profile get the default plan.
+func (r *PlanRepository) GetByEditor(ctx context.Context, editorID uuid.UUID) (*EditorPlan, error) {
+	query := `
+		WITH candidates AS (
+			SELECT plan_id, 'assigned' AS source, NULL::timestamptz AS ends_at
+			FROM profiles
+			WHERE id = $1
+			UNION ALL
+			SELECT plan_id, source, ends_at
+			FROM plan_grants
+			WHERE editor_id = $1 AND starts_at <= now() AND ends_at > now()
+		)
+		SELECT p.id, p.name, p.max_subscribers, p.monthly_emails, p.custom_domain, c.source, c.ends_at
+		FROM candidates c
+		JOIN plans p ON p.id = c.plan_id
+		ORDER BY p.max_subscribers DESC NULLS FIRST, p.monthly_emails DESC NULLS FIRST, c.ends_at DESC NULLS FIRST
+		LIMIT 1
+	`
+	editorPlan := &EditorPlan{}
+	err := r.db.QueryRow(ctx, query, editorID).Scan(
+		&editorPlan.Plan.Id,
+		&editorPlan.Plan.Name,
+		&editorPlan.Plan.MaxSubscribers,
+		&editorPlan.Plan.MonthlyEmails,
+		&editorPlan.Plan.CustomDomain,
+		&editorPlan.Source,
+		&editorPlan.ExpiresAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		plan, err := r.GetByID(ctx, DefaultPlanID)
+		if err != nil {
+			return nil, err
+		}
+		return &EditorPlan{Plan: *plan, Source: generated.PlanSourceAssigned}, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get editor plan", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	return editorPlan, nil
+}
+
+// ListGrants returns the editor's trials and coupon redemptions that have not ended, in start order
+func (r *PlanRepository) ListGrants(ctx context.Context, editorID uuid.UUID) ([]generated.PlanGrant, error) {
+	query := `
+		SELECT ` + planGrantColumns + `
+		FROM plan_grants
+		WHERE editor_id = $1 AND ends_at > now()
+		ORDER BY starts_at
+	`
+	rows, err := r.db.Query(ctx, query, editorID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query plan grants", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []generated.PlanGrant{}
+	for rows.Next() {
+		grant, err := scanPlanGrant(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan plan grant row", "error", err)
+			return nil, err
+		}
+		grants = append(grants, *grant)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating plan grant rows", "error", err)
+		return nil, err
+	}
+	return grants, nil
+}
+
+// AssignToEditor moves the editor to the plan; ErrNotFound when the editor has no profile
+func (r *PlanRepository) AssignToEditor(ctx context.Context, editorID uuid.UUID, planID string) error {
+	query := `
+		UPDATE profiles
+		SET plan_id = $2, updated_at = now()
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(ctx, query, editorID, planID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to assign plan", "editorId", editorID, "planId", planID, "error", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CountActiveSubscribers counts the subscribers that have not unsubscribed across the editor's newsletters,
+// leaving out sample and deleted subscribers
+func (r *PlanRepository) CountActiveSubscribers(ctx context.Context, editorID uuid.UUID) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM subscribers s
+		JOIN newsletters n ON n.id = s.newsletter_id
+		WHERE n.editor_id = $1 AND n.deleted_at IS NULL AND s.unsubscribed_at IS NULL AND s.deleted_at IS NULL AND NOT s.is_sample
+	`
+	var count int64
+	if err := r.db.QueryRow(ctx, query, editorID).Scan(&count); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count editor subscribers", "editorId", editorID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountEmailsSince sums the emails sent for the editor's posts published since the given time
+func (r *PlanRepository) CountEmailsSince(ctx context.Context, editorID uuid.UUID, since time.Time) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(p.emails_sent), 0)
+		FROM published_posts p
+		JOIN newsletters n ON n.id = p.newsletter_id
+		WHERE n.editor_id = $1 AND p.published_at >= $2
+	`
+	var count int64
+	if err := r.db.QueryRow(ctx, query, editorID, since).Scan(&count); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count editor emails", "editorId", editorID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func scanPlan(row pgx.Row) (*generated.Plan, error) {
+	plan := &generated.Plan{}
+	err := row.Scan(
+		&plan.Id,
+		&plan.Name,
+		&plan.MaxSubscribers,
+		&plan.MonthlyEmails,
+		&plan.CustomDomain,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func scanPlanGrant(row pgx.Row) (*generated.PlanGrant, error) {
+	grant := &generated.PlanGrant{}
+	err := row.Scan(
+		&grant.Id,
+		&grant.PlanId,
+		&grant.Source,
+		&grant.CouponCode,
+		&grant.StartsAt,
+		&grant.EndsAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
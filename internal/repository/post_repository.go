@@ -2,45 +2,62 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"go-newsletter/internal/emailcrypt"
 	"go-newsletter/internal/models"
 	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
 	"log/slog"
 	"time"
 
 	"go-newsletter/pkg/generated"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostRepository struct {
 	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
 	logger *slog.Logger
 }
 
-func NewPostRepository(db *pgxpool.Pool, logger *slog.Logger) *PostRepository {
+// NewPostRepository creates the repository; with emails set, recipients of queued post emails
+// are stored encrypted
+func NewPostRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *PostRepository {
 	return &PostRepository{
 		db:     db,
+		emails: emails,
 		logger: logger,
 	}
 }
 
-func (r *PostRepository) GetPostsByNewsletterId(ctx context.Context, newsletterID uuid.UUID, published bool) ([]*generated.PublishedPost, error) {
+// GetPostsByNewsletterId retrieves a page of the newsletter's published posts, most recently
+// published first, or of its unpublished posts other than drafts, most recently created first
+func (r *PostRepository) GetPostsByNewsletterId(ctx context.Context, newsletterID uuid.UUID, published bool, page pagination.Page) ([]*generated.PublishedPost, *pagination.Cursor, error) {
 	query := `
-		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at
+		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
 		FROM published_posts
-		WHERE newsletter_id = $1`
+		WHERE newsletter_id = $1 AND deleted_at IS NULL`
 
+	sortColumn := "created_at"
 	if published {
+		sortColumn = "published_at"
 		query += ` AND published_at IS NOT NULL`
 	} else {
-		query += ` AND published_at IS NULL`
+		query += ` AND published_at IS NULL AND status <> '` + enums.Draft.String() + `'`
 	}
+	query += `
+		  AND ($2::timestamptz IS NULL OR (` + sortColumn + `, id) < ($2, $3::uuid))
+		ORDER BY ` + sortColumn + ` DESC, id DESC
+		LIMIT $4`
 
-	rows, err := r.db.Query(ctx, query, newsletterID)
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, newsletterID, after, afterID, page.FetchLimit())
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to query posts", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -58,27 +75,74 @@ func (r *PostRepository) GetPostsByNewsletterId(ctx context.Context, newsletterI
 			&s.ScheduledAt,
 			&s.PublishedAt,
 			&s.CreatedAt,
+			&s.Summary,
+			&s.ContentMarkdown,
 		)
 		if err != nil {
 			r.logger.ErrorContext(ctx, "Failed to scan post row", "error", err)
-			return nil, err
+			return nil, nil, err
 		}
 		posts = append(posts, s)
 	}
 
 	if err = rows.Err(); err != nil {
 		r.logger.ErrorContext(ctx, "Error iterating post rows", "error", err)
+		return nil, nil, err
+	}
+
+	posts, next := pagination.Trim(posts, page, func(p *generated.PublishedPost) pagination.Cursor {
+		if published {
+			return pagination.Cursor{Time: *p.PublishedAt, ID: p.Id.String()}
+		}
+		return pagination.Cursor{Time: *p.CreatedAt, ID: p.Id.String()}
+	})
+	return posts, next, nil
+}
+
+// PublishedPostDate is a published post without its content, for listings like sitemaps
+type PublishedPostDate struct {
+	ID          uuid.UUID
+	PublishedAt time.Time
+}
+
+// GetPublishedPostDates returns when the newsletter's latest published posts were published,
+// most recent first, without reading their content
+func (r *PostRepository) GetPublishedPostDates(ctx context.Context, newsletterID uuid.UUID, limit int) ([]PublishedPostDate, error) {
+	query := `
+		SELECT id, published_at
+		FROM published_posts
+		WHERE newsletter_id = $1 AND published_at IS NOT NULL AND deleted_at IS NULL
+		ORDER BY published_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, newsletterID, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query published post dates", "error", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	return posts, nil
+	var dates []PublishedPostDate
+	for rows.Next() {
+		var date PublishedPostDate
+		if err := rows.Scan(&date.ID, &date.PublishedAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan published post date", "error", err)
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating published post dates", "error", err)
+		return nil, err
+	}
+	return dates, nil
 }
 
 func (r *PostRepository) GetPostById(ctx context.Context, postId uuid.UUID) (*generated.PublishedPost, error) {
 	query := `
-		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at
+		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
 		FROM published_posts
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	post := &generated.PublishedPost{}
 	err := r.db.QueryRow(ctx, query, postId).Scan(
@@ -92,6 +156,8 @@ func (r *PostRepository) GetPostById(ctx context.Context, postId uuid.UUID) (*ge
 		&post.ScheduledAt,
 		&post.PublishedAt,
 		&post.CreatedAt,
+		&post.Summary,
+		&post.ContentMarkdown,
 	)
 
 	if err != nil {
@@ -102,14 +168,39 @@ func (r *PostRepository) GetPostById(ctx context.Context, postId uuid.UUID) (*ge
 	return post, nil
 }
 
-// GetPostsDueForPublication returns all scheduled posts that are due for publication
-func (r *PostRepository) GetPostsDueForPublication(ctx context.Context, currentTime time.Time) ([]*generated.PublishedPost, error) {
-	query := `
-		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at
-		FROM published_posts
+// dueForPublication selects the scheduled posts due at $2 ($1 is the scheduled status) that
+// are not deleted, nor is their newsletter
+const dueForPublication = `
 		WHERE status = $1
 		AND scheduled_at <= $2
 		AND published_at IS NULL
+		AND deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM newsletters n
+			WHERE n.id = published_posts.newsletter_id AND n.deleted_at IS NOT NULL
+		)
+	`
+
+// CountPostsDueForPublication returns how many scheduled posts are due but not yet published
+func (r *PostRepository) CountPostsDueForPublication(ctx context.Context, currentTime time.Time) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM published_posts` + dueForPublication + `
+	`
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, enums.Scheduled.String(), currentTime).Scan(&count); err != nil {
+		r.logger.ErrorContext(ctx, "Error counting posts due for publication", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetPostsDueForPublication returns all scheduled posts that are due for publication
+func (r *PostRepository) GetPostsDueForPublication(ctx context.Context, currentTime time.Time) ([]*generated.PublishedPost, error) {
+	query := `
+		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+		FROM published_posts` + dueForPublication + `
 	`
 
 	rows, err := r.db.Query(ctx, query, enums.Scheduled.String(), currentTime)
@@ -133,6 +224,8 @@ func (r *PostRepository) GetPostsDueForPublication(ctx context.Context, currentT
 			&s.ScheduledAt,
 			&s.PublishedAt,
 			&s.CreatedAt,
+			&s.Summary,
+			&s.ContentMarkdown,
 		)
 		if err != nil {
 			r.logger.ErrorContext(ctx, "Error reading post row", "error", err)
@@ -149,35 +242,129 @@ func (r *PostRepository) GetPostsDueForPublication(ctx context.Context, currentT
 	return posts, nil
 }
 
-// PublishPost updates the status of a post to published
-func (r *PostRepository) PublishPost(ctx context.Context, postId uuid.UUID) error {
-	query := `
+// ErrPostNotScheduled is returned when a post to publish is no longer scheduled, or another
+// publisher is publishing it right now
+var ErrPostNotScheduled = errors.New("post is no longer scheduled")
+
+// PublishPost claims a scheduled post, queues its emails in the outbox and only then marks it
+// published, in one transaction: the emails of a published post are never lost. The claim skips
+// a post another publisher (another instance, or an editor publishing it right away) holds, so
+// concurrent publishers never send it twice; they get ErrPostNotScheduled instead of waiting.
+func (r *PostRepository) PublishPost(ctx context.Context, postId uuid.UUID, emails []NewOutboxEmail) error {
+	claim := `
+		SELECT id
+		FROM published_posts
+		WHERE id = $1 AND status = $2 AND published_at IS NULL AND deleted_at IS NULL
+		FOR UPDATE SKIP LOCKED
+	`
+	markPublished := `
 		UPDATE published_posts
 		SET status = $2, published_at = $3
 		WHERE id = $1
 	`
 
-	now := time.Now()
-	result, err := r.db.Exec(ctx, query, postId, enums.Posted.String(), now)
-	if err != nil {
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var claimed uuid.UUID
+		err := tx.QueryRow(ctx, claim, postId, enums.Scheduled.String()).Scan(&claimed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrPostNotScheduled
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(emails) > 0 {
+			batch, err := outboxBatch(r.emails, emails)
+			if err != nil {
+				return err
+			}
+			if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(ctx, markPublished, postId, enums.Posted.String(), time.Now())
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrPostNotScheduled) {
 		r.logger.ErrorContext(ctx, "REPO: error publishing post", "id", postId, "error", err)
+	}
+	return err
+}
+
+// SkipPost marks a still scheduled post as skipped so the scheduler no longer picks it up
+func (r *PostRepository) SkipPost(ctx context.Context, postId uuid.UUID) error {
+	query := `
+		UPDATE published_posts
+		SET status = $2
+		WHERE id = $1 AND status = $3 AND published_at IS NULL AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, postId, enums.Skipped.String(), enums.Scheduled.String())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: error skipping post", "id", postId, "error", err)
 		return err
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		r.logger.ErrorContext(ctx, "REPO: Post not found for publishing", "id", postId)
+	if result.RowsAffected() == 0 {
+		r.logger.ErrorContext(ctx, "REPO: Scheduled post not found for skipping", "id", postId)
 		return models.NewNotFoundError("Post not found")
 	}
 
 	return nil
 }
 
-func (r *PostRepository) DeletePostById(ctx context.Context, postId uuid.UUID) error {
+// AddDeliveryCounts adds the outcome of a dispatch to the post's delivery counters
+func (r *PostRepository) AddDeliveryCounts(ctx context.Context, postId uuid.UUID, sent int, failed int) error {
 	query := `
-		DELETE
+		UPDATE published_posts
+		SET emails_sent = emails_sent + $2, emails_failed = emails_failed + $3
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, postId, sent, failed); err != nil {
+		r.logger.ErrorContext(ctx, "REPO: error updating post delivery counts", "id", postId, "error", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateSummary stores the post's summary; an empty summary clears it
+func (r *PostRepository) UpdateSummary(ctx context.Context, postId uuid.UUID, summary string) error {
+	query := `
+		UPDATE published_posts
+		SET summary = NULLIF($2, '')
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, postId, summary); err != nil {
+		r.logger.ErrorContext(ctx, "REPO: error updating post summary", "id", postId, "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetDeliveryCounts returns the post's delivery counters
+func (r *PostRepository) GetDeliveryCounts(ctx context.Context, postId uuid.UUID) (sent int, failed int, err error) {
+	query := `
+		SELECT emails_sent, emails_failed
 		FROM published_posts
-		WHERE id = $1`
+		WHERE id = $1
+	`
+
+	err = r.db.QueryRow(ctx, query, postId).Scan(&sent, &failed)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: error reading post delivery counts", "id", postId, "error", err)
+		return 0, 0, err
+	}
+	return sent, failed, nil
+}
+
+// DeletePostById marks the post deleted; the retention job deletes it for good later
+func (r *PostRepository) DeletePostById(ctx context.Context, postId uuid.UUID) error {
+	query := `
+		UPDATE published_posts
+		SET deleted_at = now()
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	result, err := r.db.Exec(ctx, query, postId)
 	if err != nil {
@@ -196,22 +383,18 @@ func (r *PostRepository) DeletePostById(ctx context.Context, postId uuid.UUID) e
 
 func (r *PostRepository) CreatePost(ctx context.Context, userId uuid.UUID, createPost *generated.PublishPostRequest, newsletterId uuid.UUID) (*generated.PublishedPost, error) {
 	query := `
-	INSERT INTO published_posts (id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at
+	INSERT INTO published_posts (id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, content_markdown)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
 	`
 
 	id := uuid.New()
 	now := time.Now()
 
+	// Posts scheduled in the past are published right after by the service, which queues their emails
 	status := enums.Scheduled
 	var publishedAt *time.Time
 
-	if createPost.ScheduledAt != nil && createPost.ScheduledAt.Before(now) || createPost.ScheduledAt.Equal(now) {
-		status = enums.Posted
-		publishedAt = &now
-	}
-
 	post := &generated.PublishedPost{}
 	err := r.db.QueryRow(ctx, query,
 		id,
@@ -224,6 +407,7 @@ func (r *PostRepository) CreatePost(ctx context.Context, userId uuid.UUID, creat
 		createPost.ScheduledAt,
 		publishedAt,
 		now,
+		createPost.ContentMarkdown,
 	).Scan(
 		&post.Id,
 		&post.NewsletterId,
@@ -235,6 +419,8 @@ func (r *PostRepository) CreatePost(ctx context.Context, userId uuid.UUID, creat
 		&post.ScheduledAt,
 		&post.PublishedAt,
 		&post.CreatedAt,
+		&post.Summary,
+		&post.ContentMarkdown,
 	)
 
 	if err != nil {
@@ -248,27 +434,23 @@ func (r *PostRepository) CreatePost(ctx context.Context, userId uuid.UUID, creat
 func (r *PostRepository) UpdatePost(ctx context.Context, postId uuid.UUID, updatePost *generated.PublishPostRequest) (*generated.PublishedPost, error) {
 	query := `
 	UPDATE published_posts 
-	SET title = $2, content_html = $3, content_text = $4, status = $5, scheduled_at = $6, published_at = $7
-	WHERE id = $1
-	RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at
+	SET title = $2, content_html = $3, content_text = $4, status = $5, scheduled_at = $6, published_at = $7, content_markdown = $8
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
 	`
 
-	now := time.Now()
-
 	originalPost, err := r.GetPostById(ctx, postId)
 	if err != nil {
 		return nil, err
 	}
 
+	// Moving the schedule into the past leaves the post scheduled; the service publishes it
 	status := enums.Scheduled
 	var publishedAt *time.Time
 
 	if originalPost.PublishedAt != nil {
 		status = enums.Posted
 		publishedAt = originalPost.PublishedAt
-	} else if updatePost.ScheduledAt != nil && (updatePost.ScheduledAt.Before(now) || updatePost.ScheduledAt.Equal(now)) {
-		status = enums.Posted
-		publishedAt = &now
 	}
 
 	post := &generated.PublishedPost{}
@@ -280,6 +462,7 @@ func (r *PostRepository) UpdatePost(ctx context.Context, postId uuid.UUID, updat
 		status.String(),
 		updatePost.ScheduledAt,
 		publishedAt,
+		updatePost.ContentMarkdown,
 	).Scan(
 		&post.Id,
 		&post.NewsletterId,
@@ -291,6 +474,8 @@ func (r *PostRepository) UpdatePost(ctx context.Context, postId uuid.UUID, updat
 		&post.ScheduledAt,
 		&post.PublishedAt,
 		&post.CreatedAt,
+		&post.Summary,
+		&post.ContentMarkdown,
 	)
 
 	if err != nil {
@@ -300,3 +485,294 @@ func (r *PostRepository) UpdatePost(ctx context.Context, postId uuid.UUID, updat
 
 	return post, nil
 }
+
+// ReturnToDraft replaces the content of a scheduled post that is not published yet and moves it
+// back to draft, unscheduled and with its review reopened, as UpdateDraft does for drafts
+func (r *PostRepository) ReturnToDraft(ctx context.Context, postId uuid.UUID, updatePost *generated.PublishPostRequest) (*generated.PublishedPost, error) {
+	query := `
+	WITH updated AS (
+		UPDATE published_posts
+		SET title = $2, content_html = $3, content_text = $4, content_markdown = $5, status = $6, scheduled_at = NULL
+		WHERE id = $1 AND status = $7 AND published_at IS NULL AND deleted_at IS NULL
+		RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	), reopened AS (
+		UPDATE post_reviews
+		SET status = 'draft'
+		WHERE post_id IN (SELECT id FROM updated) AND status IN ('in_review', 'approved')
+	)
+	SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	FROM updated
+	`
+
+	post := &generated.PublishedPost{}
+	err := scanPost(r.db.QueryRow(ctx, query,
+		postId,
+		updatePost.Title,
+		updatePost.ContentHtml,
+		updatePost.ContentText,
+		updatePost.ContentMarkdown,
+		enums.Draft.String(),
+		enums.Scheduled.String(),
+	), post)
+	if err == pgx.ErrNoRows {
+		return nil, models.NewConflictError("The post is no longer scheduled")
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to return post to draft", "id", postId, "error", err)
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// ImportedPost is a post of another tool's archive, published before it moved here
+type ImportedPost struct {
+	Title       string
+	ContentHTML string
+	ContentText string
+	Summary     string
+	PublishedAt time.Time
+}
+
+// ImportPosts stores the posts as published at their original dates, without queueing emails.
+// Posts of the newsletter with the same title and publication date are left out, so importing
+// an archive again adds nothing; the returned posts are the ones stored, in the order given.
+func (r *PostRepository) ImportPosts(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, imports []ImportedPost) ([]*generated.PublishedPost, error) {
+	query := `
+		INSERT INTO published_posts (id, newsletter_id, editor_id, title, content_html, content_text, summary, status, scheduled_at, published_at, created_at)
+		SELECT $1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $9, now()
+		WHERE NOT EXISTS (
+			SELECT 1 FROM published_posts
+			WHERE newsletter_id = $2 AND title = $4 AND published_at = $9 AND deleted_at IS NULL
+		)
+		RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	`
+
+	var posts []*generated.PublishedPost
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		posts = nil
+		for _, imported := range imports {
+			post := &generated.PublishedPost{}
+			err := scanPost(tx.QueryRow(ctx, query,
+				uuid.New(),
+				newsletterID,
+				editorID,
+				imported.Title,
+				imported.ContentHTML,
+				imported.ContentText,
+				imported.Summary,
+				enums.Posted.String(),
+				imported.PublishedAt,
+			), post)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			posts = append(posts, post)
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to import posts", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	return posts, nil
+}
+
+// scanPost reads a row of the post columns selected by the queries above, followed by any
+// extra columns
+func scanPost(row pgx.Row, post *generated.PublishedPost, extra ...any) error {
+	dest := []any{
+		&post.Id,
+		&post.NewsletterId,
+		&post.EditorId,
+		&post.Title,
+		&post.ContentHtml,
+		&post.ContentText,
+		&post.Status,
+		&post.ScheduledAt,
+		&post.PublishedAt,
+		&post.CreatedAt,
+		&post.Summary,
+		&post.ContentMarkdown,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// GetDraftsByNewsletterId retrieves a page of the newsletter's drafts, most recently created first
+func (r *PostRepository) GetDraftsByNewsletterId(ctx context.Context, newsletterID uuid.UUID, page pagination.Page) ([]*generated.PublishedPost, *pagination.Cursor, error) {
+	query := `
+		SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+		FROM published_posts
+		WHERE newsletter_id = $1 AND status = $2 AND deleted_at IS NULL
+		  AND ($3::timestamptz IS NULL OR (created_at, id) < ($3, $4::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, newsletterID, enums.Draft.String(), after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query drafts", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var drafts []*generated.PublishedPost
+	for rows.Next() {
+		draft := &generated.PublishedPost{}
+		if err := scanPost(rows, draft); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan draft row", "error", err)
+			return nil, nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating draft rows", "error", err)
+		return nil, nil, err
+	}
+
+	drafts, next := pagination.Trim(drafts, page, func(p *generated.PublishedPost) pagination.Cursor {
+		return pagination.Cursor{Time: *p.CreatedAt, ID: p.Id.String()}
+	})
+	return drafts, next, nil
+}
+
+// CreateDraft saves a new draft without a schedule
+func (r *PostRepository) CreateDraft(ctx context.Context, userId uuid.UUID, draft *generated.DraftRequest, newsletterId uuid.UUID) (*generated.PublishedPost, error) {
+	query := `
+	INSERT INTO published_posts (newsletter_id, editor_id, title, content_html, content_text, status, content_markdown)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	`
+
+	post := &generated.PublishedPost{}
+	err := scanPost(r.db.QueryRow(ctx, query,
+		newsletterId,
+		userId,
+		draft.Title,
+		draftContentHTML(draft),
+		draft.ContentText,
+		enums.Draft.String(),
+		draft.ContentMarkdown,
+	), post)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to create draft", "error", err)
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// UpdateDraft replaces the content of a draft. A draft in review or approved goes back to the
+// draft review status, so the new content is reviewed again. It fails with a not found error
+// when the post is not a draft, e.g. because it was published in the meantime.
+func (r *PostRepository) UpdateDraft(ctx context.Context, postId uuid.UUID, draft *generated.DraftRequest) (*generated.PublishedPost, error) {
+	query := `
+	WITH updated AS (
+		UPDATE published_posts
+		SET title = $2, content_html = $3, content_text = $4, content_markdown = $6
+		WHERE id = $1 AND status = $5 AND deleted_at IS NULL
+		RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	), reopened AS (
+		UPDATE post_reviews
+		SET status = 'draft'
+		WHERE post_id IN (SELECT id FROM updated) AND status IN ('in_review', 'approved')
+	)
+	SELECT id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	FROM updated
+	`
+
+	post := &generated.PublishedPost{}
+	err := scanPost(r.db.QueryRow(ctx, query,
+		postId,
+		draft.Title,
+		draftContentHTML(draft),
+		draft.ContentText,
+		enums.Draft.String(),
+		draft.ContentMarkdown,
+	), post)
+	if err == pgx.ErrNoRows {
+		return nil, models.NewNotFoundError("Draft not found")
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to update draft", "id", postId, "error", err)
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// DeleteDraft deletes a post that is still a draft
+func (r *PostRepository) DeleteDraft(ctx context.Context, postId uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM published_posts WHERE id = $1 AND status = $2 AND deleted_at IS NULL`, postId, enums.Draft.String())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to delete draft", "id", postId, "error", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return models.NewNotFoundError("Draft not found")
+	}
+	return nil
+}
+
+// PromoteDraft turns a draft into a post scheduled at scheduledAt, or at the current time when
+// scheduledAt is nil. Only one of concurrent promotions succeeds; the others get a not found
+// error, as do drafts of newsletters that require post approval whose review is not approved,
+// e.g. because the draft was edited after the service checked it.
+func (r *PostRepository) PromoteDraft(ctx context.Context, postId uuid.UUID, scheduledAt *time.Time) (*generated.PublishedPost, error) {
+	query := `
+	UPDATE published_posts p
+	SET status = $2, scheduled_at = COALESCE($3, now())
+	WHERE id = $1 AND status = $4 AND deleted_at IS NULL
+	  AND NOT EXISTS (
+		SELECT 1 FROM newsletters n
+		WHERE n.id = p.newsletter_id AND n.require_post_approval
+		  AND NOT EXISTS (SELECT 1 FROM post_reviews r WHERE r.post_id = p.id AND r.status = 'approved')
+	  )
+	RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+	`
+
+	post := &generated.PublishedPost{}
+	err := scanPost(r.db.QueryRow(ctx, query, postId, enums.Scheduled.String(), scheduledAt, enums.Draft.String()), post)
+	if err == pgx.ErrNoRows {
+		return nil, models.NewNotFoundError("Draft not found")
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to promote draft", "id", postId, "error", err)
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// PurgeDeleted deletes up to limit posts deleted before the given time for good; rows that
+// reference them cascade. Returns how many posts were deleted.
+func (r *PostRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM published_posts
+		WHERE id IN (
+			SELECT id FROM published_posts
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	result, err := r.db.Exec(ctx, query, before, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to purge deleted posts", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// draftContentHTML returns the HTML of a draft, empty while none was written yet
+func draftContentHTML(draft *generated.DraftRequest) string {
+	if draft.ContentHtml == nil {
+		return ""
+	}
+	return *draft.ContentHtml
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
+	"go-newsletter/internal/pagination"
 	"go-newsletter/pkg/generated"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -23,18 +26,21 @@ func NewProfileRepository(db *pgxpool.Pool, logger *slog.Logger) *ProfileReposit
 	}
 }
 
-// GetAll retrieves all profiles from the database
-func (r *ProfileRepository) GetAll(ctx context.Context) ([]generated.EditorProfile, error) {
+// GetAll retrieves a page of profiles from the database, newest first
+func (r *ProfileRepository) GetAll(ctx context.Context, page pagination.Page) ([]generated.EditorProfile, *pagination.Cursor, error) {
 	query := `
 		SELECT id, full_name, avatar_url, is_admin, created_at, updated_at 
 		FROM public.profiles 
-		ORDER BY created_at DESC
+		WHERE ($1::timestamptz IS NULL OR (created_at, id) < ($1, $2::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
 	`
 
-	rows, err := r.db.Query(ctx, query)
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, after, afterID, page.FetchLimit())
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to query profiles", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -43,17 +49,20 @@ func (r *ProfileRepository) GetAll(ctx context.Context) ([]generated.EditorProfi
 		var p generated.EditorProfile
 		if err := rows.Scan(&p.Id, &p.FullName, &p.AvatarUrl, &p.IsAdmin, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			r.logger.ErrorContext(ctx, "Failed to scan profile row", "error", err)
-			return nil, err
+			return nil, nil, err
 		}
 		profiles = append(profiles, p)
 	}
 
 	if err := rows.Err(); err != nil {
 		r.logger.ErrorContext(ctx, "Error iterating profile rows", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	return profiles, nil
+	profiles, next := pagination.Trim(profiles, page, func(p generated.EditorProfile) pagination.Cursor {
+		return pagination.Cursor{Time: *p.CreatedAt, ID: p.Id.String()}
+	})
+	return profiles, next, nil
 }
 
 // GetByID retrieves a single profile by ID
@@ -117,6 +126,32 @@ func (r *ProfileRepository) Create(ctx context.Context, id string) (*generated.E
 	return &p, nil
 }
 
+// CreateIfMissing creates the profile of a user with the given name and avatar unless it
+// exists. Returns the profile and whether it was created.
+func (r *ProfileRepository) CreateIfMissing(ctx context.Context, id string, fullName string, avatarURL string) (*generated.EditorProfile, bool, error) {
+	query := `
+		INSERT INTO public.profiles (id, full_name, avatar_url, is_admin, created_at, updated_at)
+		VALUES ($1, $2, $3, false, NOW(), NOW())
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id, full_name, avatar_url, is_admin, created_at, updated_at
+	`
+
+	var p generated.EditorProfile
+	err := r.db.QueryRow(ctx, query, id, fullName, avatarURL).Scan(
+		&p.Id, &p.FullName, &p.AvatarUrl, &p.IsAdmin, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		existing, err := r.GetByID(ctx, id)
+		return existing, false, err
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create profile", "id", id, "error", err)
+		return nil, false, err
+	}
+
+	return &p, true, nil
+}
+
 // GrantAdmin grants admin privileges to a user
 func (r *ProfileRepository) GrantAdmin(ctx context.Context, id string) (*generated.EditorProfile, error) {
 	query := `
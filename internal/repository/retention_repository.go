@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditRetentionDeleted is the audit action recorded for subscribers deleted by the retention job
+const AuditRetentionDeleted = "subscriber.retention_deleted"
+
+// AuditRetentionAnonymized is the audit action recorded for unconfirmed subscribers anonymized by
+// the retention job, past their retention or after their confirmation link expired
+const AuditRetentionAnonymized = "subscriber.retention_anonymized"
+
+// Reasons of AuditRetentionAnonymized entries
+const (
+	anonymizedRetention           = "retention"
+	anonymizedConfirmationExpired = "confirmation_expired"
+)
+
+// AuditConfirmationExpired is the audit action recorded for unconfirmed subscribers deleted by
+// the retention job after their confirmation link expired
+const AuditConfirmationExpired = "subscriber.confirmation_expired"
+
+// ExpiredUnconfirmed counts a newsletter's unconfirmed subscribers that are past their retention
+type ExpiredUnconfirmed struct {
+	NewsletterID   uuid.UUID
+	NewsletterName string
+	RetentionDays  int
+	Expired        int64
+}
+
+type RetentionRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewRetentionRepository(db *pgxpool.Pool, logger *slog.Logger) *RetentionRepository {
+	return &RetentionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// expiredUnconfirmed selects the unconfirmed subscribers older than their newsletter's retention,
+// or the default retention ($1, zero for none) when the newsletter has no override
+const expiredUnconfirmed = `
+		FROM subscribers s
+		JOIN newsletters n ON n.id = s.newsletter_id
+		WHERE NOT s.is_confirmed
+		  AND s.anonymized_at IS NULL
+		  AND s.subscribed_at < now() - make_interval(days => COALESCE(n.unconfirmed_retention_days, NULLIF($1, 0)))
+	`
+
+// CountExpiredUnconfirmed returns per newsletter how many unconfirmed subscribers are past their
+// retention, without deleting them
+func (r *RetentionRepository) CountExpiredUnconfirmed(ctx context.Context, defaultDays int) ([]ExpiredUnconfirmed, error) {
+	query := `
+		SELECT n.id, n.name, COALESCE(n.unconfirmed_retention_days, $1), COUNT(*)` + expiredUnconfirmed + `
+		GROUP BY n.id, n.name
+		ORDER BY COUNT(*) DESC, n.name
+	`
+	rows, err := r.db.Query(ctx, query, defaultDays)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count expired unconfirmed subscribers", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expired []ExpiredUnconfirmed
+	for rows.Next() {
+		var e ExpiredUnconfirmed
+		if err := rows.Scan(&e.NewsletterID, &e.NewsletterName, &e.RetentionDays, &e.Expired); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan expired unconfirmed subscribers", "error", err)
+			return nil, err
+		}
+		expired = append(expired, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating expired unconfirmed subscribers", "error", err)
+		return nil, err
+	}
+	return expired, nil
+}
+
+// DeleteExpiredUnconfirmed deletes up to limit unconfirmed subscribers past their retention and
+// records an audit entry for each, in one statement. Rows locked by another run are skipped.
+// Returns how many subscribers were deleted.
+func (r *RetentionRepository) DeleteExpiredUnconfirmed(ctx context.Context, defaultDays int, limit int) (int64, error) {
+	query := `
+		WITH expired AS (
+			SELECT s.id, COALESCE(n.unconfirmed_retention_days, $1) AS retention_days` + expiredUnconfirmed + `
+			ORDER BY s.subscribed_at
+			LIMIT $2
+			FOR UPDATE OF s SKIP LOCKED
+		), deleted AS (
+			DELETE FROM subscribers s
+			USING expired e
+			WHERE s.id = e.id
+			RETURNING s.id, s.newsletter_id, s.subscribed_at, e.retention_days
+		)
+		INSERT INTO audit_log (action, newsletter_id, target_id, details)
+		SELECT $3, d.newsletter_id, d.id, jsonb_build_object('subscribed_at', d.subscribed_at, 'retention_days', d.retention_days)
+		FROM deleted d
+	`
+	result, err := r.db.Exec(ctx, query, defaultDays, limit, AuditRetentionDeleted)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete expired unconfirmed subscribers", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// expiredConfirmation selects the unconfirmed subscribers whose confirmation link expired more
+// than $1 seconds ago
+const expiredConfirmation = `
+		FROM subscribers s
+		WHERE NOT s.is_confirmed
+		  AND s.confirmation_expires_at < now() - make_interval(secs => $1)
+	`
+
+// CountExpiredConfirmations returns how many unconfirmed subscribers had their confirmation link
+// expire more than keep ago, without deleting them
+func (r *RetentionRepository) CountExpiredConfirmations(ctx context.Context, keep time.Duration) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*)`+expiredConfirmation, keep.Seconds()).Scan(&count)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count subscribers with expired confirmations", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteExpiredConfirmations deletes up to limit unconfirmed subscribers whose confirmation link
+// expired more than keep ago and records an audit entry for each, in one statement. Rows locked
+// by another run are skipped. Returns how many subscribers were deleted.
+func (r *RetentionRepository) DeleteExpiredConfirmations(ctx context.Context, keep time.Duration, limit int) (int64, error) {
+	query := `
+		WITH expired AS (
+			SELECT s.id` + expiredConfirmation + `
+			ORDER BY s.confirmation_expires_at
+			LIMIT $2
+			FOR UPDATE OF s SKIP LOCKED
+		), deleted AS (
+			DELETE FROM subscribers s
+			USING expired e
+			WHERE s.id = e.id
+			RETURNING s.id, s.newsletter_id, s.subscribed_at, s.confirmation_expires_at
+		)
+		INSERT INTO audit_log (action, newsletter_id, target_id, details)
+		SELECT $3, d.newsletter_id, d.id, jsonb_build_object('subscribed_at', d.subscribed_at, 'confirmation_expired_at', d.confirmation_expires_at)
+		FROM deleted d
+	`
+	result, err := r.db.Exec(ctx, query, keep.Seconds(), limit, AuditConfirmationExpired)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete subscribers with expired confirmations", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// AnonymizeExpiredUnconfirmed anonymizes up to limit unconfirmed subscribers past their retention
+// instead of deleting them, see anonymizeExpired. Returns how many were anonymized.
+func (r *RetentionRepository) AnonymizeExpiredUnconfirmed(ctx context.Context, defaultDays int, limit int) (int64, error) {
+	result, err := r.db.Exec(ctx, anonymizeExpired(expiredUnconfirmed, "s.subscribed_at"), defaultDays, limit, AuditRetentionAnonymized, anonymizedRetention)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to anonymize expired unconfirmed subscribers", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// AnonymizeExpiredConfirmations anonymizes up to limit unconfirmed subscribers whose confirmation
+// link expired more than keep ago instead of deleting them, see anonymizeExpired. Returns how
+// many were anonymized.
+func (r *RetentionRepository) AnonymizeExpiredConfirmations(ctx context.Context, keep time.Duration, limit int) (int64, error) {
+	result, err := r.db.Exec(ctx, anonymizeExpired(expiredConfirmation, "s.confirmation_expires_at"), keep.Seconds(), limit, AuditRetentionAnonymized, anonymizedConfirmationExpired)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to anonymize subscribers with expired confirmations", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// anonymizeExpired builds the statement anonymizing up to $2 of the subscribers the expired
+// fragment selects, oldest by order first, with an audit entry for each (action $3, reason $4).
+// The address and tokens are replaced and pending address changes deleted; the subscription
+// dates are kept, so the subscriber still counts in subscriber growth. Rows locked by another run
+// are skipped.
+func anonymizeExpired(expired string, order string) string {
+	return `
+		WITH expired AS (
+			SELECT s.id` + expired + `
+			ORDER BY ` + order + `
+			LIMIT $2
+			FOR UPDATE OF s SKIP LOCKED
+		), changes AS (
+			DELETE FROM subscriber_email_changes c
+			USING expired e
+			WHERE c.subscriber_id = e.id
+		), anonymized AS (
+			UPDATE subscribers s
+			SET email = 'anonymized-' || s.id || '@invalid',
+				email_hash = NULL,
+				unsubscribe_token = gen_random_uuid()::text,
+				confirmation_token = NULL,
+				confirmation_last_error = NULL,
+				confirmation_retry_at = NULL,
+				confirmation_expires_at = NULL,
+				anonymized_at = now()
+			FROM expired e
+			WHERE s.id = e.id
+			RETURNING s.id, s.newsletter_id, s.subscribed_at
+		)
+		INSERT INTO audit_log (action, newsletter_id, target_id, details)
+		SELECT $3, a.newsletter_id, a.id, jsonb_build_object('subscribed_at', a.subscribed_at, 'reason', $4::text)
+		FROM anonymized a
+	`
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const reviewColumns = `r.post_id, r.status, r.comment, r.submitted_by, r.submitted_at, r.reviewed_by, r.reviewed_at`
+
+// errReviewChanged is returned when a review left the status a transition starts from, e.g.
+// because another owner decided on it in the meantime
+var errReviewChanged = models.NewConflictError("The review of the draft changed in the meantime")
+
+// ReviewRepository stores the approval workflow of drafts. A draft without a row was never
+// submitted and is in the draft status.
+type ReviewRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewReviewRepository(db *pgxpool.Pool, logger *slog.Logger) *ReviewRepository {
+	return &ReviewRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// scanReview reads a row of reviewColumns
+func scanReview(row pgx.Row, review *generated.PostReview) error {
+	return row.Scan(reviewDest(review)...)
+}
+
+func reviewDest(review *generated.PostReview) []any {
+	return []any{
+		&review.PostId,
+		&review.Status,
+		&review.Comment,
+		&review.SubmittedBy,
+		&review.SubmittedAt,
+		&review.ReviewedBy,
+		&review.ReviewedAt,
+	}
+}
+
+// Get returns the review of the post, in the draft status when it was never submitted
+func (r *ReviewRepository) Get(ctx context.Context, postID uuid.UUID) (*generated.PostReview, error) {
+	review := &generated.PostReview{}
+	err := scanReview(r.db.QueryRow(ctx, `SELECT `+reviewColumns+` FROM post_reviews r WHERE r.post_id = $1`, postID), review)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &generated.PostReview{PostId: postID, Status: generated.Draft}, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to get post review", "postId", postID, "error", err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// Submit puts a draft in review, clearing the decision of an earlier review. Only drafts in
+// the draft or rejected status are submitted; others fail with a conflict.
+func (r *ReviewRepository) Submit(ctx context.Context, postID uuid.UUID, editorID uuid.UUID) (*generated.PostReview, error) {
+	query := `
+		INSERT INTO post_reviews AS r (post_id, status, submitted_by, submitted_at)
+		SELECT p.id, 'in_review', $2, now()
+		FROM published_posts p
+		WHERE p.id = $1 AND p.status = $3 AND p.deleted_at IS NULL
+		ON CONFLICT (post_id) DO UPDATE
+		SET status = EXCLUDED.status, comment = NULL, submitted_by = EXCLUDED.submitted_by, submitted_at = EXCLUDED.submitted_at,
+			reviewed_by = NULL, reviewed_at = NULL
+		WHERE r.status IN ('draft', 'rejected')
+		RETURNING ` + reviewColumns
+
+	review := &generated.PostReview{}
+	err := scanReview(r.db.QueryRow(ctx, query, postID, editorID, enums.Draft.String()), review)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errReviewChanged
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to submit post for review", "postId", postID, "error", err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// Decide approves or rejects a draft in review with the reviewer's comment. Drafts that are
+// not in review fail with a conflict.
+func (r *ReviewRepository) Decide(ctx context.Context, postID uuid.UUID, reviewerID uuid.UUID, status generated.PostReviewStatus, comment *string) (*generated.PostReview, error) {
+	query := `
+		UPDATE post_reviews r
+		SET status = $2, comment = $4, reviewed_by = $3, reviewed_at = now()
+		FROM published_posts p
+		WHERE r.post_id = $1 AND r.status = 'in_review'
+		  AND p.id = r.post_id AND p.status = $5 AND p.deleted_at IS NULL
+		RETURNING ` + reviewColumns
+
+	review := &generated.PostReview{}
+	err := scanReview(r.db.QueryRow(ctx, query, postID, status, reviewerID, comment, enums.Draft.String()), review)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errReviewChanged
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to record review decision", "postId", postID, "status", status, "error", err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// Queue retrieves a page of the newsletter's drafts in review, longest waiting first. Unlike
+// the other listings it pages forward in time, so the cursor holds the last submitted_at.
+func (r *ReviewRepository) Queue(ctx context.Context, newsletterID uuid.UUID, page pagination.Page) ([]generated.ReviewQueueItem, *pagination.Cursor, error) {
+	query := `
+		SELECT p.id, p.newsletter_id, p.editor_id, p.title, p.content_html, p.content_text, p.status, p.scheduled_at, p.published_at, p.created_at, p.summary, p.content_markdown,
+			` + reviewColumns + `
+		FROM post_reviews r
+		JOIN published_posts p ON p.id = r.post_id
+		WHERE p.newsletter_id = $1 AND p.status = $2 AND p.deleted_at IS NULL AND r.status = 'in_review'
+		  AND ($3::timestamptz IS NULL OR (r.submitted_at, r.post_id) > ($3, $4::uuid))
+		ORDER BY r.submitted_at, r.post_id
+		LIMIT $5`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, newsletterID, enums.Draft.String(), after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to query review queue", "newsletterId", newsletterID, "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items := []generated.ReviewQueueItem{}
+	for rows.Next() {
+		var item generated.ReviewQueueItem
+		if err := scanPost(rows, &item.Post, reviewDest(&item.Review)...); err != nil {
+			r.logger.ErrorContext(ctx, "REPO: failed to scan review queue row", "error", err)
+			return nil, nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "REPO: error iterating review queue rows", "error", err)
+		return nil, nil, err
+	}
+
+	items, next := pagination.Trim(items, page, func(item generated.ReviewQueueItem) pagination.Cursor {
+		return pagination.Cursor{Time: *item.Review.SubmittedAt, ID: item.Post.Id.String()}
+	})
+	return items, next, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReadOnlyFlag makes mutating endpoints answer 503 while it is enabled
+const ReadOnlyFlag = "read_only"
+
+const runtimeFlagColumns = `enabled, reason, updated_by, updated_at`
+
+type RuntimeFlagRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewRuntimeFlagRepository(db *pgxpool.Pool, logger *slog.Logger) *RuntimeFlagRepository {
+	return &RuntimeFlagRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Get returns the flag; a flag that was never set is disabled
+func (r *RuntimeFlagRepository) Get(ctx context.Context, name string) (*generated.ReadOnlyMode, error) {
+	query := `
+		SELECT ` + runtimeFlagColumns + `
+		FROM runtime_flags
+		WHERE name = $1
+	`
+	flag, err := scanRuntimeFlag(r.db.QueryRow(ctx, query, name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &generated.ReadOnlyMode{Enabled: false}, nil
+		}
+		r.logger.ErrorContext(ctx, "Failed to get runtime flag", "flag", name, "error", err)
+		return nil, err
+	}
+	return flag, nil
+}
+
+// Set enables or disables the flag on behalf of an admin
+func (r *RuntimeFlagRepository) Set(ctx context.Context, name string, enabled bool, reason *string, adminID uuid.UUID) (*generated.ReadOnlyMode, error) {
+	query := `
+		INSERT INTO runtime_flags (name, enabled, reason, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+			SET enabled = EXCLUDED.enabled, reason = EXCLUDED.reason, updated_by = EXCLUDED.updated_by, updated_at = now()
+		RETURNING ` + runtimeFlagColumns
+	flag, err := scanRuntimeFlag(r.db.QueryRow(ctx, query, name, enabled, reason, adminID))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to set runtime flag", "flag", name, "error", err)
+		return nil, err
+	}
+	return flag, nil
+}
+
+func scanRuntimeFlag(row pgx.Row) (*generated.ReadOnlyMode, error) {
+	flag := &generated.ReadOnlyMode{}
+	err := row.Scan(
+		&flag.Enabled,
+		&flag.Reason,
+		&flag.UpdatedBy,
+		&flag.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+type SampleContentRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewSampleContentRepository creates the repository; emails is nil when subscriber addresses are stored in plaintext
+func NewSampleContentRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *SampleContentRepository {
+	return &SampleContentRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// Create adds a draft and confirmed sample subscribers with the given addresses to a newsletter,
+// in one transaction. Adding the same addresses twice fails with a conflict error, so a
+// newsletter gets sample content once.
+func (r *SampleContentRepository) Create(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, draft *generated.DraftRequest, emails []string) (*generated.SampleContent, error) {
+	content := &generated.SampleContent{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		err := scanPost(tx.QueryRow(ctx, `
+			INSERT INTO published_posts (newsletter_id, editor_id, title, content_html, content_text, status)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, newsletter_id, editor_id, title, content_html, content_text, status, scheduled_at, published_at, created_at, summary, content_markdown
+		`, newsletterID, editorID, draft.Title, draftContentHTML(draft), draft.ContentText, enums.Draft.String()), &content.Draft)
+		if err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			sealed, err := r.emails.Seal(email)
+			if err != nil {
+				return err
+			}
+			s := generated.Subscriber{Email: openapi_types.Email(email)}
+			err = tx.QueryRow(ctx, `
+				INSERT INTO subscribers (newsletter_id, email, email_hash, is_confirmed, confirmed_at, unsubscribe_token, is_sample)
+				VALUES ($1, $2, $3, true, NOW(), $4, true)
+				RETURNING id, newsletter_id, subscribed_at, is_confirmed, unsubscribe_token, is_sample
+			`, newsletterID, sealed, r.emails.Index(email), uuid.New().String()).Scan(
+				&s.Id,
+				&s.NewsletterId,
+				&s.SubscribedAt,
+				&s.IsConfirmed,
+				&s.UnsubscribeToken,
+				&s.IsSample,
+			)
+			if err != nil {
+				return err
+			}
+			content.Subscribers = append(content.Subscribers, s)
+		}
+		return nil
+	})
+	if isDuplicateSubscriber(err) {
+		return nil, models.NewConflictError("Sample content was already added to this newsletter")
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create sample content", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	return content, nil
+}
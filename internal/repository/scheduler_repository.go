@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schedulerLockKey is the Postgres advisory lock key guarding scheduled post publishing
+const schedulerLockKey int64 = 0x6e6c7470 // "nltp"
+
+// SchedulerRepository coordinates scheduler runs across instances using a Postgres advisory lock
+type SchedulerRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewSchedulerRepository(db *pgxpool.Pool, logger *slog.Logger) *SchedulerRepository {
+	return &SchedulerRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// TryLock attempts to take the scheduler lock without waiting. The lock is bound to a
+// dedicated pool connection which is held until the returned unlock function is called.
+func (r *SchedulerRepository) TryLock(ctx context.Context) (unlock func(), acquired bool, err error) {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to acquire connection for scheduler lock", "error", err)
+		return nil, false, err
+	}
+
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, schedulerLockKey).Scan(&acquired); err != nil {
+		conn.Release()
+		r.logger.ErrorContext(ctx, "REPO: failed to take scheduler lock", "error", err)
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock = func() {
+		// Use a fresh context: the run's context may already be cancelled
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, schedulerLockKey); err != nil {
+			r.logger.Error("REPO: failed to release scheduler lock, closing connection", "error", err)
+			// Closing the session is the only other way to drop a session level lock
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
+
+// LockHolder returns the application_name of the session holding the scheduler lock,
+// or nil if nobody holds it.
+func (r *SchedulerRepository) LockHolder(ctx context.Context) (*string, error) {
+	query := `
+		SELECT COALESCE(NULLIF(a.application_name, ''), 'pid ' || a.pid::text)
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory'
+		AND l.granted
+		AND l.objsubid = 1
+		AND ((l.classid::bigint << 32) | l.objid::bigint) = $1
+		LIMIT 1
+	`
+
+	var holder string
+	err := r.db.QueryRow(ctx, query, schedulerLockKey).Scan(&holder)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "REPO: failed to look up scheduler lock holder", "error", err)
+		return nil, err
+	}
+	return &holder, nil
+}
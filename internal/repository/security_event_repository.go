@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Audit actions of security events; every one starts with auth.
+const (
+	AuditSignIn        = "auth.sign_in"
+	AuditAccountLinked = "auth.account_linked"
+)
+
+// SecurityEvent is a security event of an editor to record in the audit log
+type SecurityEvent struct {
+	ActorID   uuid.UUID
+	Action    string
+	Method    string
+	IP        string
+	UserAgent string
+	Location  string
+	NewDevice bool
+}
+
+const securityEventColumns = `
+	id, action, details->>'method', details->>'ip_address', details->>'user_agent', details->>'location',
+	COALESCE((details->>'new_device')::boolean, false), created_at`
+
+type SecurityEventRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewSecurityEventRepository(db *pgxpool.Pool, logger *slog.Logger) *SecurityEventRepository {
+	return &SecurityEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SignInHistory reports whether the editor signed in through the API before, and whether one
+// of those sign-ins came with the user agent
+func (r *SecurityEventRepository) SignInHistory(ctx context.Context, actorID uuid.UUID, userAgent string) (signedInBefore bool, knownDevice bool, err error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM audit_log WHERE actor_id = $1 AND action = $2),
+			EXISTS (SELECT 1 FROM audit_log WHERE actor_id = $1 AND action = $2 AND details->>'user_agent' = $3)
+	`
+	err = r.db.QueryRow(ctx, query, actorID, AuditSignIn, userAgent).Scan(&signedInBefore, &knownDevice)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up sign-in history", "actorId", actorID, "error", err)
+		return false, false, err
+	}
+	return signedInBefore, knownDevice, nil
+}
+
+// Record adds the event to the audit log; empty details are left out
+func (r *SecurityEventRepository) Record(ctx context.Context, event SecurityEvent) error {
+	query := `
+		INSERT INTO audit_log (action, actor_id, target_id, details)
+		VALUES ($1, $2, $2, jsonb_strip_nulls(jsonb_build_object(
+			'method', NULLIF($3, ''),
+			'ip_address', NULLIF($4, ''),
+			'user_agent', NULLIF($5, ''),
+			'location', NULLIF($6, ''),
+			'new_device', $7::boolean
+		)))
+	`
+	_, err := r.db.Exec(ctx, query, event.Action, event.ActorID, event.Method, event.IP, event.UserAgent, event.Location, event.NewDevice)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record security event", "action", event.Action, "actorId", event.ActorID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListByActor retrieves a page of the editor's security events, newest first
+func (r *SecurityEventRepository) ListByActor(ctx context.Context, actorID uuid.UUID, page pagination.Page) ([]generated.SecurityEvent, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + securityEventColumns + `
+		FROM audit_log
+		WHERE actor_id = $1 AND action LIKE 'auth.%'
+		  AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, actorID, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query security events", "actorId", actorID, "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	events := []generated.SecurityEvent{}
+	for rows.Next() {
+		event, err := scanSecurityEvent(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan security event row", "error", err)
+			return nil, nil, err
+		}
+		events = append(events, *event)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating security event rows", "error", err)
+		return nil, nil, err
+	}
+
+	events, next := pagination.Trim(events, page, func(e generated.SecurityEvent) pagination.Cursor {
+		return pagination.Cursor{Time: *e.CreatedAt, ID: e.Id.String()}
+	})
+	return events, next, nil
+}
+
+func scanSecurityEvent(row pgx.Row) (*generated.SecurityEvent, error) {
+	var e generated.SecurityEvent
+	err := row.Scan(&e.Id, &e.Event, &e.Method, &e.IpAddress, &e.UserAgent, &e.Location, &e.NewDevice, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// MaxRecordedSendFailures caps the failed recipients kept for one dispatch
+const MaxRecordedSendFailures = 100
+
+// NewSendAttempt is the outcome of one dispatch of emails of a post
+type NewSendAttempt struct {
+	PostID       uuid.UUID
+	NewsletterID uuid.UUID
+	Sent         int
+	Failed       int
+	// Failures are kept up to MaxRecordedSendFailures
+	Failures []generated.PostSendFailure
+}
+
+// SendTotals sums up every dispatch of a post
+type SendTotals struct {
+	Recipients  int
+	Sent        int
+	Failed      int
+	FirstSentAt *time.Time
+	LastSentAt  *time.Time
+}
+
+// storedSendFailure is a failure as kept in post_send_attempts.failures. With encryption
+// enabled the recipient is sealed and RecipientHash holds its hex blind index.
+type storedSendFailure struct {
+	Recipient     string `json:"recipient"`
+	RecipientHash string `json:"recipient_hash,omitempty"`
+	Error         string `json:"error"`
+}
+
+type SendAttemptRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewSendAttemptRepository creates the repository; with emails set, failed recipients are stored
+// encrypted
+func NewSendAttemptRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *SendAttemptRepository {
+	return &SendAttemptRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// Record stores the outcome of a dispatch
+func (r *SendAttemptRepository) Record(ctx context.Context, attempt NewSendAttempt) error {
+	failures := attempt.Failures
+	if len(failures) > MaxRecordedSendFailures {
+		failures = failures[:MaxRecordedSendFailures]
+	}
+	stored := make([]storedSendFailure, 0, len(failures))
+	for _, failure := range failures {
+		recipient, err := r.emails.Seal(string(failure.Recipient))
+		if err != nil {
+			return err
+		}
+		stored = append(stored, storedSendFailure{
+			Recipient:     recipient,
+			RecipientHash: hex.EncodeToString(r.emails.Index(string(failure.Recipient))),
+			Error:         failure.Error,
+		})
+	}
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO post_send_attempts (post_id, newsletter_id, recipients, sent, failed, failures)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = r.db.Exec(ctx, query, attempt.PostID, attempt.NewsletterID, attempt.Sent+attempt.Failed, attempt.Sent, attempt.Failed, encoded)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record send attempt", "postId", attempt.PostID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListByPost retrieves a page of the dispatches of a post, newest first
+func (r *SendAttemptRepository) ListByPost(ctx context.Context, postID uuid.UUID, page pagination.Page) ([]generated.PostSendAttempt, *pagination.Cursor, error) {
+	query := `
+		SELECT id, recipients, sent, failed, sent_at, failures
+		FROM post_send_attempts
+		WHERE post_id = $1
+		  AND ($2::timestamptz IS NULL OR (sent_at, id) < ($2, $3::uuid))
+		ORDER BY sent_at DESC, id DESC
+		LIMIT $4`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, postID, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query send attempts", "postId", postID, "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	attempts := []generated.PostSendAttempt{}
+	for rows.Next() {
+		attempt, err := r.scanSendAttempt(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan send attempt row", "error", err)
+			return nil, nil, err
+		}
+		attempts = append(attempts, *attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating send attempt rows", "error", err)
+		return nil, nil, err
+	}
+
+	attempts, next := pagination.Trim(attempts, page, func(a generated.PostSendAttempt) pagination.Cursor {
+		return pagination.Cursor{Time: a.SentAt, ID: a.Id.String()}
+	})
+	return attempts, next, nil
+}
+
+// Totals sums up the dispatches of a post
+func (r *SendAttemptRepository) Totals(ctx context.Context, postID uuid.UUID) (*SendTotals, error) {
+	query := `
+		SELECT COALESCE(SUM(recipients), 0), COALESCE(SUM(sent), 0), COALESCE(SUM(failed), 0), MIN(sent_at), MAX(sent_at)
+		FROM post_send_attempts
+		WHERE post_id = $1
+	`
+	var totals SendTotals
+	err := r.db.QueryRow(ctx, query, postID).Scan(&totals.Recipients, &totals.Sent, &totals.Failed, &totals.FirstSentAt, &totals.LastSentAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to sum up send attempts", "postId", postID, "error", err)
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// scanSendAttempt scans a dispatch and opens its failed recipients
+func (r *SendAttemptRepository) scanSendAttempt(row pgx.Row) (*generated.PostSendAttempt, error) {
+	var attempt generated.PostSendAttempt
+	var failures []byte
+	err := row.Scan(
+		&attempt.Id,
+		&attempt.Recipients,
+		&attempt.Sent,
+		&attempt.Failed,
+		&attempt.SentAt,
+		&failures,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var stored []storedSendFailure
+	if err := json.Unmarshal(failures, &stored); err != nil {
+		return nil, err
+	}
+	attempt.Failures = make([]generated.PostSendFailure, 0, len(stored))
+	for _, failure := range stored {
+		recipient, err := r.emails.Open(failure.Recipient)
+		if err != nil {
+			return nil, err
+		}
+		attempt.Failures = append(attempt.Failures, generated.PostSendFailure{
+			Recipient: openapi_types.Email(recipient),
+			Error:     failure.Error,
+		})
+	}
+	return &attempt, nil
+}
+
+// EncryptStoredEmails encrypts the failed recipients of dispatches stored in plaintext. Returns
+// how many dispatches were updated.
+func (r *SendAttemptRepository) EncryptStoredEmails(ctx context.Context, batchSize int) (int, error) {
+	if !r.emails.Enabled() {
+		return 0, errors.New("subscriber email encryption is not enabled")
+	}
+
+	var encrypted int
+	err := forEachPlaintext(ctx, r.db, `
+		SELECT id, failures::text FROM post_send_attempts
+		WHERE id > $1 AND EXISTS (
+			SELECT 1 FROM jsonb_array_elements(failures) f WHERE NOT f ? 'recipient_hash'
+		)
+		ORDER BY id
+		LIMIT $2
+	`, batchSize, func(id uuid.UUID, values []string) error {
+		var failures []storedSendFailure
+		if err := json.Unmarshal([]byte(values[0]), &failures); err != nil {
+			return err
+		}
+		for i, failure := range failures {
+			if failure.RecipientHash != "" {
+				continue
+			}
+			recipient, err := r.emails.Open(failure.Recipient)
+			if err != nil {
+				return err
+			}
+			if failures[i].Recipient, err = r.emails.Seal(recipient); err != nil {
+				return err
+			}
+			failures[i].RecipientHash = hex.EncodeToString(r.emails.Index(recipient))
+		}
+		encoded, err := json.Marshal(failures)
+		if err != nil {
+			return err
+		}
+		// The failures are compared so a concurrent change is not overwritten
+		_, err = r.db.Exec(ctx, `
+			UPDATE post_send_attempts SET failures = $2
+			WHERE id = $1 AND failures = $3::jsonb
+		`, id, encoded, values[0])
+		if err != nil {
+			return err
+		}
+		encrypted++
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt send attempt recipients", "error", err)
+		return encrypted, err
+	}
+	return encrypted, nil
+}
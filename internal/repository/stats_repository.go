@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Intervals subscriber growth is bucketed by; they are date_trunc units
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// GrowthBucket counts the subscription changes of a newsletter in one interval
+type GrowthBucket struct {
+	// Start is the first day of the interval, midnight UTC
+	Start        time.Time
+	Subscribed   int
+	Confirmed    int
+	Unsubscribed int
+}
+
+// StatsRepository aggregates newsletter activity for charts
+type StatsRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewStatsRepository(db *pgxpool.Pool, logger *slog.Logger) *StatsRepository {
+	return &StatsRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SubscriberGrowth counts the subscriptions, confirmations and unsubscriptions of a newsletter
+// from from (inclusive) to to (exclusive), per interval (IntervalDay or IntervalWeek) in UTC.
+// from must be the start of an interval. Every interval has a bucket, empty ones included.
+// Sample and deleted subscribers are left out.
+func (r *StatsRepository) SubscriberGrowth(ctx context.Context, newsletterID uuid.UUID, interval string, from time.Time, to time.Time) ([]GrowthBucket, error) {
+	query := `
+		WITH buckets AS (
+			SELECT generate_series($3::timestamp, $4::timestamp - interval '1 microsecond', ('1 ' || $2)::interval) AS start
+		),
+		subs AS (
+			SELECT subscribed_at, confirmed_at, unsubscribed_at
+			FROM subscribers
+			WHERE newsletter_id = $1 AND NOT is_sample AND deleted_at IS NULL
+		),
+		events AS (
+			SELECT date_trunc($2, subscribed_at AT TIME ZONE 'UTC') AS start, 1 AS subscribed, 0 AS confirmed, 0 AS unsubscribed
+			FROM subs
+			WHERE subscribed_at >= $3 AT TIME ZONE 'UTC' AND subscribed_at < $4 AT TIME ZONE 'UTC'
+			UNION ALL
+			SELECT date_trunc($2, confirmed_at AT TIME ZONE 'UTC'), 0, 1, 0
+			FROM subs
+			WHERE confirmed_at >= $3 AT TIME ZONE 'UTC' AND confirmed_at < $4 AT TIME ZONE 'UTC'
+			UNION ALL
+			SELECT date_trunc($2, unsubscribed_at AT TIME ZONE 'UTC'), 0, 0, 1
+			FROM subs
+			WHERE unsubscribed_at >= $3 AT TIME ZONE 'UTC' AND unsubscribed_at < $4 AT TIME ZONE 'UTC'
+		)
+		SELECT b.start, COALESCE(SUM(e.subscribed), 0), COALESCE(SUM(e.confirmed), 0), COALESCE(SUM(e.unsubscribed), 0)
+		FROM buckets b
+		LEFT JOIN events e ON e.start = b.start
+		GROUP BY b.start
+		ORDER BY b.start
+	`
+	// The bounds are passed as timestamps without time zone, read as UTC
+	fromUTC := from.UTC().Format("2006-01-02 15:04:05")
+	toUTC := to.UTC().Format("2006-01-02 15:04:05")
+	rows, err := r.db.Query(ctx, query, newsletterID, interval, fromUTC, toUTC)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to aggregate subscriber growth", "newsletterId", newsletterID, "interval", interval, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []GrowthBucket
+	for rows.Next() {
+		var bucket GrowthBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Subscribed, &bucket.Confirmed, &bucket.Unsubscribed); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan subscriber growth", "newsletterId", newsletterID, "error", err)
+			return nil, err
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating subscriber growth", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	return buckets, nil
+}
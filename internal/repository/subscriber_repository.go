@@ -4,42 +4,469 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/pkg/generated"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound            = errors.New("not found")
+	ErrAlreadySubscribed   = errors.New("already subscribed")
+	ErrEmailChangeExpired  = errors.New("email change expired")
+	ErrConfirmationExpired = errors.New("confirmation expired")
+)
+
+// PendingConfirmation is an unconfirmed subscription whose confirmation email is (re)sent
+type PendingConfirmation struct {
+	SubscriberID      uuid.UUID
+	NewsletterID      uuid.UUID
+	NewsletterName    string
+	Email             string
+	ConfirmationToken string
+	// Attempts already made to send the confirmation email
+	Attempts int
+}
+
+// ChangedSubscription is a subscription that was confirmed or ended
+type ChangedSubscription struct {
+	SubscriberID uuid.UUID
+	NewsletterID uuid.UUID
+	Email        string
+	// WasConfirmed is set when a confirmed subscription was confirmed again, e.g. with a
+	// confirmation link opened twice
+	WasConfirmed bool
+}
+
+// EmailChange is a subscriber's request to move a subscription to another address
+type EmailChange struct {
+	SubscriberID uuid.UUID
+	NewsletterID uuid.UUID
+	OldEmail     string
+	NewEmail     string
+	Token        string
+	ExpiresAt    time.Time
+}
+
+// Constraints that reject a second subscription of an address to a newsletter, for plaintext
+// and encrypted addresses
+const (
+	uniqueSubscriberConstraint          = "unique_newsletter_subscriber"
+	uniqueSubscriberEmailHashConstraint = "unique_newsletter_subscriber_email_hash"
 )
 
 type SubscriberRepository struct {
-	db     *pgxpool.Pool
+	db     DB
+	emails *emailcrypt.Cipher
 	logger *slog.Logger
 }
 
-func NewSubscriberRepository(db *pgxpool.Pool, logger *slog.Logger) *SubscriberRepository {
+// NewSubscriberRepository creates the repository; emails is nil when addresses are stored in plaintext
+func NewSubscriberRepository(db DB, emails *emailcrypt.Cipher, logger *slog.Logger) *SubscriberRepository {
 	return &SubscriberRepository{
 		db:     db,
+		emails: emails,
 		logger: logger,
 	}
 }
 
-func (r *SubscriberRepository) ListByNewsletterID(ctx context.Context, newsletterID uuid.UUID) ([]*generated.Subscriber, error) {
+// sealEmail returns the value stored for the address and its blind index
+func (r *SubscriberRepository) sealEmail(email string) (string, []byte, error) {
+	sealed, err := r.emails.Seal(email)
+	if err != nil {
+		return "", nil, err
+	}
+	return sealed, r.emails.Index(email), nil
+}
+
+// openEmail replaces the stored address of a scanned subscriber with the address
+func (r *SubscriberRepository) openEmail(s *generated.Subscriber) error {
+	email, err := r.emails.Open(string(s.Email))
+	if err != nil {
+		return err
+	}
+	s.Email = openapi_types.Email(email)
+	return nil
+}
+
+func isDuplicateSubscriber(err error) bool {
+	return isUniqueViolation(err, uniqueSubscriberConstraint) || isUniqueViolation(err, uniqueSubscriberEmailHashConstraint)
+}
+
+// subscriberColumns are the columns scanned by scanSubscriber
+const subscriberColumns = `id, newsletter_id, email, subscribed_at, is_confirmed, unsubscribe_token, confirmation_status, is_sample, bounced_at, deleted_at`
+
+// scanSubscriber scans subscriberColumns and opens the stored address
+func (r *SubscriberRepository) scanSubscriber(row pgx.Row, s *generated.Subscriber) error {
+	err := row.Scan(
+		&s.Id,
+		&s.NewsletterId,
+		&s.Email,
+		&s.SubscribedAt,
+		&s.IsConfirmed,
+		&s.UnsubscribeToken,
+		&s.ConfirmationEmailStatus,
+		&s.IsSample,
+		&s.BouncedAt,
+		&s.DeletedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return r.openEmail(s)
+}
+
+// ListByNewsletterID retrieves a page of the newsletter's subscribers that are not deleted or
+// anonymized, most recent subscriptions first
+func (r *SubscriberRepository) ListByNewsletterID(ctx context.Context, newsletterID uuid.UUID, page pagination.Page) ([]*generated.Subscriber, *pagination.Cursor, error) {
 	query := `
-		SELECT id, newsletter_id, email, subscribed_at, is_confirmed, unsubscribe_token
+		SELECT ` + subscriberColumns + `
 		FROM subscribers
-		WHERE newsletter_id = $1
+		WHERE newsletter_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL
+		  AND ($2::timestamptz IS NULL OR (subscribed_at, id) < ($2, $3::uuid))
+		ORDER BY subscribed_at DESC, id DESC
+		LIMIT $4
+	`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, newsletterID, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query subscribers", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*generated.Subscriber
+	for rows.Next() {
+		s := &generated.Subscriber{}
+		if err := r.scanSubscriber(rows, s); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan subscriber row", "error", err)
+			return nil, nil, err
+		}
+		subscribers = append(subscribers, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating subscriber rows", "error", err)
+		return nil, nil, err
+	}
+
+	subscribers, next := pagination.Trim(subscribers, page, func(s *generated.Subscriber) pagination.Cursor {
+		return pagination.Cursor{Time: *s.SubscribedAt, ID: s.Id.String()}
+	})
+	return subscribers, next, nil
+}
+
+// StreamByNewsletterID scans the subscribers of a newsletter row by row, in the order of
+// ListByNewsletterID, and passes each one to fn, so callers can stream large lists without
+// loading them into memory. Iteration stops at the first error from fn.
+func (r *SubscriberRepository) StreamByNewsletterID(ctx context.Context, newsletterID uuid.UUID, fn func(*generated.Subscriber) error) error {
+	query := `
+		SELECT ` + subscriberColumns + `
+		FROM subscribers
+		WHERE newsletter_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL
+		ORDER BY subscribed_at DESC, id DESC
 	`
 
 	rows, err := r.db.Query(ctx, query, newsletterID)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to query subscribers", "error", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s := &generated.Subscriber{}
+		if err := r.scanSubscriber(rows, s); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan subscriber row", "error", err)
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating subscriber rows", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// ExportByNewsletterID calls fn with every subscriber of the newsletter, unsubscribed ones
+// included and deleted and anonymized ones left out, oldest subscription first. Rows are passed on as they are read, so exports of any
+// size use constant memory; the connection is held until fn has seen the last row.
+func (r *SubscriberRepository) ExportByNewsletterID(ctx context.Context, newsletterID uuid.UUID, fn func(row *generated.SubscriberExportRow) error) error {
+	query := `
+		SELECT id, email,
+			CASE
+				WHEN unsubscribed_at IS NOT NULL THEN 'unsubscribed'
+				WHEN is_confirmed THEN 'confirmed'
+				ELSE 'pending'
+			END,
+			is_confirmed, confirmation_status, subscribed_at, unsubscribed_at, is_sample
+		FROM subscribers
+		WHERE newsletter_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL
+		ORDER BY subscribed_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query subscribers for export", "error", err)
+		return err
+	}
+	defer rows.Close()
+
+	var row generated.SubscriberExportRow
+	for rows.Next() {
+		err := rows.Scan(
+			&row.Id,
+			&row.Email,
+			&row.Status,
+			&row.IsConfirmed,
+			&row.ConfirmationEmailStatus,
+			&row.SubscribedAt,
+			&row.UnsubscribedAt,
+			&row.IsSample,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan exported subscriber row", "error", err)
+			return err
+		}
+		email, err := r.emails.Open(string(row.Email))
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to decrypt exported subscriber email", "error", err)
+			return err
+		}
+		row.Email = openapi_types.Email(email)
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating exported subscriber rows", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Delete marks a subscriber of the newsletter deleted; ErrNotFound when there is no such
+// subscriber or it is already deleted
+func (r *SubscriberRepository) Delete(ctx context.Context, newsletterID uuid.UUID, subscriberID uuid.UUID) error {
+	query := `
+		UPDATE subscribers
+		SET deleted_at = now(), confirmation_retry_at = NULL
+		WHERE id = $1 AND newsletter_id = $2 AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, subscriberID, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete subscriber", "subscriberId", subscriberID, "error", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDeleted retrieves a page of the newsletter's subscribers deleted after since, most
+// recently deleted first
+func (r *SubscriberRepository) ListDeleted(ctx context.Context, newsletterID uuid.UUID, since time.Time, page pagination.Page) ([]*generated.Subscriber, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + subscriberColumns + `
+		FROM subscribers
+		WHERE newsletter_id = $1 AND deleted_at > $2
+		  AND ($3::timestamptz IS NULL OR (deleted_at, id) < ($3, $4::uuid))
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $5
+	`
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, newsletterID, since, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query deleted subscribers", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*generated.Subscriber
+	for rows.Next() {
+		s := &generated.Subscriber{}
+		if err := r.scanSubscriber(rows, s); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan deleted subscriber row", "error", err)
+			return nil, nil, err
+		}
+		subscribers = append(subscribers, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating deleted subscriber rows", "error", err)
+		return nil, nil, err
+	}
+
+	subscribers, next := pagination.Trim(subscribers, page, func(s *generated.Subscriber) pagination.Cursor {
+		return pagination.Cursor{Time: *s.DeletedAt, ID: s.Id.String()}
+	})
+	return subscribers, next, nil
+}
+
+// Restore undeletes a subscriber of the newsletter deleted after since; ErrNotFound when there
+// is none
+func (r *SubscriberRepository) Restore(ctx context.Context, newsletterID uuid.UUID, subscriberID uuid.UUID, since time.Time) (*generated.Subscriber, error) {
+	query := `
+		UPDATE subscribers
+		SET deleted_at = NULL
+		WHERE id = $1 AND newsletter_id = $2 AND deleted_at > $3
+		RETURNING ` + subscriberColumns
+
+	s := &generated.Subscriber{}
+	err := r.scanSubscriber(r.db.QueryRow(ctx, query, subscriberID, newsletterID, since), s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to restore subscriber", "subscriberId", subscriberID, "error", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// PurgeDeleted deletes up to limit subscribers deleted before the given time for good; rows
+// that reference them cascade. Returns how many subscribers were deleted.
+func (r *SubscriberRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM subscribers
+		WHERE id IN (
+			SELECT id FROM subscribers
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	result, err := r.db.Exec(ctx, query, before, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to purge deleted subscribers", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// AuditSubscribersMerged is the audit action of an editor merging a duplicate subscriber into another
+const AuditSubscribersMerged = "subscriber.merged"
+
+// Merge consolidates the subscriber mergeID of the newsletter into keepID, in one transaction.
+// The kept subscriber keeps its address and tokens and takes the earlier subscription date; it is
+// confirmed when either was, and an unsubscribe of either is kept. The address changes of the
+// merged subscriber move to the kept one, the merged subscriber is deleted and the merge is
+// audited. ErrNotFound unless both are subscribers of the newsletter that are not deleted.
+func (r *SubscriberRepository) Merge(ctx context.Context, newsletterID uuid.UUID, keepID uuid.UUID, mergeID uuid.UUID, editorID string) (*generated.Subscriber, error) {
+	merged := &generated.Subscriber{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		// Locked in ID order, so concurrent merges of the same pair cannot deadlock
+		rows, err := tx.Query(ctx, `
+			SELECT id FROM subscribers
+			WHERE id IN ($1, $2) AND newsletter_id = $3 AND deleted_at IS NULL
+			ORDER BY id
+			FOR UPDATE
+		`, keepID, mergeID, newsletterID)
+		if err != nil {
+			return err
+		}
+		locked := 0
+		for rows.Next() {
+			locked++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if locked != 2 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE subscribers k
+			SET subscribed_at = LEAST(k.subscribed_at, m.subscribed_at),
+			    is_confirmed = k.is_confirmed OR m.is_confirmed,
+			    confirmed_at = LEAST(k.confirmed_at, m.confirmed_at),
+			    unsubscribed_at = LEAST(k.unsubscribed_at, m.unsubscribed_at),
+			    confirmation_retry_at = CASE WHEN k.is_confirmed OR m.is_confirmed THEN NULL ELSE k.confirmation_retry_at END
+			FROM subscribers m
+			WHERE k.id = $1 AND m.id = $2
+		`, keepID, mergeID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE subscriber_email_changes SET subscriber_id = $1 WHERE subscriber_id = $2`, keepID, mergeID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			WITH deleted AS (
+				DELETE FROM subscribers WHERE id = $2
+				RETURNING subscribed_at, is_confirmed, unsubscribed_at
+			)
+			INSERT INTO audit_log (action, actor_id, newsletter_id, target_id, details)
+			SELECT $3, $4::uuid, $5, $1, jsonb_build_object('merged_id', $2::uuid, 'merged_subscribed_at', d.subscribed_at, 'merged_confirmed', d.is_confirmed, 'merged_unsubscribed_at', d.unsubscribed_at)
+			FROM deleted d
+		`, keepID, mergeID, AuditSubscribersMerged, editorID, newsletterID)
+		if err != nil {
+			return err
+		}
+
+		return r.scanSubscriber(tx.QueryRow(ctx, `SELECT `+subscriberColumns+` FROM subscribers WHERE id = $1`, keepID), merged)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			r.logger.ErrorContext(ctx, "Failed to merge subscribers", "keepId", keepID, "mergeId", mergeID, "error", err)
+		}
+		return nil, err
+	}
+	return merged, nil
+}
+
+// notSuppressed excludes subscribers on the platform-wide suppression list or on the suppression
+// list of their newsletter, which hold lowercased plaintext addresses and the blind indexes of
+// encrypted ones (emailcrypt.SuppressionKey)
+const notSuppressed = `
+		  AND NOT EXISTS (
+			SELECT 1 FROM email_suppressions es
+			WHERE es.email = lower(s.email) OR es.email = 'idx:' || encode(s.email_hash, 'hex')
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM newsletter_suppressions ns
+			WHERE ns.newsletter_id = s.newsletter_id
+			  AND (ns.email_key = lower(s.email) OR ns.email_key = 'idx:' || encode(s.email_hash, 'hex'))
+		  )
+	`
+
+// recipientFilter restricts subscribers of newsletter $1 to those a post is sent to: still
+// subscribed, not deleted, not a sample subscriber and not suppressed
+const recipientFilter = `
+		WHERE s.newsletter_id = $1
+		  AND s.unsubscribed_at IS NULL
+		  AND s.deleted_at IS NULL
+		  AND NOT s.is_sample` + notSuppressed
+
+// ListRecipientsByNewsletterID retrieves the subscribers a post of the newsletter is sent to
+func (r *SubscriberRepository) ListRecipientsByNewsletterID(ctx context.Context, newsletterID uuid.UUID) ([]*generated.Subscriber, error) {
+	query := `
+		SELECT s.id, s.newsletter_id, s.email, s.subscribed_at, s.is_confirmed, s.unsubscribe_token
+		FROM subscribers s` + recipientFilter
+
+	rows, err := r.db.Query(ctx, query, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query recipients", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -55,32 +482,86 @@ func (r *SubscriberRepository) ListByNewsletterID(ctx context.Context, newslette
 			&s.IsConfirmed,
 			&s.UnsubscribeToken,
 		)
+		if err == nil {
+			err = r.openEmail(s)
+		}
 		if err != nil {
-			r.logger.ErrorContext(ctx, "Failed to scan subscriber row", "error", err)
+			r.logger.ErrorContext(ctx, "Failed to scan recipient row", "error", err)
 			return nil, err
 		}
 		subscribers = append(subscribers, s)
 	}
 
 	if err = rows.Err(); err != nil {
-		r.logger.ErrorContext(ctx, "Error iterating subscriber rows", "error", err)
+		r.logger.ErrorContext(ctx, "Error iterating recipient rows", "error", err)
 		return nil, err
 	}
 
 	return subscribers, nil
 }
 
-// ExistsByEmail checks if a subscriber with the given email already exists for a newsletter
+// CountByNewsletterID counts the subscribers a post of the newsletter is sent to
+func (r *SubscriberRepository) CountByNewsletterID(ctx context.Context, newsletterID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM subscribers s` + recipientFilter
+
+	var count int
+	err := r.db.QueryRow(ctx, query, newsletterID).Scan(&count)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count subscribers", "error", err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkBounced marks every subscription of the address as bounced and suppresses the address,
+// in one transaction. Addresses are matched case-insensitively; an earlier bounce keeps its
+// time. Returns how many subscriptions the address has.
+func (r *SubscriberRepository) MarkBounced(ctx context.Context, email string) (int64, error) {
+	email = strings.ToLower(email)
+	var marked int64
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE subscribers
+			SET bounced_at = COALESCE(bounced_at, now())
+			WHERE lower(email) = $1 OR email_hash = $2
+		`, email, r.emails.Index(email))
+		if err != nil {
+			return err
+		}
+		marked = tag.RowsAffected()
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO email_suppressions (email, reason)
+			VALUES ($1, $2)
+			ON CONFLICT (email) DO NOTHING
+		`, r.emails.SuppressionKey(email), SuppressionBounce)
+		if err != nil {
+			return err
+		}
+		return suppressForNewsletters(ctx, tx, r.emails, email, SuppressionBounce)
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark address as bounced", "error", err)
+		return 0, err
+	}
+	return marked, nil
+}
+
+// ExistsByEmail checks if a subscriber with the given email already exists for a newsletter;
+// deleted subscribers do not count. Encrypted addresses are matched by their blind index.
 func (r *SubscriberRepository) ExistsByEmail(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error) {
 	query := `
 		SELECT EXISTS(
 			SELECT 1 FROM subscribers
-			WHERE newsletter_id = $1 AND email = $2
+			WHERE newsletter_id = $1 AND (email = $2 OR email_hash = $3) AND deleted_at IS NULL
 		)
 	`
 
 	var exists bool
-	err := r.db.QueryRow(ctx, query, newsletterID, email).Scan(&exists)
+	err := r.db.QueryRow(ctx, query, newsletterID, email, r.emails.Index(email)).Scan(&exists)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to check subscriber existence", "error", err)
 		return false, err
@@ -89,14 +570,23 @@ func (r *SubscriberRepository) ExistsByEmail(ctx context.Context, newsletterID u
 	return exists, nil
 }
 
-// Create adds a new subscriber to a newsletter
-func (r *SubscriberRepository) Create(ctx context.Context, newsletterID uuid.UUID, email string) (*generated.Subscriber, error) {
+// Create adds a new subscriber to a newsletter, confirmed already when confirmed is set. The
+// confirmation link stops working at confirmationExpiresAt, or never when it is nil. A
+// deleted subscriber with the address is deleted for good, as the address subscribed again. Concurrent requests for the same email can
+// both pass ExistsByEmail; the unique constraint decides and the loser gets ErrAlreadySubscribed.
+func (r *SubscriberRepository) Create(ctx context.Context, newsletterID uuid.UUID, email string, confirmed bool, confirmationExpiresAt *time.Time) (*generated.Subscriber, error) {
 	query := `
-		INSERT INTO subscribers (id, newsletter_id, email, subscribed_at, is_confirmed, unsubscribe_token, confirmation_token)
-		VALUES ($1, $2, $3, $4, false, $5, $6)
-		RETURNING id, newsletter_id, email, subscribed_at, is_confirmed, unsubscribe_token, confirmation_token
+		INSERT INTO subscribers (id, newsletter_id, email, email_hash, subscribed_at, is_confirmed, confirmed_at, unsubscribe_token, confirmation_token, confirmation_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $8, CASE WHEN $8 THEN $5::timestamptz END, $6, $7, $9)
+		RETURNING id, newsletter_id, subscribed_at, is_confirmed, unsubscribe_token, confirmation_token
 	`
 
+	sealed, hash, err := r.sealEmail(email)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt subscriber email", "error", err)
+		return nil, err
+	}
+
 	unsubscribeToken := uuid.New().String()
 	confirmationToken := uuid.New().String()
 
@@ -110,25 +600,40 @@ func (r *SubscriberRepository) Create(ctx context.Context, newsletterID uuid.UUI
 		ConfirmationToken: &confirmationToken,
 	}
 
-	err := r.db.QueryRow(
-		ctx,
-		query,
-		uuid.New(),
-		newsletterID,
-		email,
-		time.Now().UTC(),
-		unsubscribeToken,
-		confirmationToken,
-	).Scan(
-		&subscriber.Id,
-		&subscriber.NewsletterId,
-		&subscriber.Email,
-		&subscriber.SubscribedAt,
-		&subscriber.IsConfirmed,
-		&subscriber.UnsubscribeToken,
-		&subscriber.ConfirmationToken,
-	)
+	err = pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM subscribers
+			WHERE newsletter_id = $1 AND (email = $2 OR email_hash = $3) AND deleted_at IS NOT NULL
+		`, newsletterID, email, r.emails.Index(email))
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			ctx,
+			query,
+			uuid.New(),
+			newsletterID,
+			sealed,
+			hash,
+			time.Now().UTC(),
+			unsubscribeToken,
+			confirmationToken,
+			confirmed,
+			confirmationExpiresAt,
+		).Scan(
+			&subscriber.Id,
+			&subscriber.NewsletterId,
+			&subscriber.SubscribedAt,
+			&subscriber.IsConfirmed,
+			&subscriber.UnsubscribeToken,
+			&subscriber.ConfirmationToken,
+		)
+	})
 	if err != nil {
+		if isDuplicateSubscriber(err) {
+			return nil, ErrAlreadySubscribed
+		}
 		r.logger.ErrorContext(ctx, "Failed to create subscriber", "error", err)
 		return nil, err
 	}
@@ -136,43 +641,490 @@ func (r *SubscriberRepository) Create(ctx context.Context, newsletterID uuid.UUI
 	return subscriber, nil
 }
 
-// ConfirmByToken confirms a subscription using a confirmation token
-func (r *SubscriberRepository) ConfirmByToken(ctx context.Context, token string) error {
+// ConfirmByToken confirms a subscription using a confirmation token and returns it. An expired
+// token returns ErrConfirmationExpired, unless the subscription was confirmed already.
+func (r *SubscriberRepository) ConfirmByToken(ctx context.Context, token string) (*ChangedSubscription, error) {
 	query := `
-		UPDATE subscribers
-		SET is_confirmed = true
-		WHERE confirmation_token = $1
-		RETURNING id
+		UPDATE subscribers s
+		SET is_confirmed = true, confirmed_at = COALESCE(s.confirmed_at, NOW()), confirmation_retry_at = NULL, confirmation_expires_at = NULL
+		FROM (SELECT id, is_confirmed, confirmation_expires_at FROM subscribers WHERE confirmation_token = $1 AND deleted_at IS NULL FOR UPDATE) previous
+		WHERE s.id = previous.id
+		  AND (previous.is_confirmed OR previous.confirmation_expires_at IS NULL OR previous.confirmation_expires_at > NOW())
+		RETURNING s.id, s.newsletter_id, s.email, previous.is_confirmed
 	`
 
-	var id uuid.UUID
-	err := r.db.QueryRow(ctx, query, token).Scan(&id)
+	var changed ChangedSubscription
+	err := r.db.QueryRow(ctx, query, token).Scan(&changed.SubscriberID, &changed.NewsletterID, &changed.Email, &changed.WasConfirmed)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, r.unconfirmedTokenError(ctx, token)
+		}
 		r.logger.ErrorContext(ctx, "Failed to confirm subscription", "error", err)
-		return err
+		return nil, err
 	}
 
-	return nil
+	if changed.Email, err = r.emails.Open(changed.Email); err != nil {
+		return nil, err
+	}
+	return &changed, nil
+}
+
+// unconfirmedTokenError tells why a confirmation token confirmed nothing: ErrConfirmationExpired
+// when its subscription still exists, so the link expired, or ErrNotFound
+func (r *SubscriberRepository) unconfirmedTokenError(ctx context.Context, token string) error {
+	query := `SELECT EXISTS(SELECT 1 FROM subscribers WHERE confirmation_token = $1 AND deleted_at IS NULL)`
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, token).Scan(&exists); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up confirmation token", "error", err)
+		return err
+	}
+	if exists {
+		return ErrConfirmationExpired
+	}
+	return ErrNotFound
 }
 
-// UnsubscribeByToken unsubscribes a user using their unsubscribe token
-func (r *SubscriberRepository) UnsubscribeByToken(ctx context.Context, token string) error {
+// UnsubscribeByToken unsubscribes a user using their unsubscribe token and returns the ended subscription
+func (r *SubscriberRepository) UnsubscribeByToken(ctx context.Context, token string) (*ChangedSubscription, error) {
 	query := `
 		UPDATE subscribers
 		SET unsubscribed_at = NOW()
 		WHERE unsubscribe_token = $1 AND unsubscribed_at IS NULL
-		RETURNING id
+		RETURNING id, newsletter_id, email
 	`
 
-	var id uuid.UUID
-	err := r.db.QueryRow(ctx, query, token).Scan(&id)
+	var changed ChangedSubscription
+	err := r.db.QueryRow(ctx, query, token).Scan(&changed.SubscriberID, &changed.NewsletterID, &changed.Email)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrNotFound
+			return nil, ErrNotFound
 		}
 		r.logger.ErrorContext(ctx, "Failed to unsubscribe", "error", err)
+		return nil, err
+	}
+
+	if changed.Email, err = r.emails.Open(changed.Email); err != nil {
+		return nil, err
+	}
+	return &changed, nil
+}
+
+// GetByUnsubscribeToken returns the active subscription the unsubscribe token belongs to, unless
+// the editor deleted it
+func (r *SubscriberRepository) GetByUnsubscribeToken(ctx context.Context, token string) (*generated.Subscriber, error) {
+	query := `
+		SELECT id, newsletter_id, email, subscribed_at, is_confirmed, unsubscribe_token
+		FROM subscribers
+		WHERE unsubscribe_token = $1 AND unsubscribed_at IS NULL AND deleted_at IS NULL
+	`
+
+	s := &generated.Subscriber{}
+	err := r.db.QueryRow(ctx, query, token).Scan(
+		&s.Id,
+		&s.NewsletterId,
+		&s.Email,
+		&s.SubscribedAt,
+		&s.IsConfirmed,
+		&s.UnsubscribeToken,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get subscriber by unsubscribe token", "error", err)
+		return nil, err
+	}
+
+	if err := r.openEmail(s); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to decrypt subscriber email", "error", err)
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateEmailChange records a pending address change for the subscriber. Earlier pending
+// changes of the subscriber are discarded, so only the latest verification link works.
+func (r *SubscriberRepository) CreateEmailChange(ctx context.Context, subscriber *generated.Subscriber, newEmail string, ttl time.Duration) (*EmailChange, error) {
+	change := &EmailChange{
+		SubscriberID: *subscriber.Id,
+		NewsletterID: *subscriber.NewsletterId,
+		OldEmail:     string(subscriber.Email),
+		NewEmail:     newEmail,
+		Token:        uuid.New().String(),
+		ExpiresAt:    time.Now().UTC().Add(ttl),
+	}
+
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		oldEmail, oldEmailHash, err := r.sealEmail(change.OldEmail)
+		if err != nil {
+			return err
+		}
+		newEmail, newEmailHash, err := r.sealEmail(change.NewEmail)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM subscriber_email_changes WHERE subscriber_id = $1 AND confirmed_at IS NULL`, change.SubscriberID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO subscriber_email_changes (subscriber_id, old_email, old_email_hash, new_email, new_email_hash, token, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, change.SubscriberID, oldEmail, oldEmailHash, newEmail, newEmailHash, change.Token, change.ExpiresAt)
 		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create email change", "subscriberId", change.SubscriberID, "error", err)
+		return nil, err
 	}
 
+	return change, nil
+}
+
+// ApplyEmailChange verifies a pending change and moves the subscription to the new address in one
+// transaction. The subscription keeps its ID, tokens and confirmation state, and the change stays
+// in the history. Returns ErrNotFound for an unknown or used token, or when the subscription was
+// unsubscribed, deleted or changed since; ErrEmailChangeExpired; or ErrAlreadySubscribed when the
+// new address is already subscribed to the newsletter.
+func (r *SubscriberRepository) ApplyEmailChange(ctx context.Context, token string) (*EmailChange, error) {
+	change := &EmailChange{Token: token}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			SELECT c.subscriber_id, s.newsletter_id, c.old_email, c.new_email, c.expires_at
+			FROM subscriber_email_changes c
+			JOIN subscribers s ON s.id = c.subscriber_id
+			WHERE c.token = $1 AND c.confirmed_at IS NULL
+			FOR UPDATE
+		`
+		err := tx.QueryRow(ctx, query, token).Scan(
+			&change.SubscriberID,
+			&change.NewsletterID,
+			&change.OldEmail,
+			&change.NewEmail,
+			&change.ExpiresAt,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if !change.ExpiresAt.After(time.Now()) {
+			return ErrEmailChangeExpired
+		}
+		if change.OldEmail, err = r.emails.Open(change.OldEmail); err != nil {
+			return err
+		}
+		if change.NewEmail, err = r.emails.Open(change.NewEmail); err != nil {
+			return err
+		}
+		newEmail, newEmailHash, err := r.sealEmail(change.NewEmail)
+		if err != nil {
+			return err
+		}
+
+		// The stored address is compared in plaintext or by blind index, as ciphertexts differ
+		result, err := tx.Exec(ctx, `
+			UPDATE subscribers
+			SET email = $2, email_hash = $3
+			WHERE id = $1 AND (email = $4 OR email_hash = $5) AND unsubscribed_at IS NULL AND deleted_at IS NULL
+		`, change.SubscriberID, newEmail, newEmailHash, change.OldEmail, r.emails.Index(change.OldEmail))
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE subscriber_email_changes SET confirmed_at = now() WHERE token = $1`, token)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrEmailChangeExpired):
+			return nil, err
+		case isDuplicateSubscriber(err):
+			return nil, ErrAlreadySubscribed
+		}
+		r.logger.ErrorContext(ctx, "Failed to apply email change", "error", err)
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// renewableConfirmationFilter selects unconfirmed subscriptions a confirmation link can be sent
+// for, whether or not their current link expired
+const renewableConfirmationFilter = `
+		  AND NOT s.is_confirmed
+		  AND s.unsubscribed_at IS NULL
+		  AND s.deleted_at IS NULL
+		  AND s.confirmation_token IS NOT NULL` + notSuppressed
+
+// pendingConfirmationFilter selects unconfirmed subscriptions that can still be confirmed with
+// their current link
+const pendingConfirmationFilter = renewableConfirmationFilter + `
+		  AND (s.confirmation_expires_at IS NULL OR s.confirmation_expires_at > now())`
+
+// RecordConfirmationSend stores the outcome of a confirmation email. An empty sendError marks it
+// sent; otherwise it is marked failed and retried at retryAt, or never when retryAt is nil.
+func (r *SubscriberRepository) RecordConfirmationSend(ctx context.Context, subscriberID uuid.UUID, sendError string, retryAt *time.Time) error {
+	query := `
+		UPDATE subscribers
+		SET confirmation_status = CASE WHEN $2 = '' THEN 'sent' ELSE 'failed' END,
+			confirmation_attempts = confirmation_attempts + 1,
+			confirmation_last_error = NULLIF($2, ''),
+			confirmation_sent_at = now(),
+			confirmation_retry_at = $3
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(ctx, query, subscriberID, sendError, retryAt); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record confirmation email outcome", "subscriberId", subscriberID, "error", err)
+		return err
+	}
 	return nil
 }
+
+// ClaimConfirmationRetries returns up to limit failed confirmations whose retry is due and
+// pushes their retry back by lease, so other instances skip them while this one sends.
+// Retries of subscriptions that can no longer be confirmed are dropped.
+func (r *SubscriberRepository) ClaimConfirmationRetries(ctx context.Context, limit int, lease time.Duration) ([]PendingConfirmation, error) {
+	var claimed []PendingConfirmation
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE subscribers
+			SET confirmation_retry_at = NULL
+			WHERE confirmation_retry_at IS NOT NULL
+			  AND (is_confirmed OR unsubscribed_at IS NOT NULL OR deleted_at IS NOT NULL OR confirmation_token IS NULL
+				OR confirmation_expires_at <= now())
+		`)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			UPDATE subscribers s
+			SET confirmation_retry_at = now() + make_interval(secs => $2)
+			FROM newsletters n
+			WHERE n.id = s.newsletter_id
+			  AND s.id IN (
+				SELECT id FROM subscribers
+				WHERE confirmation_retry_at <= now()
+				  AND newsletter_id NOT IN (SELECT id FROM newsletters WHERE deleted_at IS NOT NULL)
+				ORDER BY confirmation_retry_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			  )`+pendingConfirmationFilter+`
+			RETURNING s.id, s.newsletter_id, n.name, s.email, s.confirmation_token, s.confirmation_attempts
+		`, limit, lease.Seconds())
+		if err != nil {
+			return err
+		}
+		claimed, err = r.collectPendingConfirmations(rows)
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to claim confirmation retries", "error", err)
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// ListPendingConfirmations returns the newsletter's unconfirmed subscriptions whose last
+// confirmation email is older than cooldown or was never sent
+func (r *SubscriberRepository) ListPendingConfirmations(ctx context.Context, newsletterID uuid.UUID, cooldown time.Duration) ([]PendingConfirmation, error) {
+	query := `
+		SELECT s.id, s.newsletter_id, n.name, s.email, s.confirmation_token, s.confirmation_attempts
+		FROM subscribers s
+		JOIN newsletters n ON n.id = s.newsletter_id
+		WHERE s.newsletter_id = $1` + pendingConfirmationFilter + `
+		  AND (s.confirmation_sent_at IS NULL OR s.confirmation_sent_at < now() - make_interval(secs => $2))
+		ORDER BY s.subscribed_at
+	`
+	rows, err := r.db.Query(ctx, query, newsletterID, cooldown.Seconds())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query pending confirmations", "error", err)
+		return nil, err
+	}
+	pending, err := r.collectPendingConfirmations(rows)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to scan pending confirmations", "error", err)
+		return nil, err
+	}
+	return pending, nil
+}
+
+// RenewConfirmation gives the newsletter's unconfirmed subscription of email a new confirmation
+// link, valid until expiresAt or for good when it is nil, and returns it for sending. The
+// previous link stops working. Subscriptions sent a link within cooldown are left alone. Returns
+// ErrNotFound when there is no such subscription or it is in its cooldown.
+func (r *SubscriberRepository) RenewConfirmation(ctx context.Context, newsletterID uuid.UUID, email string, expiresAt *time.Time, cooldown time.Duration) (*PendingConfirmation, error) {
+	query := `
+		UPDATE subscribers s
+		SET confirmation_token = $4,
+			confirmation_expires_at = $5,
+			confirmation_attempts = 0,
+			confirmation_retry_at = NULL
+		FROM newsletters n
+		WHERE n.id = s.newsletter_id
+		  AND s.newsletter_id = $1
+		  AND (s.email = $2 OR s.email_hash = $3)` + renewableConfirmationFilter + `
+		  AND (s.confirmation_sent_at IS NULL OR s.confirmation_sent_at < now() - make_interval(secs => $6))
+		RETURNING s.id, s.newsletter_id, n.name, s.email, s.confirmation_token, s.confirmation_attempts
+	`
+	rows, err := r.db.Query(ctx, query, newsletterID, email, r.emails.Index(email), uuid.New().String(), expiresAt, cooldown.Seconds())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to renew confirmation", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	renewed, err := r.collectPendingConfirmations(rows)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to scan renewed confirmation", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	if len(renewed) == 0 {
+		return nil, ErrNotFound
+	}
+	return &renewed[0], nil
+}
+
+func (r *SubscriberRepository) collectPendingConfirmations(rows pgx.Rows) ([]PendingConfirmation, error) {
+	defer rows.Close()
+	var pending []PendingConfirmation
+	for rows.Next() {
+		var p PendingConfirmation
+		if err := rows.Scan(&p.SubscriberID, &p.NewsletterID, &p.NewsletterName, &p.Email, &p.ConfirmationToken, &p.Attempts); err != nil {
+			return nil, err
+		}
+		email, err := r.emails.Open(p.Email)
+		if err != nil {
+			return nil, err
+		}
+		p.Email = email
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// EncryptStoredEmails encrypts the plaintext addresses of subscribers and of their address
+// changes, reading batchSize rows at a time, for deployments that enable encryption on existing
+// data. An address that differs from an already encrypted subscription of the same newsletter
+// only by case stays in plaintext and is counted as a duplicate.
+func (r *SubscriberRepository) EncryptStoredEmails(ctx context.Context, batchSize int) (encrypted int, duplicates int, err error) {
+	if !r.emails.Enabled() {
+		return 0, 0, errors.New("subscriber email encryption is not enabled")
+	}
+
+	err = forEachPlaintext(ctx, r.db, `
+		SELECT id, email FROM subscribers
+		WHERE id > $1 AND email_hash IS NULL
+		ORDER BY id
+		LIMIT $2
+	`, batchSize, func(id uuid.UUID, values []string) error {
+		sealed, hash, err := r.sealEmail(values[0])
+		if err != nil {
+			return err
+		}
+		// The address is compared so a concurrent change is not overwritten
+		_, err = r.db.Exec(ctx, `
+			UPDATE subscribers SET email = $2, email_hash = $3
+			WHERE id = $1 AND email = $4
+		`, id, sealed, hash, values[0])
+		switch {
+		case isDuplicateSubscriber(err):
+			r.logger.WarnContext(ctx, "Subscriber email left unencrypted, the newsletter has it encrypted in another case", "subscriberId", id)
+			duplicates++
+			return nil
+		case err != nil:
+			return err
+		}
+		encrypted++
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt subscriber emails", "error", err)
+		return encrypted, duplicates, err
+	}
+
+	err = forEachPlaintext(ctx, r.db, `
+		SELECT id, old_email, new_email FROM subscriber_email_changes
+		WHERE id > $1 AND (old_email_hash IS NULL OR new_email_hash IS NULL)
+		ORDER BY id
+		LIMIT $2
+	`, batchSize, func(id uuid.UUID, values []string) error {
+		// Changes encrypted before old_email_hash existed only need their index
+		var addresses [2]string
+		for i, value := range values {
+			address, err := r.emails.Open(value)
+			if err != nil {
+				return err
+			}
+			addresses[i] = address
+		}
+		oldEmail, oldEmailHash, err := r.sealEmail(addresses[0])
+		if err != nil {
+			return err
+		}
+		newEmail, newEmailHash, err := r.sealEmail(addresses[1])
+		if err != nil {
+			return err
+		}
+		_, err = r.db.Exec(ctx, `
+			UPDATE subscriber_email_changes SET old_email = $2, old_email_hash = $3, new_email = $4, new_email_hash = $5
+			WHERE id = $1 AND (old_email_hash IS NULL OR new_email_hash IS NULL)
+		`, id, oldEmail, oldEmailHash, newEmail, newEmailHash)
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt subscriber email changes", "error", err)
+		return encrypted, duplicates, err
+	}
+
+	return encrypted, duplicates, nil
+}
+
+// forEachPlaintext pages through query, which takes the last seen id ($1) and a batch size ($2)
+// and selects an id followed by text columns, passing each row to fn. Repositories use it to
+// encrypt the addresses they stored in plaintext.
+func forEachPlaintext(ctx context.Context, db DB, query string, batchSize int, fn func(id uuid.UUID, values []string) error) error {
+	type row struct {
+		id     uuid.UUID
+		values []string
+	}
+
+	after := uuid.Nil
+	for {
+		rows, err := db.Query(ctx, query, after, batchSize)
+		if err != nil {
+			return err
+		}
+		var batch []row
+		for rows.Next() {
+			values := make([]string, len(rows.FieldDescriptions())-1)
+			dest := []any{new(uuid.UUID)}
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, row{id: *dest[0].(*uuid.UUID), values: values})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, b := range batch {
+			if err := fn(b.id, b.values); err != nil {
+				return err
+			}
+		}
+		after = batch[len(batch)-1].id
+	}
+}
@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// Reasons recorded for suppressed addresses
+const (
+	// SuppressionUnsubscribeAll is recorded for addresses that used an unsubscribe-all link
+	SuppressionUnsubscribeAll = "unsubscribe_all"
+	// SuppressionBounce is recorded for addresses the email provider reported a permanent bounce for
+	SuppressionBounce = "bounce"
+	// SuppressionComplaint is recorded for addresses whose recipient marked a post as spam
+	SuppressionComplaint = "complaint"
+	// SuppressionManual is recorded for addresses an editor blocked for their newsletter
+	SuppressionManual = "manual"
+)
+
+const (
+	uniqueNewsletterSuppressionConstraint = "unique_newsletter_suppression"
+	newsletterSuppressionColumns          = `id, email, reason, note, created_at`
+)
+
+// ErrNotManualSuppression is returned when an editor tries to lift a bounce or complaint
+var ErrNotManualSuppression = errors.New("suppression was not added manually")
+
+type SuppressionRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewSuppressionRepository creates the repository; with emails set, addresses are suppressed by
+// their blind index instead of in plaintext
+func NewSuppressionRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *SuppressionRepository {
+	return &SuppressionRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// UnsubscribeAll unsubscribes the address from every newsletter and suppresses it, in one
+// transaction. Addresses are matched case-insensitively. Returns the subscriptions that ended.
+func (r *SuppressionRepository) UnsubscribeAll(ctx context.Context, email string, reason string) ([]ChangedSubscription, error) {
+	email = strings.ToLower(email)
+	var unsubscribed []ChangedSubscription
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE subscribers
+			SET unsubscribed_at = NOW()
+			WHERE (lower(email) = $1 OR email_hash = $2) AND unsubscribed_at IS NULL
+			RETURNING id, newsletter_id
+		`, email, r.emails.Index(email))
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			changed := ChangedSubscription{Email: email}
+			if err := rows.Scan(&changed.SubscriberID, &changed.NewsletterID); err != nil {
+				rows.Close()
+				return err
+			}
+			unsubscribed = append(unsubscribed, changed)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO email_suppressions (email, reason)
+			VALUES ($1, $2)
+			ON CONFLICT (email) DO NOTHING
+		`, r.emails.SuppressionKey(email), reason)
+		if err != nil || reason != SuppressionComplaint {
+			return err
+		}
+		return suppressForNewsletters(ctx, tx, r.emails, email, reason)
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to unsubscribe address from all newsletters", "error", err)
+		return nil, err
+	}
+	return unsubscribed, nil
+}
+
+// suppressForNewsletters lists the address on the suppression list of every newsletter it
+// subscribes to, within tx. Entries already there, manual blocks included, are kept.
+func suppressForNewsletters(ctx context.Context, tx pgx.Tx, emails *emailcrypt.Cipher, email string, reason string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO newsletter_suppressions (newsletter_id, email_key, email, reason)
+		SELECT newsletter_id, $3, email, $4
+		FROM subscribers
+		WHERE lower(email) = $1 OR email_hash = $2
+		ON CONFLICT (newsletter_id, email_key) DO NOTHING
+	`, strings.ToLower(email), emails.Index(email), emails.SuppressionKey(email), reason)
+	return err
+}
+
+// Remove lifts the suppression of the address, together with the bounces and complaints listed
+// for its newsletters; manual blocks stay. It is not an error when there is none.
+func (r *SuppressionRepository) Remove(ctx context.Context, email string) (bool, error) {
+	keys := []string{strings.ToLower(email), r.emails.SuppressionKey(email)}
+	var removed bool
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM email_suppressions WHERE email = ANY($1)`, keys)
+		if err != nil {
+			return err
+		}
+		removed = result.RowsAffected() > 0
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM newsletter_suppressions
+			WHERE email_key = ANY($1) AND reason IN ($2, $3)
+		`, keys, SuppressionBounce, SuppressionComplaint)
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to remove email suppression", "error", err)
+		return false, err
+	}
+	return removed, nil
+}
+
+// ListByNewsletter retrieves a page of the newsletter's suppression list, newest first
+func (r *SuppressionRepository) ListByNewsletter(ctx context.Context, newsletterID uuid.UUID, page pagination.Page) ([]*generated.NewsletterSuppression, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + newsletterSuppressionColumns + `
+		FROM newsletter_suppressions
+		WHERE newsletter_id = $1
+		  AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, newsletterID, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query newsletter suppressions", "newsletterId", newsletterID, "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var suppressions []*generated.NewsletterSuppression
+	for rows.Next() {
+		suppression, err := r.scanNewsletterSuppression(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan newsletter suppression row", "error", err)
+			return nil, nil, err
+		}
+		suppressions = append(suppressions, suppression)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating newsletter suppression rows", "error", err)
+		return nil, nil, err
+	}
+
+	suppressions, next := pagination.Trim(suppressions, page, func(s *generated.NewsletterSuppression) pagination.Cursor {
+		return pagination.Cursor{Time: *s.CreatedAt, ID: s.Id.String()}
+	})
+	return suppressions, next, nil
+}
+
+// AddToNewsletter blocks the address for the newsletter; ErrAlreadyExists when it is already
+// on the newsletter's suppression list
+func (r *SuppressionRepository) AddToNewsletter(ctx context.Context, newsletterID uuid.UUID, email string, note *string) (*generated.NewsletterSuppression, error) {
+	sealed, err := r.emails.Seal(email)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO newsletter_suppressions (newsletter_id, email_key, email, reason, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + newsletterSuppressionColumns
+	suppression, err := r.scanNewsletterSuppression(r.db.QueryRow(ctx, query, newsletterID, r.emails.SuppressionKey(email), sealed, SuppressionManual, note))
+	if isUniqueViolation(err, uniqueNewsletterSuppressionConstraint) {
+		return nil, ErrAlreadyExists
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to add newsletter suppression", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	return suppression, nil
+}
+
+// RemoveFromNewsletter lifts a manual block of the newsletter; ErrNotFound when the newsletter
+// has no such entry and ErrNotManualSuppression when it is a bounce or complaint
+func (r *SuppressionRepository) RemoveFromNewsletter(ctx context.Context, newsletterID uuid.UUID, id uuid.UUID) error {
+	var reason string
+	err := r.db.QueryRow(ctx, `
+		WITH entry AS (
+			SELECT id, reason FROM newsletter_suppressions WHERE id = $1 AND newsletter_id = $2
+		), deleted AS (
+			DELETE FROM newsletter_suppressions
+			WHERE id IN (SELECT id FROM entry WHERE reason = $3)
+		)
+		SELECT reason FROM entry
+	`, id, newsletterID, SuppressionManual).Scan(&reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to remove newsletter suppression", "id", id, "error", err)
+		return err
+	}
+	if reason != SuppressionManual {
+		return ErrNotManualSuppression
+	}
+	return nil
+}
+
+// IsBlocked reports whether an editor blocked the address for the newsletter
+func (r *SuppressionRepository) IsBlocked(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM newsletter_suppressions
+			WHERE newsletter_id = $1 AND email_key = ANY($2) AND reason = $3
+		)
+	`, newsletterID, []string{strings.ToLower(email), r.emails.SuppressionKey(email)}, SuppressionManual).Scan(&blocked)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check newsletter suppression", "newsletterId", newsletterID, "error", err)
+		return false, err
+	}
+	return blocked, nil
+}
+
+// IsSuppressed reports whether the address is suppressed in a way only a confirmed subscription
+// lifts (see Remove): on the platform-wide list, or bounced or complained on the newsletter
+func (r *SuppressionRepository) IsSuppressed(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error) {
+	var suppressed bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM email_suppressions WHERE email = ANY($2)
+		) OR EXISTS (
+			SELECT 1 FROM newsletter_suppressions
+			WHERE newsletter_id = $1 AND email_key = ANY($2) AND reason IN ($3, $4)
+		)
+	`, newsletterID, []string{strings.ToLower(email), r.emails.SuppressionKey(email)}, SuppressionBounce, SuppressionComplaint).Scan(&suppressed)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check email suppression", "newsletterId", newsletterID, "error", err)
+		return false, err
+	}
+	return suppressed, nil
+}
+
+// scanNewsletterSuppression scans a row of newsletterSuppressionColumns and opens its address
+func (r *SuppressionRepository) scanNewsletterSuppression(row pgx.Row) (*generated.NewsletterSuppression, error) {
+	var suppression generated.NewsletterSuppression
+	var stored string
+	err := row.Scan(
+		&suppression.Id,
+		&stored,
+		&suppression.Reason,
+		&suppression.Note,
+		&suppression.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	email, err := r.emails.Open(stored)
+	if err != nil {
+		return nil, err
+	}
+	address := openapi_types.Email(email)
+	suppression.Email = &address
+	return &suppression, nil
+}
+
+// EncryptStoredEmails replaces the plaintext addresses on the suppression list by their blind
+// index, in one transaction. Returns how many addresses were replaced.
+func (r *SuppressionRepository) EncryptStoredEmails(ctx context.Context) (int, error) {
+	if !r.emails.Enabled() {
+		return 0, errors.New("subscriber email encryption is not enabled")
+	}
+
+	var replaced int
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT email FROM email_suppressions WHERE email NOT LIKE 'idx:%' FOR UPDATE`)
+		if err != nil {
+			return err
+		}
+		var emails []string
+		for rows.Next() {
+			var email string
+			if err := rows.Scan(&email); err != nil {
+				rows.Close()
+				return err
+			}
+			emails = append(emails, email)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO email_suppressions (email, reason, created_at)
+				SELECT $2, reason, created_at FROM email_suppressions WHERE email = $1
+				ON CONFLICT (email) DO NOTHING
+			`, email, r.emails.SuppressionKey(email))
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM email_suppressions WHERE email = $1`, email); err != nil {
+				return err
+			}
+			replaced++
+		}
+
+		n, err := r.encryptNewsletterSuppressions(ctx, tx)
+		replaced += n
+		return err
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt suppressed addresses", "error", err)
+		return 0, err
+	}
+	return replaced, nil
+}
+
+// encryptNewsletterSuppressions keys the plaintext entries of the newsletters' suppression lists
+// by their blind index and encrypts their addresses, within tx. A plaintext entry whose address is
+// already listed by its index is dropped. Returns how many entries were replaced.
+func (r *SuppressionRepository) encryptNewsletterSuppressions(ctx context.Context, tx pgx.Tx) (int, error) {
+	rows, err := tx.Query(ctx, `SELECT id, email FROM newsletter_suppressions WHERE email_key NOT LIKE 'idx:%' FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+	type entry struct {
+		id    uuid.UUID
+		email string
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.email); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range entries {
+		email, err := r.emails.Open(e.email)
+		if err != nil {
+			return 0, err
+		}
+		sealed, err := r.emails.Seal(email)
+		if err != nil {
+			return 0, err
+		}
+		key := r.emails.SuppressionKey(email)
+		_, err = tx.Exec(ctx, `
+			DELETE FROM newsletter_suppressions p
+			WHERE id = $1 AND EXISTS (
+				SELECT 1 FROM newsletter_suppressions WHERE newsletter_id = p.newsletter_id AND email_key = $2
+			)
+		`, e.id, key)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE newsletter_suppressions SET email_key = $2, email = $3 WHERE id = $1`, e.id, key, sealed); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Kinds of tracked email events
+const (
+	EventOpen  = "open"
+	EventClick = "click"
+)
+
+// maxTrackedLinks bounds the links listed in the stats of a post
+const maxTrackedLinks = 50
+
+// TrackingCounts sums up the opens and clicks of the emails of a post. A click counts the
+// email as opened too, as clients blocking images never load the pixel.
+type TrackingCounts struct {
+	Opens        int
+	UniqueOpens  int
+	Clicks       int
+	UniqueClicks int
+	Links        []LinkClicks
+}
+
+// LinkClicks counts the clicks on one link of a post
+type LinkClicks struct {
+	URL          string
+	Clicks       int
+	UniqueClicks int
+}
+
+type TrackingRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewTrackingRepository(db *pgxpool.Pool, logger *slog.Logger) *TrackingRepository {
+	return &TrackingRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RecordEvent stores an open or click of the message; url is the target of a click. Events of
+// unknown messages are dropped, and false is returned.
+func (r *TrackingRepository) RecordEvent(ctx context.Context, messageID uuid.UUID, kind string, url *string) (bool, error) {
+	query := `
+		INSERT INTO email_message_events (message_id, post_id, kind, url)
+		SELECT id, post_id, $2, $3
+		FROM email_messages
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, messageID, kind, url)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record email event", "messageId", messageID, "kind", kind, "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// PostCounts returns the opens and clicks of the emails of a post, with the most clicked links
+func (r *TrackingRepository) PostCounts(ctx context.Context, postID uuid.UUID) (*TrackingCounts, error) {
+	query := `
+		SELECT
+			count(*) FILTER (WHERE kind = $2),
+			count(DISTINCT message_id),
+			count(*) FILTER (WHERE kind = $3),
+			count(DISTINCT message_id) FILTER (WHERE kind = $3)
+		FROM email_message_events
+		WHERE post_id = $1
+	`
+	counts := &TrackingCounts{Links: []LinkClicks{}}
+	err := r.db.QueryRow(ctx, query, postID, EventOpen, EventClick).Scan(&counts.Opens, &counts.UniqueOpens, &counts.Clicks, &counts.UniqueClicks)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count email events", "postId", postID, "error", err)
+		return nil, err
+	}
+
+	linksQuery := `
+		SELECT url, count(*), count(DISTINCT message_id)
+		FROM email_message_events
+		WHERE post_id = $1 AND kind = $2
+		GROUP BY url
+		ORDER BY count(*) DESC, url
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, linksQuery, postID, EventClick, maxTrackedLinks)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count link clicks", "postId", postID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link LinkClicks
+		if err := rows.Scan(&link.URL, &link.Clicks, &link.UniqueClicks); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan link clicks", "postId", postID, "error", err)
+			return nil, err
+		}
+		counts.Links = append(counts.Links, link)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating link clicks", "postId", postID, "error", err)
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UsageIncrement is the number of calls an editor made to an endpoint since the last flush
+type UsageIncrement struct {
+	EditorID     uuid.UUID
+	PeriodStart  time.Time
+	Endpoint     string
+	Calls        int64
+	LastCalledAt time.Time
+}
+
+type UsageRepository struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewUsageRepository(db *pgxpool.Pool, logger *slog.Logger) *UsageRepository {
+	return &UsageRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AddCalls adds the increments to the stored counters in a single batch
+func (r *UsageRepository) AddCalls(ctx context.Context, increments []UsageIncrement) error {
+	if len(increments) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO api_usage (editor_id, period_start, endpoint, call_count, last_called_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (editor_id, period_start, endpoint) DO UPDATE
+		SET call_count = api_usage.call_count + EXCLUDED.call_count,
+			last_called_at = GREATEST(api_usage.last_called_at, EXCLUDED.last_called_at)
+	`
+	batch := &pgx.Batch{}
+	for _, inc := range increments {
+		batch.Queue(query, inc.EditorID, inc.PeriodStart, inc.Endpoint, inc.Calls, inc.LastCalledAt)
+	}
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to store API usage", "count", len(increments), "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListByEditor returns the editor's calls per endpoint in the period, most used first
+func (r *UsageRepository) ListByEditor(ctx context.Context, editorID uuid.UUID, periodStart time.Time) ([]generated.ApiUsageEndpoint, error) {
+	query := `
+		SELECT endpoint, call_count, last_called_at
+		FROM api_usage
+		WHERE editor_id = $1 AND period_start = $2
+		ORDER BY call_count DESC, endpoint
+	`
+	rows, err := r.db.Query(ctx, query, editorID, periodStart)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query API usage", "editorId", editorID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []generated.ApiUsageEndpoint{}
+	for rows.Next() {
+		var endpoint generated.ApiUsageEndpoint
+		if err := rows.Scan(&endpoint.Endpoint, &endpoint.Calls, &endpoint.LastCalledAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan API usage row", "error", err)
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating API usage rows", "error", err)
+		return nil, err
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/emailcrypt"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	webhookColumns         = `id, newsletter_id, url, events, created_at`
+	webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, response_status, last_error,
+		CASE WHEN status = 'pending' THEN available_at END, created_at, finished_at`
+)
+
+// WebhookCall is a pending webhook delivery claimed for sending
+type WebhookCall struct {
+	ID        uuid.UUID
+	WebhookID uuid.UUID
+	URL       string
+	Secret    string
+	Event     string
+	// Payload is the JSON body, the same for every attempt
+	Payload []byte
+	// Attempts counts the calls of the delivery, including the current one
+	Attempts int
+}
+
+type WebhookRepository struct {
+	db     *pgxpool.Pool
+	emails *emailcrypt.Cipher
+	logger *slog.Logger
+}
+
+// NewWebhookRepository creates the repository; with emails set, the subscriber address in the
+// payload of a delivery (data.email) is stored encrypted and opened again when it is sent
+func NewWebhookRepository(db *pgxpool.Pool, emails *emailcrypt.Cipher, logger *slog.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db:     db,
+		emails: emails,
+		logger: logger,
+	}
+}
+
+// Create registers a webhook of the newsletter
+func (r *WebhookRepository) Create(ctx context.Context, newsletterID uuid.UUID, url string, secret string, events []string) (*generated.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (newsletter_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + webhookColumns
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, newsletterID, url, secret, events))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create webhook", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListByNewsletter returns the webhooks of the newsletter, newest first
+func (r *WebhookRepository) ListByNewsletter(ctx context.Context, newsletterID uuid.UUID) ([]generated.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE newsletter_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(ctx, query, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query webhooks", "newsletterId", newsletterID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []generated.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan webhook row", "error", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating webhook rows", "error", err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// CountByNewsletter returns the number of webhooks of the newsletter
+func (r *WebhookRepository) CountByNewsletter(ctx context.Context, newsletterID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, `SELECT count(*) FROM webhooks WHERE newsletter_id = $1`, newsletterID).Scan(&count); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count webhooks", "newsletterId", newsletterID, "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Exists reports whether the newsletter has the webhook
+func (r *WebhookRepository) Exists(ctx context.Context, newsletterID uuid.UUID, webhookID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND newsletter_id = $2)`, webhookID, newsletterID).Scan(&exists)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up webhook", "id", webhookID, "error", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// Delete deletes a webhook of the newsletter with its deliveries; ErrNotFound when the
+// newsletter has no such webhook
+func (r *WebhookRepository) Delete(ctx context.Context, newsletterID uuid.UUID, webhookID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND newsletter_id = $2`, webhookID, newsletterID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete webhook", "id", webhookID, "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Enqueue queues a delivery of the event for every webhook of the newsletter that subscribes
+// to it. Returns how many deliveries were queued.
+func (r *WebhookRepository) Enqueue(ctx context.Context, newsletterID uuid.UUID, event string, payload []byte) (int64, error) {
+	var email string
+	payload, err := replacePayloadEmail(payload, func(address string) (string, error) {
+		email = address
+		return r.emails.Seal(address)
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt webhook payload", "newsletterId", newsletterID, "event", event, "error", err)
+		return 0, err
+	}
+	var emailHash []byte
+	if email != "" {
+		emailHash = r.emails.Index(email)
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, email_hash)
+		SELECT id, $2::text, $3::jsonb, $4
+		FROM webhooks
+		WHERE newsletter_id = $1 AND $2::text = ANY(events)
+	`
+	tag, err := r.db.Exec(ctx, query, newsletterID, event, payload, emailHash)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to queue webhook deliveries", "newsletterId", newsletterID, "event", event, "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Claim leases up to limit due pending deliveries, oldest first, and counts an attempt on
+// each, the same way OutboxRepository.Claim does for emails
+func (r *WebhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]WebhookCall, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET available_at = now() + $2::interval, attempts = d.attempts + 1
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND available_at <= now()
+			ORDER BY available_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.attempts
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, lease)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to claim webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []WebhookCall
+	for rows.Next() {
+		var call WebhookCall
+		err := rows.Scan(
+			&call.ID,
+			&call.WebhookID,
+			&call.URL,
+			&call.Secret,
+			&call.Event,
+			&call.Payload,
+			&call.Attempts,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan webhook delivery row", "error", err)
+			return nil, err
+		}
+		if call.Payload, err = replacePayloadEmail(call.Payload, r.emails.Open); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to decrypt webhook payload", "id", call.ID, "error", err)
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating webhook delivery rows", "error", err)
+		return nil, err
+	}
+	return calls, nil
+}
+
+// MarkDelivered marks a delivery as delivered with the status code of the webhook's answer
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', response_status = $2, last_error = NULL, finished_at = now()
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(ctx, query, id, responseStatus); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark webhook delivery as delivered", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Reschedule returns a claimed delivery to the queue, due again after delay. responseStatus
+// is nil when the webhook did not answer.
+func (r *WebhookRepository) Reschedule(ctx context.Context, id uuid.UUID, delay time.Duration, responseStatus *int, callError string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET available_at = now() + $2::interval, response_status = $3, last_error = $4
+		WHERE id = $1 AND status = 'pending'
+	`
+	if _, err := r.db.Exec(ctx, query, id, delay, responseStatus, callError); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to reschedule webhook delivery", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// MarkFailed marks a delivery as failed for good
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id uuid.UUID, responseStatus *int, callError string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'failed', response_status = $2, last_error = $3, finished_at = now()
+		WHERE id = $1
+	`
+	if _, err := r.db.Exec(ctx, query, id, responseStatus, callError); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark webhook delivery as failed", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries retrieves a page of the webhook's deliveries, newest first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, page pagination.Page) ([]generated.WebhookDelivery, *pagination.Cursor, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		  AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4`
+
+	after, afterID := page.KeysetArgs()
+	rows, err := r.db.Query(ctx, query, webhookID, after, afterID, page.FetchLimit())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query webhook deliveries", "webhookId", webhookID, "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []generated.WebhookDelivery{}
+	for rows.Next() {
+		var delivery generated.WebhookDelivery
+		err := rows.Scan(
+			&delivery.Id,
+			&delivery.WebhookId,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.ResponseStatus,
+			&delivery.LastError,
+			&delivery.NextAttemptAt,
+			&delivery.CreatedAt,
+			&delivery.FinishedAt,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan webhook delivery row", "error", err)
+			return nil, nil, err
+		}
+		if err := r.openPayloadEmail(delivery.Payload); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to decrypt webhook payload", "id", delivery.Id, "error", err)
+			return nil, nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating webhook delivery rows", "error", err)
+		return nil, nil, err
+	}
+
+	deliveries, next := pagination.Trim(deliveries, page, func(d generated.WebhookDelivery) pagination.Cursor {
+		return pagination.Cursor{Time: *d.CreatedAt, ID: d.Id.String()}
+	})
+	return deliveries, next, nil
+}
+
+// Purge deletes delivered and failed deliveries that finished more than retention ago
+func (r *WebhookRepository) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+		DELETE FROM webhook_deliveries
+		WHERE status <> 'pending' AND finished_at < now() - $1::interval
+	`
+	result, err := r.db.Exec(ctx, query, retention)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to purge finished webhook deliveries", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// replacePayloadEmail returns payload with its subscriber address, data.email, replaced by
+// replace. Payloads without an address are returned unchanged.
+func replacePayloadEmail(payload []byte, replace func(string) (string, error)) ([]byte, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(event["data"], &data); err != nil {
+		// Not every event carries an object
+		return payload, nil
+	}
+	var email string
+	if err := json.Unmarshal(data["email"], &email); err != nil || email == "" {
+		return payload, nil
+	}
+
+	replaced, err := replace(email)
+	if err != nil {
+		return nil, err
+	}
+	if data["email"], err = json.Marshal(replaced); err != nil {
+		return nil, err
+	}
+	if event["data"], err = json.Marshal(data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(event)
+}
+
+// openPayloadEmail opens the subscriber address of a scanned payload in place
+func (r *WebhookRepository) openPayloadEmail(payload *map[string]interface{}) error {
+	if payload == nil {
+		return nil
+	}
+	data, ok := (*payload)["data"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	email, ok := data["email"].(string)
+	if !ok {
+		return nil
+	}
+	opened, err := r.emails.Open(email)
+	if err != nil {
+		return err
+	}
+	data["email"] = opened
+	return nil
+}
+
+func scanWebhook(row pgx.Row) (*generated.Webhook, error) {
+	var webhook generated.Webhook
+	var events []string
+	err := row.Scan(
+		&webhook.Id,
+		&webhook.NewsletterId,
+		&webhook.Url,
+		&events,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	subscribed := make([]generated.WebhookEvent, 0, len(events))
+	for _, event := range events {
+		subscribed = append(subscribed, generated.WebhookEvent(event))
+	}
+	webhook.Events = &subscribed
+	return &webhook, nil
+}
+
+// EncryptStoredEmails encrypts the subscriber addresses in the payloads of deliveries stored in
+// plaintext. Returns how many deliveries were updated.
+func (r *WebhookRepository) EncryptStoredEmails(ctx context.Context, batchSize int) (int, error) {
+	if !r.emails.Enabled() {
+		return 0, errors.New("subscriber email encryption is not enabled")
+	}
+
+	var encrypted int
+	err := forEachPlaintext(ctx, r.db, `
+		SELECT id, payload::text FROM webhook_deliveries
+		WHERE id > $1 AND email_hash IS NULL AND payload->'data' ? 'email'
+		ORDER BY id
+		LIMIT $2
+	`, batchSize, func(id uuid.UUID, values []string) error {
+		var email string
+		payload, err := replacePayloadEmail([]byte(values[0]), func(stored string) (string, error) {
+			opened, err := r.emails.Open(stored)
+			if err != nil {
+				return "", err
+			}
+			email = opened
+			return r.emails.Seal(opened)
+		})
+		if err != nil {
+			return err
+		}
+		if email == "" {
+			return nil
+		}
+		_, err = r.db.Exec(ctx, `
+			UPDATE webhook_deliveries SET payload = $2::jsonb, email_hash = $3
+			WHERE id = $1 AND email_hash IS NULL
+		`, id, payload, r.emails.Index(email))
+		if err != nil {
+			return err
+		}
+		encrypted++
+		return nil
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to encrypt webhook payload addresses", "error", err)
+		return encrypted, err
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,92 @@
+// Package sanitize cleans the HTML of posts before it is stored and emailed, and the emails
+// rendered from newsletters' own templates. The policy keeps what email layouts rely on (tables
+// with presentational attributes, inline styles, images and links) and drops scripts, iframes,
+// forms, embedded objects, <style> blocks and event handlers.
+package sanitize
+
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// emailStyles are the CSS properties allowed in style attributes. Properties that can load a
+// URL, such as background-image, are left out so styles cannot fetch trackers.
+var emailStyles = []string{
+	"color", "background-color",
+	"font", "font-family", "font-size", "font-style", "font-weight",
+	"line-height", "letter-spacing", "text-align", "text-decoration", "text-transform",
+	"vertical-align", "white-space",
+	"margin", "margin-top", "margin-right", "margin-bottom", "margin-left",
+	"padding", "padding-top", "padding-right", "padding-bottom", "padding-left",
+	"border", "border-top", "border-right", "border-bottom", "border-left",
+	"border-color", "border-style", "border-width", "border-collapse", "border-radius",
+	"width", "max-width", "min-width", "height", "max-height",
+	"display",
+}
+
+var (
+	color     = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+)$`)
+	dimension = regexp.MustCompile(`^[0-9]+%?$`)
+)
+
+// Policies are built once; bluemonday policies are safe for concurrent use once built
+var (
+	policy         = emailPolicy()
+	documentPolicy = emailDocumentPolicy()
+)
+
+// EmailHTML returns html with everything the email policy does not allow removed
+func EmailHTML(html string) string {
+	return policy.Sanitize(html)
+}
+
+// EmailDocument sanitizes a whole email, such as the output of a newsletter's own template, with
+// the email policy. It also keeps the html, head, body and title elements and named meta tags
+// that wrap an email; the doctype is dropped.
+func EmailDocument(html string) string {
+	return documentPolicy.Sanitize(html)
+}
+
+func emailPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowStandardURLs()
+	// Links in emails are followed by readers, not crawlers
+	p.RequireNoFollowOnLinks(false)
+	p.AllowStandardAttributes()
+	p.AllowStyles(emailStyles...).Globally()
+
+	p.AllowElements(
+		"article", "section", "div", "font", "span", "p", "br", "hr", "center",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"b", "strong", "i", "em", "u", "s", "strike", "del", "ins", "mark", "small",
+		"sub", "sup", "abbr", "cite", "code", "kbd", "pre", "q", "blockquote",
+		"figure", "figcaption", "ul", "ol", "li",
+		"table", "thead", "tbody", "tfoot", "tr", "caption",
+	)
+	p.AllowLists()
+	p.AllowTables()
+	p.AllowImages()
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("target").Matching(regexp.MustCompile(`^_blank$`)).OnElements("a")
+	p.AllowAttrs("bgcolor").Matching(color).OnElements("table", "tr", "td", "th")
+	p.AllowAttrs("border", "cellpadding", "cellspacing").Matching(dimension).OnElements("table")
+	p.AllowAttrs("align").Matching(bluemonday.CellAlign).OnElements("table", "p", "div", "h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("border").Matching(dimension).OnElements("img")
+	p.AllowAttrs("color").Matching(color).OnElements("font")
+	p.AllowAttrs("face").Matching(bluemonday.Paragraph).OnElements("font")
+	p.AllowAttrs("size").Matching(regexp.MustCompile(`^[+-]?[1-7]$`)).OnElements("font")
+
+	return p
+}
+
+func emailDocumentPolicy() *bluemonday.Policy {
+	p := emailPolicy()
+	p.AllowElements("html", "head", "body", "title")
+	// Without http-equiv, meta tags cannot redirect or set cookies
+	p.AllowAttrs("name", "content", "charset").OnElements("meta")
+	p.AllowAttrs("bgcolor").Matching(color).OnElements("body")
+	return p
+}
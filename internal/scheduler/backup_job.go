@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BackupJob periodically writes a snapshot of the critical tables to object storage. With
+// several instances running, only one of them takes each snapshot.
+type BackupJob struct {
+	*periodic
+	backupService *services.BackupService
+	logger        *slog.Logger
+}
+
+// NewBackupJob creates a new instance of BackupJob running every interval
+func NewBackupJob(backupService *services.BackupService, interval time.Duration, logger *slog.Logger) *BackupJob {
+	utils.RequireDependencies("BackupJob",
+		utils.Dep("backupService", backupService),
+		utils.Dep("logger", logger),
+	)
+	j := &BackupJob{
+		backupService: backupService,
+		logger:        logger,
+	}
+	j.periodic = newPeriodic("backup job", interval, j.backup, logger)
+	return j
+}
+
+func (j *BackupJob) backup(runCtx context.Context) {
+	ctx := utils.WithCorrelationID(runCtx, "backup-"+uuid.NewString())
+	result, err := j.backupService.RunScheduled(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Backup run failed", "error", err)
+		return
+	}
+	if result != nil {
+		j.logger.InfoContext(ctx, "Wrote backup snapshot", "location", result.Location, "rows", result.Rows, "bytes", result.Bytes)
+	}
+}
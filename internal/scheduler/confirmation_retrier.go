@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConfirmationRetrier periodically resends subscription confirmation emails that failed to send.
+// Retries a stopped run did not send stay due and are picked up by the next start.
+type ConfirmationRetrier struct {
+	*periodic
+	subscriberService *services.SubscriberService
+	logger            *slog.Logger
+}
+
+// NewConfirmationRetrier creates a new instance of ConfirmationRetrier
+func NewConfirmationRetrier(subscriberService *services.SubscriberService, logger *slog.Logger) *ConfirmationRetrier {
+	utils.RequireDependencies("ConfirmationRetrier",
+		utils.Dep("subscriberService", subscriberService),
+		utils.Dep("logger", logger),
+	)
+	c := &ConfirmationRetrier{
+		subscriberService: subscriberService,
+		logger:            logger,
+	}
+	c.periodic = newPeriodic("confirmation email retrier", time.Minute, c.retry, logger)
+	return c
+}
+
+func (c *ConfirmationRetrier) retry(runCtx context.Context) {
+	ctx := utils.WithCorrelationID(runCtx, "confirmation-retry-"+uuid.NewString())
+	sent, failed, err := c.subscriberService.RetryFailedConfirmations(ctx)
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Confirmation email retry run failed", "error", err)
+		return
+	}
+	if sent+failed > 0 {
+		c.logger.InfoContext(ctx, "Retried confirmation emails", "sent", sent, "failed", failed)
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outboxPurgeInterval is the time between purges of finished emails from the outbox
+const outboxPurgeInterval = time.Hour
+
+// OutboxDispatcher periodically sends the post emails queued in the email outbox. Several
+// instances may run it at once; each claims its own emails. Emails a stopped run did not
+// send are claimed again by the next run.
+type OutboxDispatcher struct {
+	*periodic
+	postService *services.PostService
+	logger      *slog.Logger
+
+	// lastPurge is only touched by runs, which never overlap
+	lastPurge time.Time
+}
+
+// NewOutboxDispatcher creates a new instance of OutboxDispatcher polling every interval
+func NewOutboxDispatcher(postService *services.PostService, interval time.Duration, logger *slog.Logger) *OutboxDispatcher {
+	utils.RequireDependencies("OutboxDispatcher",
+		utils.Dep("postService", postService),
+		utils.Dep("logger", logger),
+	)
+	d := &OutboxDispatcher{
+		postService: postService,
+		logger:      logger,
+	}
+	d.periodic = newPeriodic("outbox dispatcher", interval, d.dispatch, logger)
+	return d
+}
+
+func (d *OutboxDispatcher) dispatch(runCtx context.Context) {
+	ctx := utils.WithCorrelationID(runCtx, "outbox-"+uuid.NewString())
+	sent, failed, err := d.postService.DeliverQueuedEmails(ctx)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "Outbox dispatch run failed", "error", err)
+	}
+	if sent+failed > 0 {
+		d.logger.InfoContext(ctx, "Sent queued emails", "sent", sent, "failed", failed)
+	}
+
+	if time.Since(d.lastPurge) < outboxPurgeInterval {
+		return
+	}
+	d.lastPurge = time.Now()
+	purged, err := d.postService.PurgeFinishedEmails(ctx)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "Failed to purge finished emails from the outbox", "error", err)
+		return
+	}
+	if purged > 0 {
+		d.logger.InfoContext(ctx, "Purged finished emails from the outbox", "count", purged)
+	}
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// periodic calls run every interval in the background until stopped. It carries the start and
+// stop handling shared by the background jobs.
+type periodic struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context)
+	logger   *slog.Logger
+
+	// runCtx is the parent of every run; cancelling it aborts an in-flight run
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	shutdownCh chan struct{}
+	done       sync.WaitGroup
+	stopOnce   sync.Once
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+}
+
+func newPeriodic(name string, interval time.Duration, run func(ctx context.Context), logger *slog.Logger) *periodic {
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	return &periodic{
+		name:       name,
+		interval:   interval,
+		run:        run,
+		logger:     logger,
+		runCtx:     runCtx,
+		cancelRuns: cancelRuns,
+		shutdownCh: make(chan struct{}),
+	}
+}
+
+// Start begins the runs in the background; a non-positive interval disables them
+func (p *periodic) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started || p.stopped {
+		return
+	}
+	if p.interval <= 0 {
+		p.logger.Info("Not starting "+p.name+", its interval is not positive", "interval", p.interval)
+		return
+	}
+	p.logger.Info("Starting "+p.name, "interval", p.interval)
+	p.started = true
+	p.done.Add(1)
+	go func() {
+		defer p.done.Done()
+		p.loop()
+	}()
+}
+
+// Stop ends the run loop. A run in progress may finish until ctx is done, after which it is
+// cancelled; the work it did not reach is picked up by the next start.
+func (p *periodic) Stop(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		p.stopped = true
+		p.mu.Unlock()
+		close(p.shutdownCh)
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		p.done.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancelRuns()
+		return nil
+	case <-ctx.Done():
+		p.cancelRuns()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+func (p *periodic) loop() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.run(p.runCtx)
+		case <-p.shutdownCh:
+			p.logger.Info("Stopped " + p.name)
+			return
+		}
+	}
+}
@@ -2,39 +2,138 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
 	"go-newsletter/internal/services"
+	"go-newsletter/internal/tracing"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
 	"log/slog"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statsWindow is how far back the status endpoint reports run statistics
+const statsWindow = 24 * time.Hour
+
+var (
+	publisherRunsTotal  = metrics.NewCounterVec("scheduler_runs_total", "Scheduler runs by outcome: completed, lock_busy (another instance ran) or error.", "outcome")
+	postsPublishedTotal = metrics.NewCounter("scheduler_posts_published_total", "Scheduled posts the scheduler published.")
+	postsFailedTotal    = metrics.NewCounter("scheduler_posts_failed_total", "Scheduled posts the scheduler failed to publish; they are retried on the next run.")
+	postsSkippedTotal   = metrics.NewCounter("scheduler_posts_skipped_total", "Overdue posts the scheduler skipped because of the newsletter's catch-up policy.")
+	lastCompletedRun    = metrics.NewGauge("scheduler_last_completed_run_timestamp_seconds", "Unix time the last scheduler run of this instance that held the lock finished.")
 )
 
 // PostPublisher is a service for automatically publishing scheduled posts
 type PostPublisher struct {
-	postService *services.PostService
-	interval    time.Duration
-	shutdownCh  chan struct{}
-	logger      *slog.Logger
+	postService   *services.PostService
+	schedulerRepo *repository.SchedulerRepository
+	interval      time.Duration
+	shutdownCh    chan struct{}
+	logger        *slog.Logger
+
+	// runCtx is the parent of every run; cancelling it aborts in-flight publishes
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	// inFlight tracks the publisher loop and manual runs so Stop can drain them
+	inFlight sync.WaitGroup
+	stopOnce sync.Once
+
+	// runMu serialises runs within this instance; the advisory lock does so across instances
+	runMu sync.Mutex
+
+	mu         sync.Mutex
+	started    bool
+	stopped    bool
+	inProgress bool
+	lastRun    *RunResult
+	nextRun    time.Time
+	recentRuns []RunResult
+}
+
+// RunResult describes a single publishing run
+type RunResult struct {
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	LockAcquired bool
+	Published    int
+	Failed       int
+	Skipped      int
 }
 
 // NewPostPublisher creates a new instance of PostPublisher
-func NewPostPublisher(postService *services.PostService, logger *slog.Logger) *PostPublisher {
+func NewPostPublisher(postService *services.PostService, schedulerRepo *repository.SchedulerRepository, logger *slog.Logger) *PostPublisher {
+	utils.RequireDependencies("PostPublisher",
+		utils.Dep("postService", postService),
+		utils.Dep("schedulerRepo", schedulerRepo),
+		utils.Dep("logger", logger),
+	)
+	runCtx, cancelRuns := context.WithCancel(context.Background())
 	return &PostPublisher{
-		postService: postService,
-		interval:    time.Minute, // Check every minute
-		shutdownCh:  make(chan struct{}),
-		logger:      logger,
+		postService:   postService,
+		schedulerRepo: schedulerRepo,
+		interval:      time.Minute, // Check every minute
+		shutdownCh:    make(chan struct{}),
+		runCtx:        runCtx,
+		cancelRuns:    cancelRuns,
+		logger:        logger,
 	}
 }
 
 // Start begins the background publishing process
 func (p *PostPublisher) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started || p.stopped {
+		return
+	}
 	p.logger.Info("Starting scheduled post publisher service")
-	go p.run()
+	p.started = true
+	p.inFlight.Add(1)
+	go func() {
+		defer p.inFlight.Done()
+		p.run()
+	}()
 }
 
-// Stop terminates the publishing process
-func (p *PostPublisher) Stop() {
-	p.logger.Info("Stopping scheduled post publisher service")
-	close(p.shutdownCh)
+// Stop terminates the publishing process. No new runs start after it is called; a run
+// already in progress is allowed to finish until ctx is done, after which it is cancelled
+// and its unsent emails are reported as failed. Stop returns ctx.Err() if the grace
+// period was exceeded.
+func (p *PostPublisher) Stop(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		p.logger.Info("Stopping scheduled post publisher service")
+		p.mu.Lock()
+		p.started = false
+		p.stopped = true
+		p.nextRun = time.Time{}
+		p.mu.Unlock()
+		close(p.shutdownCh)
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		p.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancelRuns()
+		p.logger.Info("Scheduled post publisher drained")
+		return nil
+	case <-ctx.Done():
+		p.logger.Warn("Shutdown grace period exceeded, cancelling in-flight publish")
+		p.cancelRuns()
+		<-drained
+		return ctx.Err()
+	}
 }
 
 // run is the main loop for checking and publishing scheduled posts
@@ -43,11 +142,13 @@ func (p *PostPublisher) run() {
 	defer ticker.Stop()
 
 	// Check immediately upon starting
+	p.setNextRun(time.Now().Add(p.interval))
 	p.publishScheduledPosts()
 
 	for {
 		select {
 		case <-ticker.C:
+			p.setNextRun(time.Now().Add(p.interval))
 			p.publishScheduledPosts()
 		case <-p.shutdownCh:
 			p.logger.Info("Scheduled post publisher service stopped")
@@ -56,10 +157,177 @@ func (p *PostPublisher) run() {
 	}
 }
 
-// publishScheduledPosts finds and publishes all posts whose publication time has arrived
+// RunNow runs the publisher immediately and waits for it to finish.
+// It fails with a conflict if a run is already in progress on this instance.
+func (p *PostPublisher) RunNow(ctx context.Context) (RunResult, error) {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return RunResult{}, models.NewConflictError("The scheduler is shutting down")
+	}
+	p.inFlight.Add(1)
+	p.mu.Unlock()
+	defer p.inFlight.Done()
+
+	if !p.runMu.TryLock() {
+		return RunResult{}, models.NewConflictError("A scheduler run is already in progress")
+	}
+	defer p.runMu.Unlock()
+
+	// Detach from the caller so a dropped admin connection cannot abort a run halfway,
+	// but still honour a forced shutdown
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	defer context.AfterFunc(p.runCtx, cancel)()
+
+	p.logger.InfoContext(ctx, "Manual scheduler run triggered")
+	return p.runLocked(ctx), nil
+}
+
+// Status reports the publisher state of this instance together with the cluster wide
+// lock holder and the number of posts waiting to be published
+func (p *PostPublisher) Status(ctx context.Context) (*generated.SchedulerStatus, error) {
+	queueDepth, err := p.postService.CountPostsDueForPublication(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	lockHolder, err := p.schedulerRepo.LockHolder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneRecentRuns(time.Now())
+	published, failed, skipped := 0, 0, 0
+	for _, r := range p.recentRuns {
+		published += r.Published
+		failed += r.Failed
+		skipped += r.Skipped
+	}
+
+	instanceID := utils.InstanceID()
+	intervalSeconds := int(p.interval.Seconds())
+	running, inProgress := p.started, p.inProgress
+	status := &generated.SchedulerStatus{
+		InstanceId:       &instanceID,
+		Running:          &running,
+		InProgress:       &inProgress,
+		IntervalSeconds:  &intervalSeconds,
+		PublishedLast24h: &published,
+		FailedLast24h:    &failed,
+		SkippedLast24h:   &skipped,
+		LockHolder:       lockHolder,
+		QueueDepth:       &queueDepth,
+	}
+	if p.lastRun != nil {
+		lastRunAt := p.lastRun.StartedAt
+		duration := p.lastRun.FinishedAt.Sub(p.lastRun.StartedAt).Milliseconds()
+		status.LastRunAt = &lastRunAt
+		status.LastRunDurationMs = &duration
+	}
+	if p.started && !p.nextRun.IsZero() {
+		nextRun := p.nextRun
+		status.NextRunAt = &nextRun
+	}
+	return status, nil
+}
+
+func (p *PostPublisher) setNextRun(t time.Time) {
+	p.mu.Lock()
+	p.nextRun = t
+	p.mu.Unlock()
+}
+
+func (p *PostPublisher) pruneRecentRuns(now time.Time) {
+	cutoff := now.Add(-statsWindow)
+	i := 0
+	for i < len(p.recentRuns) && p.recentRuns[i].StartedAt.Before(cutoff) {
+		i++
+	}
+	p.recentRuns = p.recentRuns[i:]
+}
+
+// publishScheduledPosts runs a periodic publish unless a run is already in progress on this instance
 func (p *PostPublisher) publishScheduledPosts() {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if !p.runMu.TryLock() {
+		p.logger.Info("Previous scheduler run still in progress, skipping tick")
+		return
+	}
+	defer p.runMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(p.runCtx, 30*time.Second)
 	defer cancel()
+	p.runLocked(ctx)
+}
+
+// runLocked takes the cluster wide scheduler lock, publishes due posts and records the result.
+// The caller must hold runMu.
+func (p *PostPublisher) runLocked(ctx context.Context) RunResult {
+	result := RunResult{StartedAt: time.Now().UTC()}
+
+	// Manual runs keep the correlation ID of the API request that triggered them
+	if utils.CorrelationID(ctx) == "" {
+		ctx = utils.WithCorrelationID(ctx, "scheduler-"+uuid.NewString())
+	}
+	p.logger.InfoContext(ctx, "Scheduler run started", "correlation_id", utils.CorrelationID(ctx))
+	ctx, span := tracing.Tracer().Start(ctx, "scheduler.run")
+	defer func() {
+		span.SetAttributes(
+			attribute.Bool("scheduler.lock_acquired", result.LockAcquired),
+			attribute.Int("scheduler.published", result.Published),
+			attribute.Int("scheduler.failed", result.Failed),
+			attribute.Int("scheduler.skipped", result.Skipped),
+		)
+		span.End()
+	}()
+
+	p.mu.Lock()
+	p.inProgress = true
+	p.mu.Unlock()
+	defer func() {
+		result.FinishedAt = time.Now().UTC()
+		p.mu.Lock()
+		p.inProgress = false
+		p.lastRun = &result
+		if result.LockAcquired {
+			p.recentRuns = append(p.recentRuns, result)
+		}
+		p.pruneRecentRuns(result.FinishedAt)
+		p.mu.Unlock()
+	}()
+
+	unlock, acquired, err := p.schedulerRepo.TryLock(ctx)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "Error taking scheduler lock", "error", err)
+		publisherRunsTotal.Inc("error")
+		return result
+	}
+	if !acquired {
+		p.logger.InfoContext(ctx, "Scheduler lock held by another instance, skipping run")
+		publisherRunsTotal.Inc("lock_busy")
+		return result
+	}
+	defer unlock()
+	result.LockAcquired = true
+
+	result.Published, result.Failed, result.Skipped, err = p.publishDuePosts(ctx)
+	postsPublishedTotal.Add(float64(result.Published))
+	postsFailedTotal.Add(float64(result.Failed))
+	postsSkippedTotal.Add(float64(result.Skipped))
+	if err != nil {
+		publisherRunsTotal.Inc("error")
+		return result
+	}
+	publisherRunsTotal.Inc("completed")
+	lastCompletedRun.Set(float64(time.Now().Unix()))
+	return result
+}
+
+// publishDuePosts finds and publishes all posts whose publication time has arrived. The error
+// is that of finding them; posts that fail to publish are counted instead.
+func (p *PostPublisher) publishDuePosts(ctx context.Context) (published, failed, skippedCount int, err error) {
 	p.logger.InfoContext(ctx, "Checking for scheduled posts to publish")
 
 	now := time.Now().UTC()
@@ -69,16 +337,31 @@ func (p *PostPublisher) publishScheduledPosts() {
 	posts, err := p.postService.GetPostsDueForPublication(ctx, now)
 	if err != nil {
 		p.logger.ErrorContext(ctx, "Error fetching scheduled posts", "error", err)
-		return
+		return 0, 0, 0, err
 	}
 
 	if len(posts) == 0 {
 		p.logger.InfoContext(ctx, "No posts scheduled for publication at this time")
-		return
+		return 0, 0, 0, nil
 	}
 
 	p.logger.InfoContext(ctx, "Found posts to publish", "count", len(posts))
 
+	// Apply the newsletters' catch-up policies to posts left over from downtime
+	posts, skipped := p.postService.ApplyCatchUpPolicy(ctx, posts, now)
+	for _, post := range skipped {
+		if err := p.postService.SkipPost(ctx, *post.Id); err != nil {
+			p.logger.ErrorContext(ctx, "Error skipping overdue post", "postId", post.Id, "error", err)
+			continue
+		}
+		skippedCount++
+		p.logger.InfoContext(ctx, "Skipped overdue post due to catch-up policy",
+			"postId", post.Id,
+			"newsletterId", post.NewsletterId,
+			"title", post.Title,
+			"scheduledAt", post.ScheduledAt.Format(time.RFC3339))
+	}
+
 	// Publish each post
 	successCount := 0
 	failureCount := 0
@@ -94,7 +377,15 @@ func (p *PostPublisher) publishScheduledPosts() {
 			"title", post.Title,
 			"scheduledAt", post.ScheduledAt.Format(time.RFC3339))
 
-		err := p.postService.PublishPost(ctx, *post.Id)
+		postCtx, span := tracing.Tracer().Start(ctx, "scheduler.publish_post", trace.WithAttributes(attribute.String("post.id", post.Id.String())))
+		err := p.postService.PublishPost(postCtx, *post.Id)
+		if errors.Is(err, repository.ErrPostNotScheduled) {
+			span.End()
+			// Published, skipped or being published by someone else since it was found due
+			p.logger.InfoContext(ctx, "Post no longer due, leaving it", "postId", post.Id)
+			continue
+		}
+		tracing.End(span, err)
 		if err != nil {
 			p.logger.ErrorContext(ctx, "Error publishing post", "postId", post.Id, "error", err)
 			failureCount++
@@ -105,5 +396,6 @@ func (p *PostPublisher) publishScheduledPosts() {
 		p.logger.InfoContext(ctx, "Post published successfully", "postId", post.Id, "title", post.Title)
 	}
 
-	p.logger.InfoContext(ctx, "Post publishing completed", "successCount", successCount, "failureCount", failureCount)
+	p.logger.InfoContext(ctx, "Post publishing completed", "successCount", successCount, "failureCount", failureCount, "skippedCount", skippedCount)
+	return successCount, failureCount, skippedCount, nil
 }
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionJob periodically deletes or anonymizes subscribers who never confirmed once they are
+// past their newsletter's retention or their confirmation link expired, and deletes deleted
+// newsletters, posts and subscribers past their restore window, old inbox notifications and
+// expired integration access tokens. Rows a stopped run did not reach are purged by the next one.
+type RetentionJob struct {
+	*periodic
+	retentionService *services.RetentionService
+	inboxService     *services.InboxService
+	oauthService     *services.OAuthService
+	logger           *slog.Logger
+}
+
+// NewRetentionJob creates a new instance of RetentionJob running every interval
+func NewRetentionJob(retentionService *services.RetentionService, inboxService *services.InboxService, oauthService *services.OAuthService, interval time.Duration, logger *slog.Logger) *RetentionJob {
+	utils.RequireDependencies("RetentionJob",
+		utils.Dep("retentionService", retentionService),
+		utils.Dep("inboxService", inboxService),
+		utils.Dep("oauthService", oauthService),
+		utils.Dep("logger", logger),
+	)
+	j := &RetentionJob{
+		retentionService: retentionService,
+		inboxService:     inboxService,
+		oauthService:     oauthService,
+		logger:           logger,
+	}
+	j.periodic = newPeriodic("retention job", interval, j.purge, logger)
+	return j
+}
+
+func (j *RetentionJob) purge(runCtx context.Context) {
+	ctx := utils.WithCorrelationID(runCtx, "retention-"+uuid.NewString())
+	deleted, err := j.retentionService.PurgeUnconfirmed(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Retention run failed", "purged", deleted, "error", err)
+		return
+	}
+	if deleted > 0 {
+		j.logger.InfoContext(ctx, "Purged unconfirmed subscribers past their retention", "action", j.retentionService.UnconfirmedAction(), "purged", deleted)
+	}
+
+	deleted, err = j.retentionService.PurgeExpiredConfirmations(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Failed to purge subscribers with expired confirmation links", "purged", deleted, "error", err)
+		return
+	}
+	if deleted > 0 {
+		j.logger.InfoContext(ctx, "Purged unconfirmed subscribers whose confirmation link expired", "action", j.retentionService.UnconfirmedAction(), "purged", deleted)
+	}
+
+	newsletters, posts, subscribers, err := j.retentionService.PurgeDeleted(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Failed to delete deleted newsletters, posts and subscribers for good", "newsletters", newsletters, "posts", posts, "subscribers", subscribers, "error", err)
+		return
+	}
+	if newsletters > 0 || posts > 0 || subscribers > 0 {
+		j.logger.InfoContext(ctx, "Deleted newsletters, posts and subscribers past their restore window for good", "newsletters", newsletters, "posts", posts, "subscribers", subscribers)
+	}
+
+	deleted, err = j.inboxService.PurgeOld(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Failed to delete old inbox notifications", "deleted", deleted, "error", err)
+		return
+	}
+	if deleted > 0 {
+		j.logger.InfoContext(ctx, "Deleted old inbox notifications", "deleted", deleted)
+	}
+
+	deleted, err = j.oauthService.PurgeExpiredTokens(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Failed to delete expired access tokens", "deleted", deleted, "error", err)
+		return
+	}
+	if deleted > 0 {
+		j.logger.InfoContext(ctx, "Deleted expired access tokens", "deleted", deleted)
+	}
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"context"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// webhookPurgeInterval is the time between purges of finished deliveries from the delivery log
+const webhookPurgeInterval = time.Hour
+
+// WebhookDispatcher periodically calls the webhooks of queued newsletter events. Like the
+// outbox dispatcher, several instances may run it at once.
+type WebhookDispatcher struct {
+	*periodic
+	webhookService *services.WebhookService
+	logger         *slog.Logger
+
+	// lastPurge is only touched by runs, which never overlap
+	lastPurge time.Time
+}
+
+// NewWebhookDispatcher creates a new instance of WebhookDispatcher polling every interval
+func NewWebhookDispatcher(webhookService *services.WebhookService, interval time.Duration, logger *slog.Logger) *WebhookDispatcher {
+	utils.RequireDependencies("WebhookDispatcher",
+		utils.Dep("webhookService", webhookService),
+		utils.Dep("logger", logger),
+	)
+	d := &WebhookDispatcher{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+	d.periodic = newPeriodic("webhook dispatcher", interval, d.dispatch, logger)
+	return d
+}
+
+func (d *WebhookDispatcher) dispatch(runCtx context.Context) {
+	ctx := utils.WithCorrelationID(runCtx, "webhooks-"+uuid.NewString())
+	delivered, failed, err := d.webhookService.DeliverQueued(ctx)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "Webhook dispatch run failed", "error", err)
+	}
+	if delivered+failed > 0 {
+		d.logger.InfoContext(ctx, "Called webhooks", "delivered", delivered, "failed", failed)
+	}
+
+	if time.Since(d.lastPurge) < webhookPurgeInterval {
+		return
+	}
+	d.lastPurge = time.Now()
+	purged, err := d.webhookService.PurgeFinishedDeliveries(ctx)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "Failed to purge finished webhook deliveries", "error", err)
+		return
+	}
+	if purged > 0 {
+		d.logger.InfoContext(ctx, "Purged finished webhook deliveries", "count", purged)
+	}
+}
@@ -0,0 +1,414 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-newsletter/internal/alerting"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/middleware"
+	"go-newsletter/internal/services"
+	"go-newsletter/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// NewRouter wires the middleware stack and mounts every API route under /api/v1
+// When readOnly is set, requests that would write to the database are answered with 503;
+// API writes are also refused while an admin has switched on read-only mode.
+func NewRouter(logger *slog.Logger, apiServer *Server, assetHandler http.Handler, cfg *config.Config, notifier alerting.Notifier, usage *services.UsageService, readOnlyService *services.ReadOnlyService, readOnly bool) (chi.Router, error) {
+	trustedProxies, err := utils.ParseCIDRs(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	r := chi.NewRouter()
+
+	// Middleware
+	r.Use(chimiddleware.RequestID)
+	r.Use(middleware.RequestIDPropagation)
+	r.Use(middleware.Tracing)
+	r.Use(middleware.TrustedRealIP(trustedProxies))
+	r.Use(middleware.SecurityHeadersMiddleware(apiSecurityHeaders(cfg.Security)))
+	r.Use(SlogMiddleware(logger))
+	r.Use(middleware.RequestMetrics)
+	r.Use(middleware.Recoverer(logger, notifier))
+
+	// Health check route
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler())
+
+	// Stylesheets of hosted pages and uploaded images, outside the API so pages on custom
+	// domains reach them at the same path
+	r.Method(http.MethodGet, "/assets/*", assetHandler)
+	r.Method(http.MethodHead, "/assets/*", assetHandler)
+
+	// Create API router with auth middleware
+	apiRouter := chi.NewRouter()
+	authMiddleware := middleware.NewAuthMiddleware(apiServer.GetAuthService(), apiServer.GetAPIKeyService(), apiServer.GetOAuthService(), apiServer.GetProfileService(), logger)
+	usageTracking := middleware.UsageTracking(usage)
+	// The schema guard is fixed at startup. The incident flag only covers API writes: links
+	// opened from emails (confirm, unsubscribe) keep working, and admins can switch it off.
+	var schemaReadOnly func(ctx context.Context) bool
+	if readOnly {
+		schemaReadOnly = func(ctx context.Context) bool { return true }
+	}
+	readOnlyWrites := middleware.ReadOnly(func(ctx context.Context) bool {
+		return readOnly || readOnlyService.Enabled(ctx)
+	}, false)
+	// Heavy routes share a concurrency limit per group, so they cannot starve the pool
+	publishLimit := middleware.ConcurrencyLimit("publish", cfg.Server.PublishConcurrency)
+	bulkLimit := middleware.ConcurrencyLimit("bulk", cfg.Server.BulkConcurrency)
+	apiKeyScopes := middleware.APIKeyScopes(apiRouter, apiKeyScopeGrants)
+	// Sign-ins record where they came from in the editor's security events
+	clientInfo := middleware.ClientInfo(cfg.Security.LocationHeader)
+
+	// Public routes (no auth required)
+	apiRouter.Group(func(r chi.Router) {
+		// Auth
+		r.Post("/auth/signup", apiServer.PostAuthSignup)
+		r.Post("/auth/signin", apiServer.PostAuthSignin)
+		r.Post("/auth/password-reset-request", apiServer.PostAuthPasswordResetRequest)
+		r.Post("/auth/password-reset", apiServer.PostAuthPasswordResetRequest) // legacy path, kept for existing clients
+		r.Post("/auth/magic-link", apiServer.PostAuthMagicLink)
+		r.With(clientInfo).Post("/auth/magic-link/verify", apiServer.PostAuthMagicLinkVerify)
+		r.Post("/auth/refresh", apiServer.PostAuthRefresh)
+		// Sign-in with an identity provider through Supabase, opened in the browser
+		r.Get("/auth/oauth/{provider}/start", apiServer.GetAuthOauthProviderStart)
+		r.With(clientInfo).Get("/auth/oauth/{provider}/callback", apiServer.GetAuthOauthProviderCallback)
+		r.With(readOnlyWrites, clientInfo).Post("/auth/oauth/link", apiServer.PostAuthOauthLink)
+
+		// Newsletter Subscription
+		r.Route("/newsletters/{newsletterId}/subscribe", func(r chi.Router) {
+			r.Use(middleware.UUIDParamValidationMiddleware("newsletterId"))
+			r.Use(readOnlyWrites)
+			r.Post("/", apiServer.PostNewslettersNewsletterIdSubscribe)
+			r.Post("/resend-confirmation", apiServer.PostNewslettersNewsletterIdSubscribeResendConfirmation)
+		})
+
+		// Public archive of published posts, its feed and the subscriber count badge
+		r.Route("/public/newsletters/{newsletterId}", func(r chi.Router) {
+			r.Use(middleware.UUIDParamValidationMiddleware("newsletterId"))
+			r.Get("/", apiServer.GetPublicNewslettersNewsletterId)
+			r.Get("/posts", apiServer.GetPublicNewslettersNewsletterIdPosts)
+			r.With(middleware.UUIDParamValidationMiddleware("postId")).Get("/posts/{postId}", apiServer.GetPublicNewslettersNewsletterIdPostsPostId)
+			r.Get("/badge.svg", apiServer.GetPublicNewslettersNewsletterIdBadgeSvg)
+			r.Get("/feed.xml", apiServer.GetPublicNewslettersNewsletterIdFeedXml)
+			r.Get("/sitemap.xml", apiServer.GetPublicNewslettersNewsletterIdSitemapXml)
+		})
+
+		// Data requests of subscribers, verified by a token emailed to the address
+		r.Post("/gdpr/subscribers/verification", apiServer.PostGdprSubscribersVerification)
+		r.Post("/gdpr/subscribers/export", apiServer.PostGdprSubscribersExport)
+		r.With(readOnlyWrites).Delete("/gdpr/subscribers", apiServer.DeleteGdprSubscribers)
+
+		// Subscribers manage their subscription with the unsubscribe token from any post
+		r.With(readOnlyWrites).Post("/subscriptions/{unsubscribeToken}/email-change", func(w http.ResponseWriter, r *http.Request) {
+			apiServer.PostSubscriptionsUnsubscribeTokenEmailChange(w, r, chi.URLParam(r, "unsubscribeToken"))
+		})
+		// One-click unsubscribe from the List-Unsubscribe header of post emails. Mail clients
+		// post without cookies, so it sits outside the CSRF group; the token is the credential.
+		// Like the unsubscribe link it only stops for an incompatible schema.
+		r.With(middleware.ReadOnly(schemaReadOnly, true)).Post("/unsubscribe/{unsubscribeToken}", func(w http.ResponseWriter, r *http.Request) {
+			apiServer.PostUnsubscribeUnsubscribeToken(w, r, chi.URLParam(r, "unsubscribeToken"))
+		})
+		// Open pixel and click redirects of post emails. Like the unsubscribe link they skip the
+		// read-only guards: links in sent emails keep working, and events that cannot be stored
+		// are dropped.
+		r.With(middleware.UUIDParamValidationMiddleware("messageId")).Get("/t/open/{messageId}", apiServer.GetTOpenMessageId)
+		r.With(middleware.UUIDParamValidationMiddleware("messageId")).Get("/t/click/{messageId}", apiServer.GetTClickMessageId)
+		// Bounce and complaint events from Resend, authenticated by their signature. In read-only
+		// mode they are rejected with 503, which Resend retries later.
+		r.With(readOnlyWrites).Post("/webhooks/resend", apiServer.PostWebhooksResend)
+
+		// OAuth2 client credentials flow of integration clients, authenticated by client secret
+		r.With(readOnlyWrites).Post("/oauth/token", apiServer.PostOauthToken)
+		r.Post("/oauth/introspect", apiServer.PostOauthIntrospect)
+	})
+
+	// Pages opened from email links in a browser. Hosted forms posting back here are
+	// protected by CSRF tokens; JSON API routes above and below use bearer tokens instead.
+	csrf := middleware.NewCSRF(cfg.Security.CSRFSecret, cfg.Security.CSRFSecureCookie)
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(middleware.SecurityHeadersMiddleware(htmlSecurityHeaders(cfg.Security)))
+		r.Use(csrf.Protect)
+		r.Use(middleware.ReadOnly(schemaReadOnly, true))
+
+		r.Route("/subscribe/confirm/{confirmationToken}", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				token := chi.URLParam(r, "confirmationToken")
+				apiServer.GetSubscribeConfirmConfirmationToken(w, r, token)
+			})
+		})
+		// Not a subrouter, so the one-click POST on the same path can skip the CSRF check
+		r.Get("/unsubscribe/{unsubscribeToken}", func(w http.ResponseWriter, r *http.Request) {
+			token := chi.URLParam(r, "unsubscribeToken")
+			apiServer.GetUnsubscribeUnsubscribeToken(w, r, token)
+		})
+		r.Route("/unsubscribe-all/{token}", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				token := chi.URLParam(r, "token")
+				apiServer.GetUnsubscribeAllToken(w, r, token)
+			})
+		})
+		r.Route("/subscriptions/email-change/confirm/{changeToken}", func(r chi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				token := chi.URLParam(r, "changeToken")
+				apiServer.GetSubscriptionsEmailChangeConfirmChangeToken(w, r, token)
+			})
+		})
+	})
+
+	// Protected routes (require authentication, any editor)
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(authMiddleware.RequireAuth)
+		r.Use(apiKeyScopes)
+		r.Use(usageTracking)
+		r.Use(readOnlyWrites)
+
+		// Profile management
+		r.Get("/me", apiServer.GetMe)
+		r.Put("/me", apiServer.PutMe)
+		r.Get("/me/usage", apiServer.GetMeUsage)
+		r.Get("/me/costs", apiServer.GetMeCosts)
+		r.Get("/me/plan", apiServer.GetMePlan)
+		r.Get("/me/onboarding", apiServer.GetMeOnboarding)
+		r.Get("/me/security-events", apiServer.GetMeSecurityEvents)
+		r.Post("/me/invitations/accept", apiServer.PostMeInvitationsAccept)
+		r.Post("/me/coupons/redeem", apiServer.PostMeCouponsRedeem)
+		r.Get("/me/api-keys", apiServer.GetMeApiKeys)
+		r.Post("/me/api-keys", apiServer.PostMeApiKeys)
+		r.With(middleware.UUIDParamValidationMiddleware("apiKeyId")).Delete("/me/api-keys/{apiKeyId}", apiServer.DeleteMeApiKeysApiKeyId)
+		r.Get("/me/integration-clients", apiServer.GetMeIntegrationClients)
+		r.Post("/me/integration-clients", apiServer.PostMeIntegrationClients)
+		r.With(middleware.UUIDParamValidationMiddleware("integrationClientId")).Delete("/me/integration-clients/{integrationClientId}", apiServer.DeleteMeIntegrationClientsIntegrationClientId)
+		r.Get("/me/notifications", apiServer.GetMeNotifications)
+		r.Get("/me/notifications/unread-count", apiServer.GetMeNotificationsUnreadCount)
+		r.Post("/me/notifications/read-all", apiServer.PostMeNotificationsReadAll)
+		r.With(middleware.UUIDParamValidationMiddleware("notificationId")).Post("/me/notifications/{notificationId}/read", apiServer.PostMeNotificationsNotificationIdRead)
+
+		// Newsletter management (owners and members)
+		r.Get("/newsletters", apiServer.GetNewsletters)
+		r.Post("/newsletters", apiServer.PostNewsletters)
+
+		r.Route("/newsletters/{newsletterId}", func(r chi.Router) {
+			r.Use(middleware.UUIDParamValidationMiddleware("newsletterId"))
+			r.Get("/", apiServer.GetNewslettersNewsletterId)
+			r.Put("/", apiServer.PutNewslettersNewsletterId)
+			r.Delete("/", apiServer.DeleteNewslettersNewsletterId)
+			r.With(bulkLimit).Get("/config-bundle", apiServer.GetNewslettersNewsletterIdConfigBundle)
+			r.With(bulkLimit).Put("/config-bundle", apiServer.PutNewslettersNewsletterIdConfigBundle)
+
+			// Subscriber management
+			r.With(bulkLimit).Get("/subscribers", apiServer.GetNewslettersNewsletterIdSubscribers)
+			r.With(bulkLimit).Get("/subscribers/export", apiServer.GetNewslettersNewsletterIdSubscribersExport)
+			r.With(bulkLimit).Post("/subscribers/resend-confirmations", apiServer.PostNewslettersNewsletterIdSubscribersResendConfirmations)
+			r.Post("/subscribers/merge", apiServer.PostNewslettersNewsletterIdSubscribersMerge)
+			r.Get("/subscribers/deleted", apiServer.GetNewslettersNewsletterIdSubscribersDeleted)
+			r.With(middleware.UUIDParamValidationMiddleware("subscriberId")).Delete("/subscribers/{subscriberId}", apiServer.DeleteNewslettersNewsletterIdSubscribersSubscriberId)
+			r.With(middleware.UUIDParamValidationMiddleware("subscriberId")).Post("/subscribers/{subscriberId}/restore", apiServer.PostNewslettersNewsletterIdSubscribersSubscriberIdRestore)
+			r.Get("/costs", apiServer.GetNewslettersNewsletterIdCosts)
+			r.Get("/stats", apiServer.GetNewslettersNewsletterIdStats)
+			r.Post("/sample-content", apiServer.PostNewslettersNewsletterIdSampleContent)
+
+			// Suppression list: blocked, bounced and complaining addresses
+			r.Get("/suppressions", apiServer.GetNewslettersNewsletterIdSuppressions)
+			r.Post("/suppressions", apiServer.PostNewslettersNewsletterIdSuppressions)
+			r.With(middleware.UUIDParamValidationMiddleware("suppressionId")).Delete("/suppressions/{suppressionId}", apiServer.DeleteNewslettersNewsletterIdSuppressionsSuppressionId)
+
+			// Members sharing the newsletter and their invitations
+			r.Get("/members", apiServer.GetNewslettersNewsletterIdMembers)
+			r.Post("/members", apiServer.PostNewslettersNewsletterIdMembers)
+			r.With(middleware.UUIDParamValidationMiddleware("editorId")).Delete("/members/{editorId}", apiServer.DeleteNewslettersNewsletterIdMembersEditorId)
+
+			// Webhooks of newsletter events
+			r.Get("/webhooks", apiServer.GetNewslettersNewsletterIdWebhooks)
+			r.Post("/webhooks", apiServer.PostNewslettersNewsletterIdWebhooks)
+			r.With(middleware.UUIDParamValidationMiddleware("webhookId")).Delete("/webhooks/{webhookId}", apiServer.DeleteNewslettersNewsletterIdWebhooksWebhookId)
+			r.With(middleware.UUIDParamValidationMiddleware("webhookId")).Get("/webhooks/{webhookId}/deliveries", apiServer.GetNewslettersNewsletterIdWebhooksWebhookIdDeliveries)
+
+			// Email templates replacing the default ones
+			r.Get("/email-templates", apiServer.GetNewslettersNewsletterIdEmailTemplates)
+			r.Get("/email-templates/{kind}", apiServer.GetNewslettersNewsletterIdEmailTemplatesKind)
+			r.Put("/email-templates/{kind}", apiServer.PutNewslettersNewsletterIdEmailTemplatesKind)
+			r.Delete("/email-templates/{kind}", apiServer.DeleteNewslettersNewsletterIdEmailTemplatesKind)
+			r.Get("/template/variables", apiServer.GetNewslettersNewsletterIdTemplateVariables)
+
+			// Post management (editor-owned)
+			r.Route("/posts", func(r chi.Router) {
+				r.Get("/", apiServer.GetNewslettersNewsletterIdPosts)
+				r.With(publishLimit).Post("/", apiServer.PostNewslettersNewsletterIdPosts)
+				r.With(bulkLimit).Post("/import", apiServer.PostNewslettersNewsletterIdPostsImport)
+				r.With(middleware.UUIDParamValidationMiddleware("postId")).Get("/{postId}/delivery", apiServer.GetNewslettersNewsletterIdPostsPostIdDelivery)
+				r.With(middleware.UUIDParamValidationMiddleware("postId")).Get("/{postId}/delivery-report", apiServer.GetNewslettersNewsletterIdPostsPostIdDeliveryReport)
+				r.With(middleware.UUIDParamValidationMiddleware("postId")).Get("/{postId}/stats", apiServer.GetNewslettersNewsletterIdPostsPostIdStats)
+				r.With(middleware.UUIDParamValidationMiddleware("postId")).Get("/{postId}/preflight", apiServer.GetNewslettersNewsletterIdPostsPostIdPreflight)
+				r.With(middleware.UUIDParamValidationMiddleware("postId")).Post("/{postId}/suggestions", apiServer.PostNewslettersNewsletterIdPostsPostIdSuggestions)
+			})
+
+			// Scheduled Post management (editor-owned)
+			r.Route("/scheduled-posts", func(r chi.Router) {
+				r.Get("/", apiServer.GetNewslettersNewsletterIdScheduledPosts)
+				r.Route("/{postId}", func(r chi.Router) {
+					r.Use(middleware.UUIDParamValidationMiddleware("postId"))
+					r.Get("/", apiServer.GetNewslettersNewsletterIdScheduledPostsPostId)
+					r.With(publishLimit).Put("/", apiServer.PutNewslettersNewsletterIdScheduledPostsPostId)
+					r.Delete("/", apiServer.DeleteNewslettersNewsletterIdScheduledPostsPostId)
+				})
+			})
+
+			// Drafts waiting for an owner's approval
+			r.Get("/review-queue", apiServer.GetNewslettersNewsletterIdReviewQueue)
+
+			// Draft management (editor-owned)
+			r.Route("/drafts", func(r chi.Router) {
+				r.Get("/", apiServer.GetNewslettersNewsletterIdDrafts)
+				r.Post("/", apiServer.PostNewslettersNewsletterIdDrafts)
+				r.Route("/{postId}", func(r chi.Router) {
+					r.Use(middleware.UUIDParamValidationMiddleware("postId"))
+					r.Get("/", apiServer.GetNewslettersNewsletterIdDraftsPostId)
+					r.Put("/", apiServer.PutNewslettersNewsletterIdDraftsPostId)
+					r.Delete("/", apiServer.DeleteNewslettersNewsletterIdDraftsPostId)
+					r.With(publishLimit).Post("/publish", apiServer.PostNewslettersNewsletterIdDraftsPostIdPublish)
+					r.Get("/review", apiServer.GetNewslettersNewsletterIdDraftsPostIdReview)
+					r.Post("/review/submit", apiServer.PostNewslettersNewsletterIdDraftsPostIdReviewSubmit)
+					r.Post("/review/approve", apiServer.PostNewslettersNewsletterIdDraftsPostIdReviewApprove)
+					r.Post("/review/reject", apiServer.PostNewslettersNewsletterIdDraftsPostIdReviewReject)
+				})
+			})
+		})
+	})
+
+	// Admin routes
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(authMiddleware.RequireAdmin)
+		r.Use(usageTracking)
+		r.Use(readOnlyWrites)
+		r.Get("/admin/users", apiServer.GetAdminUsers)
+		r.Get("/admin/config", apiServer.GetAdminConfig)
+		r.Get("/admin/scheduler/status", apiServer.GetAdminSchedulerStatus)
+		r.With(publishLimit).Post("/admin/scheduler/run", apiServer.PostAdminSchedulerRun)
+		r.Get("/admin/retention/unconfirmed", apiServer.GetAdminRetentionUnconfirmed)
+		r.With(middleware.UUIDParamValidationMiddleware("postId"), publishLimit).Post("/admin/posts/{postId}/republish", apiServer.PostAdminPostsPostIdRepublish)
+		r.Get("/admin/jobs", apiServer.GetAdminJobs)
+		r.With(middleware.UUIDParamValidationMiddleware("jobId")).Post("/admin/jobs/{jobId}/retry", apiServer.PostAdminJobsJobIdRetry)
+		r.Get("/admin/newsletters", apiServer.GetAdminNewsletters)
+		r.Get("/admin/newsletters/deleted", apiServer.GetAdminNewslettersDeleted)
+		r.With(middleware.UUIDParamValidationMiddleware("newsletterId")).Delete("/admin/newsletters/{newsletterId}", apiServer.DeleteAdminNewslettersNewsletterId)
+		r.With(middleware.UUIDParamValidationMiddleware("newsletterId")).Post("/admin/newsletters/{newsletterId}/restore", apiServer.PostAdminNewslettersNewsletterIdRestore)
+		r.With(middleware.UUIDParamValidationMiddleware("newsletterId"), publishLimit).Post("/admin/newsletters/{newsletterId}/notify-subscribers", apiServer.PostAdminNewslettersNewsletterIdNotifySubscribers)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Put("/admin/users/{userId}/grant-admin", apiServer.PutAdminUsersUserIdGrantAdmin)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Put("/admin/users/{userId}/revoke-admin", apiServer.PutAdminUsersUserIdRevokeAdmin)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Post("/admin/users/{userId}/revoke-sessions", apiServer.PostAdminUsersUserIdRevokeSessions)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Get("/admin/users/{userId}/usage", apiServer.GetAdminUsersUserIdUsage)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Get("/admin/users/{userId}/costs", apiServer.GetAdminUsersUserIdCosts)
+		r.Get("/admin/plans", apiServer.GetAdminPlans)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Put("/admin/users/{userId}/plan", apiServer.PutAdminUsersUserIdPlan)
+		r.With(middleware.UUIDParamValidationMiddleware("userId")).Post("/admin/users/{userId}/trial", apiServer.PostAdminUsersUserIdTrial)
+		r.Get("/admin/coupons", apiServer.GetAdminCoupons)
+		r.Post("/admin/coupons", apiServer.PostAdminCoupons)
+	})
+
+	// Admin read-only toggle, exempt from the incident flag so it can be switched off again
+	apiRouter.Group(func(r chi.Router) {
+		r.Use(authMiddleware.RequireAdmin)
+		r.Use(usageTracking)
+		r.Use(middleware.ReadOnly(schemaReadOnly, false))
+		r.Get("/admin/config/read-only", apiServer.GetAdminConfigReadOnly)
+		r.Put("/admin/config/read-only", apiServer.PutAdminConfigReadOnly)
+	})
+
+	// Browsers opening a mistyped or truncated email link get a page instead of plain text. Set
+	// before mounting, so the API router inherits it.
+	r.With(middleware.SecurityHeadersMiddleware(htmlSecurityHeaders(cfg.Security))).NotFound(apiServer.NotFound)
+
+	// Mount the API router
+	r.Mount("/api/v1", apiRouter)
+
+	return r, nil
+}
+
+// apiKeyScopeGrants lists the routes scoped API keys and integration tokens may call and the scopes allowing each;
+// keep it in step with the protected routes above. Every scope can read the newsletters to find
+// the ones it works on.
+var apiKeyScopeGrants = map[string][]string{
+	"GET /newsletters":                              {services.ScopePostsWrite, services.ScopeSubscribersRead, services.ScopeAnalyticsRead},
+	"GET /newsletters/{newsletterId}":               {services.ScopePostsWrite, services.ScopeSubscribersRead, services.ScopeAnalyticsRead},
+	"GET /newsletters/{newsletterId}/posts":         {services.ScopePostsWrite, services.ScopeAnalyticsRead},
+	"POST /newsletters/{newsletterId}/posts":        {services.ScopePostsWrite},
+	"POST /newsletters/{newsletterId}/posts/import": {services.ScopePostsWrite},
+
+	"GET /newsletters/{newsletterId}/posts/{postId}/preflight":    {services.ScopePostsWrite},
+	"POST /newsletters/{newsletterId}/posts/{postId}/suggestions": {services.ScopePostsWrite},
+	"GET /newsletters/{newsletterId}/scheduled-posts":             {services.ScopePostsWrite},
+	"GET /newsletters/{newsletterId}/scheduled-posts/{postId}":    {services.ScopePostsWrite},
+	"PUT /newsletters/{newsletterId}/scheduled-posts/{postId}":    {services.ScopePostsWrite},
+	"DELETE /newsletters/{newsletterId}/scheduled-posts/{postId}": {services.ScopePostsWrite},
+	"GET /newsletters/{newsletterId}/drafts":                      {services.ScopePostsWrite},
+	"POST /newsletters/{newsletterId}/drafts":                     {services.ScopePostsWrite},
+	"GET /newsletters/{newsletterId}/drafts/{postId}":             {services.ScopePostsWrite},
+	"PUT /newsletters/{newsletterId}/drafts/{postId}":             {services.ScopePostsWrite},
+	"DELETE /newsletters/{newsletterId}/drafts/{postId}":          {services.ScopePostsWrite},
+	"POST /newsletters/{newsletterId}/drafts/{postId}/publish":    {services.ScopePostsWrite},
+
+	"GET /newsletters/{newsletterId}/subscribers":         {services.ScopeSubscribersRead},
+	"GET /newsletters/{newsletterId}/subscribers/export":  {services.ScopeSubscribersRead},
+	"GET /newsletters/{newsletterId}/subscribers/deleted": {services.ScopeSubscribersRead},
+	"GET /newsletters/{newsletterId}/suppressions":        {services.ScopeSubscribersRead},
+
+	"GET /newsletters/{newsletterId}/stats":                          {services.ScopeAnalyticsRead},
+	"GET /newsletters/{newsletterId}/posts/{postId}/delivery":        {services.ScopeAnalyticsRead},
+	"GET /newsletters/{newsletterId}/posts/{postId}/delivery-report": {services.ScopeAnalyticsRead},
+	"GET /newsletters/{newsletterId}/posts/{postId}/stats":           {services.ScopeAnalyticsRead},
+}
+
+// apiSecurityHeaders is the policy applied to every response
+func apiSecurityHeaders(cfg config.SecurityConfig) middleware.SecurityHeaders {
+	return middleware.SecurityHeaders{
+		HSTSMaxAge:            cfg.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.HSTSIncludeSubdomains,
+		ContentSecurityPolicy: cfg.APIContentSecurityPolicy,
+		ReferrerPolicy:        cfg.ReferrerPolicy,
+	}
+}
+
+// htmlSecurityHeaders is the policy for hosted pages; it overrides the API policy set globally
+func htmlSecurityHeaders(cfg config.SecurityConfig) middleware.SecurityHeaders {
+	headers := apiSecurityHeaders(cfg)
+	headers.ContentSecurityPolicy = cfg.HTMLContentSecurityPolicy
+	return headers
+}
+
+// SlogMiddleware is a chi middleware for logging requests using slog.
+func SlogMiddleware(logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tstart := time.Now()
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				logger.Info("Served request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", ww.Status(),
+					"latency_ms", time.Since(tstart).Milliseconds(),
+					"bytes_out", ww.BytesWritten(),
+					"request_id", chimiddleware.GetReqID(r.Context()),
+					"remote_ip", r.RemoteAddr,
+					"user_agent", r.UserAgent(),
+				)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"log/slog"
 	"net/http"
 
+	"go-newsletter/internal/config"
 	"go-newsletter/internal/handlers"
+	"go-newsletter/internal/hostedpage"
+	"go-newsletter/internal/scheduler"
 	"go-newsletter/internal/services"
 	"go-newsletter/internal/utils"
 	"go-newsletter/pkg/generated"
@@ -12,30 +15,141 @@ import (
 
 // Server implements the generated ServerInterface
 type Server struct {
-	profileHandler    *handlers.ProfileHandler
-	authHandler       *handlers.AuthHandler
-	authService       *services.AuthService
-	mailingService    *services.MailingService
-	postService       *services.PostService
-	newsletterHandler *handlers.NewsletterHandler
-	subscriberHandler *handlers.SubscriberHandler
-	postHandler       *handlers.PostHandler
-	responder         *utils.HTTPResponder
-	logger            *slog.Logger // Keep logger for non-HTTP operations
+	profileHandler       *handlers.ProfileHandler
+	authHandler          *handlers.AuthHandler
+	authService          *services.AuthService
+	apiKeyService        *services.APIKeyService
+	oauthService         *services.OAuthService
+	profileService       *services.ProfileService
+	mailingService       *services.MailingService
+	postService          *services.PostService
+	newsletterHandler    *handlers.NewsletterHandler
+	subscriberHandler    *handlers.SubscriberHandler
+	postHandler          *handlers.PostHandler
+	schedulerHandler     *handlers.SchedulerHandler
+	configHandler        *handlers.ConfigHandler
+	emailJobHandler      *handlers.EmailJobHandler
+	usageHandler         *handlers.UsageHandler
+	costHandler          *handlers.CostHandler
+	retentionHandler     *handlers.RetentionHandler
+	planHandler          *handlers.PlanHandler
+	notificationHandler  *handlers.NotificationHandler
+	onboardingHandler    *handlers.OnboardingHandler
+	sampleContentHandler *handlers.SampleContentHandler
+	suggestionHandler    *handlers.SuggestionHandler
+	apiKeyHandler        *handlers.APIKeyHandler
+	webhookHandler       *handlers.WebhookHandler
+	emailTemplateHandler *handlers.EmailTemplateHandler
+	inboxHandler         *handlers.InboxHandler
+	archiveHandler       *handlers.ArchiveHandler
+	resendWebhookHandler *handlers.ResendWebhookHandler
+	oauthHandler         *handlers.OAuthHandler
+	suppressionHandler   *handlers.SuppressionHandler
+	socialAuthHandler    *handlers.SocialAuthHandler
+	magicLinkHandler     *handlers.MagicLinkHandler
+	securityEventHandler *handlers.SecurityEventHandler
+	memberHandler        *handlers.MemberHandler
+	sessionHandler       *handlers.SessionHandler
+	trackingHandler      *handlers.TrackingHandler
+	hostedPageHandler    *handlers.HostedPageHandler
+	statsHandler         *handlers.StatsHandler
+	gdprHandler          *handlers.GDPRHandler
+	reviewHandler        *handlers.ReviewHandler
+	responder            *utils.HTTPResponder
+	logger               *slog.Logger // Keep logger for non-HTTP operations
+}
+
+// Deps are the services and shared components the server's handlers are built from. Fields
+// are named so two services of the same type cannot be swapped without a compile error.
+type Deps struct {
+	Config      *config.Config
+	Logger      *slog.Logger
+	Responder   *utils.HTTPResponder
+	HostedPages *hostedpage.Pages
+	// PostPublisher is the scheduled post publisher admins inspect and trigger
+	PostPublisher *scheduler.PostPublisher
+
+	Profile       *services.ProfileService
+	Auth          *services.AuthService
+	Mailing       *services.MailingService
+	Newsletter    *services.NewsletterService
+	Subscriber    *services.SubscriberService
+	Post          *services.PostService
+	EmailJob      *services.EmailJobService
+	Usage         *services.UsageService
+	Plan          *services.PlanService
+	Coupon        *services.CouponService
+	Suppression   *services.SuppressionService
+	ReadOnly      *services.ReadOnlyService
+	Cost          *services.CostService
+	Retention     *services.RetentionService
+	Notification  *services.NotificationService
+	Onboarding    *services.OnboardingService
+	SampleContent *services.SampleContentService
+	Suggestion    *services.SuggestionService
+	APIKey        *services.APIKeyService
+	Webhook       *services.WebhookService
+	EmailTemplate *services.EmailTemplateService
+	Inbox         *services.InboxService
+	Badge         *services.BadgeService
+	ResendWebhook *services.ResendWebhookService
+	OAuth         *services.OAuthService
+	SocialAuth    *services.SocialAuthService
+	MagicLink     *services.MagicLinkService
+	SecurityEvent *services.SecurityEventService
+	Member        *services.MemberService
+	Session       *services.SessionService
+	Tracking      *services.TrackingService
+	Stats         *services.StatsService
+	GDPR          *services.GDPRService
+	Review        *services.ReviewService
 }
 
 // NewServer creates a new server instance
-func NewServer(profileService *services.ProfileService, authService *services.AuthService, logger *slog.Logger, mailingService *services.MailingService, newsletterService *services.NewsletterService, subscriberService *services.SubscriberService, postService *services.PostService, responder *utils.HTTPResponder) *Server {
+func NewServer(d Deps) *Server {
+	hostedPageHandler := handlers.NewHostedPageHandler(d.HostedPages, d.Responder)
 	return &Server{
-		logger:            logger,
-		profileHandler:    handlers.NewProfileHandler(profileService, authService, logger),
-		authHandler:       handlers.NewAuthHandler(authService, logger),
-		authService:       authService,
-		mailingService:    mailingService,
-		postService:       postService,
-		newsletterHandler: handlers.NewNewsletterHandler(newsletterService, profileService, responder),
-		subscriberHandler: handlers.NewSubscriberHandler(subscriberService, responder),
-		postHandler:       handlers.NewPostHandler(postService, responder),
+		logger:               d.Logger,
+		profileHandler:       handlers.NewProfileHandler(d.Profile, d.Auth, d.Logger),
+		authHandler:          handlers.NewAuthHandler(d.Auth, d.Logger),
+		authService:          d.Auth,
+		apiKeyService:        d.APIKey,
+		oauthService:         d.OAuth,
+		profileService:       d.Profile,
+		mailingService:       d.Mailing,
+		postService:          d.Post,
+		newsletterHandler:    handlers.NewNewsletterHandler(d.Newsletter, d.Responder),
+		subscriberHandler:    handlers.NewSubscriberHandler(d.Subscriber, d.Suppression, hostedPageHandler, d.Responder),
+		postHandler:          handlers.NewPostHandler(d.Post, d.Responder),
+		schedulerHandler:     handlers.NewSchedulerHandler(d.PostPublisher, d.Responder),
+		configHandler:        handlers.NewConfigHandler(d.Config, d.ReadOnly, d.Responder),
+		emailJobHandler:      handlers.NewEmailJobHandler(d.EmailJob, d.Responder),
+		usageHandler:         handlers.NewUsageHandler(d.Usage, d.Profile, d.Responder),
+		costHandler:          handlers.NewCostHandler(d.Cost, d.Profile, d.Responder),
+		retentionHandler:     handlers.NewRetentionHandler(d.Retention, d.Responder),
+		planHandler:          handlers.NewPlanHandler(d.Plan, d.Coupon, d.Responder),
+		notificationHandler:  handlers.NewNotificationHandler(d.Notification, d.Responder),
+		onboardingHandler:    handlers.NewOnboardingHandler(d.Onboarding, d.Responder),
+		sampleContentHandler: handlers.NewSampleContentHandler(d.SampleContent, d.Responder),
+		suggestionHandler:    handlers.NewSuggestionHandler(d.Suggestion, d.Responder),
+		apiKeyHandler:        handlers.NewAPIKeyHandler(d.APIKey, d.Responder),
+		webhookHandler:       handlers.NewWebhookHandler(d.Webhook, d.Responder),
+		emailTemplateHandler: handlers.NewEmailTemplateHandler(d.EmailTemplate, d.Responder),
+		inboxHandler:         handlers.NewInboxHandler(d.Inbox, d.Responder),
+		archiveHandler:       handlers.NewArchiveHandler(d.Newsletter, d.Post, d.Badge, d.Responder),
+		resendWebhookHandler: handlers.NewResendWebhookHandler(d.ResendWebhook, d.Responder),
+		oauthHandler:         handlers.NewOAuthHandler(d.OAuth, d.Responder),
+		suppressionHandler:   handlers.NewSuppressionHandler(d.Suppression, d.Responder),
+		socialAuthHandler:    handlers.NewSocialAuthHandler(d.SocialAuth, d.Config.Security.CSRFSecureCookie, d.Responder),
+		magicLinkHandler:     handlers.NewMagicLinkHandler(d.MagicLink, d.Responder),
+		securityEventHandler: handlers.NewSecurityEventHandler(d.SecurityEvent, d.Responder),
+		memberHandler:        handlers.NewMemberHandler(d.Member, d.Responder),
+		sessionHandler:       handlers.NewSessionHandler(d.Session, d.Responder),
+		trackingHandler:      handlers.NewTrackingHandler(d.Tracking, d.Responder),
+		hostedPageHandler:    hostedPageHandler,
+		statsHandler:         handlers.NewStatsHandler(d.Stats, d.Responder),
+		gdprHandler:          handlers.NewGDPRHandler(d.GDPR, d.Responder),
+		reviewHandler:        handlers.NewReviewHandler(d.Review, d.Responder),
 	}
 }
 
@@ -43,6 +157,26 @@ func (s *Server) GetAuthService() *services.AuthService {
 	return s.authService
 }
 
+// GetAPIKeyService returns the service the auth middleware checks X-API-Key headers with
+func (s *Server) GetAPIKeyService() *services.APIKeyService {
+	return s.apiKeyService
+}
+
+// GetProfileService returns the service the auth middleware looks up admin status with
+func (s *Server) GetProfileService() *services.ProfileService {
+	return s.profileService
+}
+
+// GetOAuthService returns the service the auth middleware introspects integration tokens with
+func (s *Server) GetOAuthService() *services.OAuthService {
+	return s.oauthService
+}
+
+// NotFound answers requests for unknown paths, with a branded page for browsers
+func (s *Server) NotFound(w http.ResponseWriter, r *http.Request) {
+	s.hostedPageHandler.NotFound(w, r)
+}
+
 func (s *Server) GetMe(w http.ResponseWriter, r *http.Request) {
 	s.profileHandler.GetMe(w, r)
 }
@@ -59,6 +193,26 @@ func (s *Server) DeleteAdminNewslettersNewsletterId(w http.ResponseWriter, r *ht
 	s.newsletterHandler.DeleteNewsletterByID(w, r)
 }
 
+// GetAdminNewslettersDeleted handles GET /admin/newsletters/deleted
+func (s *Server) GetAdminNewslettersDeleted(w http.ResponseWriter, r *http.Request) {
+	s.newsletterHandler.GetDeletedNewsletters(w, r)
+}
+
+// PostAdminNewslettersNewsletterIdRestore handles POST /admin/newsletters/{newsletterId}/restore
+func (s *Server) PostAdminNewslettersNewsletterIdRestore(w http.ResponseWriter, r *http.Request) {
+	s.newsletterHandler.RestoreNewsletter(w, r)
+}
+
+// GetNewslettersNewsletterIdConfigBundle handles GET /newsletters/{newsletterId}/config-bundle
+func (s *Server) GetNewslettersNewsletterIdConfigBundle(w http.ResponseWriter, r *http.Request) {
+	s.newsletterHandler.ExportConfigBundle(w, r)
+}
+
+// PutNewslettersNewsletterIdConfigBundle handles PUT /newsletters/{newsletterId}/config-bundle
+func (s *Server) PutNewslettersNewsletterIdConfigBundle(w http.ResponseWriter, r *http.Request) {
+	s.newsletterHandler.ImportConfigBundle(w, r)
+}
+
 func (s *Server) GetAdminUsers(w http.ResponseWriter, r *http.Request) {
 	s.profileHandler.GetAllProfiles(w, r)
 }
@@ -73,6 +227,181 @@ func (s *Server) PutAdminUsersUserIdRevokeAdmin(w http.ResponseWriter, r *http.R
 	s.profileHandler.RevokeAdmin(w, r)
 }
 
+// PostAuthRefresh handles POST /auth/refresh
+func (s *Server) PostAuthRefresh(w http.ResponseWriter, r *http.Request) {
+	s.sessionHandler.Refresh(w, r)
+}
+
+// PostAdminUsersUserIdRevokeSessions handles POST /admin/users/{userId}/revoke-sessions
+func (s *Server) PostAdminUsersUserIdRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	s.sessionHandler.RevokeSessions(w, r)
+}
+
+// GetMeUsage handles GET /me/usage
+func (s *Server) GetMeUsage(w http.ResponseWriter, r *http.Request) {
+	s.usageHandler.GetMyUsage(w, r)
+}
+
+// GetAdminUsersUserIdUsage handles GET /admin/users/{userId}/usage
+func (s *Server) GetAdminUsersUserIdUsage(w http.ResponseWriter, r *http.Request) {
+	s.usageHandler.GetUserUsage(w, r)
+}
+
+// GetMeCosts handles GET /me/costs
+func (s *Server) GetMeCosts(w http.ResponseWriter, r *http.Request) {
+	s.costHandler.GetMyCosts(w, r)
+}
+
+// GetNewslettersNewsletterIdCosts handles GET /newsletters/{newsletterId}/costs
+func (s *Server) GetNewslettersNewsletterIdCosts(w http.ResponseWriter, r *http.Request) {
+	s.costHandler.GetNewsletterCosts(w, r)
+}
+
+// GetNewslettersNewsletterIdStats handles GET /newsletters/{newsletterId}/stats
+func (s *Server) GetNewslettersNewsletterIdStats(w http.ResponseWriter, r *http.Request) {
+	s.statsHandler.GetSubscriberGrowth(w, r)
+}
+
+// GetAdminUsersUserIdCosts handles GET /admin/users/{userId}/costs
+func (s *Server) GetAdminUsersUserIdCosts(w http.ResponseWriter, r *http.Request) {
+	s.costHandler.GetUserCosts(w, r)
+}
+
+// GetMeOnboarding handles GET /me/onboarding
+func (s *Server) GetMeOnboarding(w http.ResponseWriter, r *http.Request) {
+	s.onboardingHandler.GetChecklist(w, r)
+}
+
+// GetMeApiKeys handles GET /me/api-keys
+func (s *Server) GetMeApiKeys(w http.ResponseWriter, r *http.Request) {
+	s.apiKeyHandler.ListKeys(w, r)
+}
+
+// PostMeApiKeys handles POST /me/api-keys
+func (s *Server) PostMeApiKeys(w http.ResponseWriter, r *http.Request) {
+	s.apiKeyHandler.CreateKey(w, r)
+}
+
+// DeleteMeApiKeysApiKeyId handles DELETE /me/api-keys/{apiKeyId}
+func (s *Server) DeleteMeApiKeysApiKeyId(w http.ResponseWriter, r *http.Request) {
+	s.apiKeyHandler.RevokeKey(w, r)
+}
+
+// PostMeInvitationsAccept handles POST /me/invitations/accept
+func (s *Server) PostMeInvitationsAccept(w http.ResponseWriter, r *http.Request) {
+	s.memberHandler.AcceptInvitation(w, r)
+}
+
+// GetMeSecurityEvents handles GET /me/security-events
+func (s *Server) GetMeSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	s.securityEventHandler.List(w, r)
+}
+
+// GetMeNotifications handles GET /me/notifications
+func (s *Server) GetMeNotifications(w http.ResponseWriter, r *http.Request) {
+	s.inboxHandler.ListNotifications(w, r)
+}
+
+// GetMeNotificationsUnreadCount handles GET /me/notifications/unread-count
+func (s *Server) GetMeNotificationsUnreadCount(w http.ResponseWriter, r *http.Request) {
+	s.inboxHandler.UnreadCount(w, r)
+}
+
+// PostMeNotificationsReadAll handles POST /me/notifications/read-all
+func (s *Server) PostMeNotificationsReadAll(w http.ResponseWriter, r *http.Request) {
+	s.inboxHandler.MarkAllRead(w, r)
+}
+
+// PostMeNotificationsNotificationIdRead handles POST /me/notifications/{notificationId}/read
+func (s *Server) PostMeNotificationsNotificationIdRead(w http.ResponseWriter, r *http.Request) {
+	s.inboxHandler.MarkRead(w, r)
+}
+
+// GetMePlan handles GET /me/plan
+func (s *Server) GetMePlan(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.GetMyPlan(w, r)
+}
+
+// GetAdminPlans handles GET /admin/plans
+func (s *Server) GetAdminPlans(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.ListPlans(w, r)
+}
+
+// PutAdminUsersUserIdPlan handles PUT /admin/users/{userId}/plan
+func (s *Server) PutAdminUsersUserIdPlan(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.AssignPlan(w, r)
+}
+
+// PostMeCouponsRedeem handles POST /me/coupons/redeem
+func (s *Server) PostMeCouponsRedeem(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.RedeemCoupon(w, r)
+}
+
+// GetAdminCoupons handles GET /admin/coupons
+func (s *Server) GetAdminCoupons(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.ListCoupons(w, r)
+}
+
+// PostAdminCoupons handles POST /admin/coupons
+func (s *Server) PostAdminCoupons(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.CreateCoupon(w, r)
+}
+
+// PostAdminUsersUserIdTrial handles POST /admin/users/{userId}/trial
+func (s *Server) PostAdminUsersUserIdTrial(w http.ResponseWriter, r *http.Request) {
+	s.planHandler.ExtendTrial(w, r)
+}
+
+// GetAdminConfig handles GET /admin/config
+func (s *Server) GetAdminConfig(w http.ResponseWriter, r *http.Request) {
+	s.configHandler.GetEffective(w, r)
+}
+
+// GetAdminConfigReadOnly handles GET /admin/config/read-only
+func (s *Server) GetAdminConfigReadOnly(w http.ResponseWriter, r *http.Request) {
+	s.configHandler.GetReadOnly(w, r)
+}
+
+// PutAdminConfigReadOnly handles PUT /admin/config/read-only
+func (s *Server) PutAdminConfigReadOnly(w http.ResponseWriter, r *http.Request) {
+	s.configHandler.PutReadOnly(w, r)
+}
+
+// GetAdminJobs handles GET /admin/jobs
+func (s *Server) GetAdminJobs(w http.ResponseWriter, r *http.Request) {
+	s.emailJobHandler.ListJobs(w, r)
+}
+
+// PostAdminJobsJobIdRetry handles POST /admin/jobs/{jobId}/retry
+func (s *Server) PostAdminJobsJobIdRetry(w http.ResponseWriter, r *http.Request) {
+	s.emailJobHandler.RetryJob(w, r)
+}
+
+// GetAdminSchedulerStatus handles GET /admin/scheduler/status
+func (s *Server) GetAdminSchedulerStatus(w http.ResponseWriter, r *http.Request) {
+	s.schedulerHandler.GetStatus(w, r)
+}
+
+// GetAdminRetentionUnconfirmed handles GET /admin/retention/unconfirmed
+func (s *Server) GetAdminRetentionUnconfirmed(w http.ResponseWriter, r *http.Request) {
+	s.retentionHandler.GetUnconfirmedReport(w, r)
+}
+
+// PostAdminSchedulerRun handles POST /admin/scheduler/run
+func (s *Server) PostAdminSchedulerRun(w http.ResponseWriter, r *http.Request) {
+	s.schedulerHandler.Run(w, r)
+}
+
+// PostAdminNewslettersNewsletterIdNotifySubscribers handles POST /admin/newsletters/{newsletterId}/notify-subscribers
+func (s *Server) PostAdminNewslettersNewsletterIdNotifySubscribers(w http.ResponseWriter, r *http.Request) {
+	s.notificationHandler.NotifySubscribers(w, r)
+}
+
+// PostAdminPostsPostIdRepublish handles POST /admin/posts/{postId}/republish
+func (s *Server) PostAdminPostsPostIdRepublish(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.RepublishPost(w, r)
+}
+
 // PostAuthSignup handles POST /auth/signup endpoint
 func (s *Server) PostAuthSignup(w http.ResponseWriter, r *http.Request) {
 	s.authHandler.PostAuthSignup(w, r)
@@ -88,6 +417,31 @@ func (s *Server) PostAuthPasswordResetRequest(w http.ResponseWriter, r *http.Req
 	s.authHandler.PostAuthPasswordResetRequest(w, r)
 }
 
+// PostAuthOauthLink handles POST /auth/oauth/link
+func (s *Server) PostAuthOauthLink(w http.ResponseWriter, r *http.Request) {
+	s.socialAuthHandler.Link(w, r)
+}
+
+// PostAuthMagicLink handles POST /auth/magic-link
+func (s *Server) PostAuthMagicLink(w http.ResponseWriter, r *http.Request) {
+	s.magicLinkHandler.Send(w, r)
+}
+
+// PostAuthMagicLinkVerify handles POST /auth/magic-link/verify
+func (s *Server) PostAuthMagicLinkVerify(w http.ResponseWriter, r *http.Request) {
+	s.magicLinkHandler.Verify(w, r)
+}
+
+// GetAuthOauthProviderStart handles GET /auth/oauth/{provider}/start
+func (s *Server) GetAuthOauthProviderStart(w http.ResponseWriter, r *http.Request) {
+	s.socialAuthHandler.Start(w, r)
+}
+
+// GetAuthOauthProviderCallback handles GET /auth/oauth/{provider}/callback
+func (s *Server) GetAuthOauthProviderCallback(w http.ResponseWriter, r *http.Request) {
+	s.socialAuthHandler.Callback(w, r)
+}
+
 // GetNewsletters handles GET /newsletters - get newsletters owned by current editor
 func (s *Server) GetNewsletters(w http.ResponseWriter, r *http.Request) {
 	s.newsletterHandler.GetNewslettersOwnedByEditor(w, r)
@@ -126,6 +480,31 @@ func (s *Server) GetNewslettersNewsletterIdScheduledPosts(w http.ResponseWriter,
 	s.postHandler.GetPostsByNewsletterId(w, r, false)
 }
 
+// GetNewslettersNewsletterIdPostsPostIdDelivery handles GET /newsletters/{newsletterId}/posts/{postId}/delivery
+func (s *Server) GetNewslettersNewsletterIdPostsPostIdDelivery(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.GetDeliveryStats(w, r)
+}
+
+// GetNewslettersNewsletterIdPostsPostIdStats handles GET /newsletters/{newsletterId}/posts/{postId}/stats
+func (s *Server) GetNewslettersNewsletterIdPostsPostIdStats(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.GetStats(w, r)
+}
+
+// GetNewslettersNewsletterIdPostsPostIdDeliveryReport handles GET /newsletters/{newsletterId}/posts/{postId}/delivery-report
+func (s *Server) GetNewslettersNewsletterIdPostsPostIdDeliveryReport(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.GetDeliveryReport(w, r)
+}
+
+// PostNewslettersNewsletterIdPostsImport handles POST /newsletters/{newsletterId}/posts/import
+func (s *Server) PostNewslettersNewsletterIdPostsImport(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.ImportArchive(w, r)
+}
+
+// GetNewslettersNewsletterIdPostsPostIdPreflight handles GET /newsletters/{newsletterId}/posts/{postId}/preflight
+func (s *Server) GetNewslettersNewsletterIdPostsPostIdPreflight(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.GetPreflight(w, r)
+}
+
 func (s *Server) DeleteNewslettersNewsletterIdScheduledPostsPostId(w http.ResponseWriter, r *http.Request) {
 	s.postHandler.DeletePostById(w, r)
 }
@@ -138,14 +517,229 @@ func (s *Server) PutNewslettersNewsletterIdScheduledPostsPostId(w http.ResponseW
 	s.postHandler.PutPost(w, r)
 }
 
+// PostNewslettersNewsletterIdPostsPostIdSuggestions handles POST /newsletters/{newsletterId}/posts/{postId}/suggestions
+func (s *Server) PostNewslettersNewsletterIdPostsPostIdSuggestions(w http.ResponseWriter, r *http.Request) {
+	s.suggestionHandler.SuggestForPost(w, r)
+}
+
+// PostNewslettersNewsletterIdSampleContent handles POST /newsletters/{newsletterId}/sample-content
+func (s *Server) PostNewslettersNewsletterIdSampleContent(w http.ResponseWriter, r *http.Request) {
+	s.sampleContentHandler.CreateSampleContent(w, r)
+}
+
+// GetNewslettersNewsletterIdMembers handles GET /newsletters/{newsletterId}/members
+func (s *Server) GetNewslettersNewsletterIdMembers(w http.ResponseWriter, r *http.Request) {
+	s.memberHandler.ListMembers(w, r)
+}
+
+// PostNewslettersNewsletterIdMembers handles POST /newsletters/{newsletterId}/members
+func (s *Server) PostNewslettersNewsletterIdMembers(w http.ResponseWriter, r *http.Request) {
+	s.memberHandler.InviteMember(w, r)
+}
+
+// DeleteNewslettersNewsletterIdMembersEditorId handles DELETE /newsletters/{newsletterId}/members/{editorId}
+func (s *Server) DeleteNewslettersNewsletterIdMembersEditorId(w http.ResponseWriter, r *http.Request) {
+	s.memberHandler.RemoveMember(w, r)
+}
+
+// GetNewslettersNewsletterIdWebhooks handles GET /newsletters/{newsletterId}/webhooks
+func (s *Server) GetNewslettersNewsletterIdWebhooks(w http.ResponseWriter, r *http.Request) {
+	s.webhookHandler.ListWebhooks(w, r)
+}
+
+// PostNewslettersNewsletterIdWebhooks handles POST /newsletters/{newsletterId}/webhooks
+func (s *Server) PostNewslettersNewsletterIdWebhooks(w http.ResponseWriter, r *http.Request) {
+	s.webhookHandler.CreateWebhook(w, r)
+}
+
+// DeleteNewslettersNewsletterIdWebhooksWebhookId handles DELETE /newsletters/{newsletterId}/webhooks/{webhookId}
+func (s *Server) DeleteNewslettersNewsletterIdWebhooksWebhookId(w http.ResponseWriter, r *http.Request) {
+	s.webhookHandler.DeleteWebhook(w, r)
+}
+
+// GetNewslettersNewsletterIdWebhooksWebhookIdDeliveries handles GET /newsletters/{newsletterId}/webhooks/{webhookId}/deliveries
+func (s *Server) GetNewslettersNewsletterIdWebhooksWebhookIdDeliveries(w http.ResponseWriter, r *http.Request) {
+	s.webhookHandler.ListDeliveries(w, r)
+}
+
+// GetNewslettersNewsletterIdEmailTemplates handles GET /newsletters/{newsletterId}/email-templates
+func (s *Server) GetNewslettersNewsletterIdEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	s.emailTemplateHandler.ListTemplates(w, r)
+}
+
+// GetNewslettersNewsletterIdTemplateVariables handles GET /newsletters/{newsletterId}/template/variables
+func (s *Server) GetNewslettersNewsletterIdTemplateVariables(w http.ResponseWriter, r *http.Request) {
+	s.emailTemplateHandler.ListVariables(w, r)
+}
+
+// GetNewslettersNewsletterIdEmailTemplatesKind handles GET /newsletters/{newsletterId}/email-templates/{kind}
+func (s *Server) GetNewslettersNewsletterIdEmailTemplatesKind(w http.ResponseWriter, r *http.Request) {
+	s.emailTemplateHandler.GetTemplate(w, r)
+}
+
+// PutNewslettersNewsletterIdEmailTemplatesKind handles PUT /newsletters/{newsletterId}/email-templates/{kind}
+func (s *Server) PutNewslettersNewsletterIdEmailTemplatesKind(w http.ResponseWriter, r *http.Request) {
+	s.emailTemplateHandler.SetTemplate(w, r)
+}
+
+// DeleteNewslettersNewsletterIdEmailTemplatesKind handles DELETE /newsletters/{newsletterId}/email-templates/{kind}
+func (s *Server) DeleteNewslettersNewsletterIdEmailTemplatesKind(w http.ResponseWriter, r *http.Request) {
+	s.emailTemplateHandler.ResetTemplate(w, r)
+}
+
+// GetNewslettersNewsletterIdDrafts handles GET /newsletters/{newsletterId}/drafts
+func (s *Server) GetNewslettersNewsletterIdDrafts(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.GetDrafts(w, r)
+}
+
+// PostNewslettersNewsletterIdDrafts handles POST /newsletters/{newsletterId}/drafts
+func (s *Server) PostNewslettersNewsletterIdDrafts(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.PostDraft(w, r)
+}
+
+// GetNewslettersNewsletterIdDraftsPostId handles GET /newsletters/{newsletterId}/drafts/{postId}
+func (s *Server) GetNewslettersNewsletterIdDraftsPostId(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.GetDraft(w, r)
+}
+
+// PutNewslettersNewsletterIdDraftsPostId handles PUT /newsletters/{newsletterId}/drafts/{postId}
+func (s *Server) PutNewslettersNewsletterIdDraftsPostId(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.PutDraft(w, r)
+}
+
+// DeleteNewslettersNewsletterIdDraftsPostId handles DELETE /newsletters/{newsletterId}/drafts/{postId}
+func (s *Server) DeleteNewslettersNewsletterIdDraftsPostId(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.DeleteDraft(w, r)
+}
+
+// PostNewslettersNewsletterIdDraftsPostIdPublish handles POST /newsletters/{newsletterId}/drafts/{postId}/publish
+func (s *Server) PostNewslettersNewsletterIdDraftsPostIdPublish(w http.ResponseWriter, r *http.Request) {
+	s.postHandler.PublishDraft(w, r)
+}
+
+// GetNewslettersNewsletterIdDraftsPostIdReview handles GET /newsletters/{newsletterId}/drafts/{postId}/review
+func (s *Server) GetNewslettersNewsletterIdDraftsPostIdReview(w http.ResponseWriter, r *http.Request) {
+	s.reviewHandler.GetReview(w, r)
+}
+
+// PostNewslettersNewsletterIdDraftsPostIdReviewSubmit handles POST /newsletters/{newsletterId}/drafts/{postId}/review/submit
+func (s *Server) PostNewslettersNewsletterIdDraftsPostIdReviewSubmit(w http.ResponseWriter, r *http.Request) {
+	s.reviewHandler.Submit(w, r)
+}
+
+// PostNewslettersNewsletterIdDraftsPostIdReviewApprove handles POST /newsletters/{newsletterId}/drafts/{postId}/review/approve
+func (s *Server) PostNewslettersNewsletterIdDraftsPostIdReviewApprove(w http.ResponseWriter, r *http.Request) {
+	s.reviewHandler.Approve(w, r)
+}
+
+// PostNewslettersNewsletterIdDraftsPostIdReviewReject handles POST /newsletters/{newsletterId}/drafts/{postId}/review/reject
+func (s *Server) PostNewslettersNewsletterIdDraftsPostIdReviewReject(w http.ResponseWriter, r *http.Request) {
+	s.reviewHandler.Reject(w, r)
+}
+
+// GetNewslettersNewsletterIdReviewQueue handles GET /newsletters/{newsletterId}/review-queue
+func (s *Server) GetNewslettersNewsletterIdReviewQueue(w http.ResponseWriter, r *http.Request) {
+	s.reviewHandler.GetQueue(w, r)
+}
+
 func (s *Server) PostNewslettersNewsletterIdSubscribe(w http.ResponseWriter, r *http.Request) {
 	s.subscriberHandler.Subscribe(w, r)
 }
 
+// PostNewslettersNewsletterIdSubscribeResendConfirmation handles POST /newsletters/{newsletterId}/subscribe/resend-confirmation
+func (s *Server) PostNewslettersNewsletterIdSubscribeResendConfirmation(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.ResendConfirmation(w, r)
+}
+
+// GetPublicNewslettersNewsletterId handles GET /public/newsletters/{newsletterId}
+func (s *Server) GetPublicNewslettersNewsletterId(w http.ResponseWriter, r *http.Request) {
+	s.archiveHandler.GetNewsletter(w, r)
+}
+
+// GetPublicNewslettersNewsletterIdPosts handles GET /public/newsletters/{newsletterId}/posts
+func (s *Server) GetPublicNewslettersNewsletterIdPosts(w http.ResponseWriter, r *http.Request) {
+	s.archiveHandler.ListPosts(w, r)
+}
+
+// GetPublicNewslettersNewsletterIdBadgeSvg handles GET /public/newsletters/{newsletterId}/badge.svg
+func (s *Server) GetPublicNewslettersNewsletterIdBadgeSvg(w http.ResponseWriter, r *http.Request) {
+	s.archiveHandler.GetBadge(w, r)
+}
+
+// GetPublicNewslettersNewsletterIdFeedXml handles GET /public/newsletters/{newsletterId}/feed.xml
+func (s *Server) GetPublicNewslettersNewsletterIdFeedXml(w http.ResponseWriter, r *http.Request) {
+	s.archiveHandler.GetFeed(w, r)
+}
+
+// GetTOpenMessageId handles GET /t/open/{messageId}
+func (s *Server) GetTOpenMessageId(w http.ResponseWriter, r *http.Request) {
+	s.trackingHandler.Open(w, r)
+}
+
+// GetTClickMessageId handles GET /t/click/{messageId}
+func (s *Server) GetTClickMessageId(w http.ResponseWriter, r *http.Request) {
+	s.trackingHandler.Click(w, r)
+}
+
+// GetPublicNewslettersNewsletterIdPostsPostId handles GET /public/newsletters/{newsletterId}/posts/{postId}
+func (s *Server) GetPublicNewslettersNewsletterIdPostsPostId(w http.ResponseWriter, r *http.Request) {
+	s.archiveHandler.GetPost(w, r)
+}
+
+// GetPublicNewslettersNewsletterIdSitemapXml handles GET /public/newsletters/{newsletterId}/sitemap.xml
+func (s *Server) GetPublicNewslettersNewsletterIdSitemapXml(w http.ResponseWriter, r *http.Request) {
+	s.archiveHandler.GetSitemap(w, r)
+}
+
 func (s *Server) GetNewslettersNewsletterIdSubscribers(w http.ResponseWriter, r *http.Request) {
 	s.subscriberHandler.ListSubscribers(w, r)
 }
 
+// GetNewslettersNewsletterIdSubscribersExport handles GET /newsletters/{newsletterId}/subscribers/export
+func (s *Server) GetNewslettersNewsletterIdSubscribersExport(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.ExportSubscribers(w, r)
+}
+
+// PostNewslettersNewsletterIdSubscribersMerge handles POST /newsletters/{newsletterId}/subscribers/merge
+func (s *Server) PostNewslettersNewsletterIdSubscribersMerge(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.MergeSubscribers(w, r)
+}
+
+// GetNewslettersNewsletterIdSubscribersDeleted handles GET /newsletters/{newsletterId}/subscribers/deleted
+func (s *Server) GetNewslettersNewsletterIdSubscribersDeleted(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.ListDeletedSubscribers(w, r)
+}
+
+// DeleteNewslettersNewsletterIdSubscribersSubscriberId handles DELETE /newsletters/{newsletterId}/subscribers/{subscriberId}
+func (s *Server) DeleteNewslettersNewsletterIdSubscribersSubscriberId(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.DeleteSubscriber(w, r)
+}
+
+// PostNewslettersNewsletterIdSubscribersSubscriberIdRestore handles POST /newsletters/{newsletterId}/subscribers/{subscriberId}/restore
+func (s *Server) PostNewslettersNewsletterIdSubscribersSubscriberIdRestore(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.RestoreSubscriber(w, r)
+}
+
+// PostNewslettersNewsletterIdSubscribersResendConfirmations handles POST /newsletters/{newsletterId}/subscribers/resend-confirmations
+func (s *Server) PostNewslettersNewsletterIdSubscribersResendConfirmations(w http.ResponseWriter, r *http.Request) {
+	s.subscriberHandler.ResendConfirmations(w, r)
+}
+
+// GetNewslettersNewsletterIdSuppressions handles GET /newsletters/{newsletterId}/suppressions
+func (s *Server) GetNewslettersNewsletterIdSuppressions(w http.ResponseWriter, r *http.Request) {
+	s.suppressionHandler.ListSuppressions(w, r)
+}
+
+// PostNewslettersNewsletterIdSuppressions handles POST /newsletters/{newsletterId}/suppressions
+func (s *Server) PostNewslettersNewsletterIdSuppressions(w http.ResponseWriter, r *http.Request) {
+	s.suppressionHandler.CreateSuppression(w, r)
+}
+
+// DeleteNewslettersNewsletterIdSuppressionsSuppressionId handles DELETE /newsletters/{newsletterId}/suppressions/{suppressionId}
+func (s *Server) DeleteNewslettersNewsletterIdSuppressionsSuppressionId(w http.ResponseWriter, r *http.Request) {
+	s.suppressionHandler.DeleteSuppression(w, r)
+}
+
 func (s *Server) GetSubscribeConfirmConfirmationToken(w http.ResponseWriter, r *http.Request, confirmationToken string) {
 	s.subscriberHandler.ConfirmSubscription(w, r, confirmationToken)
 }
@@ -154,6 +748,46 @@ func (s *Server) GetUnsubscribeUnsubscribeToken(w http.ResponseWriter, r *http.R
 	s.subscriberHandler.Unsubscribe(w, r, unsubscribeToken)
 }
 
+// PostUnsubscribeUnsubscribeToken handles POST /unsubscribe/{unsubscribeToken}
+func (s *Server) PostUnsubscribeUnsubscribeToken(w http.ResponseWriter, r *http.Request, unsubscribeToken string) {
+	s.subscriberHandler.Unsubscribe(w, r, unsubscribeToken)
+}
+
+// GetUnsubscribeAllToken handles GET /unsubscribe-all/{token}
+func (s *Server) GetUnsubscribeAllToken(w http.ResponseWriter, r *http.Request, token string) {
+	s.subscriberHandler.UnsubscribeAll(w, r, token)
+}
+
+// PostGdprSubscribersVerification handles POST /gdpr/subscribers/verification
+func (s *Server) PostGdprSubscribersVerification(w http.ResponseWriter, r *http.Request) {
+	s.gdprHandler.RequestVerification(w, r)
+}
+
+// PostGdprSubscribersExport handles POST /gdpr/subscribers/export
+func (s *Server) PostGdprSubscribersExport(w http.ResponseWriter, r *http.Request) {
+	s.gdprHandler.Export(w, r)
+}
+
+// DeleteGdprSubscribers handles DELETE /gdpr/subscribers
+func (s *Server) DeleteGdprSubscribers(w http.ResponseWriter, r *http.Request) {
+	s.gdprHandler.Erase(w, r)
+}
+
+// PostWebhooksResend handles POST /webhooks/resend
+func (s *Server) PostWebhooksResend(w http.ResponseWriter, r *http.Request) {
+	s.resendWebhookHandler.ReceiveEvent(w, r)
+}
+
+// PostSubscriptionsUnsubscribeTokenEmailChange handles POST /subscriptions/{unsubscribeToken}/email-change
+func (s *Server) PostSubscriptionsUnsubscribeTokenEmailChange(w http.ResponseWriter, r *http.Request, unsubscribeToken string) {
+	s.subscriberHandler.RequestEmailChange(w, r, unsubscribeToken)
+}
+
+// GetSubscriptionsEmailChangeConfirmChangeToken handles GET /subscriptions/email-change/confirm/{changeToken}
+func (s *Server) GetSubscriptionsEmailChangeConfirmChangeToken(w http.ResponseWriter, r *http.Request, changeToken string) {
+	s.subscriberHandler.ConfirmEmailChange(w, r, changeToken)
+}
+
 func (s *Server) notImplemented(w http.ResponseWriter, r *http.Request) {
 	errorResponse := generated.Error{
 		Code:    501,
@@ -161,3 +795,28 @@ func (s *Server) notImplemented(w http.ResponseWriter, r *http.Request) {
 	}
 	s.responder.RespondJSON(w, http.StatusNotImplemented, errorResponse)
 }
+
+// GetMeIntegrationClients handles GET /me/integration-clients
+func (s *Server) GetMeIntegrationClients(w http.ResponseWriter, r *http.Request) {
+	s.oauthHandler.ListClients(w, r)
+}
+
+// PostMeIntegrationClients handles POST /me/integration-clients
+func (s *Server) PostMeIntegrationClients(w http.ResponseWriter, r *http.Request) {
+	s.oauthHandler.CreateClient(w, r)
+}
+
+// DeleteMeIntegrationClientsIntegrationClientId handles DELETE /me/integration-clients/{integrationClientId}
+func (s *Server) DeleteMeIntegrationClientsIntegrationClientId(w http.ResponseWriter, r *http.Request) {
+	s.oauthHandler.RevokeClient(w, r)
+}
+
+// PostOauthToken handles POST /oauth/token
+func (s *Server) PostOauthToken(w http.ResponseWriter, r *http.Request) {
+	s.oauthHandler.Token(w, r)
+}
+
+// PostOauthIntrospect handles POST /oauth/introspect
+func (s *Server) PostOauthIntrospect(w http.ResponseWriter, r *http.Request) {
+	s.oauthHandler.Introspect(w, r)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyPrefix marks newsletter API keys, so secret scanners and editors recognize them
+	apiKeyPrefix = "nl_"
+	// apiKeyPrefixLength is how much of a key is stored in the clear to tell keys apart
+	apiKeyPrefixLength  = 10
+	maxAPIKeyNameLength = 100
+	maxActiveAPIKeys    = 20
+	// apiKeyTouchInterval limits last_used_at updates to one per key and interval
+	apiKeyTouchInterval = time.Minute
+)
+
+// Scopes an API key can be limited to
+const (
+	ScopePostsWrite      = "posts:write"
+	ScopeSubscribersRead = "subscribers:read"
+	ScopeAnalyticsRead   = "analytics:read"
+)
+
+// APIKeyScopes are all the scopes, in the order they are stored
+var APIKeyScopes = []string{ScopePostsWrite, ScopeSubscribersRead, ScopeAnalyticsRead}
+
+// ErrInvalidAPIKey is returned for a key that is unknown, malformed or revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKeyService manages editor API keys and authenticates requests made with them
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+	logger     *slog.Logger
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository, logger *slog.Logger) *APIKeyService {
+	utils.RequireDependencies("APIKeyService",
+		utils.Dep("apiKeyRepo", apiKeyRepo),
+		utils.Dep("logger", logger),
+	)
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		logger:     logger,
+	}
+}
+
+// ListKeys returns the editor's API keys, newest first, without the keys themselves
+func (s *APIKeyService) ListKeys(ctx context.Context, editorID uuid.UUID) ([]generated.ApiKey, error) {
+	return s.apiKeyRepo.ListByEditor(ctx, editorID)
+}
+
+// CreateKey generates an API key for the editor. The returned ApiKey is the only one that
+// carries the key; only its hash is stored.
+func (s *APIKeyService) CreateKey(ctx context.Context, editorID uuid.UUID, req generated.ApiKeyCreate) (*generated.ApiKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return nil, models.NewBadRequestError("name must be 1 to 100 characters")
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := s.apiKeyRepo.CountActiveByEditor(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	if active >= maxActiveAPIKeys {
+		return nil, models.NewConflictError(fmt.Sprintf("An editor can have at most %d active API keys, revoke one first", maxActiveAPIKeys))
+	}
+
+	key, err := newSecret(apiKeyPrefix, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.apiKeyRepo.Create(ctx, editorID, name, key[:apiKeyPrefixLength], hashSecret(key), scopes)
+	if err != nil {
+		return nil, err
+	}
+	created.Key = &key
+	s.logger.InfoContext(ctx, "API key created", "editorId", editorID, "apiKeyId", created.Id, "scopes", scopes)
+	return created, nil
+}
+
+// RevokeKey revokes one of the editor's API keys
+func (s *APIKeyService) RevokeKey(ctx context.Context, editorID uuid.UUID, keyID uuid.UUID) error {
+	err := s.apiKeyRepo.Revoke(ctx, editorID, keyID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("API key not found")
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "API key revoked", "editorId", editorID, "apiKeyId", keyID)
+	return nil
+}
+
+// Authenticate returns the editor an active API key belongs to, or ErrInvalidAPIKey
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*UserContext, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	owner, err := s.apiKeyRepo.GetActiveOwner(ctx, hashSecret(key))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Best effort: a failed update must not fail the request
+	if owner.LastUsedAt == nil || time.Since(*owner.LastUsedAt) > apiKeyTouchInterval {
+		_ = s.apiKeyRepo.TouchLastUsed(ctx, owner.KeyID)
+	}
+
+	keyID := owner.KeyID
+	return &UserContext{
+		UserID:   owner.EditorID,
+		APIKeyID: &keyID,
+		Scopes:   owner.Scopes,
+	}, nil
+}
+
+// Automated reports whether the request authenticated with an API key or an integration
+// access token instead of a signed-in session
+func (u *UserContext) Automated() bool {
+	return u.APIKeyID != nil || u.IntegrationClientID != nil
+}
+
+// HasAnyScope reports whether the user may act within one of the scopes. Only scoped API keys
+// and integration tokens are limited; signed-in editors and unrestricted keys have every scope.
+func (u *UserContext) HasAnyScope(scopes ...string) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	for _, scope := range scopes {
+		if slices.Contains(u.Scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes validates requested scopes and returns them without duplicates in the order
+// of APIKeyScopes; nil when no scopes were requested, which leaves the key unrestricted
+func normalizeScopes(requested *[]string) ([]string, error) {
+	if requested == nil {
+		return nil, nil
+	}
+	if len(*requested) == 0 {
+		return nil, models.NewBadRequestError("scopes must not be empty; omit them for an unrestricted key")
+	}
+	for _, scope := range *requested {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, models.NewBadRequestError(fmt.Sprintf("Unknown scope %q, expected one of %s", scope, strings.Join(APIKeyScopes, ", ")))
+		}
+	}
+	scopes := []string{}
+	for _, scope := range APIKeyScopes {
+		if slices.Contains(*requested, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// newSecret returns prefix followed by n random bytes, base64url encoded
+func newSecret(prefix string, n int) (string, error) {
+	secret := make([]byte, n)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashSecret returns the hex SHA-256 of an API key, client secret or access token. They carry
+// 256 random bits, so no salt or slow hash is needed, and the hash can be looked up directly.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
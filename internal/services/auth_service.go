@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -12,8 +13,10 @@ import (
 
 // AuthService handles JWT validation and user authentication
 type AuthService struct {
-	jwtSecret string
-	logger    *slog.Logger
+	// jwtSecrets are tried in order: the current secret, then previous ones still accepted
+	// while Supabase rotates its secret
+	jwtSecrets []string
+	logger     *slog.Logger
 }
 
 // UserClaims represents the claims in our JWT token
@@ -31,29 +34,55 @@ type UserContext struct {
 	Email  string
 	Role   string
 	AAL    string
+	// APIKeyID is set when the request authenticated with an API key instead of a JWT
+	APIKeyID *uuid.UUID
+	// IntegrationClientID is set when the request authenticated with an access token of an
+	// integration client
+	IntegrationClientID *uuid.UUID
+	// Scopes limit what a scoped API key or integration token can do; nil for JWTs and
+	// unrestricted keys
+	Scopes []string
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(jwtSecret string, logger *slog.Logger) *AuthService {
+// NewAuthService creates a new auth service that accepts tokens signed with any of the
+// secrets, the current one first
+func NewAuthService(jwtSecrets []string, logger *slog.Logger) *AuthService {
 	return &AuthService{
-		jwtSecret: jwtSecret,
-		logger:    logger,
+		jwtSecrets: jwtSecrets,
+		logger:     logger,
 	}
 }
 
-// ValidateJWT validates a JWT token and returns user claims
+// ValidateJWT validates a JWT token and returns user claims. The token may be signed with
+// any of the configured secrets; they are tried in order.
 func (s *AuthService) ValidateJWT(tokenString string) (*UserClaims, error) {
 	// Remove "Bearer " prefix if present
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-	// Parse the token
-	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	if len(s.jwtSecrets) == 0 {
+		return nil, fmt.Errorf("failed to parse token: no JWT secret configured")
+	}
+
+	var token *jwt.Token
+	var err error
+	for i, secret := range s.jwtSecrets {
+		// Parse the token
+		token, err = jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+			// Verify the signing method
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(secret), nil
+		})
+		// Only a signature mismatch means another secret may have signed the token; an
+		// expired or malformed token is rejected whichever secret signed it
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			if err == nil && i > 0 {
+				s.logger.Debug("Token signed with a previous JWT secret", "secretIndex", i)
+			}
+			break
 		}
-		return []byte(s.jwtSecret), nil
-	})
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -96,7 +125,7 @@ func (s *AuthService) GetUserFromToken(tokenString string) (*UserContext, error)
 
 // IsAdmin checks if the user has admin role
 func (uc *UserContext) IsAdmin() bool {
-	// Note: Admin status is stored in profiles table and checked at the handler level
+	// Note: Admin status is stored in profiles table and checked by the RequireAdmin middleware
 	// This method is kept for future use if we decide to include admin status in JWT
 	return false
 }
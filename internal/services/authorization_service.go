@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// roleRanks orders the member roles; a role grants everything the lower ranked ones do
+var roleRanks = map[string]int{
+	repository.RoleViewer: 1,
+	repository.RoleEditor: 2,
+	repository.RoleOwner:  3,
+}
+
+// AuthorizationService decides what editors may do with a newsletter by their role in it:
+// viewers read, editors also write posts and manage subscribers, owners manage the newsletter
+// itself, its members and webhooks
+type AuthorizationService struct {
+	memberRepo *repository.MemberRepository
+	logger     *slog.Logger
+}
+
+func NewAuthorizationService(memberRepo *repository.MemberRepository, logger *slog.Logger) *AuthorizationService {
+	utils.RequireDependencies("AuthorizationService", utils.Dep("memberRepo", memberRepo), utils.Dep("logger", logger))
+	return &AuthorizationService{
+		memberRepo: memberRepo,
+		logger:     logger,
+	}
+}
+
+// Role returns the editor's role in the newsletter, empty when they are not a member. The
+// editor the newsletter belongs to is always its owner.
+func (s *AuthorizationService) Role(ctx context.Context, newsletter *generated.Newsletter, editorID string) (string, error) {
+	if newsletter.EditorId != nil && newsletter.EditorId.String() == editorID {
+		return repository.RoleOwner, nil
+	}
+	id, err := uuid.Parse(editorID)
+	if err != nil {
+		return "", nil
+	}
+	role, err := s.memberRepo.GetRole(ctx, uuid.UUID(*newsletter.Id), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil
+	}
+	return role, err
+}
+
+// Authorize returns a Forbidden error unless the editor's role in the newsletter grants role
+func (s *AuthorizationService) Authorize(ctx context.Context, newsletter *generated.Newsletter, editorID string, role string) error {
+	current, err := s.Role(ctx, newsletter, editorID)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		s.logger.WarnContext(ctx, "SERVICE: unauthorized access attempt",
+			"requested_editor_id", editorID,
+			"newsletter_id", newsletter.Id.String())
+		return models.NewForbiddenError("You don't have access to this newsletter")
+	}
+	if roleRanks[current] < roleRanks[role] {
+		s.logger.WarnContext(ctx, "SERVICE: newsletter member lacks the role",
+			"requested_editor_id", editorID,
+			"newsletter_id", newsletter.Id.String(),
+			"role", current,
+			"required_role", role)
+		return models.NewForbiddenError("Your role in this newsletter does not allow this, it requires the " + role + " role")
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/database"
+	"go-newsletter/internal/objectstore"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+)
+
+const (
+	// backupFormat identifies snapshot files; backupFormatVersion changes with their layout
+	backupFormat        = "go-newsletter-backup"
+	backupFormatVersion = 1
+	// restoreBatchSize is the number of rows inserted per statement during a restore
+	restoreBatchSize = 500
+	// maxBackupLine bounds one snapshot line, i.e. one row as JSON
+	maxBackupLine = 64 << 20
+)
+
+// ErrBackupsDisabled is returned when no BACKUP_URL is configured
+var ErrBackupsDisabled = errors.New("backups are disabled, set BACKUP_URL")
+
+// backupHeader is the first line of a snapshot
+type backupHeader struct {
+	Format        string    `json:"format"`
+	FormatVersion int       `json:"format_version"`
+	SchemaVersion int       `json:"schema_version"`
+	Tables        []string  `json:"tables"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// backupLine is any line after the header: a row of table T, or the trailer with End set
+// and the number of rows written, which tells a complete snapshot from a truncated one
+type backupLine struct {
+	Table string          `json:"t,omitempty"`
+	Row   json.RawMessage `json:"r,omitempty"`
+	End   bool            `json:"end,omitempty"`
+	Rows  int64           `json:"rows,omitempty"`
+}
+
+// BackupResult describes a written snapshot
+type BackupResult struct {
+	Key      string
+	Location string
+	Rows     int64
+	Bytes    int64
+}
+
+// BackupService writes snapshots of the critical tables (subscribers, posts, deliveries and
+// what they reference) to object storage as gzipped JSON lines, and restores them
+type BackupService struct {
+	backupRepo *repository.BackupRepository
+	store      objectstore.Store
+	config     config.BackupConfig
+	logger     *slog.Logger
+}
+
+// NewBackupService creates a new instance of BackupService. store is nil when backups are disabled.
+func NewBackupService(backupRepo *repository.BackupRepository, store objectstore.Store, cfg *config.Config, logger *slog.Logger) *BackupService {
+	utils.RequireDependencies("BackupService",
+		utils.Dep("backupRepo", backupRepo),
+		utils.Dep("config", cfg),
+		utils.Dep("logger", logger),
+	)
+	return &BackupService{
+		backupRepo: backupRepo,
+		store:      store,
+		config:     cfg.Backup,
+		logger:     logger,
+	}
+}
+
+// Enabled reports whether a backup location is configured
+func (s *BackupService) Enabled() bool {
+	return s.store != nil
+}
+
+// CreateSnapshot writes a snapshot now, regardless of when the last one was taken
+func (s *BackupService) CreateSnapshot(ctx context.Context) (*BackupResult, error) {
+	return s.snapshot(ctx, 0)
+}
+
+// RunScheduled writes a snapshot unless one was taken within the backup interval, e.g. by
+// another instance. It returns nil when the snapshot was skipped.
+func (s *BackupService) RunScheduled(ctx context.Context) (*BackupResult, error) {
+	// Leave slack for ticker jitter, so an instance does not skip its own next run
+	return s.snapshot(ctx, s.config.Interval-s.config.Interval/10)
+}
+
+func (s *BackupService) snapshot(ctx context.Context, minAge time.Duration) (*BackupResult, error) {
+	if !s.Enabled() {
+		return nil, ErrBackupsDisabled
+	}
+
+	key := fmt.Sprintf("newsletter-backup-%s.jsonl.gz", time.Now().UTC().Format("20060102T150405Z"))
+	id, started, err := s.backupRepo.StartBackup(ctx, key, database.SchemaVersion, minAge)
+	if err != nil || !started {
+		return nil, err
+	}
+
+	result, err := s.writeSnapshot(ctx, key)
+	if err != nil {
+		if failErr := s.backupRepo.FailBackup(context.WithoutCancel(ctx), id, err.Error()); failErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to record backup failure", "backupId", id, "error", failErr)
+		}
+		return nil, err
+	}
+	if err := s.backupRepo.FinishBackup(ctx, id, result.Rows, result.Bytes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeSnapshot exports the tables to a temporary file and uploads it, so a slow upload does
+// not hold the database snapshot open
+func (s *BackupService) writeSnapshot(ctx context.Context, key string) (*BackupResult, error) {
+	tmp, err := os.CreateTemp("", "newsletter-backup-*.jsonl.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	w := bufio.NewWriter(gz)
+	enc := json.NewEncoder(w)
+	var rows int64
+
+	err = s.backupRepo.ExportTables(ctx,
+		func(schemaVersion int) error {
+			return enc.Encode(backupHeader{
+				Format:        backupFormat,
+				FormatVersion: backupFormatVersion,
+				SchemaVersion: schemaVersion,
+				Tables:        repository.BackupTables,
+				CreatedAt:     time.Now().UTC(),
+			})
+		},
+		func(table string, data []byte) error {
+			rows++
+			return enc.Encode(backupLine{Table: table, Row: data})
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(backupLine{End: true, Rows: rows}); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, key, tmp); err != nil {
+		return nil, fmt.Errorf("upload snapshot: %w", err)
+	}
+
+	return &BackupResult{Key: key, Location: s.store.Location(key), Rows: rows, Bytes: size}, nil
+}
+
+// Restore inserts the rows of the snapshot stored under key in one transaction. Rows that
+// already exist are kept, so it fills an empty or partly lost database without overwriting
+// newer data. The snapshot must be complete and taken at the schema version of this build.
+func (s *BackupService) Restore(ctx context.Context, key string) (int64, error) {
+	if !s.Enabled() {
+		return 0, ErrBackupsDisabled
+	}
+
+	object, err := s.store.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("open snapshot %s: %w", s.store.Location(key), err)
+	}
+	defer object.Close()
+	gz, err := gzip.NewReader(object)
+	if err != nil {
+		return 0, fmt.Errorf("snapshot %s is not gzip compressed: %w", key, err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxBackupLine)
+	if !scanner.Scan() {
+		return 0, fmt.Errorf("snapshot %s is empty: %w", key, scanner.Err())
+	}
+	var header backupHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != backupFormat {
+		return 0, fmt.Errorf("%s is not a newsletter snapshot", key)
+	}
+	if header.FormatVersion != backupFormatVersion {
+		return 0, fmt.Errorf("snapshot format version %d is not supported, this build reads %d", header.FormatVersion, backupFormatVersion)
+	}
+	if header.SchemaVersion != database.SchemaVersion {
+		return 0, fmt.Errorf("snapshot was taken at migration %d, this build is for %d; restore it with a matching build", header.SchemaVersion, database.SchemaVersion)
+	}
+
+	var inserted int64
+	err = s.backupRepo.RestoreTables(ctx, func(insert repository.BackupInsertFunc) error {
+		var (
+			table string
+			batch []json.RawMessage
+			read  int64
+		)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			n, err := insert(ctx, table, batch)
+			inserted += n
+			batch = batch[:0]
+			return err
+		}
+
+		for scanner.Scan() {
+			var line backupLine
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				return fmt.Errorf("snapshot line %d: %w", read+2, err)
+			}
+			if line.End {
+				if line.Rows != read {
+					return fmt.Errorf("snapshot is corrupt: trailer counts %d rows, read %d", line.Rows, read)
+				}
+				return flush()
+			}
+			if line.Table != table || len(batch) == restoreBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+				table = line.Table
+			}
+			// The scanner reuses its buffer, so keep a copy of the row
+			batch = append(batch, append(json.RawMessage(nil), line.Row...))
+			read++
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return errors.New("snapshot is truncated, it has no trailer")
+	})
+	if err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
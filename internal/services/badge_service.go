@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-newsletter/internal/badge"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// BadgeCacheTTL is how long a rendered badge is reused before the count is queried again
+	BadgeCacheTTL = 5 * time.Minute
+	// maxCachedBadges bounds the cache; when it is full, expired badges are dropped
+	maxCachedBadges = 10000
+	badgeLabel      = "subscribers"
+	badgeColor      = "#007ec6"
+)
+
+// Badge is a rendered subscriber count badge
+type Badge struct {
+	SVG []byte
+	// ETag changes whenever the SVG does
+	ETag       string
+	renderedAt time.Time
+}
+
+// BadgeService renders the public subscriber count badges of newsletters that enabled them.
+// Badges are embedded on editors' sites, so each is cached for BadgeCacheTTL rather than
+// counting subscribers on every page view.
+type BadgeService struct {
+	newsletterService *NewsletterService
+	subscriberRepo    *repository.SubscriberRepository
+	logger            *slog.Logger
+
+	mu     sync.Mutex
+	badges map[uuid.UUID]*Badge
+}
+
+func NewBadgeService(newsletterService *NewsletterService, subscriberRepo *repository.SubscriberRepository, logger *slog.Logger) *BadgeService {
+	utils.RequireDependencies("BadgeService",
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("subscriberRepo", subscriberRepo),
+		utils.Dep("logger", logger),
+	)
+	return &BadgeService{
+		newsletterService: newsletterService,
+		subscriberRepo:    subscriberRepo,
+		logger:            logger,
+		badges:            make(map[uuid.UUID]*Badge),
+	}
+}
+
+// SubscriberBadge returns the badge with the number of subscribers posts of the newsletter
+// are sent to. Newsletters without public_badge have none and are reported as not found, so
+// the badge does not reveal which newsletters exist.
+func (s *BadgeService) SubscriberBadge(ctx context.Context, newsletterID uuid.UUID) (*Badge, error) {
+	s.mu.Lock()
+	cached, ok := s.badges[newsletterID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.renderedAt) < BadgeCacheTTL {
+		return cached, nil
+	}
+
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if newsletter.PublicBadge == nil || !*newsletter.PublicBadge {
+		s.forget(newsletterID)
+		return nil, models.NewNotFoundError("Newsletter not found")
+	}
+
+	count, err := s.subscriberRepo.CountByNewsletterID(ctx, newsletterID)
+	if err != nil {
+		return nil, err
+	}
+
+	svg := badge.Render(badgeLabel, badge.Count(count), badgeColor)
+	sum := sha256.Sum256(svg)
+	rendered := &Badge{
+		SVG:        svg,
+		ETag:       `"` + hex.EncodeToString(sum[:8]) + `"`,
+		renderedAt: time.Now(),
+	}
+	s.remember(newsletterID, rendered)
+	return rendered, nil
+}
+
+func (s *BadgeService) remember(newsletterID uuid.UUID, b *Badge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.badges) >= maxCachedBadges {
+		for id, cached := range s.badges {
+			if time.Since(cached.renderedAt) >= BadgeCacheTTL {
+				delete(s.badges, id)
+			}
+		}
+		if len(s.badges) >= maxCachedBadges {
+			return
+		}
+	}
+	s.badges[newsletterID] = b
+}
+
+func (s *BadgeService) forget(newsletterID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.badges, newsletterID)
+}
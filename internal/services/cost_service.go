@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// microsPerUnit converts between currency units and the micros costs are stored in
+const microsPerUnit = 1_000_000
+
+// CostService estimates what sending emails costs with the configured provider pricing and
+// reports it per month, newsletter and kind of email
+type CostService struct {
+	costRepo          *repository.CostRepository
+	newsletterService *NewsletterService
+	config            config.CostsConfig
+	unitCostMicros    int64
+	logger            *slog.Logger
+}
+
+func NewCostService(costRepo *repository.CostRepository, newsletterService *NewsletterService, cfg *config.Config, logger *slog.Logger) *CostService {
+	utils.RequireDependencies("CostService",
+		utils.Dep("costRepo", costRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("config", cfg),
+		utils.Dep("logger", logger),
+	)
+	return &CostService{
+		costRepo:          costRepo,
+		newsletterService: newsletterService,
+		config:            cfg.Costs,
+		unitCostMicros:    int64(math.Round(max(cfg.Costs.PricePerThousand, 0) * microsPerUnit / 1000)),
+		logger:            logger,
+	}
+}
+
+// Record stores the estimated cost of emails accepted by the provider for the newsletter.
+// Recording is best effort: a failure is logged and does not affect the send.
+func (s *CostService) Record(ctx context.Context, newsletterID uuid.UUID, postID *uuid.UUID, kind enums.EmailJobKind, emails int) {
+	if emails <= 0 {
+		return
+	}
+	err := s.costRepo.Record(context.WithoutCancel(ctx), repository.EmailCost{
+		NewsletterID:   newsletterID,
+		PostID:         postID,
+		Kind:           kind.String(),
+		Provider:       s.config.Provider,
+		Emails:         emails,
+		UnitCostMicros: s.unitCostMicros,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Email cost could not be recorded", "newsletterId", newsletterID, "kind", kind, "emails", emails)
+	}
+}
+
+// GetEditorCosts returns the costs of the editor's newsletters for period (YYYY-MM, UTC);
+// an empty period means the current month
+func (s *CostService) GetEditorCosts(ctx context.Context, editorID uuid.UUID, period string) (*generated.EmailCostSummary, error) {
+	start, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.costRepo.SummarizeByEditor(ctx, editorID, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	return s.summarize(start.Format(usagePeriodLayout), lines), nil
+}
+
+// GetNewsletterCosts checks newsletter ownership and returns the newsletter's costs for period
+func (s *CostService) GetNewsletterCosts(ctx context.Context, newsletterID uuid.UUID, editorID string, period string) (*generated.EmailCostSummary, error) {
+	start, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify newsletter ownership
+	_, err = s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleViewer)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	lines, err := s.costRepo.SummarizeByNewsletter(ctx, newsletterID, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	return s.summarize(start.Format(usagePeriodLayout), lines), nil
+}
+
+// summarize totals the cost lines overall, per kind and per newsletter, keeping their order.
+// Amounts are added up in micros and converted once, so totals match their parts.
+func (s *CostService) summarize(period string, lines []repository.CostLine) *generated.EmailCostSummary {
+	type group struct {
+		emails int64
+		micros int64
+	}
+	var total group
+	var kinds []string
+	kindTotals := make(map[string]*group)
+	// Deleted newsletters are reported together under the nil key
+	var newsletters []*uuid.UUID
+	newsletterNames := make(map[uuid.UUID]*string)
+	newsletterTotals := make(map[uuid.UUID]*group)
+
+	for _, line := range lines {
+		total.emails += line.Emails
+		total.micros += line.CostMicros
+
+		if _, ok := kindTotals[line.Kind]; !ok {
+			kinds = append(kinds, line.Kind)
+			kindTotals[line.Kind] = &group{}
+		}
+		kindTotals[line.Kind].emails += line.Emails
+		kindTotals[line.Kind].micros += line.CostMicros
+
+		key := uuid.Nil
+		if line.NewsletterID != nil {
+			key = *line.NewsletterID
+		}
+		if _, ok := newsletterTotals[key]; !ok {
+			newsletters = append(newsletters, line.NewsletterID)
+			newsletterNames[key] = line.NewsletterName
+			newsletterTotals[key] = &group{}
+		}
+		newsletterTotals[key].emails += line.Emails
+		newsletterTotals[key].micros += line.CostMicros
+	}
+
+	byKind := make([]generated.EmailCostLine, 0, len(kinds))
+	for _, kind := range kinds {
+		t := kindTotals[kind]
+		byKind = append(byKind, generated.EmailCostLine{
+			Kind:   &kind,
+			Emails: &t.emails,
+			Cost:   costFromMicros(t.micros),
+		})
+	}
+
+	byNewsletter := make([]generated.NewsletterEmailCost, 0, len(newsletters))
+	for _, id := range newsletters {
+		key := uuid.Nil
+		if id != nil {
+			key = *id
+		}
+		t := newsletterTotals[key]
+		byNewsletter = append(byNewsletter, generated.NewsletterEmailCost{
+			NewsletterId:   id,
+			NewsletterName: newsletterNames[key],
+			Emails:         &t.emails,
+			Cost:           costFromMicros(t.micros),
+		})
+	}
+
+	currency := s.config.Currency
+	return &generated.EmailCostSummary{
+		Period:       &period,
+		Currency:     &currency,
+		Emails:       &total.emails,
+		Cost:         costFromMicros(total.micros),
+		ByKind:       &byKind,
+		ByNewsletter: &byNewsletter,
+	}
+}
+
+// costFromMicros converts a stored amount to currency units
+func costFromMicros(micros int64) *float64 {
+	cost := float64(micros) / microsPerUnit
+	return &cost
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxCouponDurationDays = 3650
+	maxTrialDays          = 365
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,64}$`)
+
+// CouponService issues coupons, redeems them for editors and manages trials. Redeemed
+// coupons and trials become plan grants, which PlanService takes into account for quotas.
+type CouponService struct {
+	couponRepo  *repository.CouponRepository
+	planService *PlanService
+	logger      *slog.Logger
+}
+
+func NewCouponService(couponRepo *repository.CouponRepository, planService *PlanService, logger *slog.Logger) *CouponService {
+	utils.RequireDependencies("CouponService",
+		utils.Dep("couponRepo", couponRepo),
+		utils.Dep("planService", planService),
+		utils.Dep("logger", logger),
+	)
+	return &CouponService{
+		couponRepo:  couponRepo,
+		planService: planService,
+		logger:      logger,
+	}
+}
+
+// ListCoupons returns a page of coupons, newest first
+func (s *CouponService) ListCoupons(ctx context.Context, page pagination.Page) ([]generated.Coupon, *pagination.Cursor, error) {
+	return s.couponRepo.List(ctx, page)
+}
+
+// CreateCoupon issues a coupon on behalf of an admin
+func (s *CouponService) CreateCoupon(ctx context.Context, adminID uuid.UUID, req generated.CouponCreate) (*generated.Coupon, error) {
+	code := normalizeCouponCode(req.Code)
+	if !couponCodePattern.MatchString(code) {
+		return nil, models.NewBadRequestError("code must be 3 to 64 letters, digits, '-' or '_'")
+	}
+	if req.DurationDays < 1 || req.DurationDays > maxCouponDurationDays {
+		return nil, models.NewBadRequestError("duration_days must be between 1 and 3650")
+	}
+	if req.MaxRedemptions != nil && *req.MaxRedemptions < 1 {
+		return nil, models.NewBadRequestError("max_redemptions must be at least 1")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, models.NewBadRequestError("expires_at must be in the future")
+	}
+	if _, err := s.planService.GetPlan(ctx, req.PlanId); err != nil {
+		return nil, err
+	}
+
+	coupon, err := s.couponRepo.Create(ctx, repository.NewCoupon{
+		Code:           code,
+		PlanID:         req.PlanId,
+		DurationDays:   req.DurationDays,
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+		CreatedBy:      adminID,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, models.NewConflictError("A coupon with this code already exists")
+		}
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Coupon created", "code", code, "planId", req.PlanId, "adminId", adminID)
+	return coupon, nil
+}
+
+// RedeemCoupon grants the coupon's plan to the editor and returns the resulting plan usage
+func (s *CouponService) RedeemCoupon(ctx context.Context, editorID uuid.UUID, code string) (*generated.PlanUsage, error) {
+	code = normalizeCouponCode(code)
+	if code == "" {
+		return nil, models.NewBadRequestError("code is required")
+	}
+
+	grant, err := s.couponRepo.Redeem(ctx, editorID, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, models.NewNotFoundError("Coupon not found")
+		case errors.Is(err, repository.ErrCouponExpired):
+			return nil, models.NewConflictError("This coupon has expired")
+		case errors.Is(err, repository.ErrCouponExhausted):
+			return nil, models.NewConflictError("This coupon has been fully redeemed")
+		case errors.Is(err, repository.ErrAlreadyExists):
+			return nil, models.NewConflictError("You have already redeemed this coupon")
+		}
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Coupon redeemed", "code", code, "editorId", editorID, "planId", *grant.PlanId, "endsAt", *grant.EndsAt)
+
+	return s.planService.GetPlanUsage(ctx, editorID)
+}
+
+// ExtendTrial extends the editor's trial of a plan, or starts one, on behalf of an admin
+func (s *CouponService) ExtendTrial(ctx context.Context, adminID uuid.UUID, editorID uuid.UUID, req generated.TrialExtension) (*generated.PlanUsage, error) {
+	if req.Days < 1 || req.Days > maxTrialDays {
+		return nil, models.NewBadRequestError("days must be between 1 and 365")
+	}
+	if _, err := s.planService.GetPlan(ctx, req.PlanId); err != nil {
+		return nil, err
+	}
+
+	grant, err := s.couponRepo.ExtendTrial(ctx, editorID, req.PlanId, req.Days, adminID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.NewNotFoundError("Profile not found")
+		}
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Trial extended", "editorId", editorID, "planId", req.PlanId, "days", req.Days, "endsAt", *grant.EndsAt, "adminId", adminID)
+
+	return s.planService.GetPlanUsage(ctx, editorID)
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"log/slog"
+	"strings"
+
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/lint"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+)
+
+// DeliverabilityService checks post emails against the deliverability rules (LINT_RULES_FILE)
+// and, with LINT_BLOCK_SEVERITY set, refuses to send posts with serious issues
+type DeliverabilityService struct {
+	linter *lint.Linter
+	// blockAt is nil when issues never block a send
+	blockAt *lint.Severity
+	logger  *slog.Logger
+}
+
+func NewDeliverabilityService(linter *lint.Linter, blockAt *lint.Severity, logger *slog.Logger) *DeliverabilityService {
+	utils.RequireDependencies("DeliverabilityService",
+		utils.Dep("linter", linter),
+		utils.Dep("logger", logger),
+	)
+	return &DeliverabilityService{
+		linter:  linter,
+		blockAt: blockAt,
+		logger:  logger,
+	}
+}
+
+// Check renders a post email and reports its issues
+func (s *DeliverabilityService) Check(email emailrender.PostEmail) (*generated.PreflightReport, error) {
+	findings, err := s.check(email)
+	if err != nil {
+		return nil, err
+	}
+	report := &generated.PreflightReport{
+		Issues:  make([]generated.DeliverabilityIssue, 0, len(findings)),
+		Blocked: s.blocks(findings),
+	}
+	if s.blockAt != nil {
+		blockSeverity := generated.DeliverabilitySeverity(s.blockAt.String())
+		report.BlockSeverity = &blockSeverity
+	}
+	for _, finding := range findings {
+		report.Issues = append(report.Issues, generated.DeliverabilityIssue{
+			Rule:     finding.Rule,
+			Severity: generated.DeliverabilitySeverity(finding.Severity.String()),
+			Message:  finding.Message,
+		})
+	}
+	return report, nil
+}
+
+// CheckSend returns an error listing the blocking issues of a post email, or nil when it may be sent
+func (s *DeliverabilityService) CheckSend(email emailrender.PostEmail) error {
+	findings, err := s.check(email)
+	if err != nil {
+		return err
+	}
+	if !s.blocks(findings) {
+		return nil
+	}
+	var messages []string
+	for _, finding := range findings {
+		if finding.Severity >= *s.blockAt {
+			messages = append(messages, finding.Message)
+		}
+	}
+	return models.NewUnprocessableError("deliverability", "The post fails deliverability checks: "+strings.Join(messages, "; "))
+}
+
+func (s *DeliverabilityService) check(email emailrender.PostEmail) ([]lint.Finding, error) {
+	rendered, err := emailrender.RenderPost(email)
+	if err != nil {
+		return nil, templateOutputError(err)
+	}
+	return s.linter.Check(lint.Email{Subject: rendered.Subject, HTML: rendered.HTML}), nil
+}
+
+func (s *DeliverabilityService) blocks(findings []lint.Finding) bool {
+	return s.blockAt != nil && lint.Blocks(findings, *s.blockAt)
+}
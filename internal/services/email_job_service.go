@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/internal/logging"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// EmailJobService records failed email deliveries and lets admins inspect and retry them
+type EmailJobService struct {
+	jobRepo         *repository.EmailJobRepository
+	mailingService  *MailingService
+	incidentService *IncidentService
+	costService     *CostService
+	logger          *slog.Logger
+}
+
+func NewEmailJobService(jobRepo *repository.EmailJobRepository, mailingService *MailingService, incidentService *IncidentService, costService *CostService, logger *slog.Logger) *EmailJobService {
+	utils.RequireDependencies("EmailJobService",
+		utils.Dep("jobRepo", jobRepo),
+		utils.Dep("mailingService", mailingService),
+		utils.Dep("incidentService", incidentService),
+		utils.Dep("costService", costService),
+		utils.Dep("logger", logger),
+	)
+	return &EmailJobService{
+		jobRepo:         jobRepo,
+		mailingService:  mailingService,
+		incidentService: incidentService,
+		costService:     costService,
+		logger:          logger,
+	}
+}
+
+// RecordPostFailures stores the emails of a post dispatch that could not be delivered.
+// Recording is best effort: a failure is logged and does not affect the publish outcome.
+func (s *EmailJobService) RecordPostFailures(ctx context.Context, post *generated.PublishedPost, emails []OutgoingEmail, result DispatchResult) {
+	if len(result.Errors) == 0 {
+		return
+	}
+
+	byRecipient := make(map[string]OutgoingEmail, len(emails))
+	for _, email := range emails {
+		byRecipient[email.To] = email
+	}
+
+	postID := uuid.UUID(*post.Id)
+	jobs := make([]repository.NewEmailJob, 0, len(result.Errors))
+	for _, failure := range result.Errors {
+		email := byRecipient[failure.Recipient]
+		jobs = append(jobs, repository.NewEmailJob{
+			Kind:            enums.PostEmail.String(),
+			NewsletterID:    uuid.UUID(*post.NewsletterId),
+			PostID:          &postID,
+			Recipient:       failure.Recipient,
+			Subject:         email.Subject,
+			HTML:            email.HTML,
+			CorrelationID:   utils.CorrelationID(ctx),
+			ListUnsubscribe: email.ListUnsubscribe,
+			Error:           failure.Err.Error(),
+			Attempts:        failure.Attempts,
+		})
+	}
+
+	if err := s.jobRepo.RecordFailures(context.WithoutCancel(ctx), jobs); err != nil {
+		s.logger.ErrorContext(ctx, "Failed email deliveries could not be recorded for retry", "postId", postID, "count", len(jobs))
+	}
+}
+
+// RecordConfirmationFailure stores a subscription confirmation email that could not be delivered
+func (s *EmailJobService) RecordConfirmationFailure(ctx context.Context, newsletterID uuid.UUID, email OutgoingEmail, sendErr error) {
+	err := s.jobRepo.RecordFailures(context.WithoutCancel(ctx), []repository.NewEmailJob{{
+		Kind:          enums.ConfirmationEmail.String(),
+		NewsletterID:  newsletterID,
+		Recipient:     email.To,
+		Subject:       email.Subject,
+		HTML:          email.HTML,
+		CorrelationID: utils.CorrelationID(ctx),
+		Error:         sendErr.Error(),
+		Attempts:      1,
+	}})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed confirmation email could not be recorded for retry", "newsletterId", newsletterID)
+	}
+}
+
+// ListJobs returns a page of jobs in the given status (failed by default) with tokens redacted
+func (s *EmailJobService) ListJobs(ctx context.Context, params generated.GetAdminJobsParams, page pagination.Page) ([]generated.EmailJob, *pagination.Cursor, error) {
+	status := generated.Failed
+	if params.Status != nil {
+		status = *params.Status
+	}
+	if status != generated.Failed && status != generated.Succeeded {
+		return nil, nil, models.NewBadRequestError("status must be failed or succeeded")
+	}
+
+	jobs, next, err := s.jobRepo.List(ctx, status, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jobs {
+		redactEmailJob(&jobs[i])
+	}
+	return jobs, next, nil
+}
+
+// RetryJob sends the stored email of a failed job again and records the outcome
+func (s *EmailJobService) RetryJob(ctx context.Context, jobID uuid.UUID) (*generated.EmailJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.NewNotFoundError("Job not found")
+		}
+		s.logger.ErrorContext(ctx, "Failed to get email job", "jobId", jobID, "error", err)
+		return nil, err
+	}
+	if job.Status != nil && *job.Status == generated.Succeeded {
+		return nil, models.NewConflictError("Job was already delivered")
+	}
+
+	listUnsubscribe, err := s.jobRepo.GetListUnsubscribe(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	sendError := ""
+	email := OutgoingEmail{To: string(*job.Recipient), Subject: *job.Subject, HTML: *job.Html, ListUnsubscribe: listUnsubscribe}
+	if err := s.mailingService.SendEmail(ctx, email); err != nil {
+		sendError = err.Error()
+	}
+
+	job, err = s.jobRepo.RecordAttempt(ctx, jobID, sendError)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Retried email job", "jobId", jobID, "status", *job.Status, "attempts", *job.Attempts)
+	if sendError != "" {
+		s.incidentService.ReportRecipientFailure(ctx, job)
+	} else {
+		s.costService.Record(ctx, uuid.UUID(*job.NewsletterId), job.PostId, enums.EmailJobKind(*job.Kind), 1)
+	}
+
+	redactEmailJob(job)
+	return job, nil
+}
+
+// CountPendingRetries counts the post's failed emails not yet delivered by a retry
+func (s *EmailJobService) CountPendingRetries(ctx context.Context, postID uuid.UUID) (int, error) {
+	return s.jobRepo.CountFailedByPost(ctx, postID)
+}
+
+func redactEmailJob(job *generated.EmailJob) {
+	if job.Html != nil {
+		redacted := logging.RedactTokenURLs(*job.Html)
+		job.Html = &redacted
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// EmailTemplateService manages the email templates of newsletters and loads them for sending
+type EmailTemplateService struct {
+	templateRepo      *repository.EmailTemplateRepository
+	newsletterService *NewsletterService
+	logger            *slog.Logger
+}
+
+func NewEmailTemplateService(templateRepo *repository.EmailTemplateRepository, newsletterService *NewsletterService, logger *slog.Logger) *EmailTemplateService {
+	utils.RequireDependencies("EmailTemplateService",
+		utils.Dep("templateRepo", templateRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("logger", logger),
+	)
+	return &EmailTemplateService{
+		templateRepo:      templateRepo,
+		newsletterService: newsletterService,
+		logger:            logger,
+	}
+}
+
+// ListTemplates returns the template of every kind for a newsletter of the editor, its own
+// where it has one and the default one otherwise
+func (s *EmailTemplateService) ListTemplates(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID) ([]generated.EmailTemplate, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleViewer); err != nil {
+		return nil, err
+	}
+	custom, err := s.templateRepo.ListByNewsletter(ctx, newsletterID)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]generated.EmailTemplate, 0, len(emailrender.Kinds))
+	for _, kind := range emailrender.Kinds {
+		template := defaultEmailTemplate(kind)
+		for _, own := range custom {
+			if own.Kind == generated.EmailTemplateKind(kind) {
+				template = own
+			}
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// GetTemplate returns the template of the kind for a newsletter of the editor
+func (s *EmailTemplateService) GetTemplate(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, kind emailrender.Kind) (*generated.EmailTemplate, error) {
+	if !emailrender.ValidKind(kind) {
+		return nil, models.NewBadRequestError("Unknown email template kind")
+	}
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleViewer); err != nil {
+		return nil, err
+	}
+	template, err := s.templateRepo.Get(ctx, newsletterID, string(kind))
+	if errors.Is(err, repository.ErrNotFound) {
+		template := defaultEmailTemplate(kind)
+		return &template, nil
+	}
+	return template, err
+}
+
+// SetTemplate validates and saves the template of the kind for a newsletter of the editor
+func (s *EmailTemplateService) SetTemplate(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, kind emailrender.Kind, req generated.EmailTemplateUpdate) (*generated.EmailTemplate, error) {
+	if !emailrender.ValidKind(kind) {
+		return nil, models.NewBadRequestError("Unknown email template kind")
+	}
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleEditor); err != nil {
+		return nil, err
+	}
+	if _, err := emailrender.ParseTemplate(kind, req.Html); err != nil {
+		return nil, models.NewBadRequestError("Invalid email template: " + err.Error())
+	}
+
+	template, err := s.templateRepo.Upsert(ctx, newsletterID, string(kind), req.Html)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Email template set", "newsletterId", newsletterID, "kind", kind)
+	return template, nil
+}
+
+// ResetTemplate deletes the newsletter's own template of the kind, so the default one is used again
+func (s *EmailTemplateService) ResetTemplate(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, kind emailrender.Kind) error {
+	if !emailrender.ValidKind(kind) {
+		return models.NewBadRequestError("Unknown email template kind")
+	}
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleEditor); err != nil {
+		return err
+	}
+	err := s.templateRepo.Delete(ctx, newsletterID, string(kind))
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("The newsletter has no template of this kind")
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Email template reset", "newsletterId", newsletterID, "kind", kind)
+	return nil
+}
+
+// Template returns the newsletter's parsed template of the kind for sending, or nil for the
+// default one. A stored template that no longer parses falls back to the default one, so
+// emails are still sent.
+func (s *EmailTemplateService) Template(ctx context.Context, newsletterID uuid.UUID, kind emailrender.Kind) (*emailrender.Template, error) {
+	stored, err := s.templateRepo.Get(ctx, newsletterID, string(kind))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	template, err := emailrender.ParseTemplate(kind, stored.Html)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Stored email template is invalid, using the default one", "newsletterId", newsletterID, "kind", kind, "error", err)
+		return nil, nil
+	}
+	return template, nil
+}
+
+// templateOutputError reports a custom template that cannot render an email as an error for
+// the editor, who has to fix or reset the template before the email can be sent
+func templateOutputError(err error) error {
+	if errors.Is(err, emailrender.ErrTemplateOutput) {
+		return models.NewUnprocessableError("email_template", "Fix or reset the newsletter's email template: "+err.Error())
+	}
+	return err
+}
+
+// ListVariables returns the variables templates of the kind can use, or of every kind when kind
+// is empty, for a newsletter of the editor
+func (s *EmailTemplateService) ListVariables(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, kind emailrender.Kind) ([]generated.TemplateVariable, error) {
+	kinds := emailrender.Kinds
+	if kind != "" {
+		if !emailrender.ValidKind(kind) {
+			return nil, models.NewBadRequestError("Unknown email template kind")
+		}
+		kinds = []emailrender.Kind{kind}
+	}
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	variables := []generated.TemplateVariable{}
+	for _, kind := range kinds {
+		for _, v := range emailrender.Variables(kind) {
+			variables = append(variables, generated.TemplateVariable{
+				Kind:        generated.EmailTemplateKind(v.Kind),
+				Name:        v.Name,
+				Tag:         v.Tag(),
+				Description: v.Description,
+				Sample:      v.Sample,
+				Required:    v.Required,
+			})
+		}
+	}
+	return variables, nil
+}
+
+func defaultEmailTemplate(kind emailrender.Kind) generated.EmailTemplate {
+	custom := false
+	return generated.EmailTemplate{
+		Kind:   generated.EmailTemplateKind(kind),
+		Html:   emailrender.DefaultSource(kind),
+		Custom: &custom,
+	}
+}
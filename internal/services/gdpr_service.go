@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+)
+
+// gdprPurpose separates data request signatures from other uses of the same secret
+const gdprPurpose = "gdpr:"
+
+// gdprTokenTTL is how long a verification token emailed for a data request is valid
+const gdprTokenTTL = time.Hour
+
+// GDPRService answers data subject requests of subscribers: exporting or erasing what is stored
+// about an address across all newsletters. Requests carry a token emailed to the address, signed
+// like unsubscribe-all links, so only someone reading the address's mail can make them.
+type GDPRService struct {
+	gdprRepo       *repository.GDPRRepository
+	mailingService *MailingService
+	secret         []byte
+	logger         *slog.Logger
+}
+
+func NewGDPRService(gdprRepo *repository.GDPRRepository, mailingService *MailingService, config *config.Config, logger *slog.Logger) *GDPRService {
+	utils.RequireDependencies("GDPRService",
+		utils.Dep("gdprRepo", gdprRepo),
+		utils.Dep("mailingService", mailingService),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &GDPRService{
+		gdprRepo:       gdprRepo,
+		mailingService: mailingService,
+		secret:         []byte(config.Security.UnsubscribeSecret),
+		logger:         logger,
+	}
+}
+
+// RequestVerification emails a verification token to the address when anything is stored about
+// it. Unknown addresses get no email and no error, so the response does not reveal subscribers.
+func (s *GDPRService) RequestVerification(ctx context.Context, email string) error {
+	found, err := s.gdprRepo.HasData(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !found {
+		s.logger.InfoContext(ctx, "Data request verification not sent, no data stored for the address")
+		return nil
+	}
+
+	expiresAt := time.Now().Add(gdprTokenTTL)
+	body := fmt.Sprintf(`
+		<h1>Your subscriber data</h1>
+		<p>Someone asked to export or erase the data of this address across all newsletters it subscribed to.</p>
+		<p>Your verification token is <code>%s</code>; send it with your request. It expires on %s.</p>
+		<p>If you did not ask for this, you can ignore this email.</p>
+	`,
+		html.EscapeString(s.token(email, expiresAt)),
+		html.EscapeString(expiresAt.UTC().Format("2 January 2006 15:04 MST")),
+	)
+	// A failed send is only logged; an error would tell the address apart from unknown ones
+	if err := s.mailingService.SendMail(ctx, email, "Verify your subscriber data request", body); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to send data request verification", "error", err)
+	}
+	return nil
+}
+
+// Export verifies the token, then returns everything stored about the address
+func (s *GDPRService) Export(ctx context.Context, req generated.SubscriberDataRequest) (*generated.SubscriberDataExport, error) {
+	email := string(req.Email)
+	if !s.verify(email, req.Token) {
+		return nil, models.NewForbiddenError("Invalid or expired verification token")
+	}
+
+	export, err := s.gdprRepo.Export(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	export.ExportedAt = time.Now().UTC()
+	s.logger.InfoContext(ctx, "Subscriber data exported", "subscriptions", len(export.Subscriptions))
+	return export, nil
+}
+
+// Erase verifies the token, then deletes everything stored about the address. Erasing an
+// address again finds nothing and is not an error.
+func (s *GDPRService) Erase(ctx context.Context, req generated.SubscriberDataRequest) (*generated.SubscriberErasure, error) {
+	email := string(req.Email)
+	if !s.verify(email, req.Token) {
+		return nil, models.NewForbiddenError("Invalid or expired verification token")
+	}
+
+	erased, err := s.gdprRepo.Erase(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Subscriber data erased",
+		"subscriptions", erased.Subscriptions,
+		"emailChanges", erased.EmailChanges,
+		"suppressions", erased.Suppressions,
+		"emails", erased.Emails,
+		"webhookDeliveries", erased.WebhookDeliveries,
+		"deliveryReports", erased.DeliveryReports,
+	)
+	return erased, nil
+}
+
+// token is the expiry in Unix seconds "." base64url(HMAC-SHA256(email and expiry)), with the
+// email lower-cased
+func (s *GDPRService) token(email string, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(s.sign(email, expiry))
+}
+
+func (s *GDPRService) verify(email string, token string) bool {
+	expiry, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sig, s.sign(email, expiry))
+}
+
+func (s *GDPRService) sign(email string, expiry string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(gdprPurpose + strings.ToLower(email) + ":" + expiry))
+	return mac.Sum(nil)
+}
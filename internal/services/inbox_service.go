@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of inbox notifications
+const (
+	InboxPostPublished     = "post_published"
+	InboxDeliveryIncident  = "delivery_incident"
+	InboxAdminNotification = "admin_notification"
+)
+
+// InboxService keeps the in-app notifications of editors. Services add to it where they
+// already tell editors about events, the same way they notify webhooks.
+type InboxService struct {
+	inboxRepo *repository.InboxRepository
+	config    *config.Config
+	logger    *slog.Logger
+}
+
+func NewInboxService(inboxRepo *repository.InboxRepository, config *config.Config, logger *slog.Logger) *InboxService {
+	utils.RequireDependencies("InboxService",
+		utils.Dep("inboxRepo", inboxRepo),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &InboxService{
+		inboxRepo: inboxRepo,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// ListNotifications returns a page of the editor's notifications, newest first
+func (s *InboxService) ListNotifications(ctx context.Context, editorID uuid.UUID, unreadOnly bool, page pagination.Page) ([]generated.InboxNotification, *pagination.Cursor, error) {
+	return s.inboxRepo.ListByEditor(ctx, editorID, unreadOnly, page)
+}
+
+// UnreadCount returns how many of the editor's notifications are unread
+func (s *InboxService) UnreadCount(ctx context.Context, editorID uuid.UUID) (*generated.UnreadNotificationCount, error) {
+	count, err := s.inboxRepo.CountUnread(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	return &generated.UnreadNotificationCount{Count: count}, nil
+}
+
+// MarkRead marks a notification of the editor as read
+func (s *InboxService) MarkRead(ctx context.Context, editorID uuid.UUID, notificationID uuid.UUID) error {
+	err := s.inboxRepo.MarkRead(ctx, editorID, notificationID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("Notification not found")
+	}
+	return err
+}
+
+// MarkAllRead marks every notification of the editor as read
+func (s *InboxService) MarkAllRead(ctx context.Context, editorID uuid.UUID) error {
+	_, err := s.inboxRepo.MarkAllRead(ctx, editorID)
+	return err
+}
+
+// PostPublished tells the editor that the emails of a post were queued
+func (s *InboxService) PostPublished(ctx context.Context, post *generated.PublishedPost, recipients int) {
+	postID := uuid.UUID(*post.Id)
+	s.add(ctx, repository.NewInboxNotification{
+		NewsletterID: uuid.UUID(*post.NewsletterId),
+		PostID:       &postID,
+		Kind:         InboxPostPublished,
+		Title:        fmt.Sprintf("%q was published", post.Title),
+		Body:         fmt.Sprintf("The post is being sent to %d subscribers.", recipients),
+	})
+}
+
+// DeliveryIncident tells the editor that emails of the newsletter could not be delivered;
+// postID is nil when the failed email was not a post
+func (s *InboxService) DeliveryIncident(ctx context.Context, newsletterID uuid.UUID, postID *uuid.UUID, message string) {
+	s.add(ctx, repository.NewInboxNotification{
+		NewsletterID: newsletterID,
+		PostID:       postID,
+		Kind:         InboxDeliveryIncident,
+		Title:        "Some newsletter emails could not be delivered",
+		Body:         message,
+	})
+}
+
+// AdminNotification tells the editor that administrators are emailing the newsletter's subscribers
+func (s *InboxService) AdminNotification(ctx context.Context, newsletterID uuid.UUID, notification *generated.SubscriberNotification) {
+	s.add(ctx, repository.NewInboxNotification{
+		NewsletterID: newsletterID,
+		Kind:         InboxAdminNotification,
+		Title:        "An administrator is emailing your subscribers",
+		Body: fmt.Sprintf("%d subscribers will receive %q on %s. Reason: %s",
+			*notification.RecipientCount,
+			*notification.Subject,
+			notification.ScheduledAt.UTC().Format("2006-01-02 15:04 MST"),
+			*notification.Reason,
+		),
+	})
+}
+
+// PurgeOld deletes notifications older than RETENTION_NOTIFICATION_DAYS in batches and returns
+// how many were deleted. Zero days keeps them; in dry-run mode nothing is deleted.
+func (s *InboxService) PurgeOld(ctx context.Context) (int64, error) {
+	days := s.config.Retention.NotificationDays
+	if days <= 0 || s.config.Retention.DryRun {
+		return 0, nil
+	}
+
+	var deleted int64
+	for {
+		n, err := s.inboxRepo.DeleteOlderThan(ctx, days, s.config.Retention.BatchSize)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if n < int64(s.config.Retention.BatchSize) || ctx.Err() != nil {
+			return deleted, nil
+		}
+	}
+}
+
+// add stores a notification; like webhook events it is best effort and never fails the
+// operation that raised it
+func (s *InboxService) add(ctx context.Context, notification repository.NewInboxNotification) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.inboxRepo.CreateForNewsletter(ctx, notification); err != nil {
+		s.logger.WarnContext(ctx, "Failed to add inbox notification", "newsletterId", notification.NewsletterID, "kind", notification.Kind, "error", err)
+	}
+}
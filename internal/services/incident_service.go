@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+
+	"go-newsletter/internal/alerting"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// IncidentService turns permanently failed deliveries into incident records, tells the
+// newsletter's editor and alerts admins when a failure looks systemic
+type IncidentService struct {
+	incidentRepo    *repository.IncidentRepository
+	mailingService  *MailingService
+	inboxService    *InboxService
+	notifier        alerting.Notifier
+	systemicPercent int
+	logger          *slog.Logger
+}
+
+func NewIncidentService(
+	incidentRepo *repository.IncidentRepository,
+	mailingService *MailingService,
+	inboxService *InboxService,
+	notifier alerting.Notifier,
+	config *config.Config,
+	logger *slog.Logger,
+) *IncidentService {
+	utils.RequireDependencies("IncidentService",
+		utils.Dep("incidentRepo", incidentRepo),
+		utils.Dep("mailingService", mailingService),
+		utils.Dep("inboxService", inboxService),
+		utils.Dep("notifier", notifier),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &IncidentService{
+		incidentRepo:    incidentRepo,
+		mailingService:  mailingService,
+		inboxService:    inboxService,
+		notifier:        notifier,
+		systemicPercent: config.Mailing.SystemicFailurePercent,
+		logger:          logger,
+	}
+}
+
+// ReportPostDelivery raises a post incident when recipients of a dispatch still failed after
+// all retries. Reporting is best effort and never fails the publish.
+func (s *IncidentService) ReportPostDelivery(ctx context.Context, post *generated.PublishedPost, result DispatchResult) {
+	if result.Failed == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	total := result.Sent + result.Failed
+	systemic := result.Failed*100 >= s.systemicPercent*total
+	postID := uuid.UUID(*post.Id)
+	newsletterID := uuid.UUID(*post.NewsletterId)
+
+	message := fmt.Sprintf("%d of %d emails for %q could not be delivered after %d attempts", result.Failed, total, post.Title, s.mailingService.MaxSendAttempts())
+	incident, err := s.incidentRepo.Create(ctx, repository.NewIncident{
+		NewsletterID: newsletterID,
+		PostID:       &postID,
+		Scope:        generated.IncidentScopePost,
+		Systemic:     systemic,
+		FailedCount:  result.Failed,
+		Message:      message,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record delivery incident", "postId", postID, "error", err)
+		return
+	}
+	s.logger.WarnContext(ctx, "Delivery incident raised", "incidentId", *incident.Id, "postId", postID, "failed", result.Failed, "systemic", systemic)
+
+	if systemic {
+		details := map[string]string{
+			"incident_id":   incident.Id.String(),
+			"newsletter_id": newsletterID.String(),
+			"post_id":       postID.String(),
+			"failed":        fmt.Sprint(result.Failed),
+			"total":         fmt.Sprint(total),
+		}
+		if len(result.Errors) > 0 {
+			details["first_error"] = result.Errors[0].Err.Error()
+		}
+		s.notifier.Notify(ctx, alerting.Alert{
+			Source:    "mailing",
+			Message:   "Systemic email delivery failure",
+			RequestID: utils.CorrelationID(ctx),
+			Details:   details,
+		})
+	}
+
+	s.notifyEditor(ctx, newsletterID, &postID, message+". Failed emails can be retried by an administrator.")
+}
+
+// ReportRecipientFailure raises a recipient incident for an email job that failed again on retry
+func (s *IncidentService) ReportRecipientFailure(ctx context.Context, job *generated.EmailJob) {
+	ctx = context.WithoutCancel(ctx)
+
+	jobID := uuid.UUID(*job.Id)
+	newsletterID := uuid.UUID(*job.NewsletterId)
+	var postID *uuid.UUID
+	if job.PostId != nil {
+		id := uuid.UUID(*job.PostId)
+		postID = &id
+	}
+
+	message := fmt.Sprintf("Email %q to %s could not be delivered after %d attempts", *job.Subject, *job.Recipient, *job.Attempts)
+	if _, err := s.incidentRepo.Create(ctx, repository.NewIncident{
+		NewsletterID: newsletterID,
+		PostID:       postID,
+		EmailJobID:   &jobID,
+		Scope:        generated.IncidentScopeRecipient,
+		FailedCount:  1,
+		Message:      message,
+	}); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record recipient incident", "jobId", jobID, "error", err)
+		return
+	}
+
+	s.notifyEditor(ctx, newsletterID, postID, message+".")
+}
+
+// ListByPost returns the incidents raised for a post
+func (s *IncidentService) ListByPost(ctx context.Context, postID uuid.UUID) ([]generated.Incident, error) {
+	return s.incidentRepo.ListByPost(ctx, postID)
+}
+
+func (s *IncidentService) notifyEditor(ctx context.Context, newsletterID uuid.UUID, postID *uuid.UUID, message string) {
+	s.inboxService.DeliveryIncident(ctx, newsletterID, postID, message)
+
+	editorEmail, err := s.incidentRepo.NewsletterEditorEmail(ctx, newsletterID)
+	if err != nil {
+		return
+	}
+
+	body := fmt.Sprintf(`
+		<h1>Email delivery problem</h1>
+		<p>%s</p>
+		<p>You can see the delivery stats of the post in the newsletter dashboard.</p>
+	`, html.EscapeString(message))
+	if err := s.mailingService.SendMail(ctx, editorEmail, "Some newsletter emails could not be delivered", body); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to notify editor about delivery incident", "newsletterId", newsletterID, "error", err)
+	}
+}
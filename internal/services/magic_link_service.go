@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+)
+
+// MagicLinkService signs editors in without a password: Supabase Auth emails them a link, and a
+// one-time code, that is exchanged for a session here. Unknown addresses get an account, so the
+// same flow signs new editors up.
+type MagicLinkService struct {
+	supabase *supabaseAuth
+	config   *config.Config
+	logger   *slog.Logger
+}
+
+func NewMagicLinkService(authService *AuthService, profileService *ProfileService, securityEventService *SecurityEventService, httpClient *http.Client, config *config.Config, logger *slog.Logger) *MagicLinkService {
+	utils.RequireDependencies("MagicLinkService",
+		utils.Dep("authService", authService),
+		utils.Dep("profileService", profileService),
+		utils.Dep("securityEventService", securityEventService),
+		utils.Dep("httpClient", httpClient),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &MagicLinkService{
+		supabase: &supabaseAuth{
+			authService:    authService,
+			profileService: profileService,
+			securityEvents: securityEventService,
+			httpClient:     httpClient,
+			config:         config,
+			logger:         logger,
+		},
+		config: config,
+		logger: logger,
+	}
+}
+
+// Send has Supabase Auth email a magic link to the address
+func (s *MagicLinkService) Send(ctx context.Context, req generated.MagicLinkRequest) error {
+	email := strings.TrimSpace(string(req.Email))
+	if email == "" {
+		return models.NewBadRequestError("email is required")
+	}
+
+	path := "/auth/v1/otp"
+	if redirect := s.config.Supabase.MagicLinkRedirectURL; redirect != "" {
+		path += "?redirect_to=" + url.QueryEscape(redirect)
+	}
+	err := s.supabase.post(ctx, path, map[string]any{"email": email, "create_user": true}, nil)
+	var authErr *supabaseAuthError
+	if errors.As(err, &authErr) {
+		switch {
+		case authErr.Status == http.StatusTooManyRequests:
+			return models.NewTooManyRequestsError("A sign-in link was sent to this address moments ago, please wait before asking for another")
+		case authErr.Status >= 400 && authErr.Status < 500:
+			s.logger.WarnContext(ctx, "Supabase Auth refused to send a magic link", "status", authErr.Status, "code", authErr.Code, "detail", authErr.Detail)
+			return authErr.apiError(models.NewBadRequestError("A sign-in link cannot be sent to this address"))
+		}
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to send a magic link", "error", err)
+		return models.NewBadGatewayError("The sign-in link could not be sent, please try again later")
+	}
+	return nil
+}
+
+// Verify exchanges the token of a magic link, or the one-time code of its email with the
+// address, for a session and creates the editor's profile on their first sign-in
+func (s *MagicLinkService) Verify(ctx context.Context, req generated.MagicLinkVerification) (*generated.AuthResponse, error) {
+	body := map[string]string{"type": "email"}
+	switch {
+	case req.TokenHash != nil && *req.TokenHash != "":
+		body["token_hash"] = *req.TokenHash
+	case req.Email != nil && req.Token != nil && *req.Token != "":
+		body["email"] = strings.TrimSpace(string(*req.Email))
+		body["token"] = strings.TrimSpace(*req.Token)
+	default:
+		return nil, models.NewBadRequestError("Send the token_hash of the link, or the email and the code from the email")
+	}
+
+	var session supabaseSession
+	err := s.supabase.post(ctx, "/auth/v1/verify", body, &session)
+	var authErr *supabaseAuthError
+	if errors.As(err, &authErr) && authErr.Status >= 400 && authErr.Status < 500 {
+		s.logger.WarnContext(ctx, "Supabase Auth refused a magic link token", "status", authErr.Status, "detail", authErr.Detail)
+		return nil, models.NewUnauthorizedError("The sign-in link or code is invalid or expired, please ask for a new one")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to verify a magic link token", "error", err)
+		return nil, models.NewBadGatewayError("Sign-in failed, please try again later")
+	}
+	return s.supabase.signIn(ctx, &session, "magic_link")
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitPause is how long sends pause after a 429 without a usable Retry-After
+const defaultRateLimitPause = time.Second
+
+// sendThrottle spaces provider requests to a configured rate, shared by all dispatch
+// workers, and pauses them all when the provider answers 429
+type sendThrottle struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newSendThrottle allows perSecond requests per second; zero or less only applies pauses
+func newSendThrottle(perSecond float64) *sendThrottle {
+	t := &sendThrottle{}
+	if perSecond > 0 {
+		t.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return t
+}
+
+// Wait blocks until the caller may send the next request
+func (t *sendThrottle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	slot := t.next
+	if slot.Before(now) {
+		slot = now
+	}
+	t.next = slot.Add(t.interval)
+	t.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PauseFor holds back every request for d, e.g. the provider's Retry-After
+func (t *sendThrottle) PauseFor(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(d); until.After(t.next) {
+		t.next = until
+	}
+}
+
+// providerResponse is what the transport saw of the provider's answer to one send. The
+// Resend client reduces errors to their message, so the status is captured underneath it.
+type providerResponse struct {
+	status     int
+	retryAfter time.Duration
+}
+
+type providerResponseKey struct{}
+
+// withProviderResponse returns a context whose send records the provider's response in resp
+func withProviderResponse(ctx context.Context, resp *providerResponse) context.Context {
+	return context.WithValue(ctx, providerResponseKey{}, resp)
+}
+
+// providerResponseTransport records the status and Retry-After of provider responses in the
+// providerResponse of the request context, if any
+type providerResponseTransport struct {
+	base http.RoundTripper
+}
+
+func (t *providerResponseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	if resp, ok := req.Context().Value(providerResponseKey{}).(*providerResponse); ok {
+		resp.status = res.StatusCode
+		resp.retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+	}
+	return res, nil
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date; zero when absent
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// permanentFailure reports whether the provider rejected the email itself (invalid address,
+// unverified sender, ...), so sending it again cannot succeed
+func (r *providerResponse) permanentFailure() bool {
+	return r.status >= 400 && r.status < 500 && r.status != http.StatusRequestTimeout && r.status != http.StatusTooManyRequests
+}
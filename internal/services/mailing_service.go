@@ -2,45 +2,307 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go-newsletter/internal/config"
-	"go-newsletter/internal/models"
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/tracing"
+	"go-newsletter/internal/utils"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/resend/resend-go/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	emailsSentTotal   = metrics.NewCounter("newsletter_emails_sent_total", "Emails accepted by the email provider.")
+	emailsFailedTotal = metrics.NewCounter("newsletter_emails_failed_total", "Emails the email provider failed to accept.")
+	dispatchDuration  = metrics.NewHistogram("newsletter_email_dispatch_duration_seconds", "Duration of bulk email dispatches.",
+		[]float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600})
+	dispatchThroughput = metrics.NewGauge("newsletter_email_dispatch_throughput_per_second", "Recipients per second achieved by the most recent bulk dispatch.")
+	rateLimitedTotal   = metrics.NewCounter("newsletter_email_rate_limited_total", "Sends the email provider rejected with 429 Too Many Requests.")
+)
+
+// errEmailRejected marks send failures the provider will not accept on a retry either
+var errEmailRejected = errors.New("email rejected by the provider")
+
 type MailingService struct {
-	cfg    *config.ResendConfig
-	logger *slog.Logger
+	cfg          *config.ResendConfig
+	workers      int
+	maxAttempts  int
+	retryBackoff time.Duration
+	throttle     *sendThrottle
+	client       *resend.Client
+	logger       *slog.Logger
 }
 
-func NewMailingService(cfg *config.ResendConfig, logger *slog.Logger) *MailingService {
-	return &MailingService{
-		cfg:    cfg,
-		logger: logger,
+// OutgoingEmail is a single personalised message for one recipient
+type OutgoingEmail struct {
+	To      string
+	Subject string
+	HTML    string
+	// ListUnsubscribe is the recipient's one-click unsubscribe URL; when set, the email
+	// carries List-Unsubscribe headers so mail clients offer an unsubscribe button
+	ListUnsubscribe string
+	// MessageID identifies a post email in its open and click tracking; uuid.Nil when untracked
+	MessageID uuid.UUID
+}
+
+// RecipientError ties a send failure to the recipient it happened for
+type RecipientError struct {
+	Recipient string
+	Err       error
+	// Attempts is how often the email was tried before giving up
+	Attempts int
+}
+
+func (e RecipientError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Recipient, e.Err)
+}
+
+func (e RecipientError) Unwrap() error {
+	return e.Err
+}
+
+// DispatchResult summarises a bulk dispatch
+type DispatchResult struct {
+	Sent     int
+	Failed   int
+	Errors   []RecipientError
+	Duration time.Duration
+}
+
+// Err returns the aggregated per-recipient errors, or nil if every email was sent
+func (r DispatchResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
 	}
+	errs := make([]error, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		errs = append(errs, e)
+	}
+	return fmt.Errorf("failed to send %d of %d emails: %w", r.Failed, r.Sent+r.Failed, errors.Join(errs...))
 }
 
-func (s *MailingService) SendMail(to []string, subject string, html string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// NewMailingService creates a new MailingService. The Resend client is built once
+// on top of the shared HTTP client so connections are reused between sends; its transport
+// also reports the provider's status codes, which the client itself drops. An invalid
+// RESEND_BASE_URL is an error rather than a reason to send to the real Resend endpoint.
+func NewMailingService(cfg *config.Config, httpClient *http.Client, logger *slog.Logger) (*MailingService, error) {
+	utils.RequireDependencies("MailingService",
+		utils.Dep("config", cfg),
+		utils.Dep("httpClient", httpClient),
+		utils.Dep("logger", logger),
+	)
+	workers := cfg.Mailing.DispatchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	providerClient := &http.Client{
+		Transport: &providerResponseTransport{base: transport},
+		Timeout:   httpClient.Timeout,
+	}
+	client := resend.NewCustomClient(providerClient, cfg.Resend.ApiKey)
+	if cfg.Resend.BaseURL != "" {
+		baseURL, err := url.Parse(cfg.Resend.BaseURL)
+		if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+			return nil, fmt.Errorf("invalid RESEND_BASE_URL %q, use an absolute http or https URL", cfg.Resend.BaseURL)
+		}
+		client.BaseURL = baseURL
+	}
+	return &MailingService{
+		cfg:          &cfg.Resend,
+		workers:      workers,
+		maxAttempts:  max(cfg.Mailing.MaxSendAttempts, 1),
+		retryBackoff: cfg.Mailing.RetryBackoff,
+		throttle:     newSendThrottle(cfg.Mailing.RatePerSecond),
+		client:       client,
+		logger:       logger,
+	}, nil
+}
+
+// SendMail sends one email to a single recipient; it carries the correlation ID of ctx, if any.
+// Every email is its own provider request, so recipients never see each other's addresses.
+func (s *MailingService) SendMail(ctx context.Context, to string, subject string, html string) error {
+	return s.send(ctx, OutgoingEmail{To: to, Subject: subject, HTML: html})
+}
 
-	client := resend.NewClient(s.cfg.ApiKey)
+// SendEmail sends one prepared email, including its List-Unsubscribe headers
+func (s *MailingService) SendEmail(ctx context.Context, email OutgoingEmail) error {
+	return s.send(ctx, email)
+}
+
+// send waits for the configured send rate and sends one email. A 429 pauses all sends for
+// the provider's Retry-After; emails the provider rejects outright wrap errEmailRejected.
+func (s *MailingService) send(ctx context.Context, email OutgoingEmail) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "resend.send", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.throttle.Wait(ctx); err != nil {
+		return err
+	}
+	span.AddEvent("throttle passed")
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	params := &resend.SendEmailRequest{
 		From:    s.cfg.Sender,
-		To:      to,
-		Subject: subject,
-		Html:    html,
+		To:      []string{email.To},
+		Subject: email.Subject,
+		Html:    email.HTML,
+		Headers: map[string]string{},
+	}
+	correlationID := utils.CorrelationID(ctx)
+	if correlationID != "" {
+		// The header travels with the email, the tag shows up in the provider's logs and webhooks
+		params.Headers[utils.CorrelationHeader] = correlationID
+		params.Tags = []resend.Tag{{Name: "correlation_id", Value: resendTagValue(correlationID)}}
+	}
+	if email.ListUnsubscribe != "" {
+		// RFC 8058 one-click unsubscribe: the mail client POSTs to the URL without opening it
+		params.Headers["List-Unsubscribe"] = "<" + email.ListUnsubscribe + ">"
+		params.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
 	}
 
-	_, err := client.Emails.SendWithContext(ctx, params)
+	var resp providerResponse
+	_, err = s.client.Emails.SendWithContext(withProviderResponse(ctx, &resp), params)
+	if resp.status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.status))
+	}
 
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Error when sending mail", "error", err)
-		return models.NewInternalServerError("Failed to send email")
+		emailsFailedTotal.Inc()
+		s.logger.ErrorContext(ctx, "Error when sending mail", "error", err, "status", resp.status, "correlation_id", correlationID)
+		if resp.status == http.StatusTooManyRequests {
+			rateLimitedTotal.Inc()
+			pause := resp.retryAfter
+			if pause <= 0 {
+				pause = defaultRateLimitPause
+			}
+			s.throttle.PauseFor(pause)
+		}
+		// Keep the provider's reason so it ends up in per-recipient errors and email jobs;
+		// handlers never expose non-API errors to clients
+		if resp.permanentFailure() {
+			return fmt.Errorf("failed to send email: %w: %w", errEmailRejected, err)
+		}
+		return fmt.Errorf("failed to send email: %w", err)
 	}
-	s.logger.Info("Email sent")
+	emailsSentTotal.Inc()
+	s.logger.Info("Email sent", "correlation_id", correlationID)
 	return nil
 }
+
+// resendTagValue maps an ID to the characters Resend accepts in tag values (ASCII letters, digits, _ and -)
+func resendTagValue(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// MaxSendAttempts is how often Dispatch tries each recipient
+func (s *MailingService) MaxSendAttempts() int {
+	return s.maxAttempts
+}
+
+// sendWithRetry tries an email up to maxAttempts times with exponential backoff. Emails the
+// provider rejected outright are not retried.
+func (s *MailingService) sendWithRetry(ctx context.Context, email OutgoingEmail) (attempts int, err error) {
+	backoff := s.retryBackoff
+	for attempts = 1; ; attempts++ {
+		err = s.send(ctx, email)
+		if err == nil || attempts >= s.maxAttempts || errors.Is(err, errEmailRejected) {
+			return attempts, err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return attempts, err
+		}
+	}
+}
+
+// Dispatch sends personalised emails concurrently using a bounded number of workers, at
+// most at the configured send rate. Each recipient is retried up to MaxSendAttempts times;
+// failures are collected per recipient instead of aborting the whole dispatch.
+func (s *MailingService) Dispatch(ctx context.Context, emails []OutgoingEmail) DispatchResult {
+	ctx, span := tracing.Tracer().Start(ctx, "email.dispatch", trace.WithAttributes(attribute.Int("email.recipients", len(emails))))
+	defer span.End()
+	start := time.Now()
+	var (
+		mu     sync.Mutex
+		result DispatchResult
+		wg     sync.WaitGroup
+	)
+
+	record := func(email OutgoingEmail, attempts int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, RecipientError{Recipient: email.To, Err: err, Attempts: attempts})
+			return
+		}
+		result.Sent++
+	}
+
+	jobs := make(chan OutgoingEmail)
+	workers := min(s.workers, len(emails))
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range jobs {
+				attempts, err := s.sendWithRetry(ctx, email)
+				record(email, attempts, err)
+			}
+		}()
+	}
+
+feed:
+	for i, email := range emails {
+		select {
+		case jobs <- email:
+		case <-ctx.Done():
+			// Everything not yet handed to a worker is reported as failed
+			for _, skipped := range emails[i:] {
+				record(skipped, 0, ctx.Err())
+			}
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	dispatchDuration.ObserveDuration(result.Duration)
+	if secs := result.Duration.Seconds(); secs > 0 {
+		dispatchThroughput.Set(float64(result.Sent) / secs)
+	}
+
+	span.SetAttributes(attribute.Int("email.sent", result.Sent), attribute.Int("email.failed", result.Failed))
+	s.logger.InfoContext(ctx, "Email dispatch finished",
+		"sent", result.Sent,
+		"failed", result.Failed,
+		"workers", workers,
+		"duration_ms", result.Duration.Milliseconds())
+
+	return result
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/httpclient"
+
+	"github.com/google/uuid"
+)
+
+// benchmarkProviderLatency is how long the stubbed email provider takes to accept an email
+const benchmarkProviderLatency = 2 * time.Millisecond
+
+// BenchmarkPublishDispatch measures the publish path past the database: rendering the post
+// email of every subscriber and fanning the sends out to an email provider stubbed with a
+// fixed latency, for several worker counts. cmd/loadtest publish runs the whole pipeline
+// against a real database.
+func BenchmarkPublishDispatch(b *testing.B) {
+	const subscribers = 200
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		time.Sleep(benchmarkProviderLatency)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": uuid.NewString()})
+	}))
+	b.Cleanup(provider.Close)
+
+	for _, workers := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			service := newBenchmarkMailingService(b, provider.URL, workers)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				emails := renderBenchmarkEmails(b, subscribers)
+				if result := service.Dispatch(ctx, emails); result.Failed > 0 {
+					b.Fatalf("Dispatch failed %d of %d emails: %v", result.Failed, subscribers, result.Err())
+				}
+			}
+			b.ReportMetric(float64(subscribers*b.N)/b.Elapsed().Seconds(), "emails/s")
+		})
+	}
+}
+
+// newBenchmarkMailingService sends through the pooled client the application uses, without a
+// send rate cap or retries
+func newBenchmarkMailingService(b *testing.B, providerURL string, workers int) *MailingService {
+	b.Helper()
+	cfg := &config.Config{
+		Resend: config.ResendConfig{Sender: "bench@example.com", ApiKey: "re_bench", BaseURL: providerURL + "/"},
+		Mailing: config.MailingConfig{
+			DispatchWorkers: workers,
+			MaxSendAttempts: 1,
+		},
+		HTTPClient: config.HTTPClientConfig{
+			Timeout:             15 * time.Second,
+			DialTimeout:         5 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 64,
+		},
+	}
+	client, err := httpclient.New(cfg.HTTPClient)
+	if err != nil {
+		b.Fatalf("creating HTTP client: %v", err)
+	}
+	mailing, err := NewMailingService(cfg, client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		b.Fatalf("creating mailing service: %v", err)
+	}
+	return mailing
+}
+
+// renderBenchmarkEmails renders the post email of n subscribers with the default layout, as
+// PostService does when a post is published
+func renderBenchmarkEmails(b *testing.B, n int) []OutgoingEmail {
+	b.Helper()
+	emails := make([]OutgoingEmail, 0, n)
+	for i := 0; i < n; i++ {
+		rendered, err := emailrender.RenderPost(emailrender.PostEmail{
+			NewsletterName: "Weekly",
+			Title:          "Spring issue",
+			ContentHTML:    "<h1>Spring issue</h1><p>What happened this week, and what comes next.</p>",
+			UnsubscribeURL: "http://localhost:8080/api/v1/unsubscribe/" + uuid.NewString(),
+		})
+		if err != nil {
+			b.Fatalf("rendering email: %v", err)
+		}
+		emails = append(emails, OutgoingEmail{
+			To:              fmt.Sprintf("subscriber-%d@example.com", i),
+			Subject:         rendered.Subject,
+			HTML:            rendered.HTML,
+			ListUnsubscribe: rendered.ListUnsubscribeURL,
+		})
+	}
+	return emails
+}
+
+func TestNewMailingServiceRejectsInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"resend-stub:8080", "localhost/emails", "://resend", "ftp://resend.example.com/"} {
+		t.Run(baseURL, func(t *testing.T) {
+			cfg := &config.Config{Resend: config.ResendConfig{ApiKey: "re_test", BaseURL: baseURL}}
+			service, err := NewMailingService(cfg, http.DefaultClient, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err == nil {
+				t.Fatalf("NewMailingService() with RESEND_BASE_URL=%q = %+v, want error", baseURL, service.client.BaseURL)
+			}
+			if !strings.Contains(err.Error(), "RESEND_BASE_URL") {
+				t.Errorf("NewMailingService() error = %q, want it to name RESEND_BASE_URL", err)
+			}
+		})
+	}
+}
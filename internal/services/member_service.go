@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+const invitationTokenPrefix = "nlinv_"
+
+// MemberService shares newsletters: owners invite editors and viewers by email, and the
+// invited editors join by accepting the invitation signed in with that address
+type MemberService struct {
+	memberRepo        *repository.MemberRepository
+	newsletterService *NewsletterService
+	mailingService    *MailingService
+	config            *config.Config
+	logger            *slog.Logger
+}
+
+func NewMemberService(memberRepo *repository.MemberRepository, newsletterService *NewsletterService, mailingService *MailingService, config *config.Config, logger *slog.Logger) *MemberService {
+	utils.RequireDependencies("MemberService",
+		utils.Dep("memberRepo", memberRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("mailingService", mailingService),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &MemberService{
+		memberRepo:        memberRepo,
+		newsletterService: newsletterService,
+		mailingService:    mailingService,
+		config:            config,
+		logger:            logger,
+	}
+}
+
+// ListMembers returns the members of a newsletter the editor is a member of
+func (s *MemberService) ListMembers(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID) ([]generated.NewsletterMember, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.memberRepo.List(ctx, newsletterID)
+}
+
+// Invite emails an invitation to join the owner's newsletter with the role to the address
+func (s *MemberService) Invite(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, req generated.NewsletterMemberInvite) (*generated.NewsletterInvitation, error) {
+	email := strings.TrimSpace(string(req.Email))
+	if email == "" {
+		return nil, models.NewBadRequestError("email is required")
+	}
+	if req.Role != repository.RoleEditor && req.Role != repository.RoleViewer {
+		return nil, models.NewBadRequestError("role must be editor or viewer")
+	}
+
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.memberRepo.IsMemberEmail(ctx, newsletterID, email)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return nil, models.NewConflictError("An editor with this email is a member of the newsletter already")
+	}
+
+	token, err := newSecret(invitationTokenPrefix, 32)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := s.memberRepo.CreateInvitation(ctx, hashSecret(token), repository.NewInvitation{
+		NewsletterID: newsletterID,
+		Email:        email,
+		Role:         req.Role,
+		InvitedBy:    editorID,
+		ExpiresAt:    time.Now().Add(s.config.Invitations.TTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Newsletter invitation created", "newsletterId", newsletterID, "invitationId", invitation.Id, "role", req.Role)
+	s.sendInvitation(ctx, newsletter.Name, email, req.Role, token, *invitation.ExpiresAt)
+	return invitation, nil
+}
+
+// Accept makes the editor a member of the newsletter of the invitation. The editor must be
+// signed in with the address the invitation was sent to.
+func (s *MemberService) Accept(ctx context.Context, user *UserContext, req generated.InvitationAcceptance) (*generated.NewsletterMember, error) {
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		return nil, models.NewBadRequestError("token is required")
+	}
+	tokenHash := hashSecret(token)
+
+	invitation, err := s.memberRepo.GetPendingInvitation(ctx, tokenHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewBadRequestError("The invitation is invalid, expired or was accepted already")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(*invitation.Email, user.Email) {
+		s.logger.WarnContext(ctx, "Newsletter invitation accepted with another email", "invitationId", invitation.Id, "editorId", user.UserID)
+		return nil, models.NewForbiddenError("The invitation was sent to another email address, sign in with that address to accept it")
+	}
+
+	member, err := s.memberRepo.AcceptInvitation(ctx, tokenHash, user.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewBadRequestError("The invitation is invalid, expired or was accepted already")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Newsletter invitation accepted", "newsletterId", invitation.NewsletterId, "editorId", user.UserID, "role", *member.Role)
+	return member, nil
+}
+
+// RemoveMember removes an editor or viewer from the newsletter. Owners remove anyone but
+// themselves; other members can only leave.
+func (s *MemberService) RemoveMember(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, memberID uuid.UUID) error {
+	role := repository.RoleOwner
+	if memberID == editorID {
+		role = repository.RoleViewer
+	}
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), role)
+	if err != nil {
+		return err
+	}
+	if newsletter.EditorId != nil && uuid.UUID(*newsletter.EditorId) == memberID {
+		return models.NewBadRequestError("The owner cannot be removed from the newsletter")
+	}
+
+	err = s.memberRepo.Remove(ctx, newsletterID, memberID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("Member not found")
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Newsletter member removed", "newsletterId", newsletterID, "editorId", memberID, "removedBy", editorID)
+	return nil
+}
+
+// sendInvitation emails the invitation; a failure is logged, the owner can invite again
+func (s *MemberService) sendInvitation(ctx context.Context, newsletterName string, email string, role string, token string, expiresAt time.Time) {
+	accept := fmt.Sprintf(`<p>Your invitation code is <code>%s</code>; enter it in the newsletter app to accept.</p>`, html.EscapeString(token))
+	if base := s.config.Invitations.URL; base != "" {
+		link := base + "?token=" + url.QueryEscape(token)
+		if strings.Contains(base, "?") {
+			link = base + "&token=" + url.QueryEscape(token)
+		}
+		accept = fmt.Sprintf(`<p><a href="%s">Accept the invitation</a></p>`, html.EscapeString(link))
+	}
+
+	body := fmt.Sprintf(`
+		<h1>You are invited to %s</h1>
+		<p>You were invited to join the newsletter %s as %s.</p>
+		%s
+		<p>Sign in with this email address to accept. The invitation expires on %s.</p>
+	`,
+		html.EscapeString(newsletterName),
+		html.EscapeString(newsletterName),
+		html.EscapeString(roleName(role)),
+		accept,
+		html.EscapeString(expiresAt.UTC().Format("2 January 2006 15:04 MST")),
+	)
+	if err := s.mailingService.SendMail(ctx, email, "You are invited to "+newsletterName, body); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to send newsletter invitation", "error", err)
+	}
+}
+
+func roleName(role string) string {
+	if role == repository.RoleViewer {
+		return "a viewer"
+	}
+	return "an editor"
+}
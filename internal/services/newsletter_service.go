@@ -2,12 +2,16 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"go-newsletter/internal/config"
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
 	"go-newsletter/pkg/generated"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -22,17 +26,26 @@ var (
 	ErrEmptyName      = models.NewBadRequestError("Newsletter name cannot be empty")
 )
 
+// ConfigBundleFormatVersion is the newsletter config bundle format this server exports and imports
+const ConfigBundleFormatVersion = 1
+
 type NewsletterService struct {
 	repo   *repository.NewsletterRepository
+	authz  *AuthorizationService
 	logger *slog.Logger
 	config *config.NewsletterConfig
+	// deletedDays is how long deleted newsletters can be restored; zero for as long as they are kept
+	deletedDays int
 }
 
-func NewNewsletterService(repo *repository.NewsletterRepository, logger *slog.Logger) *NewsletterService {
+func NewNewsletterService(repo *repository.NewsletterRepository, authz *AuthorizationService, cfg *config.Config, logger *slog.Logger) *NewsletterService {
+	utils.RequireDependencies("NewsletterService", utils.Dep("repo", repo), utils.Dep("authz", authz), utils.Dep("config", cfg), utils.Dep("logger", logger))
 	return &NewsletterService{
-		repo:   repo,
-		logger: logger,
-		config: config.DefaultNewsletterConfig(),
+		repo:        repo,
+		authz:       authz,
+		logger:      logger,
+		config:      config.DefaultNewsletterConfig(),
+		deletedDays: max(cfg.Retention.DeletedDays, 0),
 	}
 }
 
@@ -88,6 +101,23 @@ func (s *NewsletterService) validateNewsletterUpdate(ctx context.Context, editor
 	if update.Description != nil && len(*update.Description) > s.config.MaxDescriptionLength {
 		return models.NewBadRequestError(s.config.TooLongDescMessage)
 	}
+	if update.CatchUpPolicy != nil {
+		switch *update.CatchUpPolicy {
+		case generated.SendAll, generated.LatestOnly:
+		case generated.SkipOlderThan:
+			if update.CatchUpMaxAgeMinutes == nil {
+				return models.NewBadRequestError(s.config.MissingCatchUpAgeMessage)
+			}
+		default:
+			return models.NewBadRequestError(s.config.InvalidCatchUpMessage)
+		}
+	}
+	if update.CatchUpMaxAgeMinutes != nil && *update.CatchUpMaxAgeMinutes < 1 {
+		return models.NewBadRequestError(s.config.InvalidCatchUpAgeMessage)
+	}
+	if update.UnconfirmedRetentionDays != nil && *update.UnconfirmedRetentionDays < 1 {
+		return models.NewBadRequestError(s.config.InvalidRetentionMessage)
+	}
 	return nil
 }
 
@@ -99,17 +129,44 @@ func (s *NewsletterService) validateNewsletterID(id string) error {
 	return nil
 }
 
-func (s *NewsletterService) GetNewslettersOwnedByEditor(ctx context.Context, editorID string) ([]generated.Newsletter, error) {
-	newsletters, err := s.repo.GetNewslettersOwnedByEditor(ctx, editorID)
+func (s *NewsletterService) GetNewslettersOwnedByEditor(ctx context.Context, editorID string, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
+	newsletters, next, err := s.repo.GetNewslettersOwnedByEditor(ctx, editorID, page)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "SERVICE: failed to find newsletters of current editor", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
-	return newsletters, nil
+	return newsletters, next, nil
 }
 
-// GetNewsletterByIDCheckOwnership returns the newsletter if the user is authorized for it
-func (s *NewsletterService) GetNewsletterByIDCheckOwnership(ctx context.Context, newsletterID string, editorID string) (*generated.Newsletter, error) {
+// GetNewslettersOwnedByEditorWithIncludes lists a page of the editor's newsletters, embedding the
+// aggregates named in includes (subscriber_count, last_published_at)
+func (s *NewsletterService) GetNewslettersOwnedByEditorWithIncludes(ctx context.Context, editorID string, includes []generated.GetNewslettersParamsInclude, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
+	if len(includes) == 0 {
+		return s.GetNewslettersOwnedByEditor(ctx, editorID, page)
+	}
+
+	var opts repository.NewsletterListOptions
+	for _, include := range includes {
+		switch include {
+		case generated.SubscriberCount:
+			opts.IncludeSubscriberCount = true
+		case generated.LastPublishedAt:
+			opts.IncludeLastPublishedAt = true
+		default:
+			return nil, nil, models.NewBadRequestError("Unsupported include value: " + string(include))
+		}
+	}
+
+	newsletters, next, err := s.repo.GetNewslettersOwnedByEditorEnriched(ctx, editorID, opts, page)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "SERVICE: failed to find enriched newsletters of current editor", "error", err)
+		return nil, nil, err
+	}
+	return newsletters, next, nil
+}
+
+// GetNewsletterForRole returns the newsletter if the user's role in it grants role
+func (s *NewsletterService) GetNewsletterForRole(ctx context.Context, newsletterID string, editorID string, role string) (*generated.Newsletter, error) {
 	// Validate input
 	if err := s.validateNewsletterID(newsletterID); err != nil {
 		return nil, err
@@ -121,13 +178,18 @@ func (s *NewsletterService) GetNewsletterByIDCheckOwnership(ctx context.Context,
 		return nil, err
 	}
 
-	if err := s.checkNewsletterOwnership(ctx, newsletter, editorID); err != nil {
+	if err := s.authz.Authorize(ctx, newsletter, editorID, role); err != nil {
 		return nil, err
 	}
 
 	return newsletter, nil
 }
 
+// GetNewsletterForMember returns the newsletter if the user is a member of it, in any role
+func (s *NewsletterService) GetNewsletterForMember(ctx context.Context, newsletterID string, editorID string) (*generated.Newsletter, error) {
+	return s.GetNewsletterForRole(ctx, newsletterID, editorID, repository.RoleViewer)
+}
+
 // GetNewsletterByID returns the newsletter by ID without checking for ownership
 func (s *NewsletterService) GetNewsletterByID(ctx context.Context, newsletterID string) (*generated.Newsletter, error) {
 	// Validate input
@@ -144,6 +206,36 @@ func (s *NewsletterService) GetNewsletterByID(ctx context.Context, newsletterID
 	return newsletter, nil
 }
 
+// Robots directives of public archive pages, for their robots meta tag and X-Robots-Tag header
+const (
+	RobotsIndex   = "index, follow"
+	RobotsNoIndex = "noindex, nofollow"
+)
+
+// GetPublicNewsletter returns the details of a newsletter shown on its public archive
+func (s *NewsletterService) GetPublicNewsletter(ctx context.Context, newsletterID string) (*generated.PublicNewsletter, error) {
+	newsletter, err := s.GetNewsletterByID(ctx, newsletterID)
+	if err != nil {
+		return nil, err
+	}
+	return &generated.PublicNewsletter{
+		Id:          *newsletter.Id,
+		Name:        newsletter.Name,
+		Description: newsletter.Description,
+		CreatedAt:   *newsletter.CreatedAt,
+		Robots:      Robots(newsletter),
+	}, nil
+}
+
+// Robots returns the robots directives of the newsletter's public archive. Only newsletters
+// whose editor turned search indexing off are kept out of search engines.
+func Robots(newsletter *generated.Newsletter) string {
+	if newsletter.SearchIndexing != nil && !*newsletter.SearchIndexing {
+		return RobotsNoIndex
+	}
+	return RobotsIndex
+}
+
 func (s *NewsletterService) CreateNewsletter(ctx context.Context, editorID string, newsletterCreate generated.NewsletterCreate) (*generated.Newsletter, error) {
 	// Validate input
 	if err := s.validateNewsletterCreate(ctx, editorID, newsletterCreate); err != nil {
@@ -178,7 +270,7 @@ func (s *NewsletterService) UpdateNewsletter(ctx context.Context, editorID strin
 		return nil, err
 	}
 
-	if err := s.checkNewsletterOwnership(ctx, newsletter, editorID); err != nil {
+	if err := s.authz.Authorize(ctx, newsletter, editorID, repository.RoleOwner); err != nil {
 		return nil, err
 	}
 
@@ -192,16 +284,76 @@ func (s *NewsletterService) UpdateNewsletter(ctx context.Context, editorID strin
 	return updatedNewsletter, nil
 }
 
-// Check if the requesting user is the editor of this newsletter
-func (s *NewsletterService) checkNewsletterOwnership(ctx context.Context, newsletter *generated.Newsletter, editorId string) error {
-	if newsletter.EditorId.String() != editorId {
-		s.logger.WarnContext(ctx, "SERVICE: unauthorized access attempt",
-			"requested_editor_id", editorId,
-			"newsletter_editor_id", newsletter.EditorId.String())
-		return models.NewForbiddenError("You don't have access to this newsletter")
+// ExportConfigBundle returns the newsletter's branding and settings as a portable bundle
+func (s *NewsletterService) ExportConfigBundle(ctx context.Context, editorID string, newsletterID string) (*generated.NewsletterConfigBundle, error) {
+	newsletter, err := s.GetNewsletterForRole(ctx, newsletterID, editorID, repository.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	catchUpPolicy := generated.SendAll
+	if newsletter.CatchUpPolicy != nil {
+		catchUpPolicy = *newsletter.CatchUpPolicy
+	}
+	exportedAt := time.Now().UTC()
+	return &generated.NewsletterConfigBundle{
+		FormatVersion:      ConfigBundleFormatVersion,
+		ExportedAt:         &exportedAt,
+		SourceNewsletterId: newsletter.Id,
+		Branding:           &generated.NewsletterBranding{Name: newsletter.Name},
+		Settings: generated.NewsletterSettings{
+			Description:              newsletter.Description,
+			CatchUpPolicy:            catchUpPolicy,
+			CatchUpMaxAgeMinutes:     newsletter.CatchUpMaxAgeMinutes,
+			UnconfirmedRetentionDays: newsletter.UnconfirmedRetentionDays,
+			PublicBadge:              newsletter.PublicBadge,
+			SearchIndexing:           newsletter.SearchIndexing,
+			DoubleOptIn:              newsletter.DoubleOptIn,
+			RequirePostApproval:      newsletter.RequirePostApproval,
+		},
+	}, nil
+}
+
+// ImportConfigBundle replaces the newsletter's settings, and its branding when the bundle has
+// one, with the bundle's. Everything is validated before anything is written.
+func (s *NewsletterService) ImportConfigBundle(ctx context.Context, editorID string, newsletterID string, bundle generated.NewsletterConfigBundle) (*generated.Newsletter, error) {
+	if bundle.FormatVersion != ConfigBundleFormatVersion {
+		return nil, models.NewBadRequestError(fmt.Sprintf("Unsupported config bundle format_version %d, expected %d", bundle.FormatVersion, ConfigBundleFormatVersion))
 	}
 
-	return nil
+	newsletter, err := s.GetNewsletterForRole(ctx, newsletterID, editorID, repository.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := bundle.Settings
+	update := generated.NewsletterUpdate{
+		Description:              settings.Description,
+		CatchUpPolicy:            &settings.CatchUpPolicy,
+		CatchUpMaxAgeMinutes:     settings.CatchUpMaxAgeMinutes,
+		UnconfirmedRetentionDays: settings.UnconfirmedRetentionDays,
+		PublicBadge:              settings.PublicBadge,
+		SearchIndexing:           settings.SearchIndexing,
+		DoubleOptIn:              settings.DoubleOptIn,
+		RequirePostApproval:      settings.RequirePostApproval,
+	}
+	name := newsletter.Name
+	if bundle.Branding != nil && bundle.Branding.Name != newsletter.Name {
+		name = bundle.Branding.Name
+		update.Name = &name
+	}
+	if err := s.validateNewsletterUpdate(ctx, editorID, newsletterID, update); err != nil {
+		return nil, err
+	}
+
+	imported, err := s.repo.ApplyConfig(ctx, newsletterID, name, settings)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "SERVICE: failed to import newsletter config", "error", err)
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Newsletter config imported", "newsletterId", newsletterID, "sourceNewsletterId", bundle.SourceNewsletterId)
+
+	return imported, nil
 }
 
 func (s *NewsletterService) DeleteNewsletter(ctx context.Context, editorID string, newsletterID string) error {
@@ -221,7 +373,7 @@ func (s *NewsletterService) DeleteNewsletter(ctx context.Context, editorID strin
 		return err
 	}
 
-	if err := s.checkNewsletterOwnership(ctx, newsletter, editorID); err != nil {
+	if err := s.authz.Authorize(ctx, newsletter, editorID, repository.RoleOwner); err != nil {
 		return err
 	}
 
@@ -234,13 +386,13 @@ func (s *NewsletterService) DeleteNewsletter(ctx context.Context, editorID strin
 	return nil
 }
 
-func (s *NewsletterService) AdminGetAllNewsletters(ctx context.Context) ([]generated.Newsletter, error) {
-	newsletters, err := s.repo.AdminGetAll(ctx)
+func (s *NewsletterService) AdminGetAllNewsletters(ctx context.Context, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
+	newsletters, next, err := s.repo.AdminGetAll(ctx, page)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "SERVICE: failed to get all newsletters", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
-	return newsletters, nil
+	return newsletters, next, nil
 }
 
 func (s *NewsletterService) AdminDeleteNewsletterByID(ctx context.Context, newsletterID string) error {
@@ -250,3 +402,48 @@ func (s *NewsletterService) AdminDeleteNewsletterByID(ctx context.Context, newsl
 	}
 	return nil
 }
+
+// AdminGetDeletedNewsletters lists the deleted newsletters that can still be restored
+func (s *NewsletterService) AdminGetDeletedNewsletters(ctx context.Context, page pagination.Page) ([]generated.Newsletter, *pagination.Cursor, error) {
+	newsletters, next, err := s.repo.GetDeleted(ctx, s.restorableSince(), page)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "SERVICE: failed to get deleted newsletters", "error", err)
+		return nil, nil, err
+	}
+	return newsletters, next, nil
+}
+
+// AdminRestoreNewsletter restores a deleted newsletter unless it was deleted too long ago or
+// its editor has another newsletter with the same name by now
+func (s *NewsletterService) AdminRestoreNewsletter(ctx context.Context, newsletterID string) (*generated.Newsletter, error) {
+	if err := s.validateNewsletterID(newsletterID); err != nil {
+		return nil, err
+	}
+	deleted, err := s.repo.GetDeletedByID(ctx, newsletterID, s.restorableSince())
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.CheckDuplicateName(ctx, deleted.EditorId.String(), deleted.Name, newsletterID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, models.NewConflictError(fmt.Sprintf("The editor has another newsletter named %q; rename it before restoring this one", deleted.Name))
+	}
+
+	restored, err := s.repo.Restore(ctx, newsletterID, s.restorableSince())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Newsletter restored", "newsletterId", newsletterID, "deletedAt", deleted.DeletedAt)
+	return restored, nil
+}
+
+// restorableSince is the earliest deletion time of newsletters that can still be restored
+func (s *NewsletterService) restorableSince() time.Time {
+	if s.deletedDays == 0 {
+		return time.Time{}
+	}
+	return time.Now().AddDate(0, 0, -s.deletedDays)
+}
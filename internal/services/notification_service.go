@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// NotificationService lets admins email all subscribers of a newsletter in exceptional cases,
+// e.g. a service migration. Every message is audited and its newsletter's editor is told.
+type NotificationService struct {
+	notificationRepo  *repository.NotificationRepository
+	newsletterService *NewsletterService
+	postService       *PostService
+	mailingService    *MailingService
+	inboxService      *InboxService
+	logger            *slog.Logger
+}
+
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	newsletterService *NewsletterService,
+	postService *PostService,
+	mailingService *MailingService,
+	inboxService *InboxService,
+	logger *slog.Logger,
+) *NotificationService {
+	utils.RequireDependencies("NotificationService",
+		utils.Dep("notificationRepo", notificationRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("postService", postService),
+		utils.Dep("mailingService", mailingService),
+		utils.Dep("inboxService", inboxService),
+		utils.Dep("logger", logger),
+	)
+	return &NotificationService{
+		notificationRepo:  notificationRepo,
+		newsletterService: newsletterService,
+		postService:       postService,
+		mailingService:    mailingService,
+		inboxService:      inboxService,
+		logger:            logger,
+	}
+}
+
+// NotifySubscribers queues an admin-authored message for every subscriber of a newsletter, to
+// be sent by the outbox dispatcher at the requested time. The emails are rendered like posts,
+// with the subscribers' unsubscribe links, but are not counted against the editor's allowance.
+func (s *NotificationService) NotifySubscribers(ctx context.Context, adminID uuid.UUID, newsletterID uuid.UUID, req generated.SubscriberNotificationRequest) (*generated.SubscriberNotification, error) {
+	if strings.TrimSpace(req.Subject) == "" {
+		return nil, models.NewBadRequestError("Subject is required")
+	}
+	if strings.TrimSpace(req.ContentHtml) == "" {
+		return nil, models.NewBadRequestError("Content is required")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, models.NewBadRequestError("A reason is required for the audit log")
+	}
+
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.NewNotFoundError("Newsletter not found")
+		}
+		return nil, err
+	}
+
+	rendered, err := s.postService.RenderSubscriberEmails(ctx, newsletterID, req.Subject, req.ContentHtml)
+	if err != nil {
+		return nil, err
+	}
+	emails := make([]repository.NewOutboxEmail, 0, len(rendered))
+	for _, email := range rendered {
+		emails = append(emails, repository.NewOutboxEmail{
+			NewsletterID:    newsletterID,
+			Recipient:       email.To,
+			Subject:         email.Subject,
+			HTML:            email.HTML,
+			CorrelationID:   utils.CorrelationID(ctx),
+			ListUnsubscribe: email.ListUnsubscribe,
+		})
+	}
+
+	scheduledAt := req.ScheduledAt
+	if scheduledAt != nil && !scheduledAt.After(time.Now()) {
+		scheduledAt = nil
+	}
+	notification, editorEmail, err := s.notificationRepo.Create(ctx, repository.NewNotification{
+		NewsletterID: newsletterID,
+		AdminID:      adminID,
+		Subject:      req.Subject,
+		ContentHTML:  req.ContentHtml,
+		Reason:       req.Reason,
+		ScheduledAt:  scheduledAt,
+	}, emails)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Queued admin notification to subscribers", "notificationId", notification.Id, "newsletterId", newsletterID, "adminId", adminID, "recipientCount", len(emails), "scheduledAt", notification.ScheduledAt)
+
+	s.inboxService.AdminNotification(ctx, newsletterID, notification)
+	s.notifyEditor(ctx, editorEmail, newsletter.Name, notification)
+	return notification, nil
+}
+
+// notifyEditor tells the newsletter's editor about a notification; it is best effort
+func (s *NotificationService) notifyEditor(ctx context.Context, editorEmail string, newsletterName string, notification *generated.SubscriberNotification) {
+	if editorEmail == "" {
+		s.logger.WarnContext(ctx, "Newsletter editor has no email, not telling them about the notification", "notificationId", notification.Id)
+		return
+	}
+
+	body := fmt.Sprintf(`
+		<h1>An administrator is emailing your subscribers</h1>
+		<p>The subscribers of %s will receive a message from the administrators of the platform on %s.</p>
+		<p>Subject: %s<br>Reason: %s<br>Recipients: %d</p>
+	`,
+		html.EscapeString(newsletterName),
+		notification.ScheduledAt.UTC().Format("2006-01-02 15:04 MST"),
+		html.EscapeString(*notification.Subject),
+		html.EscapeString(*notification.Reason),
+		*notification.RecipientCount,
+	)
+	if err := s.mailingService.SendMail(ctx, editorEmail, "An administrator is emailing your subscribers", body); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to tell editor about subscriber notification", "notificationId", notification.Id, "error", err)
+	}
+}
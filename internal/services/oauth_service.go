@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// AccessTokenPrefix marks access tokens of integration clients, so the auth middleware can
+	// tell them from Supabase JWTs
+	AccessTokenPrefix           = "nlat_"
+	integrationClientIDPrefix   = "nlc_"
+	integrationSecretPrefix     = "nls_"
+	maxIntegrationClientName    = 100
+	maxActiveIntegrationClients = 20
+	grantTypeClientCredentials  = "client_credentials"
+)
+
+// ErrInvalidAccessToken is returned for an access token that is unknown, expired or of a
+// revoked client
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// OAuthError is an error of the token and introspection endpoints, answered in the OAuth2
+// format (RFC 6749, section 5.2) instead of the Error schema
+type OAuthError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(status int, code string, description string) *OAuthError {
+	return &OAuthError{Status: status, Code: code, Description: description}
+}
+
+// ClientCredentials are what an integration client authenticates with at the OAuth2 endpoints
+type ClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// OAuthService manages the integration clients of editors and issues them short-lived access
+// tokens through the OAuth2 client credentials flow, as an alternative to static API keys
+type OAuthService struct {
+	clientRepo *repository.IntegrationClientRepository
+	config     *config.Config
+	logger     *slog.Logger
+}
+
+func NewOAuthService(clientRepo *repository.IntegrationClientRepository, config *config.Config, logger *slog.Logger) *OAuthService {
+	utils.RequireDependencies("OAuthService",
+		utils.Dep("clientRepo", clientRepo),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &OAuthService{
+		clientRepo: clientRepo,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// ListClients returns the editor's integration clients, newest first, without their secrets
+func (s *OAuthService) ListClients(ctx context.Context, editorID uuid.UUID) ([]generated.IntegrationClient, error) {
+	return s.clientRepo.ListByEditor(ctx, editorID)
+}
+
+// CreateClient registers an integration client of the editor. The returned client is the only
+// one that carries the secret; only its hash is stored.
+func (s *OAuthService) CreateClient(ctx context.Context, editorID uuid.UUID, req generated.IntegrationClientCreate) (*generated.IntegrationClient, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxIntegrationClientName {
+		return nil, models.NewBadRequestError("name must be 1 to 100 characters")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, models.NewBadRequestError(fmt.Sprintf("scopes must list at least one of %s", strings.Join(APIKeyScopes, ", ")))
+	}
+	scopes, err := normalizeScopes(&req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := s.clientRepo.CountActiveByEditor(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	if active >= maxActiveIntegrationClients {
+		return nil, models.NewConflictError(fmt.Sprintf("An editor can have at most %d active integration clients, revoke one first", maxActiveIntegrationClients))
+	}
+
+	clientID, err := newSecret(integrationClientIDPrefix, 12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newSecret(integrationSecretPrefix, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.clientRepo.Create(ctx, editorID, name, clientID, hashSecret(secret), scopes)
+	if err != nil {
+		return nil, err
+	}
+	created.ClientSecret = &secret
+	s.logger.InfoContext(ctx, "Integration client registered", "editorId", editorID, "integrationClientId", created.Id, "scopes", scopes)
+	return created, nil
+}
+
+// RevokeClient revokes one of the editor's integration clients together with its tokens
+func (s *OAuthService) RevokeClient(ctx context.Context, editorID uuid.UUID, id uuid.UUID) error {
+	err := s.clientRepo.Revoke(ctx, editorID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("Integration client not found")
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Integration client revoked", "editorId", editorID, "integrationClientId", id)
+	return nil
+}
+
+// IssueToken grants an access token to a client for the client credentials grant. scope is the
+// space-separated list of scopes requested; empty grants all of the client's scopes.
+func (s *OAuthService) IssueToken(ctx context.Context, creds ClientCredentials, grantType string, scope string) (*generated.OAuthToken, error) {
+	if grantType == "" {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_request", "grant_type is required")
+	}
+	if grantType != grantTypeClientCredentials {
+		return nil, newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "Only the client_credentials grant is supported")
+	}
+
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := client.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(client.Scopes, scope) {
+				return nil, newOAuthError(http.StatusBadRequest, "invalid_scope", fmt.Sprintf("The client may not request the scope %q", scope))
+			}
+		}
+		scopes = slices.DeleteFunc(slices.Clone(client.Scopes), func(scope string) bool {
+			return !slices.Contains(requested, scope)
+		})
+	}
+
+	token, err := newSecret(AccessTokenPrefix, 32)
+	if err != nil {
+		return nil, err
+	}
+	ttl := s.config.OAuth.TokenTTL
+	if err := s.clientRepo.CreateToken(ctx, client.ID, hashSecret(token), scopes, time.Now().Add(ttl)); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Access token issued", "editorId", client.EditorID, "integrationClientId", client.ID, "scopes", scopes)
+	return &generated.OAuthToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Introspect tells a client whether one of its tokens is active (RFC 7662). Tokens of other
+// clients are reported inactive, so a client learns nothing about them.
+func (s *OAuthService) Introspect(ctx context.Context, creds ClientCredentials, token string) (*generated.OAuthIntrospection, error) {
+	if token == "" {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_request", "token is required")
+	}
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.activeToken(ctx, token)
+	if errors.Is(err, ErrInvalidAccessToken) || (err == nil && owner.ClientID != client.ID) {
+		return &generated.OAuthIntrospection{Active: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(owner.Scopes, " ")
+	tokenType := "Bearer"
+	exp := owner.ExpiresAt.Unix()
+	return &generated.OAuthIntrospection{
+		Active:    true,
+		Scope:     &scope,
+		ClientId:  &client.ClientID,
+		TokenType: &tokenType,
+		Exp:       &exp,
+	}, nil
+}
+
+// Authenticate introspects an access token for the auth middleware and returns the editor it
+// acts for, limited to its scopes, or ErrInvalidAccessToken
+func (s *OAuthService) Authenticate(ctx context.Context, token string) (*UserContext, error) {
+	owner, err := s.activeToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	clientID := owner.ClientID
+	return &UserContext{
+		UserID:              owner.EditorID,
+		IntegrationClientID: &clientID,
+		Scopes:              owner.Scopes,
+	}, nil
+}
+
+// PurgeExpiredTokens deletes expired access tokens in batches and returns how many were deleted
+func (s *OAuthService) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	var deleted int64
+	for {
+		n, err := s.clientRepo.DeleteExpiredTokens(ctx, s.config.Retention.BatchSize)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if n < int64(s.config.Retention.BatchSize) || ctx.Err() != nil {
+			return deleted, nil
+		}
+	}
+}
+
+func (s *OAuthService) activeToken(ctx context.Context, token string) (*repository.AccessTokenOwner, error) {
+	if !strings.HasPrefix(token, AccessTokenPrefix) {
+		return nil, ErrInvalidAccessToken
+	}
+	owner, err := s.clientRepo.GetActiveToken(ctx, hashSecret(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAccessToken
+	}
+	return owner, err
+}
+
+// authenticateClient returns the active client the credentials belong to. Unknown clients and
+// wrong secrets get the same invalid_client error.
+func (s *OAuthService) authenticateClient(ctx context.Context, creds ClientCredentials) (*repository.IntegrationClientCredentials, error) {
+	if creds.ClientID == "" || creds.ClientSecret == "" {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "Client authentication is required")
+	}
+	invalid := newOAuthError(http.StatusUnauthorized, "invalid_client", "Unknown client or wrong secret")
+	if !strings.HasPrefix(creds.ClientID, integrationClientIDPrefix) {
+		return nil, invalid
+	}
+
+	client, err := s.clientRepo.GetActiveCredentials(ctx, creds.ClientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(creds.ClientSecret)), []byte(client.SecretHash)) != 1 {
+		s.logger.WarnContext(ctx, "Integration client authentication failed", "integrationClientId", client.ID)
+		return nil, invalid
+	}
+	return client, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// OnboardingService reports the setup steps an editor has done, for a guided setup flow
+type OnboardingService struct {
+	onboardingRepo *repository.OnboardingRepository
+	logger         *slog.Logger
+}
+
+func NewOnboardingService(onboardingRepo *repository.OnboardingRepository, logger *slog.Logger) *OnboardingService {
+	utils.RequireDependencies("OnboardingService",
+		utils.Dep("onboardingRepo", onboardingRepo),
+		utils.Dep("logger", logger),
+	)
+	return &OnboardingService{
+		onboardingRepo: onboardingRepo,
+		logger:         logger,
+	}
+}
+
+// GetChecklist returns the editor's setup steps in the order they are usually done
+func (s *OnboardingService) GetChecklist(ctx context.Context, editorID uuid.UUID) (*generated.OnboardingChecklist, error) {
+	state, err := s.onboardingRepo.GetState(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []struct {
+		key       generated.OnboardingStepKey
+		completed bool
+	}{
+		{generated.ProfileFilled, state.ProfileFilled},
+		{generated.NewsletterCreated, state.NewsletterCreated},
+		{generated.SenderVerified, state.SenderVerified},
+		{generated.FirstSubscriber, state.FirstSubscriber},
+		{generated.FirstPost, state.FirstPost},
+	}
+
+	steps := make([]generated.OnboardingStep, 0, len(done))
+	completedSteps := 0
+	for _, step := range done {
+		if step.completed {
+			completedSteps++
+		}
+		steps = append(steps, generated.OnboardingStep{Key: &step.key, Completed: &step.completed})
+	}
+	totalSteps := len(steps)
+	complete := completedSteps == totalSteps
+
+	return &generated.OnboardingChecklist{
+		Steps:          &steps,
+		CompletedSteps: &completedSteps,
+		TotalSteps:     &totalSteps,
+		Complete:       &complete,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// Reasons reported with upgrade-required (402) errors
+const (
+	ReasonSubscriberLimit    = "plan_subscriber_limit"
+	ReasonEmailLimit         = "plan_email_limit"
+	ReasonFeatureUnavailable = "plan_feature_unavailable"
+)
+
+// PlanService resolves editors' plans and enforces their limits. Other services call the
+// Check* methods before doing work that counts against a quota.
+type PlanService struct {
+	planRepo *repository.PlanRepository
+	logger   *slog.Logger
+}
+
+func NewPlanService(planRepo *repository.PlanRepository, logger *slog.Logger) *PlanService {
+	utils.RequireDependencies("PlanService",
+		utils.Dep("planRepo", planRepo),
+		utils.Dep("logger", logger),
+	)
+	return &PlanService{
+		planRepo: planRepo,
+		logger:   logger,
+	}
+}
+
+// ListPlans returns all plans
+func (s *PlanService) ListPlans(ctx context.Context) ([]generated.Plan, error) {
+	return s.planRepo.List(ctx)
+}
+
+// GetPlan returns a plan by ID; an unknown plan is a bad request
+func (s *PlanService) GetPlan(ctx context.Context, planID string) (*generated.Plan, error) {
+	if planID == "" {
+		return nil, models.NewBadRequestError("plan_id is required")
+	}
+	plan, err := s.planRepo.GetByID(ctx, planID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.NewBadRequestError(fmt.Sprintf("Unknown plan %q", planID))
+		}
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetEditorPlan returns the plan whose limits apply to the editor, including active trials and coupons
+func (s *PlanService) GetEditorPlan(ctx context.Context, editorID uuid.UUID) (*generated.Plan, error) {
+	editorPlan, err := s.planRepo.GetByEditor(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	return &editorPlan.Plan, nil
+}
+
+// GetPlanUsage returns the editor's plan together with the usage counted against it
+func (s *PlanService) GetPlanUsage(ctx context.Context, editorID uuid.UUID) (*generated.PlanUsage, error) {
+	editorPlan, err := s.planRepo.GetByEditor(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := s.planRepo.ListGrants(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	subscribers, err := s.planRepo.CountActiveSubscribers(ctx, editorID)
+	if err != nil {
+		return nil, err
+	}
+	emails, err := s.planRepo.CountEmailsSince(ctx, editorID, periodStart(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.PlanUsage{
+		EditorId:        &editorID,
+		Plan:            &editorPlan.Plan,
+		PlanSource:      &editorPlan.Source,
+		PlanExpiresAt:   editorPlan.ExpiresAt,
+		Grants:          &grants,
+		Subscribers:     &subscribers,
+		EmailsThisMonth: &emails,
+	}, nil
+}
+
+// AssignPlan moves the editor to another plan. Active trials and coupons of a larger plan
+// keep precedence until they end.
+func (s *PlanService) AssignPlan(ctx context.Context, editorID uuid.UUID, planID string) (*generated.PlanUsage, error) {
+	if _, err := s.GetPlan(ctx, planID); err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.AssignToEditor(ctx, editorID, planID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.NewNotFoundError("Profile not found")
+		}
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Editor plan changed", "editorId", editorID, "planId", planID)
+
+	return s.GetPlanUsage(ctx, editorID)
+}
+
+// CheckSubscriberQuota fails with an upgrade-required error when the editor's newsletters
+// cannot take another subscriber
+func (s *PlanService) CheckSubscriberQuota(ctx context.Context, editorID uuid.UUID) error {
+	plan, err := s.GetEditorPlan(ctx, editorID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxSubscribers == nil {
+		return nil
+	}
+
+	count, err := s.planRepo.CountActiveSubscribers(ctx, editorID)
+	if err != nil {
+		return err
+	}
+	if count >= int64(*plan.MaxSubscribers) {
+		s.logger.InfoContext(ctx, "Subscriber limit reached", "editorId", editorID, "planId", *plan.Id, "limit", *plan.MaxSubscribers)
+		return models.NewUpgradeRequiredError(ReasonSubscriberLimit, "This newsletter is not accepting new subscribers at the moment")
+	}
+	return nil
+}
+
+// CheckEmailQuota fails with an upgrade-required error when sending recipients more emails
+// would exceed the editor's monthly allowance
+func (s *PlanService) CheckEmailQuota(ctx context.Context, editorID uuid.UUID, recipients int) error {
+	plan, err := s.GetEditorPlan(ctx, editorID)
+	if err != nil {
+		return err
+	}
+	if plan.MonthlyEmails == nil {
+		return nil
+	}
+
+	sent, err := s.planRepo.CountEmailsSince(ctx, editorID, periodStart(time.Now()))
+	if err != nil {
+		return err
+	}
+	if sent+int64(recipients) > int64(*plan.MonthlyEmails) {
+		s.logger.InfoContext(ctx, "Monthly email limit reached", "editorId", editorID, "planId", *plan.Id, "limit", *plan.MonthlyEmails, "sent", sent, "recipients", recipients)
+		return models.NewUpgradeRequiredError(ReasonEmailLimit, fmt.Sprintf(
+			"Sending to %d subscribers would exceed the %d emails per month of the %s plan (%d already sent)",
+			recipients, *plan.MonthlyEmails, *plan.Name, sent))
+	}
+	return nil
+}
+
+// RequireFeature fails with an upgrade-required error when the editor's plan lacks the feature
+func (s *PlanService) RequireFeature(ctx context.Context, editorID uuid.UUID, feature enums.PlanFeature) error {
+	plan, err := s.GetEditorPlan(ctx, editorID)
+	if err != nil {
+		return err
+	}
+
+	available := false
+	switch feature {
+	case enums.CustomDomainFeature:
+		available = plan.CustomDomain != nil && *plan.CustomDomain
+	}
+	if !available {
+		return models.NewUpgradeRequiredError(ReasonFeatureUnavailable, fmt.Sprintf("The %s plan does not include %s", *plan.Name, feature))
+	}
+	return nil
+}
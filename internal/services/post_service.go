@@ -4,75 +4,296 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go-newsletter/internal/archiveimport"
 	"go-newsletter/internal/config"
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/feed"
+	"go-newsletter/internal/logging"
+	"go-newsletter/internal/markdown"
 	"go-newsletter/internal/models"
 	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/repository"
+	"go-newsletter/internal/sanitize"
+	"go-newsletter/internal/sitemap"
+	"go-newsletter/internal/summarize"
+	"go-newsletter/internal/tracking"
+	"go-newsletter/internal/utils"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
 	"go-newsletter/pkg/generated"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
 type PostService struct {
-	postRepo          *repository.PostRepository
-	newsletterService *NewsletterService
-	subscriberService *SubscriberService
-	mailingService    *MailingService
-	config            *config.Config
-	logger            *slog.Logger
-}
-
-func NewPostService(
-	postRepo *repository.PostRepository,
-	newsletterService *NewsletterService,
-	subscriberService *SubscriberService,
-	mailingService *MailingService,
-	config *config.Config,
-	logger *slog.Logger,
-) *PostService {
+	postRepo             *repository.PostRepository
+	outboxRepo           *repository.OutboxRepository
+	sendAttemptRepo      *repository.SendAttemptRepository
+	newsletterService    *NewsletterService
+	subscriberService    *SubscriberService
+	mailingService       *MailingService
+	emailJobService      *EmailJobService
+	incidentService      *IncidentService
+	planService          *PlanService
+	suppressionService   *SuppressionService
+	costService          *CostService
+	summaryService       *SummaryService
+	deliverability       *DeliverabilityService
+	webhookService       *WebhookService
+	inboxService         *InboxService
+	emailTemplateService *EmailTemplateService
+	trackingService      *TrackingService
+	reviewService        *ReviewService
+	config               *config.Config
+	logger               *slog.Logger
+}
+
+// PostServiceDeps are the repositories and services PostService is built from. Fields are
+// named so two dependencies of the same type cannot be swapped without a compile error.
+type PostServiceDeps struct {
+	Posts        *repository.PostRepository
+	Outbox       *repository.OutboxRepository
+	SendAttempts *repository.SendAttemptRepository
+
+	Newsletter     *NewsletterService
+	Subscriber     *SubscriberService
+	Mailing        *MailingService
+	EmailJob       *EmailJobService
+	Incident       *IncidentService
+	Plan           *PlanService
+	Suppression    *SuppressionService
+	Cost           *CostService
+	Summary        *SummaryService
+	Deliverability *DeliverabilityService
+	Webhook        *WebhookService
+	Inbox          *InboxService
+	EmailTemplate  *EmailTemplateService
+	Tracking       *TrackingService
+	Review         *ReviewService
+
+	Config *config.Config
+	Logger *slog.Logger
+}
+
+func NewPostService(d PostServiceDeps) *PostService {
+	utils.RequireDependencies("PostService",
+		utils.Dep("Posts", d.Posts),
+		utils.Dep("Outbox", d.Outbox),
+		utils.Dep("SendAttempts", d.SendAttempts),
+		utils.Dep("Newsletter", d.Newsletter),
+		utils.Dep("Subscriber", d.Subscriber),
+		utils.Dep("Mailing", d.Mailing),
+		utils.Dep("EmailJob", d.EmailJob),
+		utils.Dep("Incident", d.Incident),
+		utils.Dep("Plan", d.Plan),
+		utils.Dep("Suppression", d.Suppression),
+		utils.Dep("Cost", d.Cost),
+		utils.Dep("Summary", d.Summary),
+		utils.Dep("Deliverability", d.Deliverability),
+		utils.Dep("Webhook", d.Webhook),
+		utils.Dep("Inbox", d.Inbox),
+		utils.Dep("EmailTemplate", d.EmailTemplate),
+		utils.Dep("Tracking", d.Tracking),
+		utils.Dep("Review", d.Review),
+		utils.Dep("Config", d.Config),
+		utils.Dep("Logger", d.Logger),
+	)
 	return &PostService{
-		postRepo:          postRepo,
-		newsletterService: newsletterService,
-		subscriberService: subscriberService,
-		mailingService:    mailingService,
-		config:            config,
-		logger:            logger,
+		postRepo:             d.Posts,
+		outboxRepo:           d.Outbox,
+		sendAttemptRepo:      d.SendAttempts,
+		newsletterService:    d.Newsletter,
+		subscriberService:    d.Subscriber,
+		mailingService:       d.Mailing,
+		emailJobService:      d.EmailJob,
+		incidentService:      d.Incident,
+		planService:          d.Plan,
+		suppressionService:   d.Suppression,
+		costService:          d.Cost,
+		summaryService:       d.Summary,
+		deliverability:       d.Deliverability,
+		webhookService:       d.Webhook,
+		inboxService:         d.Inbox,
+		emailTemplateService: d.EmailTemplate,
+		trackingService:      d.Tracking,
+		reviewService:        d.Review,
+		config:               d.Config,
+		logger:               d.Logger,
 	}
 }
 
-// GetPostsByNewsletterId retrieves a list of published posts for a newsletter
+// GetPostsByNewsletterId retrieves a page of the published (or unpublished) posts of a newsletter
 func (s *PostService) GetPostsByNewsletterId(
 	ctx context.Context,
 	newsletterID uuid.UUID,
 	editorID string,
 	published bool,
-) ([]*generated.PublishedPost, error) {
+	page pagination.Page,
+) ([]*generated.PublishedPost, *pagination.Cursor, error) {
 	// validate newsletter ownership
-	_, err := s.newsletterService.GetNewsletterByIDCheckOwnership(ctx, newsletterID.String(), editorID)
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleViewer)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrNotFound
+			return nil, nil, ErrNotFound
 		}
 		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	posts, err := s.postRepo.GetPostsByNewsletterId(ctx, newsletterID, published)
+	posts, next, err := s.postRepo.GetPostsByNewsletterId(ctx, newsletterID, published, page)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to list posts", "error", err)
+		return nil, nil, err
+	}
+
+	return posts, next, nil
+}
+
+// ListPublicPosts retrieves a page of the published posts of a newsletter for its public
+// archive, newest first, without editor details. The HTML is sanitized again, as in emails,
+// for posts stored before sanitizing was added.
+func (s *PostService) ListPublicPosts(ctx context.Context, newsletterID uuid.UUID, page pagination.Page) ([]generated.PublicPost, *pagination.Cursor, error) {
+	if _, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String()); err != nil {
+		return nil, nil, err
+	}
+
+	posts, next, err := s.postRepo.GetPostsByNewsletterId(ctx, newsletterID, true, page)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to list public posts", "error", err)
+		return nil, nil, err
+	}
+
+	public := make([]generated.PublicPost, 0, len(posts))
+	for _, post := range posts {
+		public = append(public, generated.PublicPost{
+			Id:          *post.Id,
+			Title:       post.Title,
+			Summary:     post.Summary,
+			ContentHtml: sanitize.EmailHTML(post.ContentHtml),
+			PublishedAt: *post.PublishedAt,
+		})
+	}
+	return public, next, nil
+}
+
+// GetPublicPost retrieves a published post of a newsletter for its public archive. Posts not
+// published yet are not found, like posts of other newsletters.
+func (s *PostService) GetPublicPost(ctx context.Context, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PublicPost, error) {
+	if _, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String()); err != nil {
 		return nil, err
 	}
 
-	return posts, nil
+	post, err := s.postRepo.GetPostById(ctx, postID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (uuid.UUID(*post.NewsletterId) != newsletterID || post.PublishedAt == nil)) {
+		return nil, models.NewNotFoundError("Post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.PublicPost{
+		Id:          *post.Id,
+		Title:       post.Title,
+		Summary:     post.Summary,
+		ContentHtml: sanitize.EmailHTML(post.ContentHtml),
+		PublishedAt: *post.PublishedAt,
+	}, nil
+}
+
+// PublicSitemap returns the public pages of a newsletter for its sitemap: the archive, last
+// modified when the newest post was published, and each published post. Posts link to the
+// page of PublicPostURL, or to their public API URL. It is built from the posts on every
+// request, so a post is listed as soon as it is published. Newsletters kept out of search
+// engines have no sitemap.
+func (s *PostService) PublicSitemap(ctx context.Context, newsletterID uuid.UUID) ([]sitemap.URL, error) {
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if Robots(newsletter) == RobotsNoIndex {
+		return nil, models.NewNotFoundError("Newsletter has no sitemap")
+	}
+
+	posts, err := s.postRepo.GetPublishedPostDates(ctx, newsletterID, sitemap.MaxURLs-1)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to list posts for the sitemap", "error", err)
+		return nil, err
+	}
+
+	archiveURL := fmt.Sprintf("%s/public/newsletters/%s", s.config.BuildApiBaseUrl(), newsletterID)
+	archive := sitemap.URL{Loc: archiveURL, LastMod: *newsletter.CreatedAt}
+	if len(posts) > 0 && posts[0].PublishedAt.After(archive.LastMod) {
+		archive.LastMod = posts[0].PublishedAt
+	}
+
+	urls := make([]sitemap.URL, 0, len(posts)+1)
+	urls = append(urls, archive)
+	for _, post := range posts {
+		loc := archiveURL + "/posts/" + post.ID.String()
+		if template := s.config.Server.PublicPostURL; template != "" {
+			loc = strings.NewReplacer("{newsletterId}", newsletterID.String(), "{postId}", post.ID.String()).Replace(template)
+		}
+		urls = append(urls, sitemap.URL{Loc: loc, LastMod: post.PublishedAt})
+	}
+	return urls, nil
+}
+
+// publicFeedSize is how many of the latest posts the public feed lists
+const publicFeedSize = 20
+
+// PublicFeed returns the newsletter and its latest published posts for the public RSS and
+// Atom feeds, with links into the public archive
+func (s *PostService) PublicFeed(ctx context.Context, newsletterID uuid.UUID) (*feed.Feed, error) {
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	posts, _, err := s.postRepo.GetPostsByNewsletterId(ctx, newsletterID, true, pagination.Page{Limit: publicFeedSize})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to list posts for the public feed", "error", err)
+		return nil, err
+	}
+
+	archiveURL := fmt.Sprintf("%s/public/newsletters/%s", s.config.BuildApiBaseUrl(), newsletterID)
+	f := &feed.Feed{
+		ID:      newsletterID.String(),
+		Title:   newsletter.Name,
+		Link:    archiveURL,
+		SelfURL: archiveURL + "/feed.xml",
+		Updated: *newsletter.CreatedAt,
+		Items:   make([]feed.Item, 0, len(posts)),
+	}
+	if newsletter.Description != nil {
+		f.Description = *newsletter.Description
+	}
+	for _, post := range posts {
+		item := feed.Item{
+			ID:          post.Id.String(),
+			Title:       post.Title,
+			ContentHTML: sanitize.EmailHTML(post.ContentHtml),
+			Published:   *post.PublishedAt,
+		}
+		if post.Summary != nil {
+			item.Summary = *post.Summary
+		}
+		if item.Published.After(f.Updated) {
+			f.Updated = item.Published
+		}
+		f.Items = append(f.Items, item)
+	}
+	return f, nil
 }
 
 func (s *PostService) GetPostById(ctx context.Context, newsletterID uuid.UUID, postId uuid.UUID, editorID string) (*generated.PublishedPost, error) {
 	// validate newsletter ownership
-	_, err := s.newsletterService.GetNewsletterByIDCheckOwnership(ctx, newsletterID.String(), editorID)
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleViewer)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrNotFound
@@ -92,7 +313,7 @@ func (s *PostService) GetPostById(ctx context.Context, newsletterID uuid.UUID, p
 
 func (s *PostService) DeletePostById(ctx context.Context, newsletterID uuid.UUID, postId uuid.UUID, editorID string) error {
 	// validate newsletter ownership
-	_, err := s.newsletterService.GetNewsletterByIDCheckOwnership(ctx, newsletterID.String(), editorID)
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return ErrNotFound
@@ -112,7 +333,7 @@ func (s *PostService) DeletePostById(ctx context.Context, newsletterID uuid.UUID
 
 func (s *PostService) CreatePost(ctx context.Context, editorID uuid.UUID, createPost generated.PublishPostRequest, newsletterId uuid.UUID) (*generated.PublishedPost, error) {
 	// validate newsletter ownership
-	_, err := s.newsletterService.GetNewsletterByIDCheckOwnership(ctx, newsletterId.String(), editorID.String())
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterId.String(), editorID.String(), repository.RoleEditor)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrNotFound
@@ -120,82 +341,540 @@ func (s *PostService) CreatePost(ctx context.Context, editorID uuid.UUID, create
 		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
 		return nil, err
 	}
+	if err := s.reviewService.CheckDirectPost(ctx, newsletter, editorID); err != nil {
+		return nil, err
+	}
 
 	// Validate input
 	if err := s.validatePublishPostRequest(createPost); err != nil {
 		return nil, err
 	}
+	if err := renderPostContent(&createPost); err != nil {
+		return nil, err
+	}
+	if err := s.checkDeliverability(ctx, newsletterId, createPost.Title, *createPost.ContentHtml); err != nil {
+		return nil, err
+	}
+
+	// Posts sent right away must fit in the monthly email allowance; scheduled ones are checked when due
+	if publishesImmediately(createPost) {
+		if err := s.checkEmailQuota(ctx, newsletterId); err != nil {
+			return nil, err
+		}
+	}
 
 	post, err := s.postRepo.CreatePost(ctx, editorID, &createPost, newsletterId)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "SERVICE: failed to publish post", "error", err)
 		return nil, err
 	}
+	s.updateSummary(ctx, post)
 
-	if *post.Status == enums.Posted.String() && post.PublishedAt != nil {
-		if err := s.sendMailToSubscribers(ctx, post); err != nil {
-			s.logger.ErrorContext(ctx, "Failed to send emails for new post", "error", err, "postId", post.Id)
-		}
+	if publishesImmediately(createPost) {
+		return s.publishNow(ctx, post), nil
 	}
 
 	return post, nil
 }
 
-// sendMailToSubscribers sends a mail to all subscribers of a newsletter if the post is published
-func (s *PostService) sendMailToSubscribers(ctx context.Context, post *generated.PublishedPost) error {
-	if *post.Status != enums.Posted.String() || post.PublishedAt == nil {
-		s.logger.InfoContext(ctx, "Skipping email sending for non-published post", "postId", post.Id, "status", post.Status)
-		return nil
+// ImportArchive stores the posts of an archive exported from another tool, see archiveimport,
+// as published at their original dates. They are never emailed, and their summaries are
+// extracted rather than written by the suggestions provider, as archives can be long.
+func (s *PostService) ImportArchive(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, archive []byte) (*generated.PostImportResult, error) {
+	newsletter, err := s.newsletterForRole(ctx, newsletterID, editorID.String(), repository.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	// Imported posts are published without review, so only the reviewers may import them
+	if requiresPostApproval(newsletter) {
+		if err := s.checkNewsletterRole(ctx, newsletterID, editorID.String(), repository.RoleOwner); err != nil {
+			return nil, err
+		}
+	}
+
+	posts, skipped, err := archiveimport.Read(archive)
+	if errors.Is(err, archiveimport.ErrInvalidArchive) {
+		return nil, models.NewBadRequestError("The request body must be a zip archive of HTML files")
+	}
+	if err != nil {
+		return nil, models.NewBadRequestError(err.Error())
+	}
+
+	result := &generated.PostImportResult{Skipped: []generated.PostImportSkippedFile{}}
+	now := time.Now()
+	imports := make([]repository.ImportedPost, 0, len(posts))
+	for _, post := range posts {
+		if post.PublishedAt.After(now) {
+			result.Skipped = append(result.Skipped, generated.PostImportSkippedFile{File: post.File, Reason: "the publication date is in the future"})
+			continue
+		}
+		imports = append(imports, repository.ImportedPost{
+			Title:       post.Title,
+			ContentHTML: post.HTML,
+			ContentText: post.Text,
+			Summary:     summarize.Extract(post.Text, s.config.Summary.MaxChars),
+			PublishedAt: post.PublishedAt,
+		})
+	}
+	for _, file := range skipped {
+		result.Skipped = append(result.Skipped, generated.PostImportSkippedFile{File: file.File, Reason: file.Reason})
+	}
+
+	if len(imports) > 0 {
+		stored, err := s.postRepo.ImportPosts(ctx, editorID, newsletterID, imports)
+		if err != nil {
+			return nil, err
+		}
+		result.Imported = len(stored)
+	}
+	result.AlreadyImported = len(imports) - result.Imported
+
+	s.logger.InfoContext(ctx, "Post archive imported", "newsletterId", newsletterID, "editorId", editorID,
+		"imported", result.Imported, "alreadyImported", result.AlreadyImported, "skipped", len(result.Skipped))
+	return result, nil
+}
+
+// updateSummary stores a fresh summary of the post's content and sets it on post. A post
+// without one is still published; listings then fall back to its content.
+func (s *PostService) updateSummary(ctx context.Context, post *generated.PublishedPost) {
+	newsletterName := ""
+	if newsletter, err := s.newsletterService.GetNewsletterByID(ctx, post.NewsletterId.String()); err == nil {
+		newsletterName = newsletter.Name
+	}
+	summary := s.summaryService.Summarize(ctx, newsletterName, post.Title, post.ContentHtml, post.ContentText)
+	if err := s.postRepo.UpdateSummary(ctx, uuid.UUID(*post.Id), summary); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to store post summary", "error", err, "postId", post.Id)
+		return
+	}
+	post.Summary = nil
+	if summary != "" {
+		post.Summary = &summary
+	}
+}
+
+// publishNow publishes a post that is due right away and returns it as published. If that
+// fails the post stays scheduled and due, so the scheduler publishes it on its next run.
+func (s *PostService) publishNow(ctx context.Context, post *generated.PublishedPost) *generated.PublishedPost {
+	err := s.publish(ctx, post)
+	if errors.Is(err, repository.ErrPostNotScheduled) {
+		s.logger.InfoContext(ctx, "Post is already being published by the scheduler", "postId", post.Id)
+		return post
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to publish post, leaving it to the scheduler", "error", err, "postId", post.Id)
+		return post
 	}
+	published, err := s.postRepo.GetPostById(ctx, uuid.UUID(*post.Id))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to reload published post", "error", err, "postId", post.Id)
+		return post
+	}
+	return published
+}
 
-	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, post.NewsletterId.String())
+// publish marks a scheduled post as published, queues its emails in the outbox, where the
+// outbox dispatcher sends them, and notifies the newsletter's webhooks and editor
+func (s *PostService) publish(ctx context.Context, post *generated.PublishedPost) error {
+	emails, err := s.buildPostEmails(ctx, post)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to get newsletter for email", "error", err, "newsletterId", *post.NewsletterId)
 		return err
 	}
 
-	subscribers, err := s.subscriberService.ListSubscribersWithouCheck(ctx, *post.NewsletterId)
+	postID := uuid.UUID(*post.Id)
+	if err := s.postRepo.PublishPost(ctx, postID, s.outboxEmails(ctx, post, emails)); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Queued newsletter emails", "postId", postID, "recipientCount", len(emails))
+	s.webhookService.PostPublished(ctx, post)
+	s.inboxService.PostPublished(ctx, post, len(emails))
+	return nil
+}
+
+// enqueuePostEmails queues the emails of an already published post, e.g. after it was edited
+func (s *PostService) enqueuePostEmails(ctx context.Context, post *generated.PublishedPost) error {
+	emails, err := s.buildPostEmails(ctx, post)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to get subscribers for newsletter", "error", err, "newsletterId", *post.NewsletterId)
 		return err
 	}
+	if err := s.outboxRepo.Enqueue(ctx, s.outboxEmails(ctx, post, emails)); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Queued newsletter emails", "postId", post.Id, "recipientCount", len(emails))
+	return nil
+}
 
-	if len(subscribers) == 0 {
-		s.logger.InfoContext(ctx, "No subscribers for newsletter", "newsletterId", *post.NewsletterId)
-		return nil
+// outboxEmails turns rendered emails of a post into outbox rows carrying the correlation ID of ctx
+func (s *PostService) outboxEmails(ctx context.Context, post *generated.PublishedPost, emails []OutgoingEmail) []repository.NewOutboxEmail {
+	postID := uuid.UUID(*post.Id)
+	queued := make([]repository.NewOutboxEmail, 0, len(emails))
+	for _, email := range emails {
+		queued = append(queued, repository.NewOutboxEmail{
+			NewsletterID:    uuid.UUID(*post.NewsletterId),
+			PostID:          &postID,
+			Recipient:       email.To,
+			Subject:         email.Subject,
+			HTML:            email.HTML,
+			CorrelationID:   utils.CorrelationID(ctx),
+			ListUnsubscribe: email.ListUnsubscribe,
+			MessageID:       email.MessageID,
+		})
 	}
+	return queued
+}
 
-	subject := post.Title
-	if newsletter.Name != "" {
-		subject = newsletter.Name + ": " + post.Title
+// buildPostEmails renders the post for every subscriber of its newsletter, with opens and
+// clicks tracked when tracking is on. Their message IDs are registered with the outbox insert,
+// so a lost publish claim leaves no tracking behind.
+func (s *PostService) buildPostEmails(ctx context.Context, post *generated.PublishedPost) ([]OutgoingEmail, error) {
+	return s.renderSubscriberEmails(ctx, uuid.UUID(*post.NewsletterId), post.Title, post.ContentHtml, true)
+}
+
+// RenderSubscriberEmails renders a message for every subscriber of a newsletter, with the
+// newsletter's name in the subject and the subscriber's unsubscribe links in the footer.
+// contentHTML is sanitized again, which also covers posts stored before sanitizing was added.
+func (s *PostService) RenderSubscriberEmails(ctx context.Context, newsletterID uuid.UUID, title string, contentHTML string) ([]OutgoingEmail, error) {
+	return s.renderSubscriberEmails(ctx, newsletterID, title, contentHTML, false)
+}
+
+// renderSubscriberEmails renders the messages of RenderSubscriberEmails. With track, and
+// tracking on, every message gets a message ID, an open pixel and tracked links.
+func (s *PostService) renderSubscriberEmails(ctx context.Context, newsletterID uuid.UUID, title string, contentHTML string, track bool) ([]OutgoingEmail, error) {
+	contentHTML = sanitize.EmailHTML(contentHTML)
+	var tracked *tracking.Body
+	if track {
+		tracked = s.trackingService.Prepare(ctx, contentHTML)
+	}
+	template, err := s.emailTemplateService.Template(ctx, newsletterID, emailrender.KindPost)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get post template for email", "error", err, "newsletterId", newsletterID)
+		return nil, err
+	}
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get newsletter for email", "error", err, "newsletterId", newsletterID)
+		return nil, err
 	}
 
-	emailCount := 0
+	subscribers, err := s.subscriberService.ListSubscribersWithouCheck(ctx, newsletterID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get subscribers for newsletter", "error", err, "newsletterId", newsletterID)
+		return nil, err
+	}
+
+	emails := make([]OutgoingEmail, 0, len(subscribers))
 	for _, subscriber := range subscribers {
-		unsubscribeLink := fmt.Sprintf("%s/unsubscribe/%s", s.config.BuildApiBaseUrl(), *subscriber.UnsubscribeToken)
+		content, messageID := contentHTML, uuid.Nil
+		if tracked != nil {
+			messageID = uuid.New()
+			content = s.trackingService.TrackedHTML(*tracked, messageID)
+		}
+		postEmail := emailrender.PostEmail{
+			NewsletterName: newsletter.Name,
+			Title:          title,
+			ContentHTML:    content,
+			Template:       template,
+			UnsubscribeURL: fmt.Sprintf("%s/unsubscribe/%s", s.config.BuildApiBaseUrl(), *subscriber.UnsubscribeToken),
+		}
+		if s.config.Mailing.UnsubscribeAllLink {
+			postEmail.UnsubscribeAllURL = s.suppressionService.UnsubscribeAllURL(string(subscriber.Email))
+		}
+		rendered, err := emailrender.RenderPost(postEmail)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Post template cannot render the email", "error", err, "newsletterId", newsletterID)
+			return nil, templateOutputError(err)
+		}
 
-		htmlContentWithUnsubscribe := fmt.Sprintf(`
-			%s
-			<br><br>
-			<hr>
-			<p><small>Pokud už nechcete dostávat tyto zprávy, můžete se <a href="%s">odhlásit zde</a>.</small></p>
-		`, post.ContentHtml, unsubscribeLink)
+		emails = append(emails, OutgoingEmail{
+			To:              string(subscriber.Email),
+			Subject:         rendered.Subject,
+			HTML:            rendered.HTML,
+			ListUnsubscribe: rendered.ListUnsubscribeURL,
+			MessageID:       messageID,
+		})
+	}
 
-		err = s.mailingService.SendMail([]string{string(subscriber.Email)}, subject, htmlContentWithUnsubscribe)
-		if err != nil {
-			s.logger.ErrorContext(ctx, "Failed to send newsletter email to subscriber", "error", err, "postId", post.Id, "email", subscriber.Email)
-			// Pokračovat v odesílání i když jeden email selže
-			continue
+	return emails, nil
+}
+
+// dispatchPostEmails sends the rendered emails of a post and logs per-recipient failures
+func (s *PostService) dispatchPostEmails(ctx context.Context, post *generated.PublishedPost, emails []OutgoingEmail) DispatchResult {
+	result := s.mailingService.Dispatch(ctx, emails)
+	s.recordDelivery(ctx, post, emails, result)
+	return result
+}
+
+// recordDelivery books the outcome of sending emails of a post: failures are logged and kept
+// for an admin retry, sent emails are costed and counted, and failures raise an incident
+func (s *PostService) recordDelivery(ctx context.Context, post *generated.PublishedPost, emails []OutgoingEmail, result DispatchResult) {
+	for _, failure := range result.Errors {
+		s.logger.ErrorContext(ctx, "Failed to send newsletter email to subscriber", "error", failure.Err, "postId", post.Id, "email", logging.Email(failure.Recipient))
+	}
+	s.emailJobService.RecordPostFailures(ctx, post, emails, result)
+	postID := uuid.UUID(*post.Id)
+	s.costService.Record(ctx, uuid.UUID(*post.NewsletterId), &postID, enums.PostEmail, result.Sent)
+	if err := s.postRepo.AddDeliveryCounts(context.WithoutCancel(ctx), uuid.UUID(*post.Id), result.Sent, result.Failed); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to update post delivery stats", "error", err, "postId", post.Id)
+	}
+	s.recordSendAttempt(ctx, post, result)
+	s.incidentService.ReportPostDelivery(ctx, post, result)
+
+	s.logger.InfoContext(ctx, "Newsletter email sent successfully", "postId", post.Id, "recipientCount", result.Sent, "failedCount", result.Failed)
+}
+
+// recordSendAttempt keeps the outcome of a dispatch for the post's delivery report
+func (s *PostService) recordSendAttempt(ctx context.Context, post *generated.PublishedPost, result DispatchResult) {
+	attempt := repository.NewSendAttempt{
+		PostID:       uuid.UUID(*post.Id),
+		NewsletterID: uuid.UUID(*post.NewsletterId),
+		Sent:         result.Sent,
+		Failed:       result.Failed,
+	}
+	for _, failure := range result.Errors {
+		if len(attempt.Failures) == repository.MaxRecordedSendFailures {
+			break
+		}
+		attempt.Failures = append(attempt.Failures, generated.PostSendFailure{
+			Recipient: openapi_types.Email(failure.Recipient),
+			Error:     failure.Err.Error(),
+		})
+	}
+	if err := s.sendAttemptRepo.Record(context.WithoutCancel(ctx), attempt); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record send attempt for the delivery report", "error", err, "postId", post.Id)
+	}
+}
+
+// RepublishPost re-runs the publish pipeline for an already published post. With dryRun
+// the emails are rendered and recipients resolved, but nothing is sent.
+func (s *PostService) RepublishPost(ctx context.Context, postId uuid.UUID, dryRun bool) (*generated.RepublishResult, error) {
+	post, err := s.postRepo.GetPostById(ctx, postId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.NewNotFoundError("Post not found")
 		}
+		s.logger.ErrorContext(ctx, "Failed to get post for republish", "postId", postId, "error", err)
+		return nil, err
+	}
 
-		emailCount++
+	if post.Status == nil || *post.Status != enums.Posted.String() || post.PublishedAt == nil {
+		return nil, models.NewConflictError("Only published posts can be republished")
 	}
 
-	s.logger.InfoContext(ctx, "Newsletter email sent successfully", "postId", post.Id, "recipientCount", emailCount)
-	return nil
+	emails, err := s.buildPostEmails(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := post.Title
+	recipients := make([]openapi_types.Email, 0, len(emails))
+	for _, email := range emails {
+		subject = email.Subject
+		recipients = append(recipients, openapi_types.Email(email.To))
+	}
+	recipientCount := len(emails)
+
+	result := &generated.RepublishResult{
+		PostId:         post.Id,
+		DryRun:         &dryRun,
+		Subject:        &subject,
+		RecipientCount: &recipientCount,
+		Recipients:     &recipients,
+	}
+
+	if dryRun {
+		if len(emails) > 0 {
+			result.HtmlPreview = &emails[0].HTML
+		}
+		sent, failed := 0, 0
+		result.Sent, result.Failed = &sent, &failed
+		s.logger.InfoContext(ctx, "Dry run republish", "postId", postId, "recipientCount", recipientCount)
+		return result, nil
+	}
+
+	s.logger.InfoContext(ctx, "Republishing post", "postId", postId, "recipientCount", recipientCount)
+	dispatch := s.dispatchPostEmails(ctx, post, emails)
+	errs := make([]string, 0, len(dispatch.Errors))
+	for _, failure := range dispatch.Errors {
+		errs = append(errs, failure.Error())
+	}
+	result.Sent, result.Failed, result.Errors = &dispatch.Sent, &dispatch.Failed, &errs
+
+	return result, nil
+}
+
+// PreflightPost checks the email of one of the editor's posts, drafts included, against the
+// deliverability rules without sending it
+func (s *PostService) PreflightPost(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PreflightReport, error) {
+	post, err := s.GetPostById(ctx, newsletterID, postID, editorID.String())
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && uuid.UUID(*post.NewsletterId) != newsletterID) {
+		return nil, models.NewNotFoundError("Post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := s.previewEmail(ctx, newsletterID, post.Title, post.ContentHtml)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliverability.Check(email)
+}
+
+// checkDeliverability refuses post content with issues at or above LINT_BLOCK_SEVERITY
+func (s *PostService) checkDeliverability(ctx context.Context, newsletterID uuid.UUID, title string, contentHTML string) error {
+	email, err := s.previewEmail(ctx, newsletterID, title, contentHTML)
+	if err != nil {
+		return err
+	}
+	return s.deliverability.CheckSend(email)
+}
+
+// previewEmail is the post email as subscribers get it, with a placeholder unsubscribe link
+func (s *PostService) previewEmail(ctx context.Context, newsletterID uuid.UUID, title string, contentHTML string) (emailrender.PostEmail, error) {
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get newsletter for email preview", "error", err, "newsletterId", newsletterID)
+		return emailrender.PostEmail{}, err
+	}
+	template, err := s.emailTemplateService.Template(ctx, newsletterID, emailrender.KindPost)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get post template for email preview", "error", err, "newsletterId", newsletterID)
+		return emailrender.PostEmail{}, err
+	}
+	return emailrender.PostEmail{
+		NewsletterName: newsletter.Name,
+		Title:          title,
+		ContentHTML:    sanitize.EmailHTML(contentHTML),
+		UnsubscribeURL: s.config.BuildApiBaseUrl() + "/unsubscribe/preview",
+		Template:       template,
+	}, nil
+}
+
+// GetPostDeliveryStats returns the delivery counters and incidents of a post owned by the editor
+func (s *PostService) GetPostDeliveryStats(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PostDeliveryStats, error) {
+	post, err := s.getOwnedPost(ctx, editorID, newsletterID, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, failed, err := s.postRepo.GetDeliveryCounts(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.emailJobService.CountPendingRetries(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	queued, err := s.outboxRepo.CountPending(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := s.incidentService.ListByPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.PostDeliveryStats{
+		PostId:         post.Id,
+		EmailsSent:     &sent,
+		EmailsFailed:   &failed,
+		PendingRetries: &pending,
+		EmailsQueued:   &queued,
+		Incidents:      &incidents,
+	}, nil
+}
+
+// GetPostEngagementStats returns the opens and clicks of a post owned by the editor, with
+// rates relative to the emails sent
+func (s *PostService) GetPostEngagementStats(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PostEngagementStats, error) {
+	if _, err := s.getOwnedPost(ctx, editorID, newsletterID, postID); err != nil {
+		return nil, err
+	}
+
+	sent, _, err := s.postRepo.GetDeliveryCounts(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return s.trackingService.PostStats(ctx, postID, sent)
+}
+
+// GetPostDeliveryReport returns the dispatches of a post owned by the editor, a page at a
+// time, with totals over all of them and the emails still queued
+func (s *PostService) GetPostDeliveryReport(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, page pagination.Page) (*generated.PostDeliveryReport, *pagination.Cursor, error) {
+	post, err := s.getOwnedPost(ctx, editorID, newsletterID, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totals, err := s.sendAttemptRepo.Totals(ctx, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	queued, err := s.outboxRepo.CountPending(ctx, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	attempts, next, err := s.sendAttemptRepo.ListByPost(ctx, postID, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &generated.PostDeliveryReport{
+		PostId:      *post.Id,
+		Recipients:  totals.Recipients,
+		Sent:        totals.Sent,
+		Failed:      totals.Failed,
+		Queued:      queued,
+		FirstSentAt: totals.FirstSentAt,
+		LastSentAt:  totals.LastSentAt,
+		Attempts:    attempts,
+	}, next, nil
+}
+
+// getOwnedPost returns a post of the newsletter after checking that the editor can view the newsletter
+func (s *PostService) getOwnedPost(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PublishedPost, error) {
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleViewer)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return nil, err
+	}
+
+	post, err := s.postRepo.GetPostById(ctx, postID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.NewNotFoundError("Post not found")
+		}
+		return nil, err
+	}
+	if uuid.UUID(*post.NewsletterId) != newsletterID {
+		return nil, models.NewNotFoundError("Post not found")
+	}
+	return post, nil
 }
 
 // validatePublishPostRequest validates the post creation request
+// checkEmailQuota verifies that a post to the newsletter's subscribers fits in its editor's
+// monthly email allowance
+func (s *PostService) checkEmailQuota(ctx context.Context, newsletterID uuid.UUID) error {
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get newsletter for quota check", "error", err, "newsletterId", newsletterID)
+		return err
+	}
+	recipients, err := s.subscriberService.CountRecipients(ctx, newsletterID)
+	if err != nil {
+		return err
+	}
+	return s.planService.CheckEmailQuota(ctx, uuid.UUID(*newsletter.EditorId), recipients)
+}
+
+// publishesImmediately reports whether a post scheduled in the past (or now) is published right away
+func publishesImmediately(post generated.PublishPostRequest) bool {
+	return post.ScheduledAt != nil && !post.ScheduledAt.After(time.Now())
+}
+
 func (s *PostService) validatePublishPostRequest(post generated.PublishPostRequest) error {
 	if strings.TrimSpace(post.Title) == "" {
 		return models.NewBadRequestError("Title is required")
@@ -207,8 +886,45 @@ func (s *PostService) validatePublishPostRequest(post generated.PublishPostReque
 	return nil
 }
 
+// renderPostContent fills in the HTML of a post, and its plain text unless given, from its
+// Markdown. A post has either Markdown or HTML content.
+func renderPostContent(post *generated.PublishPostRequest) error {
+	var err error
+	post.ContentHtml, post.ContentText, err = renderMarkdown(post.ContentMarkdown, post.ContentHtml, post.ContentText)
+	if err != nil {
+		return err
+	}
+	if post.ContentHtml == nil || strings.TrimSpace(*post.ContentHtml) == "" {
+		return models.NewBadRequestError("content_html or content_markdown is required")
+	}
+	return nil
+}
+
+// renderMarkdown returns the HTML and plain text of content given as Markdown, or the sanitized
+// contentHTML and contentText as it is when there is no Markdown
+func renderMarkdown(source *string, contentHTML *string, contentText *string) (*string, *string, error) {
+	if source == nil {
+		if contentHTML != nil {
+			sanitized := sanitize.EmailHTML(*contentHTML)
+			contentHTML = &sanitized
+		}
+		return contentHTML, contentText, nil
+	}
+	if contentHTML != nil && *contentHTML != "" {
+		return nil, nil, models.NewBadRequestError("Send either content_markdown or content_html, not both")
+	}
+	rendered, err := markdown.Render(*source)
+	if err != nil {
+		return nil, nil, models.NewBadRequestError("content_markdown could not be rendered")
+	}
+	if contentText == nil || *contentText == "" {
+		contentText = &rendered.Text
+	}
+	return &rendered.HTML, contentText, nil
+}
+
 func (s *PostService) UpdatePost(ctx context.Context, editorID uuid.UUID, postId uuid.UUID, updatePost generated.PublishPostRequest, newsletterId uuid.UUID) (*generated.PublishedPost, error) {
-	_, err := s.newsletterService.GetNewsletterByIDCheckOwnership(ctx, newsletterId.String(), editorID.String())
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterId.String(), editorID.String(), repository.RoleEditor)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrNotFound
@@ -216,7 +932,6 @@ func (s *PostService) UpdatePost(ctx context.Context, editorID uuid.UUID, postId
 		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
 		return nil, err
 	}
-
 	existingPost, err := s.postRepo.GetPostById(ctx, postId)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to get post for update", "error", err)
@@ -226,19 +941,208 @@ func (s *PostService) UpdatePost(ctx context.Context, editorID uuid.UUID, postId
 	if existingPost.NewsletterId.String() != newsletterId.String() {
 		return nil, models.NewForbiddenError("Post does not belong to the specified newsletter")
 	}
+	if existingPost.Status != nil && *existingPost.Status == enums.Draft.String() {
+		return nil, models.NewConflictError("Drafts are edited and published through the drafts endpoints")
+	}
+	toDraft, err := s.reviewService.EditReturnsToDraft(ctx, newsletter, editorID, existingPost)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := renderPostContent(&updatePost); err != nil {
+		return nil, err
+	}
+	if err := s.checkDeliverability(ctx, newsletterId, updatePost.Title, *updatePost.ContentHtml); err != nil {
+		return nil, err
+	}
+
+	// The edit needs an owner's approval again before the post is scheduled
+	if toDraft {
+		post, err := s.postRepo.ReturnToDraft(ctx, postId, &updatePost)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.InfoContext(ctx, "Edited scheduled post returned to draft for review", "newsletterId", newsletterId, "postId", postId, "editorId", editorID)
+		return post, nil
+	}
+
+	// Updating a published post sends it again, as does moving the schedule into the past
+	if existingPost.PublishedAt != nil || publishesImmediately(updatePost) {
+		if err := s.checkEmailQuota(ctx, newsletterId); err != nil {
+			return nil, err
+		}
+	}
 
 	post, err := s.postRepo.UpdatePost(ctx, postId, &updatePost)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "SERVICE: failed to update post", "error", err)
 		return nil, err
 	}
+	s.updateSummary(ctx, post)
+
+	if existingPost.PublishedAt != nil {
+		if err := s.enqueuePostEmails(ctx, post); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to queue emails for updated post", "error", err, "postId", post.Id)
+		}
+	} else if publishesImmediately(updatePost) {
+		return s.publishNow(ctx, post), nil
+	}
+
+	return post, nil
+}
+
+// GetDrafts retrieves a page of the drafts of a newsletter the editor can view
+func (s *PostService) GetDrafts(ctx context.Context, newsletterID uuid.UUID, editorID string, page pagination.Page) ([]*generated.PublishedPost, *pagination.Cursor, error) {
+	if err := s.checkNewsletterRole(ctx, newsletterID, editorID, repository.RoleViewer); err != nil {
+		return nil, nil, err
+	}
+
+	drafts, next, err := s.postRepo.GetDraftsByNewsletterId(ctx, newsletterID, page)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to list drafts", "error", err)
+		return nil, nil, err
+	}
+	return drafts, next, nil
+}
+
+// GetDraft retrieves a draft of a newsletter the editor can view
+func (s *PostService) GetDraft(ctx context.Context, newsletterID uuid.UUID, postID uuid.UUID, editorID string) (*generated.PublishedPost, error) {
+	if err := s.checkNewsletterRole(ctx, newsletterID, editorID, repository.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.getDraft(ctx, newsletterID, postID)
+}
+
+// CreateDraft saves a work-in-progress post that is not scheduled and never sent until published
+func (s *PostService) CreateDraft(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, draft generated.DraftRequest) (*generated.PublishedPost, error) {
+	if err := s.checkNewsletterRole(ctx, newsletterID, editorID.String(), repository.RoleEditor); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(draft.Title) == "" {
+		return nil, models.NewBadRequestError("Title is required")
+	}
+	var err error
+	if draft.ContentHtml, draft.ContentText, err = renderMarkdown(draft.ContentMarkdown, draft.ContentHtml, draft.ContentText); err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.CreateDraft(ctx, editorID, &draft, newsletterID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "SERVICE: failed to create draft", "error", err)
+		return nil, err
+	}
+	return post, nil
+}
+
+// UpdateDraft replaces the content of a draft
+func (s *PostService) UpdateDraft(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, draft generated.DraftRequest) (*generated.PublishedPost, error) {
+	if err := s.checkNewsletterRole(ctx, newsletterID, editorID.String(), repository.RoleEditor); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(draft.Title) == "" {
+		return nil, models.NewBadRequestError("Title is required")
+	}
+	var err error
+	if draft.ContentHtml, draft.ContentText, err = renderMarkdown(draft.ContentMarkdown, draft.ContentHtml, draft.ContentText); err != nil {
+		return nil, err
+	}
+	if _, err := s.getDraft(ctx, newsletterID, postID); err != nil {
+		return nil, err
+	}
+
+	post, err := s.postRepo.UpdateDraft(ctx, postID, &draft)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "SERVICE: failed to update draft", "error", err)
+		return nil, err
+	}
+	return post, nil
+}
+
+// DeleteDraft deletes a draft
+func (s *PostService) DeleteDraft(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) error {
+	if err := s.checkNewsletterRole(ctx, newsletterID, editorID.String(), repository.RoleEditor); err != nil {
+		return err
+	}
+	if _, err := s.getDraft(ctx, newsletterID, postID); err != nil {
+		return err
+	}
+	return s.postRepo.DeleteDraft(ctx, postID)
+}
+
+// PublishDraft promotes a draft: with a scheduled_at in the future it is scheduled for the
+// scheduler, otherwise it is published and its emails are queued right away
+func (s *PostService) PublishDraft(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, req generated.PublishDraftRequest) (*generated.PublishedPost, error) {
+	newsletter, err := s.newsletterForRole(ctx, newsletterID, editorID.String(), repository.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	draft, err := s.getDraft(ctx, newsletterID, postID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(draft.ContentHtml) == "" {
+		return nil, models.NewBadRequestError("A draft needs content before it can be published")
+	}
+	if err := s.reviewService.CheckPublishable(ctx, newsletter, postID); err != nil {
+		return nil, err
+	}
+	if err := s.checkDeliverability(ctx, newsletterID, draft.Title, draft.ContentHtml); err != nil {
+		return nil, err
+	}
+
+	immediately := req.ScheduledAt == nil || !req.ScheduledAt.After(time.Now())
+	if immediately {
+		if err := s.checkEmailQuota(ctx, newsletterID); err != nil {
+			return nil, err
+		}
+	}
+
+	post, err := s.postRepo.PromoteDraft(ctx, postID, req.ScheduledAt)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "SERVICE: failed to publish draft", "postId", postID, "error", err)
+		return nil, err
+	}
+	s.updateSummary(ctx, post)
 
-	if *post.Status == enums.Posted.String() && post.PublishedAt != nil {
-		if err := s.sendMailToSubscribers(ctx, post); err != nil {
-			s.logger.ErrorContext(ctx, "Failed to send emails for updated post", "error", err, "postId", post.Id)
+	if immediately {
+		return s.publishNow(ctx, post), nil
+	}
+
+	return post, nil
+}
+
+// checkNewsletterRole verifies that the newsletter exists and the editor's role in it grants role
+func (s *PostService) checkNewsletterRole(ctx context.Context, newsletterID uuid.UUID, editorID string, role string) error {
+	_, err := s.newsletterForRole(ctx, newsletterID, editorID, role)
+	return err
+}
+
+// newsletterForRole returns the newsletter if the editor's role in it grants role
+func (s *PostService) newsletterForRole(ctx context.Context, newsletterID uuid.UUID, editorID string, role string) (*generated.Newsletter, error) {
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, role)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
 		}
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return nil, err
 	}
+	return newsletter, nil
+}
 
+// getDraft loads a post of the newsletter that is still a draft
+func (s *PostService) getDraft(ctx context.Context, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PublishedPost, error) {
+	post, err := s.postRepo.GetPostById(ctx, postID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.NewNotFoundError("Draft not found")
+		}
+		s.logger.ErrorContext(ctx, "Failed to get draft", "postId", postID, "error", err)
+		return nil, err
+	}
+	if uuid.UUID(*post.NewsletterId) != newsletterID || post.Status == nil || *post.Status != enums.Draft.String() {
+		return nil, models.NewNotFoundError("Draft not found")
+	}
 	return post, nil
 }
 
@@ -252,23 +1156,291 @@ func (s *PostService) GetPostsDueForPublication(ctx context.Context, currentTime
 	return posts, nil
 }
 
-// PublishPost updates a post status to published and sends emails to subscribers
-func (s *PostService) PublishPost(ctx context.Context, postId uuid.UUID) error {
-	err := s.postRepo.PublishPost(ctx, postId)
+// CountPostsDueForPublication returns the number of scheduled posts waiting to be published
+func (s *PostService) CountPostsDueForPublication(ctx context.Context, currentTime time.Time) (int64, error) {
+	count, err := s.postRepo.CountPostsDueForPublication(ctx, currentTime)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to publish post", "postId", postId, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to count posts due for publication", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ApplyCatchUpPolicy splits due posts into the ones to publish and the ones to skip.
+// Posts whose scheduled time is older than the configured catch-up threshold (e.g. after
+// downtime) are handled according to their newsletter's catch-up policy; newsletters
+// without overdue posts are unaffected.
+func (s *PostService) ApplyCatchUpPolicy(ctx context.Context, posts []*generated.PublishedPost, now time.Time) (publish []*generated.PublishedPost, skip []*generated.PublishedPost) {
+	cutoff := now.Add(-s.config.Scheduler.CatchUpThreshold)
+
+	byNewsletter := make(map[uuid.UUID][]*generated.PublishedPost)
+	var order []uuid.UUID
+	for _, post := range posts {
+		if post == nil || post.NewsletterId == nil || post.ScheduledAt == nil {
+			publish = append(publish, post)
+			continue
+		}
+		id := *post.NewsletterId
+		if _, ok := byNewsletter[id]; !ok {
+			order = append(order, id)
+		}
+		byNewsletter[id] = append(byNewsletter[id], post)
+	}
+
+	for _, newsletterID := range order {
+		group := byNewsletter[newsletterID]
+
+		overdue := false
+		for _, post := range group {
+			if post.ScheduledAt.Before(cutoff) {
+				overdue = true
+				break
+			}
+		}
+		if !overdue {
+			publish = append(publish, group...)
+			continue
+		}
+
+		newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+		if err != nil {
+			// Fall back to sending everything rather than silently dropping posts
+			s.logger.ErrorContext(ctx, "Failed to load catch-up policy, sending all overdue posts", "newsletterId", newsletterID, "error", err)
+			publish = append(publish, group...)
+			continue
+		}
+
+		policy := generated.SendAll
+		if newsletter.CatchUpPolicy != nil {
+			policy = *newsletter.CatchUpPolicy
+		}
+
+		switch policy {
+		case generated.LatestOnly:
+			sort.SliceStable(group, func(i, j int) bool {
+				return group[i].ScheduledAt.Before(*group[j].ScheduledAt)
+			})
+			skip = append(skip, group[:len(group)-1]...)
+			publish = append(publish, group[len(group)-1])
+		case generated.SkipOlderThan:
+			if newsletter.CatchUpMaxAgeMinutes == nil {
+				s.logger.WarnContext(ctx, "skip_older_than policy without max age, sending all overdue posts", "newsletterId", newsletterID)
+				publish = append(publish, group...)
+				continue
+			}
+			maxAge := time.Duration(*newsletter.CatchUpMaxAgeMinutes) * time.Minute
+			for _, post := range group {
+				if now.Sub(*post.ScheduledAt) > maxAge {
+					skip = append(skip, post)
+				} else {
+					publish = append(publish, post)
+				}
+			}
+		default:
+			publish = append(publish, group...)
+		}
+	}
+
+	return publish, skip
+}
+
+// SkipPost marks a scheduled post as skipped so it is never sent
+func (s *PostService) SkipPost(ctx context.Context, postId uuid.UUID) error {
+	if err := s.postRepo.SkipPost(ctx, postId); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to skip post", "postId", postId, "error", err)
 		return err
 	}
+	return nil
+}
 
+// PublishPost marks a scheduled post as published and queues its emails for the outbox
+// dispatcher. A post that would exceed the editor's monthly email allowance stays scheduled
+// and is retried on later runs, so it goes out once the editor upgrades or the month rolls over.
+func (s *PostService) PublishPost(ctx context.Context, postId uuid.UUID) error {
 	post, err := s.postRepo.GetPostById(ctx, postId)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to get published post for sending emails", "postId", postId, "error", err)
+		s.logger.ErrorContext(ctx, "Failed to get scheduled post", "postId", postId, "error", err)
 		return err
 	}
-
-	if err := s.sendMailToSubscribers(ctx, post); err != nil {
-		s.logger.ErrorContext(ctx, "Failed to send emails for published post", "error", err, "postId", postId)
+	if err := s.checkEmailQuota(ctx, uuid.UUID(*post.NewsletterId)); err != nil {
+		return err
+	}
+	// Rules may have changed since the post was scheduled; a blocked post is skipped, not retried
+	if err := s.checkDeliverability(ctx, uuid.UUID(*post.NewsletterId), post.Title, post.ContentHtml); err != nil {
+		var apiErr models.APIError
+		if errors.As(err, &apiErr) {
+			if skipErr := s.SkipPost(ctx, postId); skipErr != nil {
+				return skipErr
+			}
+			s.logger.WarnContext(ctx, "Skipped scheduled post that fails deliverability checks", "postId", postId, "error", err)
+		}
+		return err
 	}
 
+	if err := s.publish(ctx, post); err != nil {
+		if !errors.Is(err, repository.ErrPostNotScheduled) {
+			s.logger.ErrorContext(ctx, "Failed to publish post", "postId", postId, "error", err)
+		}
+		return err
+	}
 	return nil
 }
+
+// DeliverQueuedEmails sends the emails queued in the outbox, a claimed batch at a time until
+// none is due, and returns how many were sent and how many failed for good. A failed email is
+// queued again after a backoff; once it used up MAIL_OUTBOX_MAX_ATTEMPTS, or the provider
+// rejected it, it is marked failed. Failed post emails are also kept for an admin retry and
+// reported as an incident.
+func (s *PostService) DeliverQueuedEmails(ctx context.Context) (sent int, failed int, err error) {
+	for ctx.Err() == nil {
+		queued, err := s.outboxRepo.Claim(ctx, s.config.Mailing.OutboxBatchSize, s.config.Mailing.OutboxLease)
+		if err != nil {
+			return sent, failed, err
+		}
+		if len(queued) == 0 {
+			break
+		}
+
+		// Emails of a post, or of a notification, are dispatched and booked together
+		bySource := make(map[uuid.UUID][]repository.OutboxEmail)
+		var order []uuid.UUID
+		for _, email := range queued {
+			source := outboxSource(email)
+			if _, ok := bySource[source]; !ok {
+				order = append(order, source)
+			}
+			bySource[source] = append(bySource[source], email)
+		}
+		for _, source := range order {
+			group := bySource[source]
+			var groupSent, groupFailed int
+			if group[0].PostID != nil {
+				groupSent, groupFailed = s.deliverQueuedPostEmails(ctx, group)
+			} else {
+				groupSent, groupFailed = s.deliverQueuedNotificationEmails(ctx, group)
+			}
+			sent += groupSent
+			failed += groupFailed
+		}
+	}
+	return sent, failed, nil
+}
+
+// outboxSource is the ID of the post or notification a queued email belongs to
+func outboxSource(email repository.OutboxEmail) uuid.UUID {
+	if email.PostID != nil {
+		return *email.PostID
+	}
+	return *email.NotificationID
+}
+
+// deliverQueuedPostEmails sends claimed emails of one post and books the outcome on the post
+func (s *PostService) deliverQueuedPostEmails(ctx context.Context, queued []repository.OutboxEmail) (sent int, failed int) {
+	ctx = queuedEmailContext(ctx, queued)
+	postID := *queued[0].PostID
+
+	post, err := s.postRepo.GetPostById(ctx, postID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get post of queued emails", "postId", postID, "error", err)
+		for _, email := range queued {
+			s.outboxRepo.Reschedule(context.WithoutCancel(ctx), email.ID, s.outboxRetryDelay(email.Attempts), err.Error())
+		}
+		return 0, 0
+	}
+
+	result, failedEmails := s.dispatchQueued(ctx, queued)
+	if result.Sent+result.Failed == 0 {
+		return 0, 0
+	}
+	s.recordDelivery(ctx, post, failedEmails, result)
+	return result.Sent, result.Failed
+}
+
+// deliverQueuedNotificationEmails sends claimed emails of one subscriber notification. They
+// are not costed to the editor and do not raise incidents; failures are logged.
+func (s *PostService) deliverQueuedNotificationEmails(ctx context.Context, queued []repository.OutboxEmail) (sent int, failed int) {
+	ctx = queuedEmailContext(ctx, queued)
+	notificationID := *queued[0].NotificationID
+
+	result, _ := s.dispatchQueued(ctx, queued)
+	for _, failure := range result.Errors {
+		s.logger.ErrorContext(ctx, "Failed to send notification email to subscriber", "error", failure.Err, "notificationId", notificationID, "email", logging.Email(failure.Recipient))
+	}
+	if result.Sent+result.Failed > 0 {
+		s.logger.InfoContext(ctx, "Notification emails sent", "notificationId", notificationID, "recipientCount", result.Sent, "failedCount", result.Failed)
+	}
+	return result.Sent, result.Failed
+}
+
+// queuedEmailContext carries the correlation ID of the request that queued the emails
+func queuedEmailContext(ctx context.Context, queued []repository.OutboxEmail) context.Context {
+	if id := queued[0].CorrelationID; id != "" {
+		return utils.WithCorrelationID(ctx, id)
+	}
+	return ctx
+}
+
+// dispatchQueued sends claimed emails and settles each of them in the outbox. It returns the
+// emails sent and the ones that failed for good, with those emails; emails queued again for a
+// retry are in neither.
+func (s *PostService) dispatchQueued(ctx context.Context, queued []repository.OutboxEmail) (DispatchResult, []OutgoingEmail) {
+	// The outcome is written even when ctx is cancelled, so sent emails are not sent again
+	bookkeeping := context.WithoutCancel(ctx)
+
+	emails := make([]OutgoingEmail, 0, len(queued))
+	for _, email := range queued {
+		emails = append(emails, OutgoingEmail{To: email.Recipient, Subject: email.Subject, HTML: email.HTML, ListUnsubscribe: email.ListUnsubscribe})
+	}
+	result := s.mailingService.Dispatch(ctx, emails)
+	failures := make(map[string]RecipientError, len(result.Errors))
+	for _, failure := range result.Errors {
+		failures[failure.Recipient] = failure
+	}
+
+	var (
+		sentIDs      []uuid.UUID
+		final        = DispatchResult{Duration: result.Duration}
+		failedEmails []OutgoingEmail
+	)
+	for i, email := range queued {
+		failure, ok := failures[email.Recipient]
+		switch {
+		case !ok:
+			sentIDs = append(sentIDs, email.ID)
+			final.Sent++
+		case ctx.Err() != nil:
+			// Stopped before the email went out; it is due again on the next run
+			s.outboxRepo.Reschedule(bookkeeping, email.ID, 0, failure.Err.Error())
+		case email.Attempts < s.config.Mailing.OutboxMaxAttempts && !errors.Is(failure.Err, errEmailRejected):
+			s.logger.WarnContext(ctx, "Failed to send queued email, retrying later", "error", failure.Err, "outboxId", email.ID, "email", logging.Email(email.Recipient), "attempts", email.Attempts)
+			s.outboxRepo.Reschedule(bookkeeping, email.ID, s.outboxRetryDelay(email.Attempts), failure.Err.Error())
+		default:
+			s.outboxRepo.MarkFailed(bookkeeping, email.ID, failure.Err.Error())
+			final.Failed++
+			final.Errors = append(final.Errors, failure)
+			failedEmails = append(failedEmails, emails[i])
+		}
+	}
+
+	// Emails that are not marked sent are sent again once their lease expires
+	if err := s.outboxRepo.MarkSent(bookkeeping, sentIDs); err != nil {
+		s.logger.ErrorContext(ctx, "Sent emails may be sent again", "count", len(sentIDs))
+	}
+	return final, failedEmails
+}
+
+// outboxRetryDelay is the wait before the next dispatch of an email after its attempts-th
+// failed one: MAIL_OUTBOX_RETRY_BACKOFF, doubled for every further attempt
+func (s *PostService) outboxRetryDelay(attempts int) time.Duration {
+	delay := s.config.Mailing.OutboxRetryBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// PurgeFinishedEmails deletes outbox emails that were sent or failed longer than
+// MAIL_OUTBOX_RETENTION ago
+func (s *PostService) PurgeFinishedEmails(ctx context.Context) (int64, error) {
+	return s.outboxRepo.Purge(ctx, s.config.Mailing.OutboxRetention)
+}
@@ -3,8 +3,11 @@ package services
 import (
 	"context"
 	"log/slog"
+	"sync"
+	"time"
 
 	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/repository"
 	"go-newsletter/internal/utils"
 	"go-newsletter/pkg/generated"
@@ -12,32 +15,79 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// AdminCacheTTL is how long RequireAdmin trusts the admin status it looked up. Granting or
+// revoking admin applies on this replica immediately, on the others within the TTL.
+const AdminCacheTTL = 30 * time.Second
+
 // ProfileService handles business logic for profiles
 type ProfileService struct {
 	repo   *repository.ProfileRepository
 	logger *slog.Logger
+
+	mu     sync.Mutex
+	admins map[string]adminStatus
+}
+
+// adminStatus is a cached admin flag of a profile
+type adminStatus struct {
+	isAdmin   bool
+	checkedAt time.Time
 }
 
 // NewProfileService creates a new ProfileService
 func NewProfileService(repo *repository.ProfileRepository, logger *slog.Logger) *ProfileService {
+	utils.RequireDependencies("ProfileService", utils.Dep("repo", repo), utils.Dep("logger", logger))
 	return &ProfileService{
 		repo:   repo,
 		logger: logger,
+		admins: make(map[string]adminStatus),
+	}
+}
+
+// IsAdmin reports whether the user's profile has admin privileges, from the cache when it is
+// fresh. Users without a profile are not admins. The cache is per process: GrantAdmin and
+// RevokeAdmin only update the replica that handled them, so with several replicas a revoked
+// admin keeps admin access on the others for up to AdminCacheTTL.
+func (s *ProfileService) IsAdmin(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	cached, ok := s.admins[id]
+	s.mu.Unlock()
+	if ok && time.Since(cached.checkedAt) < AdminCacheTTL {
+		return cached.isAdmin, nil
+	}
+
+	profile, err := s.repo.GetByID(ctx, id)
+	if err != nil && err != pgx.ErrNoRows {
+		return false, err
 	}
+	isAdmin := err == nil && profile.IsAdmin != nil && *profile.IsAdmin
+	s.rememberAdmin(id, isAdmin)
+	return isAdmin, nil
 }
 
-// GetAllProfiles retrieves all profiles
-func (s *ProfileService) GetAllProfiles(ctx context.Context) ([]generated.EditorProfile, error) {
-	profiles, err := s.repo.GetAll(ctx)
+func (s *ProfileService) rememberAdmin(id string, isAdmin bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for cachedID, cached := range s.admins {
+		if time.Since(cached.checkedAt) >= AdminCacheTTL {
+			delete(s.admins, cachedID)
+		}
+	}
+	s.admins[id] = adminStatus{isAdmin: isAdmin, checkedAt: time.Now()}
+}
+
+// GetAllProfiles retrieves a page of profiles
+func (s *ProfileService) GetAllProfiles(ctx context.Context, page pagination.Page) ([]generated.EditorProfile, *pagination.Cursor, error) {
+	profiles, next, err := s.repo.GetAll(ctx, page)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var result []generated.EditorProfile
 	for _, p := range profiles {
 		result = append(result, utils.ProfileToEditorProfile(p))
 	}
-	return result, nil
+	return result, next, nil
 }
 
 // GetProfileByID retrieves a profile by ID
@@ -74,6 +124,20 @@ func (s *ProfileService) UpdateProfile(ctx context.Context, id string, req gener
 	return &result, nil
 }
 
+// EnsureProfile returns the profile of a user, creating it on their first sign-in with the
+// name and avatar their identity provider knows them by
+func (s *ProfileService) EnsureProfile(ctx context.Context, id string, fullName string, avatarURL string) (*generated.EditorProfile, error) {
+	profile, created, err := s.repo.CreateIfMissing(ctx, id, fullName, avatarURL)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		s.logger.InfoContext(ctx, "Profile created on first sign-in", "userId", id)
+	}
+	result := utils.ProfileToEditorProfile(*profile)
+	return &result, nil
+}
+
 // GrantAdmin grants admin privileges to a user
 func (s *ProfileService) GrantAdmin(ctx context.Context, id string) (*generated.EditorProfile, error) {
 	// Check if profile exists
@@ -89,6 +153,7 @@ func (s *ProfileService) GrantAdmin(ctx context.Context, id string) (*generated.
 	if err != nil {
 		return nil, err
 	}
+	s.rememberAdmin(id, true)
 	result := utils.ProfileToEditorProfile(*profile)
 	return &result, nil
 }
@@ -108,6 +173,7 @@ func (s *ProfileService) RevokeAdmin(ctx context.Context, id string) (*generated
 	if err != nil {
 		return nil, err
 	}
+	s.rememberAdmin(id, false)
 	result := utils.ProfileToEditorProfile(*profile)
 	return &result, nil
 } 
\ No newline at end of file
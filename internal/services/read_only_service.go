@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// readOnlyLookupTimeout bounds the flag lookup done on the request path
+const readOnlyLookupTimeout = time.Second
+
+// ReadOnlyService holds the read-only mode admins switch on during incidents. The flag lives in
+// the database so every replica sees it; each replica caches it for the refresh interval.
+type ReadOnlyService struct {
+	flagRepo        *repository.RuntimeFlagRepository
+	refreshInterval time.Duration
+	logger          *slog.Logger
+
+	mu        sync.Mutex
+	enabled   bool
+	checkedAt time.Time
+}
+
+func NewReadOnlyService(flagRepo *repository.RuntimeFlagRepository, cfg *config.Config, logger *slog.Logger) *ReadOnlyService {
+	utils.RequireDependencies("ReadOnlyService",
+		utils.Dep("flagRepo", flagRepo),
+		utils.Dep("config", cfg),
+		utils.Dep("logger", logger),
+	)
+	return &ReadOnlyService{
+		flagRepo:        flagRepo,
+		refreshInterval: cfg.Server.ReadOnlyRefreshInterval,
+		logger:          logger,
+	}
+}
+
+// Enabled reports whether read-only mode is on, from the cache when it is fresh. When the flag
+// cannot be read the last known value is kept, so a database outage does not flip the mode.
+func (s *ReadOnlyService) Enabled(ctx context.Context) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) < s.refreshInterval {
+		return s.enabled
+	}
+
+	lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), readOnlyLookupTimeout)
+	defer cancel()
+	flag, err := s.flagRepo.Get(lookupCtx, repository.ReadOnlyFlag)
+	s.checkedAt = time.Now()
+	if err != nil {
+		s.logger.WarnContext(ctx, "Could not refresh read-only mode, keeping the last known value", "enabled", s.enabled, "error", err)
+		return s.enabled
+	}
+	if flag.Enabled != s.enabled {
+		s.logger.InfoContext(ctx, "Read-only mode changed", "enabled", flag.Enabled)
+	}
+	s.enabled = flag.Enabled
+	return s.enabled
+}
+
+// Get returns the read-only mode as stored
+func (s *ReadOnlyService) Get(ctx context.Context) (*generated.ReadOnlyMode, error) {
+	return s.flagRepo.Get(ctx, repository.ReadOnlyFlag)
+}
+
+// Set switches read-only mode on behalf of an admin. This replica applies it immediately,
+// the others within the refresh interval.
+func (s *ReadOnlyService) Set(ctx context.Context, adminID uuid.UUID, req generated.ReadOnlyModeUpdate) (*generated.ReadOnlyMode, error) {
+	flag, err := s.flagRepo.Set(ctx, repository.ReadOnlyFlag, req.Enabled, req.Reason, adminID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WarnContext(ctx, "Read-only mode set by admin", "enabled", flag.Enabled, "adminId", adminID)
+
+	s.mu.Lock()
+	s.enabled = flag.Enabled
+	s.checkedAt = time.Now()
+	s.mu.Unlock()
+	return flag, nil
+}
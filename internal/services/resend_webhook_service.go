@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/logging"
+	"go-newsletter/internal/metrics"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+)
+
+// resendSignatureTolerance bounds the age of a webhook signature, so captured requests
+// cannot be replayed later
+const resendSignatureTolerance = 5 * time.Minute
+
+// Resend event types acted upon
+const (
+	resendEmailBounced    = "email.bounced"
+	resendEmailComplained = "email.complained"
+	resendEmailDelivered  = "email.delivered"
+	// resendTransientBounce marks soft bounces, e.g. a full mailbox; the address is kept
+	resendTransientBounce = "Transient"
+)
+
+var providerEventsTotal = metrics.NewCounterVec("newsletter_provider_events_total", "Email events received from the email provider's webhooks.", "type")
+
+// ResendWebhookService handles the email events Resend sends to /webhooks/resend: permanent
+// bounces and spam complaints suppress the address, so posts stop going to dead or unwilling
+// inboxes.
+type ResendWebhookService struct {
+	subscriberRepo     *repository.SubscriberRepository
+	suppressionService *SuppressionService
+	config             *config.Config
+	logger             *slog.Logger
+}
+
+func NewResendWebhookService(subscriberRepo *repository.SubscriberRepository, suppressionService *SuppressionService, config *config.Config, logger *slog.Logger) *ResendWebhookService {
+	utils.RequireDependencies("ResendWebhookService",
+		utils.Dep("subscriberRepo", subscriberRepo),
+		utils.Dep("suppressionService", suppressionService),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &ResendWebhookService{
+		subscriberRepo:     subscriberRepo,
+		suppressionService: suppressionService,
+		config:             config,
+		logger:             logger,
+	}
+}
+
+// Verify checks the Svix signature Resend signs webhooks with: an HMAC-SHA256 of
+// "id.timestamp.body" keyed with the base64 part of RESEND_WEBHOOK_SECRET. The signature
+// header may list several space-separated "v1,<base64>" signatures during a secret rotation.
+func (s *ResendWebhookService) Verify(id string, timestamp string, signatures string, body []byte) error {
+	secret := s.config.Resend.WebhookSecret
+	if secret == "" {
+		return models.NewNotFoundError("Resend webhooks are not configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		s.logger.Error("RESEND_WEBHOOK_SECRET is not a valid signing secret", "error", err)
+		return models.NewInternalServerError("Resend webhooks are misconfigured")
+	}
+
+	if id == "" || timestamp == "" || signatures == "" {
+		return models.NewUnauthorizedError("Missing webhook signature")
+	}
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return models.NewUnauthorizedError("Invalid webhook timestamp")
+	}
+	if age := time.Since(time.Unix(sentAt, 0)); age > resendSignatureTolerance || age < -resendSignatureTolerance {
+		return models.NewUnauthorizedError("Webhook timestamp is too old or too new")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, signature := range strings.Fields(signatures) {
+		version, encoded, ok := strings.Cut(signature, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return models.NewUnauthorizedError("Invalid webhook signature")
+}
+
+// HandleEvent acts on a verified event. Resend retries events that fail, and acting on an
+// event twice is harmless, so errors are returned rather than swallowed.
+func (s *ResendWebhookService) HandleEvent(ctx context.Context, body []byte) error {
+	var event generated.ResendEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return models.NewBadRequestError("Invalid event")
+	}
+	providerEventsTotal.Inc(event.Type)
+
+	var recipients []string
+	if event.Data.To != nil {
+		recipients = *event.Data.To
+	}
+
+	switch event.Type {
+	case resendEmailBounced:
+		if event.Data.Bounce != nil && event.Data.Bounce.Type != nil && *event.Data.Bounce.Type == resendTransientBounce {
+			s.logger.InfoContext(ctx, "Ignoring transient bounce", "emailId", event.Data.EmailId)
+			return nil
+		}
+		for _, recipient := range recipients {
+			marked, err := s.subscriberRepo.MarkBounced(ctx, recipient)
+			if err != nil {
+				return err
+			}
+			s.logger.InfoContext(ctx, "Address bounced and was suppressed", "email", logging.Email(recipient), "subscriptions", marked)
+		}
+	case resendEmailComplained:
+		for _, recipient := range recipients {
+			if err := s.suppressionService.SuppressComplaint(ctx, recipient); err != nil {
+				return err
+			}
+		}
+	case resendEmailDelivered:
+		// Only counted; deliveries are already booked when the provider accepts an email
+	default:
+		s.logger.DebugContext(ctx, "Ignoring Resend event", "type", event.Type)
+	}
+	return nil
+}
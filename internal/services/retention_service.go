@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+)
+
+// defaultRetentionBatchSize is used when RETENTION_BATCH_SIZE is not positive
+const defaultRetentionBatchSize = 500
+
+// What the retention job does with unconfirmed subscribers past their retention
+const (
+	// UnconfirmedDelete deletes them
+	UnconfirmedDelete = "delete"
+	// UnconfirmedAnonymize replaces their address and tokens but keeps their subscription dates,
+	// so they still count in subscriber growth
+	UnconfirmedAnonymize = "anonymize"
+)
+
+// RetentionService deletes subscribers who never confirmed once they are past the retention of
+// their newsletter or their confirmation link expired, for data minimization, and deleted
+// newsletters, posts and subscribers once they can no longer be restored
+type RetentionService struct {
+	retentionRepo  *repository.RetentionRepository
+	newsletterRepo *repository.NewsletterRepository
+	postRepo       *repository.PostRepository
+	subscriberRepo *repository.SubscriberRepository
+	config         config.RetentionConfig
+	// expiredConfirmationRetention is how long subscriptions are kept after their confirmation
+	// link expired
+	expiredConfirmationRetention time.Duration
+	logger                       *slog.Logger
+}
+
+func NewRetentionService(retentionRepo *repository.RetentionRepository, newsletterRepo *repository.NewsletterRepository, postRepo *repository.PostRepository, subscriberRepo *repository.SubscriberRepository, cfg *config.Config, logger *slog.Logger) *RetentionService {
+	utils.RequireDependencies("RetentionService",
+		utils.Dep("retentionRepo", retentionRepo),
+		utils.Dep("newsletterRepo", newsletterRepo),
+		utils.Dep("postRepo", postRepo),
+		utils.Dep("subscriberRepo", subscriberRepo),
+		utils.Dep("config", cfg),
+		utils.Dep("logger", logger),
+	)
+	retention := cfg.Retention
+	if retention.BatchSize < 1 {
+		retention.BatchSize = defaultRetentionBatchSize
+	}
+	return &RetentionService{
+		retentionRepo:                retentionRepo,
+		newsletterRepo:               newsletterRepo,
+		postRepo:                     postRepo,
+		subscriberRepo:               subscriberRepo,
+		config:                       retention,
+		expiredConfirmationRetention: max(cfg.Mailing.ConfirmationExpiredRetention, 0),
+		logger:                       logger,
+	}
+}
+
+// GetUnconfirmedReport reports per newsletter the unconfirmed subscribers the next run deletes
+func (s *RetentionService) GetUnconfirmedReport(ctx context.Context) (*generated.RetentionReport, error) {
+	expired, err := s.retentionRepo.CountExpiredUnconfirmed(ctx, s.defaultDays())
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	newsletters := make([]generated.NewsletterRetention, 0, len(expired))
+	for _, e := range expired {
+		total += e.Expired
+		newsletters = append(newsletters, generated.NewsletterRetention{
+			NewsletterId:   &e.NewsletterID,
+			NewsletterName: &e.NewsletterName,
+			RetentionDays:  &e.RetentionDays,
+			Expired:        &e.Expired,
+		})
+	}
+
+	defaultDays := s.defaultDays()
+	return &generated.RetentionReport{
+		DefaultRetentionDays: &defaultDays,
+		Action:               &s.config.UnconfirmedAction,
+		DryRun:               &s.config.DryRun,
+		Expired:              &total,
+		Newsletters:          &newsletters,
+	}, nil
+}
+
+// PurgeUnconfirmed deletes or anonymizes (RETENTION_UNCONFIRMED_ACTION) the unconfirmed
+// subscribers past their retention in batches and returns how many were. In dry-run mode it only
+// logs what would be purged.
+func (s *RetentionService) PurgeUnconfirmed(ctx context.Context) (int64, error) {
+	if s.config.DryRun {
+		report, err := s.GetUnconfirmedReport(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for _, n := range *report.Newsletters {
+			s.logger.InfoContext(ctx, "Retention dry run: unconfirmed subscribers would be purged", "action", s.config.UnconfirmedAction, "newsletterId", n.NewsletterId, "retentionDays", *n.RetentionDays, "expired", *n.Expired)
+		}
+		return 0, nil
+	}
+
+	return s.purgeBatches(ctx, func() (int64, error) {
+		if s.anonymize() {
+			return s.retentionRepo.AnonymizeExpiredUnconfirmed(ctx, s.defaultDays(), s.config.BatchSize)
+		}
+		return s.retentionRepo.DeleteExpiredUnconfirmed(ctx, s.defaultDays(), s.config.BatchSize)
+	})
+}
+
+// PurgeExpiredConfirmations deletes or anonymizes in batches the unconfirmed subscribers whose
+// confirmation link expired more than MAIL_CONFIRMATION_EXPIRED_RETENTION ago, and returns how
+// many were. In dry-run mode it only logs how many would be purged.
+func (s *RetentionService) PurgeExpiredConfirmations(ctx context.Context) (int64, error) {
+	if s.config.DryRun {
+		expired, err := s.retentionRepo.CountExpiredConfirmations(ctx, s.expiredConfirmationRetention)
+		if err != nil {
+			return 0, err
+		}
+		if expired > 0 {
+			s.logger.InfoContext(ctx, "Retention dry run: subscribers with expired confirmation links would be purged", "action", s.config.UnconfirmedAction, "expired", expired)
+		}
+		return 0, nil
+	}
+	return s.purgeBatches(ctx, func() (int64, error) {
+		if s.anonymize() {
+			return s.retentionRepo.AnonymizeExpiredConfirmations(ctx, s.expiredConfirmationRetention, s.config.BatchSize)
+		}
+		return s.retentionRepo.DeleteExpiredConfirmations(ctx, s.expiredConfirmationRetention, s.config.BatchSize)
+	})
+}
+
+// PurgeDeleted deletes the newsletters, posts and subscribers deleted more than
+// RETENTION_DELETED_DAYS ago for good, in batches, and returns how many of each were deleted. It
+// does nothing when deleted rows are kept or in dry-run mode.
+func (s *RetentionService) PurgeDeleted(ctx context.Context) (newsletters int64, posts int64, subscribers int64, err error) {
+	if s.config.DeletedDays <= 0 || s.config.DryRun {
+		return 0, 0, 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -s.config.DeletedDays)
+
+	newsletters, err = s.purgeBatches(ctx, func() (int64, error) {
+		return s.newsletterRepo.PurgeDeleted(ctx, before, s.config.BatchSize)
+	})
+	if err != nil {
+		return newsletters, 0, 0, err
+	}
+	posts, err = s.purgeBatches(ctx, func() (int64, error) {
+		return s.postRepo.PurgeDeleted(ctx, before, s.config.BatchSize)
+	})
+	if err != nil {
+		return newsletters, posts, 0, err
+	}
+	subscribers, err = s.purgeBatches(ctx, func() (int64, error) {
+		return s.subscriberRepo.PurgeDeleted(ctx, before, s.config.BatchSize)
+	})
+	return newsletters, posts, subscribers, err
+}
+
+// purgeBatches runs a batch delete until a batch comes back short and returns the total
+func (s *RetentionService) purgeBatches(ctx context.Context, deleteBatch func() (int64, error)) (int64, error) {
+	var deleted int64
+	for {
+		n, err := deleteBatch()
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if n < int64(s.config.BatchSize) || ctx.Err() != nil {
+			return deleted, nil
+		}
+	}
+}
+
+// UnconfirmedAction is what purges do with unconfirmed subscribers, UnconfirmedDelete or
+// UnconfirmedAnonymize
+func (s *RetentionService) UnconfirmedAction() string {
+	return s.config.UnconfirmedAction
+}
+
+// anonymize reports whether unconfirmed subscribers are anonymized rather than deleted
+func (s *RetentionService) anonymize() bool {
+	return s.config.UnconfirmedAction == UnconfirmedAnonymize
+}
+
+// defaultDays is the platform retention; negative values are treated as none
+func (s *RetentionService) defaultDays() int {
+	return max(s.config.UnconfirmedDays, 0)
+}
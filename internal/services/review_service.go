@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// The repositories and services ReviewService calls, narrowed so tests can stand in for them
+type (
+	reviewStore interface {
+		Get(ctx context.Context, postID uuid.UUID) (*generated.PostReview, error)
+		Submit(ctx context.Context, postID uuid.UUID, editorID uuid.UUID) (*generated.PostReview, error)
+		Decide(ctx context.Context, postID uuid.UUID, reviewerID uuid.UUID, status generated.PostReviewStatus, comment *string) (*generated.PostReview, error)
+		Queue(ctx context.Context, newsletterID uuid.UUID, page pagination.Page) ([]generated.ReviewQueueItem, *pagination.Cursor, error)
+	}
+	reviewPosts interface {
+		GetPostById(ctx context.Context, postId uuid.UUID) (*generated.PublishedPost, error)
+	}
+	reviewNewsletters interface {
+		GetNewsletterForRole(ctx context.Context, newsletterID string, editorID string, role string) (*generated.Newsletter, error)
+	}
+)
+
+// ReviewService runs the approval workflow of drafts: editors submit drafts for review, and
+// owners approve or reject them with a comment. Newsletters with require_post_approval only
+// publish or schedule approved drafts, see CheckPublishable.
+type ReviewService struct {
+	reviewRepo        reviewStore
+	postRepo          reviewPosts
+	newsletterService reviewNewsletters
+	logger            *slog.Logger
+}
+
+func NewReviewService(reviewRepo *repository.ReviewRepository, postRepo *repository.PostRepository, newsletterService *NewsletterService, logger *slog.Logger) *ReviewService {
+	utils.RequireDependencies("ReviewService",
+		utils.Dep("reviewRepo", reviewRepo),
+		utils.Dep("postRepo", postRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("logger", logger),
+	)
+	return &ReviewService{
+		reviewRepo:        reviewRepo,
+		postRepo:          postRepo,
+		newsletterService: newsletterService,
+		logger:            logger,
+	}
+}
+
+// errApprovalRequired refuses posts that would be published or scheduled without review
+var errApprovalRequired = models.NewConflictError("This newsletter requires post approval; write the post as a draft and submit it for review")
+
+// requiresPostApproval reports whether the newsletter only publishes approved drafts. Owners,
+// who approve the drafts, are exempt for posts they write directly, see CheckDirectPost; drafts
+// are only published once approved, whoever publishes them.
+func requiresPostApproval(newsletter *generated.Newsletter) bool {
+	return newsletter.RequirePostApproval != nil && *newsletter.RequirePostApproval
+}
+
+// GetReview returns the review of a draft of a newsletter the editor can view
+func (s *ReviewService) GetReview(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PostReview, error) {
+	if _, err := s.draftForRole(ctx, editorID, newsletterID, postID, repository.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.Get(ctx, postID)
+}
+
+// Submit puts a draft with content in the review queue. Drafts never submitted and rejected
+// ones can be submitted.
+func (s *ReviewService) Submit(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PostReview, error) {
+	draft, err := s.draftForRole(ctx, editorID, newsletterID, postID, repository.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(draft.ContentHtml) == "" {
+		return nil, models.NewBadRequestError("A draft needs content before it can be submitted for review")
+	}
+
+	review, err := s.reviewRepo.Get(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	switch review.Status {
+	case generated.InReview:
+		return nil, models.NewConflictError("The draft is in review already")
+	case generated.Approved:
+		return nil, models.NewConflictError("The draft is approved already")
+	}
+
+	review, err = s.reviewRepo.Submit(ctx, postID, editorID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Draft submitted for review", "newsletterId", newsletterID, "postId", postID, "editorId", editorID)
+	return review, nil
+}
+
+// Approve approves a draft in review; the comment is optional
+func (s *ReviewService) Approve(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, decision generated.PostReviewDecision) (*generated.PostReview, error) {
+	return s.decide(ctx, editorID, newsletterID, postID, generated.Approved, reviewComment(decision))
+}
+
+// Reject rejects a draft in review with a comment for its author, who can edit the draft and
+// submit it again
+func (s *ReviewService) Reject(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, decision generated.PostReviewDecision) (*generated.PostReview, error) {
+	comment := reviewComment(decision)
+	if comment == nil {
+		return nil, models.NewBadRequestError("A comment is required to reject a draft")
+	}
+	return s.decide(ctx, editorID, newsletterID, postID, generated.Rejected, comment)
+}
+
+// decide records the decision of an owner on a draft in review
+func (s *ReviewService) decide(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, status generated.PostReviewStatus, comment *string) (*generated.PostReview, error) {
+	if _, err := s.draftForRole(ctx, editorID, newsletterID, postID, repository.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	review, err := s.reviewRepo.Get(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != generated.InReview {
+		return nil, models.NewConflictError("Only drafts in review can be approved or rejected")
+	}
+
+	review, err = s.reviewRepo.Decide(ctx, postID, editorID, status, comment)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Draft reviewed", "newsletterId", newsletterID, "postId", postID, "status", status, "reviewerId", editorID)
+	return review, nil
+}
+
+// Queue returns a page of the drafts in review of a newsletter the editor can view, longest
+// waiting first
+func (s *ReviewService) Queue(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, page pagination.Page) ([]generated.ReviewQueueItem, *pagination.Cursor, error) {
+	if _, err := s.newsletterForRole(ctx, editorID, newsletterID, repository.RoleViewer); err != nil {
+		return nil, nil, err
+	}
+	return s.reviewRepo.Queue(ctx, newsletterID, page)
+}
+
+// CheckPublishable fails with a conflict when the newsletter requires post approval and the
+// draft is not approved
+func (s *ReviewService) CheckPublishable(ctx context.Context, newsletter *generated.Newsletter, postID uuid.UUID) error {
+	if !requiresPostApproval(newsletter) {
+		return nil
+	}
+	review, err := s.reviewRepo.Get(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if review.Status != generated.Approved {
+		return models.NewConflictError("This newsletter requires post approval; the draft must be approved before it is published or scheduled")
+	}
+	return nil
+}
+
+// CheckDirectPost fails with a conflict when the newsletter requires post approval and the
+// editor, who creates a post without a draft, is not an owner
+func (s *ReviewService) CheckDirectPost(ctx context.Context, newsletter *generated.Newsletter, editorID uuid.UUID) error {
+	exempt, err := s.exemptFromReview(ctx, newsletter, editorID)
+	if err != nil {
+		return err
+	}
+	if !exempt {
+		return errApprovalRequired
+	}
+	return nil
+}
+
+// EditReturnsToDraft reports whether an edit of the scheduled or published post by the editor
+// moves it back to draft for review. Owners edit posts in place, as does everyone when the
+// newsletter does not require post approval. Edits of other editors unschedule a scheduled post
+// and reopen its review; a published post, which an edit sends again, only owners can edit.
+func (s *ReviewService) EditReturnsToDraft(ctx context.Context, newsletter *generated.Newsletter, editorID uuid.UUID, post *generated.PublishedPost) (bool, error) {
+	exempt, err := s.exemptFromReview(ctx, newsletter, editorID)
+	if err != nil || exempt {
+		return false, err
+	}
+	if post.PublishedAt != nil {
+		return false, models.NewConflictError("This newsletter requires post approval; only owners can edit published posts")
+	}
+	return true, nil
+}
+
+// exemptFromReview reports whether the editor's posts skip review: the newsletter does not
+// require post approval, or the editor is one of its owners
+func (s *ReviewService) exemptFromReview(ctx context.Context, newsletter *generated.Newsletter, editorID uuid.UUID) (bool, error) {
+	if !requiresPostApproval(newsletter) {
+		return true, nil
+	}
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletter.Id.String(), editorID.String(), repository.RoleOwner)
+	var apiErr models.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// newsletterForRole returns the newsletter if the editor's role in it grants role
+func (s *ReviewService) newsletterForRole(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, role string) (*generated.Newsletter, error) {
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), role)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return newsletter, nil
+}
+
+// draftForRole checks the editor's role in the newsletter and loads a post of it that is
+// still a draft
+func (s *ReviewService) draftForRole(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID, role string) (*generated.PublishedPost, error) {
+	if _, err := s.newsletterForRole(ctx, editorID, newsletterID, role); err != nil {
+		return nil, err
+	}
+	post, err := s.postRepo.GetPostById(ctx, postID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.NewNotFoundError("Draft not found")
+		}
+		s.logger.ErrorContext(ctx, "Failed to get draft for review", "postId", postID, "error", err)
+		return nil, err
+	}
+	if post.NewsletterId == nil || uuid.UUID(*post.NewsletterId) != newsletterID || post.Status == nil || *post.Status != enums.Draft.String() {
+		return nil, models.NewNotFoundError("Draft not found")
+	}
+	return post, nil
+}
+
+// reviewComment returns the trimmed comment of a decision, nil when it is empty
+func reviewComment(decision generated.PostReviewDecision) *string {
+	if decision.Comment == nil {
+		return nil
+	}
+	comment := strings.TrimSpace(*decision.Comment)
+	if comment == "" {
+		return nil
+	}
+	return &comment
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// fakeReviews keeps reviews in memory and refuses the transitions ReviewRepository's queries
+// refuse
+type fakeReviews map[uuid.UUID]*generated.PostReview
+
+func (f fakeReviews) Get(_ context.Context, postID uuid.UUID) (*generated.PostReview, error) {
+	if review, ok := f[postID]; ok {
+		copied := *review
+		return &copied, nil
+	}
+	return &generated.PostReview{PostId: postID, Status: generated.Draft}, nil
+}
+
+func (f fakeReviews) Submit(ctx context.Context, postID uuid.UUID, editorID uuid.UUID) (*generated.PostReview, error) {
+	current, _ := f.Get(ctx, postID)
+	if current.Status != generated.Draft && current.Status != generated.Rejected {
+		return nil, models.NewConflictError("The review of the draft changed in the meantime")
+	}
+	f[postID] = &generated.PostReview{PostId: postID, Status: generated.InReview, SubmittedBy: &editorID}
+	return f.Get(ctx, postID)
+}
+
+func (f fakeReviews) Decide(ctx context.Context, postID uuid.UUID, reviewerID uuid.UUID, status generated.PostReviewStatus, comment *string) (*generated.PostReview, error) {
+	current, _ := f.Get(ctx, postID)
+	if current.Status != generated.InReview {
+		return nil, models.NewConflictError("The review of the draft changed in the meantime")
+	}
+	current.Status, current.Comment, current.ReviewedBy = status, comment, &reviewerID
+	f[postID] = current
+	return f.Get(ctx, postID)
+}
+
+func (f fakeReviews) Queue(context.Context, uuid.UUID, pagination.Page) ([]generated.ReviewQueueItem, *pagination.Cursor, error) {
+	return nil, nil, errors.New("unexpected Queue")
+}
+
+// fakeDrafts holds the posts of the newsletter under review
+type fakeDrafts map[uuid.UUID]*generated.PublishedPost
+
+func (f fakeDrafts) GetPostById(_ context.Context, postID uuid.UUID) (*generated.PublishedPost, error) {
+	if post, ok := f[postID]; ok {
+		return post, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// fakeMembers grants editors their role in one newsletter, like AuthorizationService
+type fakeMembers struct {
+	newsletter *generated.Newsletter
+	roles      map[uuid.UUID]string
+}
+
+func (f *fakeMembers) GetNewsletterForRole(_ context.Context, newsletterID string, editorID string, role string) (*generated.Newsletter, error) {
+	if newsletterID != f.newsletter.Id.String() {
+		return nil, repository.ErrNotFound
+	}
+	current := f.roles[uuid.MustParse(editorID)]
+	if current == "" {
+		return nil, models.NewForbiddenError("You don't have access to this newsletter")
+	}
+	if roleRanks[current] < roleRanks[role] {
+		return nil, models.NewForbiddenError("Your role in this newsletter does not allow this, it requires the " + role + " role")
+	}
+	return f.newsletter, nil
+}
+
+type reviewFixture struct {
+	service      *ReviewService
+	reviews      fakeReviews
+	newsletter   *generated.Newsletter
+	newsletterID uuid.UUID
+	owner        uuid.UUID
+	editor       uuid.UUID
+	viewer       uuid.UUID
+	draftID      uuid.UUID
+	emptyDraftID uuid.UUID
+	postID       uuid.UUID
+}
+
+func newReviewFixture(requireApproval bool) *reviewFixture {
+	f := &reviewFixture{
+		reviews:      fakeReviews{},
+		newsletterID: uuid.New(),
+		owner:        uuid.New(),
+		editor:       uuid.New(),
+		viewer:       uuid.New(),
+		draftID:      uuid.New(),
+		emptyDraftID: uuid.New(),
+		postID:       uuid.New(),
+	}
+	ownerID := openapi_types.UUID(f.owner)
+	f.newsletter = &generated.Newsletter{Id: &f.newsletterID, Name: "Weekly", EditorId: &ownerID, RequirePostApproval: &requireApproval}
+
+	post := func(id uuid.UUID, status enums.PostStatus, content string) *generated.PublishedPost {
+		newsletterID := openapi_types.UUID(f.newsletterID)
+		s := status.String()
+		return &generated.PublishedPost{Id: &id, NewsletterId: &newsletterID, Title: "Spring issue", ContentHtml: content, Status: &s}
+	}
+	posts := fakeDrafts{
+		f.draftID:      post(f.draftID, enums.Draft, "<p>Hello</p>"),
+		f.emptyDraftID: post(f.emptyDraftID, enums.Draft, ""),
+		f.postID:       post(f.postID, enums.Scheduled, "<p>Already scheduled</p>"),
+	}
+	members := &fakeMembers{newsletter: f.newsletter, roles: map[uuid.UUID]string{
+		f.owner:  repository.RoleOwner,
+		f.editor: repository.RoleEditor,
+		f.viewer: repository.RoleViewer,
+	}}
+
+	f.service = &ReviewService{
+		reviewRepo:        f.reviews,
+		postRepo:          posts,
+		newsletterService: members,
+		logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	return f
+}
+
+// wantCode fails unless err is an APIError with the HTTP status code
+func wantCode(t *testing.T, step string, err error, code int) {
+	t.Helper()
+	var apiErr models.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != code {
+		t.Fatalf("%s: error = %v, want a %d error", step, err, code)
+	}
+}
+
+func decision(comment string) generated.PostReviewDecision {
+	return generated.PostReviewDecision{Comment: &comment}
+}
+
+func TestReviewWorkflow(t *testing.T) {
+	f := newReviewFixture(true)
+	ctx := context.Background()
+
+	review, err := f.service.GetReview(ctx, f.viewer, f.newsletterID, f.draftID)
+	if err != nil || review.Status != generated.Draft {
+		t.Fatalf("GetReview before submitting = %+v, %v, want the draft status", review, err)
+	}
+
+	_, err = f.service.Submit(ctx, f.viewer, f.newsletterID, f.draftID)
+	wantCode(t, "viewer submits", err, 403)
+
+	review, err = f.service.Submit(ctx, f.editor, f.newsletterID, f.draftID)
+	if err != nil || review.Status != generated.InReview {
+		t.Fatalf("Submit = %+v, %v, want in_review", review, err)
+	}
+	_, err = f.service.Submit(ctx, f.editor, f.newsletterID, f.draftID)
+	wantCode(t, "submitting a draft in review", err, 409)
+
+	_, err = f.service.Approve(ctx, f.editor, f.newsletterID, f.draftID, generated.PostReviewDecision{})
+	wantCode(t, "editor approves", err, 403)
+	_, err = f.service.Reject(ctx, f.owner, f.newsletterID, f.draftID, decision("  "))
+	wantCode(t, "rejecting without a comment", err, 400)
+
+	review, err = f.service.Reject(ctx, f.owner, f.newsletterID, f.draftID, decision(" Shorten the intro "))
+	if err != nil || review.Status != generated.Rejected || review.Comment == nil || *review.Comment != "Shorten the intro" {
+		t.Fatalf("Reject = %+v, %v, want rejected with the trimmed comment", review, err)
+	}
+	_, err = f.service.Approve(ctx, f.owner, f.newsletterID, f.draftID, generated.PostReviewDecision{})
+	wantCode(t, "approving a rejected draft", err, 409)
+
+	review, err = f.service.Submit(ctx, f.editor, f.newsletterID, f.draftID)
+	if err != nil || review.Status != generated.InReview || review.Comment != nil {
+		t.Fatalf("Submit after rejection = %+v, %v, want in_review without the old comment", review, err)
+	}
+
+	review, err = f.service.Approve(ctx, f.owner, f.newsletterID, f.draftID, generated.PostReviewDecision{})
+	if err != nil || review.Status != generated.Approved || review.Comment != nil || review.ReviewedBy == nil || *review.ReviewedBy != f.owner {
+		t.Fatalf("Approve = %+v, %v, want approved by the owner without a comment", review, err)
+	}
+	_, err = f.service.Submit(ctx, f.editor, f.newsletterID, f.draftID)
+	wantCode(t, "submitting an approved draft", err, 409)
+	_, err = f.service.Reject(ctx, f.owner, f.newsletterID, f.draftID, decision("Too late"))
+	wantCode(t, "rejecting an approved draft", err, 409)
+}
+
+func TestReviewSubmitChecks(t *testing.T) {
+	f := newReviewFixture(true)
+	ctx := context.Background()
+
+	_, err := f.service.Submit(ctx, f.editor, f.newsletterID, f.emptyDraftID)
+	wantCode(t, "submitting a draft without content", err, 400)
+
+	_, err = f.service.Submit(ctx, f.editor, f.newsletterID, f.postID)
+	wantCode(t, "submitting a scheduled post", err, 404)
+
+	_, err = f.service.Submit(ctx, f.editor, f.newsletterID, uuid.New())
+	wantCode(t, "submitting a missing draft", err, 404)
+
+	_, err = f.service.Submit(ctx, f.editor, uuid.New(), f.draftID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("submitting to another newsletter: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCheckPublishable(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireApproval bool
+		status          generated.PostReviewStatus
+		wantConflict    bool
+	}{
+		{name: "approval off, never submitted", status: generated.Draft},
+		{name: "approval off, rejected", status: generated.Rejected},
+		{name: "never submitted", requireApproval: true, status: generated.Draft, wantConflict: true},
+		{name: "in review", requireApproval: true, status: generated.InReview, wantConflict: true},
+		{name: "rejected", requireApproval: true, status: generated.Rejected, wantConflict: true},
+		{name: "approved", requireApproval: true, status: generated.Approved},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newReviewFixture(tc.requireApproval)
+			if tc.status != generated.Draft {
+				f.reviews[f.draftID] = &generated.PostReview{PostId: f.draftID, Status: tc.status}
+			}
+
+			err := f.service.CheckPublishable(context.Background(), f.newsletter, f.draftID)
+			if tc.wantConflict {
+				wantCode(t, "CheckPublishable", err, 409)
+			} else if err != nil {
+				t.Fatalf("CheckPublishable: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckDirectPost(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireApproval bool
+		editor          func(f *reviewFixture) uuid.UUID
+		wantConflict    bool
+	}{
+		{name: "approval off, editor", editor: func(f *reviewFixture) uuid.UUID { return f.editor }},
+		{name: "owner", requireApproval: true, editor: func(f *reviewFixture) uuid.UUID { return f.owner }},
+		{name: "editor", requireApproval: true, editor: func(f *reviewFixture) uuid.UUID { return f.editor }, wantConflict: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newReviewFixture(tc.requireApproval)
+
+			err := f.service.CheckDirectPost(context.Background(), f.newsletter, tc.editor(f))
+			if tc.wantConflict {
+				wantCode(t, "CheckDirectPost", err, 409)
+			} else if err != nil {
+				t.Fatalf("CheckDirectPost: %v", err)
+			}
+		})
+	}
+}
+
+func TestEditReturnsToDraft(t *testing.T) {
+	publishedAt := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name            string
+		requireApproval bool
+		owner           bool
+		published       bool
+		wantDraft       bool
+		wantConflict    bool
+	}{
+		{name: "approval off, editor edits a scheduled post"},
+		{name: "approval off, editor edits a published post", published: true},
+		{name: "owner edits a scheduled post", requireApproval: true, owner: true},
+		{name: "owner edits a published post", requireApproval: true, owner: true, published: true},
+		{name: "editor edits a scheduled post", requireApproval: true, wantDraft: true},
+		{name: "editor edits a published post", requireApproval: true, published: true, wantConflict: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newReviewFixture(tc.requireApproval)
+			editorID := f.editor
+			if tc.owner {
+				editorID = f.owner
+			}
+			post, _ := f.service.postRepo.GetPostById(context.Background(), f.postID)
+			if tc.published {
+				copied := *post
+				copied.PublishedAt = &publishedAt
+				post = &copied
+			}
+
+			toDraft, err := f.service.EditReturnsToDraft(context.Background(), f.newsletter, editorID, post)
+			if tc.wantConflict {
+				wantCode(t, "EditReturnsToDraft", err, 409)
+				return
+			}
+			if err != nil {
+				t.Fatalf("EditReturnsToDraft: %v", err)
+			}
+			if toDraft != tc.wantDraft {
+				t.Errorf("EditReturnsToDraft = %v, want %v", toDraft, tc.wantDraft)
+			}
+		})
+	}
+}
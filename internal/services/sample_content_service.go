@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// sampleSubscriberEmails are the addresses of sample subscribers. The .invalid top-level domain
+// is reserved (RFC 2606), so they could not receive mail even if a send slipped through.
+var sampleSubscriberEmails = []string{
+	"ada.sample@newsletter.invalid",
+	"grace.sample@newsletter.invalid",
+	"linus.sample@newsletter.invalid",
+}
+
+// SampleContentService fills a new newsletter with demo content, so editors can try scheduling
+// and previews before they have an audience
+type SampleContentService struct {
+	sampleContentRepo *repository.SampleContentRepository
+	newsletterService *NewsletterService
+	logger            *slog.Logger
+}
+
+func NewSampleContentService(sampleContentRepo *repository.SampleContentRepository, newsletterService *NewsletterService, logger *slog.Logger) *SampleContentService {
+	utils.RequireDependencies("SampleContentService",
+		utils.Dep("sampleContentRepo", sampleContentRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("logger", logger),
+	)
+	return &SampleContentService{
+		sampleContentRepo: sampleContentRepo,
+		newsletterService: newsletterService,
+		logger:            logger,
+	}
+}
+
+// CreateSampleContent adds a demo draft and sample subscribers to one of the editor's newsletters.
+// Sample subscribers are never emailed, see repository.recipientFilter.
+func (s *SampleContentService) CreateSampleContent(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID) (*generated.SampleContent, error) {
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleEditor)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.NewNotFoundError("Newsletter not found")
+		}
+		return nil, err
+	}
+
+	contentHTML := fmt.Sprintf(`
+		<h1>Welcome to %s</h1>
+		<p>This is a sample draft. Edit it, preview it or schedule it to see how publishing works.</p>
+		<p>The sample subscribers added with it never receive emails; only your real subscribers do.</p>
+	`, html.EscapeString(newsletter.Name))
+	contentText := fmt.Sprintf("Welcome to %s\n\nThis is a sample draft. Edit it, preview it or schedule it to see how publishing works.\n"+
+		"The sample subscribers added with it never receive emails; only your real subscribers do.", newsletter.Name)
+	draft := &generated.DraftRequest{
+		Title:       fmt.Sprintf("Welcome to %s", newsletter.Name),
+		ContentHtml: &contentHTML,
+		ContentText: &contentText,
+	}
+
+	content, err := s.sampleContentRepo.Create(ctx, editorID, newsletterID, draft, sampleSubscriberEmails)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Created sample content", "newsletterId", newsletterID, "draftId", content.Draft.Id, "subscribers", len(content.Subscribers))
+	return content, nil
+}
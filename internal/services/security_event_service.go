@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+const ClientInfoContextKey contextKey = "clientInfo"
+
+// ClientInfo describes the browser or device a request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// Location is the approximate location a CDN in front of the API reports, if any
+	Location string
+}
+
+// WithClientInfo adds client info to the request context
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, ClientInfoContextKey, info)
+}
+
+// ClientInfoFromContext extracts client info from request context; zero when there is none
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(ClientInfoContextKey).(ClientInfo)
+	return info
+}
+
+// SecurityEventService records sign-ins and other security events of editors in the audit
+// log, and emails editors when their account is signed in to from a new browser or device
+type SecurityEventService struct {
+	repo           *repository.SecurityEventRepository
+	mailingService *MailingService
+	logger         *slog.Logger
+}
+
+func NewSecurityEventService(repo *repository.SecurityEventRepository, mailingService *MailingService, logger *slog.Logger) *SecurityEventService {
+	utils.RequireDependencies("SecurityEventService",
+		utils.Dep("repo", repo),
+		utils.Dep("mailingService", mailingService),
+		utils.Dep("logger", logger),
+	)
+	return &SecurityEventService{
+		repo:           repo,
+		mailingService: mailingService,
+		logger:         logger,
+	}
+}
+
+// RecordSignIn records a sign-in with the client info of the request. When the editor signed in
+// before, but never with this user agent, the sign-in is from a new device and they are emailed.
+// Failures are logged, they never fail the sign-in.
+func (s *SecurityEventService) RecordSignIn(ctx context.Context, userID uuid.UUID, email string, method string) {
+	ctx = context.WithoutCancel(ctx)
+	client := ClientInfoFromContext(ctx)
+
+	signedInBefore, knownDevice, err := s.repo.SignInHistory(ctx, userID, client.UserAgent)
+	if err != nil {
+		return
+	}
+	newDevice := signedInBefore && !knownDevice
+
+	err = s.repo.Record(ctx, repository.SecurityEvent{
+		ActorID:   userID,
+		Action:    repository.AuditSignIn,
+		Method:    method,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Location:  client.Location,
+		NewDevice: newDevice,
+	})
+	if err != nil || !newDevice || email == "" {
+		return
+	}
+	s.notifyNewDevice(ctx, userID, email, method, client)
+}
+
+// RecordAccountLinked records that an identity provider was linked to the editor's account
+func (s *SecurityEventService) RecordAccountLinked(ctx context.Context, accountID uuid.UUID, provider string) {
+	ctx = context.WithoutCancel(ctx)
+	client := ClientInfoFromContext(ctx)
+
+	_ = s.repo.Record(ctx, repository.SecurityEvent{
+		ActorID:   accountID,
+		Action:    repository.AuditAccountLinked,
+		Method:    provider,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Location:  client.Location,
+	})
+}
+
+// List returns a page of the editor's security events, newest first
+func (s *SecurityEventService) List(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]generated.SecurityEvent, *pagination.Cursor, error) {
+	return s.repo.ListByActor(ctx, userID, page)
+}
+
+func (s *SecurityEventService) notifyNewDevice(ctx context.Context, userID uuid.UUID, email string, method string, client ClientInfo) {
+	body := fmt.Sprintf(`
+		<h1>New sign-in to your account</h1>
+		<p>Your newsletter account was signed in to from a browser or device it was not used on before.</p>
+		<ul>
+			<li>When: %s</li>
+			<li>How: %s</li>
+			<li>Device: %s</li>
+			<li>IP address: %s</li>
+			<li>Location: %s</li>
+		</ul>
+		<p>If this was you, there is nothing to do. If it was not, reset your password and review the sign-ins of your account.</p>
+	`,
+		html.EscapeString(time.Now().UTC().Format("2 January 2006 15:04 MST")),
+		html.EscapeString(signInMethodName(method)),
+		html.EscapeString(orUnknown(client.UserAgent)),
+		html.EscapeString(orUnknown(client.IP)),
+		html.EscapeString(orUnknown(client.Location)),
+	)
+	if err := s.mailingService.SendMail(ctx, email, "New sign-in to your account", body); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to notify editor about new device sign-in", "userId", userID, "error", err)
+	}
+}
+
+func signInMethodName(method string) string {
+	if method == "magic_link" {
+		return "sign-in link"
+	}
+	return method
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// SessionService refreshes the Supabase sessions of editors, and signs users out of every
+// browser and device for admins responding to a compromised account
+type SessionService struct {
+	repo     *repository.SessionRepository
+	supabase *supabaseAuth
+	logger   *slog.Logger
+}
+
+func NewSessionService(repo *repository.SessionRepository, authService *AuthService, profileService *ProfileService, httpClient *http.Client, config *config.Config, logger *slog.Logger) *SessionService {
+	utils.RequireDependencies("SessionService",
+		utils.Dep("repo", repo),
+		utils.Dep("authService", authService),
+		utils.Dep("profileService", profileService),
+		utils.Dep("httpClient", httpClient),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &SessionService{
+		repo: repo,
+		supabase: &supabaseAuth{
+			authService:    authService,
+			profileService: profileService,
+			httpClient:     httpClient,
+			config:         config,
+			logger:         logger,
+		},
+		logger: logger,
+	}
+}
+
+// Refresh exchanges the refresh token of a session for a new one at Supabase Auth. Tokens
+// Supabase refuses (invalid, used, expired or of a revoked session) mean the editor must sign
+// in again; a refresh is not a sign-in, so it is not recorded as a security event.
+func (s *SessionService) Refresh(ctx context.Context, req generated.RefreshTokenRequest) (*generated.AuthResponse, error) {
+	refreshToken := strings.TrimSpace(req.RefreshToken)
+	if refreshToken == "" {
+		return nil, models.NewBadRequestError("refresh_token is required")
+	}
+
+	var session supabaseSession
+	err := s.supabase.post(ctx, "/auth/v1/token?grant_type=refresh_token", map[string]string{"refresh_token": refreshToken}, &session)
+	var authErr *supabaseAuthError
+	if errors.As(err, &authErr) {
+		switch {
+		case authErr.Status == http.StatusTooManyRequests:
+			return nil, models.NewTooManyRequestsError("Too many refreshes of this session, please wait before trying again")
+		case authErr.Status >= 400 && authErr.Status < 500:
+			s.logger.InfoContext(ctx, "Supabase Auth refused a refresh token", "status", authErr.Status, "detail", authErr.Detail)
+			return nil, models.NewUnauthorizedError("The session expired or was signed out, please sign in again")
+		}
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to refresh a session", "error", err)
+		return nil, models.NewBadGatewayError("The session could not be refreshed, please try again later")
+	}
+
+	claims, err := s.supabase.verify(ctx, &session, "refresh_token")
+	if err != nil {
+		return nil, err
+	}
+	return s.supabase.authResponse(ctx, &session, claims)
+}
+
+// RevokeSessions signs the user out of every session through the admin API of Supabase Auth,
+// so their refresh tokens no longer renew a sign-in, and revokes their API keys and integration
+// clients when asked to. Access tokens already issued stay valid until they expire.
+func (s *SessionService) RevokeSessions(ctx context.Context, adminID uuid.UUID, userID uuid.UUID, req generated.SessionRevocation) (*generated.SessionRevocationResult, error) {
+	if s.supabase.config.Supabase.ServiceRoleKey == "" {
+		s.logger.ErrorContext(ctx, "Cannot revoke sessions without SUPABASE_SERVICE_ROLE_KEY", "userId", userID)
+		return nil, models.NewInternalServerError("Revoking sessions is not configured")
+	}
+
+	sessions, err := s.repo.CountSessions(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewNotFoundError("User not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.supabase.admin(ctx, http.MethodPost, "/auth/v1/admin/users/"+userID.String()+"/logout", nil)
+	var authErr *supabaseAuthError
+	if errors.As(err, &authErr) && authErr.Status == http.StatusNotFound {
+		return nil, models.NewNotFoundError("User not found")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to sign a user out", "userId", userID, "error", err)
+		return nil, models.NewBadGatewayError("The sessions could not be revoked, please try again later")
+	}
+
+	credentials := req.RevokeCredentials != nil && *req.RevokeCredentials
+	revoked, err := s.repo.RevokeAll(ctx, adminID, userID, sessions, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Admin revoked the sessions of a user", "adminId", adminID, "userId", userID,
+		"sessions", revoked.Sessions, "apiKeys", revoked.APIKeys, "integrationClients", revoked.IntegrationClients)
+	return &generated.SessionRevocationResult{
+		SessionsRevoked:           &revoked.Sessions,
+		ApiKeysRevoked:            &revoked.APIKeys,
+		IntegrationClientsRevoked: &revoked.IntegrationClients,
+	}, nil
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const (
+	// accountLinkTTL is how long the editor has to confirm linking a sign-in to their account
+	accountLinkTTL         = 15 * time.Minute
+	accountLinkTokenPrefix = "nllt_"
+)
+
+// SocialSignIn is where to send the browser to sign in with an identity provider, and the PKCE
+// verifier to keep in the browser until the provider redirects back
+type SocialSignIn struct {
+	URL      string
+	Verifier string
+}
+
+// AccountLinkRequiredError is returned by Complete when the email of a first sign-in with a
+// provider belongs to a password account. Instead of a second profile, the editor links the
+// provider to that account with LinkAccount.
+type AccountLinkRequiredError struct {
+	Link generated.AccountLinkRequired
+}
+
+func (e *AccountLinkRequiredError) Error() string {
+	return "sign-in must be linked to the password account of " + string(e.Link.Email)
+}
+
+// SocialAuthService signs editors in with Google, GitHub or another identity provider through
+// the OAuth flow of Supabase Auth. The flow uses PKCE, so a code returned to the callback can
+// only be exchanged by the browser that started the sign-in.
+type SocialAuthService struct {
+	supabase        *supabaseAuth
+	accountLinkRepo *repository.AccountLinkRepository
+	config          *config.Config
+	logger          *slog.Logger
+}
+
+func NewSocialAuthService(authService *AuthService, profileService *ProfileService, securityEventService *SecurityEventService, accountLinkRepo *repository.AccountLinkRepository, httpClient *http.Client, config *config.Config, logger *slog.Logger) *SocialAuthService {
+	utils.RequireDependencies("SocialAuthService",
+		utils.Dep("authService", authService),
+		utils.Dep("profileService", profileService),
+		utils.Dep("securityEventService", securityEventService),
+		utils.Dep("accountLinkRepo", accountLinkRepo),
+		utils.Dep("httpClient", httpClient),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &SocialAuthService{
+		supabase: &supabaseAuth{
+			authService:    authService,
+			profileService: profileService,
+			securityEvents: securityEventService,
+			httpClient:     httpClient,
+			config:         config,
+			logger:         logger,
+		},
+		accountLinkRepo: accountLinkRepo,
+		config:          config,
+		logger:          logger,
+	}
+}
+
+// Start returns the Supabase URL that signs the browser in with the provider and redirects it
+// back to the callback of the provider
+func (s *SocialAuthService) Start(provider string) (*SocialSignIn, error) {
+	if err := s.checkProvider(provider); err != nil {
+		return nil, err
+	}
+
+	verifier, query, err := s.authorizeQuery(provider)
+	if err != nil {
+		return nil, err
+	}
+	return &SocialSignIn{
+		URL:      s.supabase.url("/auth/v1/authorize") + "?" + query.Encode(),
+		Verifier: verifier,
+	}, nil
+}
+
+// Complete exchanges the code the provider returned for a Supabase session and creates the
+// editor's profile on their first sign-in. A first sign-in with the email of a password account
+// returns *AccountLinkRequiredError instead.
+func (s *SocialAuthService) Complete(ctx context.Context, provider string, code string, verifier string) (*generated.AuthResponse, error) {
+	if err := s.checkProvider(provider); err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, models.NewBadRequestError("code is required")
+	}
+
+	session, err := s.exchangeCode(ctx, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.supabase.verify(ctx, session, provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireLink(ctx, session, claims, provider); err != nil {
+		return nil, err
+	}
+	return s.supabase.respond(ctx, session, claims, provider)
+}
+
+// LinkAccount completes a link request once the password proves the editor owns the account:
+// the name and avatar from the provider fill in those the account's profile lacks, the user the
+// sign-in created is deleted, and the returned sign-in links the provider to the account. The
+// browser goes through the provider once more and comes back to the callback signed in to the
+// account.
+func (s *SocialAuthService) LinkAccount(ctx context.Context, req generated.AccountLinkConfirmation) (*SocialSignIn, error) {
+	if req.LinkToken == "" || req.Password == "" {
+		return nil, models.NewBadRequestError("link_token and password are required")
+	}
+	tokenHash := hashSecret(req.LinkToken)
+	link, err := s.accountLinkRepo.GetActive(ctx, tokenHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewBadRequestError("The link request expired, please sign in with the provider again")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session supabaseSession
+	err = s.supabase.post(ctx, "/auth/v1/token?grant_type=password", map[string]string{"email": link.Email, "password": req.Password}, &session)
+	var authErr *supabaseAuthError
+	if errors.As(err, &authErr) && authErr.Status >= 400 && authErr.Status < 500 {
+		s.logger.WarnContext(ctx, "Password of an account link was refused", "accountId", link.AccountID, "status", authErr.Status)
+		return nil, models.NewUnauthorizedError("The password is incorrect")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to check the password of an account link", "error", err)
+		return nil, models.NewBadGatewayError("Linking failed, please try again later")
+	}
+	claims, err := s.supabase.verify(ctx, &session, "password")
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserID != link.AccountID.String() {
+		s.logger.WarnContext(ctx, "Password of an account link signed in to another account", "accountId", link.AccountID, "userId", claims.UserID)
+		return nil, models.NewConflictError("The email now belongs to another account, please sign in with the provider again")
+	}
+
+	signIn, err := s.authorizeLink(ctx, session.AccessToken, link.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.accountLinkRepo.Merge(ctx, tokenHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewBadRequestError("The link request expired, please sign in with the provider again")
+	}
+	if errors.Is(err, repository.ErrAccountInUse) {
+		return nil, models.NewConflictError("The account of this sign-in already has newsletters, so it cannot be merged")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "Merged a sign-in into the password account", "provider", link.Provider, "userId", link.UserID, "accountId", link.AccountID)
+	s.supabase.securityEvents.RecordAccountLinked(ctx, link.AccountID, link.Provider)
+	return signIn, nil
+}
+
+// CallbackURL is where Supabase sends the browser back to after signing in with the provider;
+// it must be on the allowed redirect URLs of the Supabase project
+func (s *SocialAuthService) CallbackURL(provider string) string {
+	return fmt.Sprintf("%s/auth/oauth/%s/callback", s.config.BuildApiBaseUrl(), url.PathEscape(provider))
+}
+
+// requireLink returns *AccountLinkRequiredError and stores the link request when the sign-in
+// created a new user for the email of a password account
+func (s *SocialAuthService) requireLink(ctx context.Context, session *supabaseSession, claims *UserClaims, provider string) error {
+	if claims.Email == "" {
+		return nil
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return err
+	}
+	accountID, err := s.accountLinkRepo.FindPasswordAccount(ctx, userID, claims.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newSecret(accountLinkTokenPrefix, 32)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(accountLinkTTL)
+	err = s.accountLinkRepo.Create(ctx, hashSecret(token), repository.AccountLinkRequest{
+		UserID:    userID,
+		AccountID: accountID,
+		Email:     claims.Email,
+		Provider:  provider,
+		FullName:  metadataString(session.User.UserMetadata, "full_name", "name"),
+		AvatarURL: metadataString(session.User.UserMetadata, "avatar_url", "picture"),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Sign-in must be linked to a password account", "provider", provider, "userId", userID, "accountId", accountID)
+	return &AccountLinkRequiredError{Link: generated.AccountLinkRequired{
+		LinkToken: token,
+		Email:     openapi_types.Email(claims.Email),
+		Provider:  provider,
+		ExpiresAt: expiresAt,
+	}}
+}
+
+// authorizeLink returns the Supabase URL that links the provider to the user of accessToken.
+// Supabase only links identities when manual linking is enabled in the project.
+func (s *SocialAuthService) authorizeLink(ctx context.Context, accessToken string, provider string) (*SocialSignIn, error) {
+	verifier, query, err := s.authorizeQuery(provider)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("skip_http_redirect", "true")
+
+	var authorize struct {
+		URL string `json:"url"`
+	}
+	err = s.supabase.do(ctx, http.MethodGet, "/auth/v1/user/identities/authorize?"+query.Encode(), accessToken, nil, &authorize)
+	if err == nil && authorize.URL == "" {
+		err = errors.New("supabase auth: identity link without URL")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to start linking the provider; is manual linking enabled?", "provider", provider, "error", err)
+		return nil, models.NewBadGatewayError("Linking failed, please try again later")
+	}
+	return &SocialSignIn{URL: authorize.URL, Verifier: verifier}, nil
+}
+
+// authorizeQuery returns a new PKCE verifier and the query that signs in with the provider
+// and comes back to its callback
+func (s *SocialAuthService) authorizeQuery(provider string) (string, url.Values, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(random)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := url.Values{}
+	query.Set("provider", provider)
+	query.Set("redirect_to", s.CallbackURL(provider))
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "s256")
+	return verifier, query, nil
+}
+
+func (s *SocialAuthService) checkProvider(provider string) error {
+	if !slices.Contains(s.config.Supabase.OAuthProviders, provider) {
+		return models.NewBadRequestError(fmt.Sprintf("Unsupported identity provider, use one of %s", strings.Join(s.config.Supabase.OAuthProviders, ", ")))
+	}
+	return nil
+}
+
+// exchangeCode redeems the code of a PKCE sign-in at Supabase Auth. Codes Supabase refuses
+// (expired, used or of another browser) are the caller's problem; anything else is Supabase's.
+func (s *SocialAuthService) exchangeCode(ctx context.Context, code string, verifier string) (*supabaseSession, error) {
+	var session supabaseSession
+	err := s.supabase.post(ctx, "/auth/v1/token?grant_type=pkce", map[string]string{"auth_code": code, "code_verifier": verifier}, &session)
+	var authErr *supabaseAuthError
+	if errors.As(err, &authErr) && authErr.Status >= 400 && authErr.Status < 500 {
+		s.logger.WarnContext(ctx, "Supabase Auth refused the sign-in code", "status", authErr.Status, "detail", authErr.Detail)
+		return nil, models.NewUnauthorizedError("The sign-in expired or was already used, please start again")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Supabase Auth failed to exchange the sign-in code", "error", err)
+		return nil, models.NewBadGatewayError("Sign-in failed, please try again later")
+	}
+	return &session, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// statsDateLayout is the format of the from and to query parameters and of bucket starts
+const statsDateLayout = "2006-01-02"
+
+// maxGrowthBuckets bounds the intervals of one subscriber growth request, a year of days
+const maxGrowthBuckets = 366
+
+// defaultGrowthBuckets is how many intervals are reported when from is not given
+var defaultGrowthBuckets = map[string]int{
+	repository.IntervalDay:  30,
+	repository.IntervalWeek: 12,
+}
+
+// StatsService reports newsletter activity over time, for editors to chart
+type StatsService struct {
+	statsRepo         *repository.StatsRepository
+	newsletterService *NewsletterService
+	logger            *slog.Logger
+}
+
+func NewStatsService(statsRepo *repository.StatsRepository, newsletterService *NewsletterService, logger *slog.Logger) *StatsService {
+	utils.RequireDependencies("StatsService",
+		utils.Dep("statsRepo", statsRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("logger", logger),
+	)
+	return &StatsService{
+		statsRepo:         statsRepo,
+		newsletterService: newsletterService,
+		logger:            logger,
+	}
+}
+
+// GetSubscriberGrowth checks the editor may view the newsletter and returns its subscriptions,
+// confirmations and unsubscriptions per day or week (interval) from from to to, both
+// YYYY-MM-DD (UTC) and included. Empty parameters mean daily counts of the last 30 days, or
+// weekly counts of the last 12 weeks. Weeks start on Monday.
+func (s *StatsService) GetSubscriberGrowth(ctx context.Context, newsletterID uuid.UUID, editorID string, interval string, from string, to string) (*generated.SubscriberGrowth, error) {
+	if interval == "" {
+		interval = repository.IntervalDay
+	}
+	if interval != repository.IntervalDay && interval != repository.IntervalWeek {
+		return nil, models.NewBadRequestError("interval must be day or week")
+	}
+	start, end, err := growthRange(interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleViewer)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	buckets, err := s.statsRepo.SubscriberGrowth(ctx, newsletterID, interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	growth := &generated.SubscriberGrowth{
+		NewsletterId: newsletterID,
+		Interval:     interval,
+		From:         start.Format(statsDateLayout),
+		To:           end.AddDate(0, 0, -1).Format(statsDateLayout),
+		Buckets:      make([]generated.SubscriberGrowthBucket, 0, len(buckets)),
+	}
+	for _, bucket := range buckets {
+		growth.Buckets = append(growth.Buckets, generated.SubscriberGrowthBucket{
+			Start:        bucket.Start.Format(statsDateLayout),
+			Subscribed:   bucket.Subscribed,
+			Confirmed:    bucket.Confirmed,
+			Unsubscribed: bucket.Unsubscribed,
+		})
+	}
+	return growth, nil
+}
+
+// growthRange returns the start of the first interval and the end of the last one (exclusive)
+// covering the days from and to
+func growthRange(interval string, from string, to string) (time.Time, time.Time, error) {
+	last := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		parsed, err := time.Parse(statsDateLayout, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, models.NewBadRequestError("to must be a date as YYYY-MM-DD")
+		}
+		last = parsed
+	}
+
+	step := func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
+	if interval == repository.IntervalWeek {
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	}
+	end := step(intervalStart(interval, last), 1)
+
+	var start time.Time
+	if from == "" {
+		start = step(end, -defaultGrowthBuckets[interval])
+	} else {
+		parsed, err := time.Parse(statsDateLayout, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, models.NewBadRequestError("from must be a date as YYYY-MM-DD")
+		}
+		if parsed.After(last) {
+			return time.Time{}, time.Time{}, models.NewBadRequestError("from must not be after to")
+		}
+		start = intervalStart(interval, parsed)
+	}
+
+	if step(start, maxGrowthBuckets).Before(end) {
+		return time.Time{}, time.Time{}, models.NewBadRequestError(fmt.Sprintf("The range must not cover more than %d intervals", maxGrowthBuckets))
+	}
+	return start, end, nil
+}
+
+// intervalStart returns the start of the interval containing day, a UTC midnight
+func intervalStart(interval string, day time.Time) time.Time {
+	if interval == repository.IntervalWeek {
+		// Monday starts the week, like date_trunc('week')
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
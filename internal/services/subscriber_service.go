@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go-newsletter/internal/utils"
 	"log/slog"
+	"strings"
+	"time"
 
 	"go-newsletter/internal/config"
+	"go-newsletter/internal/emailrender"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/models/enums"
+	"go-newsletter/internal/pagination"
 	"go-newsletter/internal/repository"
 	"go-newsletter/pkg/generated"
 
@@ -18,67 +25,264 @@ import (
 var (
 	ErrNotFound          = errors.New("not found")
 	ErrForbidden         = errors.New("forbidden")
-	ErrAlreadySubscribed = errors.New("already subscribed")
+	ErrAlreadySubscribed = repository.ErrAlreadySubscribed
+)
+
+// emailChangeTTL is how long the verification link of an address change stays valid
+const emailChangeTTL = 24 * time.Hour
+
+const (
+	// confirmationResendCooldown keeps editors from resending a confirmation the subscriber just got
+	confirmationResendCooldown = time.Hour
+	// confirmationRequestCooldown keeps anyone from flooding an address with confirmation links
+	confirmationRequestCooldown = 10 * time.Minute
+	// maxConfirmationRetryDelay caps the exponential backoff between confirmation retries
+	maxConfirmationRetryDelay = 24 * time.Hour
+	// confirmationRetryBatch is how many due retries one run of the retry job sends
+	confirmationRetryBatch = 100
+	// confirmationRetryLease keeps a claimed retry from other instances while it is sent
+	confirmationRetryLease = 10 * time.Minute
+)
+
+// The services SubscriberService calls on its way to creating subscriptions, narrowed so tests
+// can stand in for them
+type (
+	subscriberNewsletters interface {
+		GetNewsletterByID(ctx context.Context, newsletterID string) (*generated.Newsletter, error)
+		GetNewsletterForRole(ctx context.Context, newsletterID string, editorID string, role string) (*generated.Newsletter, error)
+	}
+	subscriberQuota interface {
+		CheckSubscriberQuota(ctx context.Context, editorID uuid.UUID) error
+	}
+	subscriberSuppressions interface {
+		IsBlocked(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error)
+		IsSuppressed(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error)
+		Lift(ctx context.Context, email string) error
+	}
+	subscriberEvents interface {
+		SubscriberConfirmed(ctx context.Context, subscription *repository.ChangedSubscription)
+		SubscriberUnsubscribed(ctx context.Context, subscription *repository.ChangedSubscription)
+	}
 )
 
 type SubscriberService struct {
-	subscriberRepo    *repository.SubscriberRepository
-	newsletterService *NewsletterService
-	mailingService    *MailingService
-	logger            *slog.Logger
-	config            *config.Config
-}
-
-func NewSubscriberService(
-	subscriberRepo *repository.SubscriberRepository,
-	newsletterService *NewsletterService,
-	mailingService *MailingService,
-	config *config.Config,
-	logger *slog.Logger,
-) *SubscriberService {
+	subscriberRepo       *repository.SubscriberRepository
+	newsletterService    subscriberNewsletters
+	mailingService       *MailingService
+	emailJobService      *EmailJobService
+	planService          subscriberQuota
+	suppressionService   subscriberSuppressions
+	costService          *CostService
+	webhookService       subscriberEvents
+	emailTemplateService *EmailTemplateService
+	logger               *slog.Logger
+	config               *config.Config
+}
+
+// SubscriberServiceDeps are the repository and services SubscriberService is built from,
+// named like PostServiceDeps
+type SubscriberServiceDeps struct {
+	Subscribers *repository.SubscriberRepository
+
+	Newsletter    *NewsletterService
+	Mailing       *MailingService
+	EmailJob      *EmailJobService
+	Plan          *PlanService
+	Suppression   *SuppressionService
+	Cost          *CostService
+	Webhook       *WebhookService
+	EmailTemplate *EmailTemplateService
+
+	Config *config.Config
+	Logger *slog.Logger
+}
+
+func NewSubscriberService(d SubscriberServiceDeps) *SubscriberService {
+	utils.RequireDependencies("SubscriberService",
+		utils.Dep("Subscribers", d.Subscribers),
+		utils.Dep("Newsletter", d.Newsletter),
+		utils.Dep("Mailing", d.Mailing),
+		utils.Dep("EmailJob", d.EmailJob),
+		utils.Dep("Plan", d.Plan),
+		utils.Dep("Suppression", d.Suppression),
+		utils.Dep("Cost", d.Cost),
+		utils.Dep("Webhook", d.Webhook),
+		utils.Dep("EmailTemplate", d.EmailTemplate),
+		utils.Dep("Config", d.Config),
+		utils.Dep("Logger", d.Logger),
+	)
 	return &SubscriberService{
-		subscriberRepo:    subscriberRepo,
-		newsletterService: newsletterService,
-		mailingService:    mailingService,
-		config:            config,
-		logger:            logger,
+		subscriberRepo:       d.Subscribers,
+		newsletterService:    d.Newsletter,
+		mailingService:       d.Mailing,
+		emailJobService:      d.EmailJob,
+		planService:          d.Plan,
+		suppressionService:   d.Suppression,
+		costService:          d.Cost,
+		webhookService:       d.Webhook,
+		emailTemplateService: d.EmailTemplate,
+		config:               d.Config,
+		logger:               d.Logger,
 	}
 }
 
-// ListSubscribers retrieves a list of subscribers for a newsletter, checks if the user is the owner of the newsletter
+// ListSubscribers retrieves a page of subscribers for a newsletter, checks if the user is the owner of the newsletter
 func (s *SubscriberService) ListSubscribers(
 	ctx context.Context,
 	newsletterID uuid.UUID,
 	editorID string,
-) ([]*generated.Subscriber, error) {
+	page pagination.Page,
+) ([]*generated.Subscriber, *pagination.Cursor, error) {
 	// Verify newsletter ownership
-	_, err := s.newsletterService.GetNewsletterByIDCheckOwnership(ctx, newsletterID.String(), editorID)
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleViewer)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrNotFound
+			return nil, nil, ErrNotFound
 		}
 		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Get subscribers
-	subscribers, err := s.subscriberRepo.ListByNewsletterID(ctx, newsletterID)
+	subscribers, next, err := s.subscriberRepo.ListByNewsletterID(ctx, newsletterID, page)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to list subscribers", "error", err)
+		return nil, nil, err
+	}
+
+	return subscribers, next, nil
+}
+
+// StreamSubscribers checks the user's role on the newsletter and then passes the newsletter's
+// subscribers to fn one by one
+func (s *SubscriberService) StreamSubscribers(
+	ctx context.Context,
+	newsletterID uuid.UUID,
+	editorID string,
+	fn func(*generated.Subscriber) error,
+) error {
+	// Verify newsletter ownership
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleViewer)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return err
+	}
+
+	if err := s.subscriberRepo.StreamByNewsletterID(ctx, newsletterID, fn); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to stream subscribers", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// ExportSubscribers passes every subscriber of one of the editor's newsletters to fn as it is
+// read from the database
+func (s *SubscriberService) ExportSubscribers(ctx context.Context, newsletterID uuid.UUID, editorID string, fn func(row *generated.SubscriberExportRow) error) error {
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return err
+	}
+
+	return s.subscriberRepo.ExportByNewsletterID(ctx, newsletterID, fn)
+}
+
+// DeleteSubscriber marks a subscriber of the newsletter deleted. Deleted subscribers get no posts
+// and can be restored until the retention job deletes them for good.
+func (s *SubscriberService) DeleteSubscriber(ctx context.Context, newsletterID uuid.UUID, subscriberID uuid.UUID, editorID string) error {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor); err != nil {
+		return err
+	}
+
+	err := s.subscriberRepo.Delete(ctx, newsletterID, subscriberID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("Subscriber not found")
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Subscriber deleted", "newsletterId", newsletterID, "subscriberId", subscriberID, "editorId", editorID)
+	return nil
+}
+
+// ListDeletedSubscribers lists the newsletter's deleted subscribers that can still be restored
+func (s *SubscriberService) ListDeletedSubscribers(ctx context.Context, newsletterID uuid.UUID, editorID string, page pagination.Page) ([]*generated.Subscriber, *pagination.Cursor, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor); err != nil {
+		return nil, nil, err
+	}
+	return s.subscriberRepo.ListDeleted(ctx, newsletterID, s.restorableSince(), page)
+}
+
+// RestoreSubscriber restores a deleted subscriber of the newsletter unless it was deleted too
+// long ago. The restored subscriber counts towards the subscriber limit of the owner's plan.
+func (s *SubscriberService) RestoreSubscriber(ctx context.Context, newsletterID uuid.UUID, subscriberID uuid.UUID, editorID string) (*generated.Subscriber, error) {
+	newsletter, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.planService.CheckSubscriberQuota(ctx, uuid.UUID(*newsletter.EditorId)); err != nil {
 		return nil, err
 	}
 
-	return subscribers, nil
+	restored, err := s.subscriberRepo.Restore(ctx, newsletterID, subscriberID, s.restorableSince())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewNotFoundError("Deleted subscriber not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Subscriber restored", "newsletterId", newsletterID, "subscriberId", subscriberID, "editorId", editorID)
+	return restored, nil
+}
+
+// MergeSubscribers merges a duplicate subscriber of the newsletter into another, see
+// SubscriberRepository.Merge
+func (s *SubscriberService) MergeSubscribers(ctx context.Context, newsletterID uuid.UUID, editorID string, req generated.SubscriberMerge) (*generated.Subscriber, error) {
+	if req.KeepId == uuid.Nil || req.MergeId == uuid.Nil {
+		return nil, models.NewBadRequestError("keep_id and merge_id are required")
+	}
+	if req.KeepId == req.MergeId {
+		return nil, models.NewBadRequestError("keep_id and merge_id must be different subscribers")
+	}
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	merged, err := s.subscriberRepo.Merge(ctx, newsletterID, req.KeepId, req.MergeId, editorID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, models.NewNotFoundError("Subscriber not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Subscribers merged", "newsletterId", newsletterID, "keepId", req.KeepId, "mergeId", req.MergeId, "editorId", editorID)
+	return merged, nil
+}
+
+// restorableSince is the earliest deletion time of subscribers that can still be restored
+func (s *SubscriberService) restorableSince() time.Time {
+	if s.config.Retention.DeletedDays <= 0 {
+		return time.Time{}
+	}
+	return time.Now().AddDate(0, 0, -s.config.Retention.DeletedDays)
 }
 
-// ListSubscribers retrieves a list of subscribers for a newsletter
+// ListSubscribersWithouCheck retrieves the subscribers a post of the newsletter is sent to:
+// unsubscribed and suppressed addresses are left out
 func (s *SubscriberService) ListSubscribersWithouCheck(
 	ctx context.Context,
 	newsletterID uuid.UUID,
 ) ([]*generated.Subscriber, error) {
 
 	// Get subscribers
-	subscribers, err := s.subscriberRepo.ListByNewsletterID(ctx, newsletterID)
+	subscribers, err := s.subscriberRepo.ListRecipientsByNewsletterID(ctx, newsletterID)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to list subscribers", "error", err)
 		return nil, err
@@ -87,6 +291,11 @@ func (s *SubscriberService) ListSubscribersWithouCheck(
 	return subscribers, nil
 }
 
+// CountRecipients returns how many subscribers a post of the newsletter is sent to
+func (s *SubscriberService) CountRecipients(ctx context.Context, newsletterID uuid.UUID) (int, error) {
+	return s.subscriberRepo.CountByNewsletterID(ctx, newsletterID)
+}
+
 // Subscribe adds a new subscriber to a newsletter
 func (s *SubscriberService) Subscribe(
 	ctx context.Context,
@@ -113,50 +322,317 @@ func (s *SubscriberService) Subscribe(
 		return nil, ErrAlreadySubscribed
 	}
 
-	// Create subscriber
-	subscriber, err := s.subscriberRepo.Create(ctx, newsletterID, string(email))
+	// Enforce the subscriber limit of the editor's plan
+	if err := s.planService.CheckSubscriberQuota(ctx, uuid.UUID(*newsletter.EditorId)); err != nil {
+		return nil, err
+	}
+
+	// Addresses the editor blocked subscribe like any other, so the response does not tell them
+	// apart, but get no confirmation email; retries and resends skip them as well
+	blocked, err := s.suppressionService.IsBlocked(ctx, newsletterID, string(email))
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to create subscriber", "error", err)
+		s.logger.ErrorContext(ctx, "Failed to check newsletter suppression list", "error", err)
 		return nil, err
 	}
 
-	// Send confirmation email
-	confirmationLink := fmt.Sprintf("%s/subscribe/confirm/%s", s.config.BuildApiBaseUrl(), *subscriber.ConfirmationToken)
-	htmlContent := fmt.Sprintf(`
-		<h1>Confirm Your Subscription to %s</h1>
-		<p>Thank you for subscribing to our newsletter! Please click the link below to confirm your subscription:</p>
-		<p><a href="%s">Confirm Subscription</a></p>
-		<p>If you did not request this subscription, you can safely ignore this email.</p>
-	`, newsletter.Name, confirmationLink)
+	// Without double opt-in the subscription is confirmed at once. Suppressed addresses still
+	// confirm by email, as only an explicit opt-in lifts their suppression.
+	confirmNow := newsletter.DoubleOptIn != nil && !*newsletter.DoubleOptIn
+	if confirmNow {
+		suppressed, err := s.suppressionService.IsSuppressed(ctx, newsletterID, string(email))
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Failed to check email suppression", "error", err)
+			return nil, err
+		}
+		confirmNow = !suppressed
+	}
+
+	var confirmationExpiresAt *time.Time
+	if !confirmNow {
+		confirmationExpiresAt = s.confirmationExpiry()
+	}
 
-	err = s.mailingService.SendMail([]string{string(email)}, "Confirm Your Newsletter Subscription", htmlContent)
+	// Create subscriber; a concurrent request may have won the race since ExistsByEmail
+	subscriber, err := s.subscriberRepo.Create(ctx, newsletterID, string(email), confirmNow, confirmationExpiresAt)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "Failed to send confirmation email", "error", err)
+		if errors.Is(err, ErrAlreadySubscribed) {
+			return nil, ErrAlreadySubscribed
+		}
+		s.logger.ErrorContext(ctx, "Failed to create subscriber", "error", err)
+		return nil, err
 	}
 
+	if blocked {
+		s.logger.InfoContext(ctx, "Confirmation email not sent, the address is blocked for the newsletter", "newsletterId", newsletterID, "subscriberId", subscriber.Id)
+		return subscriber, nil
+	}
+
+	if confirmNow {
+		s.webhookService.SubscriberConfirmed(ctx, &repository.ChangedSubscription{
+			SubscriberID: *subscriber.Id,
+			NewsletterID: newsletterID,
+			Email:        string(email),
+		})
+		return subscriber, nil
+	}
+
+	// Send confirmation email; a failed send is recorded on the subscriber and retried
+	s.sendConfirmation(ctx, repository.PendingConfirmation{
+		SubscriberID:      *subscriber.Id,
+		NewsletterID:      newsletterID,
+		NewsletterName:    newsletter.Name,
+		Email:             string(email),
+		ConfirmationToken: *subscriber.ConfirmationToken,
+	})
+
 	return subscriber, nil
 }
 
+// ResendConfirmation sends a new confirmation link to the newsletter's unconfirmed subscription
+// of email, replacing the previous link, unless one was sent within confirmationRequestCooldown.
+// Unknown and confirmed addresses are not reported, so the result does not reveal subscribers;
+// only an unknown newsletter is.
+func (s *SubscriberService) ResendConfirmation(ctx context.Context, newsletterID uuid.UUID, email openapi_types.Email) error {
+	if _, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return err
+	}
+
+	pending, err := s.subscriberRepo.RenewConfirmation(ctx, newsletterID, string(email), s.confirmationExpiry(), confirmationRequestCooldown)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.logger.InfoContext(ctx, "Confirmation not resent, no pending subscription or resent recently", "newsletterId", newsletterID)
+			return nil
+		}
+		return err
+	}
+
+	// A failed send is recorded on the subscriber and retried like the first one
+	s.sendConfirmation(ctx, *pending)
+	return nil
+}
+
+// confirmationExpiry is when a confirmation link sent now stops working, nil when links do not expire
+func (s *SubscriberService) confirmationExpiry() *time.Time {
+	if s.config.Mailing.ConfirmationTokenTTL <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(s.config.Mailing.ConfirmationTokenTTL)
+	return &expiresAt
+}
+
+// RetryFailedConfirmations resends the confirmation emails whose retry is due. Safe to run on
+// several instances at once: each due retry is claimed by one of them.
+func (s *SubscriberService) RetryFailedConfirmations(ctx context.Context) (sent int, failed int, err error) {
+	due, err := s.subscriberRepo.ClaimConfirmationRetries(ctx, confirmationRetryBatch, confirmationRetryLease)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, pending := range due {
+		if s.sendConfirmation(ctx, pending) != nil {
+			failed++
+		} else {
+			sent++
+		}
+	}
+	return sent, failed, nil
+}
+
+// ResendPendingConfirmations sends the confirmation email again to the newsletter's unconfirmed
+// subscribers, except those who were sent one within confirmationResendCooldown
+func (s *SubscriberService) ResendPendingConfirmations(ctx context.Context, newsletterID uuid.UUID, editorID string) (*generated.ConfirmationResendResult, error) {
+	// Verify newsletter ownership
+	_, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID, repository.RoleEditor)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return nil, err
+	}
+
+	pending, err := s.subscriberRepo.ListPendingConfirmations(ctx, newsletterID, confirmationResendCooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &generated.ConfirmationResendResult{}
+	for _, p := range pending {
+		if s.sendConfirmation(ctx, p) != nil {
+			result.Failed++
+		} else {
+			result.Sent++
+		}
+	}
+	s.logger.InfoContext(ctx, "Resent pending confirmation emails", "newsletterId", newsletterID, "sent", result.Sent, "failed", result.Failed)
+	return result, nil
+}
+
+// sendConfirmation sends the confirmation email and records the outcome on the subscriber.
+// A failed send is retried with exponential backoff until ConfirmationMaxAttempts is reached;
+// the last failure is then kept as an email job for admins.
+func (s *SubscriberService) sendConfirmation(ctx context.Context, pending repository.PendingConfirmation) error {
+	confirmationLink := fmt.Sprintf("%s/subscribe/confirm/%s", s.config.BuildApiBaseUrl(), pending.ConfirmationToken)
+	template, err := s.emailTemplateService.Template(ctx, pending.NewsletterID, emailrender.KindConfirmation)
+	if err != nil {
+		// The confirmation still goes out, with the default template
+		s.logger.WarnContext(ctx, "Failed to load confirmation template", "newsletterId", pending.NewsletterID, "error", err)
+	}
+	rendered, sendErr := emailrender.RenderConfirmation(emailrender.ConfirmationEmail{
+		NewsletterName: pending.NewsletterName,
+		ConfirmURL:     confirmationLink,
+		Template:       template,
+	})
+
+	// A template that cannot render the email fails the send like the provider would, so it is
+	// recorded and shows up among the failed confirmations
+	confirmation := OutgoingEmail{To: pending.Email, Subject: rendered.Subject, HTML: rendered.HTML}
+	if sendErr == nil {
+		sendErr = s.mailingService.SendMail(ctx, confirmation.To, confirmation.Subject, confirmation.HTML)
+	}
+
+	sendError := ""
+	var retryAt *time.Time
+	if sendErr == nil {
+		s.costService.Record(ctx, pending.NewsletterID, nil, enums.ConfirmationEmail, 1)
+	} else {
+		sendError = sendErr.Error()
+		attempts := pending.Attempts + 1
+		if attempts < s.config.Mailing.ConfirmationMaxAttempts {
+			next := time.Now().Add(confirmationRetryDelay(s.config.Mailing.ConfirmationRetryBackoff, attempts))
+			retryAt = &next
+			s.logger.WarnContext(ctx, "Failed to send confirmation email, will retry", "subscriberId", pending.SubscriberID, "attempts", attempts, "retryAt", next, "error", sendErr)
+		} else {
+			s.logger.ErrorContext(ctx, "Failed to send confirmation email, giving up", "subscriberId", pending.SubscriberID, "attempts", attempts, "error", sendErr)
+			s.emailJobService.RecordConfirmationFailure(ctx, pending.NewsletterID, confirmation, sendErr)
+		}
+	}
+
+	if err := s.subscriberRepo.RecordConfirmationSend(context.WithoutCancel(ctx), pending.SubscriberID, sendError, retryAt); err != nil {
+		s.logger.ErrorContext(ctx, "Confirmation email outcome could not be recorded", "subscriberId", pending.SubscriberID)
+	}
+	return sendErr
+}
+
+// confirmationRetryDelay is backoff doubled for every attempt after the first, capped at maxConfirmationRetryDelay
+func confirmationRetryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < maxConfirmationRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxConfirmationRetryDelay)
+}
+
 // ConfirmSubscription confirms a subscription using a confirmation token
 func (s *SubscriberService) ConfirmSubscription(ctx context.Context, token string) error {
-	err := s.subscriberRepo.ConfirmByToken(ctx, token)
+	confirmed, err := s.subscriberRepo.ConfirmByToken(ctx, token)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
 			return ErrNotFound
+		case errors.Is(err, repository.ErrConfirmationExpired):
+			return models.NewConflictError("This confirmation link has expired, please request a new one")
 		}
 		return err
 	}
+
+	// Confirming a new subscription is an explicit opt-in that overrides an earlier unsubscribe-all
+	if err := s.suppressionService.Lift(ctx, confirmed.Email); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to lift email suppression", "error", err)
+		return err
+	}
+	if !confirmed.WasConfirmed {
+		s.webhookService.SubscriberConfirmed(ctx, confirmed)
+	}
 	return nil
 }
 
 // Unsubscribe handles unsubscription using a token
 func (s *SubscriberService) Unsubscribe(ctx context.Context, token string) error {
-	err := s.subscriberRepo.UnsubscribeByToken(ctx, token)
+	unsubscribed, err := s.subscriberRepo.UnsubscribeByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.webhookService.SubscriberUnsubscribed(ctx, unsubscribed)
+	return nil
+}
+
+// RequestEmailChange starts moving the subscription identified by its unsubscribe token to a
+// new address. The change only applies once the new address opens the verification link.
+func (s *SubscriberService) RequestEmailChange(ctx context.Context, unsubscribeToken string, newEmail openapi_types.Email) error {
+	subscriber, err := s.subscriberRepo.GetByUnsubscribeToken(ctx, unsubscribeToken)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return ErrNotFound
 		}
 		return err
 	}
+	if strings.EqualFold(string(subscriber.Email), string(newEmail)) {
+		return models.NewBadRequestError("The new email address is the same as the current one")
+	}
+
+	exists, err := s.subscriberRepo.ExistsByEmail(ctx, *subscriber.NewsletterId, string(newEmail))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to check subscription", "error", err)
+		return err
+	}
+	if exists {
+		return ErrAlreadySubscribed
+	}
+
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, subscriber.NewsletterId.String())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get newsletter", "error", err)
+		return err
+	}
+
+	change, err := s.subscriberRepo.CreateEmailChange(ctx, subscriber, string(newEmail), emailChangeTTL)
+	if err != nil {
+		return err
+	}
+
+	// Send the verification link to the new address
+	verificationLink := fmt.Sprintf("%s/subscriptions/email-change/confirm/%s", s.config.BuildApiBaseUrl(), change.Token)
+	rendered := emailrender.RenderEmailChange(emailrender.ConfirmationEmail{NewsletterName: newsletter.Name, ConfirmURL: verificationLink})
+
+	verification := OutgoingEmail{To: change.NewEmail, Subject: rendered.Subject, HTML: rendered.HTML}
+	err = s.mailingService.SendMail(ctx, verification.To, verification.Subject, verification.HTML)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to send email change verification", "error", err)
+		s.emailJobService.RecordConfirmationFailure(ctx, change.NewsletterID, verification, err)
+	} else {
+		s.costService.Record(ctx, change.NewsletterID, nil, enums.ConfirmationEmail, 1)
+	}
+
+	s.logger.InfoContext(ctx, "Email change requested", "subscriberId", change.SubscriberID)
+	return nil
+}
+
+// ConfirmEmailChange applies a verified address change
+func (s *SubscriberService) ConfirmEmailChange(ctx context.Context, token string) error {
+	change, err := s.subscriberRepo.ApplyEmailChange(ctx, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return ErrNotFound
+		case errors.Is(err, repository.ErrEmailChangeExpired):
+			return models.NewConflictError("This email change link has expired, please request a new one")
+		}
+		return err
+	}
+
+	// Verifying the new address is an explicit opt-in, like confirming a subscription
+	if err := s.suppressionService.Lift(ctx, change.NewEmail); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to lift email suppression", "error", err)
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Subscriber email changed", "subscriberId", change.SubscriberID)
 	return nil
 }
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/repository"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// raceDB stands in for the database under concurrent subscribes of one address: every
+// existence check waits until all callers have made theirs and finds no subscriber, then the
+// first insert wins and every later one violates the unique constraint, as PostgreSQL would
+// report it
+type raceDB struct {
+	checked sync.WaitGroup
+	mu      sync.Mutex
+	created bool
+}
+
+func (db *raceDB) Begin(context.Context) (pgx.Tx, error) {
+	return &raceTx{db: db}, nil
+}
+
+func (db *raceDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("unexpected Exec")
+}
+
+func (db *raceDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("unexpected Query")
+}
+
+// QueryRow answers ExistsByEmail
+func (db *raceDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	db.checked.Done()
+	db.checked.Wait()
+	return fakeRow(func(dest ...any) error {
+		*dest[0].(*bool) = false
+		return nil
+	})
+}
+
+// raceTx is the transaction of SubscriberRepository.Create; methods it does not call are left
+// to the nil pgx.Tx
+type raceTx struct {
+	pgx.Tx
+	db *raceDB
+}
+
+// Exec deletes a previously deleted subscriber with the address; there is none
+func (tx *raceTx) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("DELETE 0"), nil
+}
+
+// QueryRow inserts the subscriber
+func (tx *raceTx) QueryRow(context.Context, string, ...any) pgx.Row {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	if tx.db.created {
+		return fakeRow(func(...any) error {
+			return &pgconn.PgError{Code: "23505", ConstraintName: "unique_newsletter_subscriber"}
+		})
+	}
+	tx.db.created = true
+	return fakeRow(func(...any) error { return nil })
+}
+
+func (tx *raceTx) Commit(context.Context) error   { return nil }
+func (tx *raceTx) Rollback(context.Context) error { return nil }
+
+type fakeRow func(dest ...any) error
+
+func (r fakeRow) Scan(dest ...any) error { return r(dest...) }
+
+// fakeSubscribeDeps stands in for the newsletter, plan, suppression and webhook services of
+// a newsletter without double opt-in, so subscriptions are confirmed without an email
+type fakeSubscribeDeps struct {
+	newsletter *generated.Newsletter
+	confirmed  atomic.Int32
+}
+
+func (f *fakeSubscribeDeps) GetNewsletterByID(context.Context, string) (*generated.Newsletter, error) {
+	return f.newsletter, nil
+}
+
+func (f *fakeSubscribeDeps) GetNewsletterForRole(context.Context, string, string, string) (*generated.Newsletter, error) {
+	return f.newsletter, nil
+}
+
+func (f *fakeSubscribeDeps) CheckSubscriberQuota(context.Context, uuid.UUID) error { return nil }
+
+func (f *fakeSubscribeDeps) IsBlocked(context.Context, uuid.UUID, string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeSubscribeDeps) IsSuppressed(context.Context, uuid.UUID, string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeSubscribeDeps) Lift(context.Context, string) error { return nil }
+
+func (f *fakeSubscribeDeps) SubscriberConfirmed(context.Context, *repository.ChangedSubscription) {
+	f.confirmed.Add(1)
+}
+
+func (f *fakeSubscribeDeps) SubscriberUnsubscribed(context.Context, *repository.ChangedSubscription) {
+}
+
+func TestSubscribeConcurrentSameEmail(t *testing.T) {
+	const callers = 20
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := &raceDB{}
+	db.checked.Add(callers)
+
+	newsletterID := uuid.New()
+	editorID := openapi_types.UUID(uuid.New())
+	doubleOptIn := false
+	deps := &fakeSubscribeDeps{newsletter: &generated.Newsletter{
+		Id:          &newsletterID,
+		Name:        "Weekly",
+		EditorId:    &editorID,
+		DoubleOptIn: &doubleOptIn,
+	}}
+
+	service := &SubscriberService{
+		subscriberRepo:     repository.NewSubscriberRepository(db, nil, logger),
+		newsletterService:  deps,
+		planService:        deps,
+		suppressionService: deps,
+		webhookService:     deps,
+		config:             &config.Config{},
+		logger:             logger,
+	}
+
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+		duplicate atomic.Int32
+	)
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Subscribe(context.Background(), newsletterID, "reader@example.com")
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case errors.Is(err, ErrAlreadySubscribed):
+				duplicate.Add(1)
+			default:
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Subscribe returned an unexpected error: %v", err)
+	}
+	if got := succeeded.Load(); got != 1 {
+		t.Errorf("%d subscribes succeeded, want exactly 1", got)
+	}
+	if got := duplicate.Load(); got != callers-1 {
+		t.Errorf("%d subscribes got ErrAlreadySubscribed, want %d", got, callers-1)
+	}
+	if got := deps.confirmed.Load(); got != 1 {
+		t.Errorf("%d subscriber.confirmed events, want 1", got)
+	}
+}
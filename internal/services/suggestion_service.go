@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/suggest"
+	"go-newsletter/internal/summarize"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// SuggestionService proposes subject lines and preheaders for posts with a language model.
+// It is behind the SUGGESTIONS_ENABLED feature flag.
+type SuggestionService struct {
+	// provider is nil while suggestions are disabled
+	provider          suggest.Provider
+	postService       *PostService
+	newsletterService *NewsletterService
+	config            config.SuggestionsConfig
+	logger            *slog.Logger
+}
+
+func NewSuggestionService(provider suggest.Provider, postService *PostService, newsletterService *NewsletterService, cfg *config.Config, logger *slog.Logger) *SuggestionService {
+	utils.RequireDependencies("SuggestionService",
+		utils.Dep("postService", postService),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("config", cfg),
+		utils.Dep("logger", logger),
+	)
+	return &SuggestionService{
+		provider:          provider,
+		postService:       postService,
+		newsletterService: newsletterService,
+		config:            cfg.Suggestions,
+		logger:            logger,
+	}
+}
+
+// Enabled reports whether the feature flag is on and a provider is configured
+func (s *SuggestionService) Enabled() bool {
+	return s.provider != nil
+}
+
+// SuggestForPost asks the provider for subject line and preheader alternatives of one of the
+// editor's posts, drafts included. Nothing is stored; the editor picks and saves one themselves.
+func (s *SuggestionService) SuggestForPost(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, postID uuid.UUID) (*generated.PostSuggestions, error) {
+	if !s.Enabled() {
+		return nil, models.NewNotFoundError("Suggestions are not enabled")
+	}
+
+	post, err := s.postService.GetPostById(ctx, newsletterID, postID, editorID.String())
+	if errors.Is(err, ErrNotFound) {
+		return nil, models.NewNotFoundError("Newsletter not found")
+	}
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && uuid.UUID(*post.NewsletterId) != newsletterID) {
+		return nil, models.NewNotFoundError("Post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	newsletter, err := s.newsletterService.GetNewsletterByID(ctx, newsletterID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	text := summarize.PlainText(post.ContentHtml)
+	if post.ContentText != nil && *post.ContentText != "" {
+		text = *post.ContentText
+	}
+	if text == "" {
+		return nil, models.NewBadRequestError("The post has no content to base suggestions on")
+	}
+
+	suggestions, err := s.provider.Suggest(ctx, suggest.Post{NewsletterName: newsletter.Name, Title: post.Title, Text: text}, s.config.Count)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to get suggestions from provider", "postId", postID, "provider", s.config.Provider, "error", err)
+		return nil, models.NewBadGatewayError("The suggestion provider is unavailable, please try again later")
+	}
+	s.logger.InfoContext(ctx, "Suggested subject lines", "postId", postID, "provider", s.config.Provider, "subjectLines", len(suggestions.SubjectLines), "preheaders", len(suggestions.Preheaders))
+
+	return &generated.PostSuggestions{
+		SubjectLines: suggestions.SubjectLines,
+		Preheaders:   suggestions.Preheaders,
+	}, nil
+}
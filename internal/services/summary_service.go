@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/suggest"
+	"go-newsletter/internal/summarize"
+	"go-newsletter/internal/utils"
+)
+
+// SummaryService writes the short summaries stored on posts, which listings and feeds show
+// instead of the start of the post's HTML
+type SummaryService struct {
+	// provider is nil unless SUMMARY_MODE is llm
+	provider suggest.Provider
+	config   config.SummaryConfig
+	logger   *slog.Logger
+}
+
+func NewSummaryService(provider suggest.Provider, cfg *config.Config, logger *slog.Logger) *SummaryService {
+	utils.RequireDependencies("SummaryService",
+		utils.Dep("config", cfg),
+		utils.Dep("logger", logger),
+	)
+	return &SummaryService{
+		provider: provider,
+		config:   cfg.Summary,
+		logger:   logger,
+	}
+}
+
+// Summarize returns the summary of a post, or "" for a post without text. contentText is the
+// post's plain text version and preferred over its HTML when set. With a provider the model
+// writes the summary; if it fails the summary is extracted from the post instead.
+func (s *SummaryService) Summarize(ctx context.Context, newsletterName string, title string, contentHTML string, contentText *string) string {
+	text := summarize.PlainText(contentHTML)
+	if contentText != nil && *contentText != "" {
+		text = *contentText
+	}
+	if text == "" {
+		return ""
+	}
+
+	if s.provider != nil {
+		summary, err := s.provider.Summarize(ctx, suggest.Post{NewsletterName: newsletterName, Title: title, Text: text}, s.config.MaxChars)
+		if err == nil {
+			return summarize.Truncate(summary, s.config.MaxChars)
+		}
+		s.logger.WarnContext(ctx, "Failed to summarize post with provider, extracting the summary instead", "error", err)
+	}
+	return summarize.Extract(text, s.config.MaxChars)
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// supabaseSession is the part of a Supabase Auth session response sign-in needs
+type supabaseSession struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         struct {
+		UserMetadata map[string]any `json:"user_metadata"`
+	} `json:"user"`
+}
+
+// supabaseAuthError is a response of Supabase Auth outside 2xx. Code is the error_code of its
+// payload, like email_exists or weak_password, and empty for older Supabase versions.
+type supabaseAuthError struct {
+	Status  int
+	Code    string
+	Message string
+	Detail  string
+}
+
+// supabaseErrorCodes maps the error codes of Supabase Auth the client can act on to our
+// errors; the reason is the code itself
+var supabaseErrorCodes = map[string]int{
+	"email_exists":            http.StatusConflict,
+	"user_already_exists":     http.StatusConflict,
+	"identity_already_exists": http.StatusConflict,
+	"phone_exists":            http.StatusConflict,
+	"weak_password":           http.StatusUnprocessableEntity,
+	"email_address_invalid":   http.StatusUnprocessableEntity,
+	"validation_failed":       http.StatusUnprocessableEntity,
+	"same_password":           http.StatusUnprocessableEntity,
+}
+
+func (e *supabaseAuthError) Error() string {
+	return fmt.Sprintf("supabase auth: %d: %s", e.Status, e.Detail)
+}
+
+// apiError passes the error on to the client when its code is one the client can act on, with
+// Supabase's message, and returns fallback otherwise
+func (e *supabaseAuthError) apiError(fallback models.APIError) models.APIError {
+	message := e.Message
+	if message == "" {
+		message = fallback.Message
+	}
+	switch supabaseErrorCodes[e.Code] {
+	case http.StatusConflict:
+		return models.APIError{Code: http.StatusConflict, Message: message, Reason: e.Code}
+	case http.StatusUnprocessableEntity:
+		return models.NewUnprocessableError(e.Code, message)
+	}
+	return fallback
+}
+
+// newSupabaseAuthError reads the error payload of Supabase Auth, which is
+// {"code", "error_code", "msg"} since error codes were introduced and
+// {"error", "error_description"} on the token endpoint of older versions
+func newSupabaseAuthError(status int, body []byte) *supabaseAuthError {
+	authErr := &supabaseAuthError{Status: status, Detail: strings.TrimSpace(string(body))}
+	var payload struct {
+		ErrorCode        string `json:"error_code"`
+		Msg              string `json:"msg"`
+		Message          string `json:"message"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return authErr
+	}
+	authErr.Code = payload.ErrorCode
+	for _, message := range []string{payload.Msg, payload.Message, payload.ErrorDescription} {
+		if message != "" {
+			authErr.Message = message
+			break
+		}
+	}
+	return authErr
+}
+
+// supabaseAuth calls the Supabase Auth API for the sign-in flows that run through the backend
+// and turns their sessions into our auth response
+type supabaseAuth struct {
+	authService    *AuthService
+	profileService *ProfileService
+	securityEvents *SecurityEventService
+	httpClient     *http.Client
+	config         *config.Config
+	logger         *slog.Logger
+}
+
+// post sends body to the Supabase Auth endpoint and decodes the answer into out, unless out is
+// nil. Answers outside 2xx are returned as *supabaseAuthError.
+func (a *supabaseAuth) post(ctx context.Context, path string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return a.do(ctx, http.MethodPost, path, "", bytes.NewReader(payload), out)
+}
+
+// do calls the Supabase Auth endpoint, as the user of accessToken unless it is empty
+func (a *supabaseAuth) do(ctx context.Context, method string, path string, accessToken string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, a.url(path), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("apikey", a.config.Supabase.AnonKey)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return a.send(req, out)
+}
+
+// admin calls the admin API of Supabase Auth with the service role key
+func (a *supabaseAuth) admin(ctx context.Context, method string, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, a.url(path), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", a.config.Supabase.ServiceRoleKey)
+	req.Header.Set("Authorization", "Bearer "+a.config.Supabase.ServiceRoleKey)
+	return a.send(req, out)
+}
+
+// send sends req and decodes the answer into out, unless out is nil. Answers outside 2xx are
+// returned as *supabaseAuthError.
+func (a *supabaseAuth) send(req *http.Request, out any) error {
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return newSupabaseAuthError(resp.StatusCode, detail)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (a *supabaseAuth) url(path string) string {
+	return strings.TrimSuffix(a.config.Supabase.URL, "/") + path
+}
+
+// signIn verifies the access token of a new session like any other and returns it with the
+// editor's profile, which is created on their first sign-in
+func (a *supabaseAuth) signIn(ctx context.Context, session *supabaseSession, method string) (*generated.AuthResponse, error) {
+	claims, err := a.verify(ctx, session, method)
+	if err != nil {
+		return nil, err
+	}
+	return a.respond(ctx, session, claims, method)
+}
+
+// verify validates the access token of a new session and returns its claims
+func (a *supabaseAuth) verify(ctx context.Context, session *supabaseSession, method string) (*UserClaims, error) {
+	if session.AccessToken == "" {
+		a.logger.ErrorContext(ctx, "Supabase Auth answered with a session without access token", "method", method)
+		return nil, models.NewBadGatewayError("Sign-in failed, please try again later")
+	}
+	claims, err := a.authService.ValidateJWT(session.AccessToken)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Supabase returned an access token that does not verify", "method", method, "error", err)
+		return nil, models.NewBadGatewayError("Sign-in failed, please try again later")
+	}
+	return claims, nil
+}
+
+// respond returns the verified session with the editor's profile, creating it on their first
+// sign-in, and records the sign-in as a security event
+func (a *supabaseAuth) respond(ctx context.Context, session *supabaseSession, claims *UserClaims, method string) (*generated.AuthResponse, error) {
+	auth, err := a.authResponse(ctx, session, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.InfoContext(ctx, "Editor signed in", "method", method, "userId", claims.UserID)
+	if userID, err := uuid.Parse(claims.UserID); err == nil {
+		a.securityEvents.RecordSignIn(ctx, userID, claims.Email, method)
+	}
+	return auth, nil
+}
+
+// authResponse returns the verified session with the editor's profile, creating it when it is
+// missing
+func (a *supabaseAuth) authResponse(ctx context.Context, session *supabaseSession, claims *UserClaims) (*generated.AuthResponse, error) {
+	profile, err := a.profileService.EnsureProfile(ctx, claims.UserID,
+		metadataString(session.User.UserMetadata, "full_name", "name"),
+		metadataString(session.User.UserMetadata, "avatar_url", "picture"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Email != "" {
+		email := openapi_types.Email(claims.Email)
+		profile.Email = &email
+	}
+
+	auth := &generated.AuthResponse{
+		AccessToken: &session.AccessToken,
+		User:        profile,
+	}
+	if session.RefreshToken != "" {
+		auth.RefreshToken = &session.RefreshToken
+	}
+	if session.TokenType != "" {
+		auth.TokenType = &session.TokenType
+	}
+	if session.ExpiresIn > 0 {
+		auth.ExpiresIn = &session.ExpiresIn
+	}
+	return auth, nil
+}
+
+// metadataString returns the first of the keys that is a non-empty string in the user metadata
+// providers fill in, which name the same things differently
+func metadataString(metadata map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := metadata[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
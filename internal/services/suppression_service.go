@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// unsubscribeAllPurpose separates unsubscribe-all signatures from other uses of the same secret
+const unsubscribeAllPurpose = "unsubscribe-all:"
+
+// maxSuppressionNote bounds the note an editor keeps with a blocked address
+const maxSuppressionNote = 500
+
+// SuppressionService handles platform-wide opt-outs and the suppression lists of newsletters.
+// Unsubscribe-all links carry the address and an HMAC of it, so they work without a
+// per-subscriber token and never expire.
+type SuppressionService struct {
+	suppressionRepo   *repository.SuppressionRepository
+	newsletterService *NewsletterService
+	webhookService    *WebhookService
+	secret            []byte
+	config            *config.Config
+	logger            *slog.Logger
+}
+
+func NewSuppressionService(suppressionRepo *repository.SuppressionRepository, newsletterService *NewsletterService, webhookService *WebhookService, config *config.Config, logger *slog.Logger) *SuppressionService {
+	utils.RequireDependencies("SuppressionService",
+		utils.Dep("suppressionRepo", suppressionRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("webhookService", webhookService),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &SuppressionService{
+		suppressionRepo:   suppressionRepo,
+		newsletterService: newsletterService,
+		webhookService:    webhookService,
+		secret:            []byte(config.Security.UnsubscribeSecret),
+		config:            config,
+		logger:            logger,
+	}
+}
+
+// UnsubscribeAllURL returns the link that unsubscribes the address from all newsletters
+func (s *SuppressionService) UnsubscribeAllURL(email string) string {
+	return fmt.Sprintf("%s/unsubscribe-all/%s", s.config.BuildApiBaseUrl(), s.token(email))
+}
+
+// UnsubscribeAll verifies an unsubscribe-all token, then unsubscribes its address from every
+// newsletter and suppresses it so no post is sent to it again. Using a link twice is not an error.
+func (s *SuppressionService) UnsubscribeAll(ctx context.Context, token string) error {
+	email, ok := s.verify(token)
+	if !ok {
+		return models.NewBadRequestError("Invalid unsubscribe link")
+	}
+
+	unsubscribed, err := s.suppressionRepo.UnsubscribeAll(ctx, email, repository.SuppressionUnsubscribeAll)
+	if err != nil {
+		return err
+	}
+	for i := range unsubscribed {
+		s.webhookService.SubscriberUnsubscribed(ctx, &unsubscribed[i])
+	}
+	s.logger.InfoContext(ctx, "Address unsubscribed from all newsletters", "subscriptions", len(unsubscribed))
+	return nil
+}
+
+// SuppressComplaint unsubscribes an address whose recipient marked a post as spam from every
+// newsletter and suppresses it, so nothing is sent to it again
+func (s *SuppressionService) SuppressComplaint(ctx context.Context, email string) error {
+	unsubscribed, err := s.suppressionRepo.UnsubscribeAll(ctx, email, repository.SuppressionComplaint)
+	if err != nil {
+		return err
+	}
+	for i := range unsubscribed {
+		s.webhookService.SubscriberUnsubscribed(ctx, &unsubscribed[i])
+	}
+	s.logger.InfoContext(ctx, "Address unsubscribed from all newsletters after a spam complaint", "subscriptions", len(unsubscribed))
+	return nil
+}
+
+// Lift removes the address from the suppression list, e.g. once it confirms a new subscription
+func (s *SuppressionService) Lift(ctx context.Context, email string) error {
+	lifted, err := s.suppressionRepo.Remove(ctx, email)
+	if err != nil {
+		return err
+	}
+	if lifted {
+		s.logger.InfoContext(ctx, "Email suppression lifted after a confirmed subscription")
+	}
+	return nil
+}
+
+// ListNewsletterSuppressions returns a page of the suppression list of a newsletter of the editor
+func (s *SuppressionService) ListNewsletterSuppressions(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, page pagination.Page) ([]*generated.NewsletterSuppression, *pagination.Cursor, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleViewer); err != nil {
+		return nil, nil, err
+	}
+	return s.suppressionRepo.ListByNewsletter(ctx, newsletterID, page)
+}
+
+// BlockForNewsletter adds an address to the suppression list of a newsletter of the editor
+func (s *SuppressionService) BlockForNewsletter(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, req generated.NewsletterSuppressionCreate) (*generated.NewsletterSuppression, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleEditor); err != nil {
+		return nil, err
+	}
+	email := strings.TrimSpace(string(req.Email))
+	if email == "" {
+		return nil, models.NewBadRequestError("email is required")
+	}
+	note := req.Note
+	if note != nil {
+		trimmed := strings.TrimSpace(*note)
+		if utf8.RuneCountInString(trimmed) > maxSuppressionNote {
+			return nil, models.NewBadRequestError(fmt.Sprintf("note must be at most %d characters", maxSuppressionNote))
+		}
+		note = &trimmed
+		if trimmed == "" {
+			note = nil
+		}
+	}
+
+	suppression, err := s.suppressionRepo.AddToNewsletter(ctx, newsletterID, email, note)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return nil, models.NewConflictError("The address is already on the suppression list of this newsletter")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Address blocked for newsletter", "newsletterId", newsletterID, "suppressionId", suppression.Id)
+	return suppression, nil
+}
+
+// UnblockForNewsletter removes an address the editor blocked from the suppression list of their
+// newsletter. Bounces and complaints stay until the address confirms a new subscription.
+func (s *SuppressionService) UnblockForNewsletter(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, id uuid.UUID) error {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleEditor); err != nil {
+		return err
+	}
+	err := s.suppressionRepo.RemoveFromNewsletter(ctx, newsletterID, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return models.NewNotFoundError("Suppression list entry not found")
+	case errors.Is(err, repository.ErrNotManualSuppression):
+		return models.NewConflictError("Bounced and complaining addresses cannot be unblocked, they are lifted when the address confirms a new subscription")
+	case err != nil:
+		return err
+	}
+	s.logger.InfoContext(ctx, "Address unblocked for newsletter", "newsletterId", newsletterID, "suppressionId", id)
+	return nil
+}
+
+// IsBlocked reports whether the editor of the newsletter blocked the address
+func (s *SuppressionService) IsBlocked(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error) {
+	return s.suppressionRepo.IsBlocked(ctx, newsletterID, email)
+}
+
+// IsSuppressed reports whether the address unsubscribed from all newsletters, or bounced or
+// complained on the newsletter, so that only confirming a subscription lifts it
+func (s *SuppressionService) IsSuppressed(ctx context.Context, newsletterID uuid.UUID, email string) (bool, error) {
+	return s.suppressionRepo.IsSuppressed(ctx, newsletterID, email)
+}
+
+// token is base64url(email) "." base64url(HMAC-SHA256(email)), with the email lower-cased
+func (s *SuppressionService) token(email string) string {
+	email = strings.ToLower(email)
+	return base64.RawURLEncoding.EncodeToString([]byte(email)) + "." + base64.RawURLEncoding.EncodeToString(s.sign(email))
+}
+
+func (s *SuppressionService) verify(token string) (string, bool) {
+	encodedEmail, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	email, err := base64.RawURLEncoding.DecodeString(encodedEmail)
+	if err != nil || len(email) == 0 {
+		return "", false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", false
+	}
+	if !hmac.Equal(sig, s.sign(string(email))) {
+		return "", false
+	}
+	return string(email), true
+}
+
+func (s *SuppressionService) sign(email string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsubscribeAllPurpose + email))
+	return mac.Sum(nil)
+}
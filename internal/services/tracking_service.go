@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/tracking"
+	"go-newsletter/internal/utils"
+	"log/slog"
+
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// TrackingService records opens and clicks of post emails when MAIL_TRACKING is on. Click
+// links are signed like unsubscribe-all links, so the click redirect is not an open redirect.
+type TrackingService struct {
+	trackingRepo *repository.TrackingRepository
+	tracker      *tracking.Tracker
+	config       *config.Config
+	logger       *slog.Logger
+}
+
+func NewTrackingService(trackingRepo *repository.TrackingRepository, config *config.Config, logger *slog.Logger) *TrackingService {
+	utils.RequireDependencies("TrackingService",
+		utils.Dep("trackingRepo", trackingRepo),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &TrackingService{
+		trackingRepo: trackingRepo,
+		tracker:      tracking.NewTracker(config.BuildApiBaseUrl(), []byte(config.Security.UnsubscribeSecret)),
+		config:       config,
+		logger:       logger,
+	}
+}
+
+// Prepare readies sanitized post HTML for tracking. It returns nil when tracking is off, or
+// when the HTML cannot be parsed, in which case the emails go out untracked.
+func (s *TrackingService) Prepare(ctx context.Context, contentHTML string) *tracking.Body {
+	if !s.config.Mailing.Tracking {
+		return nil
+	}
+	body, err := tracking.Prepare(contentHTML)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to prepare post for tracking, sending it untracked", "error", err)
+		return nil
+	}
+	return &body
+}
+
+// TrackedHTML returns the prepared post HTML of the email with the message ID
+func (s *TrackingService) TrackedHTML(body tracking.Body, messageID uuid.UUID) string {
+	return s.tracker.HTML(body, messageID)
+}
+
+// RecordOpen records that the email with the message ID was opened. Failures are only logged,
+// as the pixel is served regardless.
+func (s *TrackingService) RecordOpen(ctx context.Context, messageID uuid.UUID) {
+	if _, err := s.trackingRepo.RecordEvent(ctx, messageID, repository.EventOpen, nil); err != nil {
+		s.logger.WarnContext(ctx, "Failed to record email open", "messageId", messageID, "error", err)
+	}
+}
+
+// RecordClick verifies a click link of the email with the message ID, records the click and
+// returns where to redirect. A failure to record does not stop the redirect.
+func (s *TrackingService) RecordClick(ctx context.Context, messageID uuid.UUID, target string, sig string) (string, error) {
+	if target == "" || !s.tracker.Verify(messageID, target, sig) {
+		return "", models.NewBadRequestError("Invalid tracking link")
+	}
+	if _, err := s.trackingRepo.RecordEvent(ctx, messageID, repository.EventClick, &target); err != nil {
+		s.logger.WarnContext(ctx, "Failed to record email click", "messageId", messageID, "error", err)
+	}
+	return target, nil
+}
+
+// PostStats returns the opens and clicks of a post, with rates relative to its sent emails
+func (s *TrackingService) PostStats(ctx context.Context, postID uuid.UUID, sent int) (*generated.PostEngagementStats, error) {
+	counts, err := s.trackingRepo.PostCounts(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]generated.PostLinkClicks, 0, len(counts.Links))
+	for _, link := range counts.Links {
+		links = append(links, generated.PostLinkClicks{
+			Url:          link.URL,
+			Clicks:       link.Clicks,
+			UniqueClicks: link.UniqueClicks,
+		})
+	}
+	return &generated.PostEngagementStats{
+		PostId:          postID,
+		TrackingEnabled: s.config.Mailing.Tracking,
+		EmailsSent:      sent,
+		Opens:           counts.Opens,
+		UniqueOpens:     counts.UniqueOpens,
+		OpenRate:        trackingRate(counts.UniqueOpens, sent),
+		Clicks:          counts.Clicks,
+		UniqueClicks:    counts.UniqueClicks,
+		ClickRate:       trackingRate(counts.UniqueClicks, sent),
+		Links:           links,
+	}, nil
+}
+
+// trackingRate is part of total as a fraction from 0 to 1, or 0 without a total
+func trackingRate(part int, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return min(float64(part)/float64(total), 1)
+}
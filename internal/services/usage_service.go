@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+// usagePeriodLayout is the format of the period query parameter and response field
+const usagePeriodLayout = "2006-01"
+
+type usageKey struct {
+	editorID    uuid.UUID
+	periodStart time.Time
+	endpoint    string
+}
+
+type usageCounter struct {
+	calls        int64
+	lastCalledAt time.Time
+}
+
+// UsageService counts authenticated API calls per editor and endpoint. Calls are
+// aggregated in memory and flushed periodically, so recording never touches the database
+// on the request path.
+type UsageService struct {
+	usageRepo     *repository.UsageRepository
+	flushInterval time.Duration
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	pending map[usageKey]*usageCounter
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+	done      chan struct{}
+}
+
+func NewUsageService(usageRepo *repository.UsageRepository, config *config.Config, logger *slog.Logger) *UsageService {
+	utils.RequireDependencies("UsageService",
+		utils.Dep("usageRepo", usageRepo),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	flushInterval := config.Usage.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 30 * time.Second
+	}
+	return &UsageService{
+		usageRepo:     usageRepo,
+		flushInterval: flushInterval,
+		logger:        logger,
+		pending:       make(map[usageKey]*usageCounter),
+		stopCh:        make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Record counts one call of the editor to endpoint ("METHOD /route/pattern")
+func (s *UsageService) Record(editorID uuid.UUID, endpoint string) {
+	now := time.Now().UTC()
+	key := usageKey{editorID: editorID, periodStart: periodStart(now), endpoint: endpoint}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.pending[key]
+	if !ok {
+		counter = &usageCounter{}
+		s.pending[key] = counter
+	}
+	counter.calls++
+	counter.lastCalledAt = now
+}
+
+// Start flushes the recorded calls every flush interval until Stop is called
+func (s *UsageService) Start() {
+	s.startOnce.Do(func() {
+		go func() {
+			defer close(s.done)
+			ticker := time.NewTicker(s.flushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.Flush(context.Background())
+				case <-s.stopCh:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop ends the flush loop and writes the remaining counts
+func (s *UsageService) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+		// Mark the loop as started so a later Start is a no-op, and wait for it if it runs
+		s.startOnce.Do(func() { close(s.done) })
+	})
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.Flush(ctx)
+}
+
+// Flush writes the calls recorded since the last flush. Counts that cannot be written
+// are kept and retried on the next flush.
+func (s *UsageService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]*usageCounter)
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	increments := make([]repository.UsageIncrement, 0, len(pending))
+	for key, counter := range pending {
+		increments = append(increments, repository.UsageIncrement{
+			EditorID:     key.editorID,
+			PeriodStart:  key.periodStart,
+			Endpoint:     key.endpoint,
+			Calls:        counter.calls,
+			LastCalledAt: counter.lastCalledAt,
+		})
+	}
+
+	if err := s.usageRepo.AddCalls(ctx, increments); err != nil {
+		s.logger.WarnContext(ctx, "API usage flush failed, keeping counts for the next flush", "count", len(increments), "error", err)
+		s.restore(pending)
+		return err
+	}
+	return nil
+}
+
+// restore merges counts that could not be written back into the pending set
+func (s *UsageService) restore(counts map[usageKey]*usageCounter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, counter := range counts {
+		current, ok := s.pending[key]
+		if !ok {
+			s.pending[key] = counter
+			continue
+		}
+		current.calls += counter.calls
+		if counter.lastCalledAt.After(current.lastCalledAt) {
+			current.lastCalledAt = counter.lastCalledAt
+		}
+	}
+}
+
+// GetUsage returns the editor's API usage for period (YYYY-MM, UTC); an empty period
+// means the current month
+func (s *UsageService) GetUsage(ctx context.Context, editorID uuid.UUID, period string) (*generated.ApiUsage, error) {
+	start, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include this instance's latest calls; other instances catch up on their next flush
+	s.Flush(ctx)
+
+	endpoints, err := s.usageRepo.ListByEditor(ctx, editorID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	var lastActivity *time.Time
+	for _, endpoint := range endpoints {
+		total += *endpoint.Calls
+		if lastActivity == nil || endpoint.LastCalledAt.After(*lastActivity) {
+			lastActivity = endpoint.LastCalledAt
+		}
+	}
+
+	periodLabel := start.Format(usagePeriodLayout)
+	return &generated.ApiUsage{
+		EditorId:       &editorID,
+		Period:         &periodLabel,
+		TotalCalls:     &total,
+		LastActivityAt: lastActivity,
+		Endpoints:      &endpoints,
+	}, nil
+}
+
+// parsePeriod returns the first day of the month period (YYYY-MM, UTC); an empty period
+// means the current month
+func parsePeriod(period string) (time.Time, error) {
+	if period == "" {
+		return periodStart(time.Now().UTC()), nil
+	}
+	start, err := time.Parse(usagePeriodLayout, period)
+	if err != nil {
+		return time.Time{}, models.NewBadRequestError("period must be a month in YYYY-MM format")
+	}
+	return start, nil
+}
+
+// periodStart is the first day of t's calendar month in UTC
+func periodStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-newsletter/internal/config"
+	"go-newsletter/internal/httpclient"
+	"go-newsletter/internal/models"
+	"go-newsletter/internal/pagination"
+	"go-newsletter/internal/repository"
+	"go-newsletter/internal/utils"
+	"go-newsletter/pkg/generated"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// webhookSecretPrefix marks webhook signing secrets, so secret scanners and editors recognize them
+	webhookSecretPrefix      = "whsec_"
+	maxWebhooksPerNewsletter = 10
+	maxWebhookURLLength      = 2048
+	// maxWebhookErrorLength caps the error stored in the delivery log
+	maxWebhookErrorLength = 500
+	// webhookConcurrency is the number of webhook calls a dispatcher makes at once, so a batch
+	// of slow endpoints finishes well within WEBHOOK_LEASE
+	webhookConcurrency = 10
+)
+
+// Headers sent with every webhook call
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookEvent is the JSON body of a webhook call
+type webhookEvent struct {
+	Event        generated.WebhookEvent `json:"event"`
+	NewsletterID uuid.UUID              `json:"newsletter_id"`
+	OccurredAt   time.Time              `json:"occurred_at"`
+	Data         any                    `json:"data"`
+}
+
+type webhookPost struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Summary     *string   `json:"summary,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type webhookSubscriber struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+// WebhookService manages the webhooks of newsletters and delivers newsletter events to them
+type WebhookService struct {
+	webhookRepo       *repository.WebhookRepository
+	newsletterService *NewsletterService
+	client            *http.Client
+	config            *config.Config
+	logger            *slog.Logger
+}
+
+// NewWebhookService creates the service; client calls the webhook URLs and should refuse
+// internal addresses, see httpclient.NewOutbound
+func NewWebhookService(webhookRepo *repository.WebhookRepository, newsletterService *NewsletterService, client *http.Client, config *config.Config, logger *slog.Logger) *WebhookService {
+	utils.RequireDependencies("WebhookService",
+		utils.Dep("webhookRepo", webhookRepo),
+		utils.Dep("newsletterService", newsletterService),
+		utils.Dep("client", client),
+		utils.Dep("config", config),
+		utils.Dep("logger", logger),
+	)
+	return &WebhookService{
+		webhookRepo:       webhookRepo,
+		newsletterService: newsletterService,
+		client:            client,
+		config:            config,
+		logger:            logger,
+	}
+}
+
+// ListWebhooks returns the webhooks of a newsletter of the editor, newest first, without their secrets
+func (s *WebhookService) ListWebhooks(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID) ([]generated.Webhook, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListByNewsletter(ctx, newsletterID)
+}
+
+// CreateWebhook registers a webhook for a newsletter of the editor. The returned Webhook is
+// the only one that carries the signing secret.
+func (s *WebhookService) CreateWebhook(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, req generated.WebhookCreate) (*generated.Webhook, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleOwner); err != nil {
+		return nil, err
+	}
+	if err := s.validateURL(req.Url); err != nil {
+		return nil, err
+	}
+	events, err := webhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.webhookRepo.CountByNewsletter(ctx, newsletterID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooksPerNewsletter {
+		return nil, models.NewConflictError(fmt.Sprintf("A newsletter can have at most %d webhooks, delete one first", maxWebhooksPerNewsletter))
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	secret := webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	webhook, err := s.webhookRepo.Create(ctx, newsletterID, req.Url, secret, events)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = &secret
+	s.logger.InfoContext(ctx, "Webhook registered", "newsletterId", newsletterID, "webhookId", webhook.Id, "events", events)
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook of a newsletter of the editor, with its deliveries
+func (s *WebhookService) DeleteWebhook(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, webhookID uuid.UUID) error {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleOwner); err != nil {
+		return err
+	}
+	err := s.webhookRepo.Delete(ctx, newsletterID, webhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.NewNotFoundError("Webhook not found")
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Webhook deleted", "newsletterId", newsletterID, "webhookId", webhookID)
+	return nil
+}
+
+// ListDeliveries returns a page of the delivery log of a webhook, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, editorID uuid.UUID, newsletterID uuid.UUID, webhookID uuid.UUID, page pagination.Page) ([]generated.WebhookDelivery, *pagination.Cursor, error) {
+	if _, err := s.newsletterService.GetNewsletterForRole(ctx, newsletterID.String(), editorID.String(), repository.RoleOwner); err != nil {
+		return nil, nil, err
+	}
+	exists, err := s.webhookRepo.Exists(ctx, newsletterID, webhookID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, models.NewNotFoundError("Webhook not found")
+	}
+	return s.webhookRepo.ListDeliveries(ctx, webhookID, page)
+}
+
+// validateURL accepts absolute HTTP(S) URLs. Host names are checked when they are dialed; IP
+// addresses and localhost can be refused right away.
+func (s *WebhookService) validateURL(raw string) error {
+	if raw == "" || len(raw) > maxWebhookURLLength {
+		return models.NewBadRequestError("url must be 1 to 2048 characters")
+	}
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Hostname() == "" {
+		return models.NewBadRequestError("url must be an absolute http or https URL")
+	}
+	if target.User != nil {
+		return models.NewBadRequestError("url must not contain credentials")
+	}
+	if s.config.Webhooks.AllowPrivateTargets {
+		return nil
+	}
+	host := strings.ToLower(target.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return models.NewBadRequestError("url must not point to a loopback or private address")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !httpclient.IsPublicAddr(addr) {
+		return models.NewBadRequestError("url must not point to a loopback or private address")
+	}
+	return nil
+}
+
+// webhookEvents checks and deduplicates the events of a webhook
+func webhookEvents(requested []generated.WebhookEvent) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, models.NewBadRequestError("events must name at least one event")
+	}
+	var events []string
+	seen := make(map[generated.WebhookEvent]bool)
+	for _, event := range requested {
+		switch event {
+		case generated.PostPublished, generated.SubscriberConfirmed, generated.SubscriberUnsubscribed:
+		default:
+			return nil, models.NewBadRequestError(fmt.Sprintf("unknown event %q", event))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, string(event))
+		}
+	}
+	return events, nil
+}
+
+// PostPublished queues the post.published event of a post that was just published
+func (s *WebhookService) PostPublished(ctx context.Context, post *generated.PublishedPost) {
+	publishedAt := time.Now().UTC()
+	if post.PublishedAt != nil {
+		publishedAt = *post.PublishedAt
+	}
+	s.emit(ctx, uuid.UUID(*post.NewsletterId), generated.PostPublished, webhookPost{
+		ID:          uuid.UUID(*post.Id),
+		Title:       post.Title,
+		Summary:     post.Summary,
+		PublishedAt: publishedAt,
+	})
+}
+
+// SubscriberConfirmed queues the subscriber.confirmed event of a subscription
+func (s *WebhookService) SubscriberConfirmed(ctx context.Context, subscription *repository.ChangedSubscription) {
+	s.emit(ctx, subscription.NewsletterID, generated.SubscriberConfirmed, webhookSubscriber{
+		ID:    subscription.SubscriberID,
+		Email: subscription.Email,
+	})
+}
+
+// SubscriberUnsubscribed queues the subscriber.unsubscribed event of a subscription
+func (s *WebhookService) SubscriberUnsubscribed(ctx context.Context, subscription *repository.ChangedSubscription) {
+	s.emit(ctx, subscription.NewsletterID, generated.SubscriberUnsubscribed, webhookSubscriber{
+		ID:    subscription.SubscriberID,
+		Email: subscription.Email,
+	})
+}
+
+// emit queues an event for the webhooks of the newsletter that subscribe to it. The event has
+// already happened, so a failure is logged and not returned to the caller, and the event is
+// queued even when the request that caused it is cancelled.
+func (s *WebhookService) emit(ctx context.Context, newsletterID uuid.UUID, event generated.WebhookEvent, data any) {
+	ctx = context.WithoutCancel(ctx)
+	payload, err := json.Marshal(webhookEvent{
+		Event:        event,
+		NewsletterID: newsletterID,
+		OccurredAt:   time.Now().UTC(),
+		Data:         data,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to encode webhook event", "event", event, "error", err)
+		return
+	}
+	queued, err := s.webhookRepo.Enqueue(ctx, newsletterID, string(event), payload)
+	if err != nil {
+		return
+	}
+	if queued > 0 {
+		s.logger.DebugContext(ctx, "Queued webhook deliveries", "newsletterId", newsletterID, "event", event, "count", queued)
+	}
+}
+
+// DeliverQueued calls the webhooks of the queued deliveries, a claimed batch at a time until
+// none is due, and returns how many were delivered and how many failed for good. A delivery
+// whose webhook does not answer with a 2xx status is retried after WEBHOOK_RETRY_BACKOFF,
+// doubled for every further attempt, until it used up WEBHOOK_MAX_ATTEMPTS.
+func (s *WebhookService) DeliverQueued(ctx context.Context) (delivered int, failed int, err error) {
+	for ctx.Err() == nil {
+		calls, err := s.webhookRepo.Claim(ctx, s.config.Webhooks.BatchSize, s.config.Webhooks.Lease)
+		if err != nil {
+			return delivered, failed, err
+		}
+		if len(calls) == 0 {
+			break
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, webhookConcurrency)
+		for _, call := range calls {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(call repository.WebhookCall) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				ok, final := s.deliver(ctx, call)
+				mu.Lock()
+				defer mu.Unlock()
+				if ok {
+					delivered++
+				} else if final {
+					failed++
+				}
+			}(call)
+		}
+		wg.Wait()
+	}
+	return delivered, failed, nil
+}
+
+// deliver makes one call of a claimed delivery and settles it. It reports whether the call
+// succeeded and, if not, whether the delivery failed for good.
+func (s *WebhookService) deliver(ctx context.Context, call repository.WebhookCall) (ok bool, final bool) {
+	status, callErr := s.call(ctx, call)
+
+	// Settle even when the run is cancelled, so the delivery is not retried before its backoff
+	settleCtx := context.WithoutCancel(ctx)
+	if callErr == nil {
+		s.webhookRepo.MarkDelivered(settleCtx, call.ID, *status)
+		return true, false
+	}
+
+	message := callErr.Error()
+	if len(message) > maxWebhookErrorLength {
+		message = strings.ToValidUTF8(message[:maxWebhookErrorLength], "")
+	}
+	if call.Attempts >= s.config.Webhooks.MaxAttempts {
+		s.logger.WarnContext(ctx, "Webhook delivery failed for good", "deliveryId", call.ID, "webhookId", call.WebhookID, "attempts", call.Attempts, "error", message)
+		s.webhookRepo.MarkFailed(settleCtx, call.ID, status, message)
+		return false, true
+	}
+	s.webhookRepo.Reschedule(settleCtx, call.ID, s.retryDelay(call.Attempts), status, message)
+	return false, false
+}
+
+// call posts the payload of a delivery to its webhook. It returns the status code of the
+// answer, nil when there was none, and an error unless the status is 2xx.
+func (s *WebhookService) call(ctx context.Context, call repository.WebhookCall) (*int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, call.URL, bytes.NewReader(call.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-newsletter-webhooks")
+	req.Header.Set(WebhookEventHeader, call.Event)
+	req.Header.Set(WebhookDeliveryHeader, call.ID.String())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(call.Secret, timestamp, call.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return &status, nil
+}
+
+// retryDelay is the wait before the next call of a delivery after its attempts-th failed one:
+// WEBHOOK_RETRY_BACKOFF, doubled for every further attempt
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.config.Webhooks.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// PurgeFinishedDeliveries deletes deliveries that were delivered or failed longer than
+// WEBHOOK_RETENTION ago
+func (s *WebhookService) PurgeFinishedDeliveries(ctx context.Context) (int64, error) {
+	return s.webhookRepo.Purge(ctx, s.config.Webhooks.Retention)
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed with the webhook
+// secret. Receivers recompute it to check that a call is authentic and, with the timestamp,
+// recent.
+func SignWebhook(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,49 @@
+// Package sitemap renders sitemaps in the sitemaps.org protocol, which tell search engines the
+// pages of a site and when they last changed.
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs is how many URLs the protocol allows in one sitemap
+const MaxURLs = 50000
+
+// URL is a page of the site
+type URL struct {
+	Loc string
+	// LastMod is when the page last changed; the zero time leaves it out
+	LastMod time.Time
+}
+
+type urlset struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []url    `xml:"url"`
+}
+
+type url struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Render renders the URLs as a sitemap, leaving out those past MaxURLs
+func Render(urls []URL) ([]byte, error) {
+	if len(urls) > MaxURLs {
+		urls = urls[:MaxURLs]
+	}
+	doc := urlset{URLs: make([]url, 0, len(urls))}
+	for _, u := range urls {
+		entry := url{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			entry.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		doc.URLs = append(doc.URLs, entry)
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
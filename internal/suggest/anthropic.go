@@ -0,0 +1,62 @@
+package suggest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// anthropicVersion is the Messages API version the requests below are written against
+const anthropicVersion = "2023-06-01"
+
+// anthropicClient uses the Anthropic Messages API
+type anthropicClient struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func (c *anthropicClient) complete(ctx context.Context, system string, user string) (string, error) {
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	request := anthropicRequest{
+		Model:     c.model,
+		MaxTokens: 1024,
+		System:    system,
+		Messages:  []anthropicMessage{{Role: "user", Content: user}},
+	}
+
+	var response anthropicResponse
+	if err := postJSON(ctx, c.client, c.baseURL+"/v1/messages", headers, request, &response); err != nil {
+		return "", err
+	}
+	var answer strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			answer.WriteString(block.Text)
+		}
+	}
+	return answer.String(), nil
+}
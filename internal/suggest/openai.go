@@ -0,0 +1,56 @@
+package suggest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// openAIClient uses the chat completions API of OpenAI, which many self-hosted and
+// third-party model servers implement as well
+type openAIClient struct {
+	client  *http.Client
+	baseURL string
+	// apiKey may be empty for self-hosted servers
+	apiKey string
+	model  string
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (c *openAIClient) complete(ctx context.Context, system string, user string) (string, error) {
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	request := openAIRequest{
+		Model: c.model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+	}
+
+	var response openAIResponse
+	if err := postJSON(ctx, c.client, c.baseURL+"/chat/completions", headers, request, &response); err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", errors.New("suggestion provider answered without choices")
+	}
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,187 @@
+// Package suggest asks a language model for subject lines, preview texts (preheaders) and
+// summaries of a post. The provider is configured with SUGGESTIONS_PROVIDER.
+package suggest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go-newsletter/internal/config"
+)
+
+// maxContentChars caps the post text sent to the provider; subject lines rarely need more
+const maxContentChars = 8000
+
+// Post is what suggestions are based on
+type Post struct {
+	NewsletterName string
+	Title          string
+	// Text is the plain text of the post
+	Text string
+}
+
+// Suggestions are alternative subject lines and preheaders for a post
+type Suggestions struct {
+	SubjectLines []string `json:"subject_lines"`
+	Preheaders   []string `json:"preheaders"`
+}
+
+// Provider writes suggestions for posts
+type Provider interface {
+	// Suggest returns count subject lines and preheaders for a post
+	Suggest(ctx context.Context, post Post, count int) (*Suggestions, error)
+	// Summarize returns a plain text summary of a post of at most maxChars characters
+	Summarize(ctx context.Context, post Post, maxChars int) (string, error)
+}
+
+// completer sends one system and one user message to a model API and returns its answer
+type completer interface {
+	complete(ctx context.Context, system string, user string) (string, error)
+}
+
+// New returns the provider configured by cfg.Provider, "openai" (or any OpenAI compatible API
+// via cfg.BaseURL) or "anthropic"
+func New(cfg config.SuggestionsConfig, client *http.Client) (Provider, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("SUGGESTIONS_MODEL is required for model suggestions")
+	}
+
+	switch cfg.Provider {
+	case "openai":
+		return &provider{&openAIClient{
+			client:  client,
+			baseURL: baseURL(cfg.BaseURL, "https://api.openai.com/v1"),
+			apiKey:  cfg.APIKey,
+			model:   cfg.Model,
+		}}, nil
+	case "anthropic":
+		if cfg.APIKey == "" {
+			return nil, errors.New("SUGGESTIONS_API_KEY is required for the anthropic provider")
+		}
+		return &provider{&anthropicClient{
+			client:  client,
+			baseURL: baseURL(cfg.BaseURL, "https://api.anthropic.com"),
+			apiKey:  cfg.APIKey,
+			model:   cfg.Model,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported suggestions provider %q, use openai or anthropic", cfg.Provider)
+	}
+}
+
+// provider writes the prompts and reads the answers; the completer talks to the model API
+type provider struct {
+	completer
+}
+
+func (p *provider) Suggest(ctx context.Context, post Post, count int) (*Suggestions, error) {
+	answer, err := p.complete(ctx, suggestPrompt(count), userPrompt(post))
+	if err != nil {
+		return nil, err
+	}
+	return parseSuggestions(answer, count)
+}
+
+func (p *provider) Summarize(ctx context.Context, post Post, maxChars int) (string, error) {
+	answer, err := p.complete(ctx, summarizePrompt(maxChars), userPrompt(post))
+	if err != nil {
+		return "", err
+	}
+	summary := strings.Join(strings.Fields(answer), " ")
+	if summary == "" {
+		return "", errors.New("suggestion provider answered without a summary")
+	}
+	return summary, nil
+}
+
+func baseURL(configured, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+	return strings.TrimSuffix(configured, "/")
+}
+
+// suggestPrompt asks for a JSON answer so it can be parsed without the provider's structured
+// output features, which differ between providers
+func suggestPrompt(count int) string {
+	return fmt.Sprintf(`You write email subject lines and preview texts (preheaders) for newsletter posts.
+Answer with a JSON object only, no other text: {"subject_lines": [...], "preheaders": [...]}.
+Give exactly %d subject lines of at most 60 characters and %d preheaders of at most 110 characters.
+Write in the language of the post and do not invent facts that are not in it.`, count, count)
+}
+
+func summarizePrompt(maxChars int) string {
+	return fmt.Sprintf(`You summarize newsletter posts for previews in listings and feeds.
+Answer with the summary only: plain text, no markdown, no quotes, at most %d characters.
+Write in the language of the post and do not invent facts that are not in it.`, maxChars)
+}
+
+func userPrompt(post Post) string {
+	text := post.Text
+	if len(text) > maxContentChars {
+		text = strings.ToValidUTF8(text[:maxContentChars], "")
+	}
+	return fmt.Sprintf("Newsletter: %s\nCurrent subject: %s\n\nPost:\n%s", post.NewsletterName, post.Title, text)
+}
+
+// parseSuggestions reads the JSON object of a model answer, ignoring text around it such as
+// code fences, and keeps at most count non-empty entries of each list
+func parseSuggestions(answer string, count int) (*Suggestions, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("suggestion provider answered without a JSON object")
+	}
+	var suggestions Suggestions
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &suggestions); err != nil {
+		return nil, fmt.Errorf("suggestion provider answered with invalid JSON: %w", err)
+	}
+	suggestions.SubjectLines = cleanList(suggestions.SubjectLines, count)
+	suggestions.Preheaders = cleanList(suggestions.Preheaders, count)
+	if len(suggestions.SubjectLines) == 0 && len(suggestions.Preheaders) == 0 {
+		return nil, errors.New("suggestion provider answered without suggestions")
+	}
+	return &suggestions, nil
+}
+
+func cleanList(values []string, count int) []string {
+	cleaned := make([]string, 0, count)
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" && len(cleaned) < count {
+			cleaned = append(cleaned, value)
+		}
+	}
+	return cleaned
+}
+
+// postJSON sends body as JSON and decodes a 2xx answer into out
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("suggestion provider %s: %s: %s", url, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,143 @@
+// Package summarize turns post HTML into plain text and extracts short summaries from it
+// without external services: the sentences that best represent the post, in their order.
+package summarize
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// blockPattern matches tags that end a line of text, so their content does not run together
+	blockPattern = regexp.MustCompile(`(?i)</?(p|div|br|li|h[1-6]|tr|blockquote|section|article)\b[^>]*>`)
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	// sentenceEnd matches the end of a sentence and the space after it
+	sentenceEnd = regexp.MustCompile(`([.!?]+["')\]]*)\s+`)
+)
+
+// PlainText turns post HTML into text, one line per block element
+func PlainText(contentHTML string) string {
+	text := blockPattern.ReplaceAllString(contentHTML, "\n")
+	text = html.UnescapeString(tagPattern.ReplaceAllString(text, " "))
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Extract returns the sentences of text that best summarize it, in their original order and
+// at most maxChars long together. Sentences score by how frequent their words are in the
+// whole text; the first sentence gets a bonus, as posts usually open with their point. Text
+// without a sentence short enough is cut at a word boundary with an ellipsis.
+func Extract(text string, maxChars int) string {
+	sentences := splitSentences(text)
+	if len(sentences) == 0 || maxChars <= 0 {
+		return ""
+	}
+
+	frequency := make(map[string]int)
+	for _, sentence := range sentences {
+		for _, word := range words(sentence) {
+			frequency[word]++
+		}
+	}
+
+	type scored struct {
+		index int
+		score float64
+	}
+	ranked := make([]scored, 0, len(sentences))
+	for i, sentence := range sentences {
+		sentenceWords := words(sentence)
+		if len(sentenceWords) == 0 {
+			continue
+		}
+		total := 0
+		for _, word := range sentenceWords {
+			total += frequency[word]
+		}
+		score := float64(total) / float64(len(sentenceWords))
+		if i == 0 {
+			score *= 1.5
+		}
+		ranked = append(ranked, scored{index: i, score: score})
+	}
+	sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].score > ranked[b].score })
+
+	chosen := make([]bool, len(sentences))
+	length := 0
+	for _, candidate := range ranked {
+		size := utf8.RuneCountInString(sentences[candidate.index])
+		if length > 0 {
+			size++ // the space joining it to the others
+		}
+		if length+size > maxChars {
+			continue
+		}
+		chosen[candidate.index] = true
+		length += size
+	}
+
+	var summary []string
+	for i, sentence := range sentences {
+		if chosen[i] {
+			summary = append(summary, sentence)
+		}
+	}
+	if len(summary) == 0 {
+		return Truncate(sentences[0], maxChars)
+	}
+	return strings.Join(summary, " ")
+}
+
+// Truncate cuts text to at most maxChars characters at a word boundary, marking the cut with an ellipsis
+func Truncate(text string, maxChars int) string {
+	if utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	runes := []rune(text)[:maxChars-1]
+	cut := strings.LastIndexFunc(string(runes), unicode.IsSpace)
+	if cut <= 0 {
+		return string(runes) + "…"
+	}
+	return strings.TrimRightFunc(string(runes)[:cut], func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// splitSentences splits text at sentence ends and line breaks
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		marked := sentenceEnd.ReplaceAllString(strings.TrimSpace(line), "$1\n")
+		for _, sentence := range strings.Split(marked, "\n") {
+			if sentence = strings.TrimSpace(sentence); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+		}
+	}
+	return sentences
+}
+
+// words returns the lower-cased words of a sentence that carry meaning: short words are
+// mostly articles, pronouns and prepositions in any language, so they are left out
+func words(sentence string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	meaningful := fields[:0]
+	for _, word := range fields {
+		if utf8.RuneCountInString(word) > 3 {
+			meaningful = append(meaningful, word)
+		}
+	}
+	return meaningful
+}
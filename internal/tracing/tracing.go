@@ -0,0 +1,70 @@
+// Package tracing sends OpenTelemetry traces of requests, SQL statements, email sends and
+// scheduler runs to an OTLP/HTTP collector. Without OTEL_EXPORTER_OTLP_ENDPOINT the global
+// tracer provider stays the no-op one, so spans cost next to nothing. Spans never carry email
+// addresses, tokens or the bound arguments of SQL statements.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"go-newsletter/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the application's own spans
+const instrumentationName = "go-newsletter"
+
+// Setup installs the global tracer provider exporting to the configured collector and the
+// W3C trace context propagator. The returned function flushes the spans not exported yet and
+// must be called on shutdown; with tracing disabled it does nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig, logger *slog.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Failed to export traces", "error", err)
+	}))
+	logger.Info("Tracing enabled", "endpoint", cfg.OTLPEndpoint, "sampleRatio", cfg.SampleRatio)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the application's spans, from the global provider at the time
+// of the call, so spans started after Setup are exported
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records err on span, unless it is nil, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
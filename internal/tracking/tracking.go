@@ -0,0 +1,155 @@
+// Package tracking adds open and click tracking to post emails. Each email carries a message
+// ID in a 1x1 pixel loaded when it is opened and in its links, which are rewritten to pass
+// through a redirect recording the click. Click links are signed, so the redirect cannot be
+// used to send people to addresses that were never in a post.
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// clickPurpose separates click link signatures from other uses of the same secret
+const clickPurpose = "click:"
+
+// signatureBytes is the length of the HMAC kept in click links
+const signatureBytes = 16
+
+// Pixel is a transparent 1x1 GIF, served for the open pixel
+var Pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// Body is the HTML of a post prepared for tracking: the HTML around its trackable links, so
+// the links can be rewritten for every recipient without parsing the HTML again
+type Body struct {
+	// parts has one element more than links; link i sits between parts i and i+1
+	parts []string
+	links []string
+}
+
+// Prepare finds the http and https links of post HTML. Other links, like mailto: and page
+// anchors, are left as they are.
+func Prepare(contentHTML string) (Body, error) {
+	parent := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(contentHTML), parent)
+	if err != nil {
+		return Body{}, err
+	}
+
+	// Links are replaced by markers while rendering, then cut out of the result. The nonce
+	// keeps the markers from matching text of the post.
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return Body{}, err
+	}
+	marker := "tracking-" + hex.EncodeToString(nonce) + "-"
+
+	var links []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			for i, a := range n.Attr {
+				if a.Namespace == "" && strings.EqualFold(a.Key, "href") && trackable(a.Val) {
+					links = append(links, strings.TrimSpace(a.Val))
+					n.Attr[i].Val = fmt.Sprintf("%s%d-", marker, len(links)-1)
+					break
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	var rendered strings.Builder
+	for _, n := range nodes {
+		walk(n)
+		if err := html.Render(&rendered, n); err != nil {
+			return Body{}, err
+		}
+	}
+
+	body := Body{links: links}
+	rest := rendered.String()
+	for i := range links {
+		before, after, _ := strings.Cut(rest, fmt.Sprintf("%s%d-", marker, i))
+		body.parts = append(body.parts, before)
+		rest = after
+	}
+	body.parts = append(body.parts, rest)
+	return body, nil
+}
+
+func trackable(href string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Tracker builds the tracking URLs of emails and verifies click links
+type Tracker struct {
+	// baseURL is where the /t routes are served
+	baseURL string
+	secret  []byte
+}
+
+// NewTracker creates a Tracker for the /t routes under baseURL, signing with secret
+func NewTracker(baseURL string, secret []byte) *Tracker {
+	return &Tracker{baseURL: baseURL, secret: secret}
+}
+
+// HTML returns the body with its links going through click tracking and the open pixel at
+// the end, for the email with the message ID
+func (t *Tracker) HTML(body Body, messageID uuid.UUID) string {
+	var out strings.Builder
+	for i, link := range body.links {
+		out.WriteString(body.parts[i])
+		out.WriteString(html.EscapeString(t.ClickURL(messageID, link)))
+	}
+	out.WriteString(body.parts[len(body.parts)-1])
+	fmt.Fprintf(&out, `<img src="%s" width="1" height="1" alt="" style="display:block;border:0;width:1px;height:1px">`,
+		html.EscapeString(t.OpenURL(messageID)))
+	return out.String()
+}
+
+// OpenURL is the pixel of the email with the message ID
+func (t *Tracker) OpenURL(messageID uuid.UUID) string {
+	return fmt.Sprintf("%s/t/open/%s", t.baseURL, messageID)
+}
+
+// ClickURL is the link of the email with the message ID that records a click, then
+// redirects to target
+func (t *Tracker) ClickURL(messageID uuid.UUID, target string) string {
+	query := url.Values{}
+	query.Set("url", target)
+	query.Set("sig", base64.RawURLEncoding.EncodeToString(t.sign(messageID, target)))
+	return fmt.Sprintf("%s/t/click/%s?%s", t.baseURL, messageID, query.Encode())
+}
+
+// Verify reports whether sig is the signature of a click link to target in the email with
+// the message ID
+func (t *Tracker) Verify(messageID uuid.UUID, target string, sig string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(decoded, t.sign(messageID, target))
+}
+
+func (t *Tracker) sign(messageID uuid.UUID, target string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(clickPurpose + messageID.String() + "\n" + target))
+	return mac.Sum(nil)[:signatureBytes]
+}
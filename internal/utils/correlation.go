@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"context"
+)
+
+// CorrelationHeader carries the correlation ID on outbound requests and sent emails
+const CorrelationHeader = "X-Correlation-ID"
+
+type correlationKey struct{}
+
+// WithCorrelationID tags ctx with the ID of the API request or scheduler run doing the work,
+// so outbound calls and emails can be traced back to it
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID stored in ctx, or an empty string
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Dependency is a named constructor argument checked by RequireDependencies
+type Dependency struct {
+	Name  string
+	Value any
+}
+
+// Dep names a dependency for RequireDependencies
+func Dep(name string, value any) Dependency {
+	return Dependency{Name: name, Value: value}
+}
+
+// RequireDependencies panics if any dependency is nil. Constructors call it so a
+// wiring mistake fails at startup instead of as a nil pointer panic on first use.
+func RequireDependencies(component string, deps ...Dependency) {
+	for _, dep := range deps {
+		if isNil(dep.Value) {
+			panic(fmt.Sprintf("%s: missing dependency %q", component, dep.Name))
+		}
+	}
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Interface, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
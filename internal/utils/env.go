@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -32,4 +33,51 @@ func GetInt32WithDefault(key string, defaultValue int32) int32 {
 		}
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+// GetIntWithDefault returns the environment variable as int or a default value if not set/invalid
+func GetIntWithDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+// GetFloatWithDefault returns the environment variable as float64 or a default value if not set/invalid
+func GetFloatWithDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// GetBoolWithDefault returns the environment variable as bool or a default value if not set/invalid
+func GetBoolWithDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// GetListWithDefault returns a comma-separated environment variable as a list of trimmed,
+// non-empty values or a default value if not set
+func GetListWithDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"mime"
 	"net/http"
 
 	"go-newsletter/internal/models"
@@ -25,13 +28,116 @@ func NewHTTPResponder(logger *slog.Logger) *HTTPResponder {
 func (h *HTTPResponder) RespondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	
+
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		h.Logger.Error("Failed to encode JSON response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
+// streamFlushEvery controls how many array elements are written between flushes
+const streamFlushEvery = 100
+
+// StreamJSONArray writes a JSON array whose elements are produced by produce, encoding
+// and flushing them as they arrive instead of buffering the whole collection in memory.
+// Errors returned before the status is written are handled like HandleError; afterwards,
+// even when writing the first element failed, the error is only logged and the body is cut short.
+func (h *HTTPResponder) StreamJSONArray(w http.ResponseWriter, r *http.Request, status int, produce func(emit func(v interface{}) error) error) {
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	started := false
+	count := 0
+
+	emit := func(v interface{}) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			started = true
+			if _, err := w.Write([]byte("[")); err != nil {
+				return err
+			}
+		} else if _, err := w.Write([]byte(",")); err != nil {
+			return err
+		}
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		count++
+		if flusher != nil && count%streamFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if err := produce(emit); err != nil {
+		if !started {
+			h.HandleError(w, r, err)
+			return
+		}
+		h.Logger.ErrorContext(r.Context(), "Failed to stream JSON response", "error", err, "written", count)
+		return
+	}
+
+	if !started {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte("[]\n"))
+		return
+	}
+	w.Write([]byte("]\n"))
+}
+
+// StreamCSV writes a CSV document with the header row and the records produced by produce,
+// flushing them as they arrive, offered for download as filename. Errors are handled as in
+// StreamJSONArray.
+func (h *HTTPResponder) StreamCSV(w http.ResponseWriter, r *http.Request, status int, filename string, header []string, produce func(emit func(record []string) error) error) {
+	flusher, _ := w.(http.Flusher)
+	writer := csv.NewWriter(w)
+	started := false
+	count := 0
+
+	start := func() error {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.WriteHeader(status)
+		started = true
+		return writer.Write(header)
+	}
+	emit := func(record []string) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		count++
+		if count%streamFlushEvery == 0 {
+			writer.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return writer.Error()
+	}
+
+	if err := produce(emit); err != nil {
+		if !started {
+			h.HandleError(w, r, err)
+			return
+		}
+		writer.Flush()
+		h.Logger.ErrorContext(r.Context(), "Failed to stream CSV response", "error", err, "written", count)
+		return
+	}
+
+	if !started {
+		start()
+	}
+	writer.Flush()
+}
+
 // RespondError sends an error response
 func (h *HTTPResponder) RespondError(w http.ResponseWriter, status int, message string) {
 	h.RespondJSON(w, status, map[string]string{
@@ -39,23 +145,44 @@ func (h *HTTPResponder) RespondError(w http.ResponseWriter, status int, message
 	})
 }
 
+// busyRetryAfter is the Retry-After, in seconds, of responses to requests that found the
+// database pool exhausted
+const busyRetryAfter = "2"
+
 // HandleError handles API errors and sends appropriate responses
 func (h *HTTPResponder) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	errorResponse := h.ErrorBody(w, r, err)
+	h.RespondJSON(w, int(errorResponse.Code), errorResponse)
+}
+
+// ErrorBody logs err and returns the error response for it, whose code is the HTTP status. It
+// sets the headers going with the error, for responses that render errors other than as JSON.
+func (h *HTTPResponder) ErrorBody(w http.ResponseWriter, r *http.Request, err error) generated.Error {
+	if errors.Is(err, models.ErrDatabaseBusy) {
+		h.Logger.WarnContext(r.Context(), "Database pool exhausted", "error", err)
+		w.Header().Set("Retry-After", busyRetryAfter)
+		return generated.Error{
+			Code:    http.StatusServiceUnavailable,
+			Message: "The service is busy, please try again shortly",
+		}
+	}
+
 	if apiErr, ok := err.(models.APIError); ok {
 		h.Logger.WarnContext(r.Context(), "API error", "code", apiErr.Code, "message", apiErr.Message)
 		errorResponse := generated.Error{
 			Code:    int32(apiErr.Code),
 			Message: apiErr.Message,
 		}
-		h.RespondJSON(w, apiErr.Code, errorResponse)
-		return
+		if apiErr.Reason != "" {
+			errorResponse.Reason = &apiErr.Reason
+		}
+		return errorResponse
 	}
 
 	// For unexpected errors, log them and return a generic 500
 	h.Logger.ErrorContext(r.Context(), "Unexpected error", "error", err)
-	errorResponse := generated.Error{
+	return generated.Error{
 		Code:    500,
 		Message: "An unexpected error occurred",
 	}
-	h.RespondJSON(w, http.StatusInternalServerError, errorResponse)
-} 
\ No newline at end of file
+}
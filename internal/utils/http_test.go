@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// brokenWriter is a response writer whose client went away: every write fails
+type brokenWriter struct {
+	header   http.Header
+	statuses []int
+	writes   int
+}
+
+func (w *brokenWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *brokenWriter) WriteHeader(status int) {
+	w.statuses = append(w.statuses, status)
+}
+
+func (w *brokenWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestStreamStopsAfterFailedFirstWrite(t *testing.T) {
+	responder := NewHTTPResponder(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r := httptest.NewRequest(http.MethodGet, "/subscribers", nil)
+
+	streams := map[string]func(w http.ResponseWriter){
+		"json": func(w http.ResponseWriter) {
+			responder.StreamJSONArray(w, r, http.StatusOK, func(emit func(v interface{}) error) error {
+				return emit(map[string]string{"email": "reader@example.com"})
+			})
+		},
+		// The CSV writer buffers, so the record is large enough to reach the connection
+		"csv": func(w http.ResponseWriter) {
+			responder.StreamCSV(w, r, http.StatusOK, "subscribers.csv", []string{"email"}, func(emit func(record []string) error) error {
+				return emit([]string{strings.Repeat("x", 8192)})
+			})
+		},
+	}
+	for name, stream := range streams {
+		t.Run(name, func(t *testing.T) {
+			w := &brokenWriter{header: http.Header{}}
+			stream(w)
+
+			if len(w.statuses) != 1 || w.statuses[0] != http.StatusOK {
+				t.Errorf("statuses written = %v, want only %d", w.statuses, http.StatusOK)
+			}
+			if w.writes != 1 {
+				t.Errorf("writes = %d, want 1, without an error body after the failed write", w.writes)
+			}
+		})
+	}
+}
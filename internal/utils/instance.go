@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	instanceID     string
+	instanceIDOnce sync.Once
+)
+
+// InstanceID identifies this process (hostname and pid). It is used as the Postgres
+// application_name so locks held by a connection can be traced back to an instance.
+func InstanceID() string {
+	instanceIDOnce.Do(func() {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "unknown"
+		}
+		instanceID = fmt.Sprintf("go-newsletter-%s-%d", host, os.Getpid())
+	})
+	return instanceID
+}
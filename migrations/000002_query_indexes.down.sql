@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_newsletters_editor_id_created_at;
+DROP INDEX IF EXISTS idx_published_posts_newsletter_published_at;
+DROP INDEX IF EXISTS idx_published_posts_status_scheduled_at;
+DROP INDEX IF EXISTS idx_subscribers_email;
+DROP INDEX IF EXISTS idx_subscribers_newsletter_active;
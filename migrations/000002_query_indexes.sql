@@ -0,0 +1,23 @@
+-- Indexes backing the most frequent repository queries
+
+-- Subscriber listings and sends filter by newsletter and skip unsubscribed rows
+CREATE INDEX IF NOT EXISTS idx_subscribers_newsletter_active
+    ON subscribers (newsletter_id, subscribed_at)
+    WHERE unsubscribed_at IS NULL;
+
+-- Lookups by email across newsletters (duplicate checks, GDPR requests)
+CREATE INDEX IF NOT EXISTS idx_subscribers_email
+    ON subscribers (email);
+
+-- Scheduler: due posts are selected by status and scheduled_at while still unpublished
+CREATE INDEX IF NOT EXISTS idx_published_posts_status_scheduled_at
+    ON published_posts (status, scheduled_at)
+    WHERE published_at IS NULL;
+
+-- Post listings per newsletter, newest first
+CREATE INDEX IF NOT EXISTS idx_published_posts_newsletter_published_at
+    ON published_posts (newsletter_id, published_at DESC);
+
+-- Editor dashboards list newsletters by owner
+CREATE INDEX IF NOT EXISTS idx_newsletters_editor_id_created_at
+    ON newsletters (editor_id, created_at DESC);
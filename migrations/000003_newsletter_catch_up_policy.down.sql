@@ -0,0 +1,3 @@
+ALTER TABLE newsletters
+    DROP COLUMN IF EXISTS catch_up_max_age_minutes,
+    DROP COLUMN IF EXISTS catch_up_policy;
@@ -0,0 +1,9 @@
+-- Catch-up policy applied by the scheduler to posts whose scheduled time passed while it was down
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS catch_up_policy TEXT NOT NULL DEFAULT 'send_all'
+        CHECK (catch_up_policy = ANY (ARRAY['send_all'::text, 'latest_only'::text, 'skip_older_than'::text])),
+    ADD COLUMN IF NOT EXISTS catch_up_max_age_minutes INTEGER
+        CHECK (catch_up_max_age_minutes IS NULL OR catch_up_max_age_minutes > 0);
+
+COMMENT ON COLUMN newsletters.catch_up_policy IS 'How overdue scheduled posts are handled after scheduler downtime: send_all, latest_only or skip_older_than.';
+COMMENT ON COLUMN newsletters.catch_up_max_age_minutes IS 'For skip_older_than: overdue posts older than this many minutes are skipped instead of sent.';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_email_jobs_status_created_at;
+DROP TABLE IF EXISTS email_jobs;
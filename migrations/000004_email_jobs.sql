@@ -0,0 +1,24 @@
+-- Email deliveries that failed, kept so admins can inspect and retry them
+CREATE TABLE IF NOT EXISTS email_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL CHECK (kind = ANY (ARRAY['post'::text, 'confirmation'::text])),
+    status TEXT NOT NULL DEFAULT 'failed' CHECK (status = ANY (ARRAY['failed'::text, 'succeeded'::text])),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES published_posts(id) ON DELETE CASCADE,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    html TEXT NOT NULL,
+    correlation_id TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE email_jobs IS 'Failed email deliveries (post emails and subscription confirmations) that can be retried from the admin API.';
+COMMENT ON COLUMN email_jobs.html IS 'Rendered email body as sent, including the recipient''s unsubscribe or confirmation token.';
+COMMENT ON COLUMN email_jobs.correlation_id IS 'Request ID or scheduler run that produced the email.';
+
+-- Admin listing: jobs by status, newest first
+CREATE INDEX IF NOT EXISTS idx_email_jobs_status_created_at
+    ON email_jobs (status, created_at DESC);
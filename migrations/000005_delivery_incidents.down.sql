@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_incidents_post_id;
+DROP TABLE IF EXISTS incidents;
+
+ALTER TABLE published_posts
+    DROP COLUMN IF EXISTS emails_failed,
+    DROP COLUMN IF EXISTS emails_sent;
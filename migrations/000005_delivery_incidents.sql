@@ -0,0 +1,27 @@
+-- Delivery counters per post, incremented by every dispatch (including republishes)
+ALTER TABLE published_posts
+    ADD COLUMN IF NOT EXISTS emails_sent INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS emails_failed INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN published_posts.emails_sent IS 'Emails accepted by the provider for this post, across all dispatches.';
+COMMENT ON COLUMN published_posts.emails_failed IS 'Emails that still failed after all retries, across all dispatches.';
+
+-- Permanently failed deliveries surfaced to editors and admins
+CREATE TABLE IF NOT EXISTS incidents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES published_posts(id) ON DELETE CASCADE,
+    email_job_id UUID REFERENCES email_jobs(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL CHECK (scope = ANY (ARRAY['recipient'::text, 'post'::text])),
+    systemic BOOLEAN NOT NULL DEFAULT FALSE,
+    failed_count INTEGER NOT NULL DEFAULT 1,
+    message TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE incidents IS 'Email deliveries that failed permanently, for a single recipient or a whole post.';
+COMMENT ON COLUMN incidents.systemic IS 'The failure rate suggests a provider or configuration problem rather than bad addresses; admins are alerted.';
+
+CREATE INDEX IF NOT EXISTS idx_incidents_post_id
+    ON incidents (post_id, created_at DESC);
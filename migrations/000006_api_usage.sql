@@ -0,0 +1,13 @@
+-- API calls per editor, endpoint and calendar month; the basis for plan-based quotas
+CREATE TABLE IF NOT EXISTS api_usage (
+    editor_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    endpoint TEXT NOT NULL,
+    call_count BIGINT NOT NULL DEFAULT 0,
+    last_called_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (editor_id, period_start, endpoint)
+);
+
+COMMENT ON TABLE api_usage IS 'Authenticated API calls aggregated per editor, calendar month (UTC) and endpoint.';
+COMMENT ON COLUMN api_usage.period_start IS 'First day of the calendar month (UTC) the calls were made in.';
+COMMENT ON COLUMN api_usage.endpoint IS 'HTTP method and route pattern, e.g. GET /api/v1/newsletters/{newsletterId}.';
@@ -0,0 +1,2 @@
+ALTER TABLE profiles DROP COLUMN IF EXISTS plan_id;
+DROP TABLE IF EXISTS plans;
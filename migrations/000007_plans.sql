@@ -0,0 +1,24 @@
+-- Subscription tiers; a NULL limit means unlimited
+CREATE TABLE IF NOT EXISTS plans (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    max_subscribers INTEGER,
+    monthly_emails INTEGER,
+    custom_domain BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE plans IS 'Subscription tiers and the limits enforced for editors on them.';
+COMMENT ON COLUMN plans.max_subscribers IS 'Active subscribers allowed across all newsletters of an editor; NULL is unlimited.';
+COMMENT ON COLUMN plans.monthly_emails IS 'Post emails an editor may send per calendar month (UTC); NULL is unlimited.';
+COMMENT ON COLUMN plans.custom_domain IS 'Whether newsletters may use a custom sending and hosting domain.';
+
+INSERT INTO plans (id, name, max_subscribers, monthly_emails, custom_domain) VALUES
+    ('free', 'Free', 500, 2500, FALSE),
+    ('pro', 'Pro', 25000, 250000, TRUE)
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE profiles
+    ADD COLUMN IF NOT EXISTS plan_id TEXT NOT NULL DEFAULT 'free' REFERENCES plans(id);
+
+COMMENT ON COLUMN profiles.plan_id IS 'Plan whose limits apply to the editor.';
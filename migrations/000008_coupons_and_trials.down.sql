@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_plan_grants_editor_ends_at;
+DROP TABLE IF EXISTS plan_grants;
+DROP TABLE IF EXISTS coupons;
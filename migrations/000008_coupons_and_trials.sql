@@ -0,0 +1,38 @@
+-- Admin-issued coupons that grant a plan for a limited time
+CREATE TABLE IF NOT EXISTS coupons (
+    code TEXT PRIMARY KEY,
+    plan_id TEXT NOT NULL REFERENCES plans(id),
+    duration_days INTEGER NOT NULL CHECK (duration_days > 0),
+    max_redemptions INTEGER CHECK (max_redemptions > 0),
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    created_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE coupons IS 'Codes editors redeem for a time-limited plan.';
+COMMENT ON COLUMN coupons.max_redemptions IS 'Total redemptions allowed across all editors; NULL is unlimited.';
+COMMENT ON COLUMN coupons.expires_at IS 'The coupon cannot be redeemed after this time; NULL never expires.';
+
+-- Time-limited plans per editor, from trials and coupon redemptions
+CREATE TABLE IF NOT EXISTS plan_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    editor_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    plan_id TEXT NOT NULL REFERENCES plans(id),
+    source TEXT NOT NULL CHECK (source = ANY (ARRAY['trial'::text, 'coupon'::text])),
+    coupon_code TEXT REFERENCES coupons(code),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    created_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at),
+    CONSTRAINT unique_coupon_redemption UNIQUE (editor_id, coupon_code)
+);
+
+COMMENT ON TABLE plan_grants IS 'Trials and coupon redemptions; while active the granted plan overrides a smaller assigned plan.';
+COMMENT ON COLUMN plan_grants.created_by IS 'Admin who started or last extended a trial; NULL for coupon redemptions.';
+
+-- Plan resolution: an editor's grants that have not ended yet
+CREATE INDEX IF NOT EXISTS idx_plan_grants_editor_ends_at
+    ON plan_grants (editor_id, ends_at);
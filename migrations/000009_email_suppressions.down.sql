@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscribers_email_lower;
+DROP TABLE IF EXISTS email_suppressions;
@@ -0,0 +1,14 @@
+-- Addresses that opted out of every newsletter on the platform
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    email TEXT PRIMARY KEY CHECK (email = lower(email)),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE email_suppressions IS 'Addresses that unsubscribed from all newsletters; posts are never sent to them.';
+COMMENT ON COLUMN email_suppressions.email IS 'Lower-cased email address.';
+COMMENT ON COLUMN email_suppressions.reason IS 'How the address was suppressed, e.g. unsubscribe_all.';
+
+-- Unsubscribe-all matches the address case-insensitively across newsletters
+CREATE INDEX IF NOT EXISTS idx_subscribers_email_lower
+    ON subscribers (lower(email));
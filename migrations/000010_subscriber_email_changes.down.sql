@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscriber_email_changes_subscriber;
+DROP TABLE IF EXISTS subscriber_email_changes;
@@ -0,0 +1,19 @@
+-- Address changes requested by subscribers; a change applies once the new address is verified
+CREATE TABLE IF NOT EXISTS subscriber_email_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscriber_id UUID NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    old_email TEXT NOT NULL,
+    new_email TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE subscriber_email_changes IS 'History of subscriber address changes, pending until the new address is verified.';
+COMMENT ON COLUMN subscriber_email_changes.token IS 'Secret sent to the new address to verify the change.';
+COMMENT ON COLUMN subscriber_email_changes.confirmed_at IS 'When the change was applied; NULL while pending.';
+
+-- History of a subscription, newest first
+CREATE INDEX IF NOT EXISTS idx_subscriber_email_changes_subscriber
+    ON subscriber_email_changes (subscriber_id, requested_at DESC);
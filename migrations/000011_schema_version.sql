@@ -0,0 +1,18 @@
+-- Schema version checked at startup (see internal/database/schema.go). Every migration ends by
+-- recording its number here. Additive migrations keep min_compatible_version; migrations that
+-- drop, rename or tighten something older builds use raise it to their own number.
+CREATE TABLE IF NOT EXISTS schema_version (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version INTEGER NOT NULL,
+    min_compatible_version INTEGER NOT NULL CHECK (min_compatible_version <= version),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE schema_version IS 'Single row holding the last applied migration.';
+COMMENT ON COLUMN schema_version.version IS 'Number of the last applied migration.';
+COMMENT ON COLUMN schema_version.min_compatible_version IS 'Oldest build schema version that can still run against this schema.';
+
+-- Only adds this table, so builds expecting 000010 keep working
+INSERT INTO schema_version (version, min_compatible_version) VALUES (11, 10)
+ON CONFLICT (id) DO UPDATE
+    SET version = EXCLUDED.version, min_compatible_version = EXCLUDED.min_compatible_version, updated_at = now();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS runtime_flags;
+
+UPDATE schema_version SET version = 11, updated_at = now();
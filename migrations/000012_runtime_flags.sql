@@ -0,0 +1,13 @@
+-- Operational switches shared by all replicas, e.g. read-only mode during an incident
+CREATE TABLE IF NOT EXISTS runtime_flags (
+    name TEXT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT,
+    updated_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE runtime_flags IS 'Switches admins flip at runtime; replicas poll them.';
+COMMENT ON COLUMN runtime_flags.reason IS 'Why the flag was last changed, shown to admins.';
+
+UPDATE schema_version SET version = 12, updated_at = now();
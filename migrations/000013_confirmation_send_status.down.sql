@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_subscribers_confirmation_retry_at;
+ALTER TABLE subscribers
+    DROP COLUMN IF EXISTS confirmation_retry_at,
+    DROP COLUMN IF EXISTS confirmation_sent_at,
+    DROP COLUMN IF EXISTS confirmation_last_error,
+    DROP COLUMN IF EXISTS confirmation_attempts,
+    DROP COLUMN IF EXISTS confirmation_status;
+
+UPDATE schema_version SET version = 12, updated_at = now();
@@ -0,0 +1,18 @@
+-- Outcome of the confirmation email on the subscriber row, so failed sends can be retried
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS confirmation_status TEXT CHECK (confirmation_status = ANY (ARRAY['sent'::text, 'failed'::text])),
+    ADD COLUMN IF NOT EXISTS confirmation_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS confirmation_last_error TEXT,
+    ADD COLUMN IF NOT EXISTS confirmation_sent_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS confirmation_retry_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN subscribers.confirmation_status IS 'Outcome of the last confirmation email; NULL for subscribers from before it was tracked.';
+COMMENT ON COLUMN subscribers.confirmation_sent_at IS 'When the last confirmation email was attempted.';
+COMMENT ON COLUMN subscribers.confirmation_retry_at IS 'When the failed confirmation email is retried next; NULL when no retry is due.';
+
+-- Retry job: failed confirmations that are due
+CREATE INDEX IF NOT EXISTS idx_subscribers_confirmation_retry_at
+    ON subscribers (confirmation_retry_at)
+    WHERE confirmation_retry_at IS NOT NULL;
+
+UPDATE schema_version SET version = 13, updated_at = now();
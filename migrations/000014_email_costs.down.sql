@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_email_costs_newsletter_sent_at;
+DROP INDEX IF EXISTS idx_email_costs_editor_sent_at;
+DROP TABLE IF EXISTS email_costs;
+
+UPDATE schema_version SET version = 13, updated_at = now();
@@ -0,0 +1,25 @@
+-- Estimated cost of every send operation, priced with the provider pricing configured at the time
+CREATE TABLE IF NOT EXISTS email_costs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    editor_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    newsletter_id UUID REFERENCES newsletters(id) ON DELETE SET NULL,
+    post_id UUID REFERENCES published_posts(id) ON DELETE SET NULL,
+    kind TEXT NOT NULL CHECK (kind = ANY (ARRAY['post'::text, 'confirmation'::text])),
+    provider TEXT NOT NULL,
+    emails INTEGER NOT NULL CHECK (emails > 0),
+    unit_cost_micros BIGINT NOT NULL CHECK (unit_cost_micros >= 0),
+    cost_micros BIGINT NOT NULL CHECK (cost_micros >= 0),
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE email_costs IS 'Estimated email sending costs per send operation, kept after the newsletter is deleted for billing.';
+COMMENT ON COLUMN email_costs.emails IS 'Emails accepted by the provider in the send operation.';
+COMMENT ON COLUMN email_costs.unit_cost_micros IS 'Price of one email in millionths of the configured currency when it was sent.';
+
+-- Monthly summaries per editor and per newsletter
+CREATE INDEX IF NOT EXISTS idx_email_costs_editor_sent_at
+    ON email_costs (editor_id, sent_at);
+CREATE INDEX IF NOT EXISTS idx_email_costs_newsletter_sent_at
+    ON email_costs (newsletter_id, sent_at);
+
+UPDATE schema_version SET version = 14, updated_at = now();
@@ -0,0 +1,9 @@
+-- Addresses encrypted since this migration stay unreadable to earlier builds
+DROP INDEX IF EXISTS idx_subscribers_email_hash;
+DROP INDEX IF EXISTS unique_newsletter_subscriber_email_hash;
+ALTER TABLE subscriber_email_changes
+    DROP COLUMN IF EXISTS new_email_hash;
+ALTER TABLE subscribers
+    DROP COLUMN IF EXISTS email_hash;
+
+UPDATE schema_version SET version = 14, updated_at = now();
@@ -0,0 +1,24 @@
+-- Blind indexes for subscriber addresses encrypted by the application (SUBSCRIBER_EMAIL_KEY),
+-- so lookups and uniqueness keep working without decrypting
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS email_hash BYTEA;
+
+ALTER TABLE subscriber_email_changes
+    ADD COLUMN IF NOT EXISTS new_email_hash BYTEA;
+
+COMMENT ON COLUMN subscribers.email IS 'Email address of the subscriber; encrypted (enc:v1: prefix) when subscriber email encryption is enabled.';
+COMMENT ON COLUMN subscribers.email_hash IS 'HMAC-SHA256 blind index of the lower-cased address; NULL for plaintext addresses.';
+COMMENT ON COLUMN subscriber_email_changes.new_email_hash IS 'Blind index of new_email; NULL for plaintext addresses.';
+COMMENT ON COLUMN email_suppressions.email IS 'Lower-cased email address, or idx: followed by its hex blind index when encryption is enabled.';
+
+-- Encrypted addresses use a random nonce, so uniqueness per newsletter is enforced on the index
+CREATE UNIQUE INDEX IF NOT EXISTS unique_newsletter_subscriber_email_hash
+    ON subscribers (newsletter_id, email_hash)
+    WHERE email_hash IS NOT NULL;
+
+-- Unsubscribe-all and suppression lookups across newsletters
+CREATE INDEX IF NOT EXISTS idx_subscribers_email_hash
+    ON subscribers (email_hash)
+    WHERE email_hash IS NOT NULL;
+
+UPDATE schema_version SET version = 15, updated_at = now();
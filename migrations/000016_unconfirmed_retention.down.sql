@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_subscribers_unconfirmed_subscribed_at;
+DROP INDEX IF EXISTS idx_audit_log_newsletter_created_at;
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE newsletters
+    DROP COLUMN IF EXISTS unconfirmed_retention_days;
+
+UPDATE schema_version SET version = 15, updated_at = now();
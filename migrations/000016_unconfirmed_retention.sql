@@ -0,0 +1,33 @@
+-- Per-newsletter override of how long unconfirmed subscribers are kept (UNCONFIRMED_RETENTION_DAYS)
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS unconfirmed_retention_days INTEGER
+        CHECK (unconfirmed_retention_days IS NULL OR unconfirmed_retention_days > 0);
+
+COMMENT ON COLUMN newsletters.unconfirmed_retention_days IS 'Days after which subscribers that never confirmed are deleted; NULL uses the platform default.';
+
+-- Record of automated and administrative actions on personal data
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action TEXT NOT NULL,
+    actor_id UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    newsletter_id UUID REFERENCES newsletters(id) ON DELETE SET NULL,
+    target_id UUID,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE audit_log IS 'Audit trail of actions on personal data; entries never contain email addresses.';
+COMMENT ON COLUMN audit_log.action IS 'What happened, e.g. subscriber.retention_deleted.';
+COMMENT ON COLUMN audit_log.actor_id IS 'Profile that performed the action; NULL for background jobs.';
+COMMENT ON COLUMN audit_log.target_id IS 'ID of the affected record, which may no longer exist.';
+
+-- Audit trail of a newsletter, newest first
+CREATE INDEX IF NOT EXISTS idx_audit_log_newsletter_created_at
+    ON audit_log (newsletter_id, created_at DESC);
+
+-- Retention job: unconfirmed subscribers by age
+CREATE INDEX IF NOT EXISTS idx_subscribers_unconfirmed_subscribed_at
+    ON subscribers (subscribed_at)
+    WHERE NOT is_confirmed;
+
+UPDATE schema_version SET version = 16, updated_at = now();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_backups_started_at;
+DROP TABLE IF EXISTS backups;
+
+UPDATE schema_version SET version = 16, updated_at = now();
@@ -0,0 +1,24 @@
+-- Snapshots of the critical tables written to object storage (BACKUP_URL)
+CREATE TABLE IF NOT EXISTS backups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    object_key TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'succeeded', 'failed')),
+    schema_version INTEGER NOT NULL,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE backups IS 'History of table snapshots; also keeps several instances from taking the same scheduled snapshot.';
+COMMENT ON COLUMN backups.object_key IS 'Key of the snapshot below BACKUP_URL, passed to newsletterctl restore.';
+COMMENT ON COLUMN backups.schema_version IS 'Migration the snapshot was taken at; a restore needs the same version.';
+COMMENT ON COLUMN backups.row_count IS 'Rows written to the snapshot.';
+COMMENT ON COLUMN backups.size_bytes IS 'Compressed size of the snapshot.';
+
+-- Latest backups first
+CREATE INDEX IF NOT EXISTS idx_backups_started_at
+    ON backups (started_at DESC);
+
+UPDATE schema_version SET version = 17, updated_at = now();
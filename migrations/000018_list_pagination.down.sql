@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscribers_newsletter_subscribed_at_id;
+
+UPDATE schema_version SET version = 17, updated_at = now();
@@ -0,0 +1,5 @@
+-- Subscriber listings page through a newsletter's subscribers by (subscribed_at, id), newest first
+CREATE INDEX IF NOT EXISTS idx_subscribers_newsletter_subscribed_at_id
+    ON subscribers (newsletter_id, subscribed_at DESC, id DESC);
+
+UPDATE schema_version SET version = 18, updated_at = now();
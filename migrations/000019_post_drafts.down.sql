@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_published_posts_newsletter_drafts;
+
+COMMENT ON COLUMN published_posts.status IS 'Status of the post (e.g., ''draft'', ''scheduled'', ''publishing'', ''published'', ''failed'').';
+
+UPDATE schema_version SET version = 18, updated_at = now();
@@ -0,0 +1,8 @@
+-- Draft listings page through a newsletter's drafts by (created_at, id), newest first
+CREATE INDEX IF NOT EXISTS idx_published_posts_newsletter_drafts
+    ON published_posts (newsletter_id, created_at DESC, id DESC)
+    WHERE status = 'DRAFT';
+
+COMMENT ON COLUMN published_posts.status IS 'Status of the post: DRAFT (work in progress, never sent), SCHEDULED, POSTED or SKIPPED.';
+
+UPDATE schema_version SET version = 19, updated_at = now();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_outbox;
+
+UPDATE schema_version SET version = 19, updated_at = now();
@@ -0,0 +1,38 @@
+-- Post emails waiting to be sent. Publishing a post writes its emails here in the same
+-- transaction that marks it published, and the outbox dispatcher sends them, so emails of a
+-- published post survive a crash and are sent at least once.
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES published_posts(id) ON DELETE CASCADE,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    html TEXT NOT NULL,
+    correlation_id TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status = ANY (ARRAY['pending'::text, 'sent'::text, 'failed'::text])),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE email_outbox IS 'Queue of post emails; pending rows are sent by the outbox dispatcher, finished rows are purged after MAIL_OUTBOX_RETENTION.';
+COMMENT ON COLUMN email_outbox.html IS 'Rendered email body, including the recipient''s unsubscribe token.';
+COMMENT ON COLUMN email_outbox.available_at IS 'When a pending email may be claimed next: now for new emails, the end of the lease while a dispatcher sends it, the backoff after a failed attempt.';
+
+-- Dispatchers claim due pending emails, oldest first
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending_available_at
+    ON email_outbox (available_at)
+    WHERE status = 'pending';
+
+-- Delivery stats count the queued emails of a post
+CREATE INDEX IF NOT EXISTS idx_email_outbox_post_id
+    ON email_outbox (post_id);
+
+-- Purging finished emails after the retention period
+CREATE INDEX IF NOT EXISTS idx_email_outbox_finished_at
+    ON email_outbox (finished_at)
+    WHERE status <> 'pending';
+
+UPDATE schema_version SET version = 20, updated_at = now();
@@ -0,0 +1,10 @@
+DELETE FROM email_outbox WHERE post_id IS NULL;
+
+ALTER TABLE email_outbox
+    DROP CONSTRAINT IF EXISTS email_outbox_source_check,
+    DROP COLUMN IF EXISTS notification_id,
+    ALTER COLUMN post_id SET NOT NULL;
+
+DROP TABLE IF EXISTS subscriber_notifications;
+
+UPDATE schema_version SET version = 20, updated_at = now();
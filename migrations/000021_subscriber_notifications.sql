@@ -0,0 +1,27 @@
+-- Messages admins send to all subscribers of a newsletter in exceptional cases, e.g. a
+-- service migration. Their emails go through the email outbox like post emails.
+CREATE TABLE IF NOT EXISTS subscriber_notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    admin_id UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    subject TEXT NOT NULL,
+    content_html TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    recipient_count INTEGER NOT NULL DEFAULT 0,
+    scheduled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE subscriber_notifications IS 'Admin-authored messages sent to all subscribers of a newsletter; each one is also recorded in audit_log.';
+COMMENT ON COLUMN subscriber_notifications.reason IS 'Why the subscribers were contacted, shown to the editor and kept for the audit trail.';
+
+CREATE INDEX IF NOT EXISTS idx_subscriber_notifications_newsletter_created_at
+    ON subscriber_notifications (newsletter_id, created_at DESC);
+
+-- Outbox emails belong to either a post or a notification
+ALTER TABLE email_outbox
+    ALTER COLUMN post_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS notification_id UUID REFERENCES subscriber_notifications(id) ON DELETE CASCADE,
+    ADD CONSTRAINT email_outbox_source_check CHECK (num_nonnulls(post_id, notification_id) = 1);
+
+UPDATE schema_version SET version = 21, updated_at = now();
@@ -0,0 +1,7 @@
+ALTER TABLE email_jobs
+    DROP COLUMN IF EXISTS list_unsubscribe;
+
+ALTER TABLE email_outbox
+    DROP COLUMN IF EXISTS list_unsubscribe;
+
+UPDATE schema_version SET version = 21, updated_at = now();
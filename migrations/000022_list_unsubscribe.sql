@@ -0,0 +1,11 @@
+-- List-Unsubscribe header of queued and failed emails, so retries carry it like the first send
+ALTER TABLE email_outbox
+    ADD COLUMN IF NOT EXISTS list_unsubscribe TEXT;
+
+ALTER TABLE email_jobs
+    ADD COLUMN IF NOT EXISTS list_unsubscribe TEXT;
+
+COMMENT ON COLUMN email_outbox.list_unsubscribe IS 'One-click unsubscribe URL of the recipient for the List-Unsubscribe header; contains their unsubscribe token.';
+COMMENT ON COLUMN email_jobs.list_unsubscribe IS 'One-click unsubscribe URL of the recipient for the List-Unsubscribe header; contains their unsubscribe token.';
+
+UPDATE schema_version SET version = 22, updated_at = now();
@@ -0,0 +1,6 @@
+DELETE FROM subscribers WHERE is_sample;
+
+ALTER TABLE subscribers
+    DROP COLUMN IF EXISTS is_sample;
+
+UPDATE schema_version SET version = 22, updated_at = now();
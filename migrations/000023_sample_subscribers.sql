@@ -0,0 +1,8 @@
+-- Demo subscribers added with a newsletter's sample content. They appear in subscriber lists
+-- but are never emailed nor counted against plan limits.
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS is_sample BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN subscribers.is_sample IS 'Demo subscriber created by POST /newsletters/{id}/sample-content; never emailed and not counted.';
+
+UPDATE schema_version SET version = 23, updated_at = now();
@@ -0,0 +1,4 @@
+ALTER TABLE published_posts
+    DROP COLUMN IF EXISTS summary;
+
+UPDATE schema_version SET version = 23, updated_at = now();
@@ -0,0 +1,7 @@
+-- Short excerpt of a post for previews and listings, generated by the application
+ALTER TABLE published_posts
+    ADD COLUMN IF NOT EXISTS summary TEXT;
+
+COMMENT ON COLUMN published_posts.summary IS 'Plain text excerpt generated when the post is scheduled or edited (extractive or by a language model, SUMMARY_MODE); NULL for drafts.';
+
+UPDATE schema_version SET version = 24, updated_at = now();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_keys;
+
+UPDATE schema_version SET version = 24, updated_at = now();
@@ -0,0 +1,23 @@
+-- Editor API keys for programmatic access (X-API-Key header). Only a SHA-256 hash of each
+-- key is stored; the key is shown once when it is created.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    editor_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    CONSTRAINT unique_api_key_hash UNIQUE (key_hash)
+);
+
+COMMENT ON TABLE api_keys IS 'API keys editors authenticate with instead of a Supabase JWT, e.g. from CI pipelines.';
+COMMENT ON COLUMN api_keys.prefix IS 'Start of the key shown in listings so editors can tell keys apart.';
+COMMENT ON COLUMN api_keys.key_hash IS 'Hex SHA-256 of the key; keys are random, so an unsalted hash is enough.';
+COMMENT ON COLUMN api_keys.last_used_at IS 'Last request made with the key, updated at most once a minute.';
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_editor_created_at
+    ON api_keys (editor_id, created_at DESC);
+
+UPDATE schema_version SET version = 25, updated_at = now();
@@ -0,0 +1,51 @@
+-- Webhooks notify an editor's own services of newsletter events. Events are written to
+-- webhook_deliveries, one row per subscribed webhook, and the webhook dispatcher posts them
+-- with an HMAC signature, retrying failed deliveries with exponential backoff.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE webhooks IS 'Callback URLs of a newsletter and the events they receive.';
+COMMENT ON COLUMN webhooks.secret IS 'Key of the HMAC-SHA256 signature sent with every delivery; shown to the editor once.';
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_newsletter_id
+    ON webhooks (newsletter_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status = ANY (ARRAY['pending'::text, 'delivered'::text, 'failed'::text])),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE webhook_deliveries IS 'Queue and log of webhook calls; finished rows are purged after WEBHOOK_RETENTION.';
+COMMENT ON COLUMN webhook_deliveries.response_status IS 'HTTP status of the last attempt, NULL when no response was received.';
+COMMENT ON COLUMN webhook_deliveries.available_at IS 'When a pending delivery may be claimed next, as in email_outbox.';
+
+-- Dispatchers claim due pending deliveries, oldest first
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending_available_at
+    ON webhook_deliveries (available_at)
+    WHERE status = 'pending';
+
+-- The delivery log of a webhook, newest first
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_created_at
+    ON webhook_deliveries (webhook_id, created_at DESC);
+
+-- Purging finished deliveries after the retention period
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_finished_at
+    ON webhook_deliveries (finished_at)
+    WHERE status <> 'pending';
+
+UPDATE schema_version SET version = 26, updated_at = now();
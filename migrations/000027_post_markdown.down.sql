@@ -0,0 +1,3 @@
+ALTER TABLE published_posts DROP COLUMN IF EXISTS content_markdown;
+
+UPDATE schema_version SET version = 26, updated_at = now();
@@ -0,0 +1,8 @@
+-- Posts can be written in Markdown, which is rendered to content_html and content_text when the
+-- post is saved; the source is kept so editors can edit it again
+ALTER TABLE published_posts
+    ADD COLUMN IF NOT EXISTS content_markdown TEXT;
+
+COMMENT ON COLUMN published_posts.content_markdown IS 'Markdown source of content_html and content_text; NULL for posts written in HTML.';
+
+UPDATE schema_version SET version = 27, updated_at = now();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_templates;
+
+UPDATE schema_version SET version = 27, updated_at = now();
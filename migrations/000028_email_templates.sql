@@ -0,0 +1,13 @@
+-- Newsletters can replace the built-in email templates with their own, one per kind
+CREATE TABLE IF NOT EXISTS email_templates (
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('post', 'confirmation')),
+    html TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (newsletter_id, kind)
+);
+
+COMMENT ON TABLE email_templates IS 'Email templates of newsletters in html/template syntax; kinds without a row use the built-in template.';
+
+UPDATE schema_version SET version = 28, updated_at = now();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS inbox_notifications;
+
+UPDATE schema_version SET version = 28, updated_at = now();
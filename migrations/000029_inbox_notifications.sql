@@ -0,0 +1,26 @@
+-- In-app notifications of editors, shown in the dashboard next to the emails they already get
+CREATE TABLE IF NOT EXISTS inbox_notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    editor_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    newsletter_id UUID REFERENCES newsletters(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES published_posts(id) ON DELETE SET NULL,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE inbox_notifications IS 'Notifications listed under /me/notifications; deleted after RETENTION_NOTIFICATION_DAYS.';
+COMMENT ON COLUMN inbox_notifications.kind IS 'What happened: post_published, delivery_incident or admin_notification.';
+
+CREATE INDEX IF NOT EXISTS idx_inbox_notifications_editor_created_at
+    ON inbox_notifications (editor_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_notifications_unread
+    ON inbox_notifications (editor_id) WHERE read_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_inbox_notifications_created_at
+    ON inbox_notifications (created_at);
+
+UPDATE schema_version SET version = 29, updated_at = now();
@@ -0,0 +1,3 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS public_badge;
+
+UPDATE schema_version SET version = 29, updated_at = now();
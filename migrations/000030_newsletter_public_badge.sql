@@ -0,0 +1,7 @@
+-- Editors can opt in to a public badge showing the subscriber count of their newsletter
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS public_badge BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN newsletters.public_badge IS 'Whether /public/newsletters/{id}/badge.svg shows the subscriber count; off unless the editor enables it.';
+
+UPDATE schema_version SET version = 30, updated_at = now();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_send_attempts;
+
+UPDATE schema_version SET version = 30, updated_at = now();
@@ -0,0 +1,21 @@
+-- Every dispatch of emails of a post, so editors can see in the delivery report whether and
+-- when the emails of a post went out and which recipients failed
+CREATE TABLE IF NOT EXISTS post_send_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES published_posts(id) ON DELETE CASCADE,
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    recipients INTEGER NOT NULL,
+    sent INTEGER NOT NULL,
+    failed INTEGER NOT NULL,
+    failures JSONB NOT NULL DEFAULT '[]'::jsonb,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE post_send_attempts IS 'One row per dispatch of emails of a post; listed by /newsletters/{id}/posts/{postId}/delivery-report.';
+COMMENT ON COLUMN post_send_attempts.recipients IS 'Emails whose outcome the dispatch settled; emails retried later are counted by the dispatch that settles them.';
+COMMENT ON COLUMN post_send_attempts.failures IS 'Recipients that failed for good with the provider error, at most 100 per dispatch.';
+
+CREATE INDEX IF NOT EXISTS idx_post_send_attempts_post_sent_at
+    ON post_send_attempts (post_id, sent_at DESC, id DESC);
+
+UPDATE schema_version SET version = 31, updated_at = now();
@@ -0,0 +1,5 @@
+ALTER TABLE subscribers DROP COLUMN IF EXISTS bounced_at;
+
+COMMENT ON COLUMN email_suppressions.reason IS 'How the address was suppressed, e.g. unsubscribe_all.';
+
+UPDATE schema_version SET version = 31, updated_at = now();
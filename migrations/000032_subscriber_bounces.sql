@@ -0,0 +1,8 @@
+-- Bounces and spam complaints reported by Resend webhooks
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS bounced_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN subscribers.bounced_at IS 'First hard bounce Resend reported for the address; the address is suppressed, so posts are no longer sent to it.';
+COMMENT ON COLUMN email_suppressions.reason IS 'How the address was suppressed: unsubscribe_all, bounce or complaint.';
+
+UPDATE schema_version SET version = 32, updated_at = now();
@@ -0,0 +1,3 @@
+ALTER TABLE api_keys DROP COLUMN IF EXISTS scopes;
+
+UPDATE schema_version SET version = 32, updated_at = now();
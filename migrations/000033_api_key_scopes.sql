@@ -0,0 +1,7 @@
+-- Scopes limiting what an API key can do
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS scopes TEXT[];
+
+COMMENT ON COLUMN api_keys.scopes IS 'Scopes the key is limited to, e.g. posts:write; NULL grants everything the editor can do.';
+
+UPDATE schema_version SET version = 33, updated_at = now();
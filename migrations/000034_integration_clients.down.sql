@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS oauth_access_tokens;
+DROP TABLE IF EXISTS integration_clients;
+
+UPDATE schema_version SET version = 33, updated_at = now();
@@ -0,0 +1,37 @@
+-- Integration clients of editors, which get short-lived access tokens through the OAuth2
+-- client credentials flow (POST /oauth/token). Only SHA-256 hashes of client secrets and
+-- tokens are stored.
+CREATE TABLE IF NOT EXISTS integration_clients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    editor_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ,
+    CONSTRAINT unique_integration_client_id UNIQUE (client_id)
+);
+
+COMMENT ON TABLE integration_clients IS 'OAuth2 clients editors register for third-party integrations.';
+COMMENT ON COLUMN integration_clients.client_id IS 'Public identifier the client authenticates with, together with its secret.';
+COMMENT ON COLUMN integration_clients.scopes IS 'Scopes tokens of the client can be issued for, e.g. posts:write.';
+
+CREATE INDEX IF NOT EXISTS idx_integration_clients_editor_created_at
+    ON integration_clients (editor_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS oauth_access_tokens (
+    token_hash TEXT PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES integration_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE oauth_access_tokens IS 'Access tokens issued to integration clients; expired ones are deleted by the retention job.';
+COMMENT ON COLUMN oauth_access_tokens.scopes IS 'Scopes the token was issued for, a subset of the client''s scopes.';
+
+CREATE INDEX IF NOT EXISTS idx_oauth_access_tokens_expires_at
+    ON oauth_access_tokens (expires_at);
+
+UPDATE schema_version SET version = 34, updated_at = now();
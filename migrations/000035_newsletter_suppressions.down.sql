@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS newsletter_suppressions;
+
+UPDATE schema_version SET version = 34, updated_at = now();
@@ -0,0 +1,27 @@
+-- Suppression list of each newsletter: addresses its posts and confirmation emails are not sent
+-- to. Editors block addresses manually; hard bounces and spam complaints are also listed for
+-- every newsletter the address subscribes to, next to the platform-wide email_suppressions.
+CREATE TABLE IF NOT EXISTS newsletter_suppressions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    email_key TEXT NOT NULL,
+    email TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT unique_newsletter_suppression UNIQUE (newsletter_id, email_key)
+);
+
+COMMENT ON TABLE newsletter_suppressions IS 'Addresses the posts of a newsletter are never sent to.';
+COMMENT ON COLUMN newsletter_suppressions.email_key IS 'Lower-cased address, or idx: followed by its hex blind index when encryption is enabled, like email_suppressions.email.';
+COMMENT ON COLUMN newsletter_suppressions.email IS 'Address shown to the editor; encrypted (enc:v1: prefix) when subscriber email encryption is enabled.';
+COMMENT ON COLUMN newsletter_suppressions.reason IS 'manual (blocked by the editor), bounce or complaint.';
+
+CREATE INDEX IF NOT EXISTS idx_newsletter_suppressions_newsletter_created_at
+    ON newsletter_suppressions (newsletter_id, created_at DESC, id DESC);
+
+-- Lifting a platform-wide suppression also lifts the bounces and complaints listed per newsletter
+CREATE INDEX IF NOT EXISTS idx_newsletter_suppressions_email_key
+    ON newsletter_suppressions (email_key);
+
+UPDATE schema_version SET version = 35, updated_at = now();
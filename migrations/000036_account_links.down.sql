@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_link_requests;
+
+UPDATE schema_version SET version = 35, updated_at = now();
@@ -0,0 +1,26 @@
+-- Pending links of a first sign-in with an identity provider to the password account of the
+-- same email. Supabase created a second user for the sign-in; once the editor proves they own
+-- the password account, that user is deleted and the provider linked to the account instead.
+-- Only SHA-256 hashes of link tokens are stored.
+CREATE TABLE IF NOT EXISTS account_link_requests (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    full_name TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE account_link_requests IS 'Sign-ins with an identity provider waiting to be linked to the password account of the same email.';
+COMMENT ON COLUMN account_link_requests.user_id IS 'Supabase user the identity provider sign-in created, deleted when the link completes.';
+COMMENT ON COLUMN account_link_requests.account_id IS 'Existing password account the provider is linked to.';
+COMMENT ON COLUMN account_link_requests.full_name IS 'Name from the provider, merged into the account''s profile when it has none.';
+COMMENT ON COLUMN account_link_requests.avatar_url IS 'Avatar from the provider, merged into the account''s profile when it has none.';
+
+CREATE INDEX IF NOT EXISTS idx_account_link_requests_user_id
+    ON account_link_requests (user_id);
+
+UPDATE schema_version SET version = 36, updated_at = now();
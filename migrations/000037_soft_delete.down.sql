@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_published_posts_deleted_at;
+DROP INDEX IF EXISTS idx_newsletters_deleted_at;
+
+ALTER TABLE published_posts DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE newsletters DROP COLUMN IF EXISTS deleted_at;
+
+UPDATE schema_version SET version = 36, updated_at = now();
@@ -0,0 +1,22 @@
+-- Deleting a newsletter or post only marks it deleted. Admins can restore deleted newsletters
+-- until the retention job deletes them, with their subscribers and posts, for good after
+-- RETENTION_DELETED_DAYS.
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+ALTER TABLE published_posts
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN newsletters.deleted_at IS 'When the newsletter was deleted; NULL while it is not.';
+COMMENT ON COLUMN published_posts.deleted_at IS 'When the post was deleted; NULL while it is not.';
+
+-- Listing deleted newsletters for restore and purging them and deleted posts
+CREATE INDEX IF NOT EXISTS idx_newsletters_deleted_at
+    ON newsletters (deleted_at DESC, id DESC)
+    WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_published_posts_deleted_at
+    ON published_posts (deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+UPDATE schema_version SET version = 37, updated_at = now();
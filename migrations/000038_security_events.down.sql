@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_audit_log_security_events;
+
+UPDATE schema_version SET version = 37, updated_at = now();
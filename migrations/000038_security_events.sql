@@ -0,0 +1,7 @@
+-- Sign-ins and other security events of editors are recorded in the audit log as auth.* actions,
+-- with the client's IP address, user agent and approximate location in details
+CREATE INDEX IF NOT EXISTS idx_audit_log_security_events
+    ON audit_log (actor_id, created_at DESC, id DESC)
+    WHERE action LIKE 'auth.%';
+
+UPDATE schema_version SET version = 38, updated_at = now();
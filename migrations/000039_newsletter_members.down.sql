@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS newsletter_invitations;
+
+DROP INDEX IF EXISTS idx_newsletter_members_editor_id;
+ALTER TABLE newsletter_members
+    DROP CONSTRAINT IF EXISTS unique_newsletter_member,
+    ALTER COLUMN role DROP NOT NULL,
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN updated_at DROP NOT NULL;
+ALTER INDEX newsletter_members_pkey RENAME TO newsletter_editors_pkey;
+ALTER TABLE newsletter_members RENAME TO newsletter_editors;
+
+UPDATE schema_version SET version = 38, updated_at = now();
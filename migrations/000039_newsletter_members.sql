@@ -0,0 +1,44 @@
+-- Editors share newsletters as members with a role. The newsletter_editors table of the initial
+-- schema was never used, so it becomes newsletter_members; every newsletter gets its editor as
+-- owner, who stays the one newsletters.editor_id names.
+ALTER TABLE newsletter_editors RENAME TO newsletter_members;
+ALTER INDEX newsletter_editors_pkey RENAME TO newsletter_members_pkey;
+
+ALTER TABLE newsletter_members
+    ALTER COLUMN role SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL,
+    ADD CONSTRAINT unique_newsletter_member UNIQUE (newsletter_id, editor_id);
+
+COMMENT ON TABLE newsletter_members IS 'Editors with access to a newsletter and their role; the newsletter''s editor is its owner.';
+COMMENT ON COLUMN newsletter_members.editor_id IS 'References the user/editor with access to the newsletter';
+
+INSERT INTO newsletter_members (newsletter_id, editor_id, role)
+SELECT id, editor_id, 'owner' FROM newsletters
+ON CONFLICT (newsletter_id, editor_id) DO UPDATE SET role = 'owner', updated_at = now();
+
+CREATE INDEX IF NOT EXISTS idx_newsletter_members_editor_id
+    ON newsletter_members (editor_id);
+
+-- Invitations to join a newsletter, accepted by the editor signed in with the invited email.
+-- Only SHA-256 hashes of invitation tokens are stored.
+CREATE TABLE IF NOT EXISTS newsletter_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE newsletter_invitations IS 'Invitations to join a newsletter as editor or viewer.';
+COMMENT ON COLUMN newsletter_invitations.accepted_at IS 'When the invited editor joined; NULL while pending.';
+
+-- One pending invitation per address and newsletter; inviting again replaces it
+CREATE UNIQUE INDEX IF NOT EXISTS unique_pending_newsletter_invitation
+    ON newsletter_invitations (newsletter_id, lower(email)) WHERE accepted_at IS NULL;
+
+UPDATE schema_version SET version = 39, updated_at = now();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscribers_deleted_at;
+
+ALTER TABLE subscribers DROP COLUMN IF EXISTS deleted_at;
+
+UPDATE schema_version SET version = 39, updated_at = now();
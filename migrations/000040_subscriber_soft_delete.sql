@@ -0,0 +1,14 @@
+-- Deleting a subscriber only marks it deleted. Deleted subscribers get no posts and are left out
+-- of lists and exports; editors can restore them until the retention job deletes them for good
+-- after RETENTION_DELETED_DAYS.
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN subscribers.deleted_at IS 'When the editor deleted the subscriber; NULL while they did not.';
+
+-- Listing a newsletter's deleted subscribers for restore and purging them
+CREATE INDEX IF NOT EXISTS idx_subscribers_deleted_at
+    ON subscribers (newsletter_id, deleted_at DESC, id DESC)
+    WHERE deleted_at IS NOT NULL;
+
+UPDATE schema_version SET version = 40, updated_at = now();
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_message_events;
+DROP TABLE IF EXISTS email_messages;
+
+UPDATE schema_version SET version = 40, updated_at = now();
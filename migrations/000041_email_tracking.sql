@@ -0,0 +1,29 @@
+-- Opens and clicks of post emails. Every tracked email gets a message ID, which its open pixel
+-- and rewritten links carry; events of a message are counted into the stats of its post.
+-- Neither table names the recipient.
+CREATE TABLE IF NOT EXISTS email_messages (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL REFERENCES published_posts(id) ON DELETE CASCADE,
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE email_messages IS 'One row per tracked post email, created when the email is rendered.';
+
+CREATE TABLE IF NOT EXISTS email_message_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES email_messages(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES published_posts(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind = ANY (ARRAY['open'::text, 'click'::text])),
+    url TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE email_message_events IS 'Opens (pixel loads) and link clicks of tracked post emails; listed by /newsletters/{id}/posts/{postId}/stats.';
+COMMENT ON COLUMN email_message_events.url IS 'Link target of a click, NULL for opens.';
+
+-- Post stats count the events of a post
+CREATE INDEX IF NOT EXISTS idx_email_message_events_post_kind
+    ON email_message_events (post_id, kind);
+
+UPDATE schema_version SET version = 41, updated_at = now();
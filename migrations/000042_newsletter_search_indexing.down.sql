@@ -0,0 +1,3 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS search_indexing;
+
+UPDATE schema_version SET version = 41, updated_at = now();
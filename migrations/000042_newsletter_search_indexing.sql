@@ -0,0 +1,7 @@
+-- Editors can keep the public archive of their newsletter out of search engines
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS search_indexing BOOLEAN NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN newsletters.search_indexing IS 'Whether search engines may index the public archive; off sends noindex robots directives and drops the sitemap.';
+
+UPDATE schema_version SET version = 42, updated_at = now();
@@ -0,0 +1,3 @@
+ALTER TABLE subscribers DROP COLUMN IF EXISTS confirmed_at;
+
+UPDATE schema_version SET version = 42, updated_at = now();
@@ -0,0 +1,10 @@
+-- When subscriptions were confirmed, for the subscriber growth stats. Subscriptions confirmed
+-- before this migration get their subscription time, as confirmation usually follows shortly.
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ;
+
+UPDATE subscribers SET confirmed_at = subscribed_at WHERE is_confirmed AND confirmed_at IS NULL;
+
+COMMENT ON COLUMN subscribers.confirmed_at IS 'When the subscription was confirmed; NULL while it is not. Backfilled with subscribed_at for older confirmations.';
+
+UPDATE schema_version SET version = 43, updated_at = now();
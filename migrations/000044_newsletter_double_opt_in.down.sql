@@ -0,0 +1,3 @@
+ALTER TABLE newsletters DROP COLUMN IF EXISTS double_opt_in;
+
+UPDATE schema_version SET version = 43, updated_at = now();
@@ -0,0 +1,7 @@
+-- Editors can let subscriptions start without a confirmation email
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS double_opt_in BOOLEAN NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN newsletters.double_opt_in IS 'Whether new subscribers confirm by email; off confirms subscriptions when they are made.';
+
+UPDATE schema_version SET version = 44, updated_at = now();
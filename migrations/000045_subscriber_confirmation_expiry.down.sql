@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_subscribers_confirmation_expires_at;
+ALTER TABLE subscribers DROP COLUMN IF EXISTS confirmation_expires_at;
+
+UPDATE schema_version SET version = 44, updated_at = now();
@@ -0,0 +1,12 @@
+-- Confirmation links expire; subscriptions confirmed before expiry existed keep links that never do
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS confirmation_expires_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN subscribers.confirmation_expires_at IS 'When the confirmation link stops working; NULL never expires. A new link can be requested until the retention job deletes the subscription.';
+
+-- The retention job looks up unconfirmed subscriptions whose link expired
+CREATE INDEX IF NOT EXISTS idx_subscribers_confirmation_expires_at
+    ON subscribers (confirmation_expires_at)
+    WHERE NOT is_confirmed AND confirmation_expires_at IS NOT NULL;
+
+UPDATE schema_version SET version = 45, updated_at = now();
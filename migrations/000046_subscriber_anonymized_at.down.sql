@@ -0,0 +1,3 @@
+ALTER TABLE subscribers DROP COLUMN IF EXISTS anonymized_at;
+
+UPDATE schema_version SET version = 45, updated_at = now();
@@ -0,0 +1,8 @@
+-- The retention job can anonymize unconfirmed subscribers instead of deleting them, so they still
+-- count in subscriber growth
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN subscribers.anonymized_at IS 'When the retention job replaced the address and tokens of this unconfirmed subscriber; anonymized subscribers are only counted in statistics.';
+
+UPDATE schema_version SET version = 46, updated_at = now();
@@ -0,0 +1,9 @@
+-- Addresses encrypted since this migration stay unreadable to earlier builds
+ALTER TABLE webhook_deliveries
+    DROP COLUMN IF EXISTS email_hash;
+ALTER TABLE email_jobs
+    DROP COLUMN IF EXISTS recipient_hash;
+ALTER TABLE email_outbox
+    DROP COLUMN IF EXISTS recipient_hash;
+
+UPDATE schema_version SET version = 46, updated_at = now();
@@ -0,0 +1,19 @@
+-- Blind indexes for recipient addresses encrypted by the application (SUBSCRIBER_EMAIL_KEY) in
+-- queued and failed emails and in webhook payloads, so the data of an address can still be found
+ALTER TABLE email_outbox
+    ADD COLUMN IF NOT EXISTS recipient_hash BYTEA;
+
+ALTER TABLE email_jobs
+    ADD COLUMN IF NOT EXISTS recipient_hash BYTEA;
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS email_hash BYTEA;
+
+COMMENT ON COLUMN email_outbox.recipient IS 'Address the email is sent to; encrypted (enc:v1: prefix) when subscriber email encryption is enabled.';
+COMMENT ON COLUMN email_outbox.recipient_hash IS 'Blind index of recipient; NULL for plaintext addresses.';
+COMMENT ON COLUMN email_jobs.recipient IS 'Address the email is sent to; encrypted (enc:v1: prefix) when subscriber email encryption is enabled.';
+COMMENT ON COLUMN email_jobs.recipient_hash IS 'Blind index of recipient; NULL for plaintext addresses.';
+COMMENT ON COLUMN webhook_deliveries.email_hash IS 'Blind index of the subscriber address in payload data.email, which is encrypted when subscriber email encryption is enabled; NULL otherwise.';
+COMMENT ON COLUMN post_send_attempts.failures IS 'Failed recipients and their errors; recipients are encrypted, with their hex blind index in recipient_hash, when subscriber email encryption is enabled.';
+
+UPDATE schema_version SET version = 47, updated_at = now();
@@ -0,0 +1,4 @@
+ALTER TABLE subscriber_email_changes
+    DROP COLUMN IF EXISTS old_email_hash;
+
+UPDATE schema_version SET version = 47, updated_at = now();
@@ -0,0 +1,8 @@
+-- Blind index of the address a subscription moved away from, so the change history of an
+-- encrypted address can be found when its data is exported or erased
+ALTER TABLE subscriber_email_changes
+    ADD COLUMN IF NOT EXISTS old_email_hash BYTEA;
+
+COMMENT ON COLUMN subscriber_email_changes.old_email_hash IS 'Blind index of old_email; NULL for plaintext addresses.';
+
+UPDATE schema_version SET version = 48, updated_at = now();
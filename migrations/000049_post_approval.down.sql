@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS post_reviews;
+
+ALTER TABLE newsletters DROP COLUMN IF EXISTS require_post_approval;
+
+UPDATE schema_version SET version = 48, updated_at = now();
@@ -0,0 +1,25 @@
+-- Owners can require their approval before posts are published or scheduled
+ALTER TABLE newsletters
+    ADD COLUMN IF NOT EXISTS require_post_approval BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN newsletters.require_post_approval IS 'Whether drafts need an approved review before they are published or scheduled.';
+
+-- Review state of drafts submitted for approval. Drafts without a row were never submitted.
+CREATE TABLE IF NOT EXISTS post_reviews (
+    post_id UUID PRIMARY KEY REFERENCES published_posts(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('draft', 'in_review', 'approved', 'rejected')),
+    comment TEXT,
+    submitted_by UUID REFERENCES auth.users(id) ON DELETE SET NULL,
+    submitted_at TIMESTAMPTZ,
+    reviewed_by UUID REFERENCES auth.users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE post_reviews IS 'Approval workflow of drafts: draft -> in_review -> approved or rejected.';
+COMMENT ON COLUMN post_reviews.comment IS 'Comment of the last approval or rejection.';
+
+-- The review queue lists drafts in review, longest waiting first
+CREATE INDEX IF NOT EXISTS idx_post_reviews_in_review_submitted_at
+    ON post_reviews (submitted_at, post_id) WHERE status = 'in_review';
+
+UPDATE schema_version SET version = 49, updated_at = now();